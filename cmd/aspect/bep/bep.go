@@ -0,0 +1,58 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bep
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/bep"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/interceptors"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+)
+
+func NewDefaultCmd() *cobra.Command {
+	return NewCmd(ioutils.DefaultStreams)
+}
+
+func NewCmd(streams ioutils.Streams) *cobra.Command {
+	v := bep.New(streams)
+
+	cmd := &cobra.Command{
+		Use:   "bep",
+		Short: "Manage the build event stream",
+		Long: `Manage the build event stream (BEP) the CLI reads from bazel and forwards to
+the BES backends.`,
+		GroupID: "aspect",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "flush",
+		Short: "Upload the build events kept for unhealthy BES backends",
+		Long: `When bes_deferred_upload is enabled in the CLI config, the build events that
+couldn't be uploaded to a BES backend because it was unhealthy are kept in the
+Aspect cache directory. The next build uploads them in the background; flush
+uploads them right away and reports the uploads that still fail, which are
+kept to be flushed again.`,
+		Args: cobra.NoArgs,
+		RunE: interceptors.Run(
+			[]interceptors.Interceptor{},
+			v.Flush,
+		),
+	})
+
+	return cmd
+}
@@ -75,12 +75,32 @@ To create non-default outputs, consider using the ` + "`--output_groups`" + ` fl
 
 The target pattern may be further filtered using the flag
 [--build_tag_filters](https://bazel.build/reference/command-line-reference#flag--build_tag_filters)
+
+Add ` + "`--aspect:summary`" + ` to print what the build did once it completes: the cache hit rate,
+the actions executed by mnemonic, the critical path and the slowest tests.
+
+Add ` + "`--aspect:ui`" + ` to draw a compact progress display from the build events instead of
+bazel's status output: the actions running and queued, the downloads and the targets completed.
+The rest of bazel's output is printed above it. With
+` + "`--watch`" + `, it shows a full-screen dashboard instead, with the status of the cycles, the result
+of the last one, the recently changed files and the output of bazel in separate panes.
+Add ` + "`--notify`" + ` to ` + "`--watch`" + `, or set ` + "`watch_notify: true`" + ` in the Aspect CLI config, to
+notify the result and the duration of each cycle on the desktop.
+Add ` + "`--poll`" + ` to ` + "`--watch`" + ` to find the changes by scanning the workspace every second, or every
+interval with ` + "`--poll=<duration>`" + `, on NFS mounts, Docker Desktop file shares and remote devcontainers
+where the file events never arrive.
+
+With build without the bytes, the logs of the actions that failed stay in the remote cache. Set
+` + "`fetch_failure_artifacts: true`" + ` in the Aspect CLI config to download them once the build
+completes. See 'aspect fetch-logs' to fetch them again.
 `,
 		GroupID: "common",
 		RunE: interceptors.Run(
 			[]interceptors.Interceptor{
 				flags.FlagsInterceptor(streams),
 				pluginSystem.BuildHooksInterceptor(streams),
+				pluginSystem.RewriteArgsInterceptor(),
+				pluginSystem.OutputFilterInterceptor(streams),
 				pluginSystem.BESPluginInterceptor(),
 			},
 			build.New(streams, hstreams, bzl).Run,
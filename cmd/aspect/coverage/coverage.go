@@ -68,6 +68,8 @@ See 'aspect help target-syntax' for details and examples on how to specify targe
 			[]interceptors.Interceptor{
 				flags.FlagsInterceptor(streams),
 				pluginSystem.TestHooksInterceptor(streams),
+				pluginSystem.RewriteArgsInterceptor(),
+				pluginSystem.OutputFilterInterceptor(streams),
 				pluginSystem.BESPluginInterceptor(),
 			},
 			coverage.New(streams, hstreams, bzl).Run,
@@ -0,0 +1,59 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package fetchlogs
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/fetchlogs"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/interceptors"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+)
+
+func NewDefaultCmd() *cobra.Command {
+	return NewCmd(ioutils.DefaultStreams)
+}
+
+func NewCmd(streams ioutils.Streams) *cobra.Command {
+	v := fetchlogs.New(streams)
+
+	cmd := &cobra.Command{
+		Use:   "fetch-logs [invocation id]",
+		Short: "Download the logs of the failures of an invocation from the remote cache",
+		Long: `With build without the bytes, the logs of the actions that failed and the test.log
+and undeclared outputs of the tests that failed stay in the remote cache, where
+bazel refers to them with bytestream:// URIs in the build events.
+
+When fetch_failure_artifacts is enabled in the CLI config, aspect build and
+aspect test record these artifacts in the Aspect cache directory and download
+them once they complete. fetch-logs downloads those of an invocation again, or
+of the last invocation if none is given, and prints their paths.
+
+The artifacts are downloaded with TLS unless bazel connected to the remote
+cache with a grpc:// URI. Pass the headers the remote cache requires, e.g. for
+authentication, with --remote-header.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: interceptors.Run(
+			[]interceptors.Interceptor{},
+			v.Run,
+		),
+		GroupID: "aspect",
+	}
+	cmd.Flags().String("output-dir", "", "Directory to download the artifacts to, instead of that of the invocation in the Aspect cache directory")
+	cmd.Flags().StringArray("remote-header", []string{}, "Header to send to the remote cache, as name=value. Can be specified multiple times.")
+
+	return cmd
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package flaky
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/flaky"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/interceptors"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+)
+
+func NewDefaultCmd() *cobra.Command {
+	return NewCmd(ioutils.DefaultStreams, bazel.WorkspaceFromWd)
+}
+
+func NewCmd(streams ioutils.Streams, bzl bazel.Bazel) *cobra.Command {
+	v := flaky.New(streams, bzl)
+
+	cmd := &cobra.Command{
+		Use:   "flaky",
+		Short: "Find the flaky tests of the workspace",
+		Long: `Find the tests whose outcome varies on identical inputs, from the results of the
+tests recorded by aspect test --detect-flaky, or by every aspect test when
+test_history is enabled in the CLI config.`,
+		GroupID: "aspect",
+	}
+
+	report := &cobra.Command{
+		Use:   "report",
+		Short: "Report the tests that both passed and failed on identical inputs",
+		Long: `Report the tests of the workspace that both passed and failed in the same
+configuration and with the same digest of the outputs of their target, which
+changes with their inputs. A test that fails only once its inputs changed is
+broken rather than flaky, and isn't reported.
+
+The results are recorded in the Aspect cache directory; those of cached tests
+aren't recorded again.`,
+		Args: cobra.NoArgs,
+		RunE: interceptors.Run(
+			[]interceptors.Interceptor{},
+			v.Report,
+		),
+	}
+	report.Flags().Bool("json", false, "Print the flaky tests as JSON")
+	report.Flags().Duration("since", 0, "Only consider the results recorded within this duration, e.g. 168h")
+	cmd.AddCommand(report)
+
+	return cmd
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package history
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/history"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/interceptors"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+)
+
+func NewDefaultCmd() *cobra.Command {
+	return NewCmd(ioutils.DefaultStreams)
+}
+
+func NewCmd(streams ioutils.Streams) *cobra.Command {
+	v := history.New(streams)
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List the past invocations of the workspace",
+		Long: `List the past invocations of aspect build, test, coverage and run in the
+workspace, the most recent first: their invocation ID, command, exit code,
+duration and target patterns.
+
+The invocations are recorded in the output base of the workspace from their
+build events. The last 1000 invocations are kept. Set invocation_history: false
+in the Aspect CLI config to stop recording them.`,
+		Args: cobra.NoArgs,
+		RunE: interceptors.Run(
+			[]interceptors.Interceptor{},
+			v.List,
+		),
+		GroupID: "aspect",
+	}
+	cmd.Flags().Int("limit", 20, "Number of invocations to list, or all of them if 0")
+	cmd.Flags().Bool("json", false, "Print the invocations as JSON")
+
+	show := &cobra.Command{
+		Use:   "show <invocation id>",
+		Short: "Show the summary of a past invocation",
+		Long: `Show the summary of a past invocation of the workspace: its command line,
+target patterns, exit code, duration, the actions it executed, its cache hit
+rate, the outcome of its tests and the targets that failed. A prefix of the invocation ID is enough as
+long as a single invocation starts with it.`,
+		Args: cobra.ExactArgs(1),
+		RunE: interceptors.Run(
+			[]interceptors.Interceptor{},
+			v.Show,
+		),
+	}
+	show.Flags().Bool("json", false, "Print the invocation as JSON")
+	cmd.AddCommand(show)
+
+	return cmd
+}
@@ -124,8 +124,10 @@ func command(bzl bazel.Bazel, streams ioutils.Streams, args []string, startupFla
 	pluginsConfig := viper.Get("plugins")
 	pluginSystem := system.NewPluginSystem()
 
-	if !root.CheckAspectDisablePluginsFlag(args) {
-		if err := pluginSystem.Configure(streams, pluginsConfig); err != nil {
+	// Plugins are not loaded for 'aspect plugin' commands so that a broken plugin
+	// can still be diagnosed and updated.
+	if !root.CheckAspectDisablePluginsFlag(args) && !root.IsPluginCommand(args) {
+		if err := pluginSystem.Configure(context.Background(), streams, root.CommandName(args), pluginsConfig); err != nil {
 			return err
 		}
 	}
@@ -17,6 +17,8 @@
 package outputs
 
 import (
+	"context"
+
 	"github.com/spf13/cobra"
 
 	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/outputs"
@@ -24,13 +26,14 @@ import (
 	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/interceptors"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system"
 )
 
-func NewDefaultCmd() *cobra.Command {
-	return NewCmd(ioutils.DefaultStreams, bazel.WorkspaceFromWd)
+func NewDefaultCmd(pluginSystem system.PluginSystem) *cobra.Command {
+	return NewCmd(ioutils.DefaultStreams, pluginSystem, bazel.WorkspaceFromWd)
 }
 
-func NewCmd(streams ioutils.Streams, bzl bazel.Bazel) *cobra.Command {
+func NewCmd(streams ioutils.Streams, pluginSystem system.PluginSystem, bzl bazel.Bazel) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "outputs <expression> [mnemonic]",
 		Short: "Print paths to declared output files",
@@ -41,6 +44,15 @@ followed by a path to the file, relative to the workspace root.
 
 You can optionally provide an extra argument, which is a filter on the mnemonic.
 
+With --bep, the given target pattern is built instead, and the output files that Bazel reports
+in the Build Event Protocol are printed, each with the label of the target that produced it.
+These are the files of the requested output groups, by default the default outputs, exactly as
+they were built. The mnemonic filter is applied by querying the actions
+of the targets.
+
+With --json, the outputs are printed as a JSON array of objects with the label, the mnemonic or
+output group, and the path of each output.
+
 'ExecutableHash' is a special value for the mnemonic. This combines the ExecutableSymlink and
 SourceSymlinkManifest mnemonics, then hashes the outputs of these two. This provides a good hash
 for an executable target to determine if it has changed.`,
@@ -67,11 +79,30 @@ bazel-out/k8-fastbuild/bin/cli/core/core_/core
 ExecutableSymlink bazel-out/darwin-fastbuild/bin/cli/release
 SourceSymlinkManifest bazel-out/darwin-fastbuild/bin/cli/release.runfiles_manifest
 SymlinkTree bazel-out/darwin-fastbuild/bin/cli/release.runfiles/MANIFEST
-Middleman bazel-out/darwin-fastbuild/internal/_middlemen/cli_Srelease-runfiles`,
+Middleman bazel-out/darwin-fastbuild/internal/_middlemen/cli_Srelease-runfiles
+
+# Build the //cli/core target and show the files it produced:
+
+% aspect outputs --bep //cli/core
+
+//cli/core:core bazel-out/k8-fastbuild/bin/cli/core/core_/core
+
+# Build the //cli/core target and show the files it produced as JSON:
+
+% aspect outputs --bep --json //cli/core
+
+[
+  {
+    "label": "//cli/core:core",
+    "output_group": "default",
+    "path": "bazel-out/k8-fastbuild/bin/cli/core/core_/core"
+  }
+]`,
 		GroupID: "aspect",
 		RunE: interceptors.Run(
 			[]interceptors.Interceptor{
 				flags.FlagsInterceptor(streams),
+				bepInterceptor(pluginSystem),
 			},
 			outputs.New(streams, bzl).Run,
 		),
@@ -81,3 +112,20 @@ Middleman bazel-out/darwin-fastbuild/internal/_middlemen/cli_Srelease-runfiles`,
 
 	return cmd
 }
+
+// bepInterceptor uses the BES pipe interceptor when the outputs are built,
+// since their files are read from the build events. Querying the outputs
+// doesn't need one.
+func bepInterceptor(pluginSystem system.PluginSystem) interceptors.Interceptor {
+	besPipeInterceptor := pluginSystem.BESPipeInterceptor()
+	return func(ctx context.Context, cmd *cobra.Command, args []string, next interceptors.RunEContextFn) error {
+		useBEP, err := cmd.Flags().GetBool(outputs.BEPFlagName)
+		if err != nil {
+			return err
+		}
+		if !useBEP {
+			return next(ctx, cmd, args)
+		}
+		return besPipeInterceptor(ctx, cmd, args, next)
+	}
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plugin
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/plugin"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/interceptors"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+)
+
+func NewDefaultCmd() *cobra.Command {
+	return NewCmd(ioutils.DefaultStreams)
+}
+
+func NewCmd(streams ioutils.Streams) *cobra.Command {
+	v := plugin.New(streams)
+
+	cmd := &cobra.Command{
+		Use:   "plugin",
+		Short: "Manage Aspect CLI plugins",
+		Long: `Search the plugin registry and manage the plugins configured in the workspace
+.aspect/cli/config.yaml file.`,
+		GroupID: "aspect",
+	}
+
+	searchCmd := &cobra.Command{
+		Use:   "search [term]",
+		Short: "Search the plugin registry",
+		RunE: interceptors.Run(
+			[]interceptors.Interceptor{},
+			v.Search,
+		),
+	}
+	searchCmd.Flags().String("registry", plugin.DefaultRegistryURL, "URL or path of the plugin registry")
+	cmd.AddCommand(searchCmd)
+
+	installCmd := &cobra.Command{
+		Use:   "install <name>[@version]",
+		Short: "Add a plugin from the registry to the workspace config",
+		Long: `Adds a plugin from the registry to the workspace .aspect/cli/config.yaml file.
+
+The latest release is installed unless a version is given. The sha256 of the
+release binary for every published platform is pinned in the config so that
+the CLI refuses to run a binary that doesn't match.`,
+		Example: `# Install the latest release of the fix-visibility plugin
+aspect plugin install fix-visibility
+
+# Install a specific version
+aspect plugin install fix-visibility@v0.1.0`,
+		Args: cobra.ExactArgs(1),
+		RunE: interceptors.Run(
+			[]interceptors.Interceptor{},
+			v.Install,
+		),
+	}
+	installCmd.Flags().String("registry", plugin.DefaultRegistryURL, "URL or path of the plugin registry")
+	cmd.AddCommand(installCmd)
+
+	updateCmd := &cobra.Command{
+		Use:   "update [name...]",
+		Short: "Upgrade plugins in the workspace config to their latest release",
+		RunE: interceptors.Run(
+			[]interceptors.Interceptor{},
+			v.Update,
+		),
+	}
+	updateCmd.Flags().String("registry", plugin.DefaultRegistryURL, "URL or path of the plugin registry")
+	cmd.AddCommand(updateCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list [name...]",
+		Short: "List the configured plugins and what they run",
+		Long: `Launches every configured plugin, or only the named ones, and lists its
+version, the URL or path it is loaded from, the sha256 of its binary, the
+version of the plugin SDK it speaks, the build events it subscribes to and
+the custom commands it registers.
+
+Use it to audit the code the CLI runs on developer machines, or to share the
+plugin setup of a workspace when asking for support.`,
+		RunE: interceptors.Run(
+			[]interceptors.Interceptor{},
+			v.List,
+		),
+	})
+
+	devCmd := &cobra.Command{
+		Use:   "dev --bep-file=<file> <plugin-binary>",
+		Short: "Replay captured build events through a plugin under development",
+		Long: `Launches a single plugin binary and replays the build events captured with
+bazel's --build_event_binary_file flag through its BEPEventCallback, in the
+order bazel wrote them. Only the events the plugin subscribes to are passed.
+
+The plugin is set up with the properties of the workspace plugin loaded from
+the same path, if any. Plugin authors can exercise their event handling this
+way without running a full bazel build each time.`,
+		Example: `# Capture the build events of a build once
+bazel build //... --build_event_binary_file=/tmp/events.pb
+
+# Replay them after every change to the plugin
+aspect plugin dev --bep-file=/tmp/events.pb bazel-bin/my_plugin/my_plugin`,
+		Args: cobra.ExactArgs(1),
+		RunE: interceptors.Run(
+			[]interceptors.Interceptor{},
+			v.Dev,
+		),
+	}
+	devCmd.Flags().String("bep-file", "", "Path to a file written by bazel's --build_event_binary_file flag")
+	devCmd.MarkFlagRequired("bep-file")
+	cmd.AddCommand(devCmd)
+
+	daemonCmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "Manage the background daemons run by plugins",
+		Long: `Plugins may run long-lived daemons in the background, e.g. a log uploader or a
+cache warmer. The CLI starts them on the first command that loads the plugin,
+tracks them through pidfiles in the output base and reuses them across
+invocations until they are stopped.`,
+	}
+	daemonCmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "List the daemons started by plugins and whether they are running",
+		Args:  cobra.NoArgs,
+		RunE: interceptors.Run(
+			[]interceptors.Interceptor{},
+			v.DaemonStatus,
+		),
+	})
+	daemonCmd.AddCommand(&cobra.Command{
+		Use:   "stop [plugin[/daemon]...]",
+		Short: "Stop the daemons started by plugins",
+		Long: `Stops every daemon started by plugins, or only the daemons of the named plugins
+or the named daemons. A stopped daemon is started again by the next command
+that loads its plugin.`,
+		RunE: interceptors.Run(
+			[]interceptors.Interceptor{},
+			v.DaemonStop,
+		),
+	})
+	cmd.AddCommand(daemonCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "doctor [name...]",
+		Short: "Check the health of the configured plugins",
+		Long: `Launches every configured plugin, or only the named ones, and reports in a
+table whether it could be downloaded and its checksum verified, whether it
+completed the handshake with the CLI, whether its properties match the schema
+the plugin declares and whether it completed setup.
+
+Plugins are not loaded when running 'aspect plugin' commands, so doctor works
+even when a plugin prevents every other command from running.`,
+		RunE: interceptors.Run(
+			[]interceptors.Interceptor{},
+			v.Doctor,
+		),
+	})
+
+	return cmd
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package rerun
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/rerun"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/interceptors"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+)
+
+func NewDefaultCmd() *cobra.Command {
+	return NewCmd(ioutils.DefaultStreams)
+}
+
+func NewCmd(streams ioutils.Streams) *cobra.Command {
+	v := rerun.New(streams)
+
+	cmd := &cobra.Command{
+		Use:   "rerun",
+		Short: "Run the last invocation again",
+		Long: `Run the last invocation of aspect build, test, coverage or run in the workspace
+again, with the same arguments, as recorded in the invocation history (see
+'aspect history').
+
+With --failed, only the targets that failed to build and the tests that didn't
+pass are run again, with the same flags: they replace the target patterns of
+the invocation. The last invocation of aspect run is always run as it was.`,
+		Example: `# Run the tests that failed in the last aspect test again
+aspect rerun --failed`,
+		Args: cobra.NoArgs,
+		RunE: interceptors.Run(
+			[]interceptors.Interceptor{},
+			v.Run,
+		),
+		GroupID: "aspect",
+	}
+	cmd.Flags().Bool("failed", false, "Only run the targets and tests that failed again")
+
+	return cmd
+}
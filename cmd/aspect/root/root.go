@@ -19,6 +19,7 @@ package root
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/mattn/go-isatty"
@@ -27,6 +28,7 @@ import (
 	"github.com/aspect-build/aspect-cli-legacy/buildinfo"
 	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/analyzeprofile"
 	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/aquery"
+	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/bep"
 	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/build"
 	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/canonicalizeflags"
 	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/clean"
@@ -37,7 +39,10 @@ import (
 	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/docs"
 	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/dump"
 	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/fetch"
+	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/fetchlogs"
+	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/flaky"
 	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/help"
+	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/history"
 	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/info"
 	init_ "github.com/aspect-build/aspect-cli-legacy/cmd/aspect/init"
 	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/license"
@@ -45,11 +50,15 @@ import (
 	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/mobileinstall"
 	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/mod"
 	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/outputs"
+	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/plugin"
 	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/print"
 	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/printaction"
+	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/ps"
 	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/query"
+	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/rerun"
 	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/run"
 	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/shutdown"
+	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/stop"
 	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/sync"
 	"github.com/aspect-build/aspect-cli-legacy/cmd/aspect/test"
 	vendor "github.com/aspect-build/aspect-cli-legacy/cmd/aspect/vend"
@@ -97,6 +106,23 @@ func CheckAspectDisablePluginsFlag(args []string) bool {
 	return false
 }
 
+// CommandName returns the name of the command the args run, which is the first
+// argument that is not a flag, or an empty string when there is none.
+func CommandName(args []string) string {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			return arg
+		}
+	}
+	return ""
+}
+
+// IsPluginCommand reports whether the args run one of the 'aspect plugin'
+// commands, which manage plugins and must work without loading them.
+func IsPluginCommand(args []string) bool {
+	return CommandName(args) == "plugin"
+}
+
 func HandleVersionFlags(streams ioutils.Streams, args []string, bzl bazel.Bazel) {
 	if len(args) == 1 && (args[0] == "--version" || args[0] == "-v") {
 		fmt.Fprintf(streams.Stdout, "%s %s\n", buildinfo.Current().GnuName(), buildinfo.Current().Version())
@@ -146,6 +172,7 @@ func NewCmd(
 	// IMPORTANT: when adding a new command, also update the COMMAND_LIST list in /docs/command_list.bzl
 	cmd.AddCommand(analyzeprofile.NewDefaultCmd())
 	cmd.AddCommand(aquery.NewDefaultCmd())
+	cmd.AddCommand(bep.NewDefaultCmd())
 	cmd.AddCommand(build.NewDefaultCmd(pluginSystem))
 	cmd.AddCommand(canonicalizeflags.NewDefaultCmd())
 	cmd.AddCommand(clean.NewDefaultCmd())
@@ -156,18 +183,25 @@ func NewCmd(
 	cmd.AddCommand(docs.NewDefaultCmd())
 	cmd.AddCommand(dump.NewDefaultCmd())
 	cmd.AddCommand(fetch.NewDefaultCmd())
+	cmd.AddCommand(fetchlogs.NewDefaultCmd())
+	cmd.AddCommand(flaky.NewDefaultCmd())
+	cmd.AddCommand(history.NewDefaultCmd())
 	cmd.AddCommand(info.NewDefaultCmd())
 	cmd.AddCommand(init_.NewDefaultCmd())
 	cmd.AddCommand(license.NewDefaultCmd())
 	cmd.AddCommand(lint.NewDefaultCmd(pluginSystem))
 	cmd.AddCommand(mobileinstall.NewDefaultCmd())
 	cmd.AddCommand(mod.NewDefaultCmd())
-	cmd.AddCommand(outputs.NewDefaultCmd())
+	cmd.AddCommand(outputs.NewDefaultCmd(pluginSystem))
+	cmd.AddCommand(plugin.NewDefaultCmd())
 	cmd.AddCommand(print.NewDefaultCmd())
 	cmd.AddCommand(printaction.NewDefaultCmd())
+	cmd.AddCommand(ps.NewDefaultCmd())
 	cmd.AddCommand(query.NewDefaultCmd())
+	cmd.AddCommand(rerun.NewDefaultCmd())
 	cmd.AddCommand(run.NewDefaultCmd(pluginSystem))
 	cmd.AddCommand(shutdown.NewDefaultCmd())
+	cmd.AddCommand(stop.NewDefaultCmd())
 	cmd.AddCommand(sync.NewDefaultCmd())
 	cmd.AddCommand(test.NewDefaultCmd(pluginSystem))
 	cmd.AddCommand(vendor.NewDefaultCmd())
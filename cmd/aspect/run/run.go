@@ -46,7 +46,7 @@ func NewCmd(
 	bzl bazel.Bazel,
 ) *cobra.Command {
 	return &cobra.Command{
-		Use:   "run [--run_under=command-prefix] <target> [--watch] -- [args for program ...]",
+		Use:   "run [--run_under=command-prefix] [--aspect:pty | --detach] <target> [--watch [<target> ...] [--watch-settle=<duration>] [--watch-env=NAME=VALUE ...] [--notify] [--poll[=<duration>]] [--aspect:ui]] -- [args for program ...]",
 		Args:  cobra.MinimumNArgs(1),
 		Short: "Build a single target and run it with the given arguments",
 		Long: `Equivalent to ` + "`aspect build <target>`" + ` followed by spawning the resulting executable.
@@ -74,6 +74,68 @@ Use the [--run_under](https://bazel.build/docs/user-manual#run_under) flag with
 Another common approach if the program's code is in your repo (first-party) is to check for the
 presence of ` + "`BUILD_WORKSPACE_DIRECTORY`" + ` in the environment, then change the working
 directory of the process. You'd typically do this at the very beginning of the program execution.
+
+Add ` + "`--aspect:pty`" + ` to run the program in a pseudo-terminal connected to the terminal, for the colors,
+the cursor control and the prompts of programs checking for a terminal, such as REPLs. The keys are
+passed through as they are pressed, including Ctrl-C, the pseudo-terminal is resized with the terminal
+and the signals sent to aspect are forwarded to the program. With ` + "`--watch`" + `, each restart of the
+target runs in the same pseudo-terminal, Ctrl-C still ends the session and the keybindings are off.
+` + "`--aspect:pty`" + ` runs a single target, can't be combined with ` + "`--aspect:ui`" + ` and isn't supported on Windows.
+
+Add ` + "`--detach`" + ` to run the program in the background once it is built, such as a devserver of the workspace,
+with its output appended to a log file under the output base. ` + "`aspect ps`" + ` lists the programs started this way and
+` + "`aspect stop <target>`" + ` stops them, while running the same target again fails until it is stopped.
+` + "`--detach`" + ` can't be combined with ` + "`--watch`" + ` or ` + "`--aspect:pty`" + `.
+
+With ` + "`--watch`" + `, the target is rebuilt and run again whenever its inputs change,
+as reported by [watchman](https://facebook.github.io/watchman/) if installed, or else by a built-in file watcher
+that scales worse with the size of the workspace.
+Several run targets, given as absolute labels, are watched in one session with
+` + "`aspect run --watch //app:server //app:worker`" + `: the targets are rebuilt one after the other
+and only those whose inputs changed are restarted, while the others keep running. Their output is
+prefixed with their label in a color of its own, as with a Procfile runner, so that a full stack such
+as an API, a frontend and a worker runs in one session sharing one bazel server.
+Changes to the convenience symlinks of bazel, to the swap and backup files of editors, and to the
+files matching the glob patterns of ` + "`watch_ignore`" + ` in the Aspect CLI config are ignored.
+` + "`--watch-settle=<duration>`" + ` sets how long the filesystem is left to settle after each cycle,
+100ms by default or ` + "`watch_settle`" + ` in the Aspect CLI config. Repositories generating many
+files may need longer, while ` + "`--watch-settle=0s`" + ` reacts to changes immediately.
+Each cycle ends by printing the time it spent building, split into the analysis and the execution phases
+when the build events report them, detecting the changes, restarting the targets and letting the
+filesystem settle, unless ` + "`watch_timings`" + ` is false in the Aspect CLI config.
+In a terminal, press ` + "`r`" + ` to rebuild and restart the targets, ` + "`p`" + ` to pause or resume watching
+and ` + "`q`" + ` to quit, unless ` + "`watch_keys`" + ` is false in the Aspect CLI config, such as for programs
+reading their stdin.
+Before notifying or restarting a target, the changed files of the cycle are written as JSON to the
+file named by ` + "`ASPECT_WATCH_CHANGES_FILE`" + ` in its environment, such as for a devserver to replace
+the changed modules instead of reloading the page.
+Each start of a target is passed the number of the cycle, 0 for the initial build, the number of
+changed files and the invocation id of its build in ` + "`ASPECT_WATCH_CYCLE`" + `, ` + "`ASPECT_WATCH_CHANGE_COUNT`" + ` and
+` + "`ASPECT_WATCH_INVOCATION_ID`" + `. ` + "`--watch-env=NAME=VALUE`" + `, which may be repeated and takes precedence over the ` + "`watch_env`" + `
+map of the Aspect CLI config, sets further variables, whose values expand environment variables such as
+` + "`${USER}`" + ` and the variables ` + "`{cycle}`" + `, ` + "`{changes}`" + ` and ` + "`{invocation_id}`" + `. Targets notified through
+a watch protocol rather than restarted keep the variables of their initial start.
+Targets tagged ` + "`ibazel_notify_changes`" + ` may subscribe to the HotReload gRPC service at the address in
+` + "`ASPECT_WATCH_HOT_RELOAD_ADDRESS`" + ` to receive the start, the result, the changed files and the timings of each cycle
+rather than the ` + "`IBAZEL_BUILD_*`" + ` lines on their stdin, which are written until they subscribe. See
+` + "`pkg/aspect/run/hotreload/hotreload.proto`" + `.
+The changes are detected from the execution log of each build, or from the digests of the runfiles
+reported in the build events with ` + "`watch_change_detection: bep`" + ` in the Aspect CLI config, which
+spares the cost of the execution log and falls back to it when the build events are missing.
+The runfiles manifest of the target is only parsed again when it changes, and is kept in the Aspect
+cache directory for the next sessions of the same target and configuration.
+With ` + "`watch_scope_detection: cquery`" + ` in the Aspect CLI config, the source files of each target are
+queried with ` + "`cquery 'kind(\"source file\", deps(target))'`" + `, so that only their changes rebuild the
+target, which is also restarted when the build runs no action for them, such as when they are cached
+remotely. The sources are queried again when the BUILD or .bzl files change.
+Add ` + "`--aspect:ui`" + ` to show a full-screen dashboard with the status of the cycles, the result of
+the last one, the recently changed files and the output of bazel and the targets in separate panes.
+` + "`--notify`" + `, or ` + "`watch_notify: true`" + ` in the Aspect CLI config, notifies the result and the
+duration of each cycle on the desktop, with osascript on macOS, notify-send on Linux and PowerShell
+on Windows.
+Add ` + "`--poll`" + ` to find the changes by scanning the workspace every second, or every interval with
+` + "`--poll=<duration>`" + `, on NFS mounts, Docker Desktop file shares and remote devcontainers where the
+file events never arrive.
 `,
 		GroupID:               "common",
 		DisableFlagsInUseLine: true,
@@ -81,6 +143,8 @@ directory of the process. You'd typically do this at the very beginning of the p
 			[]interceptors.Interceptor{
 				flags.FlagsInterceptor(streams),
 				pluginSystem.RunHooksInterceptor(streams),
+				pluginSystem.RewriteArgsInterceptor(),
+				pluginSystem.OutputFilterInterceptor(streams),
 				pluginSystem.BESPluginInterceptor(),
 			},
 			run.New(streams, hstreams, bzl).Run,
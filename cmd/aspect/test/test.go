@@ -71,12 +71,49 @@ defaults for 'build' from your .bazelrc.  If you don't use .bazelrc,
 don't forget to pass all your 'build' options to 'test' too.
 
 See 'aspect help target-syntax' for details and examples on how to specify targets.
+
+Add ` + "`--aspect:summary`" + ` to print what the build did once the tests complete: the cache hit
+rate, the actions executed by mnemonic, the critical path and the slowest tests.
+
+Add ` + "`--aspect:ui`" + ` to draw a compact progress display from the build events instead of
+bazel's status output: the actions running and queued, the downloads and the targets completed.
+The rest of bazel's output is printed above it. With
+` + "`--watch`" + `, it shows a full-screen dashboard instead, with the status of the cycles, the result
+of the last one, the recently changed files and the output of bazel in separate panes.
+Add ` + "`--notify`" + ` to ` + "`--watch`" + `, or set ` + "`watch_notify: true`" + ` in the Aspect CLI config, to
+notify the result and the duration of each cycle on the desktop.
+Add ` + "`--poll`" + ` to ` + "`--watch`" + ` to find the changes by scanning the workspace every second, or every
+interval with ` + "`--poll=<duration>`" + `, on NFS mounts, Docker Desktop file shares and remote devcontainers
+where the file events never arrive.
+
+With ` + "`--watch`" + `, only the tests depending on the changed files are run again, from the graph of
+the dependencies of the tests queried after running all of them, and the skipped tests are counted.
+Changes to the BUILD and .bzl files, or new files in the packages of the tests, run all of them
+again, as does pressing ` + "`r`" + ` or ` + "`t`" + `. Set ` + "`watch_affected_tests: false`" + ` in the Aspect CLI config to
+always run all the tests.
+
+When stderr is a terminal, a table of the status of the tests is drawn below bazel's output while they
+run, and the logs of the tests that failed are printed once they complete. Set ` + "`test_status: false`" + `
+in the Aspect CLI config to turn it off, or ` + "`test_status: true`" + ` to print the logs of the
+failed tests when stderr is not a terminal.
+
+Add ` + "`--detect-flaky`" + ` to record the results of the tests in the test history of the workspace,
+and print those of the tests that both passed and failed on identical inputs in it. Set
+` + "`test_history: true`" + ` in the Aspect CLI config to record the results of every test run. See
+'aspect flaky report' to report the flaky tests of the whole workspace.
+
+With build without the bytes, the test.log and the undeclared outputs of the tests that failed
+stay in the remote cache. Set ` + "`fetch_failure_artifacts: true`" + ` in the Aspect CLI config to
+download them, along with the logs of the actions that failed, once the tests complete. See
+'aspect fetch-logs' to fetch them again.
 `,
 		GroupID: "common",
 		RunE: interceptors.Run(
 			[]interceptors.Interceptor{
 				flags.FlagsInterceptor(streams),
 				pluginSystem.TestHooksInterceptor(streams),
+				pluginSystem.RewriteArgsInterceptor(),
+				pluginSystem.OutputFilterInterceptor(streams),
 				pluginSystem.BESPluginInterceptor(),
 			},
 			test.New(streams, hstreams, bzl).Run,
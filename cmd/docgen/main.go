@@ -17,6 +17,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -57,7 +58,7 @@ func command(bzl bazel.Bazel, args []string, startupFlags []string) error {
 	pluginSystem := system.NewPluginSystem()
 
 	if !root.CheckAspectDisablePluginsFlag(args) {
-		if err := pluginSystem.Configure(ioutils.DefaultStreams, nil); err != nil {
+		if err := pluginSystem.Configure(context.Background(), ioutils.DefaultStreams, "", nil); err != nil {
 			return err
 		}
 	}
@@ -4,6 +4,7 @@ This module contains the list of top-level commands from the aspect CLI.
 COMMAND_LIST = [
     "analyze-profile",
     "aquery",
+    "bep",
     "build",
     "canonicalize-flags",
     "clean",
@@ -13,16 +14,23 @@ COMMAND_LIST = [
     "cquery",
     "docs",
     "fetch",
+    "fetch-logs",
+    "flaky",
+    "history",
     "info",
     "init",
     "license",
     "lint",
     "mod",
     "outputs",
+    "plugin",
     "print",
+    "ps",
     "query",
+    "rerun",
     "run",
     "shutdown",
+    "stop",
     "test",
     "version",
 ]
@@ -0,0 +1,69 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bep
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+)
+
+type BEP struct {
+	ioutils.Streams
+}
+
+func New(streams ioutils.Streams) *BEP {
+	return &BEP{
+		Streams: streams,
+	}
+}
+
+// Flush uploads the build events that couldn't be uploaded to some BES
+// backends when they were unhealthy.
+func (runner *BEP) Flush(ctx context.Context, cmd *cobra.Command, args []string) error {
+	dir, err := bep.DeferredUploadsDir()
+	if err != nil {
+		return err
+	}
+	results, err := bep.FlushDeferredUploads(ctx, dir)
+	if err != nil {
+		return err
+	}
+
+	if len(results) == 0 {
+		fmt.Fprintln(runner.Stdout, "No build events are waiting to be uploaded")
+		return nil
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			fmt.Fprintf(runner.Stderr, "Failed to upload the build events of invocation %s to %s: %v\n", r.InvocationID, r.Backend, r.Err)
+			continue
+		}
+		fmt.Fprintf(runner.Stdout, "Uploaded the build events of invocation %s to %s\n", r.InvocationID, r.Backend)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d uploads failed; they are kept to be flushed again", failed, len(results))
+	}
+	return nil
+}
@@ -18,16 +18,19 @@ package build
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"net"
 	"os"
 	"os/signal"
 	"syscall"
 
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/fetchlogs"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/progress"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/root/flags"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/summary"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/watch"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils/linefilter"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
 	logger "github.com/aspect-build/aspect-gazelle/common/logger"
 	"github.com/aspect-build/aspect-gazelle/runner/pkg/watchman"
@@ -60,10 +63,33 @@ func New(
 func (runner *Build) Run(ctx context.Context, cmd *cobra.Command, args []string) error {
 	bazelCmd := []string{"build"}
 	watch, args := flags.RemoveFlag(args, "--watch")
+	summarize, args := flags.RemoveFlag(args, flags.AspectSummaryFlag)
+	ui, args := flags.RemoveFlag(args, flags.AspectUIFlag)
 	bazelCmd = append(bazelCmd, args...)
 
+	var buildSummary *summary.Summary
+	var renderer *progress.Renderer
+	var failureArtifacts *fetchlogs.Collector
 	if bep.HasBESInterceptor(ctx) {
-		bazelCmd = flags.AddFlagToCommand(bazelCmd, bep.BESInterceptorFromContext(ctx).Args()...)
+		besInterceptor := bep.BESInterceptorFromContext(ctx)
+		besArgs, err := besInterceptor.Args()
+		if err != nil {
+			return err
+		}
+		bazelCmd = flags.AddFlagToCommand(bazelCmd, besArgs...)
+		if summarize && !watch {
+			buildSummary = summary.New()
+			besInterceptor.RegisterSubscriber(buildSummary.Callback, bep.SubscriberOptions{})
+		}
+		if ui && !watch {
+			renderer = progress.New(progress.NewRegion(runner.streams.Stderr))
+			besInterceptor.RegisterSubscriber(renderer.Callback, bep.SubscriberOptions{})
+			bazelCmd = flags.AddFlagToCommand(bazelCmd, progress.BazelFlags...)
+		}
+		if fetchlogs.Enabled() && !watch {
+			failureArtifacts = fetchlogs.NewCollector()
+			besInterceptor.RegisterSubscriber(failureArtifacts.Callback, bep.SubscriberOptions{})
+		}
 	}
 
 	bzlCommandStreams := runner.streams
@@ -76,6 +102,11 @@ func (runner *Build) Run(ctx context.Context, cmd *cobra.Command, args []string)
 			bzlCommandStreams = runner.hstreams
 		}
 	}
+	bzlCommandStreams, flushOutput := linefilter.Streams(ctx, bzlCommandStreams)
+	defer flushOutput()
+	if renderer != nil {
+		bzlCommandStreams.Stderr = renderer.Stderr(bzlCommandStreams.Stderr)
+	}
 
 	var err error
 	if watch {
@@ -97,9 +128,22 @@ func (runner *Build) Run(ctx context.Context, cmd *cobra.Command, args []string)
 			cancel()
 		}()
 
-		err = runner.buildWatch(watchCtx, bazelCmd, bzlCommandStreams)
+		err = runner.buildWatch(watchCtx, bazelCmd, bzlCommandStreams, ui)
 	} else {
 		err = runner.bzl.RunCommand(bzlCommandStreams, nil, bazelCmd...)
+		if renderer != nil {
+			renderer.Finish()
+		}
+		if buildSummary != nil {
+			if printErr := buildSummary.Print(runner.streams.Stderr); printErr != nil && err == nil {
+				err = printErr
+			}
+		}
+		if failureArtifacts != nil {
+			if fetchErr := failureArtifacts.Fetch(ctx, runner.streams.Stderr); fetchErr != nil {
+				fmt.Fprintf(runner.streams.Stderr, "%s failed to fetch the failure artifacts: %v\n", color.YellowString("WARNING:"), fetchErr)
+			}
+		}
 	}
 
 	// Check for subscriber errors
@@ -116,11 +160,27 @@ func (runner *Build) Run(ctx context.Context, cmd *cobra.Command, args []string)
 	return err
 }
 
-func (runner *Build) buildWatch(ctx context.Context, bazelCmd []string, streams ioutils.Streams) error {
+func (runner *Build) buildWatch(ctx context.Context, bazelCmd []string, streams ioutils.Streams, ui bool) error {
 	// TODO: reduce duplication with test/run--watch
 
+	ignore, err := watch.LoadIgnore()
+	if err != nil {
+		return err
+	}
+
+	var notifier *watch.Notifier
+	notify, bazelCmd := flags.RemoveFlag(bazelCmd, watch.NotifyFlag)
+	if watch.NotifyEnabled(notify) {
+		notifier = watch.NewNotifier("aspect build --watch")
+	}
+
+	poll, bazelCmd, err := watch.RemovePollFlag(bazelCmd)
+	if err != nil {
+		return err
+	}
+
 	// Start the workspace watcher
-	w := watchman.NewWatchman(runner.bzl.WorkspaceRoot())
+	w := watch.New(runner.bzl.WorkspaceRoot(), poll)
 	if err := w.Start(); err != nil {
 		return fmt.Errorf("failed to start the watcher: %w", err)
 	}
@@ -134,41 +194,57 @@ func (runner *Build) buildWatch(ctx context.Context, bazelCmd []string, streams
 		w.Close()
 	}()
 
-	err := runner.bzl.RunCommand(streams, nil, bazelCmd...)
-	if err != nil {
-		fmt.Printf("Initial Build Failed: %v", err)
+	// Show the cycles in the dashboard rather than interleaving their output.
+	var dashboard *watch.Dashboard
+	if ui {
+		dashboard, err = watch.NewDashboard("aspect build --watch")
+		if err != nil {
+			fmt.Fprintf(runner.streams.Stderr, "%s %v, showing the output instead.\n", color.YellowString("WARNING:"), err)
+		} else {
+			defer dashboard.Close()
+			streams = dashboard.Streams()
+		}
 	}
 
-	watchState := fmt.Sprintf("aspect-build-watch-%d", os.Getpid())
-
-	for cs, err := range w.Subscribe(ctx, watchman.DeferState{DeferWithinState: watchState}) {
-		if err != nil {
-			// Break the subscribe iteration if the context is done or if the watcher is closed.
-			if errors.Is(err, context.Canceled) || errors.Is(err, net.ErrClosed) {
-				break
-			}
+	err = runner.bzl.RunCommand(streams, nil, bazelCmd...)
+	dashboard.Result(err)
+	if err != nil {
+		fmt.Fprintf(streams.Stdout, "Initial Build Failed: %v", err)
+	}
 
-			return fmt.Errorf("failed to get next event: %w", err)
-		}
+	// When turned on in the config, let the changes that arrive while bazel
+	// builds preempt the build so that the next one starts from the latest
+	// sources.
+	preempt := watch.PreemptEnabled()
+	preemptible := preempt && watch.SupportsPreemptible(runner.bzl, "build")
+	if preemptible {
+		bazelCmd = flags.AddFlagToCommand(bazelCmd, watch.PreemptibleFlag)
+	}
 
-		// Enter into the build state to discard spurious changes caused by Bazel reading the
-		// inputs which leads to their atime to change.
-		if err := w.StateEnter(watchState); err != nil {
-			return fmt.Errorf("failed to enter build state: %w", err)
-		}
+	// Read the keybindings, such as to rebuild or to quit, in the terminal.
+	keys, stopKeys := watch.ReadKeys()
+	defer stopKeys()
+
+	opts := watch.CycleOptions{
+		State:     fmt.Sprintf("aspect-build-watch-%d", os.Getpid()),
+		Ignore:    ignore,
+		Preempt:   preempt,
+		Keys:      keys,
+		Dashboard: dashboard,
+		Notifier:  notifier,
+	}
 
+	return watch.Cycles(ctx, w, opts, func(ctx context.Context, cs *watchman.ChangeSet) error {
 		logger.Debugf("watchman detected changes: %v", cs.Paths)
 
-		err := runner.bzl.RunCommand(streams, nil, bazelCmd...)
-		if err != nil {
-			fmt.Printf("Incremental Build Failed: %v", err)
-		}
-
-		// Leave the build state and fast forward the subscription clock.
-		if err := w.StateLeave(watchState); err != nil {
-			return fmt.Errorf("failed to leave build state: %w", err)
+		err := watch.RunBazel(ctx, runner.bzl, streams, preemptible, bazelCmd...)
+		if ctx.Err() == nil {
+			dashboard.Result(err)
+			notifier.Result(err)
+			if err != nil {
+				fmt.Fprintf(streams.Stdout, "Incremental Build Failed: %v", err)
+			}
 		}
-	}
-
-	return nil
+		return nil
+	})
 }
@@ -53,7 +53,7 @@ func TestBuild(t *testing.T) {
 		besBackend.
 			EXPECT().
 			Args().
-			Return([]string{"--bes_backend=grpc://127.0.0.1:12345"}).
+			Return([]string{"--bes_backend=grpc://127.0.0.1:12345"}, nil).
 			Times(1)
 		besBackend.
 			EXPECT().
@@ -84,7 +84,7 @@ func TestBuild(t *testing.T) {
 		besBackend.
 			EXPECT().
 			Args().
-			Return([]string{"--bes_backend=grpc://127.0.0.1:12345"}).
+			Return([]string{"--bes_backend=grpc://127.0.0.1:12345"}, nil).
 			Times(1)
 		besBackend.
 			EXPECT().
@@ -121,7 +121,7 @@ func TestBuild(t *testing.T) {
 		besBackend.
 			EXPECT().
 			Args().
-			Return([]string{"--bes_backend=grpc://127.0.0.1:12345"}).
+			Return([]string{"--bes_backend=grpc://127.0.0.1:12345"}, nil).
 			Times(1)
 		besBackend.
 			EXPECT().
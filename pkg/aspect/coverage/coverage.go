@@ -23,6 +23,7 @@ import (
 	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/root/flags"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils/linefilter"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
 	"github.com/spf13/cobra"
 )
@@ -46,7 +47,11 @@ func (runner *Coverage) Run(ctx context.Context, cmd *cobra.Command, args []stri
 	bazelCmd = append(bazelCmd, args...)
 
 	if bep.HasBESInterceptor(ctx) {
-		bazelCmd = flags.AddFlagToCommand(bazelCmd, bep.BESInterceptorFromContext(ctx).Args()...)
+		besArgs, err := bep.BESInterceptorFromContext(ctx).Args()
+		if err != nil {
+			return err
+		}
+		bazelCmd = flags.AddFlagToCommand(bazelCmd, besArgs...)
 	}
 
 	bzlCommandStreams := runner.streams
@@ -59,6 +64,8 @@ func (runner *Coverage) Run(ctx context.Context, cmd *cobra.Command, args []stri
 			bzlCommandStreams = runner.hstreams
 		}
 	}
+	bzlCommandStreams, flushOutput := linefilter.Streams(ctx, bzlCommandStreams)
+	defer flushOutput()
 
 	err := runner.bzl.RunCommand(bzlCommandStreams, nil, bazelCmd...)
 
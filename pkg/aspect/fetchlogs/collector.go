@@ -0,0 +1,195 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package fetchlogs
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/metadata"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+)
+
+// Key is the key of the Aspect CLI config that fetches the failure artifacts of
+// aspect build and aspect test once they complete.
+const Key = "fetch_failure_artifacts"
+
+// Enabled returns true if the failure artifacts are fetched once aspect build
+// and aspect test complete.
+func Enabled() bool {
+	return viper.GetBool(Key)
+}
+
+// lastEventTimeout bounds how long the collector waits for the build events
+// that are still being streamed once bazel has exited.
+const lastEventTimeout = 10 * time.Second
+
+// Collector collects the failure artifacts of an invocation from its build
+// events: the stdout and stderr of the actions that failed, and the outputs of
+// the test attempts that didn't pass, such as test.log and the zip of their
+// undeclared outputs. Only the artifacts that bazel left in the remote cache,
+// which it refers to with bytestream:// URIs, are collected; the others are
+// already in the output base.
+type Collector struct {
+	mu        sync.Mutex
+	manifest  Manifest
+	headers   metadata.MD
+	done      chan struct{}
+	closeDone sync.Once
+}
+
+func NewCollector() *Collector {
+	return &Collector{
+		manifest: Manifest{Insecure: make(map[string]bool)},
+		headers:  metadata.MD{},
+		done:     make(chan struct{}),
+	}
+}
+
+// Callback collects the failure artifacts of a build event. It is a
+// bep.CallbackFn.
+func (c *Collector) Callback(event *buildeventstream.BuildEvent, _ int64, _ bep.StreamInfo) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case event.GetStarted() != nil:
+		c.manifest.InvocationID = event.GetStarted().GetUuid()
+		c.manifest.Time = time.Now().UTC()
+	case event.GetOptionsParsed() != nil:
+		c.parseOptions(event.GetOptionsParsed().GetCmdLine())
+	case event.GetAction() != nil:
+		action := event.GetAction()
+		if action.GetSuccess() {
+			break
+		}
+		label := cmp.Or(action.GetLabel(), event.GetId().GetActionCompleted().GetLabel())
+		dir := labelPath(label)
+		c.add(label, path.Join(dir, action.GetType()+".stdout"), action.GetStdout())
+		c.add(label, path.Join(dir, action.GetType()+".stderr"), action.GetStderr())
+	case event.GetTestResult() != nil:
+		result := event.GetTestResult()
+		if result.GetStatus() == buildeventstream.TestStatus_PASSED {
+			break
+		}
+		id := event.GetId().GetTestResult()
+		dir := path.Join(labelPath(id.GetLabel()), fmt.Sprintf("run_%d_shard_%d_attempt_%d", id.GetRun(), id.GetShard(), id.GetAttempt()))
+		for _, file := range result.GetTestActionOutput() {
+			c.add(id.GetLabel(), path.Join(dir, file.GetName()), file)
+		}
+	}
+
+	if event.GetLastMessage() {
+		c.closeDone.Do(func() { close(c.done) })
+	}
+	return nil
+}
+
+// parseOptions finds how to connect to the remote cache in the options bazel
+// ran with, including those of the bazelrc files.
+func (c *Collector) parseOptions(options []string) {
+	for _, option := range options {
+		name, value, ok := strings.Cut(strings.TrimPrefix(option, "--"), "=")
+		if !ok {
+			continue
+		}
+		switch name {
+		case "remote_cache", "remote_executor":
+			// Bazel connects without TLS to grpc:// endpoints, and with TLS to
+			// grpcs:// endpoints and to those without a scheme.
+			if u, err := url.Parse(value); err == nil && u.Host != "" {
+				c.manifest.Insecure[u.Host] = u.Scheme == "grpc"
+			}
+		case "remote_header", "remote_cache_header":
+			if k, v, ok := strings.Cut(value, "="); ok {
+				c.headers.Append(k, v)
+			}
+		}
+	}
+}
+
+func (c *Collector) add(label, name string, file *buildeventstream.File) {
+	if !strings.HasPrefix(file.GetUri(), "bytestream://") {
+		return
+	}
+	c.manifest.Artifacts = append(c.manifest.Artifacts, Artifact{
+		Label: label,
+		Path:  name,
+		URI:   file.GetUri(),
+	})
+}
+
+// Finish returns the manifest of the failure artifacts once the last build
+// event is received, or of those received so far if it doesn't arrive in time.
+func (c *Collector) Finish() *Manifest {
+	select {
+	case <-c.done:
+	case <-time.After(lastEventTimeout):
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m := c.manifest
+	return &m
+}
+
+// Fetch saves the manifest of the failure artifacts of the invocation and
+// fetches them next to it, once the last build event is received. It writes
+// where they were fetched to w, and does nothing if there are none.
+func (c *Collector) Fetch(ctx context.Context, w io.Writer) error {
+	m := c.Finish()
+	if len(m.Artifacts) == 0 {
+		return nil
+	}
+	dir, err := SaveManifest(m)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	headers := c.headers.Copy()
+	c.mu.Unlock()
+	fetched, err := Fetch(ctx, m, dir, headers)
+	if len(fetched) > 0 {
+		fmt.Fprintf(w, "Fetched %d failure artifacts from the remote cache to %s\n", len(fetched), dir)
+	}
+	return err
+}
+
+// labelPath returns the path of the directory of the artifacts of a target,
+// e.g. foo/bar/baz for //foo/bar:baz and external/repo/foo/baz for
+// @repo//foo:baz.
+func labelPath(label string) string {
+	repo, rest, ok := strings.Cut(strings.TrimPrefix(label, "@"), "//")
+	if !ok {
+		return "unknown"
+	}
+	pkg, name, _ := strings.Cut(rest, ":")
+	p := path.Join(pkg, name)
+	if repo != "" {
+		p = path.Join("external", repo, p)
+	}
+	return p
+}
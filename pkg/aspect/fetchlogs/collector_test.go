@@ -0,0 +1,94 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package fetchlogs
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/metadata"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+)
+
+func uriFile(name, uri string) *buildeventstream.File {
+	return &buildeventstream.File{Name: name, File: &buildeventstream.File_Uri{Uri: uri}}
+}
+
+func TestCollector(t *testing.T) {
+	t.Run("collects the artifacts of the failures that are in the remote cache", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		collector := NewCollector()
+		for _, event := range []*buildeventstream.BuildEvent{
+			{Payload: &buildeventstream.BuildEvent_Started{Started: &buildeventstream.BuildStarted{Uuid: "invocation"}}},
+			{Payload: &buildeventstream.BuildEvent_OptionsParsed{OptionsParsed: &buildeventstream.OptionsParsed{CmdLine: []string{
+				"--remote_cache=grpc://cache:9092",
+				"--remote_executor=grpcs://remote:443",
+				"--remote_header=x-token=secret",
+				"--keep_going",
+			}}}},
+			{Payload: &buildeventstream.BuildEvent_Action{Action: &buildeventstream.ActionExecuted{
+				Success: false,
+				Type:    "GoCompile",
+				Label:   "//foo:bar",
+				Stdout:  uriFile("stdout", "bytestream://cache:9092/blobs/1/10"),
+				Stderr:  uriFile("stderr", "file:///tmp/stderr"),
+			}}},
+			{Payload: &buildeventstream.BuildEvent_Action{Action: &buildeventstream.ActionExecuted{
+				Success: true,
+				Type:    "GoLink",
+				Label:   "//foo:bar",
+				Stderr:  uriFile("stderr", "bytestream://cache:9092/blobs/2/10"),
+			}}},
+			{
+				Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_TestResult{
+					TestResult: &buildeventstream.BuildEventId_TestResultId{Label: "@repo//pkg:test", Run: 1, Shard: 2, Attempt: 1},
+				}},
+				Payload: &buildeventstream.BuildEvent_TestResult{TestResult: &buildeventstream.TestResult{
+					Status: buildeventstream.TestStatus_FAILED,
+					TestActionOutput: []*buildeventstream.File{
+						uriFile("test.log", "bytestream://cache:9092/blobs/3/10"),
+						uriFile("test.outputs__outputs.zip", "bytestream://cache:9092/blobs/4/10"),
+					},
+				}},
+			},
+			{
+				Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_TestResult{
+					TestResult: &buildeventstream.BuildEventId_TestResultId{Label: "//pkg:passing", Run: 1, Shard: 1, Attempt: 1},
+				}},
+				Payload: &buildeventstream.BuildEvent_TestResult{TestResult: &buildeventstream.TestResult{
+					Status:           buildeventstream.TestStatus_PASSED,
+					TestActionOutput: []*buildeventstream.File{uriFile("test.log", "bytestream://cache:9092/blobs/5/10")},
+				}},
+			},
+			{LastMessage: true},
+		} {
+			g.Expect(collector.Callback(event, 0, bep.StreamInfo{})).To(Succeed())
+		}
+
+		m := collector.Finish()
+		g.Expect(m.InvocationID).To(Equal("invocation"))
+		g.Expect(m.Insecure).To(Equal(map[string]bool{"cache:9092": true, "remote:443": false}))
+		g.Expect(m.Artifacts).To(Equal([]Artifact{
+			{Label: "//foo:bar", Path: "foo/bar/GoCompile.stdout", URI: "bytestream://cache:9092/blobs/1/10"},
+			{Label: "@repo//pkg:test", Path: "external/repo/pkg/test/run_1_shard_2_attempt_1/test.log", URI: "bytestream://cache:9092/blobs/3/10"},
+			{Label: "@repo//pkg:test", Path: "external/repo/pkg/test/run_1_shard_2_attempt_1/test.outputs__outputs.zip", URI: "bytestream://cache:9092/blobs/4/10"},
+		}))
+		g.Expect(collector.headers).To(Equal(metadata.Pairs("x-token", "secret")))
+	})
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package fetchlogs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/bytestream"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// Fetch downloads the artifacts of the manifest from the remote cache to dir,
+// sending the headers with each request, and returns the paths it fetched them
+// to. It fetches the other artifacts when one fails, and returns the errors of
+// all those that failed.
+func Fetch(ctx context.Context, m *Manifest, dir string, headers metadata.MD) ([]string, error) {
+	if len(headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, headers)
+	}
+
+	conns := make(map[string]*grpc.ClientConn)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	var fetched []string
+	var errs []error
+	for _, artifact := range m.Artifacts {
+		u, err := url.Parse(artifact.URI)
+		if err != nil || u.Scheme != "bytestream" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("unsupported URI %q of %s", artifact.URI, artifact.Path))
+			continue
+		}
+		dest := filepath.Join(dir, filepath.FromSlash(artifact.Path))
+		if !strings.HasPrefix(dest, filepath.Clean(dir)+string(filepath.Separator)) {
+			errs = append(errs, fmt.Errorf("invalid path %q of %s", artifact.Path, artifact.URI))
+			continue
+		}
+		conn, ok := conns[u.Host]
+		if !ok {
+			conn, err = dial(u.Host, m.Insecure[u.Host])
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			conns[u.Host] = conn
+		}
+		if err := fetch(ctx, bytestream.NewByteStreamClient(conn), strings.TrimPrefix(u.Path, "/"), dest); err != nil {
+			errs = append(errs, fmt.Errorf("failed to fetch %s of %s: %w", artifact.Path, artifact.Label, err))
+			continue
+		}
+		fetched = append(fetched, dest)
+	}
+	return fetched, errors.Join(errs...)
+}
+
+// dial connects to the remote cache at host, with TLS unless plaintext is set.
+func dial(host string, plaintext bool) (*grpc.ClientConn, error) {
+	creds := credentials.NewClientTLSFromCert(nil, "")
+	if plaintext {
+		creds = insecure.NewCredentials()
+	}
+	conn, err := grpc.NewClient(host, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the remote cache at %s: %w", host, err)
+	}
+	return conn, nil
+}
+
+// fetch reads the blob with the given resource name to dest. The blob is
+// written to a temporary file first, so that dest is only created once it is
+// complete.
+func fetch(ctx context.Context, client bytestream.ByteStreamClient, resourceName, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), filepath.Base(dest)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	// Bazel doesn't upload empty blobs to the remote cache.
+	if !strings.HasSuffix(resourceName, "/0") {
+		if err := read(ctx, client, resourceName, tmp); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
+
+func read(ctx context.Context, client bytestream.ByteStreamClient, resourceName string, w io.Writer) error {
+	stream, err := client.Read(ctx, &bytestream.ReadRequest{ResourceName: resourceName})
+	if err != nil {
+		return err
+	}
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(resp.GetData()); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package fetchlogs
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"google.golang.org/genproto/googleapis/bytestream"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeByteStream serves blobs by resource name, in chunks of a few bytes.
+type fakeByteStream struct {
+	bytestream.UnimplementedByteStreamServer
+	blobs   map[string]string
+	mu      sync.Mutex
+	headers []string
+}
+
+func (s *fakeByteStream) Read(req *bytestream.ReadRequest, stream bytestream.ByteStream_ReadServer) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	s.mu.Lock()
+	s.headers = append(s.headers, md.Get("x-token")...)
+	s.mu.Unlock()
+	blob, ok := s.blobs[req.GetResourceName()]
+	if !ok {
+		return status.Errorf(codes.NotFound, "%s not found", req.GetResourceName())
+	}
+	for len(blob) > 0 {
+		n := min(len(blob), 4)
+		if err := stream.Send(&bytestream.ReadResponse{Data: []byte(blob[:n])}); err != nil {
+			return err
+		}
+		blob = blob[n:]
+	}
+	return nil
+}
+
+func serveByteStream(t *testing.T, s *fakeByteStream) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	bytestream.RegisterByteStreamServer(srv, s)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func TestFetch(t *testing.T) {
+	t.Run("downloads the artifacts from the remote cache", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		server := &fakeByteStream{blobs: map[string]string{
+			"instance/blobs/abc/17": "FAIL: TestSomething",
+		}}
+		host := serveByteStream(t, server)
+		dir := t.TempDir()
+		m := &Manifest{
+			Insecure: map[string]bool{host: true},
+			Artifacts: []Artifact{
+				{Label: "//pkg:test", Path: "pkg/test/test.log", URI: "bytestream://" + host + "/instance/blobs/abc/17"},
+				{Label: "//pkg:test", Path: "pkg/test/test.xml", URI: "bytestream://" + host + "/instance/blobs/def/0"},
+				{Label: "//pkg:other", Path: "pkg/other/test.log", URI: "bytestream://" + host + "/instance/blobs/missing/10"},
+				{Label: "//pkg:escape", Path: "../escape", URI: "bytestream://" + host + "/instance/blobs/abc/17"},
+			},
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		fetched, err := Fetch(ctx, m, dir, metadata.Pairs("x-token", "secret"))
+
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(strings.Split(err.Error(), "\n")).To(HaveLen(2))
+		g.Expect(err.Error()).To(ContainSubstring("pkg/other/test.log of //pkg:other"))
+		g.Expect(err.Error()).To(ContainSubstring(`invalid path "../escape"`))
+		g.Expect(fetched).To(Equal([]string{
+			filepath.Join(dir, "pkg/test/test.log"),
+			filepath.Join(dir, "pkg/test/test.xml"),
+		}))
+		g.Expect(os.ReadFile(filepath.Join(dir, "pkg/test/test.log"))).To(Equal([]byte("FAIL: TestSomething")))
+		g.Expect(os.ReadFile(filepath.Join(dir, "pkg/test/test.xml"))).To(BeEmpty())
+		g.Expect(filepath.Join(dir, "pkg/other/test.log")).NotTo(BeAnExistingFile())
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		g.Expect(server.headers).To(Equal([]string{"secret", "secret"}))
+	})
+}
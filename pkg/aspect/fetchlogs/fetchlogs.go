@@ -0,0 +1,79 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package fetchlogs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+)
+
+type FetchLogs struct {
+	ioutils.Streams
+}
+
+func New(streams ioutils.Streams) *FetchLogs {
+	return &FetchLogs{
+		Streams: streams,
+	}
+}
+
+// Run fetches the failure artifacts recorded for an invocation, or for the
+// last invocation if none is given, and prints their paths.
+func (runner *FetchLogs) Run(ctx context.Context, cmd *cobra.Command, args []string) error {
+	outputDir, err := cmd.Flags().GetString("output-dir")
+	if err != nil {
+		return err
+	}
+	remoteHeaders, err := cmd.Flags().GetStringArray("remote-header")
+	if err != nil {
+		return err
+	}
+	headers := metadata.MD{}
+	for _, header := range remoteHeaders {
+		k, v, ok := strings.Cut(header, "=")
+		if !ok {
+			return fmt.Errorf("invalid --remote-header %q, expecting name=value", header)
+		}
+		headers.Append(k, v)
+	}
+
+	var invocationID string
+	if len(args) > 0 {
+		invocationID = args[0]
+	}
+	m, dir, err := LoadManifest(invocationID)
+	if err != nil {
+		return err
+	}
+	if outputDir != "" {
+		dir = outputDir
+	}
+
+	fetched, err := Fetch(ctx, m, dir, headers)
+	for _, path := range fetched {
+		fmt.Fprintln(runner.Stdout, path)
+	}
+	if err != nil {
+		return fmt.Errorf("fetched %d of the %d failure artifacts of invocation %s: %w", len(fetched), len(m.Artifacts), m.InvocationID, err)
+	}
+	return nil
+}
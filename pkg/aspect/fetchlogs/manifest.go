@@ -0,0 +1,172 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package fetchlogs
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils/cache"
+)
+
+// maxInvocations is the number of invocations whose failure artifacts are
+// kept.
+const maxInvocations = 10
+
+const manifestName = "manifest.json"
+
+// Artifact is a failure artifact in the remote cache.
+type Artifact struct {
+	Label string `json:"label"`
+	// Path is where the artifact is fetched to, relative to the directory
+	// of the invocation.
+	Path string `json:"path"`
+	URI  string `json:"uri"`
+}
+
+// Manifest lists the failure artifacts of an invocation, so that they can be
+// fetched again later.
+type Manifest struct {
+	InvocationID string    `json:"invocation_id"`
+	Time         time.Time `json:"time"`
+	// Insecure tells the remote cache endpoints that bazel connected to
+	// without TLS.
+	Insecure  map[string]bool `json:"insecure,omitempty"`
+	Artifacts []Artifact      `json:"artifacts"`
+}
+
+// ArtifactsDir returns the directory the failure artifacts of the invocations
+// are kept in, one directory per invocation.
+func ArtifactsDir() (string, error) {
+	aspectCacheDir, err := cache.AspectCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(aspectCacheDir, "failure_artifacts"), nil
+}
+
+// SaveManifest writes the manifest to the directory of its invocation, which
+// it returns, and removes the directories of the oldest invocations.
+func SaveManifest(m *Manifest) (string, error) {
+	root, err := ArtifactsDir()
+	if err != nil {
+		return "", err
+	}
+	if m.InvocationID == "" {
+		return "", fmt.Errorf("the invocation ID of the failure artifacts is unknown")
+	}
+	dir := filepath.Join(root, m.InvocationID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create the failure artifacts directory: %w", err)
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, manifestName), b, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write the failure artifacts manifest: %w", err)
+	}
+	return dir, pruneInvocations(root, maxInvocations)
+}
+
+// LoadManifest returns the manifest of an invocation and the directory it is
+// in, or those of the last invocation if invocationID is empty.
+func LoadManifest(invocationID string) (*Manifest, string, error) {
+	root, err := ArtifactsDir()
+	if err != nil {
+		return nil, "", err
+	}
+	if invocationID == "" {
+		invocations, err := listInvocations(root)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(invocations) == 0 {
+			return nil, "", fmt.Errorf("no failure artifacts were recorded")
+		}
+		invocationID = invocations[0]
+	}
+	if filepath.Base(invocationID) != invocationID {
+		return nil, "", fmt.Errorf("invalid invocation ID %q", invocationID)
+	}
+
+	dir := filepath.Join(root, invocationID)
+	b, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, "", fmt.Errorf("no failure artifacts were recorded for invocation %s", invocationID)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read the failure artifacts manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, "", fmt.Errorf("failed to read the failure artifacts manifest of invocation %s: %w", invocationID, err)
+	}
+	return &m, dir, nil
+}
+
+// listInvocations returns the invocations with a manifest in root, the most
+// recent first.
+func listInvocations(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the failure artifacts: %w", err)
+	}
+	type invocation struct {
+		id      string
+		modTime time.Time
+	}
+	var invocations []invocation
+	for _, entry := range entries {
+		info, err := os.Stat(filepath.Join(root, entry.Name(), manifestName))
+		if err != nil {
+			continue
+		}
+		invocations = append(invocations, invocation{entry.Name(), info.ModTime()})
+	}
+	slices.SortFunc(invocations, func(a, b invocation) int {
+		return cmp.Or(b.modTime.Compare(a.modTime), cmp.Compare(a.id, b.id))
+	})
+	ids := make([]string, len(invocations))
+	for i, inv := range invocations {
+		ids[i] = inv.id
+	}
+	return ids, nil
+}
+
+// pruneInvocations removes the directories of the invocations in root but
+// the most recent keep.
+func pruneInvocations(root string, keep int) error {
+	invocations, err := listInvocations(root)
+	if err != nil {
+		return err
+	}
+	for _, id := range invocations[min(keep, len(invocations)):] {
+		if err := os.RemoveAll(filepath.Join(root, id)); err != nil {
+			return fmt.Errorf("failed to remove the failure artifacts of invocation %s: %w", id, err)
+		}
+	}
+	return nil
+}
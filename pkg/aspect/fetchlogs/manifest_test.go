@@ -0,0 +1,80 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package fetchlogs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestManifest(t *testing.T) {
+	t.Run("loads the manifest of an invocation or of the last one", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+		first := &Manifest{InvocationID: "first", Time: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC), Artifacts: []Artifact{{Label: "//a:test", Path: "a/test/test.log", URI: "bytestream://cache/blobs/1/1"}}}
+		second := &Manifest{InvocationID: "second", Insecure: map[string]bool{"cache": true}, Time: first.Time.Add(time.Minute)}
+		firstDir, err := SaveManifest(first)
+		g.Expect(err).NotTo(HaveOccurred())
+		secondDir, err := SaveManifest(second)
+		g.Expect(err).NotTo(HaveOccurred())
+		// Make the first invocation the oldest regardless of the precision
+		// of the modification times.
+		old := time.Now().Add(-time.Hour)
+		g.Expect(os.Chtimes(filepath.Join(firstDir, manifestName), old, old)).To(Succeed())
+
+		m, dir, err := LoadManifest("first")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(m).To(Equal(first))
+		g.Expect(dir).To(Equal(firstDir))
+
+		m, dir, err = LoadManifest("")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(m).To(Equal(second))
+		g.Expect(dir).To(Equal(secondDir))
+
+		_, _, err = LoadManifest("unknown")
+		g.Expect(err).To(MatchError("no failure artifacts were recorded for invocation unknown"))
+		_, _, err = LoadManifest("../first")
+		g.Expect(err).To(HaveOccurred())
+	})
+
+	t.Run("keeps the last invocations", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+		for i := range maxInvocations + 2 {
+			dir, err := SaveManifest(&Manifest{InvocationID: fmt.Sprintf("invocation-%02d", i)})
+			g.Expect(err).NotTo(HaveOccurred())
+			modTime := time.Now().Add(time.Duration(i-maxInvocations-2) * time.Minute)
+			g.Expect(os.Chtimes(filepath.Join(dir, manifestName), modTime, modTime)).To(Succeed())
+		}
+		root, err := ArtifactsDir()
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(pruneInvocations(root, maxInvocations)).To(Succeed())
+
+		invocations, err := listInvocations(root)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(invocations).To(HaveLen(maxInvocations))
+		g.Expect(invocations[0]).To(Equal(fmt.Sprintf("invocation-%02d", maxInvocations+1)))
+		g.Expect(invocations).NotTo(ContainElements("invocation-00", "invocation-01"))
+	})
+}
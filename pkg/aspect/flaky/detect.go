@@ -0,0 +1,119 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package flaky
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+// Test is a test whose outcome varied on identical inputs.
+type Test struct {
+	Label         string `json:"label"`
+	Configuration string `json:"configuration"`
+	// Passed and Failed count the attempts on the inputs the outcome varied
+	// on, and Inputs the number of distinct inputs it varied on.
+	Passed      int       `json:"passed"`
+	Failed      int       `json:"failed"`
+	Inputs      int       `json:"inputs"`
+	LastFailure time.Time `json:"last_failure"`
+}
+
+// Detect returns the tests that both passed and failed on identical inputs,
+// that is in the same configuration and with the same digest of the outputs of
+// their target, with the most failures first. Records with an unknown digest
+// are skipped, since their inputs can't be compared.
+func Detect(records []Record) []Test {
+	type inputs struct {
+		target
+		digest string
+	}
+	type outcomes struct {
+		passed, failed int
+		lastFailure    time.Time
+	}
+	groups := make(map[inputs]*outcomes)
+	for _, r := range records {
+		if r.Digest == "" {
+			continue
+		}
+		key := inputs{target{r.Label, r.Configuration}, r.Digest}
+		o := groups[key]
+		if o == nil {
+			o = &outcomes{}
+			groups[key] = o
+		}
+		switch r.Status {
+		case "PASSED":
+			o.passed++
+		case "FAILED", "TIMEOUT":
+			o.failed++
+			if r.Time.After(o.lastFailure) {
+				o.lastFailure = r.Time
+			}
+		}
+	}
+
+	tests := make(map[target]*Test)
+	for key, o := range groups {
+		if o.passed == 0 || o.failed == 0 {
+			continue
+		}
+		t := tests[key.target]
+		if t == nil {
+			t = &Test{Label: key.label, Configuration: key.configuration}
+			tests[key.target] = t
+		}
+		t.Passed += o.passed
+		t.Failed += o.failed
+		t.Inputs++
+		if o.lastFailure.After(t.LastFailure) {
+			t.LastFailure = o.lastFailure
+		}
+	}
+
+	result := make([]Test, 0, len(tests))
+	for _, t := range tests {
+		result = append(result, *t)
+	}
+	slices.SortFunc(result, func(a, b Test) int {
+		return cmp.Or(
+			cmp.Compare(b.Failed, a.Failed),
+			cmp.Compare(a.Label, b.Label),
+			cmp.Compare(a.Configuration, b.Configuration),
+		)
+	})
+	return result
+}
+
+// Print writes the flaky tests to w.
+func Print(w io.Writer, tests []Test) error {
+	if len(tests) == 0 {
+		_, err := fmt.Fprintln(w, "No flaky tests were found")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Flaky tests:")
+	for _, t := range tests {
+		fmt.Fprintf(tw, "  %s\t%d passed, %d failed\tlast failed %s\n",
+			t.Label, t.Passed, t.Failed, t.LastFailure.Local().Format(time.DateTime))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package flaky
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestDetect(t *testing.T) {
+	t1 := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+
+	t.Run("reports the tests that passed and failed on identical inputs", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		tests := Detect([]Record{
+			// Flaky on both of its inputs.
+			{Label: "//flaky:test", Configuration: "k8", Digest: "d1", Status: "PASSED", Time: t1},
+			{Label: "//flaky:test", Configuration: "k8", Digest: "d1", Status: "FAILED", Time: t1},
+			{Label: "//flaky:test", Configuration: "k8", Digest: "d2", Status: "TIMEOUT", Time: t2},
+			{Label: "//flaky:test", Configuration: "k8", Digest: "d2", Status: "PASSED", Time: t2},
+			{Label: "//flaky:test", Configuration: "k8", Digest: "d2", Status: "PASSED", Time: t2},
+			// Failed only once its inputs changed.
+			{Label: "//broken:test", Configuration: "k8", Digest: "d1", Status: "PASSED", Time: t1},
+			{Label: "//broken:test", Configuration: "k8", Digest: "d2", Status: "FAILED", Time: t2},
+			// Failed only in another configuration.
+			{Label: "//config:test", Configuration: "k8", Digest: "d1", Status: "PASSED", Time: t1},
+			{Label: "//config:test", Configuration: "arm64", Digest: "d1", Status: "FAILED", Time: t1},
+			// The inputs are unknown.
+			{Label: "//unknown:test", Configuration: "k8", Status: "PASSED", Time: t1},
+			{Label: "//unknown:test", Configuration: "k8", Status: "FAILED", Time: t1},
+			{Label: "//other:test", Configuration: "k8", Digest: "d3", Status: "FAILED", Time: t1},
+			{Label: "//other:test", Configuration: "k8", Digest: "d3", Status: "PASSED", Time: t1},
+		})
+
+		g.Expect(tests).To(Equal([]Test{
+			{Label: "//flaky:test", Configuration: "k8", Passed: 3, Failed: 2, Inputs: 2, LastFailure: t2},
+			{Label: "//other:test", Configuration: "k8", Passed: 1, Failed: 1, Inputs: 1, LastFailure: t1},
+		}))
+	})
+
+	t.Run("prints the flaky tests", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var out bytes.Buffer
+		g.Expect(Print(&out, nil)).To(Succeed())
+		g.Expect(out.String()).To(Equal("No flaky tests were found\n"))
+
+		out.Reset()
+		g.Expect(Print(&out, []Test{
+			{Label: "//flaky:test", Passed: 3, Failed: 2, LastFailure: t2},
+			{Label: "//a:test", Passed: 1, Failed: 1, LastFailure: t1},
+		})).To(Succeed())
+		g.Expect(out.String()).To(Equal("Flaky tests:\n" +
+			"  //flaky:test  3 passed, 2 failed  last failed " + t2.Local().Format(time.DateTime) + "\n" +
+			"  //a:test      1 passed, 1 failed  last failed " + t1.Local().Format(time.DateTime) + "\n"))
+	})
+}
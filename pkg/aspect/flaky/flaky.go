@@ -0,0 +1,91 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package flaky
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+)
+
+// DetectFlag is handled by the test command rather than being passed to bazel.
+const DetectFlag = "--detect-flaky"
+
+type Flaky struct {
+	ioutils.Streams
+	bzl bazel.Bazel
+}
+
+func New(streams ioutils.Streams, bzl bazel.Bazel) *Flaky {
+	return &Flaky{
+		Streams: streams,
+		bzl:     bzl,
+	}
+}
+
+// Report prints the tests of the workspace whose outcome varied on identical
+// inputs in the recorded test history.
+func (runner *Flaky) Report(ctx context.Context, cmd *cobra.Command, args []string) error {
+	asJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return err
+	}
+	since, err := cmd.Flags().GetDuration("since")
+	if err != nil {
+		return err
+	}
+
+	workspaceRoot := runner.bzl.WorkspaceRoot()
+	if workspaceRoot == "" {
+		return fmt.Errorf("the flaky tests can only be reported within a bazel workspace")
+	}
+	path, err := HistoryPath(workspaceRoot)
+	if err != nil {
+		return err
+	}
+	records, err := LoadHistory(path)
+	if err != nil {
+		return err
+	}
+	if since > 0 {
+		cutoff := time.Now().Add(-since)
+		var recent []Record
+		for _, r := range records {
+			if !r.Time.Before(cutoff) {
+				recent = append(recent, r)
+			}
+		}
+		records = recent
+	}
+
+	tests := Detect(records)
+	if asJSON {
+		enc := json.NewEncoder(runner.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(tests)
+	}
+	if len(records) == 0 {
+		fmt.Fprintf(runner.Stdout, "No test results are recorded; run aspect test with %s to record them\n", DetectFlag)
+		return nil
+	}
+	return Print(runner.Stdout, tests)
+}
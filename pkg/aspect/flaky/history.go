@@ -0,0 +1,180 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package flaky
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils/cache"
+)
+
+// HistoryKey is the key of the Aspect CLI config that records the results of
+// the tests of every aspect test in the test history, rather than only those
+// of aspect test --detect-flaky.
+const HistoryKey = "test_history"
+
+// Recording returns true if the results of every aspect test are recorded.
+func Recording() bool {
+	return viper.GetBool(HistoryKey)
+}
+
+// maxHistorySize is the size of the history above which it is pruned to the
+// records of the last historyRetention, and to at most maxRecords of them.
+const (
+	maxHistorySize   = 16 * 1024 * 1024
+	historyRetention = 30 * 24 * time.Hour
+	maxRecords       = 50000
+)
+
+// Record is the result of an attempt of a test, and what it ran on.
+type Record struct {
+	Label string `json:"label"`
+	// Configuration is the ID of the configuration the test was built in,
+	// and Digest the digest of the outputs of the test target, which are
+	// identical when the test ran on identical inputs.
+	Configuration string    `json:"configuration"`
+	Digest        string    `json:"digest"`
+	Status        string    `json:"status"`
+	Run           int32     `json:"run,omitempty"`
+	Shard         int32     `json:"shard,omitempty"`
+	Attempt       int32     `json:"attempt,omitempty"`
+	InvocationID  string    `json:"invocation_id"`
+	Time          time.Time `json:"time"`
+}
+
+// HistoryPath returns the path of the test history of a workspace, which is
+// kept in the Aspect cache directory.
+func HistoryPath(workspaceRoot string) (string, error) {
+	aspectCacheDir, err := cache.AspectCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(workspaceRoot))
+	return filepath.Join(aspectCacheDir, "test_history", hex.EncodeToString(sum[:8])+".jsonl"), nil
+}
+
+// AppendHistory appends records to the test history at path, and prunes it
+// once it grows above maxHistorySize.
+func AppendHistory(path string, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create the test history directory: %w", err)
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open the test history: %w", err)
+	}
+	// The records are appended in one write, so that those of concurrent
+	// invocations don't interleave.
+	_, err = f.Write(b.Bytes())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write the test history: %w", err)
+	}
+
+	if info, err := os.Stat(path); err == nil && info.Size() > maxHistorySize {
+		return pruneHistory(path, time.Now().Add(-historyRetention))
+	}
+	return nil
+}
+
+// LoadHistory returns the records of the test history at path, which is empty
+// when no tests were recorded yet. Lines that can't be read, e.g. of an
+// interrupted write, are skipped.
+func LoadHistory(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the test history: %w", err)
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the test history: %w", err)
+	}
+	return records, nil
+}
+
+// pruneHistory rewrites the test history at path with its records since the
+// given time, and at most maxRecords of them.
+func pruneHistory(path string, since time.Time) error {
+	records, err := LoadHistory(path)
+	if err != nil {
+		return err
+	}
+	var kept []Record
+	for _, r := range records {
+		if !r.Time.Before(since) {
+			kept = append(kept, r)
+		}
+	}
+	kept = kept[max(0, len(kept)-maxRecords):]
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to prune the test history: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	enc := json.NewEncoder(tmp)
+	for _, r := range kept {
+		if err := enc.Encode(r); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to prune the test history: %w", err)
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to prune the test history: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to prune the test history: %w", err)
+	}
+	return nil
+}
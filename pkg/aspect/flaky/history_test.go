@@ -0,0 +1,85 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package flaky
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestHistory(t *testing.T) {
+	t.Run("loads the records appended by several invocations", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		path := filepath.Join(t.TempDir(), "test_history", "history.jsonl")
+		now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+		first := []Record{{Label: "//a:test", Configuration: "k8", Digest: "d1", Status: "PASSED", InvocationID: "1", Time: now}}
+		second := []Record{
+			{Label: "//a:test", Configuration: "k8", Digest: "d1", Status: "FAILED", Attempt: 1, InvocationID: "2", Time: now},
+			{Label: "//b:test", Configuration: "k8", Digest: "d2", Status: "PASSED", Shard: 1, InvocationID: "2", Time: now},
+		}
+		g.Expect(AppendHistory(path, first)).To(Succeed())
+		g.Expect(AppendHistory(path, second)).To(Succeed())
+
+		records, err := LoadHistory(path)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(records).To(Equal(append(first, second...)))
+	})
+
+	t.Run("is empty when nothing was recorded", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		records, err := LoadHistory(filepath.Join(t.TempDir(), "history.jsonl"))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(records).To(BeEmpty())
+	})
+
+	t.Run("skips the lines that can't be read", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		path := filepath.Join(t.TempDir(), "history.jsonl")
+		g.Expect(os.WriteFile(path, []byte("{\"label\":\"//a:test\"}\n{\"label\":\n"), 0o644)).To(Succeed())
+
+		records, err := LoadHistory(path)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(records).To(Equal([]Record{{Label: "//a:test"}}))
+	})
+
+	t.Run("prunes the records older than the retention", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		path := filepath.Join(t.TempDir(), "history.jsonl")
+		now := time.Now().UTC().Truncate(time.Second)
+		recent := Record{Label: "//a:test", Status: "PASSED", Time: now}
+		g.Expect(AppendHistory(path, []Record{
+			{Label: "//a:test", Status: "FAILED", Time: now.Add(-2 * historyRetention)},
+			recent,
+		})).To(Succeed())
+
+		g.Expect(pruneHistory(path, now.Add(-historyRetention))).To(Succeed())
+
+		records, err := LoadHistory(path)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(records).To(Equal([]Record{recent}))
+		entries, err := os.ReadDir(filepath.Dir(path))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(entries).To(HaveLen(1))
+	})
+}
@@ -0,0 +1,207 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package flaky
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/plugin"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+)
+
+// lastEventTimeout bounds how long the recorder waits for the build events that
+// are still being streamed once bazel has exited.
+const lastEventTimeout = 10 * time.Second
+
+// target is a test target built in a configuration.
+type target struct {
+	label         string
+	configuration string
+}
+
+// Recorder records the results of the tests of an invocation from its
+// TestResult build events, along with the digest of the outputs of their
+// targets, so that results on identical inputs can be compared.
+type Recorder struct {
+	mu           sync.Mutex
+	namedSets    *plugin.NamedSets
+	invocationID string
+	outputs      map[target][]*buildeventstream.File
+	labels       map[string]bool
+	records      []Record
+	done         chan struct{}
+	closeDone    sync.Once
+	now          func() time.Time
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{
+		namedSets: plugin.NewNamedSets(),
+		outputs:   make(map[target][]*buildeventstream.File),
+		labels:    make(map[string]bool),
+		done:      make(chan struct{}),
+		now:       time.Now,
+	}
+}
+
+// Callback records a build event. It is a bep.CallbackFn.
+func (r *Recorder) Callback(event *buildeventstream.BuildEvent, _ int64, _ bep.StreamInfo) error {
+	r.namedSets.Add(event)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case event.GetStarted() != nil:
+		r.invocationID = event.GetStarted().GetUuid()
+	case event.GetCompleted() != nil:
+		id := event.GetId().GetTargetCompleted()
+		completed := event.GetCompleted()
+		if id.GetAspect() != "" || !completed.GetSuccess() {
+			break
+		}
+		files, err := r.namedSets.OutputFiles(completed, "default")
+		if err != nil {
+			return fmt.Errorf("failed to resolve the outputs of %s: %w", id.GetLabel(), err)
+		}
+		r.outputs[target{id.GetLabel(), id.GetConfiguration().GetId()}] = files
+	case event.GetTestResult() != nil:
+		id := event.GetId().GetTestResult()
+		result := event.GetTestResult()
+		r.labels[id.GetLabel()] = true
+		// A cached result is the result of an earlier run, which was recorded
+		// then if it was recorded at all.
+		if result.GetCachedLocally() || result.GetExecutionInfo().GetCachedRemotely() {
+			break
+		}
+		t := r.now()
+		if start := result.GetTestAttemptStart(); start != nil {
+			t = start.AsTime()
+		}
+		r.records = append(r.records, Record{
+			Label:         id.GetLabel(),
+			Configuration: id.GetConfiguration().GetId(),
+			Status:        result.GetStatus().String(),
+			Run:           id.GetRun(),
+			Shard:         id.GetShard(),
+			Attempt:       id.GetAttempt(),
+			InvocationID:  r.invocationID,
+			Time:          t.UTC(),
+		})
+	}
+
+	if event.GetLastMessage() {
+		r.closeDone.Do(func() { close(r.done) })
+	}
+	return nil
+}
+
+// Finish returns the records of the tests once the last build event is
+// received, or of the tests received so far if it doesn't arrive in time. It
+// must be called once bazel has exited, since the digests of the targets are
+// computed from their outputs.
+func (r *Recorder) Finish() []Record {
+	select {
+	case <-r.done:
+	case <-time.After(lastEventTimeout):
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	digests := make(map[target]string)
+	records := slices.Clone(r.records)
+	for i, record := range records {
+		t := target{record.Label, record.Configuration}
+		digest, ok := digests[t]
+		if !ok {
+			digest = outputsDigest(r.outputs[t])
+			digests[t] = digest
+		}
+		records[i].Digest = digest
+	}
+	return records
+}
+
+// Labels returns the labels of the tests of the invocation, including those
+// whose results were cached.
+func (r *Recorder) Labels() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	labels := slices.Collect(maps.Keys(r.labels))
+	slices.Sort(labels)
+	return labels
+}
+
+// outputsDigest returns a digest of the output files of a target, or an empty
+// string if the digest of one of them is unknown. The digest of a file is the
+// one bazel reports, or else computed from the file in the output base.
+func outputsDigest(files []*buildeventstream.File) string {
+	if len(files) == 0 {
+		return ""
+	}
+	files = slices.Clone(files)
+	slices.SortFunc(files, func(a, b *buildeventstream.File) int {
+		return cmp.Compare(filePath(a), filePath(b))
+	})
+	h := sha256.New()
+	for _, file := range files {
+		digest := file.GetDigest()
+		if digest == "" {
+			digest = localDigest(file.GetUri())
+		}
+		if digest == "" {
+			return ""
+		}
+		fmt.Fprintf(h, "%s %s\n", filePath(file), digest)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func filePath(file *buildeventstream.File) string {
+	return path.Join(append(slices.Clone(file.GetPathPrefix()), file.GetName())...)
+}
+
+// localDigest returns the digest of the file at a file:// URI, or an empty
+// string if it isn't a local file or can't be read.
+func localDigest(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	f, err := os.Open(u.Path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
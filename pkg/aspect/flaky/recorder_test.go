@@ -0,0 +1,136 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package flaky
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+)
+
+func startedEvent(uuid string) *buildeventstream.BuildEvent {
+	return &buildeventstream.BuildEvent{
+		Payload: &buildeventstream.BuildEvent_Started{Started: &buildeventstream.BuildStarted{Uuid: uuid}},
+	}
+}
+
+func namedSetEvent(id string, files ...*buildeventstream.File) *buildeventstream.BuildEvent {
+	return &buildeventstream.BuildEvent{
+		Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_NamedSet{
+			NamedSet: &buildeventstream.BuildEventId_NamedSetOfFilesId{Id: id},
+		}},
+		Payload: &buildeventstream.BuildEvent_NamedSetOfFiles{NamedSetOfFiles: &buildeventstream.NamedSetOfFiles{Files: files}},
+	}
+}
+
+func targetCompletedEvent(label, configuration, namedSet string) *buildeventstream.BuildEvent {
+	return &buildeventstream.BuildEvent{
+		Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_TargetCompleted{
+			TargetCompleted: &buildeventstream.BuildEventId_TargetCompletedId{
+				Label:         label,
+				Configuration: &buildeventstream.BuildEventId_ConfigurationId{Id: configuration},
+			},
+		}},
+		Payload: &buildeventstream.BuildEvent_Completed{Completed: &buildeventstream.TargetComplete{
+			Success: true,
+			OutputGroup: []*buildeventstream.OutputGroup{{
+				Name:     "default",
+				FileSets: []*buildeventstream.BuildEventId_NamedSetOfFilesId{{Id: namedSet}},
+			}},
+		}},
+	}
+}
+
+func testResultEvent(label, configuration string, attempt int32, status buildeventstream.TestStatus, cached bool, start time.Time) *buildeventstream.BuildEvent {
+	return &buildeventstream.BuildEvent{
+		Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_TestResult{
+			TestResult: &buildeventstream.BuildEventId_TestResultId{
+				Label:         label,
+				Configuration: &buildeventstream.BuildEventId_ConfigurationId{Id: configuration},
+				Attempt:       attempt,
+			},
+		}},
+		Payload: &buildeventstream.BuildEvent_TestResult{TestResult: &buildeventstream.TestResult{
+			Status:           status,
+			CachedLocally:    cached,
+			TestAttemptStart: timestamppb.New(start),
+		}},
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("records the results of the tests with the digest of their target", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		executable := filepath.Join(t.TempDir(), "b_test")
+		g.Expect(os.WriteFile(executable, []byte("#!/bin/sh"), 0o755)).To(Succeed())
+
+		recorder := NewRecorder()
+		for _, event := range []*buildeventstream.BuildEvent{
+			startedEvent("invocation"),
+			namedSetEvent("0", &buildeventstream.File{Name: "a_test", PathPrefix: []string{"bazel-out"}, Digest: "abc"}),
+			targetCompletedEvent("//a:test", "k8", "0"),
+			namedSetEvent("1", &buildeventstream.File{Name: "b_test", File: &buildeventstream.File_Uri{Uri: "file://" + executable}}),
+			targetCompletedEvent("//b:test", "k8", "1"),
+			namedSetEvent("2", &buildeventstream.File{Name: "c_test", File: &buildeventstream.File_Uri{Uri: "bytestream://remote/c_test"}}),
+			targetCompletedEvent("//c:test", "k8", "2"),
+			testResultEvent("//a:test", "k8", 1, buildeventstream.TestStatus_FAILED, false, start),
+			testResultEvent("//a:test", "k8", 2, buildeventstream.TestStatus_PASSED, false, start.Add(time.Second)),
+			testResultEvent("//b:test", "k8", 1, buildeventstream.TestStatus_PASSED, true, start),
+			testResultEvent("//c:test", "k8", 1, buildeventstream.TestStatus_PASSED, false, start),
+			{LastMessage: true},
+		} {
+			g.Expect(recorder.Callback(event, 0, bep.StreamInfo{})).To(Succeed())
+		}
+
+		records := recorder.Finish()
+		g.Expect(records).To(HaveLen(3))
+		digest := records[0].Digest
+		g.Expect(digest).To(Equal(outputsDigest([]*buildeventstream.File{{Name: "a_test", PathPrefix: []string{"bazel-out"}, Digest: "abc"}})))
+		g.Expect(records).To(Equal([]Record{
+			{Label: "//a:test", Configuration: "k8", Digest: digest, Status: "FAILED", Attempt: 1, InvocationID: "invocation", Time: start},
+			{Label: "//a:test", Configuration: "k8", Digest: digest, Status: "PASSED", Attempt: 2, InvocationID: "invocation", Time: start.Add(time.Second)},
+			// The digest of a file that isn't local and has no digest is
+			// unknown.
+			{Label: "//c:test", Configuration: "k8", Status: "PASSED", Attempt: 1, InvocationID: "invocation", Time: start},
+		}))
+		g.Expect(recorder.Labels()).To(Equal([]string{"//a:test", "//b:test", "//c:test"}))
+	})
+
+	t.Run("computes the digest of local files from their contents", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		executable := filepath.Join(t.TempDir(), "test")
+		file := &buildeventstream.File{Name: "test", File: &buildeventstream.File_Uri{Uri: "file://" + executable}}
+		g.Expect(os.WriteFile(executable, []byte("one"), 0o755)).To(Succeed())
+		one := outputsDigest([]*buildeventstream.File{file})
+		g.Expect(os.WriteFile(executable, []byte("two"), 0o755)).To(Succeed())
+		two := outputsDigest([]*buildeventstream.File{file})
+
+		g.Expect(one).NotTo(BeEmpty())
+		g.Expect(two).NotTo(BeEmpty())
+		g.Expect(one).NotTo(Equal(two))
+	})
+}
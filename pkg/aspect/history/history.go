@@ -0,0 +1,168 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+)
+
+type History struct {
+	ioutils.Streams
+}
+
+func New(streams ioutils.Streams) *History {
+	return &History{
+		Streams: streams,
+	}
+}
+
+// List prints the last invocations of the workspace, the most recent first.
+func (runner *History) List(ctx context.Context, cmd *cobra.Command, args []string) error {
+	limit, err := cmd.Flags().GetInt("limit")
+	if err != nil {
+		return err
+	}
+	asJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return err
+	}
+
+	invocations := []Invocation{}
+	store, err := runner.openStore()
+	if err != nil {
+		return err
+	}
+	if store != nil {
+		defer store.Close()
+		if invocations, err = store.List(limit); err != nil {
+			return err
+		}
+	}
+
+	if asJSON {
+		return printJSON(runner.Stdout, invocations)
+	}
+	if len(invocations) == 0 {
+		fmt.Fprintln(runner.Stdout, "No invocations are recorded")
+		return nil
+	}
+	w := tabwriter.NewWriter(runner.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INVOCATION\tSTART\tCOMMAND\tEXIT\tDURATION\tTARGETS")
+	for _, inv := range invocations {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			inv.ID,
+			inv.Start.Local().Format(time.DateTime),
+			inv.Command,
+			exitStatus(inv),
+			inv.Duration.Round(100*time.Millisecond),
+			strings.Join(inv.Targets, " "))
+	}
+	return w.Flush()
+}
+
+// Show prints the summary of an invocation of the workspace, given its ID or
+// a prefix of it.
+func (runner *History) Show(ctx context.Context, cmd *cobra.Command, args []string) error {
+	asJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return err
+	}
+
+	store, err := runner.openStore()
+	if err != nil {
+		return err
+	}
+	if store == nil {
+		return fmt.Errorf("invocation %s is not recorded", args[0])
+	}
+	defer store.Close()
+	inv, err := store.Get(args[0])
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("invocation %s is not recorded", args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		return printJSON(runner.Stdout, inv)
+	}
+	w := tabwriter.NewWriter(runner.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Invocation:\t%s\n", inv.ID)
+	fmt.Fprintf(w, "Command:\taspect %s\n", strings.Join(append([]string{inv.Command}, inv.Args...), " "))
+	if len(inv.Targets) > 0 {
+		fmt.Fprintf(w, "Targets:\t%s\n", strings.Join(inv.Targets, " "))
+	}
+	fmt.Fprintf(w, "Started:\t%s\n", inv.Start.Local().Format(time.DateTime))
+	fmt.Fprintf(w, "Duration:\t%s\n", inv.Duration.Round(10*time.Millisecond))
+	fmt.Fprintf(w, "Exit code:\t%s\n", exitStatus(*inv))
+	fmt.Fprintf(w, "Actions:\t%d executed, %d created\n", inv.ActionsExecuted, inv.ActionsCreated)
+	if inv.Processes > 0 {
+		fmt.Fprintf(w, "Cache hits:\t%d of %d processes (%.1f%%)\n", inv.CacheHits, inv.Processes, 100*float64(inv.CacheHits)/float64(inv.Processes))
+	}
+	if tests := inv.TestsPassed + inv.TestsFailed + inv.TestsFlaky; tests > 0 {
+		fmt.Fprintf(w, "Tests:\t%d passed, %d failed, %d flaky\n", inv.TestsPassed, inv.TestsFailed, inv.TestsFlaky)
+	}
+	if len(inv.Failed) > 0 {
+		fmt.Fprintf(w, "Failed:\t%s\n", strings.Join(inv.Failed, " "))
+	}
+	return w.Flush()
+}
+
+// openStore opens the history of the workspace for reading, or returns nil if
+// nothing was recorded in it.
+func (runner *History) openStore() (*Store, error) {
+	path, err := StorePath()
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, fmt.Errorf("the invocation history is only kept within a bazel workspace")
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return OpenStore(path, true)
+}
+
+// exitStatus returns the exit code of an invocation and its name.
+func exitStatus(inv Invocation) string {
+	if !inv.Finished {
+		return "unfinished"
+	}
+	if inv.ExitCodeName == "" {
+		return fmt.Sprint(inv.ExitCode)
+	}
+	return fmt.Sprintf("%d (%s)", inv.ExitCode, inv.ExitCodeName)
+}
+
+func printJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
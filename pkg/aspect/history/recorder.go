@@ -0,0 +1,158 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package history
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/summary"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+)
+
+// Key is the key of the Aspect CLI config that turns off recording the
+// invocations in the history when set to false.
+const Key = "invocation_history"
+
+// Enabled returns true unless recording the invocations is turned off in the
+// Aspect CLI config.
+func Enabled() bool {
+	return !viper.IsSet(Key) || viper.GetBool(Key)
+}
+
+// Invocation is the summary of an invocation of bazel.
+type Invocation struct {
+	ID      string `json:"id"`
+	Command string `json:"command"`
+	// Args are the arguments of the aspect command, so that it can be run
+	// again, and Targets the target patterns bazel expanded.
+	Args         []string      `json:"args"`
+	Targets      []string      `json:"targets,omitempty"`
+	Start        time.Time     `json:"start"`
+	Duration     time.Duration `json:"duration"`
+	ExitCode     int32         `json:"exit_code"`
+	ExitCodeName string        `json:"exit_code_name"`
+	// Finished is false if the invocation ended before bazel reported its
+	// exit code.
+	Finished        bool  `json:"finished"`
+	ActionsCreated  int64 `json:"actions_created"`
+	ActionsExecuted int64 `json:"actions_executed"`
+	CacheHits       int64 `json:"cache_hits"`
+	Processes       int64 `json:"processes"`
+	TestsPassed     int   `json:"tests_passed,omitempty"`
+	TestsFailed     int   `json:"tests_failed,omitempty"`
+	TestsFlaky      int   `json:"tests_flaky,omitempty"`
+	// Failed are the labels of the targets that failed to build and of the
+	// tests that didn't pass.
+	Failed []string `json:"failed,omitempty"`
+}
+
+// Recorder summarizes the invocations of a command from their build events. A
+// command with --watch runs several invocations through the same build event
+// stream.
+type Recorder struct {
+	mu          sync.Mutex
+	args        []string
+	invocations []*Invocation
+}
+
+func NewRecorder(args []string) *Recorder {
+	return &Recorder{args: slices.Clone(args)}
+}
+
+// Callback summarizes a build event into its invocation. It is a
+// bep.CallbackFn.
+func (r *Recorder) Callback(event *buildeventstream.BuildEvent, _ int64, _ bep.StreamInfo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if started := event.GetStarted(); started != nil {
+		start := time.Now()
+		if started.GetStartTime() != nil {
+			start = started.GetStartTime().AsTime()
+		}
+		r.invocations = append(r.invocations, &Invocation{
+			ID:      started.GetUuid(),
+			Command: started.GetCommand(),
+			Args:    r.args,
+			Start:   start.UTC(),
+		})
+		return nil
+	}
+	if len(r.invocations) == 0 {
+		return nil
+	}
+	inv := r.invocations[len(r.invocations)-1]
+
+	switch {
+	case event.GetExpanded() != nil:
+		inv.Targets = append(inv.Targets, event.GetId().GetPattern().GetPattern()...)
+	case event.GetFinished() != nil:
+		finished := event.GetFinished()
+		inv.Finished = true
+		inv.ExitCode = finished.GetExitCode().GetCode()
+		inv.ExitCodeName = finished.GetExitCode().GetName()
+		end := time.Now()
+		if finished.GetFinishTime() != nil {
+			end = finished.GetFinishTime().AsTime()
+		}
+		inv.Duration = end.Sub(inv.Start)
+	case event.GetBuildMetrics() != nil:
+		actions := event.GetBuildMetrics().GetActionSummary()
+		inv.ActionsCreated = actions.GetActionsCreated()
+		inv.ActionsExecuted = actions.GetActionsExecuted()
+		inv.CacheHits, inv.Processes = summary.CacheHits(actions.GetRunnerCount())
+	case event.GetCompleted() != nil:
+		id := event.GetId().GetTargetCompleted()
+		if id.GetAspect() == "" && !event.GetCompleted().GetSuccess() {
+			inv.addFailed(id.GetLabel())
+		}
+	case event.GetTestSummary() != nil:
+		switch event.GetTestSummary().GetOverallStatus() {
+		case buildeventstream.TestStatus_PASSED:
+			inv.TestsPassed++
+		case buildeventstream.TestStatus_FLAKY:
+			inv.TestsFlaky++
+		default:
+			inv.TestsFailed++
+			inv.addFailed(event.GetId().GetTestSummary().GetLabel())
+		}
+	}
+	return nil
+}
+
+func (inv *Invocation) addFailed(label string) {
+	if label != "" && !slices.Contains(inv.Failed, label) {
+		inv.Failed = append(inv.Failed, label)
+	}
+}
+
+// Invocations returns the invocations summarized so far.
+func (r *Recorder) Invocations() []Invocation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	invocations := make([]Invocation, len(r.invocations))
+	for i, inv := range r.invocations {
+		invocations[i] = *inv
+		invocations[i].Targets = slices.Clone(inv.Targets)
+		invocations[i].Failed = slices.Clone(inv.Failed)
+	}
+	return invocations
+}
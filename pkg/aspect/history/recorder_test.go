@@ -0,0 +1,142 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package history
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+)
+
+func startedEvent(uuid string, start time.Time) *buildeventstream.BuildEvent {
+	return &buildeventstream.BuildEvent{
+		Payload: &buildeventstream.BuildEvent_Started{Started: &buildeventstream.BuildStarted{
+			Uuid:      uuid,
+			Command:   "test",
+			StartTime: timestamppb.New(start),
+		}},
+	}
+}
+
+func finishedEvent(code int32, name string, finish time.Time) *buildeventstream.BuildEvent {
+	return &buildeventstream.BuildEvent{
+		Payload: &buildeventstream.BuildEvent_Finished{Finished: &buildeventstream.BuildFinished{
+			ExitCode:   &buildeventstream.BuildFinished_ExitCode{Code: code, Name: name},
+			FinishTime: timestamppb.New(finish),
+		}},
+	}
+}
+
+func testSummaryEvent(label string, status buildeventstream.TestStatus) *buildeventstream.BuildEvent {
+	return &buildeventstream.BuildEvent{
+		Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_TestSummary{
+			TestSummary: &buildeventstream.BuildEventId_TestSummaryId{Label: label},
+		}},
+		Payload: &buildeventstream.BuildEvent_TestSummary{TestSummary: &buildeventstream.TestSummary{OverallStatus: status}},
+	}
+}
+
+func targetCompletedEvent(label, aspect string, success bool) *buildeventstream.BuildEvent {
+	return &buildeventstream.BuildEvent{
+		Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_TargetCompleted{
+			TargetCompleted: &buildeventstream.BuildEventId_TargetCompletedId{Label: label, Aspect: aspect},
+		}},
+		Payload: &buildeventstream.BuildEvent_Completed{Completed: &buildeventstream.TargetComplete{Success: success}},
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("summarizes each invocation of the build event stream", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		recorder := NewRecorder([]string{"//...", "--watch"})
+		for _, event := range []*buildeventstream.BuildEvent{
+			startedEvent("first", start),
+			{
+				Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_Pattern{
+					Pattern: &buildeventstream.BuildEventId_PatternExpandedId{Pattern: []string{"//..."}},
+				}},
+				Payload: &buildeventstream.BuildEvent_Expanded{Expanded: &buildeventstream.PatternExpanded{}},
+			},
+			targetCompletedEvent("//lib:broken", "", false),
+			targetCompletedEvent("//lib:ok", "", true),
+			targetCompletedEvent("//lib:ok", "//lint:aspect.bzl%lint", false),
+			targetCompletedEvent("//lib:broken_test", "", false),
+			testSummaryEvent("//a:test", buildeventstream.TestStatus_PASSED),
+			testSummaryEvent("//b:test", buildeventstream.TestStatus_FLAKY),
+			testSummaryEvent("//c:test", buildeventstream.TestStatus_FAILED),
+			testSummaryEvent("//lib:broken_test", buildeventstream.TestStatus_FAILED_TO_BUILD),
+			{Payload: &buildeventstream.BuildEvent_BuildMetrics{BuildMetrics: &buildeventstream.BuildMetrics{
+				ActionSummary: &buildeventstream.BuildMetrics_ActionSummary{
+					ActionsCreated:  20,
+					ActionsExecuted: 10,
+					RunnerCount: []*buildeventstream.BuildMetrics_ActionSummary_RunnerCount{
+						{Name: "total", Count: 8},
+						{Name: "remote cache hit", Count: 6},
+						{Name: "linux-sandbox", Count: 2},
+					},
+				},
+			}}},
+			finishedEvent(3, "TESTS_FAILED", start.Add(1500*time.Millisecond)),
+			startedEvent("second", start.Add(time.Minute)),
+		} {
+			g.Expect(recorder.Callback(event, 0, bep.StreamInfo{})).To(Succeed())
+		}
+
+		g.Expect(recorder.Invocations()).To(Equal([]Invocation{
+			{
+				ID:              "first",
+				Command:         "test",
+				Args:            []string{"//...", "--watch"},
+				Targets:         []string{"//..."},
+				Start:           start,
+				Duration:        1500 * time.Millisecond,
+				ExitCode:        3,
+				ExitCodeName:    "TESTS_FAILED",
+				Finished:        true,
+				ActionsCreated:  20,
+				ActionsExecuted: 10,
+				CacheHits:       6,
+				Processes:       8,
+				TestsPassed:     1,
+				TestsFailed:     2,
+				TestsFlaky:      1,
+				Failed:          []string{"//lib:broken", "//lib:broken_test", "//c:test"},
+			},
+			{
+				ID:      "second",
+				Command: "test",
+				Args:    []string{"//...", "--watch"},
+				Start:   start.Add(time.Minute),
+			},
+		}))
+	})
+
+	t.Run("ignores the build events before the first invocation starts", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		recorder := NewRecorder(nil)
+		g.Expect(recorder.Callback(finishedEvent(0, "SUCCESS", start), 0, bep.StreamInfo{})).To(Succeed())
+		g.Expect(recorder.Invocations()).To(BeEmpty())
+	})
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package history
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel"
+)
+
+// StoreFile is the file of the output base that holds the history of the
+// invocations of the workspace.
+const StoreFile = "aspect-history.db"
+
+// maxInvocations is the number of invocations the history keeps.
+const maxInvocations = 1000
+
+// openTimeout bounds how long opening the store waits for another invocation
+// of the CLI that has it open for writing.
+const openTimeout = time.Second
+
+var invocationsBucket = []byte("invocations")
+
+// StorePath returns the path of the history of the invocations of the
+// workspace, or an empty string outside of a workspace.
+func StorePath() (string, error) {
+	workspaceRoot := bazel.WorkspaceFromWd.WorkspaceRoot()
+	if workspaceRoot == "" {
+		return "", nil
+	}
+	outputBase, err := bazel.OutputBase(workspaceRoot)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(outputBase, StoreFile), nil
+}
+
+// Store is the history of the invocations of a workspace. The invocations are
+// kept in the order they started, keyed by their start time.
+type Store struct {
+	db *bolt.DB
+}
+
+// OpenStore opens the history at path, creating it unless readOnly is set.
+func OpenStore(path string, readOnly bool) (*Store, error) {
+	if readOnly {
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("failed to open the invocation history: %w", err)
+		}
+	} else if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create the invocation history: %w", err)
+	}
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: openTimeout, ReadOnly: readOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the invocation history %s: %w", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Add adds invocations to the history, and removes the oldest ones beyond
+// maxInvocations.
+func (s *Store) Add(invocations ...Invocation) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(invocationsBucket)
+		if err != nil {
+			return err
+		}
+		for _, inv := range invocations {
+			value, err := json.Marshal(inv)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(key(inv), value); err != nil {
+				return err
+			}
+		}
+
+		var keys [][]byte
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			keys = append(keys, bytes.Clone(k))
+		}
+		for _, k := range keys[:max(0, len(keys)-maxInvocations)] {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// List returns the last limit invocations of the history, the most recent
+// first, or all of them if limit isn't positive.
+func (s *Store) List(limit int) ([]Invocation, error) {
+	invocations := []Invocation{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(invocationsBucket)
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		for k, v := c.Last(); k != nil && (limit <= 0 || len(invocations) < limit); k, v = c.Prev() {
+			var inv Invocation
+			if err := json.Unmarshal(v, &inv); err != nil {
+				return fmt.Errorf("failed to read the invocation history: %w", err)
+			}
+			invocations = append(invocations, inv)
+		}
+		return nil
+	})
+	return invocations, err
+}
+
+// Get returns the invocation whose ID is id, or starts with id as long as a
+// single invocation does.
+func (s *Store) Get(id string) (*Invocation, error) {
+	invocations, err := s.List(0)
+	if err != nil {
+		return nil, err
+	}
+	var found *Invocation
+	for i, inv := range invocations {
+		if inv.ID == id {
+			return &invocations[i], nil
+		}
+		if id != "" && strings.HasPrefix(inv.ID, id) {
+			if found != nil {
+				return nil, fmt.Errorf("several invocations start with %q", id)
+			}
+			found = &invocations[i]
+		}
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
+
+// ErrNotFound is returned by Get when no invocation has the ID.
+var ErrNotFound = errors.New("invocation not found")
+
+// key returns the key of an invocation, its start time followed by its ID, so
+// that the invocations are sorted by start time.
+func key(inv Invocation) []byte {
+	k := binary.BigEndian.AppendUint64(nil, uint64(inv.Start.UnixNano()))
+	return append(k, inv.ID...)
+}
+
+// Save adds invocations to the history of the workspace. It does nothing
+// outside of a workspace.
+func Save(invocations []Invocation) error {
+	if len(invocations) == 0 {
+		return nil
+	}
+	path, err := StorePath()
+	if err != nil || path == "" {
+		return err
+	}
+	store, err := OpenStore(path, false)
+	if err != nil {
+		return err
+	}
+	if err := store.Add(invocations...); err != nil {
+		store.Close()
+		return fmt.Errorf("failed to record the invocation: %w", err)
+	}
+	return store.Close()
+}
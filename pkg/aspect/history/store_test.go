@@ -0,0 +1,93 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package history
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestStore(t *testing.T) {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("lists the invocations, the most recent first", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		path := filepath.Join(t.TempDir(), "output_base", StoreFile)
+		store, err := OpenStore(path, false)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(store.Add(
+			Invocation{ID: "b0c1", Command: "build", Start: start.Add(time.Minute)},
+			Invocation{ID: "a1b2", Command: "test", Start: start},
+		)).To(Succeed())
+		g.Expect(store.Add(Invocation{ID: "b0c2", Command: "run", Start: start.Add(2 * time.Minute)})).To(Succeed())
+		g.Expect(store.Close()).To(Succeed())
+
+		store, err = OpenStore(path, true)
+		g.Expect(err).NotTo(HaveOccurred())
+		defer store.Close()
+
+		invocations, err := store.List(0)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(invocations).To(HaveLen(3))
+		g.Expect([]string{invocations[0].ID, invocations[1].ID, invocations[2].ID}).To(Equal([]string{"b0c2", "b0c1", "a1b2"}))
+
+		invocations, err = store.List(1)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(invocations).To(Equal([]Invocation{{ID: "b0c2", Command: "run", Start: start.Add(2 * time.Minute)}}))
+
+		inv, err := store.Get("a1")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(inv.ID).To(Equal("a1b2"))
+		inv, err = store.Get("b0c1")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(inv.Command).To(Equal("build"))
+		_, err = store.Get("b0")
+		g.Expect(err).To(MatchError(`several invocations start with "b0"`))
+		_, err = store.Get("c")
+		g.Expect(err).To(MatchError(ErrNotFound))
+	})
+
+	t.Run("can't be opened for reading before anything is recorded", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		_, err := OpenStore(filepath.Join(t.TempDir(), StoreFile), true)
+		g.Expect(err).To(HaveOccurred())
+	})
+
+	t.Run("keeps the last invocations", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		store, err := OpenStore(filepath.Join(t.TempDir(), StoreFile), false)
+		g.Expect(err).NotTo(HaveOccurred())
+		defer store.Close()
+		var invocations []Invocation
+		for i := range maxInvocations + 5 {
+			invocations = append(invocations, Invocation{ID: fmt.Sprint(i), Start: start.Add(time.Duration(i) * time.Second)})
+		}
+		g.Expect(store.Add(invocations...)).To(Succeed())
+
+		kept, err := store.List(0)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(kept).To(HaveLen(maxInvocations))
+		g.Expect(kept[0].ID).To(Equal(fmt.Sprint(maxInvocations + 4)))
+		g.Expect(kept[len(kept)-1].ID).To(Equal("5"))
+	})
+}
@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
 )
 
 // ResultForLabelAndMnemonic aggregates the relevant files we find in the BEP for
@@ -132,7 +133,7 @@ func parseLinterMnemonicFromFilename(filename string) string {
 	return s[len(s)-2]
 }
 
-func (runner *LintBEPHandler) bepEventCallback(event *buildeventstream.BuildEvent, sn int64, invocationId string) error {
+func (runner *LintBEPHandler) bepEventCallback(event *buildeventstream.BuildEvent, sn int64, stream bep.StreamInfo) error {
 	switch event.Payload.(type) {
 
 	case *buildeventstream.BuildEvent_WorkspaceInfo:
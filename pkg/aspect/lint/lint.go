@@ -263,7 +263,11 @@ lint:
 	// Setup BES subscriber to capture lint results
 	if bep.HasBESInterceptor(ctx) {
 		besInterceptor := bep.BESInterceptorFromContext(ctx)
-		bazelCmd = flags.AddFlagToCommand(bazelCmd, besInterceptor.Args()...)
+		besArgs, err := besInterceptor.Args()
+		if err != nil {
+			return err
+		}
+		bazelCmd = flags.AddFlagToCommand(bazelCmd, besArgs...)
 
 		workingDirectory, err := os.Getwd()
 		if err != nil {
@@ -276,7 +280,7 @@ lint:
 		}
 
 		lintBEPHandler = newLintBEPHandler(workspaceRoot, besCompleted)
-		besInterceptor.RegisterSubscriber(lintBEPHandler.bepEventCallback, false)
+		besInterceptor.RegisterSubscriber(lintBEPHandler.bepEventCallback, bep.SubscriberOptions{})
 	}
 
 	if postTerminateArgs != nil {
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package outputs
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/root/flags"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/plugin"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+)
+
+// buildOutputEventTypes are the build events buildOutputs subscribes to.
+var buildOutputEventTypes = []string{"started", "named_set", "target_completed", "build_finished"}
+
+// buildOutputs collects the output files of the targets built by an
+// invocation from its TargetComplete and NamedSetOfFiles build events.
+type buildOutputs struct {
+	namedSets *plugin.NamedSets
+	outputs   []outputFile
+	finished  chan struct{}
+}
+
+func newBuildOutputs() *buildOutputs {
+	return &buildOutputs{
+		namedSets: plugin.NewNamedSets(),
+		finished:  make(chan struct{}),
+	}
+}
+
+func (b *buildOutputs) callback(event *buildeventstream.BuildEvent, _ int64, _ bep.StreamInfo) error {
+	b.namedSets.Add(event)
+
+	switch event.Payload.(type) {
+	case *buildeventstream.BuildEvent_Completed:
+		id := event.GetId().GetTargetCompleted()
+		completed := event.GetCompleted()
+		// The outputs of aspects aren't outputs of the target itself, and a
+		// target that failed to build has no outputs to print.
+		if id.GetAspect() != "" || !completed.GetSuccess() {
+			return nil
+		}
+		for _, group := range completed.GetOutputGroup() {
+			files, err := b.namedSets.Files(group.GetFileSets()...)
+			if err != nil {
+				return fmt.Errorf("failed to resolve the %s outputs of %s: %w", group.GetName(), id.GetLabel(), err)
+			}
+			for _, file := range files {
+				b.outputs = append(b.outputs, outputFile{
+					Label:       id.GetLabel(),
+					OutputGroup: group.GetName(),
+					Path:        path.Join(append(slices.Clone(file.GetPathPrefix()), file.GetName())...),
+				})
+			}
+		}
+
+	case *buildeventstream.BuildEvent_Finished:
+		// Only the first invocation is collected.
+		select {
+		case <-b.finished:
+		default:
+			close(b.finished)
+		}
+	}
+	return nil
+}
+
+// runBuild builds the targets and prints the output files reported by the
+// Build Event Protocol.
+func (runner *Outputs) runBuild(ctx context.Context, cmd *cobra.Command, args []string) error {
+	nonBazelFlags, bazelFlags, err := bazel.SeparateBazelFlags("build", args)
+	if err != nil {
+		return err
+	}
+	nonBazelFlags = RemoveCobraFlagsFromArgs(cmd, nonBazelFlags)
+	asJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return err
+	}
+
+	if len(nonBazelFlags) < 1 {
+		return fmt.Errorf("a target pattern is required as the first argument to outputs command")
+	} else if len(nonBazelFlags) > 2 {
+		return fmt.Errorf("expecting a maximum of 2 arguments to outputs command but got %v", len(nonBazelFlags))
+	}
+	target := nonBazelFlags[0]
+	var mnemonicFilter string
+	if len(nonBazelFlags) == 2 {
+		mnemonicFilter = nonBazelFlags[1]
+	}
+	if mnemonicFilter == "ExecutableHash" {
+		return fmt.Errorf("'ExecutableHash' is not supported with --%s", BEPFlagName)
+	}
+
+	if !bep.HasBESInterceptor(ctx) {
+		return fmt.Errorf("BES should always be initiated when running outputs with --%s", BEPFlagName)
+	}
+	besInterceptor := bep.BESInterceptorFromContext(ctx)
+	collector := newBuildOutputs()
+	besInterceptor.RegisterSubscriber(collector.callback, bep.SubscriberOptions{}, buildOutputEventTypes...)
+
+	bazelCmd := []string{"build", target}
+	bazelCmd = append(bazelCmd, bazelFlags...)
+	besArgs, err := besInterceptor.Args()
+	if err != nil {
+		return err
+	}
+	bazelCmd = flags.AddFlagToCommand(bazelCmd, besArgs...)
+
+	// Bazel prints nothing but its progress, which goes to stderr, so that
+	// stdout only holds the outputs.
+	bzlStreams := ioutils.Streams{Stdin: runner.Stdin, Stdout: runner.Stderr, Stderr: runner.Stderr}
+	if err := runner.bzl.RunCommand(bzlStreams, nil, bazelCmd...); err != nil {
+		return err
+	}
+
+	// Wait for the BES build finished event for some maximum amount of time
+	select {
+	case <-collector.finished:
+	case <-time.After(60 * time.Second):
+		return fmt.Errorf("timed out waiting for build completed event")
+	}
+
+	if subscriberErrors := bep.BESErrors(ctx); len(subscriberErrors) > 0 {
+		for _, err := range subscriberErrors {
+			fmt.Fprintf(runner.Stderr, "Error: failed to run outputs command: %v\n", err)
+		}
+		return fmt.Errorf("%v BES subscriber error(s)", len(subscriberErrors))
+	}
+
+	outputs := collector.outputs
+	// The build events don't say which action produced a file, so the outputs
+	// are filtered on the mnemonic by querying the actions of the targets.
+	if mnemonicFilter != "" {
+		agc, err := runner.bzl.AQuery(target, bazelFlags)
+		if err != nil {
+			return fmt.Errorf("%s", err.Error())
+		}
+		paths := make(map[string]bool)
+		for _, a := range bazel.ParseOutputs(agc) {
+			if a.Mnemonic == mnemonicFilter {
+				paths[a.Path] = true
+			}
+		}
+		outputs = slices.DeleteFunc(outputs, func(o outputFile) bool {
+			return !paths[o.Path]
+		})
+		for i := range outputs {
+			outputs[i].Mnemonic = mnemonicFilter
+		}
+	}
+
+	if asJSON {
+		return runner.printJSON(outputs)
+	}
+	for _, o := range outputs {
+		if mnemonicFilter != "" {
+			fmt.Fprintf(runner.Stdout, "%s\n", o.Path)
+		} else {
+			fmt.Fprintf(runner.Stdout, "%s %s\n", o.Label, o.Path)
+		}
+	}
+	return nil
+}
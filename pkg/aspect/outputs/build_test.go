@@ -0,0 +1,104 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package outputs
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+)
+
+func namedSetEvent(id string, files []*buildeventstream.File, nested ...string) *buildeventstream.BuildEvent {
+	set := &buildeventstream.NamedSetOfFiles{Files: files}
+	for _, n := range nested {
+		set.FileSets = append(set.FileSets, &buildeventstream.BuildEventId_NamedSetOfFilesId{Id: n})
+	}
+	return &buildeventstream.BuildEvent{
+		Id: &buildeventstream.BuildEventId{
+			Id: &buildeventstream.BuildEventId_NamedSet{NamedSet: &buildeventstream.BuildEventId_NamedSetOfFilesId{Id: id}},
+		},
+		Payload: &buildeventstream.BuildEvent_NamedSetOfFiles{NamedSetOfFiles: set},
+	}
+}
+
+func completedEvent(label, aspect string, success bool, groups map[string]string) *buildeventstream.BuildEvent {
+	completed := &buildeventstream.TargetComplete{Success: success}
+	for name, set := range groups {
+		completed.OutputGroup = append(completed.OutputGroup, &buildeventstream.OutputGroup{
+			Name:     name,
+			FileSets: []*buildeventstream.BuildEventId_NamedSetOfFilesId{{Id: set}},
+		})
+	}
+	return &buildeventstream.BuildEvent{
+		Id: &buildeventstream.BuildEventId{
+			Id: &buildeventstream.BuildEventId_TargetCompleted{TargetCompleted: &buildeventstream.BuildEventId_TargetCompletedId{Label: label, Aspect: aspect}},
+		},
+		Payload: &buildeventstream.BuildEvent_Completed{Completed: completed},
+	}
+}
+
+func finishedEvent() *buildeventstream.BuildEvent {
+	return &buildeventstream.BuildEvent{
+		Id: &buildeventstream.BuildEventId{
+			Id: &buildeventstream.BuildEventId_BuildFinished{BuildFinished: &buildeventstream.BuildEventId_BuildFinishedId{}},
+		},
+		Payload: &buildeventstream.BuildEvent_Finished{Finished: &buildeventstream.BuildFinished{}},
+	}
+}
+
+func binFile(name string) *buildeventstream.File {
+	return &buildeventstream.File{Name: name, PathPrefix: []string{"bazel-out", "k8-fastbuild", "bin"}}
+}
+
+func TestBuildOutputs(t *testing.T) {
+	t.Run("collects the outputs of the completed targets", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		b := newBuildOutputs()
+		events := []*buildeventstream.BuildEvent{
+			namedSetEvent("0", []*buildeventstream.File{binFile("lib/lib.a")}),
+			namedSetEvent("1", []*buildeventstream.File{binFile("cli/cli")}, "0"),
+			completedEvent("//cli:cli", "", true, map[string]string{"default": "1"}),
+			completedEvent("//cli:cli", "//lint:aspect.bzl%lint", true, map[string]string{"report": "0"}),
+			completedEvent("//broken:broken", "", false, map[string]string{"default": "0"}),
+		}
+		for i, event := range events {
+			g.Expect(b.callback(event, int64(i), bep.StreamInfo{})).To(Succeed())
+		}
+		g.Expect(b.finished).NotTo(BeClosed())
+
+		g.Expect(b.callback(finishedEvent(), int64(len(events)), bep.StreamInfo{})).To(Succeed())
+		g.Expect(b.finished).To(BeClosed())
+		g.Expect(b.outputs).To(Equal([]outputFile{
+			{Label: "//cli:cli", OutputGroup: "default", Path: "bazel-out/k8-fastbuild/bin/cli/cli"},
+			{Label: "//cli:cli", OutputGroup: "default", Path: "bazel-out/k8-fastbuild/bin/lib/lib.a"},
+		}))
+
+		// A second build finished event doesn't close the channel again.
+		g.Expect(b.callback(finishedEvent(), int64(len(events)+1), bep.StreamInfo{})).To(Succeed())
+	})
+
+	t.Run("fails when a named set wasn't received", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		b := newBuildOutputs()
+		err := b.callback(completedEvent("//cli:cli", "", true, map[string]string{"default": "1"}), 0, bep.StreamInfo{})
+		g.Expect(err).To(MatchError(ContainSubstring("failed to resolve the default outputs of //cli:cli")))
+	})
+}
@@ -18,16 +18,22 @@ package outputs
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/root/flags"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
 )
 
+// BEPFlagName is the name of the flag that makes the outputs command build the
+// targets and print the output files reported by the Build Event Protocol.
+const BEPFlagName = "bep"
+
 type Outputs struct {
 	ioutils.Streams
 	bzl bazel.Bazel
@@ -42,6 +48,8 @@ func New(streams ioutils.Streams, bzl bazel.Bazel) *Outputs {
 
 func AddFlags(flagSet *pflag.FlagSet) {
 	flagSet.String("hash_salt", "", "When 'ExecutableHash' is specified, this value will be added as a suffix to every hash")
+	flags.RegisterNoableBool(flagSet, BEPFlagName, false, "Build the targets and print the output files reported by the Build Event Protocol, rather than querying the declared outputs")
+	flags.RegisterNoableBool(flagSet, "json", false, "Print the outputs as a JSON array")
 }
 
 func remove(slice []string, i int) []string {
@@ -51,11 +59,15 @@ func remove(slice []string, i int) []string {
 func RemoveCobraFlagsFromArgs(cmd *cobra.Command, args []string) []string {
 	cmd.Flags().VisitAll(func(f *pflag.Flag) {
 		for i, arg := range args {
-			if arg == fmt.Sprintf("--%s=%s", f.Name, f.Value) {
+			if strings.HasPrefix(arg, fmt.Sprintf("--%s=", f.Name)) {
+				args = remove(args, i)
+				break
+			} else if arg == fmt.Sprintf("--%s", f.Name) && f.NoOptDefVal != "" {
+				// A boolean flag such as --json doesn't take the next arg as its value.
 				args = remove(args, i)
 				break
 			} else if arg == fmt.Sprintf("--%s", f.Name) &&
-				len(args)+1 > i &&
+				i+1 < len(args) &&
 				args[i+1] == f.Value.String() {
 				args = remove(args, i+1)
 				args = remove(args, i)
@@ -66,19 +78,34 @@ func RemoveCobraFlagsFromArgs(cmd *cobra.Command, args []string) []string {
 	return args
 }
 
-func (runner *Outputs) Run(_ context.Context, cmd *cobra.Command, args []string) error {
+func (runner *Outputs) Run(ctx context.Context, cmd *cobra.Command, args []string) error {
+	if cmd != nil {
+		useBEP, err := cmd.Flags().GetBool(BEPFlagName)
+		if err != nil {
+			return err
+		}
+		if useBEP {
+			return runner.runBuild(ctx, cmd, args)
+		}
+	}
+
 	nonBazelFlags, bazelFlags, err := bazel.SeparateBazelFlags("aquery", args)
 	if err != nil {
 		return err
 	}
 
 	salt := ""
+	asJSON := false
 	if cmd != nil {
 		nonBazelFlags = RemoveCobraFlagsFromArgs(cmd, nonBazelFlags)
 		salt, err = cmd.Flags().GetString("hash_salt")
 		if err != nil {
 			return err
 		}
+		asJSON, err = cmd.Flags().GetBool("json")
+		if err != nil {
+			return err
+		}
 	}
 
 	// Test to see if the command has been passed the `--query_file` Bazel flag.
@@ -140,12 +167,25 @@ func (runner *Outputs) Run(_ context.Context, cmd *cobra.Command, args []string)
 		if err != nil {
 			return err
 		}
+		if asJSON {
+			return runner.printJSON(hashes)
+		}
 		for label, hash := range hashes {
 			fmt.Fprintf(runner.Stdout, "%s %s\n", label, hash)
 		}
 		return nil
 	}
 
+	if asJSON {
+		filtered := make([]outputFile, 0, len(outs))
+		for _, a := range outs {
+			if len(mnemonicFilter) == 0 || a.Mnemonic == mnemonicFilter {
+				filtered = append(filtered, outputFile{Label: a.Label, Mnemonic: a.Mnemonic, Path: a.Path})
+			}
+		}
+		return runner.printJSON(filtered)
+	}
+
 	for _, a := range outs {
 		if len(mnemonicFilter) > 0 {
 			if a.Mnemonic == mnemonicFilter {
@@ -157,3 +197,19 @@ func (runner *Outputs) Run(_ context.Context, cmd *cobra.Command, args []string)
 	}
 	return nil
 }
+
+// outputFile is an output printed by the outputs command with --json. The
+// output group is only known when the outputs are built, and the mnemonic when
+// they are queried or filtered on it.
+type outputFile struct {
+	Label       string `json:"label"`
+	Mnemonic    string `json:"mnemonic,omitempty"`
+	OutputGroup string `json:"output_group,omitempty"`
+	Path        string `json:"path"`
+}
+
+func (runner *Outputs) printJSON(v any) error {
+	encoder := json.NewEncoder(runner.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -85,4 +85,21 @@ func TestOutputs(t *testing.T) {
 		g.Expect(resultingFlags[1]).To(Equal("bar"))
 		g.Expect(resultingFlags[2]).To(Equal("baz"))
 	})
+
+	t.Run("RemoveCobraFlagsFromArgs removes boolean cobra flags", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		cmd := &cobra.Command{
+			Use: "outputs",
+		}
+
+		AddFlags(cmd.Flags())
+		g.Expect(cmd.Flags().Parse([]string{"--bep", "--json"})).To(Succeed())
+
+		resultingFlags := RemoveCobraFlagsFromArgs(cmd, []string{"foo", "--bep", "bar", "--json"})
+		g.Expect(resultingFlags).To(Equal([]string{"foo", "bar"}))
+
+		resultingFlags = RemoveCobraFlagsFromArgs(cmd, []string{"--bep=yes", "foo", "--nojson", "bar"})
+		g.Expect(resultingFlags).To(Equal([]string{"foo", "bar"}))
+	})
 }
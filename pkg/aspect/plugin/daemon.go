@@ -0,0 +1,121 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/daemon"
+)
+
+// DaemonStatus lists the daemons started by the plugins of the workspace and
+// whether they are still running.
+func (runner *Plugin) DaemonStatus(ctx context.Context, cmd *cobra.Command, args []string) error {
+	root, daemons, err := listDaemons()
+	if err != nil {
+		return err
+	}
+	if root == "" {
+		return fmt.Errorf("plugin daemons are only tracked inside a workspace")
+	}
+
+	if len(daemons) == 0 {
+		fmt.Fprintln(runner.Stdout, "No plugin daemons were started")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(runner.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PLUGIN\tDAEMON\tPID\tSTATUS\tLOG")
+	for _, d := range daemons {
+		status := "exited"
+		if d.Running {
+			status = "running"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", d.Plugin, d.Name, strconv.Itoa(d.Pid), status, d.LogPath)
+	}
+	return w.Flush()
+}
+
+// DaemonStop stops the daemons started by the plugins of the workspace, or
+// only those matching the arguments. An argument is the name of a plugin, to
+// stop all its daemons, or <plugin>/<daemon>.
+func (runner *Plugin) DaemonStop(ctx context.Context, cmd *cobra.Command, args []string) error {
+	root, daemons, err := listDaemons()
+	if err != nil {
+		return err
+	}
+	if root == "" {
+		return fmt.Errorf("plugin daemons are only tracked inside a workspace")
+	}
+
+	for _, arg := range args {
+		if !matchesAnyDaemon(daemons, arg) {
+			return fmt.Errorf("no daemon of a plugin matches %q", arg)
+		}
+	}
+
+	for _, d := range daemons {
+		if len(args) > 0 && !matchesDaemon(d, args) {
+			continue
+		}
+		if err := daemon.Stop(root, d); err != nil {
+			return err
+		}
+		if d.Running {
+			fmt.Fprintf(runner.Stdout, "Stopped daemon %s of plugin %s\n", d.Name, d.Plugin)
+		}
+	}
+	return nil
+}
+
+func listDaemons() (string, []*daemon.Daemon, error) {
+	root, err := daemon.Root()
+	if err != nil || root == "" {
+		return "", nil, err
+	}
+	daemons, err := daemon.List(root)
+	if err != nil {
+		return "", nil, err
+	}
+	return root, daemons, nil
+}
+
+// matchesDaemon reports whether any of the args names the daemon or its
+// plugin.
+func matchesDaemon(d *daemon.Daemon, args []string) bool {
+	for _, arg := range args {
+		plugin, name, hasName := strings.Cut(arg, "/")
+		if plugin == d.Plugin && (!hasName || name == d.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAnyDaemon(daemons []*daemon.Daemon, arg string) bool {
+	for _, d := range daemons {
+		if matchesDaemon(d, []string{arg}) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package plugin
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/daemon"
+)
+
+func TestMatchesDaemon(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	d := &daemon.Daemon{Plugin: "uploader", Name: "logs"}
+
+	g.Expect(matchesDaemon(d, []string{"uploader"})).To(BeTrue())
+	g.Expect(matchesDaemon(d, []string{"uploader/logs"})).To(BeTrue())
+	g.Expect(matchesDaemon(d, []string{"warmer", "uploader/logs"})).To(BeTrue())
+	g.Expect(matchesDaemon(d, []string{"uploader/metrics"})).To(BeFalse())
+	g.Expect(matchesDaemon(d, []string{"logs"})).To(BeFalse())
+}
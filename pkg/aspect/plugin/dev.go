@@ -0,0 +1,198 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/protobuf/encoding/protodelim"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/root/config"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/client"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/types"
+)
+
+// maxBuildEventSize matches the default limit applied to the events bazel
+// streams to the CLI.
+const maxBuildEventSize = bep.DefaultMaxEventSize
+
+// bepReplay is the outcome of replaying a build event file.
+type bepReplay struct {
+	read      int
+	delivered int
+	errs      []error
+}
+
+// Dev launches a single plugin binary and replays the build events captured in
+// a --build_event_binary_file through its BEPEventCallback, so that plugin
+// authors can exercise their event handling without running bazel.
+func (runner *Plugin) Dev(ctx context.Context, cmd *cobra.Command, args []string) error {
+	bepFile, err := cmd.Flags().GetString("bep-file")
+	if err != nil {
+		return fmt.Errorf("failed to get value of --bep-file flag: %w", err)
+	}
+
+	p, err := devPluginConfig(args[0])
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(bepFile)
+	if err != nil {
+		return fmt.Errorf("failed to open build event file: %w", err)
+	}
+	defer f.Close()
+
+	// The binary is rebuilt between runs, so it is trusted as is rather than
+	// checked against a recorded checksum.
+	checksum, err := fileSHA256(p.From)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(p.From, ".wasm") {
+		p.Runtime = types.RuntimeWasm
+	}
+
+	aspectplugin, err := client.Launch(&client.ResolvedPlugin{Config: p, Checksum: checksum}, runner.Streams)
+	if err != nil {
+		return err
+	}
+	defer aspectplugin.Kill()
+
+	if err := system.SetupPlugin(ctx, p, aspectplugin); err != nil {
+		return fmt.Errorf("plugin %q failed setup: %w", p.Name, err)
+	}
+
+	eventTypes, err := aspectplugin.BEPEventTypes()
+	if err != nil {
+		return fmt.Errorf("failed to get build event types: %w", err)
+	}
+	for _, eventType := range eventTypes {
+		if !bep.IsEventType(eventType) {
+			return fmt.Errorf("plugin %q subscribed to unknown build event type %q", p.Name, eventType)
+		}
+	}
+
+	replay, err := replayBEP(f, aspectplugin.BEPEventCallback, eventTypes)
+	for _, err := range replay.errs {
+		fmt.Fprintf(runner.Stderr, "Error: %v\n", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(runner.Stdout, "Replayed %d of %d build events to plugin %s\n", replay.delivered, replay.read, p.Name)
+
+	if len(replay.errs) > 0 {
+		return fmt.Errorf("plugin %q failed to handle %d build events", p.Name, len(replay.errs))
+	}
+	return nil
+}
+
+// devPluginConfig returns the config of the plugin at path. The config of a
+// workspace plugin loaded from the same path is used when there is one, so
+// that the plugin is set up with its properties.
+func devPluginConfig(path string) (types.PluginConfig, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return types.PluginConfig{}, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return types.PluginConfig{}, fmt.Errorf("%w at path %q", client.ErrPluginNotFound, path)
+	}
+
+	plugins, err := config.UnmarshalPluginConfig(viper.Get("plugins"))
+	if err != nil {
+		return types.PluginConfig{}, err
+	}
+	for _, p := range plugins {
+		from, err := filepath.Abs(client.ResolvePluginURL(p.From))
+		if err == nil && from == path {
+			p.From = path
+			return p, nil
+		}
+	}
+
+	return types.PluginConfig{
+		Name: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		From: path,
+	}, nil
+}
+
+// replayBEP reads the length-delimited build events from r and passes the
+// ones matching eventTypes to callback, in order. All events are passed when
+// eventTypes is empty. Errors returned by callback don't stop the replay.
+func replayBEP(r io.Reader, callback func(*buildeventstream.BuildEvent, int64, string) error, eventTypes []string) (*bepReplay, error) {
+	wanted := make(map[string]struct{}, len(eventTypes))
+	for _, eventType := range eventTypes {
+		wanted[eventType] = struct{}{}
+	}
+
+	replay := &bepReplay{}
+	reader := bufio.NewReader(r)
+	opts := protodelim.UnmarshalOptions{MaxSize: maxBuildEventSize}
+	invocationId := ""
+	for {
+		event := &buildeventstream.BuildEvent{}
+		if err := opts.UnmarshalFrom(reader, event); err != nil {
+			if errors.Is(err, io.EOF) {
+				return replay, nil
+			}
+			return replay, fmt.Errorf("failed to parse build event %d: %w", replay.read+1, err)
+		}
+		replay.read++
+
+		if started := event.GetStarted(); started != nil {
+			invocationId = started.GetUuid()
+		}
+
+		eventType := bep.EventType(event)
+		if _, ok := wanted[eventType]; len(wanted) > 0 && !ok {
+			continue
+		}
+		replay.delivered++
+		if err := callback(event, int64(replay.read), invocationId); err != nil {
+			replay.errs = append(replay.errs, fmt.Errorf("build event %d (%s): %w", replay.read, eventType, err))
+		}
+	}
+}
+
+// fileSHA256 returns the sha256 of the file at the given path.
+func fileSHA256(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate hash for %q: %w", path, err)
+	}
+	defer f.Close()
+	digest := sha256.New()
+	if _, err := io.Copy(digest, f); err != nil {
+		return nil, fmt.Errorf("failed to calculate hash for %q: %w", path, err)
+	}
+	return digest.Sum(nil), nil
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package plugin
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/encoding/protodelim"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+)
+
+func TestReplayBEP(t *testing.T) {
+	events := []*buildeventstream.BuildEvent{
+		{
+			Id:      &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_Started{Started: &buildeventstream.BuildEventId_BuildStartedId{}}},
+			Payload: &buildeventstream.BuildEvent_Started{Started: &buildeventstream.BuildStarted{Uuid: "invocation"}},
+		},
+		{
+			Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_Progress{Progress: &buildeventstream.BuildEventId_ProgressId{OpaqueCount: 1}}},
+		},
+		{
+			Id:          &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_Progress{Progress: &buildeventstream.BuildEventId_ProgressId{OpaqueCount: 2}}},
+			LastMessage: true,
+		},
+	}
+	var file bytes.Buffer
+	for _, event := range events {
+		if _, err := protodelim.MarshalTo(&file, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type call struct {
+		sn           int64
+		invocationId string
+	}
+
+	t.Run("passes every event when the plugin subscribes to all", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var calls []call
+		replay, err := replayBEP(bytes.NewReader(file.Bytes()), func(_ *buildeventstream.BuildEvent, sn int64, invocationId string) error {
+			calls = append(calls, call{sn, invocationId})
+			return nil
+		}, nil)
+
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(replay.read).To(Equal(3))
+		g.Expect(replay.delivered).To(Equal(3))
+		g.Expect(calls).To(Equal([]call{{1, "invocation"}, {2, "invocation"}, {3, "invocation"}}))
+	})
+
+	t.Run("passes the subscribed events only", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var calls []call
+		replay, err := replayBEP(bytes.NewReader(file.Bytes()), func(_ *buildeventstream.BuildEvent, sn int64, invocationId string) error {
+			calls = append(calls, call{sn, invocationId})
+			return nil
+		}, []string{"progress"})
+
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(replay.read).To(Equal(3))
+		g.Expect(replay.delivered).To(Equal(2))
+		g.Expect(calls).To(Equal([]call{{2, "invocation"}, {3, "invocation"}}))
+	})
+
+	t.Run("keeps replaying after the plugin fails an event", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		replay, err := replayBEP(bytes.NewReader(file.Bytes()), func(_ *buildeventstream.BuildEvent, sn int64, _ string) error {
+			if sn == 2 {
+				return errors.New("boom")
+			}
+			return nil
+		}, nil)
+
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(replay.delivered).To(Equal(3))
+		g.Expect(replay.errs).To(HaveLen(1))
+		g.Expect(replay.errs[0]).To(MatchError("build event 2 (progress): boom"))
+	})
+
+	t.Run("fails on a truncated file", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		_, err := replayBEP(bytes.NewReader(file.Bytes()[:file.Len()-1]), func(*buildeventstream.BuildEvent, int64, string) error {
+			return nil
+		}, nil)
+
+		g.Expect(err).To(MatchError(ContainSubstring("failed to parse build event 3")))
+	})
+}
@@ -0,0 +1,238 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/root/config"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/client"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/proto"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/types"
+)
+
+// Results of the individual checks of a plugin reported by Doctor.
+const (
+	checkOK      = "ok"
+	checkFailed  = "FAILED"
+	checkSkipped = "-"
+)
+
+type doctorReport struct {
+	name       string
+	download   string
+	handshake  string
+	properties string
+	setup      string
+	errs       []error
+}
+
+func (r *doctorReport) fail(check *string, err error) {
+	*check = checkFailed
+	r.errs = append(r.errs, err)
+}
+
+// Doctor launches every configured plugin and reports whether it could be
+// downloaded and its checksum verified, whether it completed the handshake
+// with the CLI, whether its properties match the schema it declares and
+// whether it completed setup.
+func (runner *Plugin) Doctor(ctx context.Context, cmd *cobra.Command, args []string) error {
+	plugins, err := config.UnmarshalPluginConfig(viper.Get("plugins"))
+	if err != nil {
+		return err
+	}
+
+	for _, name := range args {
+		if !slices.ContainsFunc(plugins, func(p types.PluginConfig) bool { return p.Name == name }) {
+			return fmt.Errorf("plugin %q is not configured", name)
+		}
+	}
+
+	if len(plugins) == 0 {
+		fmt.Fprintln(runner.Stdout, "No plugins are configured")
+		return nil
+	}
+
+	// Plugins write to stdout and stderr during setup, which would interleave
+	// with the report.
+	pluginStreams := ioutils.Streams{
+		Stdin:  runner.Stdin,
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+
+	lock := client.NewWorkspaceLock()
+	reports := make([]*doctorReport, 0, len(plugins))
+	for _, p := range plugins {
+		if len(args) > 0 && !slices.Contains(args, p.Name) {
+			continue
+		}
+		reports = append(reports, checkPlugin(ctx, p, lock, pluginStreams))
+	}
+
+	w := tabwriter.NewWriter(runner.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDOWNLOAD\tHANDSHAKE\tPROPERTIES\tSETUP")
+	for _, r := range reports {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.name, r.download, r.handshake, r.properties, r.setup)
+	}
+	w.Flush()
+
+	failed := 0
+	for _, r := range reports {
+		if len(r.errs) == 0 {
+			continue
+		}
+		failed++
+		fmt.Fprintf(runner.Stderr, "\n%s:\n", r.name)
+		for _, err := range r.errs {
+			fmt.Fprintf(runner.Stderr, "  %v\n", err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d plugins failed the health checks", failed, len(reports))
+	}
+	return nil
+}
+
+func checkPlugin(ctx context.Context, p types.PluginConfig, lock *client.Lock, streams ioutils.Streams) *doctorReport {
+	r := &doctorReport{
+		name:       p.Name,
+		download:   checkSkipped,
+		handshake:  checkSkipped,
+		properties: checkSkipped,
+		setup:      checkSkipped,
+	}
+
+	resolved, err := client.Resolve(p, lock)
+	if err != nil {
+		r.fail(&r.download, err)
+		return r
+	}
+	r.download = checkOK
+
+	aspectplugin, err := client.Launch(resolved, streams)
+	if err != nil {
+		r.fail(&r.handshake, err)
+		return r
+	}
+	defer aspectplugin.Kill()
+	r.handshake = checkOK
+
+	schema, err := aspectplugin.PropertiesSchema()
+	if err != nil {
+		r.fail(&r.properties, fmt.Errorf("failed to get properties schema: %w", err))
+	} else if err := validateProperties(schema, p.Properties); err != nil {
+		r.fail(&r.properties, err)
+	} else {
+		r.properties = checkOK
+	}
+
+	if err := system.SetupPlugin(ctx, p, aspectplugin); err != nil {
+		r.fail(&r.setup, err)
+	} else {
+		r.setup = checkOK
+	}
+
+	return r
+}
+
+// validateProperties checks the properties of a plugin config against the
+// schema declared by the plugin. Nothing is validated when the schema is empty.
+func validateProperties(schema []*proto.Property, properties map[string]any) error {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	var errs []error
+	declared := make(map[string]*proto.Property, len(schema))
+	for _, property := range schema {
+		declared[property.Name] = property
+		value, ok := properties[property.Name]
+		if !ok {
+			if property.Required {
+				errs = append(errs, fmt.Errorf("missing required property %q", property.Name))
+			}
+			continue
+		}
+		if !hasPropertyType(value, property.Type) {
+			errs = append(errs, fmt.Errorf("property %q must be of type %s, got %T", property.Name, strings.ToLower(property.Type.String()), value))
+		}
+	}
+
+	unknown := make([]string, 0)
+	for name := range properties {
+		if _, ok := declared[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	slices.Sort(unknown)
+	for _, name := range unknown {
+		errs = append(errs, fmt.Errorf("unknown property %q", name))
+	}
+
+	return errors.Join(errs...)
+}
+
+// hasPropertyType reports whether a value decoded from the YAML config is of
+// the given property type.
+func hasPropertyType(value any, t proto.Property_Type) bool {
+	switch t {
+	case proto.Property_STRING:
+		_, ok := value.(string)
+		return ok
+	case proto.Property_BOOL:
+		_, ok := value.(bool)
+		return ok
+	case proto.Property_INT:
+		switch v := value.(type) {
+		case int, int64, uint64:
+			return true
+		case float64:
+			return v == math.Trunc(v)
+		}
+		return false
+	case proto.Property_FLOAT:
+		switch value.(type) {
+		case float64, int, int64, uint64:
+			return true
+		}
+		return false
+	case proto.Property_LIST:
+		_, ok := value.([]any)
+		return ok
+	case proto.Property_MAP:
+		switch value.(type) {
+		case map[string]any, map[any]any:
+			return true
+		}
+		return false
+	}
+	return false
+}
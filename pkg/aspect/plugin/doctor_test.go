@@ -0,0 +1,72 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plugin
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/proto"
+)
+
+func TestValidateProperties(t *testing.T) {
+	schema := []*proto.Property{
+		{Name: "endpoint", Type: proto.Property_STRING, Required: true},
+		{Name: "retries", Type: proto.Property_INT},
+		{Name: "ratio", Type: proto.Property_FLOAT},
+		{Name: "verbose", Type: proto.Property_BOOL},
+		{Name: "targets", Type: proto.Property_LIST},
+		{Name: "env", Type: proto.Property_MAP},
+	}
+
+	t.Run("accepts properties matching the schema", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		err := validateProperties(schema, map[string]any{
+			"endpoint": "grpcs://example.com",
+			"retries":  3,
+			"ratio":    1,
+			"verbose":  true,
+			"targets":  []any{"//..."},
+			"env":      map[string]any{"FOO": "bar"},
+		})
+		g.Expect(err).ToNot(HaveOccurred())
+	})
+
+	t.Run("reports missing, unknown and mistyped properties", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		err := validateProperties(schema, map[string]any{
+			"retries": 1.5,
+			"verbose": "yes",
+			"extra":   1,
+		})
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(err.Error()).To(Equal(`missing required property "endpoint"
+property "retries" must be of type int, got float64
+property "verbose" must be of type bool, got string
+unknown property "extra"`))
+	})
+
+	t.Run("skips validation without a schema", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		err := validateProperties(nil, map[string]any{"anything": 1})
+		g.Expect(err).ToNot(HaveOccurred())
+	})
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package plugin
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/root/config"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/client"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/types"
+)
+
+type listEntry struct {
+	name     string
+	version  string
+	source   string
+	sha256   string
+	sdk      string
+	bep      string
+	commands string
+	err      error
+}
+
+// List launches every configured plugin, or only the named ones, and prints
+// its version, the source it is downloaded from, the checksum of its binary,
+// the SDK version it speaks and whether it subscribes to build events or
+// registers custom commands.
+func (runner *Plugin) List(ctx context.Context, cmd *cobra.Command, args []string) error {
+	plugins, err := config.UnmarshalPluginConfig(viper.Get("plugins"))
+	if err != nil {
+		return err
+	}
+
+	for _, name := range args {
+		if !slices.ContainsFunc(plugins, func(p types.PluginConfig) bool { return p.Name == name }) {
+			return fmt.Errorf("plugin %q is not configured", name)
+		}
+	}
+
+	if len(plugins) == 0 {
+		fmt.Fprintln(runner.Stdout, "No plugins are configured")
+		return nil
+	}
+
+	// Plugins write to stdout and stderr during setup, which would interleave
+	// with the list.
+	pluginStreams := ioutils.Streams{
+		Stdin:  runner.Stdin,
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+
+	lock := client.NewWorkspaceLock()
+	entries := make([]*listEntry, 0, len(plugins))
+	for _, p := range plugins {
+		if len(args) > 0 && !slices.Contains(args, p.Name) {
+			continue
+		}
+		entries = append(entries, listPlugin(ctx, p, lock, pluginStreams))
+	}
+
+	w := tabwriter.NewWriter(runner.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVERSION\tSOURCE\tSHA256\tSDK\tBEP EVENTS\tCOMMANDS")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", e.name, e.version, e.source, e.sha256, e.sdk, e.bep, e.commands)
+	}
+	w.Flush()
+
+	failed := 0
+	for _, e := range entries {
+		if e.err == nil {
+			continue
+		}
+		failed++
+		fmt.Fprintf(runner.Stderr, "\n%s:\n  %v\n", e.name, e.err)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d plugins could not be inspected, run 'aspect plugin doctor' for details", failed, len(entries))
+	}
+	return nil
+}
+
+func listPlugin(ctx context.Context, p types.PluginConfig, lock *client.Lock, streams ioutils.Streams) *listEntry {
+	e := &listEntry{
+		name:     p.Name,
+		version:  orSkipped(p.Version),
+		source:   client.ResolvePluginURL(p.From),
+		sha256:   checkSkipped,
+		sdk:      checkSkipped,
+		bep:      checkSkipped,
+		commands: checkSkipped,
+	}
+
+	resolved, err := client.Resolve(p, lock)
+	if err != nil {
+		e.err = err
+		return e
+	}
+	e.sha256 = hex.EncodeToString(resolved.Checksum)
+
+	aspectplugin, err := client.Launch(resolved, streams)
+	if err != nil {
+		e.err = err
+		return e
+	}
+	defer aspectplugin.Kill()
+	e.sdk = aspectplugin.SDK
+	if resolved.Config.Runtime == types.RuntimeWasm {
+		e.sdk += " (wasm)"
+	}
+
+	if err := system.SetupPlugin(ctx, p, aspectplugin); err != nil {
+		e.err = err
+		return e
+	}
+
+	eventTypes, err := aspectplugin.BEPEventTypes()
+	if err != nil {
+		e.err = fmt.Errorf("failed to get build event types: %w", err)
+		return e
+	}
+	e.bep = bepSubscription(p.DisableBESEvents, eventTypes)
+
+	commands, err := aspectplugin.CustomCommands()
+	if err != nil {
+		e.err = fmt.Errorf("failed to get custom commands: %w", err)
+		return e
+	}
+	names := make([]string, 0, len(commands))
+	for _, command := range commands {
+		names = append(names, strings.SplitN(command.Use, " ", 2)[0])
+	}
+	e.commands = orSkipped(strings.Join(names, ","))
+
+	return e
+}
+
+// bepSubscription describes the build events a plugin subscribes to.
+func bepSubscription(disabled bool, eventTypes []string) string {
+	switch {
+	case disabled:
+		return "none"
+	case len(eventTypes) == 0:
+		return "all"
+	}
+	return strings.Join(eventTypes, ",")
+}
+
+func orSkipped(s string) string {
+	if s == "" {
+		return checkSkipped
+	}
+	return s
+}
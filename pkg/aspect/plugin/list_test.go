@@ -0,0 +1,30 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package plugin
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestBEPSubscription(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(bepSubscription(true, []string{"test_result"})).To(Equal("none"))
+	g.Expect(bepSubscription(false, nil)).To(Equal("all"))
+	g.Expect(bepSubscription(false, []string{"test_result", "target_completed"})).To(Equal("test_result,target_completed"))
+}
@@ -0,0 +1,221 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/root/config"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/client"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/types"
+)
+
+var faint = color.New(color.Faint)
+
+type Plugin struct {
+	ioutils.Streams
+}
+
+func New(streams ioutils.Streams) *Plugin {
+	return &Plugin{
+		Streams: streams,
+	}
+}
+
+func (runner *Plugin) registry(cmd *cobra.Command) (*Registry, error) {
+	url, err := cmd.Flags().GetString("registry")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get value of --registry flag: %w", err)
+	}
+	return FetchRegistry(url)
+}
+
+// Search lists the plugins in the registry that match the optional search term.
+func (runner *Plugin) Search(ctx context.Context, cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("expected at most one search term, got %v", len(args))
+	}
+
+	registry, err := runner.registry(cmd)
+	if err != nil {
+		return err
+	}
+
+	term := ""
+	if len(args) == 1 {
+		term = args[0]
+	}
+
+	results := registry.Search(term)
+	if len(results) == 0 {
+		fmt.Fprintf(runner.Stderr, "No plugins found matching %q\n", term)
+		return nil
+	}
+	for _, p := range results {
+		fmt.Fprintf(runner.Stdout, "%s\t%s\n", p.Name, faint.Sprint(p.Repository))
+	}
+	return nil
+}
+
+// Install adds a plugin from the registry to the workspace config, pinning its
+// version and the checksums of its release binaries.
+func (runner *Plugin) Install(ctx context.Context, cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected a single plugin name, optionally followed by @version")
+	}
+	name, version, _ := strings.Cut(args[0], "@")
+
+	registry, err := runner.registry(cmd)
+	if err != nil {
+		return err
+	}
+	entry := registry.Find(name)
+	if entry == nil {
+		return fmt.Errorf("plugin %q not found in the registry, try 'aspect plugin search'", name)
+	}
+	from, err := entry.From()
+	if err != nil {
+		return err
+	}
+
+	plugins, err := loadWorkspacePlugins()
+	if err != nil {
+		return err
+	}
+
+	p := types.PluginConfig{Name: name}
+	if i := slices.IndexFunc(plugins, func(p types.PluginConfig) bool { return p.Name == name }); i != -1 {
+		// Preserve any properties and other settings of an existing entry.
+		p = plugins[i]
+	}
+	p.From = from
+
+	if err := runner.pin(&p, version); err != nil {
+		return err
+	}
+
+	plugins, _ = config.AddPlugins(plugins, []types.PluginConfig{p})
+	configFile, _, err := config.SetInWorkspaceConfig("plugins", config.MarshalPluginConfig(plugins))
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", configFile, err)
+	}
+
+	fmt.Fprintf(runner.Stdout, "Installed %s %s in %s\n", p.Name, p.Version, configFile)
+	return nil
+}
+
+// Update upgrades plugins configured in the workspace config to their latest
+// release. All plugins hosted on GitHub are updated unless names are given.
+func (runner *Plugin) Update(ctx context.Context, cmd *cobra.Command, args []string) error {
+	plugins, err := loadWorkspacePlugins()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range args {
+		if !slices.ContainsFunc(plugins, func(p types.PluginConfig) bool { return p.Name == name }) {
+			return fmt.Errorf("plugin %q is not configured in the workspace config", name)
+		}
+	}
+
+	updated := false
+	for i := range plugins {
+		p := &plugins[i]
+		if len(args) > 0 && !slices.Contains(args, p.Name) {
+			continue
+		}
+		if !strings.HasPrefix(p.From, "github.com/") {
+			if len(args) > 0 {
+				return fmt.Errorf("cannot update plugin %q: only github.com/org/repo plugins can be updated", p.Name)
+			}
+			continue
+		}
+
+		previous := p.Version
+		if err := runner.pin(p, ""); err != nil {
+			return err
+		}
+		if p.Version == previous {
+			fmt.Fprintf(runner.Stdout, "%s is up to date at %s\n", p.Name, p.Version)
+			continue
+		}
+		fmt.Fprintf(runner.Stdout, "Updated %s %s -> %s\n", p.Name, previous, p.Version)
+		updated = true
+	}
+
+	if !updated {
+		return nil
+	}
+
+	configFile, _, err := config.SetInWorkspaceConfig("plugins", config.MarshalPluginConfig(plugins))
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", configFile, err)
+	}
+	return nil
+}
+
+// pin sets the version of the plugin, resolving the latest release when version
+// is empty, along with the checksums of its release binaries.
+func (runner *Plugin) pin(p *types.PluginConfig, version string) error {
+	var err error
+	if version == "" {
+		version, err = LatestGitHubRelease(p.From)
+		if err != nil {
+			return err
+		}
+	}
+
+	sha256 := map[string]string{}
+	pluginRuntime := p.Runtime
+	if pluginRuntime != types.RuntimeWasm {
+		for _, platform := range client.SupportedPlatforms {
+			// Not every plugin is published for every platform.
+			if sum, err := client.FetchPluginChecksum(p.From, p.Name, version, platform); err == nil {
+				sha256[platform] = sum
+			}
+		}
+	}
+	if len(sha256) == 0 {
+		// Fall back to a portable WebAssembly release.
+		sum, err := client.FetchPluginChecksum(p.From, p.Name, version, client.WasmPlatform)
+		if err != nil {
+			return fmt.Errorf("no checksums are published for %s %s: %w", p.Name, version, err)
+		}
+		sha256[client.WasmPlatform] = sum
+		pluginRuntime = types.RuntimeWasm
+	}
+
+	p.Version = version
+	p.Runtime = pluginRuntime
+	p.SHA256 = sha256
+	return nil
+}
+
+func loadWorkspacePlugins() ([]types.PluginConfig, error) {
+	workspaceConfig, err := config.LoadWorkspaceConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load workspace config file: %w", err)
+	}
+	return config.UnmarshalPluginConfig(workspaceConfig.Get("plugins"))
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bazelbuild/bazelisk/httputil"
+)
+
+// DefaultRegistryURL is the plugin catalog maintained in /docs/plugins/plugins.json.
+const DefaultRegistryURL = "https://raw.githubusercontent.com/aspect-build/aspect-cli-legacy/main/docs/plugins/plugins.json"
+
+// Registry is the plugin catalog, see /docs/plugins/plugins.schema.json.
+type Registry struct {
+	Plugins []RegistryEntry `json:"plugins"`
+}
+
+// RegistryEntry is a single plugin listed in the Registry.
+type RegistryEntry struct {
+	Name        string       `json:"name"`
+	Repository  string       `json:"repository"`
+	Homepage    string       `json:"homepage,omitempty"`
+	Maintainers []Maintainer `json:"maintainers"`
+}
+
+// Maintainer of a RegistryEntry.
+type Maintainer struct {
+	Name   string `json:"name,omitempty"`
+	Email  string `json:"email,omitempty"`
+	GitHub string `json:"github,omitempty"`
+}
+
+// FetchRegistry loads the Registry from an http(s) URL or a local file.
+func FetchRegistry(url string) (*Registry, error) {
+	var b []byte
+	var err error
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		b, _, err = httputil.ReadRemoteFile(url, "")
+	} else {
+		b, err = os.ReadFile(url)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch plugin registry %s: %w", url, err)
+	}
+
+	registry := &Registry{}
+	if err := json.Unmarshal(b, registry); err != nil {
+		return nil, fmt.Errorf("failed to parse plugin registry %s: %w", url, err)
+	}
+	return registry, nil
+}
+
+// Find returns the entry with the given name or nil if there is none.
+func (r *Registry) Find(name string) *RegistryEntry {
+	for i := range r.Plugins {
+		if r.Plugins[i].Name == name {
+			return &r.Plugins[i]
+		}
+	}
+	return nil
+}
+
+// Search returns the entries whose name or repository contain the term.
+func (r *Registry) Search(term string) []RegistryEntry {
+	term = strings.ToLower(term)
+	results := []RegistryEntry{}
+	for _, p := range r.Plugins {
+		if strings.Contains(strings.ToLower(p.Name), term) || strings.Contains(strings.ToLower(p.Repository), term) {
+			results = append(results, p)
+		}
+	}
+	return results
+}
+
+// GitHubRepository returns the org/repo of an entry hosted on GitHub.
+func (e *RegistryEntry) GitHubRepository() (string, error) {
+	repo, ok := strings.CutPrefix(e.Repository, "github:")
+	if !ok {
+		return "", fmt.Errorf("plugin %q is not hosted on GitHub: %s", e.Name, e.Repository)
+	}
+	return repo, nil
+}
+
+// From returns the value of the 'from' plugin config attribute for the entry.
+func (e *RegistryEntry) From() (string, error) {
+	repo, err := e.GitHubRepository()
+	if err != nil {
+		return "", err
+	}
+	return "github.com/" + repo, nil
+}
+
+// LatestGitHubRelease returns the tag of the latest release of a github.com/org/repo 'from' attribute.
+func LatestGitHubRelease(from string) (string, error) {
+	repo, ok := strings.CutPrefix(from, "github.com/")
+	if !ok {
+		return "", fmt.Errorf("cannot determine the latest version of %s, only github.com/org/repo plugins are supported", from)
+	}
+
+	url := fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", repo)
+	b, _, err := httputil.ReadRemoteFile(url, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch the latest release of %s: %w", repo, err)
+	}
+
+	release := struct {
+		TagName string `json:"tag_name"`
+	}{}
+	if err := json.Unmarshal(b, &release); err != nil {
+		return "", fmt.Errorf("failed to parse the latest release of %s: %w", repo, err)
+	}
+	if release.TagName == "" {
+		return "", fmt.Errorf("no releases found for %s", repo)
+	}
+	return release.TagName, nil
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plugin_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/plugin"
+)
+
+const registryJSON = `{
+  "plugins": [
+    {
+      "name": "fix-visibility",
+      "repository": "github:aspect-build/plugin-fix-visibility",
+      "maintainers": [{ "name": "Aspect team" }]
+    },
+    {
+      "name": "augment-error",
+      "repository": "github:aspect-build/plugin-augment-error",
+      "maintainers": [{ "name": "Aspect team" }]
+    },
+    {
+      "name": "elsewhere",
+      "repository": "gitlab:org/elsewhere",
+      "maintainers": []
+    }
+  ]
+}`
+
+func TestRegistry(t *testing.T) {
+	registryFile := filepath.Join(t.TempDir(), "plugins.json")
+	if err := os.WriteFile(registryFile, []byte(registryJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("loads a registry from a local file", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		registry, err := plugin.FetchRegistry(registryFile)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(registry.Plugins).To(HaveLen(3))
+		g.Expect(registry.Plugins[0].Maintainers[0].Name).To(Equal("Aspect team"))
+	})
+
+	t.Run("searches by name and repository", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		registry, err := plugin.FetchRegistry(registryFile)
+		g.Expect(err).ToNot(HaveOccurred())
+
+		g.Expect(registry.Search("")).To(HaveLen(3))
+		g.Expect(registry.Search("VISIBILITY")).To(HaveLen(1))
+		g.Expect(registry.Search("aspect-build")).To(HaveLen(2))
+		g.Expect(registry.Search("nothing")).To(BeEmpty())
+	})
+
+	t.Run("resolves the from attribute of GitHub plugins", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		registry, err := plugin.FetchRegistry(registryFile)
+		g.Expect(err).ToNot(HaveOccurred())
+
+		from, err := registry.Find("augment-error").From()
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(from).To(Equal("github.com/aspect-build/plugin-augment-error"))
+
+		_, err = registry.Find("elsewhere").From()
+		g.Expect(err).To(HaveOccurred())
+
+		g.Expect(registry.Find("missing")).To(BeNil())
+	})
+}
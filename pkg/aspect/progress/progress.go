@@ -0,0 +1,244 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package progress
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+)
+
+// BazelFlags make bazel report its status in the progress events as lines
+// rather than redrawing it with cursor movements.
+var BazelFlags = []string{"--curses=no"}
+
+// maxActions and maxFetches bound the running actions and the downloads the
+// status lists.
+const (
+	maxActions = 8
+	maxFetches = 3
+)
+
+// lastEventTimeout bounds how long the renderer waits for the build events
+// that are still being streamed once bazel has exited.
+const lastEventTimeout = 10 * time.Second
+
+var (
+	// statusHeader matches the first line of a status bazel reports, which
+	// is followed by indented lines of the running actions and downloads.
+	statusHeader = regexp.MustCompile(`^(\[[\d,]+ / [\d,]+\] |(Loading|Analyzing|Computing main repo mapping): )`)
+	// actionCounts matches the actions bazel reports in the first line of a
+	// status while it executes them, e.g. "[12 / 345] 16 actions, 8 running"
+	// or "[12 / 345] 4 actions running".
+	actionCounts = regexp.MustCompile(`^\[([\d,]+) / ([\d,]+)\] (\d+) actions?(, (\d+))? running`)
+	ansiEscape   = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+)
+
+// Renderer draws the progress of a build from its build events instead of the
+// output bazel writes to the terminal: the actions running and queued and the
+// downloads from the status bazel reports in the progress events, and the
+// targets completed. The rest of the output of bazel is printed above it.
+type Renderer struct {
+	mu sync.Mutex
+	// section is where the progress is drawn.
+	section *Section
+	// partial is the end of the output of the last progress event, which
+	// wasn't a complete line yet.
+	partial string
+	// status is the last status bazel reported, and inStatus is set while
+	// its lines are read.
+	status   status
+	inStatus bool
+	// targets and completed count the targets configured and completed.
+	targets   int
+	completed int
+	// streaming is set once the build events are received, and finished
+	// once the renderer stopped rendering them.
+	streaming bool
+	finished  bool
+	done      chan struct{}
+	closeDone sync.Once
+}
+
+// status is a status bazel reported in its output.
+type status struct {
+	header  string
+	actions []string
+	fetches []string
+}
+
+// New creates a Renderer that draws the progress in a section of region.
+func New(region *Region) *Renderer {
+	return &Renderer{
+		section: region.Section(),
+		done:    make(chan struct{}),
+	}
+}
+
+// Callback renders a build event. It is a bep.CallbackFn.
+func (r *Renderer) Callback(event *buildeventstream.BuildEvent, _ int64, _ bep.StreamInfo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.finished {
+		return nil
+	}
+	r.streaming = true
+
+	var output strings.Builder
+	switch {
+	case event.GetProgress() != nil:
+		output.WriteString(event.GetProgress().GetStdout())
+		r.parse(&output, event.GetProgress().GetStderr())
+	case event.GetConfigured() != nil:
+		r.targets++
+	case event.GetCompleted() != nil:
+		if event.GetId().GetTargetCompleted().GetAspect() == "" {
+			r.completed++
+		}
+	}
+
+	if event.GetLastMessage() {
+		if r.partial != "" {
+			r.line(&output, r.partial)
+			r.partial = ""
+		}
+		r.status, r.inStatus = status{}, false
+		r.closeDone.Do(func() { close(r.done) })
+	}
+	r.section.Print(output.String(), r.lines())
+	return nil
+}
+
+// Stderr returns the writer of the output bazel writes to the terminal, which
+// is written to w until the build events are received, e.g. the errors in the
+// command line, and dropped afterwards since it's rendered from them.
+func (r *Renderer) Stderr(w io.Writer) io.Writer {
+	return &bazelOutput{renderer: r, w: w}
+}
+
+type bazelOutput struct {
+	renderer *Renderer
+	w        io.Writer
+}
+
+func (o *bazelOutput) Write(p []byte) (int, error) {
+	o.renderer.mu.Lock()
+	defer o.renderer.mu.Unlock()
+	if o.renderer.streaming {
+		return len(p), nil
+	}
+	return o.w.Write(p)
+}
+
+// Finish waits for the last build event, or what was received so far if it
+// doesn't arrive in time, and erases the progress. It doesn't wait when no
+// build events were received, since bazel's output was printed then.
+func (r *Renderer) Finish() {
+	r.mu.Lock()
+	streaming := r.streaming
+	r.mu.Unlock()
+	if streaming {
+		select {
+		case <-r.done:
+		case <-time.After(lastEventTimeout):
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finished = true
+	output := r.partial
+	if output != "" {
+		output += "\n"
+	}
+	r.partial = ""
+	r.status = status{}
+	r.section.Print(output, nil)
+}
+
+// parse reads the lines of the output of bazel, and writes those that are
+// not part of a status to output. r.mu must be held.
+func (r *Renderer) parse(output *strings.Builder, stderr string) {
+	lines := strings.Split(r.partial+stderr, "\n")
+	r.partial = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		r.line(output, line)
+	}
+}
+
+// line reads a complete line of the output of bazel. r.mu must be held.
+func (r *Renderer) line(output *strings.Builder, line string) {
+	plain := strings.TrimRight(ansiEscape.ReplaceAllString(line, ""), "\r")
+	switch {
+	case statusHeader.MatchString(plain):
+		r.status = status{header: plain}
+		r.inStatus = true
+	case r.inStatus && strings.HasPrefix(plain, "    "):
+		entry := strings.TrimSpace(plain)
+		if strings.HasPrefix(entry, "Fetching ") {
+			r.status.fetches = append(r.status.fetches, entry)
+		} else {
+			r.status.actions = append(r.status.actions, entry)
+		}
+	default:
+		r.inStatus = false
+		output.WriteString(line)
+		output.WriteString("\n")
+	}
+}
+
+// lines returns the lines of the progress. r.mu must be held.
+func (r *Renderer) lines() []string {
+	if r.status.header == "" {
+		return nil
+	}
+	header := r.status.header
+	if m := actionCounts.FindStringSubmatch(header); m != nil {
+		actions, _ := strconv.Atoi(m[3])
+		running := actions
+		if m[5] != "" {
+			running, _ = strconv.Atoi(m[5])
+		}
+		header = fmt.Sprintf("[%s / %s] %d running, %d queued", m[1], m[2], running, actions-running)
+	}
+	if r.targets > 0 {
+		header += fmt.Sprintf(" · %d of %d targets", r.completed, r.targets)
+	}
+
+	lines := []string{header}
+	lines = appendEntries(lines, r.status.actions, maxActions, "actions")
+	lines = appendEntries(lines, r.status.fetches, maxFetches, "downloads")
+	return lines
+}
+
+// appendEntries appends up to limit entries to lines, indented, followed by
+// how many of them are left out.
+func appendEntries(lines []string, entries []string, limit int, what string) []string {
+	for _, entry := range entries[:min(len(entries), limit)] {
+		lines = append(lines, "  "+entry)
+	}
+	if len(entries) > limit {
+		lines = append(lines, fmt.Sprintf("  ... and %d more %s", len(entries)-limit, what))
+	}
+	return lines
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package progress
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+)
+
+func progressEvent(stderr string) *buildeventstream.BuildEvent {
+	return &buildeventstream.BuildEvent{
+		Id:      &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_Progress{}},
+		Payload: &buildeventstream.BuildEvent_Progress{Progress: &buildeventstream.Progress{Stderr: stderr}},
+	}
+}
+
+func TestRenderer(t *testing.T) {
+	status := "[1,234 / 5,678] 12 actions, 3 running\n" +
+		"    Compiling src/a.cc; 3s linux-sandbox\n" +
+		"    GoCompilePkg pkg/b.a; 2s remote\n" +
+		"    Fetching https://example.com/c.tar.gz; 12.3 MiB (45%)\n"
+
+	t.Run("draws the status below the output on a terminal", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var out bytes.Buffer
+		r := New(&Region{out: &out, terminal: true})
+		r.Callback(&buildeventstream.BuildEvent{Payload: &buildeventstream.BuildEvent_Configured{Configured: &buildeventstream.TargetConfigured{}}}, 0, bep.StreamInfo{})
+		r.Callback(&buildeventstream.BuildEvent{Payload: &buildeventstream.BuildEvent_Configured{Configured: &buildeventstream.TargetConfigured{}}}, 0, bep.StreamInfo{})
+		r.Callback(&buildeventstream.BuildEvent{
+			Id:      &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_TargetCompleted{TargetCompleted: &buildeventstream.BuildEventId_TargetCompletedId{}}},
+			Payload: &buildeventstream.BuildEvent_Completed{Completed: &buildeventstream.TargetComplete{}},
+		}, 0, bep.StreamInfo{})
+		out.Reset()
+
+		r.Callback(progressEvent("INFO: Analyzed 2 targets\n"+status), 0, bep.StreamInfo{})
+		g.Expect(out.String()).To(Equal("INFO: Analyzed 2 targets\n" +
+			"[1,234 / 5,678] 3 running, 9 queued · 1 of 2 targets\n" +
+			"  Compiling src/a.cc; 3s linux-sandbox\n" +
+			"  GoCompilePkg pkg/b.a; 2s remote\n" +
+			"  Fetching https://example.com/c.tar.gz; 12.3 MiB (45%)\n"))
+		out.Reset()
+
+		r.Callback(progressEvent("ERROR: src/a.cc failed\n[1,240 / 5,678] 2 actions running\n"), 0, bep.StreamInfo{})
+		g.Expect(out.String()).To(Equal(strings.Repeat("\x1b[1A\x1b[2K", 4) +
+			"ERROR: src/a.cc failed\n" +
+			"[1,240 / 5,678] 2 running, 0 queued · 1 of 2 targets\n"))
+	})
+
+	t.Run("erases the status once the last build event is received", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var out bytes.Buffer
+		r := New(&Region{out: &out, terminal: true})
+		r.Callback(progressEvent(status), 0, bep.StreamInfo{})
+		out.Reset()
+
+		last := progressEvent("INFO: Build completed successfully")
+		last.LastMessage = true
+		r.Callback(last, 0, bep.StreamInfo{})
+		r.Finish()
+
+		g.Expect(out.String()).To(Equal(strings.Repeat("\x1b[1A\x1b[2K", 4) + "INFO: Build completed successfully\n"))
+	})
+
+	t.Run("lists a bounded number of actions", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var out bytes.Buffer
+		r := New(&Region{out: &out, terminal: true, width: 40})
+		r.Callback(progressEvent("[1 / 20] 10 actions running\n"+strings.Repeat("    Compiling a_very_long_file_name_that_is_truncated.cc; 1s\n", 10)), 0, bep.StreamInfo{})
+
+		lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+		g.Expect(lines).To(HaveLen(1 + maxActions + 1))
+		g.Expect(lines[1]).To(Equal("  Compiling a_very_long_file_name_that_…"))
+		g.Expect(lines[len(lines)-1]).To(Equal("  ... and 2 more actions"))
+	})
+
+	t.Run("only prints the output when not on a terminal", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var out bytes.Buffer
+		r := New(&Region{out: &out})
+		r.Callback(progressEvent("\x1b[32mINFO:\x1b[0m Analyzed 2 targets\n"+status+"Target //:a up-to-date:\n  bazel-bin/a\n"), 0, bep.StreamInfo{})
+
+		g.Expect(out.String()).To(Equal("\x1b[32mINFO:\x1b[0m Analyzed 2 targets\nTarget //:a up-to-date:\n  bazel-bin/a\n"))
+	})
+
+	t.Run("passes the output of bazel through until the build events are received", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var out, stderr bytes.Buffer
+		r := New(&Region{out: &out})
+		w := r.Stderr(&stderr)
+
+		w.Write([]byte("Starting local Bazel server and connecting to it...\n"))
+		r.Callback(progressEvent(""), 0, bep.StreamInfo{})
+		w.Write([]byte(status))
+
+		g.Expect(stderr.String()).To(Equal("Starting local Bazel server and connecting to it...\n"))
+	})
+
+	t.Run("doesn't wait when no build events were received", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var out bytes.Buffer
+		r := New(&Region{out: &out, terminal: true})
+		r.Finish()
+		r.Callback(progressEvent("INFO: late\n"), 0, bep.StreamInfo{})
+
+		g.Expect(out.String()).To(BeEmpty())
+	})
+}
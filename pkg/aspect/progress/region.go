@@ -0,0 +1,140 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package progress
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// Region is the bottom of a terminal, on which the lines of its sections are
+// drawn below the output written through it. They are erased before each
+// write and drawn again after it, once the output ends with a complete line.
+// Only the output is written when it's not a terminal.
+type Region struct {
+	mu  sync.Mutex
+	out io.Writer
+	// terminal is set when out is a terminal, and width bounds the length
+	// of the lines drawn on it so that they don't wrap.
+	terminal bool
+	width    int
+	sections []*Section
+	// drawn is the number of lines drawn, and midLine is set when the output
+	// doesn't end with a complete line, which they can't be drawn below.
+	drawn   int
+	midLine bool
+	closed  bool
+}
+
+// Section is the lines of a Region drawn by one of its owners.
+type Section struct {
+	region *Region
+	lines  []string
+}
+
+// NewRegion creates a Region at the bottom of w.
+func NewRegion(w io.Writer) *Region {
+	r := &Region{out: w}
+	if f, ok := w.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		r.terminal = true
+		r.width, _, _ = term.GetSize(int(f.Fd()))
+	}
+	return r
+}
+
+// Section adds a section below the sections of the region.
+func (r *Region) Section() *Section {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := &Section{region: r}
+	r.sections = append(r.sections, s)
+	return s
+}
+
+// Write writes p above the sections.
+func (r *Region) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.update(p, nil, nil)
+}
+
+// Close erases the sections, which are no longer drawn.
+func (r *Region) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	r.update(nil, nil, nil)
+}
+
+// Set replaces the lines of the section.
+func (s *Section) Set(lines []string) {
+	s.region.mu.Lock()
+	defer s.region.mu.Unlock()
+	s.region.update(nil, s, lines)
+}
+
+// Print writes output above the sections and replaces the lines of the
+// section.
+func (s *Section) Print(output string, lines []string) {
+	s.region.mu.Lock()
+	defer s.region.mu.Unlock()
+	s.region.update([]byte(output), s, lines)
+}
+
+// update erases the sections, writes output, replaces the lines of s and
+// draws the sections again. r.mu must be held.
+func (r *Region) update(output []byte, s *Section, lines []string) (int, error) {
+	if s != nil {
+		s.lines = lines
+	}
+	if !r.terminal {
+		return r.out.Write(output)
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.Repeat("\x1b[1A\x1b[2K", r.drawn))
+	b.Write(output)
+	if len(output) > 0 {
+		r.midLine = output[len(output)-1] != '\n'
+	}
+	r.drawn = 0
+	if !r.midLine && !r.closed {
+		for _, s := range r.sections {
+			for _, line := range s.lines {
+				b.WriteString(truncate(line, r.width))
+				b.WriteString("\n")
+				r.drawn++
+			}
+		}
+	}
+	if _, err := io.WriteString(r.out, b.String()); err != nil {
+		return 0, err
+	}
+	return len(output), nil
+}
+
+// truncate shortens s to width runes, unless width is unknown.
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if width <= 0 || len(runes) < width {
+		return s
+	}
+	return string(runes[:width-1]) + "…"
+}
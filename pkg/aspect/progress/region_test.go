@@ -0,0 +1,81 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package progress
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestRegion(t *testing.T) {
+	erase := "\x1b[1A\x1b[2K"
+
+	t.Run("draws the sections below the output", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var out bytes.Buffer
+		r := &Region{out: &out, terminal: true}
+		first, second := r.Section(), r.Section()
+		second.Set([]string{"b"})
+		first.Set([]string{"a"})
+		r.Write([]byte("output\n"))
+
+		g.Expect(out.String()).To(Equal("b\n" + erase + "a\nb\n" + erase + erase + "output\na\nb\n"))
+	})
+
+	t.Run("waits for the output to end with a complete line", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var out bytes.Buffer
+		r := &Region{out: &out, terminal: true}
+		r.Section().Set([]string{"a"})
+		out.Reset()
+
+		r.Write([]byte("partial"))
+		r.Write([]byte(" line\n"))
+
+		g.Expect(out.String()).To(Equal(erase + "partial line\na\n"))
+	})
+
+	t.Run("erases the sections once closed", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var out bytes.Buffer
+		r := &Region{out: &out, terminal: true}
+		s := r.Section()
+		s.Set([]string{"a", "b"})
+		out.Reset()
+
+		r.Close()
+		s.Set([]string{"c"})
+		r.Write([]byte("output\n"))
+
+		g.Expect(out.String()).To(Equal(erase + erase + "output\n"))
+	})
+
+	t.Run("only writes the output when not on a terminal", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var out bytes.Buffer
+		r := NewRegion(&out)
+		r.Section().Set([]string{"a"})
+		r.Write([]byte("output\n"))
+
+		g.Expect(out.String()).To(Equal("output\n"))
+	})
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package ps
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/run/detached"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+)
+
+// Ps represents the aspect ps command.
+type Ps struct {
+	ioutils.Streams
+	root func() (string, error)
+}
+
+// New creates a Ps command listing the run targets detached in the workspace.
+func New(streams ioutils.Streams) *Ps {
+	return &Ps{
+		Streams: streams,
+		root:    detached.Root,
+	}
+}
+
+// Run lists the run targets started with aspect run --detach and whether they
+// are still running.
+func (runner *Ps) Run(ctx context.Context, cmd *cobra.Command, args []string) error {
+	root, err := runner.root()
+	if err != nil {
+		return err
+	}
+	if root == "" {
+		return fmt.Errorf("detached run targets are only tracked inside a workspace")
+	}
+	processes, err := detached.List(root)
+	if err != nil {
+		return err
+	}
+
+	if len(processes) == 0 {
+		fmt.Fprintln(runner.Stdout, "No run targets were detached")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(runner.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TARGET\tPID\tSTATUS\tLOG")
+	for _, p := range processes {
+		status := "exited"
+		if p.Running {
+			status = "running"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Target, strconv.Itoa(p.Pid), status, p.LogPath)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package ps
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/run/detached"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+)
+
+func TestPs(t *testing.T) {
+	t.Run("lists the detached run targets", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		root := t.TempDir()
+		g.Expect(os.WriteFile(detached.ScriptPath(root, "//app:server"), []byte("#!/bin/sh\nexec sleep 60\n"), 0755)).To(Succeed())
+		p, err := detached.Start(root, "//app:server", os.Environ())
+		g.Expect(err).ToNot(HaveOccurred())
+		defer detached.Stop(root, p)
+
+		var stdout bytes.Buffer
+		runner := New(ioutils.Streams{Stdout: &stdout})
+		runner.root = func() (string, error) { return root, nil }
+		g.Expect(runner.Run(context.Background(), nil, nil)).To(Succeed())
+		g.Expect(stdout.String()).To(HavePrefix("TARGET        PID"))
+		g.Expect(stdout.String()).To(MatchRegexp(`//app:server  \d+ +running  `))
+	})
+
+	t.Run("tells when no run target was detached", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		root := t.TempDir()
+
+		var stdout bytes.Buffer
+		runner := New(ioutils.Streams{Stdout: &stdout})
+		runner.root = func() (string, error) { return root, nil }
+		g.Expect(runner.Run(context.Background(), nil, nil)).To(Succeed())
+		g.Expect(stdout.String()).To(Equal("No run targets were detached\n"))
+	})
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package rerun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/history"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspecterrors"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+)
+
+type Rerun struct {
+	ioutils.Streams
+}
+
+func New(streams ioutils.Streams) *Rerun {
+	return &Rerun{
+		Streams: streams,
+	}
+}
+
+// Run runs the last invocation of the workspace again, or with --failed only
+// its targets and tests that failed, with the same flags.
+func (runner *Rerun) Run(ctx context.Context, cmd *cobra.Command, args []string) error {
+	failed, err := cmd.Flags().GetBool("failed")
+	if err != nil {
+		return err
+	}
+
+	inv, err := lastInvocation()
+	if err != nil {
+		return err
+	}
+	if failed && len(inv.Failed) == 0 {
+		fmt.Fprintf(runner.Stderr, "Nothing failed in the last invocation (%s)\n", inv.ID)
+		return nil
+	}
+	rerunArgs := Args(*inv, failed)
+
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the aspect executable: %w", err)
+	}
+	fmt.Fprintf(runner.Stderr, "Rerunning aspect %s\n", strings.Join(rerunArgs, " "))
+	c := exec.CommandContext(ctx, self, rerunArgs...)
+	c.Stdin = runner.Stdin
+	c.Stdout = runner.Stdout
+	c.Stderr = runner.Stderr
+	if err := c.Run(); err != nil {
+		// The command has printed why it failed, so only its exit code is
+		// propagated.
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return &aspecterrors.ExitError{ExitCode: exitErr.ExitCode()}
+		}
+		return fmt.Errorf("failed to rerun aspect %s: %w", inv.Command, err)
+	}
+	return nil
+}
+
+// lastInvocation returns the last invocation recorded in the history of the
+// workspace.
+func lastInvocation() (*history.Invocation, error) {
+	path, err := history.StorePath()
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, fmt.Errorf("invocations can only be rerun within a bazel workspace")
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no invocations are recorded; is %s set to false in the Aspect CLI config?", history.Key)
+	}
+	store, err := history.OpenStore(path, true)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	invocations, err := store.List(1)
+	if err != nil {
+		return nil, err
+	}
+	if len(invocations) == 0 {
+		return nil, fmt.Errorf("no invocations are recorded")
+	}
+	return &invocations[0], nil
+}
+
+// targetPatternFileFlag reads the target patterns from a file, which are
+// replaced along with those of the command line.
+const targetPatternFileFlag = "--target_pattern_file"
+
+// Args returns the arguments of the aspect command that runs an invocation
+// again. With failedOnly, its target patterns are replaced by the labels of
+// its targets and tests that failed, unless it is a run command, whose
+// arguments after the target are those of the binary.
+func Args(inv history.Invocation, failedOnly bool) []string {
+	args := slices.Clone(inv.Args)
+	if !failedOnly || inv.Command == "run" {
+		return append([]string{inv.Command}, args...)
+	}
+
+	var kept []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case slices.Contains(inv.Targets, arg):
+		case arg == targetPatternFileFlag:
+			i++
+		case strings.HasPrefix(arg, targetPatternFileFlag+"="):
+		default:
+			kept = append(kept, arg)
+		}
+	}
+	// A -- only remains if it separated the target patterns from the flags.
+	if len(kept) > 0 && kept[len(kept)-1] == "--" {
+		kept = kept[:len(kept)-1]
+	}
+	return append(append([]string{inv.Command}, kept...), inv.Failed...)
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package rerun
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/history"
+)
+
+func TestArgs(t *testing.T) {
+	t.Run("runs the invocation again as it was", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		inv := history.Invocation{
+			Command: "test",
+			Args:    []string{"//...", "--config=ci"},
+			Targets: []string{"//..."},
+			Failed:  []string{"//a:test"},
+		}
+		g.Expect(Args(inv, false)).To(Equal([]string{"test", "//...", "--config=ci"}))
+	})
+
+	t.Run("replaces the target patterns with the targets that failed", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		inv := history.Invocation{
+			Command: "test",
+			Args:    []string{"--config=ci", "--test_output", "errors", "--", "//...", "-//slow/..."},
+			Targets: []string{"//...", "-//slow/..."},
+			Failed:  []string{"//lib:broken", "//a:test"},
+		}
+		g.Expect(Args(inv, true)).To(Equal([]string{"test", "--config=ci", "--test_output", "errors", "//lib:broken", "//a:test"}))
+	})
+
+	t.Run("replaces the target pattern file", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		inv := history.Invocation{
+			Command: "build",
+			Args:    []string{"--target_pattern_file", "targets.txt", "--keep_going", "--target_pattern_file=more.txt"},
+			Failed:  []string{"//lib:broken"},
+		}
+		g.Expect(Args(inv, true)).To(Equal([]string{"build", "--keep_going", "//lib:broken"}))
+	})
+
+	t.Run("runs a binary again as it was", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		inv := history.Invocation{
+			Command: "run",
+			Args:    []string{"//tools:gen", "--", "//..."},
+			Targets: []string{"//tools:gen"},
+			Failed:  []string{"//tools:gen"},
+		}
+		g.Expect(Args(inv, true)).To(Equal([]string{"run", "//tools:gen", "--", "//..."}))
+	})
+}
@@ -19,10 +19,13 @@ package config
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path"
 	"runtime"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/root/flags"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel/workspace"
@@ -37,6 +40,8 @@ type ConfigFlagValues struct {
 	SystemConfig    bool
 	WorkspaceConfig bool
 	HomeConfig      bool
+	DisabledPlugins []string
+	NoPlugins       bool
 }
 
 func AddPlugins(plugins []types.PluginConfig, new []types.PluginConfig) ([]types.PluginConfig, error) {
@@ -56,6 +61,106 @@ func AddPlugins(plugins []types.PluginConfig, new []types.PluginConfig) ([]types
 	return plugins, nil
 }
 
+// PluginConfigLayer is the plugins config of one of the config files that are
+// merged by MergePluginConfig.
+type PluginConfigLayer struct {
+	// Name describes the config file in errors, e.g. "home config file".
+	Name string
+	// Plugins is the plugins list of the config file.
+	Plugins any
+	// Workspace is set for the workspace config file.
+	Workspace bool
+	// Home is set for the home config file.
+	Home bool
+}
+
+// pinnedPluginSettings are the settings of a plugin declared in the workspace
+// config file that the home config file cannot override, so that a workspace
+// can pin the version of a plugin that is also configured in the home config.
+var pinnedPluginSettings = []string{"from", "version", "sha256"}
+
+// MergePluginConfig merges the plugins of the given config files, in
+// increasing preference:
+//
+//   - Plugins are deduplicated by name and keep the position they are first
+//     declared at.
+//   - Each setting of a plugin entry overrides the same setting of the entries
+//     for that plugin in earlier config files, so that an entry only needs to
+//     list the settings it changes.
+//   - The home config file does not override the from, version and sha256 of a
+//     plugin declared in the workspace config file.
+//   - A plugin with disabled: true, e.g. in the home config file, is left out.
+func MergePluginConfig(layers []PluginConfigLayer) ([]types.PluginConfig, error) {
+	merged := []map[string]any{}
+	byName := map[string]int{}
+	pinned := map[string]bool{}
+
+	for _, layer := range layers {
+		if layer.Plugins == nil {
+			continue
+		}
+		pluginsList, ok := layer.Plugins.([]any)
+		if !ok {
+			return nil, fmt.Errorf("failed to load %s: expected plugins config to be a list", layer.Name)
+		}
+		for i, p := range pluginsList {
+			pluginsMap, ok := p.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("failed to load %s: expected plugins config entry %v to be a map", layer.Name, i)
+			}
+			name, ok := pluginsMap["name"].(string)
+			if !ok {
+				return nil, fmt.Errorf("failed to load %s: expected plugins config entry %v to have a 'name' attribute", layer.Name, i)
+			}
+
+			j, ok := byName[name]
+			if !ok {
+				byName[name] = len(merged)
+				merged = append(merged, maps.Clone(pluginsMap))
+				pinned[name] = layer.Workspace
+				continue
+			}
+			for k, v := range pluginsMap {
+				if layer.Home && pinned[name] && slices.Contains(pinnedPluginSettings, k) {
+					continue
+				}
+				merged[j][k] = v
+			}
+			if layer.Workspace {
+				pinned[name] = true
+			}
+		}
+	}
+
+	pluginsConfig := make([]any, 0, len(merged))
+	for _, pluginsMap := range merged {
+		pluginsConfig = append(pluginsConfig, pluginsMap)
+	}
+	plugins, err := UnmarshalPluginConfig(pluginsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load plugins config: %w", err)
+	}
+	return slices.DeleteFunc(plugins, func(p types.PluginConfig) bool {
+		return p.Disabled
+	}), nil
+}
+
+// skipPlugins leaves out the plugins that are skipped for this invocation with
+// --aspect:disable_plugin or --aspect:no_plugins.
+func skipPlugins(plugins []types.PluginConfig, configFlagValues *ConfigFlagValues) ([]types.PluginConfig, error) {
+	if configFlagValues.NoPlugins {
+		return []types.PluginConfig{}, nil
+	}
+	for _, name := range configFlagValues.DisabledPlugins {
+		if !slices.ContainsFunc(plugins, func(p types.PluginConfig) bool { return p.Name == name }) {
+			return nil, fmt.Errorf("--%s=%s: no plugin named %q is configured", flags.AspectDisablePluginFlagName, name, name)
+		}
+	}
+	return slices.DeleteFunc(plugins, func(p types.PluginConfig) bool {
+		return slices.Contains(configFlagValues.DisabledPlugins, p.Name)
+	}), nil
+}
+
 func Load(v *viper.Viper, args []string) error {
 	// Load configs in increasing preference. Options in later files can override a value form an
 	// earlier file if a conflict arises. Inspired by where Bazel looks for .bazelrc and how this is
@@ -81,6 +186,9 @@ func Load(v *viper.Viper, args []string) error {
 	//    disable the search for a user rc file, such as in release builds.
 	//
 	// Viper MergeConfigMap inspired by https://github.com/spf13/viper/issues/181.
+	//
+	// The plugins lists of the config files are merged by name rather than replaced, see
+	// MergePluginConfig.
 
 	// Parse flags that affect how config files are loaded first. These are a specials flag that must
 	// be parsed before we initialize cobra flags since there are some configuration settings such as
@@ -90,7 +198,7 @@ func Load(v *viper.Viper, args []string) error {
 		return err
 	}
 
-	plugins := []types.PluginConfig{}
+	layers := []PluginConfigLayer{}
 
 	if configFlagValues.SystemConfig {
 		systemConfig, err := LoadSystemConfig()
@@ -98,14 +206,10 @@ func Load(v *viper.Viper, args []string) error {
 			return fmt.Errorf("failed to load system config file: %w", err)
 		}
 		if systemConfig != nil {
-			systemPlugins, err := UnmarshalPluginConfig(systemConfig.Get("plugins"))
-			if err != nil {
-				return fmt.Errorf("failed to load system config file: %w", err)
-			}
-			plugins, err = AddPlugins(plugins, systemPlugins)
-			if err != nil {
-				return fmt.Errorf("failed to load system config file: %w", err)
-			}
+			layers = append(layers, PluginConfigLayer{
+				Name:    "system config file",
+				Plugins: systemConfig.Get("plugins"),
+			})
 			if err := v.MergeConfigMap(systemConfig.AllSettings()); err != nil {
 				return err
 			}
@@ -121,14 +225,11 @@ func Load(v *viper.Viper, args []string) error {
 			}
 		}
 		if workspaceConfig != nil {
-			workspacePlugins, err := UnmarshalPluginConfig(workspaceConfig.Get("plugins"))
-			if err != nil {
-				return fmt.Errorf("failed to load workspace config file: %w", err)
-			}
-			plugins, err = AddPlugins(plugins, workspacePlugins)
-			if err != nil {
-				return fmt.Errorf("failed to load workspace config file: %w", err)
-			}
+			layers = append(layers, PluginConfigLayer{
+				Name:      "workspace config file",
+				Plugins:   workspaceConfig.Get("plugins"),
+				Workspace: true,
+			})
 			if err := v.MergeConfigMap(workspaceConfig.AllSettings()); err != nil {
 				return err
 			}
@@ -141,14 +242,11 @@ func Load(v *viper.Viper, args []string) error {
 			return fmt.Errorf("failed to load home config file: %w", err)
 		}
 		if homeConfig != nil {
-			homePlugins, err := UnmarshalPluginConfig(homeConfig.Get("plugins"))
-			if err != nil {
-				return fmt.Errorf("failed to load home config file: %w", err)
-			}
-			plugins, err = AddPlugins(plugins, homePlugins)
-			if err != nil {
-				return fmt.Errorf("failed to load home config file: %w", err)
-			}
+			layers = append(layers, PluginConfigLayer{
+				Name:    "home config file",
+				Plugins: homeConfig.Get("plugins"),
+				Home:    true,
+			})
 			if err := v.MergeConfigMap(homeConfig.AllSettings()); err != nil {
 				return err
 			}
@@ -165,19 +263,24 @@ func Load(v *viper.Viper, args []string) error {
 		if err != nil {
 			return fmt.Errorf("failed to load --aspect:config file %q: %w", f, err)
 		}
-		userPlugins, err := UnmarshalPluginConfig(userConfig.Get("plugins"))
-		if err != nil {
-			return fmt.Errorf("failed to load --aspect:config file %q: %w", f, err)
-		}
-		plugins, err = AddPlugins(plugins, userPlugins)
-		if err != nil {
-			return fmt.Errorf("failed to load --aspect:config file %q: %w", f, err)
-		}
+		layers = append(layers, PluginConfigLayer{
+			Name:    fmt.Sprintf("--aspect:config file %q", f),
+			Plugins: userConfig.Get("plugins"),
+		})
 		if err := v.MergeConfigMap(userConfig.AllSettings()); err != nil {
 			return err
 		}
 	}
 
+	plugins, err := MergePluginConfig(layers)
+	if err != nil {
+		return err
+	}
+	plugins, err = skipPlugins(plugins, configFlagValues)
+	if err != nil {
+		return err
+	}
+
 	// Set merged plugins lists
 	v.Set("plugins", MarshalPluginConfig(plugins))
 
@@ -200,6 +303,10 @@ func ParseConfigFlags(args []string) (*ConfigFlagValues, error) {
 	workspaceConfig := flags.RegisterNoableBool(configFlagSet, flags.AspectWorkspaceConfigFlagName, true, "")
 	homeConfig := flags.RegisterNoableBool(configFlagSet, flags.AspectHomeConfigFlagName, true, "")
 
+	var disabledPlugins = flags.MultiString{}
+	configFlagSet.Var(&disabledPlugins, flags.AspectDisablePluginFlagName, "")
+	noPlugins := configFlagSet.Bool(flags.AspectNoPluginsFlagName, false, "")
+
 	if err := configFlagSet.Parse(args[1:]); err != nil {
 		// Ignore the special help requested pflag error case
 		if err != pflag.ErrHelp {
@@ -212,6 +319,8 @@ func ParseConfigFlags(args []string) (*ConfigFlagValues, error) {
 		SystemConfig:    *systemConfig,
 		WorkspaceConfig: *workspaceConfig,
 		HomeConfig:      *homeConfig,
+		DisabledPlugins: disabledPlugins.Get(),
+		NoPlugins:       *noPlugins,
 	}, nil
 }
 
@@ -268,7 +377,7 @@ func SetInWorkspaceConfig(key string, value any) (string, bool, error) {
 
 	if !configExists {
 		// Ensure the config directory exists before writing
-		if err := os.MkdirAll(AspectConfigFolder, os.ModePerm); err != nil {
+		if err := os.MkdirAll(path.Dir(configFile), os.ModePerm); err != nil {
 			return "", false, err
 		}
 	}
@@ -382,19 +491,95 @@ func MarshalPluginConfig(plugins []types.PluginConfig) any {
 	for _, p := range plugins {
 		i := map[string]any{
 			"name":                        p.Name,
-			"from":                        p.From,
 			"multi_threaded_build_events": p.MultiThreadedBuildEvents,
 			"disable_bes_events":          p.DisableBESEvents,
 		}
+		if p.From != "" {
+			i["from"] = p.From
+		}
 		if p.Version != "" {
 			i["version"] = p.Version
 		}
 		if p.LogLevel != "" {
 			i["log_level"] = p.LogLevel
 		}
+		if p.Runtime != "" {
+			i["runtime"] = p.Runtime
+		}
 		if p.Properties != nil {
 			i["properties"] = p.Properties
 		}
+		if p.SetupTimeout != 0 {
+			i["setup_timeout"] = p.SetupTimeout.String()
+		}
+		if p.TeardownTimeout != 0 {
+			i["teardown_timeout"] = p.TeardownTimeout.String()
+		}
+		if p.PrefixOutput {
+			i["prefix_output"] = p.PrefixOutput
+		}
+		if p.Restart != "" {
+			i["restart"] = p.Restart
+		}
+		if p.MaxRestarts != 0 {
+			i["max_restarts"] = p.MaxRestarts
+		}
+		if p.Priority != 0 {
+			i["priority"] = p.Priority
+		}
+		if p.BuildEventDelivery != "" {
+			i["build_event_delivery"] = p.BuildEventDelivery
+		}
+		if p.BuildEventWorkers != 0 {
+			i["build_event_workers"] = p.BuildEventWorkers
+		}
+		if p.BuildEventQueueSize != 0 {
+			i["build_event_queue_size"] = p.BuildEventQueueSize
+		}
+		if p.BuildEventQueuePolicy != "" {
+			i["build_event_queue_policy"] = p.BuildEventQueuePolicy
+		}
+		if p.AllowCommandOverride {
+			i["allow_command_override"] = p.AllowCommandOverride
+		}
+		if p.Disabled {
+			i["disabled"] = p.Disabled
+		}
+		if len(p.DependsOn) > 0 {
+			dependsOn := make([]any, 0, len(p.DependsOn))
+			for _, dep := range p.DependsOn {
+				dependsOn = append(dependsOn, dep)
+			}
+			i["depends_on"] = dependsOn
+		}
+		if len(p.Commands) > 0 {
+			commands := make([]any, 0, len(p.Commands))
+			for _, command := range p.Commands {
+				commands = append(commands, command)
+			}
+			i["commands"] = commands
+		}
+		if p.EnvAllowlist != nil {
+			envAllowlist := make([]any, 0, len(p.EnvAllowlist))
+			for _, pattern := range p.EnvAllowlist {
+				envAllowlist = append(envAllowlist, pattern)
+			}
+			i["env_allowlist"] = envAllowlist
+		}
+		if len(p.Env) > 0 {
+			env := make([]any, 0, len(p.Env))
+			for _, k := range slices.Sorted(maps.Keys(p.Env)) {
+				env = append(env, k+"="+p.Env[k])
+			}
+			i["env"] = env
+		}
+		if len(p.SHA256) > 0 {
+			sha256 := map[string]any{}
+			for platform, sum := range p.SHA256 {
+				sha256[platform] = sum
+			}
+			i["sha256"] = sha256
+		}
 		l = append(l, i)
 	}
 	return l
@@ -424,31 +609,209 @@ func UnmarshalPluginConfig(pluginsConfig any) ([]types.PluginConfig, error) {
 			return nil, fmt.Errorf("expected plugins config entry %v to have a 'name' attribute", i)
 		}
 
+		// A disabled plugin only needs a name, so that it can be disabled without
+		// repeating where it comes from.
+		disabled, _ := pluginsMap["disabled"].(bool)
+
 		from, ok := pluginsMap["from"].(string)
-		if !ok {
+		if !ok && !disabled {
 			return nil, fmt.Errorf("expected plugins config entry '%v' to have a 'from' attribute", name)
 		}
 
 		version, _ := pluginsMap["version"].(string)
 		logLevel, _ := pluginsMap["log_level"].(string)
+		pluginRuntime, _ := pluginsMap["runtime"].(string)
 		multi_threaded_build_events, _ := pluginsMap["multi_threaded_build_events"].(bool)
 		disable_bes_events, _ := pluginsMap["disable_bes_events"].(bool)
 		properties, _ := pluginsMap["properties"].(map[string]any)
+		prefixOutput, _ := pluginsMap["prefix_output"].(bool)
+		allowCommandOverride, _ := pluginsMap["allow_command_override"].(bool)
+
+		var sha256 map[string]string
+		if sha256Map, ok := pluginsMap["sha256"].(map[string]any); ok {
+			sha256 = make(map[string]string, len(sha256Map))
+			for platform, sum := range sha256Map {
+				s, ok := sum.(string)
+				if !ok {
+					return nil, fmt.Errorf("expected plugins config entry '%v' sha256 for %q to be a string", name, platform)
+				}
+				sha256[platform] = s
+			}
+		}
+
+		var dependsOn []string
+		if dependsOnList, ok := pluginsMap["depends_on"].([]any); ok {
+			dependsOn = make([]string, 0, len(dependsOnList))
+			for _, dep := range dependsOnList {
+				s, ok := dep.(string)
+				if !ok {
+					return nil, fmt.Errorf("expected plugins config entry '%v' depends_on to be a list of plugin names", name)
+				}
+				dependsOn = append(dependsOn, s)
+			}
+		}
+
+		var commands []string
+		if commandsList, ok := pluginsMap["commands"].([]any); ok {
+			commands = make([]string, 0, len(commandsList))
+			for _, command := range commandsList {
+				s, ok := command.(string)
+				if !ok {
+					return nil, fmt.Errorf("expected plugins config entry '%v' commands to be a list of command names", name)
+				}
+				commands = append(commands, s)
+			}
+		} else if v, ok := pluginsMap["commands"]; ok && v != nil {
+			return nil, fmt.Errorf("expected plugins config entry '%v' commands to be a list of command names", name)
+		}
+
+		var envAllowlist []string
+		if v, ok := pluginsMap["env_allowlist"]; ok && v != nil {
+			envAllowlistList, ok := v.([]any)
+			if !ok {
+				return nil, fmt.Errorf("expected plugins config entry '%v' env_allowlist to be a list of environment variable names", name)
+			}
+			envAllowlist = make([]string, 0, len(envAllowlistList))
+			for _, pattern := range envAllowlistList {
+				s, ok := pattern.(string)
+				if !ok {
+					return nil, fmt.Errorf("expected plugins config entry '%v' env_allowlist to be a list of environment variable names", name)
+				}
+				envAllowlist = append(envAllowlist, s)
+			}
+		}
+
+		// Environment variables are listed as NAME=value rather than as a map,
+		// since viper lower cases the keys of maps.
+		var env map[string]string
+		if envList, ok := pluginsMap["env"].([]any); ok {
+			env = make(map[string]string, len(envList))
+			for _, kv := range envList {
+				s, _ := kv.(string)
+				k, v, ok := strings.Cut(s, "=")
+				if !ok || k == "" {
+					return nil, fmt.Errorf("expected plugins config entry '%v' env to be a list of NAME=value: %v", name, kv)
+				}
+				env[k] = v
+			}
+		} else if v, ok := pluginsMap["env"]; ok && v != nil {
+			return nil, fmt.Errorf("expected plugins config entry '%v' env to be a list of NAME=value: %v", name, v)
+		}
+
+		restart, _ := pluginsMap["restart"].(string)
+		switch restart {
+		case "", types.RestartNever, types.RestartOnFailure:
+		default:
+			return nil, fmt.Errorf("expected plugins config entry '%v' restart to be %q or %q: %q", name, types.RestartNever, types.RestartOnFailure, restart)
+		}
+
+		var maxRestarts int
+		if v, ok := pluginsMap["max_restarts"]; ok {
+			maxRestarts, ok = v.(int)
+			if !ok || maxRestarts < 0 {
+				return nil, fmt.Errorf("expected plugins config entry '%v' max_restarts to be a non-negative number: %v", name, v)
+			}
+		}
+
+		var priority int
+		if v, ok := pluginsMap["priority"]; ok {
+			priority, ok = v.(int)
+			if !ok {
+				return nil, fmt.Errorf("expected plugins config entry '%v' priority to be a whole number: %v", name, v)
+			}
+		}
+
+		buildEventDelivery, _ := pluginsMap["build_event_delivery"].(string)
+		switch buildEventDelivery {
+		case "", types.BuildEventDeliveryOrdered, types.BuildEventDeliveryUnordered:
+		default:
+			return nil, fmt.Errorf("expected plugins config entry '%v' build_event_delivery to be %q or %q: %q", name, types.BuildEventDeliveryOrdered, types.BuildEventDeliveryUnordered, buildEventDelivery)
+		}
+
+		var buildEventWorkers int
+		if v, ok := pluginsMap["build_event_workers"]; ok {
+			buildEventWorkers, ok = v.(int)
+			if !ok || buildEventWorkers < 1 {
+				return nil, fmt.Errorf("expected plugins config entry '%v' build_event_workers to be a positive number: %v", name, v)
+			}
+			if buildEventDelivery != types.BuildEventDeliveryUnordered {
+				return nil, fmt.Errorf("expected plugins config entry '%v' to set build_event_delivery to %q with build_event_workers", name, types.BuildEventDeliveryUnordered)
+			}
+		}
+
+		var buildEventQueueSize int
+		if v, ok := pluginsMap["build_event_queue_size"]; ok {
+			buildEventQueueSize, ok = v.(int)
+			if !ok || buildEventQueueSize < 1 {
+				return nil, fmt.Errorf("expected plugins config entry '%v' build_event_queue_size to be a positive number: %v", name, v)
+			}
+		}
+
+		buildEventQueuePolicy, _ := pluginsMap["build_event_queue_policy"].(string)
+		switch buildEventQueuePolicy {
+		case "", types.BuildEventQueueBlock, types.BuildEventQueueDropOldest, types.BuildEventQueueFail:
+		default:
+			return nil, fmt.Errorf("expected plugins config entry '%v' build_event_queue_policy to be %q, %q or %q: %q", name, types.BuildEventQueueBlock, types.BuildEventQueueDropOldest, types.BuildEventQueueFail, buildEventQueuePolicy)
+		}
+
+		if (buildEventQueueSize != 0 || buildEventQueuePolicy != "") && !multi_threaded_build_events {
+			return nil, fmt.Errorf("expected plugins config entry '%v' to set multi_threaded_build_events with build_event_queue_size or build_event_queue_policy", name)
+		}
+
+		setupTimeout, err := unmarshalPluginDuration(pluginsMap, name, "setup_timeout")
+		if err != nil {
+			return nil, err
+		}
+
+		teardownTimeout, err := unmarshalPluginDuration(pluginsMap, name, "teardown_timeout")
+		if err != nil {
+			return nil, err
+		}
 
 		plugins = append(plugins, types.PluginConfig{
 			Name:                     name,
 			From:                     from,
 			Version:                  version,
 			LogLevel:                 logLevel,
+			Runtime:                  pluginRuntime,
 			MultiThreadedBuildEvents: multi_threaded_build_events,
 			DisableBESEvents:         disable_bes_events,
 			Properties:               properties,
+			SHA256:                   sha256,
+			DependsOn:                dependsOn,
+			Commands:                 commands,
+			EnvAllowlist:             envAllowlist,
+			Env:                      env,
+			PrefixOutput:             prefixOutput,
+			SetupTimeout:             setupTimeout,
+			TeardownTimeout:          teardownTimeout,
+			Restart:                  restart,
+			MaxRestarts:              maxRestarts,
+			Priority:                 priority,
+			BuildEventDelivery:       buildEventDelivery,
+			BuildEventWorkers:        buildEventWorkers,
+			BuildEventQueueSize:      buildEventQueueSize,
+			BuildEventQueuePolicy:    buildEventQueuePolicy,
+			AllowCommandOverride:     allowCommandOverride,
+			Disabled:                 disabled,
 		})
 	}
 
 	return plugins, nil
 }
 
+func unmarshalPluginDuration(pluginsMap map[string]any, name string, key string) (time.Duration, error) {
+	s, ok := pluginsMap[key].(string)
+	if !ok {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("expected plugins config entry '%v' %s to be a positive duration such as 30s: %q", name, key, s)
+	}
+	return d, nil
+}
+
 func exists(name string) (bool, error) {
 	_, err := os.Stat(name)
 	if err == nil {
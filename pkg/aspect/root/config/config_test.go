@@ -22,6 +22,7 @@ import (
 	"path"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/root/config"
 	. "github.com/onsi/gomega"
@@ -164,6 +165,160 @@ plugins:
 	g.Expect(fmt.Sprintf("%v", v.Get("plugins"))).To(Equal("[map[disable_bes_events:false from:https://static.plugins.com/foo log_level:debug multi_threaded_build_events:false name:foo version:3.2.1] map[disable_bes_events:false from:https://static.plugins.com/fum multi_threaded_build_events:false name:fum version:1.2.3] map[disable_bes_events:false from:https://static.plugins.com/bar multi_threaded_build_events:false name:bar version:1.2.3]]"))
 }
 
+func TestLoadSkipsPlugins(t *testing.T) {
+	tempDir := NewTempDir(t)
+	os.MkdirAll(path.Join(tempDir, configDirectory), os.ModePerm)
+	os.WriteFile(filepath.Join(tempDir, "WORKSPACE"), []byte{}, 0644)
+	os.WriteFile(filepath.Join(tempDir, configFilename), []byte(`plugins:
+  - name: foo
+    from: https://static.plugins.com/foo
+  - name: fum
+    from: https://static.plugins.com/fum
+`), 0644)
+
+	// Config file loader searches the CWD for the WORKSPACE file
+	t.Chdir(tempDir)
+
+	load := func(g *WithT, flags ...string) []string {
+		v := viper.New()
+		err := config.Load(v, append([]string{"cmd", "--aspect:nosystem_config", "--aspect:nohome_config"}, flags...))
+		g.Expect(err).ToNot(HaveOccurred())
+		plugins, err := config.UnmarshalPluginConfig(v.Get("plugins"))
+		g.Expect(err).ToNot(HaveOccurred())
+		names := []string{}
+		for _, p := range plugins {
+			names = append(names, p.Name)
+		}
+		return names
+	}
+
+	t.Run("--aspect:disable_plugin skips the named plugins", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(load(g)).To(Equal([]string{"foo", "fum"}))
+		g.Expect(load(g, "--aspect:disable_plugin=foo")).To(Equal([]string{"fum"}))
+		g.Expect(load(g, "--aspect:disable_plugin=foo", "--aspect:disable_plugin=fum")).To(BeEmpty())
+	})
+
+	t.Run("--aspect:no_plugins skips all plugins", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(load(g, "--aspect:no_plugins")).To(BeEmpty())
+	})
+
+	t.Run("--aspect:disable_plugin fails for a plugin that is not configured", func(t *testing.T) {
+		g := NewWithT(t)
+		err := config.Load(viper.New(), []string{"cmd", "--aspect:nosystem_config", "--aspect:nohome_config", "--aspect:disable_plugin=bar"})
+		g.Expect(err).To(MatchError(ContainSubstring(`no plugin named "bar" is configured`)))
+	})
+}
+
+func TestMergePluginConfig(t *testing.T) {
+	workspace := config.PluginConfigLayer{
+		Name: "workspace config file",
+		Plugins: []any{
+			map[string]any{"name": "foo", "from": "https://static.plugins.com/foo", "version": "1.2.3"},
+			map[string]any{"name": "fum", "from": "https://static.plugins.com/fum", "version": "1.2.3"},
+		},
+		Workspace: true,
+	}
+
+	t.Run("the workspace pins the version of a plugin in the home config", func(t *testing.T) {
+		g := NewWithT(t)
+
+		home := config.PluginConfigLayer{
+			Name: "home config file",
+			Plugins: []any{
+				map[string]any{"name": "foo", "from": "https://static.plugins.com/foo", "version": "2.0.0", "log_level": "debug"},
+				map[string]any{"name": "bar", "from": "https://static.plugins.com/bar", "version": "1.0.0"},
+			},
+			Home: true,
+		}
+		plugins, err := config.MergePluginConfig([]config.PluginConfigLayer{workspace, home})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(plugins).To(HaveLen(3))
+		g.Expect(plugins[0].Name).To(Equal("foo"))
+		g.Expect(plugins[0].Version).To(Equal("1.2.3"))
+		g.Expect(plugins[0].LogLevel).To(Equal("debug"))
+		g.Expect(plugins[1].Name).To(Equal("fum"))
+		g.Expect(plugins[2].Name).To(Equal("bar"))
+		g.Expect(plugins[2].Version).To(Equal("1.0.0"))
+	})
+
+	t.Run("the workspace overrides the version of a system plugin", func(t *testing.T) {
+		g := NewWithT(t)
+
+		system := config.PluginConfigLayer{
+			Name: "system config file",
+			Plugins: []any{
+				map[string]any{"name": "fum", "from": "https://static.plugins.com/fum", "version": "0.1.0", "priority": 5},
+			},
+		}
+		plugins, err := config.MergePluginConfig([]config.PluginConfigLayer{system, workspace})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(plugins).To(HaveLen(2))
+		g.Expect(plugins[0].Name).To(Equal("fum"))
+		g.Expect(plugins[0].Version).To(Equal("1.2.3"))
+		g.Expect(plugins[0].Priority).To(Equal(5))
+		g.Expect(plugins[1].Name).To(Equal("foo"))
+	})
+
+	t.Run("an --aspect:config file overrides the version pinned by the workspace", func(t *testing.T) {
+		g := NewWithT(t)
+
+		user := config.PluginConfigLayer{
+			Name: "--aspect:config file \"myconfig.yaml\"",
+			Plugins: []any{
+				map[string]any{"name": "foo", "version": "3.2.1"},
+			},
+		}
+		plugins, err := config.MergePluginConfig([]config.PluginConfigLayer{workspace, user})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(plugins[0].Version).To(Equal("3.2.1"))
+		g.Expect(plugins[0].From).To(Equal("https://static.plugins.com/foo"))
+	})
+
+	t.Run("the home config disables a workspace plugin", func(t *testing.T) {
+		g := NewWithT(t)
+
+		home := config.PluginConfigLayer{
+			Name: "home config file",
+			Plugins: []any{
+				map[string]any{"name": "foo", "disabled": true},
+			},
+			Home: true,
+		}
+		plugins, err := config.MergePluginConfig([]config.PluginConfigLayer{workspace, home})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(plugins).To(HaveLen(1))
+		g.Expect(plugins[0].Name).To(Equal("fum"))
+	})
+
+	t.Run("fails for a plugin without a source", func(t *testing.T) {
+		g := NewWithT(t)
+
+		home := config.PluginConfigLayer{
+			Name: "home config file",
+			Plugins: []any{
+				map[string]any{"name": "bar", "version": "1.0.0"},
+			},
+			Home: true,
+		}
+		_, err := config.MergePluginConfig([]config.PluginConfigLayer{workspace, home})
+		g.Expect(err).To(MatchError(ContainSubstring("'bar' to have a 'from' attribute")))
+	})
+
+	t.Run("fails for a malformed entry naming the config file", func(t *testing.T) {
+		g := NewWithT(t)
+
+		home := config.PluginConfigLayer{
+			Name:    "home config file",
+			Plugins: []any{"foo"},
+			Home:    true,
+		}
+		_, err := config.MergePluginConfig([]config.PluginConfigLayer{workspace, home})
+		g.Expect(err).To(MatchError(ContainSubstring("failed to load home config file")))
+	})
+}
+
 func TestMarshalling(t *testing.T) {
 	g := NewWithT(t)
 
@@ -223,4 +378,329 @@ func TestMarshalling(t *testing.T) {
 	g.Expect(p3).To(Equal(p2))
 	c3 := config.MarshalPluginConfig(p3)
 	g.Expect(c3).To(Equal(c2))
+
+	p4, err := config.UnmarshalPluginConfig([]any{map[string]any{
+		"name": "foo4",
+		"from": "foo4-from.wasm",
+		// runtime should be maintained when set
+		"runtime": "wasm",
+	}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(p4[0].Runtime).To(Equal("wasm"))
+	g.Expect(config.MarshalPluginConfig(p4)).To(Equal([]any{map[string]any{
+		"name":                        "foo4",
+		"from":                        "foo4-from.wasm",
+		"multi_threaded_build_events": false,
+		"disable_bes_events":          false,
+		"runtime":                     "wasm",
+	}}))
+
+	p5, err := config.UnmarshalPluginConfig([]any{map[string]any{
+		"name": "foo5",
+		"from": "foo5-from",
+		// setup_timeout should be maintained when set
+		"setup_timeout": "1m30s",
+	}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(p5[0].SetupTimeout).To(Equal(90 * time.Second))
+	g.Expect(config.MarshalPluginConfig(p5)).To(Equal([]any{map[string]any{
+		"name":                        "foo5",
+		"from":                        "foo5-from",
+		"multi_threaded_build_events": false,
+		"disable_bes_events":          false,
+		"setup_timeout":               "1m30s",
+	}}))
+
+	p7, err := config.UnmarshalPluginConfig([]any{map[string]any{
+		"name": "foo7",
+		"from": "foo7-from",
+		// teardown_timeout should be maintained when set
+		"teardown_timeout": "2s",
+	}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(p7[0].TeardownTimeout).To(Equal(2 * time.Second))
+	g.Expect(config.MarshalPluginConfig(p7)).To(Equal([]any{map[string]any{
+		"name":                        "foo7",
+		"from":                        "foo7-from",
+		"multi_threaded_build_events": false,
+		"disable_bes_events":          false,
+		"teardown_timeout":            "2s",
+	}}))
+
+	p9, err := config.UnmarshalPluginConfig([]any{map[string]any{
+		"name": "foo9",
+		"from": "foo9-from",
+		// depends_on should be maintained when set
+		"depends_on": []any{"foo7"},
+	}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(p9[0].DependsOn).To(Equal([]string{"foo7"}))
+	g.Expect(config.MarshalPluginConfig(p9)).To(Equal([]any{map[string]any{
+		"name":                        "foo9",
+		"from":                        "foo9-from",
+		"multi_threaded_build_events": false,
+		"disable_bes_events":          false,
+		"depends_on":                  []any{"foo7"},
+	}}))
+
+	p10, err := config.UnmarshalPluginConfig([]any{map[string]any{
+		"name": "foo10",
+		"from": "foo10-from",
+		// restart and max_restarts should be maintained when set
+		"restart":      "on-failure",
+		"max_restarts": 5,
+	}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(p10[0].Restart).To(Equal("on-failure"))
+	g.Expect(p10[0].MaxRestarts).To(Equal(5))
+	g.Expect(config.MarshalPluginConfig(p10)).To(Equal([]any{map[string]any{
+		"name":                        "foo10",
+		"from":                        "foo10-from",
+		"multi_threaded_build_events": false,
+		"disable_bes_events":          false,
+		"restart":                     "on-failure",
+		"max_restarts":                5,
+	}}))
+
+	p11, err := config.UnmarshalPluginConfig([]any{map[string]any{
+		"name": "foo13",
+		"from": "foo13-from",
+		// commands should be maintained when set
+		"commands": []any{"build", "test"},
+	}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(p11[0].Commands).To(Equal([]string{"build", "test"}))
+	g.Expect(config.MarshalPluginConfig(p11)).To(Equal([]any{map[string]any{
+		"name":                        "foo13",
+		"from":                        "foo13-from",
+		"multi_threaded_build_events": false,
+		"disable_bes_events":          false,
+		"commands":                    []any{"build", "test"},
+	}}))
+
+	p12, err := config.UnmarshalPluginConfig([]any{map[string]any{
+		"name": "foo15",
+		"from": "foo15-from",
+		// env_allowlist and env should be maintained when set
+		"env_allowlist": []any{"PATH", "CI_*"},
+		"env":           []any{"NO_COLOR=1", "AWS_REGION=us-east-1"},
+	}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(p12[0].EnvAllowlist).To(Equal([]string{"PATH", "CI_*"}))
+	g.Expect(p12[0].Env).To(Equal(map[string]string{"NO_COLOR": "1", "AWS_REGION": "us-east-1"}))
+	g.Expect(config.MarshalPluginConfig(p12)).To(Equal([]any{map[string]any{
+		"name":                        "foo15",
+		"from":                        "foo15-from",
+		"multi_threaded_build_events": false,
+		"disable_bes_events":          false,
+		"env_allowlist":               []any{"PATH", "CI_*"},
+		"env":                         []any{"AWS_REGION=us-east-1", "NO_COLOR=1"},
+	}}))
+
+	p14, err := config.UnmarshalPluginConfig([]any{map[string]any{
+		"name": "foo19",
+		"from": "foo19-from",
+		// prefix_output should be maintained when set
+		"prefix_output": true,
+	}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(p14[0].PrefixOutput).To(BeTrue())
+	g.Expect(config.MarshalPluginConfig(p14)).To(Equal([]any{map[string]any{
+		"name":                        "foo19",
+		"from":                        "foo19-from",
+		"multi_threaded_build_events": false,
+		"disable_bes_events":          false,
+		"prefix_output":               true,
+	}}))
+
+	p15, err := config.UnmarshalPluginConfig([]any{map[string]any{
+		"name": "foo21",
+		"from": "foo21-from",
+		// priority should be maintained when set
+		"priority": -5,
+	}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(p15[0].Priority).To(Equal(-5))
+	g.Expect(config.MarshalPluginConfig(p15)).To(Equal([]any{map[string]any{
+		"name":                        "foo21",
+		"from":                        "foo21-from",
+		"multi_threaded_build_events": false,
+		"disable_bes_events":          false,
+		"priority":                    -5,
+	}}))
+
+	p16, err := config.UnmarshalPluginConfig([]any{map[string]any{
+		"name":                        "foo23",
+		"from":                        "foo23-from",
+		"multi_threaded_build_events": true,
+		// build_event_delivery and build_event_workers should be maintained when set
+		"build_event_delivery": "unordered",
+		"build_event_workers":  8,
+	}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(p16[0].BuildEventDelivery).To(Equal("unordered"))
+	g.Expect(p16[0].BuildEventWorkers).To(Equal(8))
+	g.Expect(config.MarshalPluginConfig(p16)).To(Equal([]any{map[string]any{
+		"name":                        "foo23",
+		"from":                        "foo23-from",
+		"multi_threaded_build_events": true,
+		"disable_bes_events":          false,
+		"build_event_delivery":        "unordered",
+		"build_event_workers":         8,
+	}}))
+
+	// An empty env_allowlist passes no environment variables to the plugin.
+	p13, err := config.UnmarshalPluginConfig([]any{map[string]any{
+		"name":          "foo16",
+		"from":          "foo16-from",
+		"env_allowlist": []any{},
+	}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(p13[0].EnvAllowlist).ToNot(BeNil())
+	g.Expect(p13[0].EnvAllowlist).To(BeEmpty())
+
+	_, err = config.UnmarshalPluginConfig([]any{map[string]any{
+		"name":          "foo6",
+		"from":          "foo6-from",
+		"setup_timeout": "soon",
+	}})
+	g.Expect(err).To(HaveOccurred())
+
+	_, err = config.UnmarshalPluginConfig([]any{map[string]any{
+		"name":             "foo8",
+		"from":             "foo8-from",
+		"teardown_timeout": "-1s",
+	}})
+	g.Expect(err).To(HaveOccurred())
+
+	_, err = config.UnmarshalPluginConfig([]any{map[string]any{
+		"name":    "foo11",
+		"from":    "foo11-from",
+		"restart": "always",
+	}})
+	g.Expect(err).To(HaveOccurred())
+
+	_, err = config.UnmarshalPluginConfig([]any{map[string]any{
+		"name":         "foo12",
+		"from":         "foo12-from",
+		"max_restarts": -1,
+	}})
+	g.Expect(err).To(HaveOccurred())
+
+	_, err = config.UnmarshalPluginConfig([]any{map[string]any{
+		"name":     "foo22",
+		"from":     "foo22-from",
+		"priority": "high",
+	}})
+	g.Expect(err).To(HaveOccurred())
+
+	_, err = config.UnmarshalPluginConfig([]any{map[string]any{
+		"name":                 "foo24",
+		"from":                 "foo24-from",
+		"build_event_delivery": "random",
+	}})
+	g.Expect(err).To(HaveOccurred())
+
+	// build_event_workers only applies to unordered delivery.
+	_, err = config.UnmarshalPluginConfig([]any{map[string]any{
+		"name":                "foo25",
+		"from":                "foo25-from",
+		"build_event_workers": 4,
+	}})
+	g.Expect(err).To(HaveOccurred())
+
+	// build_event_queue_size and build_event_queue_policy should be maintained when set
+	p19, err := config.UnmarshalPluginConfig([]any{map[string]any{
+		"name":                        "foo28",
+		"from":                        "foo28-from",
+		"multi_threaded_build_events": true,
+		"build_event_queue_size":      64,
+		"build_event_queue_policy":    "drop-oldest",
+	}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(p19[0].BuildEventQueueSize).To(Equal(64))
+	g.Expect(p19[0].BuildEventQueuePolicy).To(Equal("drop-oldest"))
+	g.Expect(config.MarshalPluginConfig(p19)).To(Equal([]any{map[string]any{
+		"name":                        "foo28",
+		"from":                        "foo28-from",
+		"multi_threaded_build_events": true,
+		"disable_bes_events":          false,
+		"build_event_queue_size":      64,
+		"build_event_queue_policy":    "drop-oldest",
+	}}))
+
+	_, err = config.UnmarshalPluginConfig([]any{map[string]any{
+		"name":                        "foo29",
+		"from":                        "foo29-from",
+		"multi_threaded_build_events": true,
+		"build_event_queue_policy":    "retry",
+	}})
+	g.Expect(err).To(HaveOccurred())
+
+	_, err = config.UnmarshalPluginConfig([]any{map[string]any{
+		"name":                        "foo30",
+		"from":                        "foo30-from",
+		"multi_threaded_build_events": true,
+		"build_event_queue_size":      0,
+	}})
+	g.Expect(err).To(HaveOccurred())
+
+	// The build event queue only applies to multi-threaded build events.
+	_, err = config.UnmarshalPluginConfig([]any{map[string]any{
+		"name":                     "foo31",
+		"from":                     "foo31-from",
+		"build_event_queue_policy": "fail",
+	}})
+	g.Expect(err).To(HaveOccurred())
+
+	// allow_command_override should be maintained when set
+	p18, err := config.UnmarshalPluginConfig([]any{map[string]any{
+		"name":                   "foo27",
+		"from":                   "foo27-from",
+		"allow_command_override": true,
+	}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(p18[0].AllowCommandOverride).To(BeTrue())
+	g.Expect(config.MarshalPluginConfig(p18)).To(Equal([]any{map[string]any{
+		"name":                        "foo27",
+		"from":                        "foo27-from",
+		"multi_threaded_build_events": false,
+		"disable_bes_events":          false,
+		"allow_command_override":      true,
+	}}))
+
+	// A disabled plugin doesn't need a 'from' attribute.
+	p17, err := config.UnmarshalPluginConfig([]any{map[string]any{
+		"name":     "foo26",
+		"disabled": true,
+	}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(p17[0].Disabled).To(BeTrue())
+	g.Expect(config.MarshalPluginConfig(p17)).To(Equal([]any{map[string]any{
+		"name":                        "foo26",
+		"multi_threaded_build_events": false,
+		"disable_bes_events":          false,
+		"disabled":                    true,
+	}}))
+
+	_, err = config.UnmarshalPluginConfig([]any{map[string]any{
+		"name":     "foo14",
+		"from":     "foo14-from",
+		"commands": "build",
+	}})
+	g.Expect(err).To(HaveOccurred())
+
+	_, err = config.UnmarshalPluginConfig([]any{map[string]any{
+		"name": "foo17",
+		"from": "foo17-from",
+		"env":  map[string]any{"aws_region": "us-east-1"},
+	}})
+	g.Expect(err).To(HaveOccurred())
+
+	_, err = config.UnmarshalPluginConfig([]any{map[string]any{
+		"name": "foo18",
+		"from": "foo18-from",
+		"env":  []any{"AWS_REGION"},
+	}})
+	g.Expect(err).To(HaveOccurred())
 }
@@ -26,5 +26,23 @@ const (
 	AspectInteractiveFlagName     = AspectFlagPrefix + "interactive"
 	AspectForceBesBackendFlagName = AspectFlagPrefix + "force_bes_backend"
 	AspectDisablePluginsFlagName  = AspectFlagPrefix + "disable_plugins"
+	AspectDisablePluginFlagName   = AspectFlagPrefix + "disable_plugin"
+	AspectNoPluginsFlagName       = AspectFlagPrefix + "no_plugins"
 	AspectHintsFlagName           = AspectFlagPrefix + "hints"
+	// AspectSummaryFlag is handled by the build and test commands rather
+	// than being a global flag.
+	AspectSummaryFlag = "--" + AspectFlagPrefix + "summary"
+	// AspectUIFlag is handled by the build and test commands rather than
+	// being a global flag.
+	AspectUIFlag = "--" + AspectFlagPrefix + "ui"
+	// AspectPtyFlag is handled by the run command rather than being a global
+	// flag.
+	AspectPtyFlag = "--" + AspectFlagPrefix + "pty"
+	// AspectBESStrictFlag is handled by the BES plugin interceptor rather
+	// than being a global flag.
+	AspectBESStrictFlag = "--" + AspectFlagPrefix + "bes_strict"
+	// AspectBESPipeUploadModeFlag is handled by the BES plugin interceptor
+	// rather than being a global flag. It takes the upload mode as its value,
+	// e.g. --aspect:bes_pipe_upload_mode=fully_async.
+	AspectBESPipeUploadModeFlag = "--" + AspectFlagPrefix + "bes_pipe_upload_mode"
 )
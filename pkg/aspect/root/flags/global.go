@@ -41,6 +41,8 @@ func AddGlobalFlags(cmd *cobra.Command, defaultInteractive bool) {
 	cmd.PersistentFlags().String(AspectConfigFlagName, "", fmt.Sprintf("User-specified Aspect CLI config file. /dev/null indicates that all further --%s flags will be ignored.", AspectConfigFlagName))
 	cmd.PersistentFlags().Bool(AspectInteractiveFlagName, defaultInteractive, "Interactive mode (e.g. prompts for user input)")
 	cmd.PersistentFlags().Bool(AspectHintsFlagName, true, "Enable hints if configured")
+	cmd.PersistentFlags().StringArray(AspectDisablePluginFlagName, nil, "Skip launching the named plugin for this invocation. Can be specified multiple times.")
+	cmd.PersistentFlags().Bool(AspectNoPluginsFlagName, false, "Skip launching any plugins for this invocation")
 
 	// Hidden global flags
 	cmd.PersistentFlags().Bool(AspectLockVersion, AspectLockVersionDefault(), "Lock the version of the Aspect CLI. This prevents the Aspect CLI from downloading and running an different version of the Aspect CLI if one is specified in .bazeliskrc or the Aspect CLI config.")
@@ -52,6 +52,20 @@ func AddFlagToCommand(command []string, flag ...string) []string {
 	return result
 }
 
+// HasFlag returns true if the flag is in the Bazel portion of args (before any
+// bare "--").
+func HasFlag(args []string, flag string) bool {
+	for _, arg := range args {
+		switch arg {
+		case flag:
+			return true
+		case "--":
+			return false
+		}
+	}
+	return false
+}
+
 func RemoveFlag(args []string, flag string) (bool, []string) {
 	for i, arg := range args {
 		switch arg {
@@ -63,3 +77,33 @@ func RemoveFlag(args []string, flag string) (bool, []string) {
 	}
 	return false, args
 }
+
+// RemoveFlagValue removes the occurrences of the flag given as "<flag>=<value>"
+// from the Bazel portion of args (before any bare "--"), and returns the value
+// of the last one, or "" if not found.
+func RemoveFlagValue(args []string, flag string) (string, []string) {
+	values, args := RemoveFlagValues(args, flag)
+	if len(values) == 0 {
+		return "", args
+	}
+	return values[len(values)-1], args
+}
+
+// RemoveFlagValues removes the occurrences of the flag given as
+// "<flag>=<value>" from the Bazel portion of args (before any bare "--"), and
+// returns their values in order.
+func RemoveFlagValues(args []string, flag string) ([]string, []string) {
+	var values []string
+	result := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			return values, append(result, args[i:]...)
+		}
+		if after, ok := strings.CutPrefix(arg, flag+"="); ok {
+			values = append(values, after)
+			continue
+		}
+		result = append(result, arg)
+	}
+	return values, result
+}
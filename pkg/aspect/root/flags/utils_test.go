@@ -64,3 +64,59 @@ func TestFindInvocationId(t *testing.T) {
 		g.Expect(flags.FindInvocationId([]string{"run", "--invocation_id", "--"})).To(Equal(""))
 	})
 }
+
+func TestHasFlag(t *testing.T) {
+	t.Run("finds the flag", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(flags.HasFlag([]string{"//...", "--aspect:summary"}, "--aspect:summary")).To(BeTrue())
+	})
+
+	t.Run("not present returns false", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(flags.HasFlag([]string{"//..."}, "--aspect:summary")).To(BeFalse())
+	})
+
+	t.Run("stops at bare --", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(flags.HasFlag([]string{"//app", "--", "--aspect:summary"}, "--aspect:summary")).To(BeFalse())
+	})
+}
+
+func TestRemoveFlagValue(t *testing.T) {
+	t.Run("removes the flag and returns its last value", func(t *testing.T) {
+		g := NewWithT(t)
+		value, args := flags.RemoveFlagValue([]string{"--aspect:mode=a", "//...", "--aspect:mode=b"}, "--aspect:mode")
+		g.Expect(value).To(Equal("b"))
+		g.Expect(args).To(Equal([]string{"//..."}))
+	})
+
+	t.Run("not present returns empty", func(t *testing.T) {
+		g := NewWithT(t)
+		value, args := flags.RemoveFlagValue([]string{"//...", "--aspect:modes=a"}, "--aspect:mode")
+		g.Expect(value).To(BeEmpty())
+		g.Expect(args).To(Equal([]string{"//...", "--aspect:modes=a"}))
+	})
+
+	t.Run("stops at bare --", func(t *testing.T) {
+		g := NewWithT(t)
+		value, args := flags.RemoveFlagValue([]string{"//app", "--", "--aspect:mode=a"}, "--aspect:mode")
+		g.Expect(value).To(BeEmpty())
+		g.Expect(args).To(Equal([]string{"//app", "--", "--aspect:mode=a"}))
+	})
+}
+
+func TestRemoveFlagValues(t *testing.T) {
+	t.Run("removes the flag and returns its values in order", func(t *testing.T) {
+		g := NewWithT(t)
+		values, args := flags.RemoveFlagValues([]string{"--aspect:env=A=1", "//...", "--aspect:env=B=2"}, "--aspect:env")
+		g.Expect(values).To(Equal([]string{"A=1", "B=2"}))
+		g.Expect(args).To(Equal([]string{"//..."}))
+	})
+
+	t.Run("stops at bare --", func(t *testing.T) {
+		g := NewWithT(t)
+		values, args := flags.RemoveFlagValues([]string{"//app", "--aspect:env=A=1", "--", "--aspect:env=B=2"}, "--aspect:env")
+		g.Expect(values).To(Equal([]string{"A=1"}))
+		g.Expect(args).To(Equal([]string{"//app", "--", "--aspect:env=B=2"}))
+	})
+}
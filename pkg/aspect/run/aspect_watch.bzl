@@ -47,6 +47,8 @@ cp {info_file} {aspect_watch_watch_manifest}
 
     return [OutputGroupInfo(
         __aspect_watch_watch_manifest = depset([watch_manifest]),
+        # The runfiles reported in the build events when detecting changes with them.
+        __aspect_watch_runfiles = default.default_runfiles.files if default.default_runfiles else depset(),
     )]
 
 watch_manifest = aspect(
@@ -18,20 +18,29 @@ package run
 
 import (
 	"bufio"
+	"bytes"
 	"crypto/sha256"
 	_ "embed"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path"
+	"path/filepath"
+	"runtime"
 	"slices"
 	"strings"
+	"sync"
 
+	"github.com/fatih/color"
 	"github.com/klauspost/compress/zstd"
 	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protowire"
 
 	"github.com/aspect-build/aspect-cli-legacy/bazel/spawn"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils/cache"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
 	logger "github.com/aspect-build/aspect-gazelle/common/logger"
 	"github.com/aspect-build/aspect-gazelle/runner/pkg/ibp"
 )
@@ -117,12 +126,25 @@ type ChangeDetector struct {
 
 	// Support bazel <8
 	useLegacyReplaceWorkspace bool
+
+	// The build events of the builds, used instead of the execution log when
+	// set, and where to warn about falling back to the execution log.
+	events   *bepOutputs
+	warnings io.Writer
+
+	// The last parsed runfiles manifest, and the Aspect cache directory where
+	// it is persisted for the next sessions, if any.
+	manifest     *manifestCache
+	cacheDir     string
+	manifestSave sync.WaitGroup
 }
 
 //go:embed aspect_watch.bzl
 var ASPECT_WATCH_BZL_CONTENT []byte
 
-func newChangeDetector(workspaceDir string, useLegacyReplaceWorkspace bool) (*ChangeDetector, error) {
+// newChangeDetector creates a change detector reading the build events of the
+// interceptor, or the execution log when besInterceptor is nil.
+func newChangeDetector(workspaceDir string, useLegacyReplaceWorkspace bool, besInterceptor bep.BESInterceptor, warnings io.Writer) (*ChangeDetector, error) {
 	execlog, err := os.CreateTemp(os.TempDir(), fmt.Sprintf("aspect-watch-%v-execlog-*.bin", os.Getpid()))
 	if err != nil {
 		return nil, err
@@ -138,6 +160,18 @@ func newChangeDetector(workspaceDir string, useLegacyReplaceWorkspace bool) (*Ch
 		return nil, err
 	}
 
+	var events *bepOutputs
+	if besInterceptor != nil {
+		events = newBEPOutputs(besInterceptor)
+	}
+
+	// The runfiles manifests are not persisted when there is no cache directory.
+	cacheDir, err := cache.AspectCacheDir()
+	if err != nil {
+		logger.Infof("no runfiles manifest cache: %v", err)
+		cacheDir = ""
+	}
+
 	return &ChangeDetector{
 		workspaceDir:         workspaceDir,
 		execlogFile:          execlog,
@@ -149,10 +183,15 @@ func newChangeDetector(workspaceDir string, useLegacyReplaceWorkspace bool) (*Ch
 		targetExecutablePath: "",
 
 		useLegacyReplaceWorkspace: useLegacyReplaceWorkspace,
+
+		events:   events,
+		warnings: warnings,
+		cacheDir: cacheDir,
 	}, nil
 }
 
 func (cd *ChangeDetector) Close() error {
+	cd.manifestSave.Wait()
 	return errors.Join(
 		cd.execlogFile.Close(),
 		cd.watchManifestFile.Close(),
@@ -164,7 +203,10 @@ func (cd *ChangeDetector) Close() error {
 func (cd *ChangeDetector) bazelFlags(trackChanges bool) []string {
 	flags := []string{}
 
-	if trackChanges {
+	if cd.events != nil {
+		// The initial build is tracked too, as the baseline of the build events.
+		flags = append(flags, cd.events.bazelFlags()...)
+	} else if trackChanges {
 		flags = append(flags, "--execution_log_compact_file", cd.execlogFile.Name())
 	}
 
@@ -224,6 +266,9 @@ func (cd *ChangeDetector) detectContext() error {
 	}
 
 	cd.localExecroot = lines[0]
+	if runtime.GOOS == "windows" {
+		cd.localExecroot = fromMsysPath(cd.localExecroot)
+	}
 	cd.targetExecutablePath = lines[1]
 	cd.targetLabel = lines[2]
 	cd.targetTags = strings.Split(lines[3], ",")
@@ -244,7 +289,7 @@ func (cd *ChangeDetector) detectChanges(sourceChanges []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to cycle the runfiles manifest: %w", err)
 	}
-	execLogEntries, err := cd.cycleExecLog()
+	execLogEntries, err := cd.cycleOutputs(latestManifest)
 	if err != nil {
 		return fmt.Errorf("failed to cycle the execlog: %w", err)
 	}
@@ -258,33 +303,21 @@ func (cd *ChangeDetector) detectChanges(sourceChanges []string) error {
 	for _, execLogEntry := range execLogEntries {
 		// The actual outputs are the files that were actually produced by the action
 		if runfile, hasRunfile := latestManifest.fromInput(execLogEntry); hasRunfile {
-			si := &ibp.SourceInfo{
-				IsSymlink: toJsonBoolPtr(runfile.is_symlink),
-				IsSource:  toJsonBoolPtr(runfile.is_source),
-			}
-
-			cd.cycleSourceChanges[runfile.runfilesPath] = si
-			cd.sourcesInfo[runfile.runfilesPath] = si
+			cd.recordChange(latestManifest, runfile)
 		}
 	}
 
 	// Some source files may not be part of any action, but are still part of the runfiles tree.
+	// They are mapped by their path in the workspace, as reported by the watcher.
 	for _, changedSource := range sourceChanges {
-		absSourcePath := path.Join(cd.localExecroot, changedSource)
-		if runfile, hasRunfile := latestManifest.fromInput(absSourcePath); hasRunfile {
-			si := &ibp.SourceInfo{
-				IsSymlink: toJsonBoolPtr(runfile.is_symlink),
-				IsSource:  toJsonBoolPtr(runfile.is_source),
-			}
-
-			cd.cycleSourceChanges[runfile.runfilesPath] = si
-			cd.sourcesInfo[runfile.runfilesPath] = si
+		if runfile, hasRunfile := latestManifest.fromInput(changedSource); hasRunfile {
+			cd.recordChange(latestManifest, runfile)
 		}
 	}
 
 	// Deleted runfiles paths are those that were in the previous sources info but not the latest runfiles.
 	for lastRunfilesPath := range cd.sourcesInfo {
-		if _, stillHasRunfilesPath := latestManifest.runfiles[lastRunfilesPath]; !stillHasRunfilesPath {
+		if !latestManifest.contains(lastRunfilesPath) {
 			// Remove from the stored "last" source info
 			delete(cd.sourcesInfo, lastRunfilesPath)
 
@@ -296,12 +329,56 @@ func (cd *ChangeDetector) detectChanges(sourceChanges []string) error {
 	return nil
 }
 
+// recordChange records the change of a runfile, and of the runfiles paths
+// reaching it through the relative symlinks of the runfiles.
+func (cd *ChangeDetector) recordChange(m *manifestMetadata, runfile *manifestEntry) {
+	si := &ibp.SourceInfo{
+		IsSymlink: toJsonBoolPtr(runfile.is_symlink),
+		IsSource:  toJsonBoolPtr(runfile.is_source),
+	}
+	cd.cycleSourceChanges[runfile.runfilesPath] = si
+	cd.sourcesInfo[runfile.runfilesPath] = si
+
+	for _, alias := range m.aliases(runfile.runfilesPath) {
+		aliasInfo := &ibp.SourceInfo{
+			IsSymlink: toJsonBoolPtr(true),
+			IsSource:  si.IsSource,
+		}
+		cd.cycleSourceChanges[alias] = aliasInfo
+		cd.sourcesInfo[alias] = aliasInfo
+	}
+}
+
 func (cd *ChangeDetector) cycleChanges() ibp.SourceInfoMap {
 	changed := cd.cycleSourceChanges
 	cd.cycleSourceChanges = make(ibp.SourceInfoMap)
 	return changed
 }
 
+// cycleOutputs returns the outputs rebuilt by the last build, from its build
+// events or else from its execution log. If the build events are incomplete,
+// the following builds fall back to the execution log and all the runfiles are
+// assumed to have changed.
+func (cd *ChangeDetector) cycleOutputs(latestManifest *manifestMetadata) ([]string, error) {
+	if cd.events == nil {
+		return cd.cycleExecLog()
+	}
+
+	outputs, err := cd.events.changedOutputs()
+	if err == nil {
+		return outputs, nil
+	}
+
+	fmt.Fprintf(cd.warnings, "%s %v, falling back to the execution log to detect changes\n", color.YellowString("WARNING:"), err)
+	cd.events = nil
+
+	outputs = make([]string, 0, len(latestManifest.runfilesOriginMapping))
+	for origin := range latestManifest.runfilesOriginMapping {
+		outputs = append(outputs, origin)
+	}
+	return outputs, nil
+}
+
 // Cycle reparses execution log to discover inputs
 func (cd *ChangeDetector) cycleExecLog() ([]string, error) {
 	logger.Infof("read execlog: %s", cd.execlogFile.Name())
@@ -315,19 +392,37 @@ func (cd *ChangeDetector) cycleExecLog() ([]string, error) {
 	return parseCompactExecLogInputs(execLogFile)
 }
 
+// The magic number starting the zstd frames, see RFC 8878.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// The field numbers of the Output ids of the compact execution logs of bazel
+// 7.1 to 7.3, replaced by output_id since.
+const (
+	legacyOutputFileId              protowire.Number = 1
+	legacyOutputDirectoryId         protowire.Number = 2
+	legacyOutputUnresolvedSymlinkId protowire.Number = 3
+)
+
 func parseCompactExecLogInputs(in io.Reader) ([]string, error) {
-	zr, err := zstd.NewReader(in)
-	if err != nil {
-		return nil, err
+	// The compact execution log is compressed with zstd by bazel, but also
+	// accept an uncompressed log, such as one decompressed for debugging.
+	r := bufio.NewReader(in)
+	if magic, _ := r.Peek(len(zstdMagic)); bytes.Equal(magic, zstdMagic) {
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+
+		r = bufio.NewReader(zr)
 	}
-	defer zr.Close()
 
-	// Track files and outputs by id
-	filesById := make(map[uint32]string)
+	// Track the paths of the files, directories and symlinks by id
+	pathsById := make(map[uint32][]string)
 	outputIds := []uint32{}
+	inputs := []string{}
 	entry := &spawn.ExecLogEntry{}
 
-	r := bufio.NewReader(zr)
 	for {
 		if err := protodelim.UnmarshalFrom(r, entry); err != nil {
 			if errors.Is(err, io.EOF) {
@@ -336,55 +431,159 @@ func parseCompactExecLogInputs(in io.Reader) ([]string, error) {
 			return nil, err
 		}
 
-		// Track all files by their id
-		if f := entry.GetFile(); f != nil {
-			filesById[entry.Id] = f.GetPath()
-			continue
-		}
-
-		// Record outputs of spawn actions
-		if s := entry.GetSpawn(); s != nil {
-			for _, o := range s.GetOutputs() {
-				outputIds = append(outputIds, o.GetOutputId())
+		switch {
+		case entry.GetFile() != nil:
+			pathsById[entry.Id] = []string{entry.GetFile().GetPath()}
+		case entry.GetDirectory() != nil:
+			// Tree artifacts and source directories are listed file by file
+			// in the runfiles manifest.
+			d := entry.GetDirectory()
+			paths := []string{d.GetPath()}
+			for _, f := range d.GetFiles() {
+				paths = append(paths, path.Join(d.GetPath(), f.GetPath()))
 			}
+			pathsById[entry.Id] = paths
+		case entry.GetUnresolvedSymlink() != nil:
+			pathsById[entry.Id] = []string{entry.GetUnresolvedSymlink().GetPath()}
+		case entry.GetSpawn() != nil:
+			// Record outputs of spawn actions
+			for _, o := range entry.GetSpawn().GetOutputs() {
+				if id, ok := outputId(o); ok {
+					outputIds = append(outputIds, id)
+				}
+			}
+		case entry.GetSymlinkAction() != nil:
+			// Symlink actions are not spawns, but still output files
+			inputs = append(inputs, entry.GetSymlinkAction().GetOutputPath())
 		}
 	}
 
 	// Assume all outputIds are potential inputs to the next action
-	inputs := make([]string, 0, len(outputIds))
 	for _, oid := range outputIds {
-		if f, ok := filesById[oid]; ok {
-			inputs = append(inputs, f)
-		}
+		inputs = append(inputs, pathsById[oid]...)
 	}
 
 	return inputs, nil
 }
 
-// Cycle reparses execution log to discover inputs
-func (cd *ChangeDetector) parseRunfilesManifest() (*manifestMetadata, error) {
-	// TODO: cache based on manifest file stats?
+// outputId returns the entry id of the spawn output, in either the current or
+// the legacy format of the compact execution log.
+func outputId(o *spawn.ExecLogEntry_Output) (uint32, bool) {
+	if id, ok := o.GetType().(*spawn.ExecLogEntry_Output_OutputId); ok {
+		return id.OutputId, true
+	}
+
+	// The legacy ids are reserved fields, kept as unknown fields.
+	b := o.ProtoReflect().GetUnknown()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return 0, false
+		}
+		b = b[n:]
+
+		if typ == protowire.VarintType && (num == legacyOutputFileId || num == legacyOutputDirectoryId || num == legacyOutputUnresolvedSymlinkId) {
+			id, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return 0, false
+			}
+			return uint32(id), true
+		}
+
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return 0, false
+		}
+		b = b[n:]
+	}
+	return 0, false
+}
 
+// parseRunfilesManifest returns the runfiles manifest of the target, which is
+// only parsed again when its file changed since the last cycle, or since it was
+// persisted by a previous session.
+func (cd *ChangeDetector) parseRunfilesManifest() (*manifestMetadata, error) {
 	if cd.targetExecutablePath == "" {
 		return nil, fmt.Errorf("targetExecutablePath is not set")
 	}
 
-	manifestPath := fmt.Sprintf("%s.runfiles_manifest", cd.targetExecutablePath)
+	manifestPath := path.Join(cd.localExecroot, fmt.Sprintf("%s.runfiles_manifest", cd.targetExecutablePath))
+
+	info, err := os.Stat(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	if cd.manifest != nil && cd.manifest.matches(manifestPath, info) {
+		logger.Infof("runfiles manifest unchanged: %s", manifestPath)
+		return cd.manifest.metadata, nil
+	}
+
+	cachePath := cd.manifestCachePath()
+	if cd.manifest == nil && cachePath != "" {
+		if cached := loadManifestCache(cachePath, manifestPath, info); cached != nil {
+			logger.Infof("load cached runfiles manifest: %s", manifestPath)
+			cd.manifest = cached
+			cd.warnUnresolvedSymlinks(cached.metadata)
+			return cached.metadata, nil
+		}
+	}
 
 	logger.Infof("parse runfiles manifest: %s", manifestPath)
 
-	manifestFile, err := os.Open(path.Join(cd.localExecroot, manifestPath))
+	manifestFile, err := os.Open(manifestPath)
 	if err != nil {
 		return nil, err
 	}
 	defer manifestFile.Close()
 
-	return parseRunfilesManifest(manifestFile, cd.workspaceDir, cd.localExecroot)
+	manifest, err := parseRunfilesManifest(manifestFile, cd.workspaceDir, cd.localExecroot)
+	if err != nil {
+		return nil, err
+	}
+
+	cd.warnUnresolvedSymlinks(manifest)
+	cd.manifest = newManifestCache(manifestPath, info, manifest)
+	if cachePath != "" {
+		// Persisted in the background, as the parsed manifest is not modified.
+		cd.manifestSave.Wait()
+		cd.manifestSave.Add(1)
+		go func(c *manifestCache) {
+			defer cd.manifestSave.Done()
+			if err := c.save(cachePath); err != nil {
+				logger.Infof("failed to persist the runfiles manifest: %v", err)
+			}
+		}(cd.manifest)
+	}
+
+	return manifest, nil
+}
+
+// warnUnresolvedSymlinks warns about the symlinks of the runfiles, and of the
+// sources in the workspace, which couldn't be resolved once the manifest was
+// parsed, as the changes reached through them go unnoticed.
+func (cd *ChangeDetector) warnUnresolvedSymlinks(m *manifestMetadata) {
+	if len(m.unresolved) == 0 || cd.warnings == nil {
+		return
+	}
+	for _, u := range m.unresolved {
+		logger.Infof("unresolved symlink %s", u)
+	}
+	examples := strings.Join(m.unresolved[:min(len(m.unresolved), 3)], ", ")
+	if len(m.unresolved) > 3 {
+		examples += ", ..."
+	}
+	fmt.Fprintf(cd.warnings, "%s %d symlinks of the runfiles of %s can't be resolved, changes through them are not detected: %s\n", color.YellowString("WARNING:"), len(m.unresolved), cd.targetLabel, examples)
 }
 
 type manifestMetadata struct {
 	runfilesOriginMapping map[string]string
 	runfiles              map[string]*manifestEntry
+
+	// The relative symlinks of the runfiles by the runfiles path they resolve
+	// to, and the symlinks which couldn't be resolved, see resolveSymlinks.
+	// They are derived from the entries, so they aren't persisted.
+	symlinksByTarget map[string][]string
+	unresolved       []string
 }
 
 type manifestEntry struct {
@@ -399,6 +598,9 @@ func parseRunfilesManifest(in io.Reader, sourceDir, localExecroot string) (*mani
 	entries := map[string]*manifestEntry{}
 	bidi := map[string]string{}
 
+	sourceDir = toSlashPath(sourceDir)
+	localExecroot = toSlashPath(localExecroot)
+
 	workspaceName := path.Base(localExecroot)
 	workspaceNameSlash := workspaceName + "/"
 	sourceDirSlash := sourceDir + "/"
@@ -409,18 +611,31 @@ func parseRunfilesManifest(in io.Reader, sourceDir, localExecroot string) (*mani
 	// collect the inputs
 	for scan.Scan() {
 		line := scan.Text()
+
+		// Lines with spaces, newlines or backslashes in their paths are escaped
+		// and start with a space since bazel 7.4.
+		escaped := strings.HasPrefix(line, " ")
+		if escaped {
+			line = line[1:]
+		}
+
 		sp := strings.SplitN(line, " ", 2)
 		if len(sp) != 2 {
 			return nil, fmt.Errorf("malformed runfiles manifest line: %s, %d", line, len(sp))
 		}
 		runfilesPath := sp[0]
 		originPath := sp[1]
+		if escaped {
+			runfilesPath = manifestUnescaper.Replace(runfilesPath)
+			originPath = manifestUnescaper.Replace(originPath)
+		}
+		originPath = toSlashPath(originPath)
 
 		is_external := false
 		is_symlink := false
 		is_source := false
 
-		if !strings.HasPrefix(originPath, "/") {
+		if !isAbsPath(originPath) {
 			// Links are relative paths
 			is_symlink = true
 		} else if strings.HasPrefix(originPath, sourceDirSlash) {
@@ -450,15 +665,280 @@ func parseRunfilesManifest(in io.Reader, sourceDir, localExecroot string) (*mani
 		}
 	}
 
-	return &manifestMetadata{runfiles: entries, runfilesOriginMapping: bidi}, nil
+	m := &manifestMetadata{runfiles: entries, runfilesOriginMapping: bidi}
+	m.resolveSymlinks()
+	m.resolveSourceSymlinks(sourceDir)
+	return m, nil
 }
 
-func (m *manifestMetadata) fromInput(f string) (*manifestEntry, bool) {
-	runfile, ok := m.runfilesOriginMapping[f]
+// maxSymlinkHops bounds the symlinks followed to resolve a path, beyond which
+// the symlinks are assumed to form a cycle, as the kernel does.
+const maxSymlinkHops = 40
+
+var (
+	errDanglingSymlink = errors.New("dangling symlink")
+	errSymlinkCycle    = errors.New("too many levels of symlinks")
+	errSymlinkOutside  = errors.New("outside of the runfiles")
+)
+
+// resolveSymlinks resolves the relative symlinks of the runfiles, such as the
+// node_modules of rules_js linking the packages of the store, to the runfiles
+// paths they point to, following the symlinks of their target and of its
+// parent directories. The symlinks that are dangling, point outside of the
+// runfiles or form a cycle are recorded as unresolved.
+func (m *manifestMetadata) resolveSymlinks() {
+	m.symlinksByTarget = make(map[string][]string)
+
+	var links, paths []string
+	for p, e := range m.runfiles {
+		if e.is_symlink {
+			links = append(links, p)
+		}
+		paths = append(paths, p)
+	}
+	if len(links) == 0 {
+		return
+	}
+	slices.Sort(links)
+	slices.Sort(paths)
+
+	for _, link := range links {
+		target, err := m.resolve(link, paths)
+		if err != nil {
+			m.unresolved = append(m.unresolved, fmt.Sprintf("%s -> %s (%v)", link, m.runfiles[link].originPath, err))
+			continue
+		}
+		m.symlinksByTarget[target] = append(m.symlinksByTarget[target], link)
+	}
+}
+
+// resolve returns the runfiles path that p resolves to, given the sorted
+// runfiles paths. The result is a runfile or a directory of the runfiles.
+func (m *manifestMetadata) resolve(p string, paths []string) (string, error) {
+	for hops := 0; ; hops++ {
+		link, ok := m.symlinkPrefix(p)
+		if !ok {
+			break
+		}
+		if hops == maxSymlinkHops {
+			return "", errSymlinkCycle
+		}
+		target := path.Join(path.Dir(link), m.runfiles[link].originPath)
+		if target == ".." || strings.HasPrefix(target, "../") {
+			return "", errSymlinkOutside
+		}
+		p = target + p[len(link):]
+	}
+
+	if _, ok := m.runfiles[p]; ok {
+		return p, nil
+	}
+	// A directory of the runfiles, such as a package linked from the sources.
+	if i, _ := slices.BinarySearch(paths, p+"/"); i < len(paths) && strings.HasPrefix(paths[i], p+"/") {
+		return p, nil
+	}
+	return "", errDanglingSymlink
+}
+
+// symlinkPrefix returns the longest of p and its parent directories that is a
+// relative symlink of the runfiles.
+func (m *manifestMetadata) symlinkPrefix(p string) (string, bool) {
+	for q := p; q != "." && q != "/"; q = path.Dir(q) {
+		if e, ok := m.runfiles[q]; ok && e.is_symlink {
+			return q, true
+		}
+	}
+	return "", false
+}
+
+// aliases returns the runfiles paths that reach the runfile at p through the
+// relative symlinks of the runfiles, to p itself or to one of its directories.
+func (m *manifestMetadata) aliases(p string) []string {
+	if len(m.symlinksByTarget) == 0 {
+		return nil
+	}
+	seen := map[string]bool{p: true}
+	var aliases []string
+	var collect func(p string, depth int)
+	collect = func(p string, depth int) {
+		if depth == maxSymlinkHops {
+			return
+		}
+		for q := p; q != "." && q != "/"; q = path.Dir(q) {
+			for _, link := range m.symlinksByTarget[q] {
+				alias := link + p[len(q):]
+				if seen[alias] {
+					continue
+				}
+				seen[alias] = true
+				aliases = append(aliases, alias)
+				// The alias may itself be reached through further symlinks.
+				collect(alias, depth+1)
+			}
+		}
+	}
+	collect(p, 0)
+	return aliases
+}
+
+// resolveSourceSymlinks maps the real paths of the sources reached through
+// symlinks of the workspace, such as a directory linked into a package, to
+// their runfiles, since the watcher reports the changes to the real paths.
+// The symlinks that are dangling or form a cycle are recorded as unresolved.
+func (m *manifestMetadata) resolveSourceSymlinks(sourceDir string) {
+	realSourceDir := sourceDir
+	if r, err := filepath.EvalSymlinks(sourceDir); err == nil {
+		realSourceDir = toSlashPath(filepath.ToSlash(r))
+	}
+
+	var sources []*manifestEntry
+	for _, e := range m.runfiles {
+		if e.is_source {
+			sources = append(sources, e)
+		}
+	}
+	slices.SortFunc(sources, func(a, b *manifestEntry) int {
+		return strings.Compare(a.runfilesPath, b.runfilesPath)
+	})
+
+	realDirs := map[string]string{}
+	for _, e := range sources {
+		real, err := realSourcePath(sourceDir, realSourceDir, e.originPath, realDirs)
+		if err != nil {
+			m.unresolved = append(m.unresolved, fmt.Sprintf("%s (%v)", e.originPath, err))
+			continue
+		}
+		if real == "" || real == e.originPath {
+			continue
+		}
+		// The sources at their real path take precedence.
+		if _, ok := m.runfilesOriginMapping[real]; !ok {
+			m.runfilesOriginMapping[real] = e.runfilesPath
+		}
+	}
+}
+
+// realSourcePath returns the path in the workspace that the source at the
+// relative path p resolves to, or an empty string when it resolves outside of
+// the workspace or can't be read. The real paths of the directories are
+// memoized in realDirs, as the sources share most of them.
+func realSourcePath(sourceDir, realSourceDir, p string, realDirs map[string]string) (string, error) {
+	relative := func(real string) (string, bool) {
+		real = toSlashPath(filepath.ToSlash(real))
+		if real == realSourceDir {
+			return ".", true
+		}
+		rel, ok := strings.CutPrefix(real, realSourceDir+"/")
+		return rel, ok
+	}
+
+	dir := path.Dir(p)
+	realDir, ok := realDirs[dir]
 	if !ok {
-		return nil, false
+		realDir = dir
+		if r, err := filepath.EvalSymlinks(path.Join(sourceDir, dir)); err == nil {
+			if realDir, ok = relative(r); !ok {
+				realDir = ""
+			}
+		}
+		realDirs[dir] = realDir
+	}
+	if realDir == "" {
+		return "", nil
+	}
+
+	real := path.Join(realDir, path.Base(p))
+	info, err := os.Lstat(path.Join(realSourceDir, real))
+	if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+		return real, nil
+	}
+	r, err := filepath.EvalSymlinks(path.Join(realSourceDir, real))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", errDanglingSymlink
+	}
+	if err != nil {
+		return "", errSymlinkCycle
+	}
+	if real, ok = relative(r); !ok {
+		return "", nil
+	}
+	return real, nil
+}
+
+// Unescapes the paths of the escaped lines of the runfiles manifests.
+var manifestUnescaper = strings.NewReplacer(`\s`, " ", `\n`, "\n", `\b`, `\`)
+
+// hasDriveLetter returns whether the path starts with a Windows drive letter,
+// e.g. C:/Users or c:\Users.
+func hasDriveLetter(p string) bool {
+	return len(p) >= 2 && p[1] == ':' && ('a' <= p[0] && p[0] <= 'z' || 'A' <= p[0] && p[0] <= 'Z')
+}
+
+// isAbsPath returns whether the path of the runfiles manifest is absolute, on
+// POSIX or on Windows once converted with toSlashPath.
+func isAbsPath(p string) bool {
+	return strings.HasPrefix(p, "/") || hasDriveLetter(p)
+}
+
+// toSlashPath converts the Windows paths of the runfiles manifest and of the
+// workspace, such as c:\Users\me\repo or \\server\share\repo, to forward
+// slashes and an upper-case drive letter, as bazel writes them in the runfiles
+// manifest. Other paths are kept as is, as backslashes are valid in the names
+// of POSIX files.
+func toSlashPath(p string) string {
+	switch {
+	case strings.HasPrefix(p, `\\`):
+		return strings.ReplaceAll(p, `\`, "/")
+	case hasDriveLetter(p):
+		return strings.ToUpper(p[:1]) + strings.ReplaceAll(p[1:], `\`, "/")
+	default:
+		return p
+	}
+}
+
+// fromMsysPath converts a path printed by the bash of MSYS2 on Windows, such
+// as /c/Users/me/repo, to a Windows path, such as C:/Users/me/repo.
+func fromMsysPath(p string) string {
+	if len(p) >= 2 && p[0] == '/' && (len(p) == 2 || p[2] == '/') {
+		if drive := p[1:2] + ":"; hasDriveLetter(drive) {
+			return toSlashPath(drive + p[2:])
+		}
+	}
+	return toSlashPath(p)
+}
+
+func (m *manifestMetadata) fromInput(f string) (*manifestEntry, bool) {
+	if runfile, ok := m.runfilesOriginMapping[f]; ok {
+		return m.runfiles[runfile], ok
+	}
+
+	// The files of the directories of the runfiles, such as the tree artifacts
+	// of the node_modules of rules_js, are under the runfiles path of their
+	// directory.
+	for dir := path.Dir(f); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if runfile, ok := m.runfilesOriginMapping[dir]; ok {
+			e := m.runfiles[runfile]
+			return &manifestEntry{
+				runfilesPath: runfile + f[len(dir):],
+				originPath:   f,
+				is_external:  e.is_external,
+				is_symlink:   e.is_symlink,
+				is_source:    e.is_source,
+			}, true
+		}
+	}
+	return nil, false
+}
+
+// contains reports whether p is the runfiles path of a runfile, or of a file
+// under one of the directories of the runfiles.
+func (m *manifestMetadata) contains(p string) bool {
+	for q := p; q != "." && q != "/"; q = path.Dir(q) {
+		if _, ok := m.runfiles[q]; ok {
+			return true
+		}
 	}
-	return m.runfiles[runfile], ok
+	return false
 }
 
 func toJsonBoolPtr(b bool) *bool {
@@ -0,0 +1,161 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package run
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+)
+
+// The output group of aspect_watch.bzl with the runfiles of the run target,
+// reported file by file in the build events.
+const watchRunfilesOutputGroup = "__aspect_watch_runfiles"
+
+// How long to wait for the build events of a build once bazel has exited.
+var bepOutputsTimeout = 5 * time.Second
+
+// The build events read to detect the rebuilt outputs.
+var bepOutputsEventTypes = []string{"target_configured", "named_set", "build_finished"}
+
+// bepOutputs detects the outputs rebuilt by the watch builds from the digests
+// of the runfiles reported in their build events, sparing the cost of writing
+// the execution log on every build.
+type bepOutputs struct {
+	mu sync.Mutex
+
+	// The invocation of the last build, and whether its events are complete.
+	invocationId string
+	finished     chan struct{}
+
+	// The targets configured and the fingerprints of the files reported by
+	// the last build, and the fingerprints of the files of the build before.
+	configured []string
+	files      map[string]string
+	previous   map[string]string
+}
+
+// newBEPOutputs subscribes to the build events of the interceptor.
+func newBEPOutputs(besInterceptor bep.BESInterceptor) *bepOutputs {
+	o := &bepOutputs{}
+	besInterceptor.RegisterSubscriber(o.callback, bep.SubscriberOptions{}, bepOutputsEventTypes...)
+	return o
+}
+
+// bazelFlags starts tracking the events of a new build, which is given its
+// own invocation id to tell its events apart.
+func (o *bepOutputs) bazelFlags() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	// The files of the initial build are the baseline of the first cycle.
+	if o.previous == nil {
+		o.previous = o.files
+	}
+
+	o.invocationId = uuid.NewString()
+	o.finished = make(chan struct{})
+	o.configured = nil
+	o.files = make(map[string]string)
+
+	return []string{
+		"--invocation_id=" + o.invocationId,
+		"--output_groups=+" + watchRunfilesOutputGroup,
+	}
+}
+
+func (o *bepOutputs) callback(event *buildeventstream.BuildEvent, _ int64, stream bep.StreamInfo) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if stream.InvocationId != o.invocationId {
+		return nil
+	}
+
+	switch {
+	case event.GetConfigured() != nil:
+		o.configured = append(o.configured, event.GetId().GetTargetConfigured().GetLabel())
+	case event.GetNamedSetOfFiles() != nil:
+		for _, f := range event.GetNamedSetOfFiles().GetFiles() {
+			o.files[path.Join(append(f.GetPathPrefix(), f.GetName())...)] = fingerprint(f)
+		}
+	case event.GetFinished() != nil:
+		select {
+		case <-o.finished:
+		default:
+			close(o.finished)
+		}
+	}
+	return nil
+}
+
+// changedOutputs waits for the events of the last build and returns the files
+// whose fingerprint changed since the build before.
+func (o *bepOutputs) changedOutputs() ([]string, error) {
+	o.mu.Lock()
+	invocationId, finished := o.invocationId, o.finished
+	o.mu.Unlock()
+
+	select {
+	case <-finished:
+	case <-time.After(bepOutputsTimeout):
+		return nil, fmt.Errorf("the build events of invocation %s did not complete within %v", invocationId, bepOutputsTimeout)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.configured) == 0 {
+		return nil, fmt.Errorf("no target was configured in the build events of invocation %s", invocationId)
+	}
+
+	changed := []string{}
+	for p, f := range o.files {
+		if last, ok := o.previous[p]; !ok || last != f {
+			changed = append(changed, p)
+		}
+	}
+	o.previous = o.files
+	return changed, nil
+}
+
+// fingerprint identifies the content of a file reported in the build events:
+// its digest when bazel reports one, or else the size and modification time
+// of the local file.
+func fingerprint(f *buildeventstream.File) string {
+	if f.GetDigest() != "" {
+		return fmt.Sprintf("%s/%d", f.GetDigest(), f.GetLength())
+	}
+	if u, err := url.Parse(f.GetUri()); err == nil && u.Scheme == "file" {
+		// The file URIs of Windows have a leading slash, e.g. file:///C:/Users.
+		p := u.Path
+		if len(p) > 2 && p[0] == '/' && hasDriveLetter(p[1:]) {
+			p = p[1:]
+		}
+		if info, err := os.Stat(p); err == nil {
+			return fmt.Sprintf("%d@%d", info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return f.GetUri()
+}
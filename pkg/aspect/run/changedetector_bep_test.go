@@ -0,0 +1,164 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package run
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+)
+
+// bepBuild reports the events of a build to the outputs, as bazel would with
+// the flags of the outputs.
+func bepBuild(t *testing.T, o *bepOutputs, configured bool, files ...*buildeventstream.File) {
+	t.Helper()
+
+	var invocationId string
+	for _, flag := range o.bazelFlags() {
+		if id, ok := strings.CutPrefix(flag, "--invocation_id="); ok {
+			invocationId = id
+		}
+	}
+	if invocationId == "" {
+		t.Fatalf("Expected an --invocation_id flag")
+	}
+	stream := bep.StreamInfo{InvocationId: invocationId}
+
+	events := []*buildeventstream.BuildEvent{}
+	if configured {
+		events = append(events, &buildeventstream.BuildEvent{
+			Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_TargetConfigured{
+				TargetConfigured: &buildeventstream.BuildEventId_TargetConfiguredId{Label: "//app:server"},
+			}},
+			Payload: &buildeventstream.BuildEvent_Configured{Configured: &buildeventstream.TargetConfigured{}},
+		})
+	}
+	events = append(events,
+		&buildeventstream.BuildEvent{
+			Payload: &buildeventstream.BuildEvent_NamedSetOfFiles{NamedSetOfFiles: &buildeventstream.NamedSetOfFiles{Files: files}},
+		},
+		&buildeventstream.BuildEvent{
+			Payload: &buildeventstream.BuildEvent_Finished{Finished: &buildeventstream.BuildFinished{}},
+		},
+	)
+	for i, event := range events {
+		if err := o.callback(event, int64(i), stream); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+}
+
+func bepFile(name, digest string) *buildeventstream.File {
+	prefix, name := filepath.Split(name)
+	return &buildeventstream.File{
+		PathPrefix: strings.Split(strings.TrimSuffix(prefix, "/"), "/"),
+		Name:       name,
+		Digest:     digest,
+	}
+}
+
+func TestBEPOutputs(t *testing.T) {
+	t.Run("detects the files whose digest changed since the last build", func(t *testing.T) {
+		o := &bepOutputs{}
+		bepBuild(t, o, true, bepFile("bazel-out/bin/app/main.js", "a"), bepFile("bazel-out/bin/app/lib.js", "b"))
+		bepBuild(t, o, true, bepFile("bazel-out/bin/app/main.js", "c"), bepFile("bazel-out/bin/app/lib.js", "b"), bepFile("bazel-out/bin/app/new.js", "d"))
+
+		changed, err := o.changedOutputs()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		slices.Sort(changed)
+		if expected := []string{"bazel-out/bin/app/main.js", "bazel-out/bin/app/new.js"}; !slices.Equal(changed, expected) {
+			t.Errorf("Expected %v, got %v", expected, changed)
+		}
+
+		bepBuild(t, o, true, bepFile("bazel-out/bin/app/main.js", "c"), bepFile("bazel-out/bin/app/lib.js", "b"), bepFile("bazel-out/bin/app/new.js", "d"))
+		if changed, err := o.changedOutputs(); err != nil || len(changed) != 0 {
+			t.Errorf("Expected no changes, got %v, %v", changed, err)
+		}
+	})
+
+	t.Run("ignores the events of other invocations", func(t *testing.T) {
+		o := &bepOutputs{}
+		bepBuild(t, o, true, bepFile("bazel-out/bin/app/main.js", "a"))
+		bepBuild(t, o, true, bepFile("bazel-out/bin/app/main.js", "a"))
+
+		other := &buildeventstream.BuildEvent{
+			Payload: &buildeventstream.BuildEvent_NamedSetOfFiles{NamedSetOfFiles: &buildeventstream.NamedSetOfFiles{
+				Files: []*buildeventstream.File{bepFile("bazel-out/bin/app/main.js", "b")},
+			}},
+		}
+		if err := o.callback(other, 0, bep.StreamInfo{InvocationId: "other"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if changed, err := o.changedOutputs(); err != nil || len(changed) != 0 {
+			t.Errorf("Expected no changes, got %v, %v", changed, err)
+		}
+	})
+
+	t.Run("falls back to the local files without digests", func(t *testing.T) {
+		dir := t.TempDir()
+		file := filepath.Join(dir, "main.js")
+		if err := os.WriteFile(file, []byte("a"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		f := &buildeventstream.File{
+			PathPrefix: []string{"bazel-out", "bin"},
+			Name:       "main.js",
+			File:       &buildeventstream.File_Uri{Uri: "file://" + filepath.ToSlash(file)},
+		}
+
+		o := &bepOutputs{}
+		bepBuild(t, o, true, f)
+		if err := os.WriteFile(file, []byte("ab"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		bepBuild(t, o, true, f)
+
+		if changed, err := o.changedOutputs(); err != nil || !slices.Equal(changed, []string{"bazel-out/bin/main.js"}) {
+			t.Errorf("Expected the changed file, got %v, %v", changed, err)
+		}
+	})
+
+	t.Run("fails without configured targets", func(t *testing.T) {
+		o := &bepOutputs{}
+		bepBuild(t, o, false, bepFile("bazel-out/bin/app/main.js", "a"))
+
+		if _, err := o.changedOutputs(); err == nil || !strings.Contains(err.Error(), "no target was configured") {
+			t.Errorf("Expected an error, got %v", err)
+		}
+	})
+
+	t.Run("fails without the end of the build events", func(t *testing.T) {
+		timeout := bepOutputsTimeout
+		bepOutputsTimeout = 10 * time.Millisecond
+		t.Cleanup(func() { bepOutputsTimeout = timeout })
+
+		o := &bepOutputs{}
+		o.bazelFlags()
+
+		if _, err := o.changedOutputs(); err == nil || !strings.Contains(err.Error(), "did not complete") {
+			t.Errorf("Expected an error, got %v", err)
+		}
+	})
+}
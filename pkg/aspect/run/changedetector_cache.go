@@ -0,0 +1,166 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package run
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	logger "github.com/aspect-build/aspect-gazelle/common/logger"
+)
+
+// manifestCache is a parsed runfiles manifest, along with the stat of the
+// manifest file it was parsed from. It is kept in memory across the cycles of
+// a watch session, and persisted in the Aspect cache directory so that a
+// restarted session doesn't parse the manifest again when it didn't change.
+type manifestCache struct {
+	Path    string
+	Size    int64
+	ModTime int64
+
+	// The entries of the manifest, and the mapping of their origin paths,
+	// which is kept as is as the later entries of an origin take precedence.
+	Entries []manifestCacheEntry
+	Origins []manifestCacheOrigin
+
+	metadata *manifestMetadata
+}
+
+type manifestCacheEntry struct {
+	RunfilesPath string
+	OriginPath   string
+	IsExternal   bool
+	IsSymlink    bool
+	IsSource     bool
+}
+
+type manifestCacheOrigin struct {
+	OriginPath   string
+	RunfilesPath string
+}
+
+// matches returns whether the cache was parsed from the manifest file at path
+// of the given stat.
+func (c *manifestCache) matches(path string, info os.FileInfo) bool {
+	return c.Path == path && c.Size == info.Size() && c.ModTime == info.ModTime().UnixNano()
+}
+
+func newManifestCache(path string, info os.FileInfo, m *manifestMetadata) *manifestCache {
+	return &manifestCache{
+		Path:     path,
+		Size:     info.Size(),
+		ModTime:  info.ModTime().UnixNano(),
+		metadata: m,
+	}
+}
+
+// manifestCachePath returns the path of the persisted manifest of a target,
+// keyed by the workspace and by the label and the executable of the target,
+// whose path is specific to the configuration it was built in.
+func (cd *ChangeDetector) manifestCachePath() string {
+	if cd.cacheDir == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s\x00%s", cd.workspaceDir, cd.localExecroot, cd.targetLabel, cd.targetExecutablePath)))
+	return filepath.Join(cd.cacheDir, "watch", hex.EncodeToString(sum[:8])+".gob")
+}
+
+// loadManifestCache returns the persisted manifest at path if it was parsed
+// from the manifest file at manifestPath of the given stat, or nil.
+func loadManifestCache(path, manifestPath string, info os.FileInfo) *manifestCache {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var c manifestCache
+	if err := gob.NewDecoder(f).Decode(&c); err != nil {
+		logger.Infof("ignore the runfiles manifest cache %s: %v", path, err)
+		return nil
+	}
+	if !c.matches(manifestPath, info) {
+		return nil
+	}
+
+	m := &manifestMetadata{
+		runfiles:              make(map[string]*manifestEntry, len(c.Entries)),
+		runfilesOriginMapping: make(map[string]string, len(c.Origins)),
+	}
+	for _, e := range c.Entries {
+		m.runfiles[e.RunfilesPath] = &manifestEntry{
+			runfilesPath: e.RunfilesPath,
+			originPath:   e.OriginPath,
+			is_external:  e.IsExternal,
+			is_symlink:   e.IsSymlink,
+			is_source:    e.IsSource,
+		}
+	}
+	for _, o := range c.Origins {
+		m.runfilesOriginMapping[o.OriginPath] = o.RunfilesPath
+	}
+	// The real paths of the sources are persisted with the origins.
+	m.resolveSymlinks()
+	c.Entries, c.Origins = nil, nil
+	c.metadata = m
+	return &c
+}
+
+// save persists the manifest at path, replacing the previous one atomically.
+func (c *manifestCache) save(path string) error {
+	m := c.metadata
+	persisted := manifestCache{
+		Path:    c.Path,
+		Size:    c.Size,
+		ModTime: c.ModTime,
+		Entries: make([]manifestCacheEntry, 0, len(m.runfiles)),
+		Origins: make([]manifestCacheOrigin, 0, len(m.runfilesOriginMapping)),
+	}
+	for _, e := range m.runfiles {
+		persisted.Entries = append(persisted.Entries, manifestCacheEntry{
+			RunfilesPath: e.runfilesPath,
+			OriginPath:   e.originPath,
+			IsExternal:   e.is_external,
+			IsSymlink:    e.is_symlink,
+			IsSource:     e.is_source,
+		})
+	}
+	for origin, runfilesPath := range m.runfilesOriginMapping {
+		persisted.Origins = append(persisted.Origins, manifestCacheOrigin{OriginPath: origin, RunfilesPath: runfilesPath})
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := gob.NewEncoder(tmp).Encode(&persisted); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
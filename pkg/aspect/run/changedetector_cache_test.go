@@ -0,0 +1,112 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package run
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRunfilesManifestCache(t *testing.T) {
+	workspaceDir := t.TempDir()
+	execroot := filepath.Join(t.TempDir(), "_main")
+	binDir := filepath.Join(execroot, "bazel-out/k8-fastbuild/bin")
+	if err := os.MkdirAll(binDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	manifestPath := filepath.Join(binDir, "dev.runfiles_manifest")
+	writeManifest := func(content string, modTime time.Time) {
+		if err := os.WriteFile(manifestPath, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(manifestPath, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	modTime := time.Now().Add(-time.Hour)
+	writeManifest("_main/README.md "+workspaceDir+"/README.md\n"+
+		"_main/lib/index.js "+binDir+"/lib/index.js\n"+
+		"_main/link lib/index.js\n", modTime)
+
+	cacheDir := t.TempDir()
+	newDetector := func() *ChangeDetector {
+		return &ChangeDetector{
+			workspaceDir:         workspaceDir,
+			localExecroot:        execroot,
+			targetLabel:          "//:dev",
+			targetExecutablePath: "bazel-out/k8-fastbuild/bin/dev",
+			cacheDir:             cacheDir,
+		}
+	}
+
+	cd := newDetector()
+	parsed, err := cd.parseRunfilesManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.runfiles) != 3 || parsed.runfilesOriginMapping["README.md"] != "_main/README.md" {
+		t.Errorf("Expected the parsed manifest, got %v", parsed.runfilesOriginMapping)
+	}
+
+	// Reused while the manifest is unchanged.
+	if again, err := cd.parseRunfilesManifest(); err != nil || again != parsed {
+		t.Errorf("Expected the unchanged manifest to be reused, got %v, %v", again, err)
+	}
+
+	// Loaded by the next session once persisted.
+	cd.manifestSave.Wait()
+	next := newDetector()
+	loaded, err := next.parseRunfilesManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded == parsed || !reflect.DeepEqual(loaded, parsed) {
+		t.Errorf("Expected the persisted manifest, got %v", loaded)
+	}
+
+	// Persisted per target.
+	other := newDetector()
+	other.targetLabel = "//:other"
+	if p := other.manifestCachePath(); p == next.manifestCachePath() {
+		t.Errorf("Expected a cache per target, got %s", p)
+	}
+
+	// Parsed again once changed.
+	writeManifest("_main/README.md "+workspaceDir+"/README.md\n", modTime.Add(time.Second))
+	changed, err := next.parseRunfilesManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed.runfiles) != 1 {
+		t.Errorf("Expected the changed manifest, got %v", changed.runfiles)
+	}
+	next.manifestSave.Wait()
+	if cached := loadManifestCache(next.manifestCachePath(), manifestPath, mustStat(t, manifestPath)); cached == nil || len(cached.metadata.runfiles) != 1 {
+		t.Errorf("Expected the changed manifest to be persisted, got %v", cached)
+	}
+}
+
+func mustStat(t *testing.T, p string) os.FileInfo {
+	info, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
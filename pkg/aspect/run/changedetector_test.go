@@ -20,12 +20,18 @@ import (
 	"bytes"
 	_ "embed"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"slices"
 	"strings"
 	"testing"
 
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/aspect-build/aspect-cli-legacy/bazel/spawn"
 	"github.com/aspect-build/aspect-gazelle/runner/pkg/ibp"
 )
 
@@ -47,6 +53,76 @@ func TestExecLogCompact(t *testing.T) {
 	}
 }
 
+func writeExecLog(t *testing.T, compress bool, entries ...*spawn.ExecLogEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := io.Writer(&buf)
+	var zw *zstd.Encoder
+	if compress {
+		var err error
+		if zw, err = zstd.NewWriter(&buf); err != nil {
+			t.Fatal(err)
+		}
+		w = zw
+	}
+	for _, e := range entries {
+		if _, err := protodelim.MarshalTo(w, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestExecLogCompactEntries(t *testing.T) {
+	legacyOutput := &spawn.ExecLogEntry_Output{}
+	legacyOutput.ProtoReflect().SetUnknown(protowire.AppendVarint(protowire.AppendTag(nil, legacyOutputDirectoryId, protowire.VarintType), 2))
+
+	entries := []*spawn.ExecLogEntry{
+		{Type: &spawn.ExecLogEntry_Invocation_{Invocation: &spawn.ExecLogEntry_Invocation{HashFunctionName: "SHA-256"}}},
+		{Id: 1, Type: &spawn.ExecLogEntry_File_{File: &spawn.ExecLogEntry_File{Path: "bazel-out/bin/app/main.js"}}},
+		{Id: 2, Type: &spawn.ExecLogEntry_Directory_{Directory: &spawn.ExecLogEntry_Directory{
+			Path:  "bazel-out/bin/app/assets",
+			Files: []*spawn.ExecLogEntry_File{{Path: "logo.svg"}, {Path: "css/app.css"}},
+		}}},
+		{Id: 3, Type: &spawn.ExecLogEntry_UnresolvedSymlink_{UnresolvedSymlink: &spawn.ExecLogEntry_UnresolvedSymlink{Path: "bazel-out/bin/app/current", TargetPath: "main.js"}}},
+		{Id: 4, Type: &spawn.ExecLogEntry_File_{File: &spawn.ExecLogEntry_File{Path: "app/unused.js"}}},
+		{Type: &spawn.ExecLogEntry_Spawn_{Spawn: &spawn.ExecLogEntry_Spawn{Outputs: []*spawn.ExecLogEntry_Output{
+			{Type: &spawn.ExecLogEntry_Output_OutputId{OutputId: 1}},
+			{Type: &spawn.ExecLogEntry_Output_OutputId{OutputId: 3}},
+			{Type: &spawn.ExecLogEntry_Output_InvalidOutputPath{InvalidOutputPath: "bazel-out/bin/app/missing.js"}},
+		}}}},
+		{Type: &spawn.ExecLogEntry_Spawn_{Spawn: &spawn.ExecLogEntry_Spawn{Outputs: []*spawn.ExecLogEntry_Output{legacyOutput}}}},
+		{Type: &spawn.ExecLogEntry_SymlinkAction_{SymlinkAction: &spawn.ExecLogEntry_SymlinkAction{InputPath: "bazel-out/bin/app/main.js", OutputPath: "bazel-out/bin/app/main"}}},
+	}
+	expected := []string{
+		"bazel-out/bin/app/assets",
+		"bazel-out/bin/app/assets/css/app.css",
+		"bazel-out/bin/app/assets/logo.svg",
+		"bazel-out/bin/app/current",
+		"bazel-out/bin/app/main",
+		"bazel-out/bin/app/main.js",
+	}
+
+	for _, compress := range []bool{true, false} {
+		t.Run(fmt.Sprintf("compressed=%v", compress), func(t *testing.T) {
+			r, err := parseCompactExecLogInputs(bytes.NewReader(writeExecLog(t, compress, entries...)))
+			if err != nil {
+				t.Fatalf("Failed to parse exec log: %v", err)
+			}
+
+			slices.Sort(r)
+			if !slices.Equal(r, expected) {
+				t.Errorf("Expected inputs %v, got %v", expected, r)
+			}
+		})
+	}
+}
+
 func TestParseRunfilesManifest(t *testing.T) {
 	// A small subset of a runfiles manifest copied from a real build
 	runfilesManifest := `
@@ -110,6 +186,186 @@ rules_nodejs~~node~nodejs_darwin_arm64/bin/nodejs/bin/node /private/var/tmp/_baz
 	}
 }
 
+func TestResolveRunfilesSymlinks(t *testing.T) {
+	runfilesManifest := `
+_main/mylib/index.js /exec/_main/bazel-out/bin/mylib/index.js
+_main/node_modules/.aspect_rules_js/mylib@0.0.0/node_modules/mylib ../../../../mylib
+_main/node_modules/mylib .aspect_rules_js/mylib@0.0.0/node_modules/mylib
+_main/node_modules/.aspect_rules_js/pkg@1.0.0/node_modules/pkg /exec/_main/bazel-out/bin/node_modules/.aspect_rules_js/pkg@1.0.0/node_modules/pkg
+_main/app/node_modules/chalk ../../node_modules/.aspect_rules_js/chalk@4.1.2/node_modules/chalk
+_main/loop/a b
+_main/loop/b a
+_main/escape ../../outside
+`
+
+	r, err := parseRunfilesManifest(strings.NewReader(strings.TrimSpace(runfilesManifest)), "/src", "/exec/_main")
+	if err != nil {
+		t.Fatalf("Failed to parse the runfiles manifest: %v", err)
+	}
+
+	// The package linked from the sources is reached through the store and
+	// through the node_modules linking the store.
+	aliases := r.aliases("_main/mylib/index.js")
+	slices.Sort(aliases)
+	expectedAliases := []string{
+		"_main/node_modules/.aspect_rules_js/mylib@0.0.0/node_modules/mylib/index.js",
+		"_main/node_modules/mylib/index.js",
+	}
+	if !slices.Equal(aliases, expectedAliases) {
+		t.Errorf("Expected aliases %v, got %v", expectedAliases, aliases)
+	}
+
+	// The files of a tree artifact are under its runfiles path.
+	treeFile, ok := r.fromInput("bazel-out/bin/node_modules/.aspect_rules_js/pkg@1.0.0/node_modules/pkg/index.js")
+	if !ok || treeFile.runfilesPath != "_main/node_modules/.aspect_rules_js/pkg@1.0.0/node_modules/pkg/index.js" {
+		t.Errorf("Expected the file of the tree artifact to map to its runfiles path, got %v", treeFile)
+	}
+	if !r.contains("_main/node_modules/.aspect_rules_js/pkg@1.0.0/node_modules/pkg/index.js") || r.contains("_main/mylib/gone.js") {
+		t.Errorf("Expected the runfiles to contain the files of their directories only")
+	}
+
+	// The dangling symlinks, the cycles and the symlinks leaving the runfiles
+	// are reported rather than failing.
+	expectedUnresolved := []string{
+		"_main/app/node_modules/chalk -> ../../node_modules/.aspect_rules_js/chalk@4.1.2/node_modules/chalk (dangling symlink)",
+		"_main/escape -> ../../outside (outside of the runfiles)",
+		"_main/loop/a -> b (too many levels of symlinks)",
+		"_main/loop/b -> a (too many levels of symlinks)",
+	}
+	if !slices.Equal(r.unresolved, expectedUnresolved) {
+		t.Errorf("Expected unresolved symlinks %v, got %v", expectedUnresolved, r.unresolved)
+	}
+}
+
+func TestResolveSourceSymlinks(t *testing.T) {
+	workspace := t.TempDir()
+	for _, dir := range []string{"shared", "web"} {
+		if err := os.Mkdir(path.Join(workspace, dir), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	for _, file := range []string{"README.md", "shared/util.js", "web/index.js"} {
+		if err := os.WriteFile(path.Join(workspace, file), nil, 0644); err != nil {
+			t.Fatalf("write %s: %v", file, err)
+		}
+	}
+	for link, target := range map[string]string{
+		"web/shared":    "../shared",
+		"web/README.md": "../README.md",
+		"web/broken.js": "missing.js",
+	} {
+		if err := os.Symlink(target, path.Join(workspace, link)); err != nil {
+			t.Fatalf("symlink %s: %v", link, err)
+		}
+	}
+
+	runfilesManifest := fmt.Sprintf(`
+_main/web/shared/util.js %[1]s/web/shared/util.js
+_main/web/README.md %[1]s/web/README.md
+_main/web/broken.js %[1]s/web/broken.js
+_main/web/index.js %[1]s/web/index.js
+`, workspace)
+
+	r, err := parseRunfilesManifest(strings.NewReader(strings.TrimSpace(runfilesManifest)), workspace, "/exec/_main")
+	if err != nil {
+		t.Fatalf("Failed to parse the runfiles manifest: %v", err)
+	}
+
+	for real, runfilesPath := range map[string]string{
+		"shared/util.js": "_main/web/shared/util.js",
+		"README.md":      "_main/web/README.md",
+		"web/index.js":   "_main/web/index.js",
+	} {
+		if r.runfilesOriginMapping[real] != runfilesPath {
+			t.Errorf("Expected %s to map to %s, got %q", real, runfilesPath, r.runfilesOriginMapping[real])
+		}
+	}
+	if !slices.Equal(r.unresolved, []string{"web/broken.js (dangling symlink)"}) {
+		t.Errorf("Expected the dangling symlink to be unresolved, got %v", r.unresolved)
+	}
+}
+
+func TestWarnUnresolvedSymlinks(t *testing.T) {
+	var warnings bytes.Buffer
+	cd := &ChangeDetector{targetLabel: "//app", warnings: &warnings}
+
+	cd.warnUnresolvedSymlinks(&manifestMetadata{})
+	if warnings.Len() != 0 {
+		t.Errorf("Expected no warning, got %q", warnings.String())
+	}
+
+	cd.warnUnresolvedSymlinks(&manifestMetadata{unresolved: []string{"a", "b", "c", "d"}})
+	if !strings.Contains(warnings.String(), "4 symlinks of the runfiles of //app can't be resolved, changes through them are not detected: a, b, c, ...") {
+		t.Errorf("Expected a warning listing the first unresolved symlinks, got %q", warnings.String())
+	}
+}
+
+func TestParseRunfilesManifestWindows(t *testing.T) {
+	// A runfiles manifest of Windows, without symlinks as the runfiles tree is
+	// not created, and with an escaped line.
+	runfilesManifest := `
+_main/README.md C:/Users/me/dev/repo/README.md
+_main/dev_/dev.exe C:/users/me/_bazel_me/3c4e0d2f/execroot/_main/bazel-out/x64_windows-fastbuild/bin/dev_/dev.exe
+_main/mylib/index.js c:\users\me\_bazel_me\3c4e0d2f\execroot\_main\bazel-out\x64_windows-fastbuild\bin\mylib\index.js
+ _main/my\sfile.txt C:\bUsers\bme\bdev\brepo\bmy\sfile.txt
+rules_nodejs~~node~nodejs_windows_amd64/bin/nodejs/node.exe C:/users/me/_bazel_me/3c4e0d2f/external/rules_nodejs~~node~nodejs_windows_amd64/bin/nodejs/node.exe
+`
+
+	r, err := parseRunfilesManifest(strings.NewReader(strings.TrimSpace(runfilesManifest)), `c:\Users\me\dev\repo`, `C:\users\me\_bazel_me\3c4e0d2f\execroot\_main`)
+	if err != nil {
+		t.Fatalf("Failed to parse runfiles manifest: %v", err)
+	}
+
+	if len(r.runfiles) != 5 {
+		t.Errorf("Expected 5 runfiles, got %d", len(r.runfiles))
+	}
+	for runfilesPath, runfile := range r.runfiles {
+		if runfile.is_symlink {
+			t.Errorf("Expected no symlinks, got %s", runfilesPath)
+		}
+	}
+
+	// Source files, with a drive letter of another case
+	if !r.runfiles["_main/README.md"].is_source || r.runfilesOriginMapping["README.md"] != "_main/README.md" {
+		t.Errorf("Expected source mappings, got %v", r.runfilesOriginMapping)
+	}
+	if !r.runfiles["_main/my file.txt"].is_source || r.runfilesOriginMapping["my file.txt"] != "_main/my file.txt" {
+		t.Errorf("Expected the escaped source mappings, got %v", r.runfilesOriginMapping)
+	}
+
+	// Generated files, with backslashes
+	if r.runfilesOriginMapping["bazel-out/x64_windows-fastbuild/bin/mylib/index.js"] != "_main/mylib/index.js" {
+		t.Errorf("Expected generated mappings, got %v", r.runfilesOriginMapping)
+	}
+	if r.runfilesOriginMapping["bazel-out/x64_windows-fastbuild/bin/dev_/dev.exe"] != "_main/dev_/dev.exe" {
+		t.Errorf("Expected generated mappings, got %v", r.runfilesOriginMapping)
+	}
+
+	// External files
+	if !r.runfiles["rules_nodejs~~node~nodejs_windows_amd64/bin/nodejs/node.exe"].is_external {
+		t.Errorf("Expected external mappings")
+	}
+}
+
+func TestWindowsPaths(t *testing.T) {
+	for _, tc := range []struct{ path, slash, msys string }{
+		{`C:\Users\me`, "C:/Users/me", "C:/Users/me"},
+		{"c:/Users/me", "C:/Users/me", "C:/Users/me"},
+		{`\\server\share`, "//server/share", "//server/share"},
+		{"/c/Users/me", "/c/Users/me", "C:/Users/me"},
+		{"/c", "/c", "C:"},
+		{"/home/me/a\\b", "/home/me/a\\b", "/home/me/a\\b"},
+		{"relative/link", "relative/link", "relative/link"},
+	} {
+		if slash := toSlashPath(tc.path); slash != tc.slash {
+			t.Errorf("Expected toSlashPath(%q) to be %q, got %q", tc.path, tc.slash, slash)
+		}
+		if msys := fromMsysPath(tc.path); msys != tc.msys {
+			t.Errorf("Expected fromMsysPath(%q) to be %q, got %q", tc.path, tc.msys, msys)
+		}
+	}
+}
+
 // detectChanges(nil) is the path used by runWatch on watchman fresh-instance
 // events: cs.Paths is unreliable, so the only reconciliation signal is the
 // runfiles manifest. Verify that entries previously in cd.sourcesInfo but
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package run
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aspect-build/aspect-gazelle/runner/pkg/ibp"
+)
+
+// The environment variable pointing the run target at the file listing the
+// changes of the last watch cycle, such as for a devserver to replace the
+// changed modules instead of reloading the page.
+const WatchChangesFileEnv = "ASPECT_WATCH_CHANGES_FILE"
+
+// The content of the changes file, in the shape of the CYCLE messages of the
+// incremental build protocol.
+type watchChanges struct {
+	CycleId int               `json:"cycle_id"`
+	Scope   ibp.WatchScope    `json:"scope,omitempty"`
+	Sources ibp.SourceInfoMap `json:"sources"`
+
+	// Whether everything should be reloaded, when the changes are unknown or
+	// when the restart was requested.
+	Reset bool `json:"reset,omitempty"`
+}
+
+// writeChanges replaces the changes file with the changes of the cycle, or
+// with a reset when changes is nil. The file is renamed into place so the run
+// target never reads a partial file.
+func writeChanges(file string, cycle int, scope ibp.WatchScope, changes ibp.SourceInfoMap) error {
+	content := watchChanges{
+		CycleId: cycle,
+		Scope:   scope,
+		Sources: changes,
+		Reset:   changes == nil,
+	}
+	if content.Sources == nil {
+		content.Sources = ibp.SourceInfoMap{}
+	}
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		return fmt.Errorf("failed to encode the changes: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create the changes file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write the changes file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write the changes file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("failed to replace the changes file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package run
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aspect-build/aspect-gazelle/runner/pkg/ibp"
+)
+
+func readChanges(t *testing.T, file string) watchChanges {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Expected the changes file, got %v", err)
+	}
+	var changes watchChanges
+	if err := json.Unmarshal(data, &changes); err != nil {
+		t.Fatalf("Expected JSON, got %v: %s", err, data)
+	}
+	return changes
+}
+
+func TestWriteChanges(t *testing.T) {
+	t.Run("lists the changes of the cycle", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "changes.json")
+		err := writeChanges(file, 3, ibp.WatchScope_Sources, ibp.SourceInfoMap{
+			"src/app.ts": {IsSource: toJsonBoolPtr(true)},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		changes := readChanges(t, file)
+		if changes.CycleId != 3 || changes.Scope != ibp.WatchScope_Sources || changes.Reset {
+			t.Errorf("Expected the cycle 3 of the sources, got %+v", changes)
+		}
+		if info, ok := changes.Sources["src/app.ts"]; len(changes.Sources) != 1 || !ok || info.IsSource == nil || !*info.IsSource {
+			t.Errorf("Expected the changed source, got %v", changes.Sources)
+		}
+	})
+
+	t.Run("replaces the previous changes with a reset", func(t *testing.T) {
+		dir := t.TempDir()
+		file := filepath.Join(dir, "changes.json")
+		if err := writeChanges(file, 1, ibp.WatchScope_Runfiles, ibp.SourceInfoMap{"app.js": {}}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := writeChanges(file, 2, "", nil); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		changes := readChanges(t, file)
+		if changes.CycleId != 2 || !changes.Reset || changes.Sources == nil || len(changes.Sources) != 0 {
+			t.Errorf("Expected a reset with no sources, got %+v", changes)
+		}
+
+		entries, _ := os.ReadDir(dir)
+		if len(entries) != 1 {
+			t.Errorf("Expected only the changes file, got %v", entries)
+		}
+	})
+}
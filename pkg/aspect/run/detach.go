@@ -0,0 +1,94 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/root/flags"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/run/detached"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/fatih/color"
+)
+
+// DetachFlag runs the target of aspect run in the background, with its output
+// written to a log file of the output base, until it is stopped with
+// aspect stop. It is listed by aspect ps.
+const DetachFlag = "--detach"
+
+// runTarget returns the run target of the bazel run command, which is the
+// first argument before any bare "--" that isn't a flag.
+func runTarget(bazelCmd []string) string {
+	for _, arg := range bazelCmd[1:] {
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			return arg
+		}
+	}
+	return ""
+}
+
+// runDetached builds the target and starts it in the background, see
+// DetachFlag. A target that is running already must be stopped first.
+func (runner *Run) runDetached(ctx context.Context, bazelCmd []string, bzlCommandStreams ioutils.Streams) error {
+	target := runTarget(bazelCmd)
+	if target == "" {
+		return fmt.Errorf("%s requires a target to run", DetachFlag)
+	}
+	root, err := detached.Root()
+	if err != nil {
+		return err
+	}
+	if root == "" {
+		return fmt.Errorf("%s only runs targets inside a workspace", DetachFlag)
+	}
+
+	// Fail before building when the target is running already.
+	p, err := detached.Load(root, target)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if p != nil && p.Running {
+		return fmt.Errorf("%s is running already with pid %d, stop it with 'aspect stop %s'", target, p.Pid, target)
+	}
+
+	// The run script is written next to the pidfile, where it stays until the
+	// target is stopped.
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", root, err)
+	}
+	if err := runner.runBazelCommand(ctx, flags.AddFlagToCommand(bazelCmd, "--script_path="+detached.ScriptPath(root, target)), bzlCommandStreams); err != nil {
+		return err
+	}
+
+	p, err = detached.Start(root, target, os.Environ())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(
+		runner.streams.Stderr,
+		"%s Started %s in the background with pid %d, logging to %s. Stop it with 'aspect stop %s'.\n",
+		color.GreenString("INFO:"), target, p.Pid, p.LogPath, target,
+	)
+	return nil
+}
@@ -0,0 +1,35 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package run
+
+import (
+	"testing"
+)
+
+func TestRunTarget(t *testing.T) {
+	for _, tc := range []struct {
+		bazelCmd []string
+		expected string
+	}{
+		{[]string{"run", "//app:server"}, "//app:server"},
+		{[]string{"run", "--config=dev", ":server", "--", "--port=8080"}, ":server"},
+		{[]string{"run", "--config=dev", "--", "server"}, ""},
+	} {
+		if target := runTarget(tc.bazelCmd); target != tc.expected {
+			t.Errorf("Expected target %q of %v, got %q", tc.expected, tc.bazelCmd, target)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package detached starts the run targets of aspect run --detach in the
+// background and keeps track of them through pidfiles in the output base, so
+// that aspect ps and aspect stop list and stop them from later invocations.
+package detached
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/daemon"
+)
+
+// Dir is the directory of the output base that holds the run script, the
+// pidfile and the log file of each detached run target.
+const Dir = "aspect-run/detached"
+
+// Process is a run target that was started in the background.
+type Process struct {
+	Target string
+	Pid    int
+	// Running is false when the process exited since it was started.
+	Running bool
+	LogPath string
+
+	// startTime tells the process apart from the processes that reuse its
+	// pid once it exited.
+	startTime string
+}
+
+// Root returns the directory that holds the detached run targets of the
+// workspace, or an empty string outside of a workspace.
+func Root() (string, error) {
+	workspaceRoot := bazel.WorkspaceFromWd.WorkspaceRoot()
+	if workspaceRoot == "" {
+		return "", nil
+	}
+	outputBase, err := bazel.OutputBase(workspaceRoot)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(outputBase, Dir), nil
+}
+
+// ScriptPath returns the path of the run script of the target, which is
+// written by bazel run --script_path before the target is started.
+func ScriptPath(root string, target string) string {
+	return filepath.Join(root, fileName(target)+".sh")
+}
+
+// Load returns the process of the target, or an error wrapping
+// fs.ErrNotExist when it wasn't started or was stopped since.
+func Load(root string, target string) (*Process, error) {
+	return load(root, fileName(target))
+}
+
+// Start runs the run script of the target with the given environment, detached
+// from the CLI so that it outlives it, with its output appended to its log
+// file. It fails when the target is running already.
+func Start(root string, target string, env []string) (*Process, error) {
+	p, err := Load(root, target)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if p != nil && p.Running {
+		return nil, fmt.Errorf("%s is running already with pid %d, stop it with 'aspect stop %s'", target, p.Pid, target)
+	}
+
+	name := fileName(target)
+	logPath := filepath.Join(root, name+".log")
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the log of %s: %w", target, err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(ScriptPath(root, target))
+	cmd.Env = env
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	daemon.Detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", target, err)
+	}
+	pid := cmd.Process.Pid
+	// The pid can't be reused before the process is released.
+	startTime, err := daemon.StartTime(pid)
+	// The process isn't waited on, it is reparented once the CLI exits.
+	cmd.Process.Release()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the start time of %s: %w", target, err)
+	}
+
+	// The target is kept in the pidfile since the file name doesn't tell it.
+	pidfile := fmt.Sprintf("%d\n%s\n%s\n", pid, startTime, target)
+	if err := os.WriteFile(filepath.Join(root, name+".pid"), []byte(pidfile), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write the pidfile of %s: %w", target, err)
+	}
+	return &Process{Target: target, Pid: pid, Running: true, LogPath: logPath, startTime: startTime}, nil
+}
+
+// List returns the run targets that were started in root, sorted by target,
+// including the ones that exited since.
+func List(root string) ([]*Process, error) {
+	pidfiles, err := filepath.Glob(filepath.Join(root, "*.pid"))
+	if err != nil {
+		return nil, err
+	}
+
+	processes := make([]*Process, 0, len(pidfiles))
+	for _, pidfile := range pidfiles {
+		p, err := load(root, strings.TrimSuffix(filepath.Base(pidfile), ".pid"))
+		if errors.Is(err, fs.ErrNotExist) {
+			// Stopped in the meantime.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		processes = append(processes, p)
+	}
+	slices.SortFunc(processes, func(a, b *Process) int {
+		return strings.Compare(a.Target, b.Target)
+	})
+	return processes, nil
+}
+
+// Stop terminates the process if it is running and removes its pidfile and
+// its run script. Its log file is kept.
+func Stop(root string, p *Process) error {
+	// The process is checked again right before it is signaled.
+	if p.Running && daemon.IsRunning(p.Pid, p.startTime) {
+		if err := daemon.Terminate(p.Pid); err != nil {
+			return fmt.Errorf("failed to stop %s: %w", p.Target, err)
+		}
+	}
+	name := fileName(p.Target)
+	for _, path := range []string{filepath.Join(root, name+".pid"), filepath.Join(root, name+".sh")} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// load reads the pidfile with the given name and checks whether its process
+// is alive.
+func load(root string, name string) (*Process, error) {
+	b, err := os.ReadFile(filepath.Join(root, name+".pid"))
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.SplitN(strings.TrimSuffix(string(b), "\n"), "\n", 3)
+	if len(lines) != 3 || lines[2] == "" {
+		return nil, fmt.Errorf("invalid pidfile %s", filepath.Join(root, name+".pid"))
+	}
+	pid, err := strconv.Atoi(lines[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid pidfile %s", filepath.Join(root, name+".pid"))
+	}
+	return &Process{
+		Target:    lines[2],
+		Pid:       pid,
+		Running:   daemon.IsRunning(pid, lines[1]),
+		LogPath:   filepath.Join(root, name+".log"),
+		startTime: lines[1],
+	}, nil
+}
+
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// fileName returns the name of the files of the target: its label made safe
+// for a file name, followed by a short hash of the label so that labels
+// differing only by their unsafe characters get files of their own.
+func fileName(target string) string {
+	sum := sha256.Sum256([]byte(target))
+	name := strings.Trim(unsafeFileNameChars.ReplaceAllString(target, "_"), "_.")
+	return name + "-" + hex.EncodeToString(sum[:4])
+}
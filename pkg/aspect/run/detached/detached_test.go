@@ -0,0 +1,106 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package detached
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func writeScript(g *WithT, root string, target string) {
+	g.Expect(os.MkdirAll(root, 0755)).To(Succeed())
+	g.Expect(os.WriteFile(ScriptPath(root, target), []byte("#!/bin/sh\necho started\nexec sleep 60\n"), 0755)).To(Succeed())
+}
+
+func TestDetached(t *testing.T) {
+	t.Run("starts a target once and stops it", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		root := t.TempDir()
+		writeScript(g, root, "//app:server")
+
+		p, err := Start(root, "//app:server", os.Environ())
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(p.Running).To(BeTrue())
+
+		processes, err := List(root)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(processes).To(HaveLen(1))
+		g.Expect(processes[0].Target).To(Equal("//app:server"))
+		g.Expect(processes[0].Pid).To(Equal(p.Pid))
+		g.Expect(processes[0].Running).To(BeTrue())
+		g.Expect(filepath.Dir(processes[0].LogPath)).To(Equal(root))
+
+		_, err = Start(root, "//app:server", os.Environ())
+		g.Expect(err).To(MatchError(ContainSubstring("//app:server is running already")))
+
+		g.Expect(Stop(root, processes[0])).To(Succeed())
+		g.Expect(List(root)).To(BeEmpty())
+		g.Expect(ScriptPath(root, "//app:server")).ToNot(BeAnExistingFile())
+	})
+
+	t.Run("lists the targets that exited", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		root := t.TempDir()
+
+		// No process can have the largest pid.
+		g.Expect(os.WriteFile(filepath.Join(root, fileName("//app:worker")+".pid"), []byte("2147483647\n1\n//app:worker\n"), 0644)).To(Succeed())
+
+		p, err := Load(root, "//app:worker")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(p.Target).To(Equal("//app:worker"))
+		g.Expect(p.Running).To(BeFalse())
+
+		writeScript(g, root, "//app:worker")
+		p, err = Start(root, "//app:worker", os.Environ())
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(Stop(root, p)).To(Succeed())
+	})
+
+	t.Run("does not stop a process that reused the pid", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		root := t.TempDir()
+
+		other := exec.Command("sleep", "60")
+		g.Expect(other.Start()).To(Succeed())
+		defer other.Wait()
+		defer other.Process.Kill()
+
+		// The target started at another time with the pid of the process.
+		pidfile := fmt.Sprintf("%d\n1\n//app:worker\n", other.Process.Pid)
+		g.Expect(os.WriteFile(filepath.Join(root, fileName("//app:worker")+".pid"), []byte(pidfile), 0644)).To(Succeed())
+
+		p, err := Load(root, "//app:worker")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(p.Running).To(BeFalse())
+
+		p.Running = true
+		g.Expect(Stop(root, p)).To(Succeed())
+		g.Expect(other.Process.Signal(syscall.Signal(0))).To(Succeed())
+	})
+
+	t.Run("gives labels differing by unsafe characters files of their own", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		g.Expect(fileName("//app:server")).To(HavePrefix("app_server-"))
+		g.Expect(fileName("//app:server")).ToNot(Equal(fileName("//app/server")))
+	})
+}
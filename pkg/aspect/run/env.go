@@ -0,0 +1,125 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package run
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// The environment variables telling the run target about the watch cycle it
+// was started for, next to IBAZEL_NOTIFY_CHANGES: the number of the cycle, 0
+// for the initial build, the number of changes of the cycle and the
+// invocation id of the bazel build of the cycle.
+const (
+	WatchCycleEnv        = "ASPECT_WATCH_CYCLE"
+	WatchChangeCountEnv  = "ASPECT_WATCH_CHANGE_COUNT"
+	WatchInvocationIdEnv = "ASPECT_WATCH_INVOCATION_ID"
+)
+
+const (
+	// WatchEnvFlag sets an environment variable of the run targets of aspect
+	// run --watch, e.g. --watch-env=BUILD_CYCLE={cycle}. It may be repeated and
+	// takes precedence over WatchEnvKey.
+	WatchEnvFlag = "--watch-env"
+
+	// WatchEnvKey is the key of the Aspect CLI config with the environment
+	// variables of the run targets of aspect run --watch. Their names are
+	// upper-cased, as the config keys are case-insensitive.
+	WatchEnvKey = "watch_env"
+)
+
+// watchEnvVar is an environment variable of the run targets. Its value is a
+// template: environment variables such as ${USER} are expanded, as well as
+// the variables {cycle}, {changes} and {invocation_id} of the cycle the
+// target is started for.
+type watchEnvVar struct {
+	name     string
+	template string
+}
+
+// watchEnv returns the environment variables of the run targets given with
+// WatchEnvFlag, or else with WatchEnvKey in the Aspect CLI config, sorted by
+// name.
+func watchEnv(flagValues []string) ([]watchEnvVar, error) {
+	templates := map[string]string{}
+	if viper.IsSet(WatchEnvKey) {
+		if _, ok := viper.Get(WatchEnvKey).(map[string]any); !ok {
+			return nil, fmt.Errorf("expected %s to be a map", WatchEnvKey)
+		}
+		for name, template := range viper.GetStringMapString(WatchEnvKey) {
+			if strings.Contains(name, "=") {
+				return nil, fmt.Errorf("invalid key of %s %q: keys must not contain '='", WatchEnvKey, name)
+			}
+			templates[strings.ToUpper(name)] = template
+		}
+	}
+	for _, value := range flagValues {
+		name, template, ok := strings.Cut(value, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid %s %q: must be NAME=VALUE", WatchEnvFlag, value)
+		}
+		templates[name] = template
+	}
+
+	vars := make([]watchEnvVar, 0, len(templates))
+	for name, template := range templates {
+		vars = append(vars, watchEnvVar{name: name, template: template})
+	}
+	slices.SortFunc(vars, func(a, b watchEnvVar) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return vars, nil
+}
+
+// cycleVars are the variables of the cycle a run target is started for.
+type cycleVars struct {
+	cycle        int
+	changes      int
+	invocationId string
+}
+
+var cycleVarPattern = regexp.MustCompile(`\{(cycle|changes|invocation_id)\}`)
+
+// env returns the environment variables of the cycle followed by vars, with
+// their templates expanded.
+func (v cycleVars) env(vars []watchEnvVar) []string {
+	values := map[string]string{
+		"cycle":         strconv.Itoa(v.cycle),
+		"changes":       strconv.Itoa(v.changes),
+		"invocation_id": v.invocationId,
+	}
+	env := []string{
+		WatchCycleEnv + "=" + values["cycle"],
+		WatchChangeCountEnv + "=" + values["changes"],
+		WatchInvocationIdEnv + "=" + values["invocation_id"],
+	}
+	for _, envVar := range vars {
+		value := os.ExpandEnv(envVar.template)
+		value = cycleVarPattern.ReplaceAllStringFunc(value, func(match string) string {
+			return values[match[1:len(match)-1]]
+		})
+		env = append(env, envVar.name+"="+value)
+	}
+	return env
+}
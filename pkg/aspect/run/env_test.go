@@ -0,0 +1,74 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package run
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestWatchEnv(t *testing.T) {
+	t.Run("reads the config and lets the flags take precedence", func(t *testing.T) {
+		viper.Set(WatchEnvKey, map[string]any{"port": "8080", "build_cycle": "{cycle}"})
+		t.Cleanup(viper.Reset)
+
+		vars, err := watchEnv([]string{"PORT=9090", "MODE=dev=1"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		expected := []watchEnvVar{
+			{name: "BUILD_CYCLE", template: "{cycle}"},
+			{name: "MODE", template: "dev=1"},
+			{name: "PORT", template: "9090"},
+		}
+		if !slices.Equal(vars, expected) {
+			t.Errorf("Expected %v, got %v", expected, vars)
+		}
+	})
+
+	t.Run("rejects a config that is not a map", func(t *testing.T) {
+		viper.Set(WatchEnvKey, []string{"PORT=8080"})
+		t.Cleanup(viper.Reset)
+
+		if _, err := watchEnv(nil); err == nil || err.Error() != "expected watch_env to be a map" {
+			t.Errorf("Expected an error about the config, got %v", err)
+		}
+	})
+}
+
+func TestCycleVarsEnv(t *testing.T) {
+	t.Setenv("WATCH_ENV_TEST_USER", "alice")
+
+	vars := cycleVars{cycle: 3, changes: 2, invocationId: "f00"}
+	env := vars.env([]watchEnvVar{
+		{name: "BUILD", template: "{cycle}/{changes}/{invocation_id}"},
+		{name: "OWNER", template: "${WATCH_ENV_TEST_USER}-{user}"},
+	})
+
+	expected := []string{
+		"ASPECT_WATCH_CYCLE=3",
+		"ASPECT_WATCH_CHANGE_COUNT=2",
+		"ASPECT_WATCH_INVOCATION_ID=f00",
+		"BUILD=3/2/f00",
+		"OWNER=alice-{user}",
+	}
+	if !slices.Equal(env, expected) {
+		t.Errorf("Expected %v, got %v", expected, env)
+	}
+}
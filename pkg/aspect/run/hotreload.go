@@ -0,0 +1,225 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package run
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/run/hotreload"
+	logger "github.com/aspect-build/aspect-gazelle/common/logger"
+	"github.com/aspect-build/aspect-gazelle/runner/pkg/ibp"
+)
+
+// HotReloadAddressEnv is the environment variable of the run targets using
+// the ibazel protocol with the address of the HotReload gRPC service, which
+// streams the events of the cycles to the target instead of the
+// IBAZEL_BUILD_* lines on its stdin, see hotreload.proto.
+const HotReloadAddressEnv = "ASPECT_WATCH_HOT_RELOAD_ADDRESS"
+
+// hotReloadBuffer is how many events a subscriber may lag behind before the
+// following ones are dropped.
+const hotReloadBuffer = 64
+
+// hotReloadServer serves the HotReload service to a run target and pushes the
+// events of its cycles to the subscribers. Its methods do nothing if it is
+// nil.
+type hotReloadServer struct {
+	hotreload.UnimplementedHotReloadServer
+
+	address    string
+	socketPath string
+	listener   net.Listener
+	server     *grpc.Server
+
+	mu          sync.Mutex
+	cycle       int32
+	subscribers map[chan *hotreload.CycleEvent]struct{}
+}
+
+// newHotReloadServer serves the HotReload service on the unix socket, or on a
+// loopback port on Windows.
+func newHotReloadServer(socketPath string) (*hotReloadServer, error) {
+	s := &hotReloadServer{subscribers: make(map[chan *hotreload.CycleEvent]struct{})}
+
+	var err error
+	if runtime.GOOS == "windows" {
+		s.listener, err = net.Listen("tcp", "127.0.0.1:0")
+		if err == nil {
+			s.address = s.listener.Addr().String()
+		}
+	} else {
+		// Remove the socket left behind by a process that was killed.
+		if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to serve the hot reload service: %w", err)
+		}
+		s.listener, err = net.Listen("unix", socketPath)
+		s.address = "unix://" + socketPath
+		s.socketPath = socketPath
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to serve the hot reload service: %w", err)
+	}
+
+	s.server = grpc.NewServer()
+	hotreload.RegisterHotReloadServer(s.server, s)
+	go func() {
+		if err := s.server.Serve(s.listener); err != nil {
+			logger.Infof("hot reload service stopped: %v", err)
+		}
+	}()
+	return s, nil
+}
+
+// Env returns the environment variable of the address of the service.
+func (s *hotReloadServer) Env() []string {
+	if s == nil {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s=%s", HotReloadAddressEnv, s.address)}
+}
+
+// Subscribe satisfies hotreload.HotReloadServer.
+func (s *hotReloadServer) Subscribe(req *hotreload.SubscribeReq, stream hotreload.HotReload_SubscribeServer) error {
+	events := make(chan *hotreload.CycleEvent, hotReloadBuffer)
+	s.mu.Lock()
+	s.subscribers[events] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subscribers, events)
+		s.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// subscribed returns whether the target subscribed to the events, which then
+// replace the lines of the ibazel protocol.
+func (s *hotReloadServer) subscribed() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subscribers) > 0
+}
+
+// started pushes the start of a cycle.
+func (s *hotReloadServer) started() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cycle++
+	s.publish(&hotreload.CycleEvent{
+		CycleId: s.cycle,
+		Event:   &hotreload.CycleEvent_Started{Started: &hotreload.CycleStarted{}},
+	})
+}
+
+// completed pushes the result of the cycle, and the changes it reported to the
+// target, if any.
+func (s *hotReloadServer) completed(success, reset bool, scope ibp.WatchScope, changes ibp.SourceInfoMap) {
+	if s == nil {
+		return
+	}
+	completed := &hotreload.CycleCompleted{Success: success, FullReload: reset, Scope: string(scope)}
+	for p, info := range changes {
+		file := &hotreload.ChangedFile{Path: p, Deleted: info == nil}
+		if info != nil {
+			file.IsSource = info.IsSource != nil && *info.IsSource
+			file.IsSymlink = info.IsSymlink != nil && *info.IsSymlink
+		}
+		completed.Changes = append(completed.Changes, file)
+	}
+	slices.SortFunc(completed.Changes, func(a, b *hotreload.ChangedFile) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.publish(&hotreload.CycleEvent{
+		CycleId: s.cycle,
+		Event:   &hotreload.CycleEvent_Completed{Completed: completed},
+	})
+}
+
+// timed pushes where the time of the cycle went at its end.
+func (s *hotReloadServer) timed(t *cycleTimings) {
+	if s == nil {
+		return
+	}
+	timings := &hotreload.CycleTimings{
+		TotalMs:           t.total.Milliseconds(),
+		ChangeDetectionMs: t.changeDetection.Milliseconds(),
+		BuildMs:           t.build.Milliseconds(),
+		AnalysisMs:        t.analysis.Milliseconds(),
+		ExecutionMs:       t.execution.Milliseconds(),
+		RestartMs:         t.restart.Milliseconds(),
+		SettleMs:          t.settle.Milliseconds(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.publish(&hotreload.CycleEvent{
+		CycleId: s.cycle,
+		Event:   &hotreload.CycleEvent_Timings{Timings: timings},
+	})
+}
+
+// publish sends the event to the subscribers. s.mu must be held.
+func (s *hotReloadServer) publish(event *hotreload.CycleEvent) {
+	for events := range s.subscribers {
+		select {
+		case events <- event:
+		default:
+			logger.Infof("hot reload subscriber lagging behind, dropping cycle %d event", event.CycleId)
+		}
+	}
+}
+
+// close stops the service and ends the subscriptions.
+func (s *hotReloadServer) close() {
+	if s == nil {
+		return
+	}
+	s.server.Stop()
+	if s.socketPath != "" {
+		os.Remove(s.socketPath)
+	}
+}
@@ -0,0 +1,642 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v7.34.0
+// source: pkg/aspect/run/hotreload/hotreload.proto
+
+package hotreload
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type SubscribeReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeReq) Reset() {
+	*x = SubscribeReq{}
+	mi := &file_pkg_aspect_run_hotreload_hotreload_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeReq) ProtoMessage() {}
+
+func (x *SubscribeReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_aspect_run_hotreload_hotreload_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeReq.ProtoReflect.Descriptor instead.
+func (*SubscribeReq) Descriptor() ([]byte, []int) {
+	return file_pkg_aspect_run_hotreload_hotreload_proto_rawDescGZIP(), []int{0}
+}
+
+type CycleEvent struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	CycleId int32                  `protobuf:"varint,1,opt,name=cycle_id,json=cycleId,proto3" json:"cycle_id,omitempty"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*CycleEvent_Started
+	//	*CycleEvent_Completed
+	//	*CycleEvent_Timings
+	Event         isCycleEvent_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CycleEvent) Reset() {
+	*x = CycleEvent{}
+	mi := &file_pkg_aspect_run_hotreload_hotreload_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CycleEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CycleEvent) ProtoMessage() {}
+
+func (x *CycleEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_aspect_run_hotreload_hotreload_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CycleEvent.ProtoReflect.Descriptor instead.
+func (*CycleEvent) Descriptor() ([]byte, []int) {
+	return file_pkg_aspect_run_hotreload_hotreload_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CycleEvent) GetCycleId() int32 {
+	if x != nil {
+		return x.CycleId
+	}
+	return 0
+}
+
+func (x *CycleEvent) GetEvent() isCycleEvent_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *CycleEvent) GetStarted() *CycleStarted {
+	if x != nil {
+		if x, ok := x.Event.(*CycleEvent_Started); ok {
+			return x.Started
+		}
+	}
+	return nil
+}
+
+func (x *CycleEvent) GetCompleted() *CycleCompleted {
+	if x != nil {
+		if x, ok := x.Event.(*CycleEvent_Completed); ok {
+			return x.Completed
+		}
+	}
+	return nil
+}
+
+func (x *CycleEvent) GetTimings() *CycleTimings {
+	if x != nil {
+		if x, ok := x.Event.(*CycleEvent_Timings); ok {
+			return x.Timings
+		}
+	}
+	return nil
+}
+
+type isCycleEvent_Event interface {
+	isCycleEvent_Event()
+}
+
+type CycleEvent_Started struct {
+	Started *CycleStarted `protobuf:"bytes,2,opt,name=started,proto3,oneof"`
+}
+
+type CycleEvent_Completed struct {
+	Completed *CycleCompleted `protobuf:"bytes,3,opt,name=completed,proto3,oneof"`
+}
+
+type CycleEvent_Timings struct {
+	Timings *CycleTimings `protobuf:"bytes,4,opt,name=timings,proto3,oneof"`
+}
+
+func (*CycleEvent_Started) isCycleEvent_Event() {}
+
+func (*CycleEvent_Completed) isCycleEvent_Event() {}
+
+func (*CycleEvent_Timings) isCycleEvent_Event() {}
+
+type CycleStarted struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CycleStarted) Reset() {
+	*x = CycleStarted{}
+	mi := &file_pkg_aspect_run_hotreload_hotreload_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CycleStarted) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CycleStarted) ProtoMessage() {}
+
+func (x *CycleStarted) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_aspect_run_hotreload_hotreload_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CycleStarted.ProtoReflect.Descriptor instead.
+func (*CycleStarted) Descriptor() ([]byte, []int) {
+	return file_pkg_aspect_run_hotreload_hotreload_proto_rawDescGZIP(), []int{2}
+}
+
+type CycleCompleted struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	FullReload    bool                   `protobuf:"varint,2,opt,name=full_reload,json=fullReload,proto3" json:"full_reload,omitempty"`
+	Scope         string                 `protobuf:"bytes,3,opt,name=scope,proto3" json:"scope,omitempty"`
+	Changes       []*ChangedFile         `protobuf:"bytes,4,rep,name=changes,proto3" json:"changes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CycleCompleted) Reset() {
+	*x = CycleCompleted{}
+	mi := &file_pkg_aspect_run_hotreload_hotreload_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CycleCompleted) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CycleCompleted) ProtoMessage() {}
+
+func (x *CycleCompleted) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_aspect_run_hotreload_hotreload_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CycleCompleted.ProtoReflect.Descriptor instead.
+func (*CycleCompleted) Descriptor() ([]byte, []int) {
+	return file_pkg_aspect_run_hotreload_hotreload_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CycleCompleted) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *CycleCompleted) GetFullReload() bool {
+	if x != nil {
+		return x.FullReload
+	}
+	return false
+}
+
+func (x *CycleCompleted) GetScope() string {
+	if x != nil {
+		return x.Scope
+	}
+	return ""
+}
+
+func (x *CycleCompleted) GetChanges() []*ChangedFile {
+	if x != nil {
+		return x.Changes
+	}
+	return nil
+}
+
+type ChangedFile struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Deleted       bool                   `protobuf:"varint,2,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	IsSource      bool                   `protobuf:"varint,3,opt,name=is_source,json=isSource,proto3" json:"is_source,omitempty"`
+	IsSymlink     bool                   `protobuf:"varint,4,opt,name=is_symlink,json=isSymlink,proto3" json:"is_symlink,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChangedFile) Reset() {
+	*x = ChangedFile{}
+	mi := &file_pkg_aspect_run_hotreload_hotreload_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChangedFile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChangedFile) ProtoMessage() {}
+
+func (x *ChangedFile) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_aspect_run_hotreload_hotreload_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChangedFile.ProtoReflect.Descriptor instead.
+func (*ChangedFile) Descriptor() ([]byte, []int) {
+	return file_pkg_aspect_run_hotreload_hotreload_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ChangedFile) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *ChangedFile) GetDeleted() bool {
+	if x != nil {
+		return x.Deleted
+	}
+	return false
+}
+
+func (x *ChangedFile) GetIsSource() bool {
+	if x != nil {
+		return x.IsSource
+	}
+	return false
+}
+
+func (x *ChangedFile) GetIsSymlink() bool {
+	if x != nil {
+		return x.IsSymlink
+	}
+	return false
+}
+
+type CycleTimings struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	TotalMs           int64                  `protobuf:"varint,1,opt,name=total_ms,json=totalMs,proto3" json:"total_ms,omitempty"`
+	ChangeDetectionMs int64                  `protobuf:"varint,2,opt,name=change_detection_ms,json=changeDetectionMs,proto3" json:"change_detection_ms,omitempty"`
+	BuildMs           int64                  `protobuf:"varint,3,opt,name=build_ms,json=buildMs,proto3" json:"build_ms,omitempty"`
+	AnalysisMs        int64                  `protobuf:"varint,4,opt,name=analysis_ms,json=analysisMs,proto3" json:"analysis_ms,omitempty"`
+	ExecutionMs       int64                  `protobuf:"varint,5,opt,name=execution_ms,json=executionMs,proto3" json:"execution_ms,omitempty"`
+	RestartMs         int64                  `protobuf:"varint,6,opt,name=restart_ms,json=restartMs,proto3" json:"restart_ms,omitempty"`
+	SettleMs          int64                  `protobuf:"varint,7,opt,name=settle_ms,json=settleMs,proto3" json:"settle_ms,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *CycleTimings) Reset() {
+	*x = CycleTimings{}
+	mi := &file_pkg_aspect_run_hotreload_hotreload_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CycleTimings) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CycleTimings) ProtoMessage() {}
+
+func (x *CycleTimings) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_aspect_run_hotreload_hotreload_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CycleTimings.ProtoReflect.Descriptor instead.
+func (*CycleTimings) Descriptor() ([]byte, []int) {
+	return file_pkg_aspect_run_hotreload_hotreload_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CycleTimings) GetTotalMs() int64 {
+	if x != nil {
+		return x.TotalMs
+	}
+	return 0
+}
+
+func (x *CycleTimings) GetChangeDetectionMs() int64 {
+	if x != nil {
+		return x.ChangeDetectionMs
+	}
+	return 0
+}
+
+func (x *CycleTimings) GetBuildMs() int64 {
+	if x != nil {
+		return x.BuildMs
+	}
+	return 0
+}
+
+func (x *CycleTimings) GetAnalysisMs() int64 {
+	if x != nil {
+		return x.AnalysisMs
+	}
+	return 0
+}
+
+func (x *CycleTimings) GetExecutionMs() int64 {
+	if x != nil {
+		return x.ExecutionMs
+	}
+	return 0
+}
+
+func (x *CycleTimings) GetRestartMs() int64 {
+	if x != nil {
+		return x.RestartMs
+	}
+	return 0
+}
+
+func (x *CycleTimings) GetSettleMs() int64 {
+	if x != nil {
+		return x.SettleMs
+	}
+	return 0
+}
+
+var File_pkg_aspect_run_hotreload_hotreload_proto protoreflect.FileDescriptor
+
+const file_pkg_aspect_run_hotreload_hotreload_proto_rawDesc = "" +
+	"\n" +
+	"(pkg/aspect/run/hotreload/hotreload.proto\x12\thotreload\"\x0e\n" +
+	"\fSubscribeReq\"\xd5\x01\n" +
+	"\n" +
+	"CycleEvent\x12\x19\n" +
+	"\bcycle_id\x18\x01 \x01(\x05R\acycleId\x123\n" +
+	"\astarted\x18\x02 \x01(\v2\x17.hotreload.CycleStartedH\x00R\astarted\x129\n" +
+	"\tcompleted\x18\x03 \x01(\v2\x19.hotreload.CycleCompletedH\x00R\tcompleted\x123\n" +
+	"\atimings\x18\x04 \x01(\v2\x17.hotreload.CycleTimingsH\x00R\atimingsB\a\n" +
+	"\x05event\"\x0e\n" +
+	"\fCycleStarted\"\x93\x01\n" +
+	"\x0eCycleCompleted\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1f\n" +
+	"\vfull_reload\x18\x02 \x01(\bR\n" +
+	"fullReload\x12\x14\n" +
+	"\x05scope\x18\x03 \x01(\tR\x05scope\x120\n" +
+	"\achanges\x18\x04 \x03(\v2\x16.hotreload.ChangedFileR\achanges\"w\n" +
+	"\vChangedFile\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x18\n" +
+	"\adeleted\x18\x02 \x01(\bR\adeleted\x12\x1b\n" +
+	"\tis_source\x18\x03 \x01(\bR\bisSource\x12\x1d\n" +
+	"\n" +
+	"is_symlink\x18\x04 \x01(\bR\tisSymlink\"\xf4\x01\n" +
+	"\fCycleTimings\x12\x19\n" +
+	"\btotal_ms\x18\x01 \x01(\x03R\atotalMs\x12.\n" +
+	"\x13change_detection_ms\x18\x02 \x01(\x03R\x11changeDetectionMs\x12\x19\n" +
+	"\bbuild_ms\x18\x03 \x01(\x03R\abuildMs\x12\x1f\n" +
+	"\vanalysis_ms\x18\x04 \x01(\x03R\n" +
+	"analysisMs\x12!\n" +
+	"\fexecution_ms\x18\x05 \x01(\x03R\vexecutionMs\x12\x1d\n" +
+	"\n" +
+	"restart_ms\x18\x06 \x01(\x03R\trestartMs\x12\x1b\n" +
+	"\tsettle_ms\x18\a \x01(\x03R\bsettleMs2J\n" +
+	"\tHotReload\x12=\n" +
+	"\tSubscribe\x12\x17.hotreload.SubscribeReq\x1a\x15.hotreload.CycleEvent0\x01BDZBgithub.com/aspect-build/aspect-cli-legacy/pkg/aspect/run/hotreloadb\x06proto3"
+
+var (
+	file_pkg_aspect_run_hotreload_hotreload_proto_rawDescOnce sync.Once
+	file_pkg_aspect_run_hotreload_hotreload_proto_rawDescData []byte
+)
+
+func file_pkg_aspect_run_hotreload_hotreload_proto_rawDescGZIP() []byte {
+	file_pkg_aspect_run_hotreload_hotreload_proto_rawDescOnce.Do(func() {
+		file_pkg_aspect_run_hotreload_hotreload_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pkg_aspect_run_hotreload_hotreload_proto_rawDesc), len(file_pkg_aspect_run_hotreload_hotreload_proto_rawDesc)))
+	})
+	return file_pkg_aspect_run_hotreload_hotreload_proto_rawDescData
+}
+
+var file_pkg_aspect_run_hotreload_hotreload_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_pkg_aspect_run_hotreload_hotreload_proto_goTypes = []any{
+	(*SubscribeReq)(nil),   // 0: hotreload.SubscribeReq
+	(*CycleEvent)(nil),     // 1: hotreload.CycleEvent
+	(*CycleStarted)(nil),   // 2: hotreload.CycleStarted
+	(*CycleCompleted)(nil), // 3: hotreload.CycleCompleted
+	(*ChangedFile)(nil),    // 4: hotreload.ChangedFile
+	(*CycleTimings)(nil),   // 5: hotreload.CycleTimings
+}
+var file_pkg_aspect_run_hotreload_hotreload_proto_depIdxs = []int32{
+	2, // 0: hotreload.CycleEvent.started:type_name -> hotreload.CycleStarted
+	3, // 1: hotreload.CycleEvent.completed:type_name -> hotreload.CycleCompleted
+	5, // 2: hotreload.CycleEvent.timings:type_name -> hotreload.CycleTimings
+	4, // 3: hotreload.CycleCompleted.changes:type_name -> hotreload.ChangedFile
+	0, // 4: hotreload.HotReload.Subscribe:input_type -> hotreload.SubscribeReq
+	1, // 5: hotreload.HotReload.Subscribe:output_type -> hotreload.CycleEvent
+	5, // [5:6] is the sub-list for method output_type
+	4, // [4:5] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_pkg_aspect_run_hotreload_hotreload_proto_init() }
+func file_pkg_aspect_run_hotreload_hotreload_proto_init() {
+	if File_pkg_aspect_run_hotreload_hotreload_proto != nil {
+		return
+	}
+	file_pkg_aspect_run_hotreload_hotreload_proto_msgTypes[1].OneofWrappers = []any{
+		(*CycleEvent_Started)(nil),
+		(*CycleEvent_Completed)(nil),
+		(*CycleEvent_Timings)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pkg_aspect_run_hotreload_hotreload_proto_rawDesc), len(file_pkg_aspect_run_hotreload_hotreload_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_pkg_aspect_run_hotreload_hotreload_proto_goTypes,
+		DependencyIndexes: file_pkg_aspect_run_hotreload_hotreload_proto_depIdxs,
+		MessageInfos:      file_pkg_aspect_run_hotreload_hotreload_proto_msgTypes,
+	}.Build()
+	File_pkg_aspect_run_hotreload_hotreload_proto = out.File
+	file_pkg_aspect_run_hotreload_hotreload_proto_goTypes = nil
+	file_pkg_aspect_run_hotreload_hotreload_proto_depIdxs = nil
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// HotReloadClient is the client API for HotReload service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type HotReloadClient interface {
+	Subscribe(ctx context.Context, in *SubscribeReq, opts ...grpc.CallOption) (HotReload_SubscribeClient, error)
+}
+
+type hotReloadClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewHotReloadClient(cc grpc.ClientConnInterface) HotReloadClient {
+	return &hotReloadClient{cc}
+}
+
+func (c *hotReloadClient) Subscribe(ctx context.Context, in *SubscribeReq, opts ...grpc.CallOption) (HotReload_SubscribeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_HotReload_serviceDesc.Streams[0], "/hotreload.HotReload/Subscribe", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &hotReloadSubscribeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type HotReload_SubscribeClient interface {
+	Recv() (*CycleEvent, error)
+	grpc.ClientStream
+}
+
+type hotReloadSubscribeClient struct {
+	grpc.ClientStream
+}
+
+func (x *hotReloadSubscribeClient) Recv() (*CycleEvent, error) {
+	m := new(CycleEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// HotReloadServer is the server API for HotReload service.
+type HotReloadServer interface {
+	Subscribe(*SubscribeReq, HotReload_SubscribeServer) error
+}
+
+// UnimplementedHotReloadServer can be embedded to have forward compatible implementations.
+type UnimplementedHotReloadServer struct {
+}
+
+func (*UnimplementedHotReloadServer) Subscribe(*SubscribeReq, HotReload_SubscribeServer) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+
+func RegisterHotReloadServer(s *grpc.Server, srv HotReloadServer) {
+	s.RegisterService(&_HotReload_serviceDesc, srv)
+}
+
+func _HotReload_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeReq)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(HotReloadServer).Subscribe(m, &hotReloadSubscribeServer{stream})
+}
+
+type HotReload_SubscribeServer interface {
+	Send(*CycleEvent) error
+	grpc.ServerStream
+}
+
+type hotReloadSubscribeServer struct {
+	grpc.ServerStream
+}
+
+func (x *hotReloadSubscribeServer) Send(m *CycleEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _HotReload_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "hotreload.HotReload",
+	HandlerType: (*HotReloadServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _HotReload_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "pkg/aspect/run/hotreload/hotreload.proto",
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package run
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/run/hotreload"
+	"github.com/aspect-build/aspect-gazelle/runner/pkg/ibp"
+)
+
+// nopCloser is a stdin pipe recording the lines of the ibazel protocol.
+type nopCloser struct{ bytes.Buffer }
+
+func (*nopCloser) Close() error { return nil }
+
+func TestHotReload(t *testing.T) {
+	s, err := newHotReloadServer(filepath.Join(t.TempDir(), "hotreload.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.close)
+
+	env := s.Env()
+	if len(env) != 1 || !strings.HasPrefix(env[0], HotReloadAddressEnv+"=unix://") {
+		t.Fatalf("Expected the address of the socket, got %v", env)
+	}
+	address := strings.TrimPrefix(env[0], HotReloadAddressEnv+"=")
+
+	// The lines on stdin until the target subscribes.
+	stdin := &nopCloser{}
+	protocol := &IBazelProtocol{stdin: stdin, hotReload: s}
+	if err := protocol.Cycle(context.Background(), ibp.WatchScope_Runfiles, nil); err != nil {
+		t.Fatal(err)
+	}
+	if stdin.String() != "IBAZEL_BUILD_STARTED\nIBAZEL_BUILD_COMPLETED SUCCESS\n" {
+		t.Errorf("Expected the ibazel protocol before subscribing, got %q", stdin.String())
+	}
+
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := hotreload.NewHotReloadClient(conn).Subscribe(ctx, &hotreload.SubscribeReq{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for !s.subscribed() {
+		time.Sleep(time.Millisecond)
+	}
+
+	s.started()
+	s.completed(true, false, ibp.WatchScope_Runfiles, ibp.SourceInfoMap{
+		"_main/src/index.js": &ibp.SourceInfo{IsSource: toJsonBoolPtr(true)},
+		"_main/src/old.js":   nil,
+	})
+	s.timed(&cycleTimings{total: 1500 * time.Millisecond, build: time.Second, settle: 100 * time.Millisecond})
+	stdin.Reset()
+	if err := protocol.Cycle(context.Background(), ibp.WatchScope_Runfiles, nil); err != nil {
+		t.Fatal(err)
+	}
+	if stdin.Len() != 0 {
+		t.Errorf("Expected no ibazel protocol once subscribed, got %q", stdin.String())
+	}
+
+	started, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if started.GetStarted() == nil || started.CycleId != 1 {
+		t.Errorf("Expected the start of the first cycle, got %v", started)
+	}
+	completed, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := completed.GetCompleted()
+	if c == nil || completed.CycleId != 1 || !c.Success || c.Scope != "runfiles" || len(c.Changes) != 2 {
+		t.Fatalf("Expected the completion of the first cycle, got %v", completed)
+	}
+	if c.Changes[0].Path != "_main/src/index.js" || !c.Changes[0].IsSource || c.Changes[1].Path != "_main/src/old.js" || !c.Changes[1].Deleted {
+		t.Errorf("Expected the changed files, got %v", c.Changes)
+	}
+
+	timed, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	timings := timed.GetTimings()
+	if timings == nil || timed.CycleId != 1 || timings.TotalMs != 1500 || timings.BuildMs != 1000 || timings.SettleMs != 100 {
+		t.Errorf("Expected the timings of the first cycle, got %v", timed)
+	}
+}
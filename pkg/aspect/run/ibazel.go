@@ -32,10 +32,34 @@ var (
 	SHUTDOWN_KILL_DELAY = 5 * time.Second
 )
 
+// settle waits for the delay, or until ctx is done, to let the filesystem
+// settle at the end of a watch cycle. The wait is recorded into the timings of
+// the cycle of ctx, if any.
+func settle(ctx context.Context, delay time.Duration) {
+	if delay <= 0 {
+		return
+	}
+	started := time.Now()
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+	}
+	if t := cycleTimingsFrom(ctx); t != nil {
+		t.settle += time.Since(started)
+	}
+}
+
 // A IncrementalBazel implementation that communicates with the ibazel protocol.
 type IBazelProtocol struct {
 	// This can be set to nil to
 	stdin io.WriteCloser
+
+	// The delay at the end of each cycle, see WatchSettleFlag.
+	settleDelay time.Duration
+
+	// The hot reload service pushing the events of the cycles instead of the
+	// lines on stdin once the target subscribed to it, if any.
+	hotReload *hotReloadServer
 }
 
 var _ ibp.IncrementalBazel = (*IBazelProtocol)(nil)
@@ -51,11 +75,7 @@ func (ib *IBazelProtocol) Cycle(ctx context.Context, scope ibp.WatchScope, chang
 	res := ib.buildOne(true)
 
 	// Add some delay to let the filesystem settle before we can exit the build state.
-	// In the future we might make this configurable.
-	select {
-	case <-time.After(100 * time.Millisecond):
-	case <-ctx.Done():
-	}
+	settle(ctx, ib.settleDelay)
 
 	return res
 }
@@ -111,6 +131,10 @@ func (events *IBazelProtocol) buildEnd(success bool) error {
 
 // Its same as running buildStart and buildEnd back to back.
 func (events *IBazelProtocol) buildOne(success bool) error {
+	// The subscribers of the hot reload service receive the events instead.
+	if events.hotReload.subscribed() {
+		return nil
+	}
 	if err := events.buildStart(); err != nil {
 		return err
 	}
@@ -124,6 +148,9 @@ func (events *IBazelProtocol) buildOne(success bool) error {
 type RestartBazelProtocol struct {
 	createRunCmd func() *exec.Cmd
 	runCmd       *exec.Cmd
+
+	// The delay at the end of each cycle, see WatchSettleFlag.
+	settleDelay time.Duration
 }
 
 var _ ibp.IncrementalBazel = (*RestartBazelProtocol)(nil)
@@ -169,11 +196,7 @@ func (rb *RestartBazelProtocol) Cycle(ctx context.Context, scope ibp.WatchScope,
 	}
 
 	// Add some delay to let the filesystem settle before we can exit the build state.
-	// In the future we might make this configurable.
-	select {
-	case <-time.After(100 * time.Millisecond):
-	case <-ctx.Done():
-	}
+	settle(ctx, rb.settleDelay)
 
 	return nil
 }
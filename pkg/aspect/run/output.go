@@ -0,0 +1,120 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package run
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils/linefilter"
+	"github.com/fatih/color"
+)
+
+// The colors of the names prefixing the output of the run targets, in the
+// order of the targets. Red and yellow are left to errors and warnings.
+var outputColors = []color.Attribute{
+	color.FgCyan,
+	color.FgMagenta,
+	color.FgBlue,
+	color.FgGreen,
+	color.FgHiCyan,
+	color.FgHiMagenta,
+	color.FgHiBlue,
+	color.FgHiGreen,
+}
+
+// outputDrainTimeout is how long the output of the processes is drained for
+// when the session ends, in case a process left running still holds it.
+const outputDrainTimeout = time.Second
+
+// outputPrefix returns the prefix of the output of the target at index among
+// labels: its label padded to the longest one, in the color of the target.
+func outputPrefix(index int, labels []string) string {
+	width := 0
+	for _, label := range labels {
+		width = max(width, len(label))
+	}
+	name := color.New(outputColors[index%len(outputColors)]).Sprintf("%-*s", width, labels[index])
+	return name + " | "
+}
+
+// prefixedOutput prefixes each line written by the processes of a run target,
+// so that the output of the targets of a session can be told apart. The
+// processes write to pipes that outlive them, so each restart of a target
+// reuses the same ones.
+type prefixedOutput struct {
+	stdout *os.File
+	stderr *os.File
+
+	// Closed once the pipes are drained and the last lines flushed.
+	drained chan struct{}
+}
+
+// newPrefixedOutput returns the output prefixing each line with prefix before
+// writing it to streams.
+func newPrefixedOutput(prefix string, streams ioutils.Streams) (*prefixedOutput, error) {
+	prefixed, flush := linefilter.Apply(streams, func(_ linefilter.Stream, line string) []string {
+		return []string{prefix + line}
+	})
+
+	stdoutReader, stdout, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the stdout pipe: %w", err)
+	}
+	stderrReader, stderr, err := os.Pipe()
+	if err != nil {
+		stdoutReader.Close()
+		stdout.Close()
+		return nil, fmt.Errorf("failed to create the stderr pipe: %w", err)
+	}
+
+	o := &prefixedOutput{stdout: stdout, stderr: stderr, drained: make(chan struct{})}
+	var copying sync.WaitGroup
+	copying.Add(2)
+	copyOutput := func(w io.Writer, r *os.File) {
+		defer copying.Done()
+		io.Copy(w, r)
+		r.Close()
+	}
+	go copyOutput(prefixed.Stdout, stdoutReader)
+	go copyOutput(prefixed.Stderr, stderrReader)
+	go func() {
+		copying.Wait()
+		flush()
+		close(o.drained)
+	}()
+	return o, nil
+}
+
+// close closes the pipes of the processes and waits for their remaining
+// output to be written, for at most outputDrainTimeout.
+func (o *prefixedOutput) close() {
+	if o == nil {
+		return
+	}
+	o.stdout.Close()
+	o.stderr.Close()
+
+	select {
+	case <-o.drained:
+	case <-time.After(outputDrainTimeout):
+	}
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package run
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/fatih/color"
+)
+
+func TestOutputPrefix(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	t.Cleanup(func() { color.NoColor = noColor })
+
+	labels := []string{"//api:server", "//web:dev"}
+	if prefix := outputPrefix(0, labels); prefix != "//api:server | " {
+		t.Errorf("Expected the label, got %q", prefix)
+	}
+	if prefix := outputPrefix(1, labels); prefix != "//web:dev    | " {
+		t.Errorf("Expected the padded label, got %q", prefix)
+	}
+}
+
+func TestPrefixedOutput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	o, err := newPrefixedOutput("api | ", ioutils.Streams{Stdout: &stdout, Stderr: &stderr})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The processes of the target write to the same pipes across restarts.
+	o.stdout.WriteString("listening\nready")
+	o.stdout.WriteString(" to serve\n")
+	o.stderr.WriteString("deprecated option\nshutting")
+	o.close()
+
+	if expected := "api | listening\napi | ready to serve\n"; stdout.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, stdout.String())
+	}
+	if expected := "api | deprecated option\napi | shutting\n"; stderr.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, stderr.String())
+	}
+}
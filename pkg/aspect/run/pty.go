@@ -0,0 +1,143 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package run
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/root/flags"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/watch"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/creack/pty"
+	"golang.org/x/term"
+)
+
+// ptyTerminal is the pseudo-terminal the run target is started in with
+// AspectPtyFlag, for the colors, the cursor control and the prompts of the
+// programs that check for a terminal. It is connected to the terminal of the
+// CLI and reused by each restart of the target.
+type ptyTerminal struct {
+	ptmx *os.File
+	tty  *os.File
+
+	// Restores the terminal of the CLI and stops following its size.
+	restore    func() error
+	stopResize func()
+
+	// Closed once the output of the pseudo-terminal is drained.
+	drained chan struct{}
+}
+
+// newPtyTerminal allocates the pseudo-terminal, which is connected to the
+// terminal of the CLI with connect.
+func newPtyTerminal() (*ptyTerminal, error) {
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate a pseudo-terminal for %s: %w", flags.AspectPtyFlag, err)
+	}
+	return &ptyTerminal{
+		ptmx:       ptmx,
+		tty:        tty,
+		restore:    func() error { return nil },
+		stopResize: func() {},
+		drained:    make(chan struct{}),
+	}, nil
+}
+
+// connect passes the input of streams through to the pseudo-terminal and its
+// output to streams. When stdin is a terminal, its keys are passed through
+// as they are pressed, and the size of the pseudo-terminal follows its size.
+// Ctrl-C still ends the session of the CLI when keepSignals is true, or else
+// interrupts the program.
+func (t *ptyTerminal) connect(streams ioutils.Streams, keepSignals bool) error {
+	if in, ok := streams.Stdin.(*os.File); ok && term.IsTerminal(int(in.Fd())) {
+		pty.InheritSize(in, t.ptmx)
+		t.stopResize = notifyResize(func() {
+			pty.InheritSize(in, t.ptmx)
+		})
+
+		restore, err := watch.RawTerminal(int(in.Fd()), keepSignals)
+		if err != nil {
+			t.stopResize()
+			return fmt.Errorf("failed to pass the terminal through to the pseudo-terminal: %w", err)
+		}
+		t.restore = restore
+	}
+
+	if streams.Stdin != nil {
+		go io.Copy(t.ptmx, streams.Stdin)
+	}
+	go func() {
+		io.Copy(streams.Stdout, t.ptmx)
+		close(t.drained)
+	}()
+	return nil
+}
+
+// attach starts cmd in the pseudo-terminal, which becomes its controlling
+// terminal.
+func (t *ptyTerminal) attach(cmd *exec.Cmd) {
+	cmd.Stdin = t.tty
+	cmd.Stdout = t.tty
+	cmd.Stderr = t.tty
+	setControllingTerminal(cmd)
+}
+
+// close closes the pseudo-terminal once its output is drained, for at most
+// outputDrainTimeout, and restores the terminal of the CLI.
+func (t *ptyTerminal) close() {
+	if t == nil {
+		return
+	}
+	t.tty.Close()
+	select {
+	case <-t.drained:
+	case <-time.After(outputDrainTimeout):
+	}
+	t.ptmx.Close()
+	t.stopResize()
+	t.restore()
+}
+
+// forwardSignals forwards the signals sent to the CLI to the process until
+// stop is called, so that the process rather than the CLI decides when to
+// exit.
+func forwardSignals(p *os.Process) (stop func()) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-sigCh:
+				p.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sigCh)
+		close(done)
+	}
+}
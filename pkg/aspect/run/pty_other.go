@@ -0,0 +1,32 @@
+//go:build !darwin && !linux
+
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package run
+
+import (
+	"os/exec"
+)
+
+// setControllingTerminal is not supported on this platform, where no
+// pseudo-terminal is allocated.
+func setControllingTerminal(cmd *exec.Cmd) {}
+
+// notifyResize is not supported on this platform.
+func notifyResize(resize func()) (stop func()) {
+	return func() {}
+}
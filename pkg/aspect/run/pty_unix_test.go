@@ -0,0 +1,56 @@
+//go:build darwin || linux
+
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package run
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+)
+
+func TestPtyTerminal(t *testing.T) {
+	terminal, err := newPtyTerminal()
+	if err != nil {
+		t.Fatalf("Expected a pseudo-terminal, got %v", err)
+	}
+
+	var stdout bytes.Buffer
+	if err := terminal.connect(ioutils.Streams{Stdout: &stdout}, true); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The restarts of a target are started in the same pseudo-terminal, which
+	// is the controlling terminal of each of them.
+	for _, name := range []string{"first", "second"} {
+		cmd := exec.Command("sh", "-c", "test -t 0 && test -t 1 && test -t 2 && echo "+name+" $(tty)")
+		terminal.attach(cmd)
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("Expected the %s process to run in a terminal, got %v", name, err)
+		}
+	}
+	terminal.close()
+
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(stdout.String(), "\r", "")), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "first /dev/") || lines[1] != "second"+strings.TrimPrefix(lines[0], "first") {
+		t.Errorf("Expected both processes to print the same terminal, got %q", stdout.String())
+	}
+}
@@ -21,28 +21,29 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"net"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path"
-	"runtime"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/root/flags"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/watch"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspecterrors"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils/linefilter"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/telemetry"
 	logger "github.com/aspect-build/aspect-gazelle/common/logger"
-	"github.com/aspect-build/aspect-gazelle/runner/pkg/ibp"
 	watcher "github.com/aspect-build/aspect-gazelle/runner/pkg/watchman"
 	"github.com/fatih/color"
 	"github.com/google/uuid"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
@@ -60,6 +61,101 @@ type Run struct {
 
 var defaultWatchConnectionTimeout = 1 * time.Second
 
+const (
+	// WatchSettleFlag sets how long aspect run --watch lets the filesystem
+	// settle at the end of each cycle before it reacts to further changes,
+	// e.g. --watch-settle=500ms. It takes precedence over WatchSettleKey.
+	WatchSettleFlag = "--watch-settle"
+
+	// WatchSettleKey is the key of the Aspect CLI config with the default of
+	// WatchSettleFlag.
+	WatchSettleKey = "watch_settle"
+
+	// defaultWatchSettle is the settle delay when neither WatchSettleFlag nor
+	// WatchSettleKey is set.
+	defaultWatchSettle = 100 * time.Millisecond
+)
+
+// watchSettle returns the settle delay of the watch cycles given with
+// WatchSettleFlag, or else with WatchSettleKey in the Aspect CLI config.
+func watchSettle(flagValue string) (time.Duration, error) {
+	name, value := WatchSettleFlag, flagValue
+	if value == "" {
+		if !viper.IsSet(WatchSettleKey) {
+			return defaultWatchSettle, nil
+		}
+		name, value = WatchSettleKey, viper.GetString(WatchSettleKey)
+	}
+	settle, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", name, value, err)
+	}
+	if settle < 0 {
+		return 0, fmt.Errorf("invalid %s %q: must not be negative", name, value)
+	}
+	return settle, nil
+}
+
+const (
+	// WatchChangeDetectionKey is the key of the Aspect CLI config selecting how
+	// aspect run --watch detects the changes to the runfiles of the targets,
+	// ChangeDetectionExecLog by default.
+	WatchChangeDetectionKey = "watch_change_detection"
+
+	// ChangeDetectionExecLog reads the outputs of the actions run by each build
+	// from its compact execution log.
+	ChangeDetectionExecLog = "execlog"
+
+	// ChangeDetectionBEP compares the digests of the runfiles reported in the
+	// build events of each build, sparing the cost of the execution log. It
+	// falls back to ChangeDetectionExecLog when the build events are missing.
+	ChangeDetectionBEP = "bep"
+)
+
+// watchChangeDetection returns how the changes are detected, as selected with
+// WatchChangeDetectionKey in the Aspect CLI config.
+func watchChangeDetection() (string, error) {
+	if !viper.IsSet(WatchChangeDetectionKey) {
+		return ChangeDetectionExecLog, nil
+	}
+	switch value := viper.GetString(WatchChangeDetectionKey); value {
+	case ChangeDetectionExecLog, ChangeDetectionBEP:
+		return value, nil
+	default:
+		return "", fmt.Errorf("invalid %s %q: must be %q or %q", WatchChangeDetectionKey, value, ChangeDetectionExecLog, ChangeDetectionBEP)
+	}
+}
+
+const (
+	// WatchScopeDetectionKey is the key of the Aspect CLI config selecting how
+	// aspect run --watch determines whether the changes affect the targets,
+	// ScopeDetectionBuild by default.
+	WatchScopeDetectionKey = "watch_scope_detection"
+
+	// ScopeDetectionBuild rebuilds the targets on every change, the changes
+	// only affecting those whose runfiles changed.
+	ScopeDetectionBuild = "build"
+
+	// ScopeDetectionCQuery queries the source files the targets depend on,
+	// only rebuilding them for the changes of those files, which also restart
+	// the targets when the build runs no action for them.
+	ScopeDetectionCQuery = "cquery"
+)
+
+// watchScopeDetection returns how the scope of the targets is determined, as
+// selected with WatchScopeDetectionKey in the Aspect CLI config.
+func watchScopeDetection() (string, error) {
+	if !viper.IsSet(WatchScopeDetectionKey) {
+		return ScopeDetectionBuild, nil
+	}
+	switch value := viper.GetString(WatchScopeDetectionKey); value {
+	case ScopeDetectionBuild, ScopeDetectionCQuery:
+		return value, nil
+	default:
+		return "", fmt.Errorf("invalid %s %q: must be %q or %q", WatchScopeDetectionKey, value, ScopeDetectionBuild, ScopeDetectionCQuery)
+	}
+}
+
 func init() {
 	timeoutEnv := os.Getenv("ASPECT_WATCH_CONNECTION_TIMEOUT_MS")
 	if timeoutEnv != "" {
@@ -101,10 +197,36 @@ func New(
 func (runner *Run) Run(ctx context.Context, cmd *cobra.Command, args []string) (exitErr error) {
 	bazelCmd := []string{"run"}
 	watch, args := flags.RemoveFlag(args, "--watch")
+	settleFlag, args := flags.RemoveFlagValue(args, WatchSettleFlag)
+	envFlags, args := flags.RemoveFlagValues(args, WatchEnvFlag)
+	ui, args := flags.RemoveFlag(args, flags.AspectUIFlag)
+	usePty, args := flags.RemoveFlag(args, flags.AspectPtyFlag)
+	detach, args := flags.RemoveFlag(args, DetachFlag)
+	settle, err := watchSettle(settleFlag)
+	if err != nil {
+		return err
+	}
+	env, err := watchEnv(envFlags)
+	if err != nil {
+		return err
+	}
+	if ui && usePty {
+		return fmt.Errorf("%s can't be combined with %s", flags.AspectPtyFlag, flags.AspectUIFlag)
+	}
+	if detach && watch {
+		return fmt.Errorf("%s can't be combined with %s", DetachFlag, "--watch")
+	}
+	if detach && usePty {
+		return fmt.Errorf("%s can't be combined with %s", DetachFlag, flags.AspectPtyFlag)
+	}
 	bazelCmd = append(bazelCmd, args...)
 
 	if bep.HasBESInterceptor(ctx) {
-		bazelCmd = flags.AddFlagToCommand(bazelCmd, bep.BESInterceptorFromContext(ctx).Args()...)
+		besArgs, err := bep.BESInterceptorFromContext(ctx).Args()
+		if err != nil {
+			return err
+		}
+		bazelCmd = flags.AddFlagToCommand(bazelCmd, besArgs...)
 	}
 
 	bzlCommandStreams := runner.streams
@@ -117,12 +239,17 @@ func (runner *Run) Run(ctx context.Context, cmd *cobra.Command, args []string) (
 			bzlCommandStreams = runner.hstreams
 		}
 	}
-
-	var err error
-	if !watch {
+	bzlCommandStreams, flushOutput := linefilter.Streams(ctx, bzlCommandStreams)
+	defer flushOutput()
+
+	if detach {
+		err = runner.runDetached(ctx, bazelCmd, bzlCommandStreams)
+	} else if !watch && usePty {
+		err = runner.runPty(ctx, bazelCmd, bzlCommandStreams)
+	} else if !watch {
 		err = runner.runBazelCommand(ctx, bazelCmd, bzlCommandStreams)
 	} else {
-		err = runner.runWatch(ctx, bazelCmd, bzlCommandStreams)
+		err = runner.runWatch(ctx, bazelCmd, bzlCommandStreams, settle, env, ui, usePty)
 	}
 
 	// Check for subscriber errors
@@ -153,6 +280,47 @@ func (runner *Run) runBazelCommand(ctx context.Context, bazelCmd []string, bzlCo
 	return err
 }
 
+// runPty builds the target and runs it in a pseudo-terminal connected to the
+// terminal of the CLI, see flags.AspectPtyFlag. The keys pressed in the
+// terminal, such as Ctrl-C, and the signals sent to the CLI go to the target.
+func (runner *Run) runPty(ctx context.Context, bazelCmd []string, bzlCommandStreams ioutils.Streams) error {
+	// Fail before building on the platforms without pseudo-terminals.
+	terminal, err := newPtyTerminal()
+	if err != nil {
+		return err
+	}
+	defer terminal.close()
+
+	// Build the target and write the script running it, which bazel would
+	// otherwise run with the output of the CLI.
+	script := path.Join(os.TempDir(), fmt.Sprintf("aspect-run-%v-pty", os.Getpid()))
+	defer os.Remove(script)
+	if err := runner.runBazelCommand(ctx, flags.AddFlagToCommand(bazelCmd, "--script_path="+script), bzlCommandStreams); err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, script)
+	terminal.attach(cmd)
+	if err := terminal.connect(runner.streams, false); err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start the run script: %w", err)
+	}
+	stopSignals := forwardSignals(cmd.Process)
+	defer stopSignals()
+
+	if err := cmd.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+			// The target printed its errors, if any.
+			return &aspecterrors.ExitError{ExitCode: exitErr.ExitCode()}
+		}
+		return fmt.Errorf("failed to run the run script: %w", err)
+	}
+	return nil
+}
+
 func (runner *Run) runCmd(c context.Context, cmd *exec.Cmd, spanName string) error {
 	var invocationId string
 
@@ -170,38 +338,134 @@ func (runner *Run) runCmd(c context.Context, cmd *exec.Cmd, spanName string) err
 	return err
 }
 
-func (runner *Run) runWatch(ctx context.Context, bazelCmd []string, bzlCommandStreams ioutils.Streams) error {
+func (runner *Run) runWatch(ctx context.Context, bazelCmd []string, bzlCommandStreams ioutils.Streams, settle time.Duration, env []watchEnvVar, ui, usePty bool) error {
 	fmt.Fprintf(
 		runner.streams.Stderr,
 		"%s Watching feature is experimental and may have breaking changes in the future.\n",
 		color.YellowString("WARNING:"),
 	)
 
+	ignore, err := watch.LoadIgnore()
+	if err != nil {
+		return err
+	}
+
+	changeDetection, err := watchChangeDetection()
+	if err != nil {
+		return err
+	}
+
+	scopeDetection, err := watchScopeDetection()
+	if err != nil {
+		return err
+	}
+
 	bazelInstall, err := runner.bzl.GetBazelInstallation()
 	if err != nil {
 		return fmt.Errorf("failed to get Bazel installation: %w", err)
 	}
 
-	changedetect, err := newChangeDetector(runner.bzl.WorkspaceRoot(), strings.HasPrefix(bazelInstall.Version, "7."))
+	// Detect the changes from the build events if selected and available.
+	var besInterceptor bep.BESInterceptor
+	if changeDetection == ChangeDetectionBEP {
+		if bep.HasBESInterceptor(ctx) {
+			besInterceptor = bep.BESInterceptorFromContext(ctx)
+		} else {
+			fmt.Fprintf(runner.streams.Stderr, "%s No build events to detect changes with, falling back to the execution log.\n", color.YellowString("WARNING:"))
+		}
+	}
+
+	// Break the builds of the cycles down into their phases from the build
+	// events, if available.
+	timings := watchTimingsEnabled()
+	var phases *buildPhases
+	if timings && bep.HasBESInterceptor(ctx) {
+		phases = newBuildPhases(bep.BESInterceptorFromContext(ctx))
+	}
+
+	var notifier *watch.Notifier
+	notify, bazelCmd := flags.RemoveFlag(bazelCmd, watch.NotifyFlag)
+	if watch.NotifyEnabled(notify) {
+		notifier = watch.NewNotifier("aspect run --watch")
+	}
+
+	poll, bazelCmd, err := watch.RemovePollFlag(bazelCmd)
 	if err != nil {
-		return fmt.Errorf("failed to created change detector: %w", err)
+		return err
 	}
-	defer changedetect.Close()
 
-	startScriptName := fmt.Sprintf("aspect-run-%v", os.Getpid())
-	if runtime.GOOS == "windows" {
-		startScriptName += ".bat"
+	// Show the cycles in the dashboard rather than interleaving their output
+	// with the output of the targets.
+	var dashboard *watch.Dashboard
+	if ui {
+		dashboard, err = watch.NewDashboard("aspect run --watch")
+		if err != nil {
+			fmt.Fprintf(runner.streams.Stderr, "%s %v, showing the output instead.\n", color.YellowString("WARNING:"), err)
+		} else {
+			defer dashboard.Close()
+			bzlCommandStreams = dashboard.Streams()
+		}
 	}
 
-	startScript := path.Join(os.TempDir(), startScriptName)
+	// The run targets of the session, which share the watcher and the bazel server.
+	labels, bazelCmds := watchTargetCommands(bazelCmd)
+
+	// Start the target in a pseudo-terminal passed the keys of the terminal,
+	// which are then not read as keybindings. It is closed after the target.
+	var terminal *ptyTerminal
+	if usePty {
+		if len(bazelCmds) > 1 {
+			return fmt.Errorf("%s runs a single target, got %d", flags.AspectPtyFlag, len(bazelCmds))
+		}
+		terminal, err = newPtyTerminal()
+		if err != nil {
+			return err
+		}
+		defer terminal.close()
+		// Ctrl-C still ends the session.
+		if err := terminal.connect(runner.streams, true); err != nil {
+			return err
+		}
+	}
+
+	targets := make([]*watchTarget, 0, len(bazelCmds))
 	defer func() {
-		os.Remove(startScript)
+		for _, target := range targets {
+			target.close()
+		}
 	}()
+	for i, bazelCmd := range bazelCmds {
+		label := ""
+		if len(bazelCmds) > 1 {
+			label = labels[i]
+		}
+		target, err := runner.newWatchTarget(i, label, bazelCmd, bzlCommandStreams, settle, strings.HasPrefix(bazelInstall.Version, "7."), besInterceptor)
+		if err != nil {
+			return err
+		}
+		target.dashboard = dashboard
+		target.notifier = notifier
+		target.queryScope = scopeDetection == ScopeDetectionCQuery
+		target.env = env
+		target.pty = terminal
+		target.timings = timings
+		target.phases = phases
+		targets = append(targets, target)
+
+		// Tell the output of the targets apart, as they run side by side.
+		if label != "" {
+			target.output, err = newPrefixedOutput(outputPrefix(i, labels), bzlCommandStreams)
+			if err != nil {
+				return err
+			}
+		}
+	}
 
 	// Primary context to rule all async and background operations.
 	// TODO: Cobras context seems to cancel too early. perhaps use that instead
 	// of using our own signal?
 	pctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
@@ -217,7 +481,7 @@ func (runner *Run) runWatch(ctx context.Context, bazelCmd []string, bzlCommandSt
 	// Start the workspace watcher.
 	// Start in the background while bazel-run is also initializing in parallel
 	// in case watchman is slow to startup.
-	w := watcher.NewWatchman(runner.bzl.WorkspaceRoot())
+	w := watch.New(runner.bzl.WorkspaceRoot(), poll)
 	watchmanStartup := errgroup.Group{}
 	watchmanStartup.Go(func() error {
 		_, t := runner.tracer.Start(watchCtx, "Watchman.Start")
@@ -238,200 +502,16 @@ func (runner *Run) runWatch(ctx context.Context, bazelCmd []string, bzlCommandSt
 		w.Close()
 	}()
 
-	// The abazel protocol, potentially used as the incremental build tool.
-	// Must initialize and start listening for connections before the initial bazel run command.
-	// Start the incremental build service in case the process supports it and connects
-	abazel := ibp.NewServer()
-
-	// Start listening for a connection immediately.
-	if err := abazel.Serve(watchCtx); err != nil {
-		return fmt.Errorf("failed to connect to aspect bazel protocol: %w", err)
-	}
-
-	// Close the watch protocol on complete, no matter what the status is
-	defer abazel.Close()
-
-	fmt.Printf("%s Listening on watch socket %s\n", color.GreenString("INFO:"), abazel.Address())
-
-	createBazelScriptCmd := func(allowDiscard, trackChanges bool) (*exec.Cmd, error) {
-		// Additional arguments for the bazel run command
-		runCmdArgs := []string{}
-
-		// ChangeDetector normally adds additional flags
-		runCmdArgs = append(runCmdArgs, changedetect.bazelFlags(trackChanges)...)
-
-		// --norun and generate a run script instead
-		runCmdArgs = append(runCmdArgs, "--norun", "--script_path", startScript)
-
-		// --noallow_analysis_cache_discard except on the intial setup run
-		if !allowDiscard {
-			runCmdArgs = append(runCmdArgs, "--noallow_analysis_cache_discard")
-		}
-
-		allArgs := flags.AddFlagToCommand(bazelCmd, runCmdArgs...)
-		cmd, err := runner.bzl.MakeBazelCommand(watchCtx, allArgs, bzlCommandStreams, nil, nil)
-		return cmd, err
-	}
-
-	createRunCmd := func() *exec.Cmd {
-		// Inherit the CLI environment variables
-		env := os.Environ()[:]
-
-		// Add the incremental build protocol(s) environment variables
-		env = append(env, "IBAZEL_NOTIFY_CHANGES=y")
-		if abazel != nil {
-			env = append(env, abazel.Env()...)
-		}
-
-		startCmd := exec.CommandContext(watchCtx, startScript)
-		startCmd.Stdin = bzlCommandStreams.Stdin
-		startCmd.Stdout = bzlCommandStreams.Stdout
-		startCmd.Stderr = bzlCommandStreams.Stderr
-		startCmd.Env = env
-		return startCmd
-	}
-
-	// Create and start the intial bazel command to build+inspect the run target
-	startCmd, incrementalProtocol, initErr := func() (_ *exec.Cmd, _ ibp.IncrementalBazel, retErr error) {
-		initCtx, initTrace := runner.tracer.Start(watchCtx, "Run.Init")
-		defer func() {
-			if retErr != nil {
-				initTrace.SetStatus(codes.Error, retErr.Error())
-			}
-			initTrace.End()
-		}()
-
-		initCmd, err := createBazelScriptCmd(true, false)
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to create initial bazel command: %w", err)
-		}
-
-		logger.Infof("initial --watch build: %v", initCmd.Args)
-
-		if err := runner.runCmd(initCtx, initCmd, "Run.Subscribe.Build"); err != nil {
-			return nil, nil, fmt.Errorf("initial bazel command failed: %w", err)
-		}
-
-		// Detect the context of the run target after this initial build.
-		if err := changedetect.detectContext(); err != nil {
-			return nil, nil, fmt.Errorf("failed to detect context on init: %w", err)
-		}
-
-		// The command to start the run target.
-		startCmd := createRunCmd()
-
-		// The incremental bazel protocol/tool to use going forward.
-		var incrementalProtocol ibp.IncrementalBazel
-
-		// If the target explicitly supports ibazel but NOT explicitly supports incremental build protocol
-		// then assume only legacy ibazel support is available.
-		if changedetect.supportsIBazelNotifyChanges() && !changedetect.explicitlySupportsIBP() {
-			// Fallback to only using the legacy ibazel protocol.
-			fmt.Printf("%s Fallback to legacy ibazel protocol\n", color.GreenString("INFO:"))
-
-			// In order to support ibazel events we need to set the stdin to a pipe.
-			// By default MakeBazelCommand sets it to bzlCommandStreams.stdin but we
-			// want to control stdin depending on the watch mode.
-			// In order to pipe stdin we need to set it to nil first and then call StdinPipe.
-			startCmd.Stdin = nil
-			runStdin, err := startCmd.StdinPipe()
-			if err != nil {
-				return nil, nil, fmt.Errorf("failed to create stdin pipe for ibazel: %w", err)
-			}
-
-			incrementalProtocol = &IBazelProtocol{
-				stdin: runStdin,
-			}
-		} else {
-			incrementalProtocol = abazel
-		}
-
-		// Start the bazel command
-		if err := startCmd.Start(); err != nil {
-			return nil, nil, fmt.Errorf("failed to start bazel command: %w", err)
-		}
-
-		// Significantly increase the timeout if the target explicitly supports the watch protocol
-		// since failure to connect will be a hard error instead of a fallback to restarting.
-		watchConnectionTimeout := defaultWatchConnectionTimeout
-		if changedetect.explicitlySupportsIBP() {
-			watchConnectionTimeout *= 10
-		}
-
-		// Give the watcher some time to start and open the connection before sending Init()
-		if !incrementalProtocol.HasConnection() {
-			// TODO: don't assume abazel is the only non-instant connection
-
-			select {
-			case <-watchCtx.Done():
-				fmt.Printf("%s Process cancelled before establishing connection: %v\n", color.RedString("ERROR:"), watchCtx.Err())
-				return nil, nil, watchCtx.Err()
-			case v := <-abazel.WaitForConnection():
-				fmt.Printf("%s Received connection to %s using abazel v%v\n", color.GreenString("INFO:"), abazel.Address(), v)
-			case <-time.After(watchConnectionTimeout):
-				fmt.Printf("%s Timeout (%vms) waiting for watch protocol connection.\n", color.YellowString("WARNING:"), watchConnectionTimeout.Milliseconds())
-			}
-		}
-
-		// Abandon the incremental protocol if the target has not responded
-		if !incrementalProtocol.HasConnection() {
-			if changedetect.explicitlySupportsIBP() {
-				fmt.Printf("%s target explicitly supports incremental build protocol but did not connect within %vms.\n", color.RedString("ERROR:"), watchConnectionTimeout.Milliseconds())
-				os.Exit(1)
-			}
-
-			fmt.Printf("%s No watch protocol connection established. Fallback to restart.\n", color.YellowString("WARNING:"))
-
-			go abazel.Close()
-			abazel = nil
-
-			incrementalProtocol = &RestartBazelProtocol{
-				createRunCmd: createRunCmd,
-				runCmd:       startCmd,
-			}
-		}
-
-		// Init() with the full runfiles list
-		cctx, initCycleTrace := runner.tracer.Start(initCtx, "Run.Cycle")
-		defer func() {
-			if retErr != nil {
-				initCycleTrace.SetStatus(codes.Error, retErr.Error())
-			}
-			initCycleTrace.End()
-		}()
-
-		initRunfiles, initRunfilesErr := changedetect.loadFullSourceInfo()
-		if initRunfilesErr != nil {
-			return nil, nil, fmt.Errorf("failed to load initial runfiles: %w", initRunfilesErr)
+	// Build and start the run targets one after the other, as they share the bazel server.
+	for _, target := range targets {
+		if err := target.init(watchCtx); err != nil {
+			return err
 		}
-		if err := incrementalProtocol.Init(cctx, ibp.WatchScope_Runfiles, initRunfiles); err != nil {
-			return nil, nil, fmt.Errorf("failed to initialize watch protocol: %w", err)
+		if target.queryScope {
+			target.refreshScope()
 		}
-
-		return startCmd, incrementalProtocol, nil
-	}()
-	if initErr != nil {
-		return initErr
 	}
-
-	// Close the incremental protocol when complete, no matter the protocol type.
-	defer incrementalProtocol.Close()
-
-	// Send an 'Exit' message to the child process when the context completes in case
-	// the context was cancelled due to the cli being shutdown.
-	go func() {
-		<-watchCtx.Done()
-
-		// If a connection still exists to the incremental protocol, send an Exit message and
-		// hope for a graceful shutdown. Ignore any errors as the process may already be in the
-		// process of shutting down.
-		if incrementalProtocol.HasConnection() {
-			incrementalProtocol.Exit(context.Background(), watchCtx.Err())
-		}
-
-		// Terminate the process if it is still running.
-		terminate(startCmd.Process)
-	}()
+	dashboard.Result(nil)
 
 	watchCtx, st := runner.tracer.Start(watchCtx, "Run.Subscribe")
 	defer st.End()
@@ -442,156 +522,44 @@ func (runner *Run) runWatch(ctx context.Context, bazelCmd []string, bzlCommandSt
 		return wErr
 	}
 
-	watchState := fmt.Sprintf("aspect-run-watch-%d", os.Getpid())
-
-	// If the client declared the watching of sources via IBP
-	// TODO: other methods of declaring watching sources? tags on targets succh as formatters?
-	watchRunfilesChanges := incrementalProtocol.WatchingScope(ibp.WatchScope_Runfiles)
-	watchSourceChanges := incrementalProtocol.WatchingScope(ibp.WatchScope_Sources)
+	// Read the keybindings, such as to restart or to quit, in the terminal.
+	var keys <-chan rune
+	if terminal == nil {
+		var stopKeys func()
+		keys, stopKeys = watch.ReadKeys()
+		defer stopKeys()
+	}
 
-	// For now the CLI only sends CYCLE messages for one or the other, not both RUNFILES and SOURCES
-	if watchRunfilesChanges && watchSourceChanges {
-		fmt.Printf("%s Watching for both source and runfiles changes UNSUPPORTED, fallback to watching sources\n", color.RedString("ERROR:"))
-		watchRunfilesChanges = false
+	opts := watch.CycleOptions{
+		State:     fmt.Sprintf("aspect-run-watch-%d", os.Getpid()),
+		Ignore:    ignore,
+		Keys:      keys,
+		Dashboard: dashboard,
+		Notifier:  notifier,
 	}
 
 	// Subscribe to further changes
-	for cs, err := range w.Subscribe(watchCtx, watcher.DeferState{DeferWithinState: watchState}) {
-		if err != nil {
-			// Break the subscribe iteration if the context is done or if the watcher is closed.
-			if errors.Is(err, context.Canceled) || errors.Is(err, net.ErrClosed) {
-				break
+	return watch.Cycles(watchCtx, w, opts, func(ctx context.Context, cs *watcher.ChangeSet) (retErr error) {
+		tctx, watchTrace := runner.tracer.Start(ctx, "Run.Subscribe.WatchEvent")
+		defer func() {
+			if retErr != nil {
+				watchTrace.SetStatus(codes.Error, retErr.Error())
 			}
+			watchTrace.End()
+		}()
 
-			return fmt.Errorf("failed to get next event: %w", err)
+		if cs.IsFreshInstance {
+			logger.Infof("watchman fresh-instance event, resetting state")
+		} else {
+			logger.Debugf("watchman detected changes: %v", cs.Paths)
 		}
 
-		if err := func() (retErr error) {
-			tctx, watchTrace := runner.tracer.Start(watchCtx, "Run.Subscribe.WatchEvent")
-			defer func() {
-				if retErr != nil {
-					watchTrace.SetStatus(codes.Error, retErr.Error())
-				}
-				watchTrace.End()
-			}()
-
-			// Enter into the build state to discard spurious changes caused by Bazel reading the
-			// inputs which leads to their atime to change.
-			if err := w.StateEnter(watchState); err != nil {
-				return fmt.Errorf("failed to enter build state: %w", err)
+		// Rebuild every target, only those whose inputs changed are restarted.
+		for _, target := range targets {
+			if err := target.cycle(tctx, cs); err != nil {
+				return err
 			}
-
-			if cs.IsFreshInstance {
-				logger.Infof("watchman fresh-instance event, resetting state")
-			} else {
-				logger.Debugf("watchman detected changes: %v", cs.Paths)
-			}
-
-			// The command to detect changes in the run target.
-			detectCmd, err := createBazelScriptCmd(false, true)
-			if err != nil {
-				return fmt.Errorf("failed to create bazel detect command: %w", err)
-			}
-
-			// Something has changed, but we have no idea if it affects our target.
-			// Normally we'd want to perform a cquery to determine if it affects but
-			// that is too costly especially in larger monorepos. So instead we rebuild
-			// the target with --execution_log_json_file and determine if it ran any
-			// actions.
-			//
-			// TODO: delay the command stdout and do not output on quick noops
-			logger.Infof("incremental --watch build: %v", detectCmd.Args)
-
-			incBuildErr := runner.runCmd(tctx, detectCmd, "Run.Subscribe.Build")
-
-			var sourceChanges []string
-			if !cs.IsFreshInstance {
-				sourceChanges = cs.Paths
-			}
-			if err := changedetect.detectChanges(sourceChanges); err != nil {
-				return fmt.Errorf("failed to detect changes: %w", err)
-			}
-
-			var (
-				cycleScope   ibp.WatchScope
-				cycleChanges ibp.SourceInfoMap
-				cycleIsReset bool
-			)
-
-			if incBuildErr != nil {
-				// The incremental build failed.
-				// Assume a temporary compilation error, assume an appropriate error message was outputted by the run command.
-				// Output a basic warning and resume waiting for changes.
-				fmt.Printf("%s incremental bazel build command failed: %v\n", color.YellowString("WARNING:"), incBuildErr)
-			} else {
-				// Drain accumulated changes every cycle to keep the
-				// detector's internal map bounded; the result may be
-				// ignored (source mode + fresh-instance) when constructing
-				// the IBP message.
-				changes := changedetect.cycleChanges()
-
-				switch {
-				case watchRunfilesChanges && len(changes) > 0:
-					// Runfiles deltas are reconciled from the runfiles manifest and
-					// execlog, so they're trustworthy even after a watchman fresh-instance
-					// where cs.Paths is empty.
-					logger.Infof("Cycle changes: %v", changes)
-
-					// For now just rerun the target, beware that RunCommand does not yield until
-					// the subprocess exits.
-					fmt.Printf("%s Found %d changes, rebuilding the target.\n", color.GreenString("INFO:"), len(changes))
-
-					cycleScope = ibp.WatchScope_Runfiles
-					cycleChanges = changes
-					// TODO: if we want to support ibazel livereload then we need to report changes.
-				case watchSourceChanges && cs.IsFreshInstance:
-					// Source-mode cycles are keyed by cs.Paths, which is unreliable on a
-					// fresh-instance and has no manifest-based reconciliation; signal a
-					// full peer reset.
-					cycleIsReset = true
-				case watchSourceChanges:
-					logger.Infof("Cycle source changes: %v", cs.Paths)
-
-					cycleScope = ibp.WatchScope_Sources
-					cycleChanges = make(ibp.SourceInfoMap, len(cs.Paths))
-					for _, changedSource := range cs.Paths {
-						cycleChanges[changedSource] = &ibp.SourceInfo{
-							IsSource: toJsonBoolPtr(true),
-						}
-					}
-				default:
-					fmt.Printf("%s Target is up-to-date.\n", color.GreenString("INFO:"))
-				}
-			}
-
-			if cycleIsReset {
-				ctctx, cycleTrace := runner.tracer.Start(tctx, "Run.Cycle")
-				defer cycleTrace.End()
-
-				if err := incrementalProtocol.CycleReset(ctctx); err != nil {
-					cycleTrace.SetStatus(codes.Error, err.Error())
-					return fmt.Errorf("failed to report cycle reset: %w", err)
-				}
-			} else if cycleScope != "" {
-				ctctx, cycleTrace := runner.tracer.Start(tctx, "Run.Cycle")
-				defer cycleTrace.End()
-
-				if err := incrementalProtocol.Cycle(ctctx, cycleScope, cycleChanges); err != nil {
-					cycleTrace.SetStatus(codes.Error, err.Error())
-					return fmt.Errorf("failed to report cycle events: %w", err)
-				}
-			}
-
-			// Leave the build state and fast forward the subscription clock.
-			if err := w.StateLeave(watchState); err != nil {
-				return fmt.Errorf("failed to leave build state: %w", err)
-			}
-
-			return nil
-		}(); err != nil {
-			return err
 		}
-	}
-
-	return nil
+		return nil
+	})
 }
@@ -19,12 +19,14 @@ package run_test
 import (
 	"context"
 	"fmt"
+	"io"
 	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
 	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
 
 	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/run"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/aspecterrors"
@@ -65,7 +67,7 @@ func TestRun(t *testing.T) {
 		besBackend.
 			EXPECT().
 			Args().
-			Return([]string{"--bes_backend=grpc://127.0.0.1:12345"}).
+			Return([]string{"--bes_backend=grpc://127.0.0.1:12345"}, nil).
 			Times(1)
 		besBackend.
 			EXPECT().
@@ -96,7 +98,7 @@ func TestRun(t *testing.T) {
 		besBackend.
 			EXPECT().
 			Args().
-			Return([]string{"--bes_backend=grpc://127.0.0.1:12345"}).
+			Return([]string{"--bes_backend=grpc://127.0.0.1:12345"}, nil).
 			Times(1)
 		besBackend.
 			EXPECT().
@@ -133,7 +135,7 @@ func TestRun(t *testing.T) {
 		besBackend.
 			EXPECT().
 			Args().
-			Return([]string{"--bes_backend=grpc://127.0.0.1:12345"}).
+			Return([]string{"--bes_backend=grpc://127.0.0.1:12345"}, nil).
 			Times(1)
 		besBackend.
 			EXPECT().
@@ -164,7 +166,7 @@ func TestRun(t *testing.T) {
 				return nil
 			})
 		besBackend := bep_mock.NewMockBESBackend(ctrl)
-		besBackend.EXPECT().Args().Return([]string{}).Times(1)
+		besBackend.EXPECT().Args().Return([]string{}, nil).Times(1)
 		besBackend.EXPECT().Errors().Times(1)
 
 		ctx := bep.InjectBESInterceptor(context.Background(), besBackend)
@@ -195,7 +197,7 @@ func TestRun(t *testing.T) {
 			}).
 			Times(2)
 		besBackend := bep_mock.NewMockBESBackend(ctrl)
-		besBackend.EXPECT().Args().Return([]string{}).Times(2)
+		besBackend.EXPECT().Args().Return([]string{}, nil).Times(2)
 		besBackend.EXPECT().Errors().Times(2)
 
 		ctx := bep.InjectBESInterceptor(context.Background(), besBackend)
@@ -227,7 +229,7 @@ func TestRun(t *testing.T) {
 				return nil
 			})
 		besBackend := bep_mock.NewMockBESBackend(ctrl)
-		besBackend.EXPECT().Args().Return([]string{}).Times(1)
+		besBackend.EXPECT().Args().Return([]string{}, nil).Times(1)
 		besBackend.EXPECT().Errors().Times(1)
 
 		ctx := bep.InjectBESInterceptor(context.Background(), besBackend)
@@ -246,4 +248,128 @@ func TestRun(t *testing.T) {
 		}
 		g.Expect(invocationIDCount).To(Equal(1), "invocation_id should appear exactly once")
 	})
+
+	t.Run("--watch-settle is not forwarded to bazel", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var capturedArgs []string
+		streams := ioutils.Streams{}
+		bzl := bazel_mock.NewMockBazel(ctrl)
+		bzl.
+			EXPECT().
+			RunCommand(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ ioutils.Streams, _ *string, args ...string) error {
+				capturedArgs = args
+				return nil
+			})
+
+		b := run.New(streams, streams, bzl)
+		err := b.Run(context.Background(), nil, []string{"//app", "--watch-settle=0s", "--", "--watch-settle=1s"})
+
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(capturedArgs[:2]).To(Equal([]string{"run", "//app"}))
+		g.Expect(capturedArgs).ToNot(ContainElement("--watch-settle=0s"))
+		g.Expect(capturedArgs).To(ContainElement("--watch-settle=1s"))
+	})
+
+	t.Run("an invalid --watch-settle fails before running bazel", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		streams := ioutils.Streams{}
+		bzl := bazel_mock.NewMockBazel(ctrl)
+
+		b := run.New(streams, streams, bzl)
+		g.Expect(b.Run(context.Background(), nil, []string{"//app", "--watch", "--watch-settle=soon"})).
+			To(MatchError(ContainSubstring(`invalid --watch-settle "soon"`)))
+		g.Expect(b.Run(context.Background(), nil, []string{"//app", "--watch", "--watch-settle=-1s"})).
+			To(MatchError(`invalid --watch-settle "-1s": must not be negative`))
+	})
+
+	t.Run("an invalid watch_settle in the config fails before running bazel", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		viper.Set(run.WatchSettleKey, "10")
+		t.Cleanup(viper.Reset)
+
+		streams := ioutils.Streams{}
+		bzl := bazel_mock.NewMockBazel(ctrl)
+
+		b := run.New(streams, streams, bzl)
+		g.Expect(b.Run(context.Background(), nil, []string{"//app", "--watch"})).
+			To(MatchError(ContainSubstring(`invalid watch_settle "10"`)))
+	})
+
+	t.Run("an invalid watch_change_detection in the config fails before running bazel", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		viper.Set(run.WatchChangeDetectionKey, "mtime")
+		t.Cleanup(viper.Reset)
+
+		streams := ioutils.Streams{Stdout: io.Discard, Stderr: io.Discard}
+		bzl := bazel_mock.NewMockBazel(ctrl)
+
+		b := run.New(streams, streams, bzl)
+		g.Expect(b.Run(context.Background(), nil, []string{"//app", "--watch"})).
+			To(MatchError(`invalid watch_change_detection "mtime": must be "execlog" or "bep"`))
+	})
+
+	t.Run("an invalid watch_scope_detection in the config fails before running bazel", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		viper.Set(run.WatchScopeDetectionKey, "query")
+		t.Cleanup(viper.Reset)
+
+		streams := ioutils.Streams{Stdout: io.Discard, Stderr: io.Discard}
+		bzl := bazel_mock.NewMockBazel(ctrl)
+
+		b := run.New(streams, streams, bzl)
+		g.Expect(b.Run(context.Background(), nil, []string{"//app", "--watch"})).
+			To(MatchError(`invalid watch_scope_detection "query": must be "build" or "cquery"`))
+	})
+	t.Run("an invalid --watch-env fails before running bazel", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		streams := ioutils.Streams{}
+		bzl := bazel_mock.NewMockBazel(ctrl)
+
+		b := run.New(streams, streams, bzl)
+		g.Expect(b.Run(context.Background(), nil, []string{"//app", "--watch", "--watch-env=PORT"})).
+			To(MatchError(`invalid --watch-env "PORT": must be NAME=VALUE`))
+	})
+	t.Run("--aspect:pty can't be combined with --aspect:ui", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		streams := ioutils.Streams{}
+		bzl := bazel_mock.NewMockBazel(ctrl)
+
+		b := run.New(streams, streams, bzl)
+		g.Expect(b.Run(context.Background(), nil, []string{"//app", "--watch", "--aspect:pty", "--aspect:ui"})).
+			To(MatchError(`--aspect:pty can't be combined with --aspect:ui`))
+	})
+	t.Run("--detach can't be combined with --watch", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		streams := ioutils.Streams{}
+		bzl := bazel_mock.NewMockBazel(ctrl)
+
+		b := run.New(streams, streams, bzl)
+		g.Expect(b.Run(context.Background(), nil, []string{"//app", "--watch", "--detach"})).
+			To(MatchError(`--detach can't be combined with --watch`))
+	})
 }
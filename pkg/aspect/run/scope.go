@@ -0,0 +1,111 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package run
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/watch"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+)
+
+// watchScope is the set of the source files of the workspace a run target
+// depends on, computed with cquery, so that the changes to other files don't
+// rebuild the target and the changes to its files are detected even when the
+// build runs no action for them, such as when they are cached remotely.
+type watchScope struct {
+	root     string
+	sources  map[string]bool
+	packages map[string]bool
+}
+
+// queryWatchScope queries the source files of the target in the configuration
+// of the bazel flags, which are those of the builds of the target so that the
+// analysis cache is kept.
+func queryWatchScope(bzl bazel.Bazel, label string, bazelFlags []string) (*watchScope, error) {
+	var stdout, stderr bytes.Buffer
+	streams := ioutils.Streams{Stdout: &stdout, Stderr: &stderr}
+
+	args := append([]string{"cquery"}, bazelFlags...)
+	args = append(args, "--output=label", "--", fmt.Sprintf("kind(\"source file\", deps(%s))", label))
+	if err := bzl.RunCommand(streams, nil, args...); err != nil {
+		return nil, fmt.Errorf("failed to query the sources of %s: %w\nstderr:\n%s", label, err, stderr.String())
+	}
+	return newWatchScope(bzl.WorkspaceRoot(), &stdout), nil
+}
+
+// newWatchScope reads the labels printed by cquery --output=label, such as
+// //app:src/main.go (null), ignoring those of the external repositories.
+func newWatchScope(root string, labels *bytes.Buffer) *watchScope {
+	s := &watchScope{
+		root:     root,
+		sources:  make(map[string]bool),
+		packages: make(map[string]bool),
+	}
+	scan := bufio.NewScanner(labels)
+	for scan.Scan() {
+		label, _, _ := strings.Cut(strings.TrimSpace(scan.Text()), " ")
+		for _, prefix := range []string{"@@//", "@//", "//"} {
+			if rest, found := strings.CutPrefix(label, prefix); found {
+				if pkg, name, ok := strings.Cut(rest, ":"); ok {
+					s.sources[path.Join(pkg, name)] = true
+					s.packages[pkg] = true
+				}
+				break
+			}
+		}
+	}
+	return s
+}
+
+// affected returns the changed files of the scope, and whether the scope
+// itself may have changed, such as with the BUILD files or with new files of
+// its packages, which are picked up by their globs.
+func (s *watchScope) affected(paths []string) (changed []string, stale bool) {
+	for _, p := range paths {
+		switch {
+		case s.sources[p]:
+			changed = append(changed, p)
+		case watch.IsBuildDefinition(p):
+			stale = true
+		case s.packages[s.packageOf(p)]:
+			changed = append(changed, p)
+			stale = true
+		}
+	}
+	return changed, stale
+}
+
+// packageOf returns the package of the workspace containing the file, the
+// closest directory with a BUILD file.
+func (s *watchScope) packageOf(p string) string {
+	for dir := path.Dir(p); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		for _, build := range []string{"BUILD.bazel", "BUILD"} {
+			if _, err := os.Stat(filepath.Join(s.root, dir, build)); err == nil {
+				return dir
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package run
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestWatchScope(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"app", "lib", "other"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, dir, "BUILD.bazel"), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := newWatchScope(root, bytes.NewBufferString(`//app:main.go (null)
+@@//lib:src/util.go (null)
+@//:go.mod (null)
+@@rules_go~//go/tools:builder.go (null)
+`))
+
+	for _, tc := range []struct {
+		paths   []string
+		changed []string
+		stale   bool
+	}{
+		// Sources of the target, at the root of the workspace too.
+		{[]string{"app/main.go", "go.mod"}, []string{"app/main.go", "go.mod"}, false},
+		{[]string{"lib/src/util.go"}, []string{"lib/src/util.go"}, false},
+		// Files of other packages.
+		{[]string{"other/main.go", "other/BUILD.txt"}, nil, false},
+		// Build definitions, which may change the scope.
+		{[]string{"other/BUILD.bazel"}, nil, true},
+		{[]string{"tools/defs.bzl"}, nil, true},
+		// New files of the packages of the scope, which may be picked up by globs.
+		{[]string{"lib/src/new.go"}, []string{"lib/src/new.go"}, true},
+	} {
+		changed, stale := s.affected(tc.paths)
+		if !slices.Equal(changed, tc.changed) || stale != tc.stale {
+			t.Errorf("affected(%v) = %v, %v, expected %v, %v", tc.paths, changed, stale, tc.changed, tc.stale)
+		}
+	}
+
+	if s.sources["go/tools/builder.go"] {
+		t.Errorf("Expected the external sources to be ignored, got %v", s.sources)
+	}
+}
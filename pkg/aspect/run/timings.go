@@ -0,0 +1,170 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package run
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+)
+
+// WatchTimingsKey is the key of the Aspect CLI config that turns off the
+// breakdown of the time of each cycle of aspect run --watch when set to false.
+const WatchTimingsKey = "watch_timings"
+
+// watchTimingsEnabled returns true unless the breakdown of the cycles is
+// turned off in the Aspect CLI config.
+func watchTimingsEnabled() bool {
+	return !viper.IsSet(WatchTimingsKey) || viper.GetBool(WatchTimingsKey)
+}
+
+// How long to wait for the build metrics of a build once bazel has exited.
+var buildPhasesTimeout = 250 * time.Millisecond
+
+// cycleTimings is where the time of a cycle of a run target went.
+type cycleTimings struct {
+	started time.Time
+	total   time.Duration
+
+	changeDetection time.Duration
+	build           time.Duration
+	restart         time.Duration
+	settle          time.Duration
+
+	// The phases of the build, when reported in its build events.
+	analysis  time.Duration
+	execution time.Duration
+	phases    bool
+}
+
+type cycleTimingsKey struct{}
+
+// withCycleTimings returns a context recording the time spent in settle into
+// the timings of the cycle.
+func withCycleTimings(ctx context.Context, t *cycleTimings) context.Context {
+	return context.WithValue(ctx, cycleTimingsKey{}, t)
+}
+
+// cycleTimingsFrom returns the timings of the cycle of ctx, or nil.
+func cycleTimingsFrom(ctx context.Context) *cycleTimings {
+	t, _ := ctx.Value(cycleTimingsKey{}).(*cycleTimings)
+	return t
+}
+
+// String formats the timings as a list of the steps that took time.
+func (t *cycleTimings) String() string {
+	build := "build " + roundTiming(t.build)
+	if t.phases {
+		build += fmt.Sprintf(" (analysis %s, execution %s)", roundTiming(t.analysis), roundTiming(t.execution))
+	}
+	steps := []string{build, "change detection " + roundTiming(t.changeDetection)}
+	if t.restart > 0 {
+		steps = append(steps, "restart "+roundTiming(t.restart))
+	}
+	if t.settle > 0 {
+		steps = append(steps, "settle "+roundTiming(t.settle))
+	}
+	return strings.Join(steps, ", ")
+}
+
+// roundTiming rounds the duration to the millisecond, or to the 10
+// milliseconds above a second.
+func roundTiming(d time.Duration) string {
+	if d >= time.Second {
+		return d.Round(10 * time.Millisecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
+
+// buildPhases reads the timing metrics of the builds from their build events,
+// to break the builds of the cycles down into their analysis and execution.
+type buildPhases struct {
+	mu      sync.Mutex
+	metrics map[string]*buildPhasesMetrics
+}
+
+type buildPhasesMetrics struct {
+	done    chan struct{}
+	metrics *buildeventstream.BuildMetrics_TimingMetrics
+}
+
+// newBuildPhases subscribes to the build metrics of the interceptor.
+func newBuildPhases(besInterceptor bep.BESInterceptor) *buildPhases {
+	p := &buildPhases{metrics: make(map[string]*buildPhasesMetrics)}
+	besInterceptor.RegisterSubscriber(p.callback, bep.SubscriberOptions{}, "build_metrics")
+	return p
+}
+
+// entry returns the metrics of the invocation, which are done once they were
+// received. p.mu must be held.
+func (p *buildPhases) entry(invocationId string) *buildPhasesMetrics {
+	m, ok := p.metrics[invocationId]
+	if !ok {
+		m = &buildPhasesMetrics{done: make(chan struct{})}
+		p.metrics[invocationId] = m
+	}
+	return m
+}
+
+func (p *buildPhases) callback(event *buildeventstream.BuildEvent, _ int64, stream bep.StreamInfo) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	m := p.entry(stream.InvocationId)
+	select {
+	case <-m.done:
+	default:
+		m.metrics = event.GetBuildMetrics().GetTimingMetrics()
+		close(m.done)
+	}
+	return nil
+}
+
+// record waits for the build metrics of the invocation and records its phases
+// into the timings. The phases are left out when the metrics don't arrive in
+// time.
+func (p *buildPhases) record(invocationId string, t *cycleTimings) {
+	if p == nil || invocationId == "" {
+		return
+	}
+	p.mu.Lock()
+	m := p.entry(invocationId)
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.metrics, invocationId)
+		p.mu.Unlock()
+	}()
+
+	select {
+	case <-m.done:
+	case <-time.After(buildPhasesTimeout):
+		return
+	}
+	if m.metrics == nil {
+		return
+	}
+	t.analysis = time.Duration(m.metrics.GetAnalysisPhaseTimeInMs()) * time.Millisecond
+	t.execution = time.Duration(m.metrics.GetExecutionPhaseTimeInMs()) * time.Millisecond
+	t.phases = true
+}
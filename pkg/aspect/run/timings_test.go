@@ -0,0 +1,109 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package run
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+)
+
+func TestCycleTimings(t *testing.T) {
+	t.Run("lists the steps that took time", func(t *testing.T) {
+		timings := &cycleTimings{
+			build:           1234 * time.Millisecond,
+			changeDetection: 12345 * time.Microsecond,
+			restart:         80 * time.Millisecond,
+			settle:          100 * time.Millisecond,
+		}
+		expected := "build 1.23s, change detection 12ms, restart 80ms, settle 100ms"
+		if timings.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, timings.String())
+		}
+	})
+
+	t.Run("breaks the build down into its phases", func(t *testing.T) {
+		timings := &cycleTimings{
+			build:           2 * time.Second,
+			analysis:        300 * time.Millisecond,
+			execution:       1500 * time.Millisecond,
+			phases:          true,
+			changeDetection: 5 * time.Millisecond,
+		}
+		expected := "build 2s (analysis 300ms, execution 1.5s), change detection 5ms"
+		if timings.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, timings.String())
+		}
+	})
+
+	t.Run("records the settle delay of the cycle", func(t *testing.T) {
+		timings := &cycleTimings{}
+		settle(withCycleTimings(context.Background(), timings), 10*time.Millisecond)
+		if timings.settle < 10*time.Millisecond {
+			t.Errorf("Expected a settle delay of at least 10ms, got %v", timings.settle)
+		}
+	})
+
+	t.Run("can be turned off in the config", func(t *testing.T) {
+		if !watchTimingsEnabled() {
+			t.Errorf("Expected the timings to be on by default")
+		}
+		viper.Set(WatchTimingsKey, false)
+		t.Cleanup(viper.Reset)
+		if watchTimingsEnabled() {
+			t.Errorf("Expected the timings to be turned off")
+		}
+	})
+}
+
+func TestBuildPhases(t *testing.T) {
+	p := &buildPhases{metrics: make(map[string]*buildPhasesMetrics)}
+
+	event := &buildeventstream.BuildEvent{
+		Payload: &buildeventstream.BuildEvent_BuildMetrics{BuildMetrics: &buildeventstream.BuildMetrics{
+			TimingMetrics: &buildeventstream.BuildMetrics_TimingMetrics{
+				AnalysisPhaseTimeInMs:  300,
+				ExecutionPhaseTimeInMs: 1200,
+			},
+		}},
+	}
+	if err := p.callback(event, 0, bep.StreamInfo{InvocationId: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	timings := &cycleTimings{}
+	p.record("a", timings)
+	if !timings.phases || timings.analysis != 300*time.Millisecond || timings.execution != 1200*time.Millisecond {
+		t.Errorf("Expected the phases of the build, got %+v", timings)
+	}
+	if len(p.metrics) != 0 {
+		t.Errorf("Expected the metrics to be forgotten once recorded, got %v", p.metrics)
+	}
+
+	// The phases are left out when the build metrics don't arrive.
+	defer func(timeout time.Duration) { buildPhasesTimeout = timeout }(buildPhasesTimeout)
+	buildPhasesTimeout = time.Millisecond
+	timings = &cycleTimings{}
+	p.record("b", timings)
+	if timings.phases {
+		t.Errorf("Expected no phases without build metrics, got %+v", timings)
+	}
+}
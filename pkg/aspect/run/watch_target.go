@@ -0,0 +1,653 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package run
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/root/flags"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/watch"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+	logger "github.com/aspect-build/aspect-gazelle/common/logger"
+	"github.com/aspect-build/aspect-gazelle/runner/pkg/ibp"
+	watcher "github.com/aspect-build/aspect-gazelle/runner/pkg/watchman"
+	"github.com/fatih/color"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// watchTargetCommands splits the bazel run command of aspect run --watch into
+// one command per run target. The targets are the absolute labels given
+// before any bare "--", e.g. //app:server //app:worker; the arguments after it
+// are passed to every target.
+func watchTargetCommands(bazelCmd []string) (labels []string, commands [][]string) {
+	for _, arg := range bazelCmd[1:] {
+		if arg == "--" {
+			break
+		}
+		if strings.HasPrefix(arg, "//") || strings.HasPrefix(arg, "@") {
+			labels = append(labels, arg)
+		}
+	}
+	if len(labels) <= 1 {
+		return labels, [][]string{bazelCmd}
+	}
+
+	for _, label := range labels {
+		command := make([]string, 0, len(bazelCmd)-len(labels)+1)
+		dashdash := false
+		for _, arg := range bazelCmd {
+			dashdash = dashdash || arg == "--"
+			if !dashdash && arg != label && slices.Contains(labels, arg) {
+				continue
+			}
+			command = append(command, arg)
+		}
+		commands = append(commands, command)
+	}
+	return labels, commands
+}
+
+// watchTarget is a run target of aspect run --watch, which is rebuilt and
+// restarted, or notified through the incremental build protocol, when its
+// inputs change.
+type watchTarget struct {
+	runner   *Run
+	index    int
+	label    string
+	bazelCmd []string
+	streams  ioutils.Streams
+	settle   time.Duration
+
+	changedetect *ChangeDetector
+	startScript  string
+
+	// The file listing the changes of the last cycle, see WatchChangesFileEnv,
+	// and the number of the cycles reported to the target.
+	changesFile string
+	cycles      int
+
+	// The environment variables of the target, see WatchEnvFlag, and the
+	// number of changes and the invocation id of the last cycle they are
+	// expanded with when the target is started.
+	env          []watchEnvVar
+	changeCount  int
+	invocationId string
+
+	// The output of the processes of the target prefixed with its label, when
+	// the session has several targets.
+	output *prefixedOutput
+
+	// The pseudo-terminal the processes of the target are started in, see
+	// flags.AspectPtyFlag.
+	pty *ptyTerminal
+
+	// The hot reload service of the target when it uses the ibazel protocol,
+	// and the socket it is served on, see HotReloadAddressEnv.
+	hotReload       *hotReloadServer
+	hotReloadSocket string
+
+	// The abazel protocol, potentially used as the incremental build tool.
+	// Its socket is per process, so only the first target offers it.
+	abazel              ibp.IncrementalBazel
+	startCmd            *exec.Cmd
+	incrementalProtocol ibp.IncrementalBazel
+
+	watchRunfilesChanges bool
+	watchSourceChanges   bool
+
+	// Whether the scope of the target is queried, see ScopeDetectionCQuery,
+	// and the last one, which is queried again after the next build when
+	// stale or missing.
+	queryScope bool
+	scope      *watchScope
+	scopeStale bool
+
+	// The dashboard and the notifier of the session, if any, which show the
+	// results of the cycles.
+	dashboard *watch.Dashboard
+	notifier  *watch.Notifier
+
+	// Whether the time of each cycle is broken down, see WatchTimingsKey, and
+	// the phases of the builds read from the build events, if any.
+	timings bool
+	phases  *buildPhases
+}
+
+// newWatchTarget creates the change detector and the run script of the
+// target. The index tells the targets of a session apart, and the changes are
+// detected from the build events of besInterceptor unless it is nil.
+func (runner *Run) newWatchTarget(index int, label string, bazelCmd []string, streams ioutils.Streams, settle time.Duration, isBazel7 bool, besInterceptor bep.BESInterceptor) (*watchTarget, error) {
+	changedetect, err := newChangeDetector(runner.bzl.WorkspaceRoot(), isBazel7, besInterceptor, streams.Stdout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to created change detector: %w", err)
+	}
+
+	startScriptName := fmt.Sprintf("aspect-run-%v", os.Getpid())
+	if index > 0 {
+		startScriptName += fmt.Sprintf("-%d", index)
+	}
+	changesFileName := startScriptName + "-changes.json"
+	hotReloadSocketName := startScriptName + "-hotreload.sock"
+	if runtime.GOOS == "windows" {
+		startScriptName += ".bat"
+	}
+
+	return &watchTarget{
+		runner:       runner,
+		index:        index,
+		label:        label,
+		bazelCmd:     bazelCmd,
+		streams:      streams,
+		settle:       settle,
+		changedetect: changedetect,
+		startScript:  path.Join(os.TempDir(), startScriptName),
+		changesFile:  path.Join(os.TempDir(), changesFileName),
+
+		hotReloadSocket: path.Join(os.TempDir(), hotReloadSocketName),
+	}, nil
+}
+
+// name returns how the target is referred to in the messages.
+func (target *watchTarget) name() string {
+	if target.label == "" {
+		return "the target"
+	}
+	return target.label
+}
+
+func (target *watchTarget) createBazelScriptCmd(ctx context.Context, allowDiscard, trackChanges bool) (*exec.Cmd, error) {
+	// Additional arguments for the bazel run command
+	runCmdArgs := []string{}
+
+	// ChangeDetector normally adds additional flags
+	runCmdArgs = append(runCmdArgs, target.changedetect.bazelFlags(trackChanges)...)
+
+	// --norun and generate a run script instead
+	runCmdArgs = append(runCmdArgs, "--norun", "--script_path", target.startScript)
+
+	// --noallow_analysis_cache_discard except on the intial setup run
+	if !allowDiscard {
+		runCmdArgs = append(runCmdArgs, "--noallow_analysis_cache_discard")
+	}
+
+	allArgs := flags.AddFlagToCommand(target.bazelCmd, runCmdArgs...)
+	cmd, err := target.runner.bzl.MakeBazelCommand(ctx, allArgs, target.streams, nil, nil)
+	return cmd, err
+}
+
+func (target *watchTarget) createRunCmd(ctx context.Context) *exec.Cmd {
+	// Inherit the CLI environment variables
+	env := os.Environ()[:]
+
+	// Add the incremental build protocol(s) environment variables
+	env = append(env, "IBAZEL_NOTIFY_CHANGES=y")
+	env = append(env, fmt.Sprintf("%s=%s", WatchChangesFileEnv, target.changesFile))
+	if target.abazel != nil {
+		env = append(env, target.abazel.Env()...)
+	}
+
+	// Add the variables of the cycle the target is started for
+	vars := cycleVars{cycle: target.cycles, changes: target.changeCount, invocationId: target.invocationId}
+	env = append(env, vars.env(target.env)...)
+
+	startCmd := exec.CommandContext(ctx, target.startScript)
+	startCmd.Stdin = target.streams.Stdin
+	startCmd.Stdout = target.streams.Stdout
+	startCmd.Stderr = target.streams.Stderr
+	if target.output != nil {
+		startCmd.Stdout = target.output.stdout
+		startCmd.Stderr = target.output.stderr
+	}
+	if target.pty != nil {
+		target.pty.attach(startCmd)
+	}
+	startCmd.Env = env
+	return startCmd
+}
+
+// init builds and starts the target, and sets up the incremental protocol with
+// it.
+func (target *watchTarget) init(watchCtx context.Context) error {
+	if target.index == 0 {
+		// Must initialize and start listening for connections before the initial bazel run command.
+		// Start the incremental build service in case the process supports it and connects
+		target.abazel = ibp.NewServer()
+
+		// Start listening for a connection immediately.
+		if err := target.abazel.Serve(watchCtx); err != nil {
+			return fmt.Errorf("failed to connect to aspect bazel protocol: %w", err)
+		}
+
+		fmt.Fprintf(target.streams.Stdout, "%s Listening on watch socket %s\n", color.GreenString("INFO:"), target.abazel.Address())
+	}
+
+	// Create and start the intial bazel command to build+inspect the run target
+	err := func() (retErr error) {
+		initCtx, initTrace := target.runner.tracer.Start(watchCtx, "Run.Init")
+		defer func() {
+			if retErr != nil {
+				initTrace.SetStatus(codes.Error, retErr.Error())
+			}
+			initTrace.End()
+		}()
+
+		initCmd, err := target.createBazelScriptCmd(watchCtx, true, false)
+		if err != nil {
+			return fmt.Errorf("failed to create initial bazel command: %w", err)
+		}
+
+		logger.Infof("initial --watch build: %v", initCmd.Args)
+
+		if err := target.runner.runCmd(initCtx, initCmd, "Run.Subscribe.Build"); err != nil {
+			return fmt.Errorf("initial bazel command failed: %w", err)
+		}
+		target.invocationId = flags.FindInvocationId(initCmd.Args)
+
+		// Detect the context of the run target after this initial build.
+		if err := target.changedetect.detectContext(); err != nil {
+			return fmt.Errorf("failed to detect context on init: %w", err)
+		}
+
+		// The command to start the run target.
+		startCmd := target.createRunCmd(watchCtx)
+
+		// The incremental bazel protocol/tool to use going forward.
+		var incrementalProtocol ibp.IncrementalBazel
+
+		// If the target explicitly supports ibazel but NOT explicitly supports incremental build protocol
+		// then assume only legacy ibazel support is available.
+		if target.changedetect.supportsIBazelNotifyChanges() && !target.changedetect.explicitlySupportsIBP() {
+			// Fallback to only using the legacy ibazel protocol.
+			fmt.Fprintf(target.streams.Stdout, "%s Fallback to legacy ibazel protocol\n", color.GreenString("INFO:"))
+
+			// In order to support ibazel events we need to set the stdin to a pipe.
+			// By default MakeBazelCommand sets it to bzlCommandStreams.stdin but we
+			// want to control stdin depending on the watch mode.
+			// In order to pipe stdin we need to set it to nil first and then call StdinPipe.
+			startCmd.Stdin = nil
+			runStdin, err := startCmd.StdinPipe()
+			if err != nil {
+				return fmt.Errorf("failed to create stdin pipe for ibazel: %w", err)
+			}
+
+			// Offer the hot reload service, which replaces the lines on stdin
+			// once the target subscribes to it.
+			hotReload, err := newHotReloadServer(target.hotReloadSocket)
+			if err != nil {
+				fmt.Fprintf(target.streams.Stdout, "%s %v\n", color.YellowString("WARNING:"), err)
+			} else {
+				target.hotReload = hotReload
+				startCmd.Env = append(startCmd.Env, hotReload.Env()...)
+			}
+
+			incrementalProtocol = &IBazelProtocol{
+				stdin:       runStdin,
+				settleDelay: target.settle,
+				hotReload:   target.hotReload,
+			}
+		} else if target.abazel != nil {
+			incrementalProtocol = target.abazel
+		} else if target.changedetect.explicitlySupportsIBP() {
+			return fmt.Errorf("%s explicitly supports the incremental build protocol, which only the first target of aspect run --watch can use", target.name())
+		}
+
+		// Start the bazel command
+		if err := startCmd.Start(); err != nil {
+			return fmt.Errorf("failed to start bazel command: %w", err)
+		}
+		target.startCmd = startCmd
+
+		// Significantly increase the timeout if the target explicitly supports the watch protocol
+		// since failure to connect will be a hard error instead of a fallback to restarting.
+		watchConnectionTimeout := defaultWatchConnectionTimeout
+		if target.changedetect.explicitlySupportsIBP() {
+			watchConnectionTimeout *= 10
+		}
+
+		// Give the watcher some time to start and open the connection before sending Init()
+		if incrementalProtocol != nil && !incrementalProtocol.HasConnection() {
+			// TODO: don't assume abazel is the only non-instant connection
+
+			select {
+			case <-watchCtx.Done():
+				fmt.Fprintf(target.streams.Stdout, "%s Process cancelled before establishing connection: %v\n", color.RedString("ERROR:"), watchCtx.Err())
+				return watchCtx.Err()
+			case v := <-target.abazel.WaitForConnection():
+				fmt.Fprintf(target.streams.Stdout, "%s Received connection to %s using abazel v%v\n", color.GreenString("INFO:"), target.abazel.Address(), v)
+			case <-time.After(watchConnectionTimeout):
+				fmt.Fprintf(target.streams.Stdout, "%s Timeout (%vms) waiting for watch protocol connection.\n", color.YellowString("WARNING:"), watchConnectionTimeout.Milliseconds())
+			}
+		}
+
+		// Abandon the incremental protocol if the target has not responded
+		if incrementalProtocol == nil || !incrementalProtocol.HasConnection() {
+			if incrementalProtocol != nil {
+				if target.changedetect.explicitlySupportsIBP() {
+					fmt.Fprintf(target.streams.Stdout, "%s target explicitly supports incremental build protocol but did not connect within %vms.\n", color.RedString("ERROR:"), watchConnectionTimeout.Milliseconds())
+					os.Exit(1)
+				}
+
+				fmt.Fprintf(target.streams.Stdout, "%s No watch protocol connection established. Fallback to restart.\n", color.YellowString("WARNING:"))
+
+				go target.abazel.Close()
+				target.abazel = nil
+			}
+
+			incrementalProtocol = &RestartBazelProtocol{
+				createRunCmd: func() *exec.Cmd { return target.createRunCmd(watchCtx) },
+				runCmd:       startCmd,
+				settleDelay:  target.settle,
+			}
+		}
+
+		// Init() with the full runfiles list
+		cctx, initCycleTrace := target.runner.tracer.Start(initCtx, "Run.Cycle")
+		defer func() {
+			if retErr != nil {
+				initCycleTrace.SetStatus(codes.Error, retErr.Error())
+			}
+			initCycleTrace.End()
+		}()
+
+		initRunfiles, initRunfilesErr := target.changedetect.loadFullSourceInfo()
+		if initRunfilesErr != nil {
+			return fmt.Errorf("failed to load initial runfiles: %w", initRunfilesErr)
+		}
+		if err := incrementalProtocol.Init(cctx, ibp.WatchScope_Runfiles, initRunfiles); err != nil {
+			return fmt.Errorf("failed to initialize watch protocol: %w", err)
+		}
+
+		target.incrementalProtocol = incrementalProtocol
+		return nil
+	}()
+	if err != nil {
+		return err
+	}
+
+	// Send an 'Exit' message to the child process when the context completes in case
+	// the context was cancelled due to the cli being shutdown.
+	go func() {
+		<-watchCtx.Done()
+
+		// If a connection still exists to the incremental protocol, send an Exit message and
+		// hope for a graceful shutdown. Ignore any errors as the process may already be in the
+		// process of shutting down.
+		if target.incrementalProtocol.HasConnection() {
+			target.incrementalProtocol.Exit(context.Background(), watchCtx.Err())
+		}
+
+		// Terminate the process if it is still running.
+		terminate(target.startCmd.Process)
+	}()
+
+	// If the client declared the watching of sources via IBP
+	// TODO: other methods of declaring watching sources? tags on targets succh as formatters?
+	target.watchRunfilesChanges = target.incrementalProtocol.WatchingScope(ibp.WatchScope_Runfiles)
+	target.watchSourceChanges = target.incrementalProtocol.WatchingScope(ibp.WatchScope_Sources)
+
+	// For now the CLI only sends CYCLE messages for one or the other, not both RUNFILES and SOURCES
+	if target.watchRunfilesChanges && target.watchSourceChanges {
+		fmt.Fprintf(target.streams.Stdout, "%s Watching for both source and runfiles changes UNSUPPORTED, fallback to watching sources\n", color.RedString("ERROR:"))
+		target.watchRunfilesChanges = false
+	}
+
+	return nil
+}
+
+// refreshScope queries the scope of the target with the flags of its builds.
+// The target is rebuilt on every change until its scope is known.
+func (target *watchTarget) refreshScope() {
+	target.scope = nil
+	target.scopeStale = false
+
+	labels, _ := watchTargetCommands(target.bazelCmd)
+	if len(labels) != 1 {
+		fmt.Fprintf(target.streams.Stdout, "%s No label to query the sources of %s with, rebuilding it on every change.\n", color.YellowString("WARNING:"), target.name())
+		return
+	}
+	bazelFlags := []string{}
+	for _, arg := range target.bazelCmd[1:] {
+		if arg == "--" {
+			break
+		}
+		if arg != labels[0] {
+			bazelFlags = append(bazelFlags, arg)
+		}
+	}
+
+	scope, err := queryWatchScope(target.runner.bzl, labels[0], bazelFlags)
+	if err != nil {
+		fmt.Fprintf(target.streams.Stdout, "%s %v, rebuilding %s on every change.\n", color.YellowString("WARNING:"), err, target.name())
+		return
+	}
+	logger.Infof("watching %d sources of %s", len(scope.sources), labels[0])
+	target.scope = scope
+}
+
+// cycle rebuilds the target for the change set, and restarts or notifies it
+// if its inputs changed.
+func (target *watchTarget) cycle(ctx context.Context, cs *watcher.ChangeSet) error {
+	// The changes to the sources of the target, when its scope is queried.
+	var scopeChanges []string
+	if target.scope != nil && !watch.Forced(ctx) {
+		if cs.IsFreshInstance {
+			target.scopeStale = true
+		} else {
+			changed, stale := target.scope.affected(cs.Paths)
+			if len(changed) == 0 && !stale {
+				fmt.Fprintf(target.streams.Stdout, "%s No source of %s changed.\n", color.GreenString("INFO:"), target.name())
+				return nil
+			}
+			scopeChanges = changed
+			target.scopeStale = target.scopeStale || stale
+		}
+	}
+
+	timings := &cycleTimings{started: time.Now()}
+	ctx = withCycleTimings(ctx, timings)
+
+	target.hotReload.started()
+
+	// The command to detect changes in the run target.
+	detectCmd, err := target.createBazelScriptCmd(ctx, false, true)
+	if err != nil {
+		return fmt.Errorf("failed to create bazel detect command: %w", err)
+	}
+
+	// Something has changed, but unless the scope of the target is queried we
+	// have no idea if it affects our target. The cquery is too costly for every
+	// change especially in larger monorepos. So instead we rebuild the target
+	// with --execution_log_json_file and determine if it ran any actions.
+	//
+	// TODO: delay the command stdout and do not output on quick noops
+	logger.Infof("incremental --watch build: %v", detectCmd.Args)
+
+	buildStarted := time.Now()
+	incBuildErr := target.runner.runCmd(ctx, detectCmd, "Run.Subscribe.Build")
+	timings.build = time.Since(buildStarted)
+	target.invocationId = flags.FindInvocationId(detectCmd.Args)
+
+	var sourceChanges []string
+	if !cs.IsFreshInstance {
+		sourceChanges = cs.Paths
+	}
+	detectStarted := time.Now()
+	if err := target.changedetect.detectChanges(sourceChanges); err != nil {
+		return fmt.Errorf("failed to detect changes: %w", err)
+	}
+	timings.changeDetection = time.Since(detectStarted)
+
+	var (
+		cycleScope   ibp.WatchScope
+		cycleChanges ibp.SourceInfoMap
+		cycleIsReset bool
+	)
+
+	if incBuildErr != nil {
+		// The incremental build failed.
+		// Assume a temporary compilation error, assume an appropriate error message was outputted by the run command.
+		// Output a basic warning and resume waiting for changes.
+		buildErr := fmt.Errorf("%s: %w", target.name(), incBuildErr)
+		target.dashboard.Result(buildErr)
+		target.notifier.Result(buildErr)
+		fmt.Fprintf(target.streams.Stdout, "%s incremental bazel build command failed: %v\n", color.YellowString("WARNING:"), incBuildErr)
+	} else {
+		target.dashboard.Result(nil)
+
+		if target.queryScope && (target.scope == nil || target.scopeStale) {
+			scopeStarted := time.Now()
+			target.refreshScope()
+			timings.changeDetection += time.Since(scopeStarted)
+		}
+
+		// Drain accumulated changes every cycle to keep the
+		// detector's internal map bounded; the result may be
+		// ignored (source mode + fresh-instance) when constructing
+		// the IBP message.
+		changes := target.changedetect.cycleChanges()
+
+		switch {
+		case watch.Forced(ctx):
+			// Restart the target as requested in the terminal, even if it is up-to-date.
+			fmt.Fprintf(target.streams.Stdout, "%s Restarting %s.\n", color.GreenString("INFO:"), target.name())
+
+			cycleIsReset = true
+		case target.watchRunfilesChanges && len(changes) > 0:
+			// Runfiles deltas are reconciled from the runfiles manifest and
+			// execlog, so they're trustworthy even after a watchman fresh-instance
+			// where cs.Paths is empty.
+			logger.Infof("Cycle changes: %v", changes)
+
+			// For now just rerun the target, beware that RunCommand does not yield until
+			// the subprocess exits.
+			fmt.Fprintf(target.streams.Stdout, "%s Found %d changes, rebuilding %s.\n", color.GreenString("INFO:"), len(changes), target.name())
+
+			cycleScope = ibp.WatchScope_Runfiles
+			cycleChanges = changes
+			// The ibazel protocol only reports that a build completed, the changes are listed in
+			// the changes file instead, see WatchChangesFileEnv.
+		case target.watchSourceChanges && cs.IsFreshInstance:
+			// Source-mode cycles are keyed by cs.Paths, which is unreliable on a
+			// fresh-instance and has no manifest-based reconciliation; signal a
+			// full peer reset.
+			cycleIsReset = true
+		case target.watchSourceChanges:
+			logger.Infof("Cycle source changes: %v", cs.Paths)
+
+			cycleScope = ibp.WatchScope_Sources
+			cycleChanges = make(ibp.SourceInfoMap, len(cs.Paths))
+			for _, changedSource := range cs.Paths {
+				cycleChanges[changedSource] = &ibp.SourceInfo{
+					IsSource: toJsonBoolPtr(true),
+				}
+			}
+		case len(scopeChanges) > 0:
+			// The sources of the target changed although the build detected no
+			// change to its runfiles, such as when its actions were cached.
+			logger.Infof("Cycle scope changes: %v", scopeChanges)
+
+			fmt.Fprintf(target.streams.Stdout, "%s Found %d changes to the sources of %s, restarting it.\n", color.GreenString("INFO:"), len(scopeChanges), target.name())
+
+			cycleIsReset = true
+		default:
+			if target.label == "" {
+				fmt.Fprintf(target.streams.Stdout, "%s Target is up-to-date.\n", color.GreenString("INFO:"))
+			} else {
+				fmt.Fprintf(target.streams.Stdout, "%s %s is up-to-date.\n", color.GreenString("INFO:"), target.label)
+			}
+		}
+	}
+
+	if cycleIsReset || cycleScope != "" {
+		// List the changes for the target before notifying or restarting it.
+		target.cycles++
+		target.changeCount = len(cycleChanges)
+		if cycleIsReset {
+			target.changeCount = len(scopeChanges)
+		}
+		if err := writeChanges(target.changesFile, target.cycles, cycleScope, cycleChanges); err != nil {
+			fmt.Fprintf(target.streams.Stdout, "%s %v\n", color.YellowString("WARNING:"), err)
+		}
+	}
+
+	target.hotReload.completed(incBuildErr == nil, cycleIsReset, cycleScope, cycleChanges)
+
+	restartStarted := time.Now()
+	if cycleIsReset {
+		ctctx, cycleTrace := target.runner.tracer.Start(ctx, "Run.Cycle")
+		defer cycleTrace.End()
+
+		if err := target.incrementalProtocol.CycleReset(ctctx); err != nil {
+			cycleTrace.SetStatus(codes.Error, err.Error())
+			return fmt.Errorf("failed to report cycle reset: %w", err)
+		}
+	} else if cycleScope != "" {
+		ctctx, cycleTrace := target.runner.tracer.Start(ctx, "Run.Cycle")
+		defer cycleTrace.End()
+
+		if err := target.incrementalProtocol.Cycle(ctctx, cycleScope, cycleChanges); err != nil {
+			cycleTrace.SetStatus(codes.Error, err.Error())
+			return fmt.Errorf("failed to report cycle events: %w", err)
+		}
+	}
+	if cycleIsReset || cycleScope != "" {
+		// The protocols let the filesystem settle once the target restarted.
+		timings.restart = time.Since(restartStarted) - timings.settle
+	}
+	target.reportTimings(timings)
+
+	return nil
+}
+
+// reportTimings prints, and pushes to the subscribers of the hot reload
+// service, where the time of the cycle went once it completed.
+func (target *watchTarget) reportTimings(t *cycleTimings) {
+	if !target.timings {
+		return
+	}
+	target.phases.record(target.invocationId, t)
+	t.total = time.Since(t.started)
+
+	fmt.Fprintf(target.streams.Stdout, "%s The cycle of %s took %s: %s.\n", color.GreenString("INFO:"), target.name(), roundTiming(t.total), t)
+	target.hotReload.timed(t)
+}
+
+// close closes the incremental protocol and removes the files of the target.
+func (target *watchTarget) close() {
+	// Close the incremental protocol when complete, no matter the protocol type.
+	if target.incrementalProtocol != nil {
+		target.incrementalProtocol.Close()
+	}
+	// Close the watch protocol on complete, no matter what the status is
+	if target.abazel != nil {
+		target.abazel.Close()
+	}
+	target.hotReload.close()
+	target.output.close()
+	os.Remove(target.startScript)
+	os.Remove(target.changesFile)
+	target.changedetect.Close()
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package run
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestWatchTargetCommands(t *testing.T) {
+	t.Run("keeps the command of a single target", func(t *testing.T) {
+		cmd := []string{"run", "--config=dev", "app:server", "--", "--port=8080"}
+		labels, commands := watchTargetCommands(cmd)
+		if len(labels) != 0 {
+			t.Errorf("Expected no absolute labels, got %v", labels)
+		}
+		if len(commands) != 1 || !slices.Equal(commands[0], cmd) {
+			t.Errorf("Expected the command to be kept, got %v", commands)
+		}
+	})
+
+	t.Run("splits the command per target", func(t *testing.T) {
+		cmd := []string{"run", "//app:server", "--config=dev", "@tools//:worker", "--", "//not:a_target"}
+		labels, commands := watchTargetCommands(cmd)
+		if !slices.Equal(labels, []string{"//app:server", "@tools//:worker"}) {
+			t.Errorf("Expected the two targets, got %v", labels)
+		}
+		if len(commands) != 2 {
+			t.Fatalf("Expected two commands, got %v", commands)
+		}
+		if expected := []string{"run", "//app:server", "--config=dev", "--", "//not:a_target"}; !slices.Equal(commands[0], expected) {
+			t.Errorf("Expected %v, got %v", expected, commands[0])
+		}
+		if expected := []string{"run", "--config=dev", "@tools//:worker", "--", "//not:a_target"}; !slices.Equal(commands[1], expected) {
+			t.Errorf("Expected %v, got %v", expected, commands[1])
+		}
+	})
+}
+
+func TestCreateRunCmd(t *testing.T) {
+	t.Run("passes the variables of the last cycle to the target", func(t *testing.T) {
+		target := &watchTarget{
+			startScript:  "/tmp/aspect-run",
+			changesFile:  "/tmp/aspect-run-changes.json",
+			cycles:       2,
+			changeCount:  5,
+			invocationId: "f00",
+			env:          []watchEnvVar{{name: "BUILD_CYCLE", template: "cycle-{cycle}"}},
+		}
+
+		env := target.createRunCmd(context.Background()).Env
+		for _, expected := range []string{
+			"IBAZEL_NOTIFY_CHANGES=y",
+			"ASPECT_WATCH_CHANGES_FILE=/tmp/aspect-run-changes.json",
+			"ASPECT_WATCH_CYCLE=2",
+			"ASPECT_WATCH_CHANGE_COUNT=5",
+			"ASPECT_WATCH_INVOCATION_ID=f00",
+			"BUILD_CYCLE=cycle-2",
+		} {
+			if !slices.Contains(env, expected) {
+				t.Errorf("Expected %s in the environment, got %v", expected, env)
+			}
+		}
+	})
+}
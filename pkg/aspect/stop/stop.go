@@ -0,0 +1,77 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package stop
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/run/detached"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+)
+
+// Stop represents the aspect stop command.
+type Stop struct {
+	ioutils.Streams
+	root func() (string, error)
+}
+
+// New creates a Stop command stopping the run targets detached in the
+// workspace.
+func New(streams ioutils.Streams) *Stop {
+	return &Stop{
+		Streams: streams,
+		root:    detached.Root,
+	}
+}
+
+// Run stops the run targets started with aspect run --detach, or only those
+// given as arguments, and forgets about the ones that exited.
+func (runner *Stop) Run(ctx context.Context, cmd *cobra.Command, args []string) error {
+	root, err := runner.root()
+	if err != nil {
+		return err
+	}
+	if root == "" {
+		return fmt.Errorf("detached run targets are only tracked inside a workspace")
+	}
+	processes, err := detached.List(root)
+	if err != nil {
+		return err
+	}
+
+	for _, arg := range args {
+		if !slices.ContainsFunc(processes, func(p *detached.Process) bool { return p.Target == arg }) {
+			return fmt.Errorf("no detached run target matches %q", arg)
+		}
+	}
+
+	for _, p := range processes {
+		if len(args) > 0 && !slices.Contains(args, p.Target) {
+			continue
+		}
+		if err := detached.Stop(root, p); err != nil {
+			return err
+		}
+		if p.Running {
+			fmt.Fprintf(runner.Stdout, "Stopped %s\n", p.Target)
+		}
+	}
+	return nil
+}
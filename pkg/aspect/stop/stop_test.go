@@ -0,0 +1,69 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package stop
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/run/detached"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+)
+
+func TestStop(t *testing.T) {
+	start := func(g *WithT, root string, target string) {
+		g.Expect(os.WriteFile(detached.ScriptPath(root, target), []byte("#!/bin/sh\nexec sleep 60\n"), 0755)).To(Succeed())
+		_, err := detached.Start(root, target, os.Environ())
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+
+	t.Run("stops the given run targets", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		root := t.TempDir()
+		start(g, root, "//app:server")
+		start(g, root, "//app:worker")
+
+		var stdout bytes.Buffer
+		runner := New(ioutils.Streams{Stdout: &stdout})
+		runner.root = func() (string, error) { return root, nil }
+		g.Expect(runner.Run(context.Background(), nil, []string{"//app:worker"})).To(Succeed())
+		g.Expect(stdout.String()).To(Equal("Stopped //app:worker\n"))
+
+		processes, err := detached.List(root)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(processes).To(HaveLen(1))
+		g.Expect(processes[0].Target).To(Equal("//app:server"))
+
+		stdout.Reset()
+		g.Expect(runner.Run(context.Background(), nil, nil)).To(Succeed())
+		g.Expect(stdout.String()).To(Equal("Stopped //app:server\n"))
+		g.Expect(detached.List(root)).To(BeEmpty())
+	})
+
+	t.Run("fails on a target that wasn't detached", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		root := t.TempDir()
+
+		runner := New(ioutils.Streams{})
+		runner.root = func() (string, error) { return root, nil }
+		g.Expect(runner.Run(context.Background(), nil, []string{"//app:server"})).
+			To(MatchError(`no detached run target matches "//app:server"`))
+	})
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package summary
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+)
+
+// maxMnemonics and maxTests bound the actions and tests the summary lists.
+const (
+	maxMnemonics = 10
+	maxTests     = 5
+)
+
+// lastEventTimeout bounds how long the summary waits for the build events that
+// are still being streamed once bazel has exited.
+const lastEventTimeout = 10 * time.Second
+
+type testDuration struct {
+	label    string
+	duration time.Duration
+}
+
+// Summary collects what a build did from its build events: the processes that
+// were cache hits, the actions executed for each mnemonic, the critical path
+// and the slowest tests.
+type Summary struct {
+	mu            sync.Mutex
+	metrics       *buildeventstream.BuildMetrics
+	failedActions int
+	tests         []testDuration
+	criticalPath  string
+	done          chan struct{}
+	closeDone     sync.Once
+}
+
+func New() *Summary {
+	return &Summary{
+		done: make(chan struct{}),
+	}
+}
+
+// Callback collects a build event into the summary. It is a bep.CallbackFn.
+func (s *Summary) Callback(event *buildeventstream.BuildEvent, _ int64, _ bep.StreamInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case event.GetBuildMetrics() != nil:
+		s.metrics = event.GetBuildMetrics()
+	case event.GetAction() != nil:
+		if !event.GetAction().GetSuccess() {
+			s.failedActions++
+		}
+	case event.GetTestSummary() != nil:
+		if d := event.GetTestSummary().GetTotalRunDuration(); d != nil {
+			s.tests = append(s.tests, testDuration{
+				label:    event.GetId().GetTestSummary().GetLabel(),
+				duration: d.AsDuration(),
+			})
+		}
+	case event.GetBuildToolLogs() != nil:
+		for _, log := range event.GetBuildToolLogs().GetLog() {
+			if log.GetName() == "critical path" && len(log.GetContents()) > 0 {
+				line, _, _ := strings.Cut(string(log.GetContents()), "\n")
+				s.criticalPath = strings.TrimSpace(strings.TrimPrefix(line, "Critical Path:"))
+			}
+		}
+	}
+
+	if event.GetLastMessage() {
+		s.closeDone.Do(func() { close(s.done) })
+	}
+	return nil
+}
+
+// Print writes the summary to w once the last build event is collected, or
+// what was collected so far if it doesn't arrive in time.
+func (s *Summary) Print(w io.Writer) error {
+	complete := true
+	select {
+	case <-s.done:
+	case <-time.After(lastEventTimeout):
+		complete = false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if complete {
+		fmt.Fprintln(tw, "Build summary:")
+	} else {
+		fmt.Fprintln(tw, "Build summary (incomplete, the build events were not all received):")
+	}
+
+	actions := s.metrics.GetActionSummary()
+	if hits, total := CacheHits(actions.GetRunnerCount()); total > 0 {
+		fmt.Fprintf(tw, "  Cache hits:\t%d of %d processes (%.1f%%)\n", hits, total, 100*float64(hits)/float64(total))
+	}
+	if s.criticalPath != "" {
+		fmt.Fprintf(tw, "  Critical path:\t%s\n", s.criticalPath)
+	}
+	if actions != nil {
+		fmt.Fprintf(tw, "  Actions:\t%d executed, %d created\n", actions.GetActionsExecuted(), actions.GetActionsCreated())
+	}
+	if s.failedActions > 0 {
+		fmt.Fprintf(tw, "  Failed actions:\t%d\n", s.failedActions)
+	}
+
+	mnemonics := slices.Clone(actions.GetActionData())
+	if len(mnemonics) > 0 {
+		slices.SortStableFunc(mnemonics, func(a, b *buildeventstream.BuildMetrics_ActionSummary_ActionData) int {
+			return cmp.Or(
+				cmp.Compare(b.GetActionsExecuted(), a.GetActionsExecuted()),
+				cmp.Compare(a.GetMnemonic(), b.GetMnemonic()),
+			)
+		})
+		fmt.Fprintln(tw, "  Actions by mnemonic:")
+		for _, data := range mnemonics[:min(len(mnemonics), maxMnemonics)] {
+			fmt.Fprintf(tw, "    %s\t%d\n", data.GetMnemonic(), data.GetActionsExecuted())
+		}
+		if len(mnemonics) > maxMnemonics {
+			fmt.Fprintf(tw, "    ... and %d more\n", len(mnemonics)-maxMnemonics)
+		}
+	}
+
+	tests := slices.Clone(s.tests)
+	if len(tests) > 0 {
+		slices.SortStableFunc(tests, func(a, b testDuration) int {
+			return cmp.Compare(b.duration, a.duration)
+		})
+		fmt.Fprintln(tw, "  Slowest tests:")
+		for _, t := range tests[:min(len(tests), maxTests)] {
+			fmt.Fprintf(tw, "    %s\t%s\n", t.label, t.duration.Round(10*time.Millisecond))
+		}
+	}
+
+	return tw.Flush()
+}
+
+// CacheHits returns the number of processes that were remote or disk cache
+// hits and the total number of processes from the runner counts of a build.
+func CacheHits(runners []*buildeventstream.BuildMetrics_ActionSummary_RunnerCount) (hits int64, total int64) {
+	var sum int64
+	reportedTotal := int64(-1)
+	for _, runner := range runners {
+		switch {
+		case runner.GetName() == "total":
+			reportedTotal = int64(runner.GetCount())
+			continue
+		case strings.HasSuffix(runner.GetName(), "cache hit"):
+			hits += int64(runner.GetCount())
+		}
+		sum += int64(runner.GetCount())
+	}
+	if reportedTotal >= 0 {
+		return hits, reportedTotal
+	}
+	return hits, sum
+}
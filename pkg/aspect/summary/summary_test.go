@@ -0,0 +1,109 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package summary
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+)
+
+func testSummaryEvent(label string, d time.Duration) *buildeventstream.BuildEvent {
+	return &buildeventstream.BuildEvent{
+		Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_TestSummary{
+			TestSummary: &buildeventstream.BuildEventId_TestSummaryId{Label: label},
+		}},
+		Payload: &buildeventstream.BuildEvent_TestSummary{TestSummary: &buildeventstream.TestSummary{
+			TotalRunDuration: durationpb.New(d),
+		}},
+	}
+}
+
+func TestSummary(t *testing.T) {
+	t.Run("summarizes the build events", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		s := New()
+		events := []*buildeventstream.BuildEvent{
+			{Payload: &buildeventstream.BuildEvent_Action{Action: &buildeventstream.ActionExecuted{Success: false}}},
+			testSummaryEvent("//fast:test", time.Second),
+			testSummaryEvent("//slow:test", 42*time.Second),
+			{Payload: &buildeventstream.BuildEvent_BuildMetrics{BuildMetrics: &buildeventstream.BuildMetrics{
+				ActionSummary: &buildeventstream.BuildMetrics_ActionSummary{
+					ActionsCreated:  30,
+					ActionsExecuted: 20,
+					ActionData: []*buildeventstream.BuildMetrics_ActionSummary_ActionData{
+						{Mnemonic: "GoLink", ActionsExecuted: 2},
+						{Mnemonic: "GoCompilePkg", ActionsExecuted: 18},
+					},
+					RunnerCount: []*buildeventstream.BuildMetrics_ActionSummary_RunnerCount{
+						{Name: "total", Count: 20},
+						{Name: "remote cache hit", Count: 12},
+						{Name: "disk cache hit", Count: 3},
+						{Name: "linux-sandbox", Count: 5},
+					},
+				},
+			}}},
+			{
+				Payload: &buildeventstream.BuildEvent_BuildToolLogs{BuildToolLogs: &buildeventstream.BuildToolLogs{
+					Log: []*buildeventstream.File{{
+						Name: "critical path",
+						File: &buildeventstream.File_Contents{Contents: []byte("Critical Path: 12.34s\n  Action ...\n")},
+					}},
+				}},
+				LastMessage: true,
+			},
+		}
+		for _, event := range events {
+			g.Expect(s.Callback(event, 0, bep.StreamInfo{})).To(Succeed())
+		}
+
+		var out strings.Builder
+		g.Expect(s.Print(&out)).To(Succeed())
+		g.Expect(out.String()).To(Equal(`Build summary:
+  Cache hits:      15 of 20 processes (75.0%)
+  Critical path:   12.34s
+  Actions:         20 executed, 30 created
+  Failed actions:  1
+  Actions by mnemonic:
+    GoCompilePkg  18
+    GoLink        2
+  Slowest tests:
+    //slow:test  42s
+    //fast:test  1s
+`))
+	})
+}
+
+func TestCacheHits(t *testing.T) {
+	t.Run("sums the runners when bazel doesn't report the total", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		hits, total := CacheHits([]*buildeventstream.BuildMetrics_ActionSummary_RunnerCount{
+			{Name: "remote cache hit", Count: 3},
+			{Name: "internal", Count: 1},
+		})
+		g.Expect(hits).To(Equal(int64(3)))
+		g.Expect(total).To(Equal(int64(4)))
+	})
+}
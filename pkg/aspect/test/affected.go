@@ -0,0 +1,289 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"google.golang.org/protobuf/proto"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/bazel/query"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/watch"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+)
+
+// AffectedTestsKey is the key of the Aspect CLI config turning off the
+// selection of the tests affected by the changes in aspect test --watch.
+const AffectedTestsKey = "watch_affected_tests"
+
+// AffectedTestsEnabled returns whether aspect test --watch only reruns the
+// tests affected by the changes, which is the default.
+func AffectedTestsEnabled() bool {
+	return !viper.IsSet(AffectedTestsKey) || viper.GetBool(AffectedTestsKey)
+}
+
+// How long to wait for the build events of a bazel test command once bazel
+// has exited.
+var testLabelsTimeout = 5 * time.Second
+
+// testLabels collects the labels of the test targets configured by a bazel
+// test command from its build events.
+type testLabels struct {
+	mu sync.Mutex
+
+	invocationId string
+	finished     chan struct{}
+	labels       []string
+}
+
+// newTestLabels subscribes to the build events of the interceptor.
+func newTestLabels(besInterceptor bep.BESInterceptor) *testLabels {
+	t := &testLabels{}
+	besInterceptor.RegisterSubscriber(t.callback, bep.SubscriberOptions{}, "target_configured", "build_finished")
+	return t
+}
+
+// bazelFlags starts collecting the labels of a new bazel test command, which
+// is given its own invocation id to tell its events apart.
+func (t *testLabels) bazelFlags() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.invocationId = uuid.NewString()
+	t.finished = make(chan struct{})
+	t.labels = nil
+	return []string{"--invocation_id=" + t.invocationId}
+}
+
+func (t *testLabels) callback(event *buildeventstream.BuildEvent, _ int64, stream bep.StreamInfo) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if stream.InvocationId != t.invocationId {
+		return nil
+	}
+
+	switch {
+	case event.GetConfigured() != nil:
+		// The kinds of the test rules end with _test, e.g. "go_test rule".
+		if strings.HasSuffix(event.GetConfigured().GetTargetKind(), "_test rule") {
+			t.labels = append(t.labels, event.GetId().GetTargetConfigured().GetLabel())
+		}
+	case event.GetFinished() != nil:
+		select {
+		case <-t.finished:
+		default:
+			close(t.finished)
+		}
+	}
+	return nil
+}
+
+// wait waits for the build events of the last bazel test command and returns
+// the labels of its test targets.
+func (t *testLabels) wait() ([]string, error) {
+	t.mu.Lock()
+	invocationId, finished := t.invocationId, t.finished
+	t.mu.Unlock()
+
+	select {
+	case <-finished:
+	case <-time.After(testLabelsTimeout):
+		return nil, fmt.Errorf("the build events of invocation %s did not complete within %v", invocationId, testLabelsTimeout)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	labels := slices.Clone(t.labels)
+	slices.Sort(labels)
+	return slices.Compact(labels), nil
+}
+
+// testGraph is the graph of the dependencies of the test targets, cached to
+// select the tests affected by the changes without querying bazel each time.
+type testGraph struct {
+	root  string
+	tests []string
+
+	// The labels of the targets depending directly on each label.
+	rdeps map[string][]string
+
+	// The labels of the source files of the workspace by path, and the
+	// packages of the workspace in the graph.
+	sources  map[string]string
+	packages map[string]bool
+}
+
+// queryTestGraph queries the dependencies of the tests.
+func queryTestGraph(bzl bazel.Bazel, tests []string) (*testGraph, error) {
+	var stdout, stderr bytes.Buffer
+	streams := ioutils.Streams{Stdout: &stdout, Stderr: &stderr}
+
+	expr := fmt.Sprintf("deps(set(%s))", strings.Join(tests, " "))
+	if err := bzl.RunCommand(streams, nil, "query", "--output=proto", "--", expr); err != nil {
+		return nil, fmt.Errorf("failed to query the dependencies of the tests: %w\nstderr:\n%s", err, stderr.String())
+	}
+
+	result := &query.QueryResult{}
+	if err := proto.Unmarshal(stdout.Bytes(), result); err != nil {
+		return nil, fmt.Errorf("failed to parse the dependencies of the tests: %w", err)
+	}
+	return newTestGraph(result, bzl.WorkspaceRoot(), tests), nil
+}
+
+func newTestGraph(result *query.QueryResult, root string, tests []string) *testGraph {
+	g := &testGraph{
+		root:     root,
+		tests:    tests,
+		rdeps:    make(map[string][]string),
+		sources:  make(map[string]string),
+		packages: make(map[string]bool),
+	}
+	for _, target := range result.GetTarget() {
+		switch target.GetType() {
+		case query.Target_RULE:
+			rule := target.GetRule()
+			for _, input := range rule.GetRuleInput() {
+				g.rdeps[input] = append(g.rdeps[input], rule.GetName())
+			}
+		case query.Target_GENERATED_FILE:
+			file := target.GetGeneratedFile()
+			g.rdeps[file.GetGeneratingRule()] = append(g.rdeps[file.GetGeneratingRule()], file.GetName())
+		case query.Target_SOURCE_FILE:
+			label := target.GetSourceFile().GetName()
+			if pkg, name, ok := workspaceLabel(label); ok {
+				g.sources[path.Join(pkg, name)] = label
+				g.packages[pkg] = true
+			}
+		}
+	}
+	return g
+}
+
+// workspaceLabel splits the label of a target of the workspace into its
+// package and name, e.g. //app:src/main.go into app and src/main.go.
+func workspaceLabel(label string) (pkg, name string, ok bool) {
+	for _, prefix := range []string{"@@//", "@//", "//"} {
+		if rest, found := strings.CutPrefix(label, prefix); found {
+			return strings.Cut(rest, ":")
+		}
+	}
+	return "", "", false
+}
+
+// affected returns the tests depending on the changed files, or false when
+// the changes may change the graph itself, such as to the BUILD files or to
+// new files of the packages, which are picked up by their globs.
+func (g *testGraph) affected(paths []string) ([]string, bool) {
+	seen := make(map[string]bool)
+	queue := []string{}
+	for _, p := range paths {
+		if watch.IsBuildDefinition(p) {
+			return nil, false
+		}
+		label, ok := g.sources[p]
+		if !ok {
+			if pkg, found := g.packageOf(p); found && g.packages[pkg] {
+				return nil, false
+			}
+			// The file is not a dependency of the tests.
+			continue
+		}
+		if !seen[label] {
+			seen[label] = true
+			queue = append(queue, label)
+		}
+	}
+
+	for len(queue) > 0 {
+		label := queue[0]
+		queue = queue[1:]
+		for _, rdep := range g.rdeps[label] {
+			if !seen[rdep] {
+				seen[rdep] = true
+				queue = append(queue, rdep)
+			}
+		}
+	}
+
+	affected := []string{}
+	for _, test := range g.tests {
+		if seen[test] {
+			affected = append(affected, test)
+		}
+	}
+	return affected, true
+}
+
+// packageOf returns the package of the workspace containing the file, the
+// closest directory with a BUILD file, or false if no directory up to the
+// workspace root has one.
+func (g *testGraph) packageOf(p string) (string, bool) {
+	for dir := path.Dir(p); ; dir = path.Dir(dir) {
+		for _, build := range []string{"BUILD.bazel", "BUILD"} {
+			if _, err := os.Stat(filepath.Join(g.root, dir, build)); err == nil {
+				if dir == "." {
+					// The root package.
+					return "", true
+				}
+				return dir, true
+			}
+		}
+		if dir == "." || dir == "/" {
+			return "", false
+		}
+	}
+}
+
+// testsCommand returns the bazel test command running the tests in place of
+// its target patterns, so that its length is bound by the number of tests
+// that are run rather than skipped.
+func testsCommand(bazelCmd []string, tests []string) ([]string, error) {
+	// The target patterns after -- are replaced as well.
+	args := bazelCmd[1:]
+	if i := slices.Index(args, "--"); i >= 0 {
+		args = args[:i]
+	}
+	nonFlags, flagArgs, err := bazel.SeparateBazelFlags(bazelCmd[0], args)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := append([]string{bazelCmd[0]}, flagArgs...)
+	for _, arg := range nonFlags {
+		// Keep the flags bazel doesn't declare, such as the build settings.
+		// The negative target patterns can only follow --.
+		if strings.HasPrefix(arg, "-") {
+			cmd = append(cmd, arg)
+		}
+	}
+	cmd = append(cmd, "--")
+	return append(cmd, tests...), nil
+}
@@ -0,0 +1,230 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"google.golang.org/protobuf/proto"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/bazel/query"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspecterrors"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+)
+
+func ruleTarget(name string, inputs ...string) *query.Target {
+	return &query.Target{
+		Type: query.Target_RULE.Enum(),
+		Rule: &query.Rule{Name: proto.String(name), RuleClass: proto.String("rule"), RuleInput: inputs},
+	}
+}
+
+func sourceTarget(name string) *query.Target {
+	return &query.Target{
+		Type:       query.Target_SOURCE_FILE.Enum(),
+		SourceFile: &query.SourceFile{Name: proto.String(name)},
+	}
+}
+
+func generatedTarget(name, rule string) *query.Target {
+	return &query.Target{
+		Type:          query.Target_GENERATED_FILE.Enum(),
+		GeneratedFile: &query.GeneratedFile{Name: proto.String(name), GeneratingRule: proto.String(rule)},
+	}
+}
+
+// testWorkspace creates a workspace with the packages of the graph.
+func testWorkspace(t *testing.T, packages ...string) string {
+	root := t.TempDir()
+	for _, pkg := range packages {
+		if err := os.MkdirAll(filepath.Join(root, pkg), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, pkg, "BUILD.bazel"), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestTestGraph(t *testing.T) {
+	result := &query.QueryResult{Target: []*query.Target{
+		ruleTarget("//lib:lib", "//lib:lib.go", "//lib:gen"),
+		sourceTarget("//lib:lib.go"),
+		ruleTarget("//lib:gen", "//lib:data/schema.json"),
+		sourceTarget("//lib:data/schema.json"),
+		generatedTarget("//lib:gen.go", "//lib:gen"),
+		ruleTarget("//lib:lib_test", "//lib:lib", "//lib:lib_test.go"),
+		sourceTarget("//lib:lib_test.go"),
+		ruleTarget("//app:app_test", "//app:app_test.go", "//lib:gen.go"),
+		sourceTarget("//app:app_test.go"),
+		ruleTarget("//other:other_test", "@@rules_go//go:stdlib"),
+	}}
+	tests := []string{"//app:app_test", "//lib:lib_test", "//other:other_test"}
+	root := testWorkspace(t, "lib", "app", "other", "docs")
+	g := newTestGraph(result, root, tests)
+
+	for _, tc := range []struct {
+		name     string
+		paths    []string
+		affected []string
+		ok       bool
+	}{
+		{"a test source", []string{"app/app_test.go"}, []string{"//app:app_test"}, true},
+		{"a library", []string{"lib/lib.go"}, []string{"//lib:lib_test"}, true},
+		{"the input of a generated file", []string{"lib/data/schema.json"}, []string{"//app:app_test", "//lib:lib_test"}, true},
+		{"a file of another package", []string{"docs/index.md", "README.md"}, []string{}, true},
+		{"a new file of a package", []string{"lib/new.go"}, nil, false},
+		{"a BUILD file", []string{"docs/BUILD.bazel"}, nil, false},
+		{"a bzl file", []string{"tools/defs.bzl"}, nil, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			affected, ok := g.affected(tc.paths)
+			if ok != tc.ok || !slices.Equal(affected, tc.affected) {
+				t.Errorf("Expected %v, %v, got %v, %v", tc.affected, tc.ok, affected, ok)
+			}
+		})
+	}
+}
+
+func TestPackageOf(t *testing.T) {
+	g := &testGraph{root: testWorkspace(t, "lib", "lib/nested/pkg")}
+
+	for _, tc := range []struct {
+		path  string
+		pkg   string
+		found bool
+	}{
+		{"lib/lib.go", "lib", true},
+		{"lib/nested/lib.go", "lib", true},
+		{"lib/nested/pkg/lib.go", "lib/nested/pkg", true},
+		{"docs/index.md", "", false},
+		{"README.md", "", false},
+	} {
+		if pkg, found := g.packageOf(tc.path); pkg != tc.pkg || found != tc.found {
+			t.Errorf("Expected the package of %s to be %q, %v, got %q, %v", tc.path, tc.pkg, tc.found, pkg, found)
+		}
+	}
+
+	// The root package is told apart from the files outside of any package.
+	if err := os.WriteFile(filepath.Join(g.root, "BUILD"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"docs/index.md", "README.md"} {
+		if pkg, found := g.packageOf(p); pkg != "" || !found {
+			t.Errorf("Expected %s to be in the root package, got %q, %v", p, pkg, found)
+		}
+	}
+}
+
+func TestTestsCommand(t *testing.T) {
+	cmd := []string{"test", "//...", "-k", "--", "-//slow/..."}
+	testsCmd, err := testsCommand(cmd, []string{"//a:a_test", "//b:b_test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(testsCmd, []string{"test", "-k", "--", "//a:a_test", "//b:b_test"}) {
+		t.Errorf("Expected the tests to replace the target patterns, got %v", testsCmd)
+	}
+	if !slices.Equal(cmd, []string{"test", "//...", "-k", "--", "-//slow/..."}) {
+		t.Errorf("Expected the command to be kept, got %v", cmd)
+	}
+
+	// The flags of the test command are only known once the CLI initialized
+	// them from bazel.
+	if _, err := testsCommand([]string{"test", "--config=ci", "//..."}, []string{"//a:a_test"}); err == nil {
+		t.Errorf("Expected an error for the flags that can't be told apart from the target patterns")
+	}
+}
+
+func TestTestLabels(t *testing.T) {
+	configured := func(label, kind string) *buildeventstream.BuildEvent {
+		return &buildeventstream.BuildEvent{
+			Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_TargetConfigured{
+				TargetConfigured: &buildeventstream.BuildEventId_TargetConfiguredId{Label: label},
+			}},
+			Payload: &buildeventstream.BuildEvent_Configured{Configured: &buildeventstream.TargetConfigured{TargetKind: kind}},
+		}
+	}
+	finished := &buildeventstream.BuildEvent{
+		Payload: &buildeventstream.BuildEvent_Finished{Finished: &buildeventstream.BuildFinished{}},
+	}
+
+	t.Run("collects the test targets of the invocation", func(t *testing.T) {
+		labels := &testLabels{}
+		id, _ := strings.CutPrefix(labels.bazelFlags()[0], "--invocation_id=")
+		stream := bep.StreamInfo{InvocationId: id}
+
+		for _, event := range []*buildeventstream.BuildEvent{
+			configured("//b:b_test", "go_test rule"),
+			configured("//a:a", "go_library rule"),
+			configured("//a:a_test", "sh_test rule"),
+			configured("//a:a_test", "sh_test rule"),
+		} {
+			labels.callback(event, 0, stream)
+		}
+		labels.callback(configured("//c:c_test", "go_test rule"), 0, bep.StreamInfo{InvocationId: "other"})
+		labels.callback(finished, 0, stream)
+
+		tests, err := labels.wait()
+		if err != nil || !slices.Equal(tests, []string{"//a:a_test", "//b:b_test"}) {
+			t.Errorf("Expected the tests, got %v, %v", tests, err)
+		}
+	})
+
+	t.Run("fails without the end of the build events", func(t *testing.T) {
+		timeout := testLabelsTimeout
+		testLabelsTimeout = 10 * time.Millisecond
+		t.Cleanup(func() { testLabelsTimeout = timeout })
+
+		labels := &testLabels{}
+		labels.bazelFlags()
+		if _, err := labels.wait(); err == nil {
+			t.Errorf("Expected an error")
+		}
+	})
+}
+
+func TestTestsBuilt(t *testing.T) {
+	if !testsBuilt(nil) {
+		t.Errorf("Expected the tests of a successful command to be built")
+	}
+	if !testsBuilt(&aspecterrors.ExitError{ExitCode: 3}) {
+		t.Errorf("Expected the tests of a command with failed tests to be built")
+	}
+	if testsBuilt(&aspecterrors.ExitError{ExitCode: 1}) {
+		t.Errorf("Expected the tests of a failed build not to be built")
+	}
+}
+
+func TestAffectedTestsEnabled(t *testing.T) {
+	t.Cleanup(viper.Reset)
+
+	if !AffectedTestsEnabled() {
+		t.Errorf("Expected the affected tests to be selected by default")
+	}
+	viper.Set(AffectedTestsKey, false)
+	if AffectedTestsEnabled() {
+		t.Errorf("Expected the affected tests not to be selected")
+	}
+}
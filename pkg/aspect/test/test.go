@@ -20,14 +20,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net"
 	"os"
+	"os/exec"
 	"os/signal"
+	"slices"
 	"syscall"
 
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/fetchlogs"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/flaky"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/progress"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/root/flags"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/summary"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/teststatus"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/watch"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspecterrors"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils/linefilter"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
 	logger "github.com/aspect-build/aspect-gazelle/common/logger"
 	"github.com/aspect-build/aspect-gazelle/runner/pkg/watchman"
@@ -52,12 +61,11 @@ func New(streams ioutils.Streams, hstreams ioutils.Streams, bzl bazel.Bazel) *Te
 func (runner *Test) Run(ctx context.Context, cmd *cobra.Command, args []string) (exitErr error) {
 	bazelCmd := []string{"test"}
 	watch, args := flags.RemoveFlag(args, "--watch")
+	summarize, args := flags.RemoveFlag(args, flags.AspectSummaryFlag)
+	ui, args := flags.RemoveFlag(args, flags.AspectUIFlag)
+	detectFlaky, args := flags.RemoveFlag(args, flaky.DetectFlag)
 	bazelCmd = append(bazelCmd, args...)
 
-	if bep.HasBESInterceptor(ctx) {
-		bazelCmd = flags.AddFlagToCommand(bazelCmd, bep.BESInterceptorFromContext(ctx).Args()...)
-	}
-
 	bzlCommandStreams := runner.streams
 	if cmd != nil {
 		hints, err := cmd.Root().PersistentFlags().GetBool(flags.AspectHintsFlagName)
@@ -69,6 +77,58 @@ func (runner *Test) Run(ctx context.Context, cmd *cobra.Command, args []string)
 		}
 	}
 
+	var buildSummary *summary.Summary
+	var renderer *progress.Renderer
+	var testStatus *teststatus.Table
+	var recorder *flaky.Recorder
+	var failureArtifacts *fetchlogs.Collector
+	var labels *testLabels
+	if bep.HasBESInterceptor(ctx) {
+		besInterceptor := bep.BESInterceptorFromContext(ctx)
+		besArgs, err := besInterceptor.Args()
+		if err != nil {
+			return err
+		}
+		bazelCmd = flags.AddFlagToCommand(bazelCmd, besArgs...)
+		if summarize && !watch {
+			buildSummary = summary.New()
+			besInterceptor.RegisterSubscriber(buildSummary.Callback, bep.SubscriberOptions{})
+		}
+		if (detectFlaky || flaky.Recording()) && !watch {
+			recorder = flaky.NewRecorder()
+			besInterceptor.RegisterSubscriber(recorder.Callback, bep.SubscriberOptions{})
+		}
+		if fetchlogs.Enabled() && !watch {
+			failureArtifacts = fetchlogs.NewCollector()
+			besInterceptor.RegisterSubscriber(failureArtifacts.Callback, bep.SubscriberOptions{})
+		}
+		if AffectedTestsEnabled() && watch {
+			labels = newTestLabels(besInterceptor)
+		}
+		// The test status table and the progress are drawn below the output
+		// of bazel.
+		showTestStatus := teststatus.Enabled() && !watch
+		if showTestStatus || ui && !watch {
+			region := progress.NewRegion(bzlCommandStreams.Stderr)
+			bzlCommandStreams.Stderr = region
+			if showTestStatus {
+				testStatus = teststatus.New(region)
+				besInterceptor.RegisterSubscriber(testStatus.Callback, bep.SubscriberOptions{})
+			}
+			if ui && !watch {
+				renderer = progress.New(region)
+				besInterceptor.RegisterSubscriber(renderer.Callback, bep.SubscriberOptions{})
+				bazelCmd = flags.AddFlagToCommand(bazelCmd, progress.BazelFlags...)
+			}
+		}
+	}
+
+	bzlCommandStreams, flushOutput := linefilter.Streams(ctx, bzlCommandStreams)
+	defer flushOutput()
+	if renderer != nil {
+		bzlCommandStreams.Stderr = renderer.Stderr(bzlCommandStreams.Stderr)
+	}
+
 	var err error
 	if watch {
 		// TODO: reduce duplication with build/run--watch
@@ -89,9 +149,30 @@ func (runner *Test) Run(ctx context.Context, cmd *cobra.Command, args []string)
 			cancel()
 		}()
 
-		err = runner.testWatch(watchCtx, bazelCmd, bzlCommandStreams)
+		err = runner.testWatch(watchCtx, bazelCmd, bzlCommandStreams, ui, labels)
 	} else {
 		err = runner.bzl.RunCommand(bzlCommandStreams, nil, bazelCmd...)
+		if renderer != nil {
+			renderer.Finish()
+		}
+		if testStatus != nil {
+			testStatus.Finish(runner.streams.Stderr)
+		}
+		if buildSummary != nil {
+			if printErr := buildSummary.Print(runner.streams.Stderr); printErr != nil && err == nil {
+				err = printErr
+			}
+		}
+		if recorder != nil {
+			if recordErr := runner.recordTests(recorder, detectFlaky); recordErr != nil && err == nil {
+				err = recordErr
+			}
+		}
+		if failureArtifacts != nil {
+			if fetchErr := failureArtifacts.Fetch(ctx, runner.streams.Stderr); fetchErr != nil {
+				fmt.Fprintf(runner.streams.Stderr, "%s failed to fetch the failure artifacts: %v\n", color.YellowString("WARNING:"), fetchErr)
+			}
+		}
 	}
 
 	// Check for subscriber errors
@@ -108,11 +189,94 @@ func (runner *Test) Run(ctx context.Context, cmd *cobra.Command, args []string)
 	return err
 }
 
-func (runner *Test) testWatch(ctx context.Context, bazelCmd []string, streams ioutils.Streams) error {
+// recordTests appends the results of the tests to the test history of the
+// workspace and, if report is set, prints those of the tests that have been
+// flaky in it.
+func (runner *Test) recordTests(recorder *flaky.Recorder, report bool) error {
+	path, err := flaky.HistoryPath(runner.bzl.WorkspaceRoot())
+	if err != nil {
+		return err
+	}
+	if err := flaky.AppendHistory(path, recorder.Finish()); err != nil {
+		return err
+	}
+	if !report {
+		return nil
+	}
+
+	history, err := flaky.LoadHistory(path)
+	if err != nil {
+		return err
+	}
+	labels := recorder.Labels()
+	var tests []flaky.Test
+	for _, t := range flaky.Detect(history) {
+		if slices.Contains(labels, t.Label) {
+			tests = append(tests, t)
+		}
+	}
+	return flaky.Print(runner.streams.Stderr, tests)
+}
+
+// testGraph queries the graph of the dependencies of the tests of the last
+// bazel test command, to only rerun the tests affected by the next changes. It
+// returns nil to rerun all the tests, such as when the tests were not built.
+func (runner *Test) testGraph(labels *testLabels, streams ioutils.Streams, testErr error) *testGraph {
+	if labels == nil || !testsBuilt(testErr) {
+		return nil
+	}
+	tests, err := labels.wait()
+	if err == nil && len(tests) == 0 {
+		return nil
+	}
+	if err == nil {
+		var graph *testGraph
+		if graph, err = queryTestGraph(runner.bzl, tests); err == nil {
+			return graph
+		}
+	}
+	fmt.Fprintf(streams.Stdout, "%s %v, rerunning all the tests on changes.\n", color.YellowString("WARNING:"), err)
+	return nil
+}
+
+// testsBuilt returns whether the bazel test command built and ran the tests,
+// whether they passed or failed.
+func testsBuilt(err error) bool {
+	// The exit code of bazel when the build succeeded but some tests failed.
+	const testsFailed = 3
+
+	var exitErr *aspecterrors.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode == testsFailed
+	}
+	var cmdErr *exec.ExitError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.ExitCode() == testsFailed
+	}
+	return err == nil
+}
+
+func (runner *Test) testWatch(ctx context.Context, bazelCmd []string, streams ioutils.Streams, ui bool, labels *testLabels) error {
 	// TODO: reduce duplication with build/run--watch
 
+	ignore, err := watch.LoadIgnore()
+	if err != nil {
+		return err
+	}
+
+	var notifier *watch.Notifier
+	notify, bazelCmd := flags.RemoveFlag(bazelCmd, watch.NotifyFlag)
+	if watch.NotifyEnabled(notify) {
+		notifier = watch.NewNotifier("aspect test --watch")
+	}
+
+	poll, bazelCmd, err := watch.RemovePollFlag(bazelCmd)
+	if err != nil {
+		return err
+	}
+
 	// Start the workspace watcher
-	w := watchman.NewWatchman(runner.bzl.WorkspaceRoot())
+	w := watch.New(runner.bzl.WorkspaceRoot(), poll)
 	if err := w.Start(); err != nil {
 		return fmt.Errorf("failed to start the watcher: %w", err)
 	}
@@ -126,41 +290,99 @@ func (runner *Test) testWatch(ctx context.Context, bazelCmd []string, streams io
 		w.Close()
 	}()
 
-	err := runner.bzl.RunCommand(streams, nil, bazelCmd...)
+	// Show the cycles in the dashboard rather than interleaving their output.
+	var dashboard *watch.Dashboard
+	if ui {
+		dashboard, err = watch.NewDashboard("aspect test --watch")
+		if err != nil {
+			fmt.Fprintf(runner.streams.Stderr, "%s %v, showing the output instead.\n", color.YellowString("WARNING:"), err)
+		} else {
+			defer dashboard.Close()
+			streams = dashboard.Streams()
+		}
+	}
+
+	initialCmd := bazelCmd
+	if labels != nil {
+		initialCmd = flags.AddFlagToCommand(initialCmd, labels.bazelFlags()...)
+	}
+	err = runner.bzl.RunCommand(streams, nil, initialCmd...)
+	dashboard.Result(err)
 	if err != nil {
-		fmt.Printf("Initial Build Failed: %v", err)
+		fmt.Fprintf(streams.Stdout, "Initial Build Failed: %v", err)
 	}
 
-	watchState := fmt.Sprintf("aspect-test-watch-%d", os.Getpid())
+	// The dependencies of the tests, to only rerun those affected by the changes.
+	graph := runner.testGraph(labels, streams, err)
 
-	for cs, err := range w.Subscribe(ctx, watchman.DeferState{DeferWithinState: watchState}) {
-		if err != nil {
-			// Break the subscribe iteration if the context is done or if the watcher is closed.
-			if errors.Is(err, context.Canceled) || errors.Is(err, net.ErrClosed) {
-				break
-			}
+	// When turned on in the config, let the changes that arrive while bazel
+	// builds preempt the build so that the next one starts from the latest
+	// sources.
+	preempt := watch.PreemptEnabled()
+	preemptible := preempt && watch.SupportsPreemptible(runner.bzl, "test")
+	if preemptible {
+		bazelCmd = flags.AddFlagToCommand(bazelCmd, watch.PreemptibleFlag)
+	}
 
-			return fmt.Errorf("failed to get next event: %w", err)
-		}
+	// Read the keybindings, such as to rebuild or to quit, in the terminal.
+	keys, stopKeys := watch.ReadKeys()
+	defer stopKeys()
 
-		// Enter into the build state to discard spurious changes caused by Bazel reading the
-		// inputs which leads to their atime to change.
-		if err := w.StateEnter(watchState); err != nil {
-			return fmt.Errorf("failed to enter build state: %w", err)
-		}
+	opts := watch.CycleOptions{
+		State:     fmt.Sprintf("aspect-test-watch-%d", os.Getpid()),
+		Ignore:    ignore,
+		Preempt:   preempt,
+		Keys:      keys,
+		Dashboard: dashboard,
+		Notifier:  notifier,
+		Tests:     true,
+	}
 
+	return watch.Cycles(ctx, w, opts, func(ctx context.Context, cs *watchman.ChangeSet) error {
 		logger.Debugf("watchman detected changes: %v", cs.Paths)
 
-		err := runner.bzl.RunCommand(streams, nil, bazelCmd...)
-		if err != nil {
-			fmt.Printf("Incremental Build Failed: %v", err)
+		cmd := bazelCmd
+		if watch.RerunTests(ctx) {
+			cmd = flags.AddFlagToCommand(cmd, "--nocache_test_results")
 		}
 
-		// Leave the build state and fast forward the subscription clock.
-		if err := w.StateLeave(watchState); err != nil {
-			return fmt.Errorf("failed to leave build state: %w", err)
+		// Skip the tests the changes do not affect, unless all of them are
+		// rerun with a key.
+		all := true
+		if graph != nil && !cs.IsFreshInstance && !watch.Forced(ctx) {
+			if affected, ok := graph.affected(cs.Paths); ok {
+				all = false
+				skipped := slices.DeleteFunc(slices.Clone(graph.tests), func(test string) bool {
+					return slices.Contains(affected, test)
+				})
+				if len(affected) == 0 {
+					fmt.Fprintf(streams.Stdout, "%s No test is affected by the changes, skipping %d tests.\n", color.GreenString("INFO:"), len(skipped))
+					return nil
+				}
+				if testsCmd, err := testsCommand(cmd, affected); err != nil {
+					fmt.Fprintf(streams.Stderr, "%s Failed to select the affected tests, running all of them: %v\n", color.YellowString("WARNING:"), err)
+					all = true
+				} else {
+					fmt.Fprintf(streams.Stdout, "%s Running %d affected tests, skipping %d.\n", color.GreenString("INFO:"), len(affected), len(skipped))
+					cmd = testsCmd
+				}
+			}
+		}
+		if all && labels != nil {
+			cmd = flags.AddFlagToCommand(cmd, labels.bazelFlags()...)
 		}
-	}
 
-	return nil
+		err := watch.RunBazel(ctx, runner.bzl, streams, preemptible, cmd...)
+		if ctx.Err() == nil {
+			dashboard.Result(err)
+			notifier.Result(err)
+			if err != nil {
+				fmt.Fprintf(streams.Stdout, "Incremental Build Failed: %v", err)
+			}
+			if all {
+				graph = runner.testGraph(labels, streams, err)
+			}
+		}
+		return nil
+	})
 }
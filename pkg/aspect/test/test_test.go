@@ -48,7 +48,7 @@ func TestTest(t *testing.T) {
 		besBackend.
 			EXPECT().
 			Args().
-			Return([]string{"--bes_backend=grpc://127.0.0.1:12345"}).
+			Return([]string{"--bes_backend=grpc://127.0.0.1:12345"}, nil).
 			Times(1)
 		besBackend.
 			EXPECT().
@@ -0,0 +1,284 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package teststatus
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/progress"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+)
+
+// Key is the key of the Aspect CLI config that turns the test status table of
+// aspect test on or off. It is on by default when stderr is a terminal.
+const Key = "test_status"
+
+// maxRows bounds the tests the table lists while they run, and maxLogLines
+// the lines of the log of a test that failed printed at the end.
+const (
+	maxRows     = 10
+	maxLogLines = 20
+	maxLogSize  = 1024 * 1024
+)
+
+// lastEventTimeout bounds how long the table waits for the build events that
+// are still being streamed once bazel has exited.
+const lastEventTimeout = 10 * time.Second
+
+// Enabled returns true if the test status table is drawn.
+func Enabled() bool {
+	if viper.IsSet(Key) {
+		return viper.GetBool(Key)
+	}
+	return term.IsTerminal(int(os.Stderr.Fd()))
+}
+
+type test struct {
+	label string
+	// status is the overall status of the test once its summary is
+	// received, done.
+	status   buildeventstream.TestStatus
+	done     bool
+	attempts int
+	duration time.Duration
+	// logs are the logs of the attempts that didn't pass.
+	logs []*buildeventstream.File
+}
+
+// Table draws the status of the tests of a build from its build events while
+// they run: whether they passed, failed or were flaky, and how long they took.
+// Once the build completes, it prints the logs of those that failed.
+type Table struct {
+	mu      sync.Mutex
+	section *progress.Section
+	tests   []*test
+	byLabel map[string]*test
+	// streaming is set once the build events are received, and finished
+	// once the table stopped drawing them.
+	streaming bool
+	finished  bool
+	done      chan struct{}
+	closeDone sync.Once
+}
+
+// New creates a Table that is drawn in a section of region.
+func New(region *progress.Region) *Table {
+	return &Table{
+		section: region.Section(),
+		byLabel: make(map[string]*test),
+		done:    make(chan struct{}),
+	}
+}
+
+// Callback collects the status of a test from a build event. It is a
+// bep.CallbackFn.
+func (t *Table) Callback(event *buildeventstream.BuildEvent, _ int64, _ bep.StreamInfo) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.finished {
+		return nil
+	}
+	t.streaming = true
+
+	changed := true
+	switch {
+	case event.GetTestResult() != nil:
+		result := event.GetTestResult()
+		tt := t.test(event.GetId().GetTestResult().GetLabel())
+		tt.attempts++
+		if !tt.done {
+			tt.duration += result.GetTestAttemptDuration().AsDuration()
+		}
+		if result.GetStatus() != buildeventstream.TestStatus_PASSED {
+			for _, f := range result.GetTestActionOutput() {
+				if f.GetName() == "test.log" {
+					tt.logs = append(tt.logs, f)
+				}
+			}
+		}
+	case event.GetTestSummary() != nil:
+		summary := event.GetTestSummary()
+		tt := t.test(event.GetId().GetTestSummary().GetLabel())
+		tt.status = summary.GetOverallStatus()
+		tt.done = true
+		if d := summary.GetTotalRunDuration(); d != nil {
+			tt.duration = d.AsDuration()
+		}
+	default:
+		changed = false
+	}
+
+	if event.GetLastMessage() {
+		t.closeDone.Do(func() { close(t.done) })
+	}
+	if changed {
+		t.section.Set(t.lines())
+	}
+	return nil
+}
+
+// test returns the test of label, which is added to the table the first time.
+// t.mu must be held.
+func (t *Table) test(label string) *test {
+	tt, ok := t.byLabel[label]
+	if !ok {
+		tt = &test{label: label}
+		t.byLabel[label] = tt
+		t.tests = append(t.tests, tt)
+	}
+	return tt
+}
+
+// Finish waits for the last build event, or what was received so far if it
+// doesn't arrive in time, erases the table and prints the logs of the tests
+// that failed or were flaky to w.
+func (t *Table) Finish(w io.Writer) {
+	t.mu.Lock()
+	streaming := t.streaming
+	t.mu.Unlock()
+	if streaming {
+		select {
+		case <-t.done:
+		case <-time.After(lastEventTimeout):
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.finished = true
+	t.section.Set(nil)
+
+	for _, tt := range t.tests {
+		if !failed(tt.status) && tt.status != buildeventstream.TestStatus_FLAKY {
+			continue
+		}
+		fmt.Fprintf(w, "%s %s (%s)\n", tt.status, tt.label, describe(tt))
+		for _, f := range tt.logs {
+			log, path := readLog(f)
+			for _, line := range log {
+				fmt.Fprintf(w, "  %s\n", line)
+			}
+			if path != "" {
+				fmt.Fprintf(w, "  See %s\n", path)
+			}
+		}
+	}
+}
+
+// lines returns the lines of the table: the number of tests that passed,
+// failed and were flaky, and the status of the tests, those that failed
+// first. t.mu must be held.
+func (t *Table) lines() []string {
+	var passed, failures, flaky, running int
+	for _, tt := range t.tests {
+		switch {
+		case !tt.done:
+			running++
+		case tt.status == buildeventstream.TestStatus_PASSED:
+			passed++
+		case tt.status == buildeventstream.TestStatus_FLAKY:
+			flaky++
+		case failed(tt.status):
+			failures++
+		}
+	}
+	lines := []string{fmt.Sprintf("Tests: %d passed, %d failed, %d flaky, %d running", passed, failures, flaky, running)}
+
+	rows := slices.Clone(t.tests)
+	slices.SortStableFunc(rows, func(a, b *test) int {
+		switch af, bf := failed(a.status), failed(b.status); {
+		case af && !bf:
+			return -1
+		case bf && !af:
+			return 1
+		}
+		return 0
+	})
+	shown := rows[:min(len(rows), maxRows)]
+	width := 0
+	for _, tt := range shown {
+		width = max(width, len(tt.label))
+	}
+	for _, tt := range shown {
+		status := "RUNNING"
+		if tt.done {
+			status = tt.status.String()
+		}
+		lines = append(lines, fmt.Sprintf("  %-8s %-*s  %s", status, width, tt.label, describe(tt)))
+	}
+	if len(rows) > maxRows {
+		lines = append(lines, fmt.Sprintf("  ... and %d more tests", len(rows)-maxRows))
+	}
+	return lines
+}
+
+// describe returns how long a test took, and how many times it ran when it
+// ran more than once.
+func describe(tt *test) string {
+	s := tt.duration.Round(10 * time.Millisecond).String()
+	if tt.attempts > 1 {
+		s += fmt.Sprintf(", %d attempts", tt.attempts)
+	}
+	return s
+}
+
+// failed returns true if a test with the given status failed.
+func failed(status buildeventstream.TestStatus) bool {
+	switch status {
+	case buildeventstream.TestStatus_FAILED,
+		buildeventstream.TestStatus_TIMEOUT,
+		buildeventstream.TestStatus_INCOMPLETE,
+		buildeventstream.TestStatus_REMOTE_FAILURE,
+		buildeventstream.TestStatus_FAILED_TO_BUILD:
+		return true
+	}
+	return false
+}
+
+// readLog returns the last lines of the log of a test, if it is inlined in
+// the build event or written to a local file, and where the log is.
+func readLog(f *buildeventstream.File) ([]string, string) {
+	path := f.GetUri()
+	contents := f.GetContents()
+	if u, err := url.Parse(path); err == nil && u.Scheme == "file" {
+		path = u.Path
+		if contents == nil {
+			if file, err := os.Open(u.Path); err == nil {
+				if info, err := file.Stat(); err == nil && info.Size() > maxLogSize {
+					file.Seek(-maxLogSize, io.SeekEnd)
+				}
+				contents, _ = io.ReadAll(file)
+				file.Close()
+			}
+		}
+	}
+	lines := strings.Split(strings.TrimRight(string(contents), "\n"), "\n")
+	if len(contents) == 0 {
+		lines = nil
+	}
+	return lines[max(0, len(lines)-maxLogLines):], path
+}
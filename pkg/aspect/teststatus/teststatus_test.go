@@ -0,0 +1,139 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package teststatus
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/progress"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+)
+
+func testResultEvent(label string, status buildeventstream.TestStatus, d time.Duration, outputs ...*buildeventstream.File) *buildeventstream.BuildEvent {
+	return &buildeventstream.BuildEvent{
+		Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_TestResult{
+			TestResult: &buildeventstream.BuildEventId_TestResultId{Label: label},
+		}},
+		Payload: &buildeventstream.BuildEvent_TestResult{TestResult: &buildeventstream.TestResult{
+			Status:              status,
+			TestAttemptDuration: durationpb.New(d),
+			TestActionOutput:    outputs,
+		}},
+	}
+}
+
+func testSummaryEvent(label string, status buildeventstream.TestStatus, d time.Duration) *buildeventstream.BuildEvent {
+	return &buildeventstream.BuildEvent{
+		Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_TestSummary{
+			TestSummary: &buildeventstream.BuildEventId_TestSummaryId{Label: label},
+		}},
+		Payload: &buildeventstream.BuildEvent_TestSummary{TestSummary: &buildeventstream.TestSummary{
+			OverallStatus:    status,
+			TotalRunDuration: durationpb.New(d),
+		}},
+	}
+}
+
+func TestTable(t *testing.T) {
+	t.Run("lists the status of the tests, those that failed first", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		table := New(progress.NewRegion(&bytes.Buffer{}))
+		for _, event := range []*buildeventstream.BuildEvent{
+			testResultEvent("//a:test", buildeventstream.TestStatus_PASSED, time.Second),
+			testSummaryEvent("//a:test", buildeventstream.TestStatus_PASSED, time.Second),
+			testResultEvent("//flaky:test", buildeventstream.TestStatus_FAILED, time.Second),
+			testResultEvent("//flaky:test", buildeventstream.TestStatus_PASSED, 2*time.Second),
+			testSummaryEvent("//flaky:test", buildeventstream.TestStatus_FLAKY, 3*time.Second),
+			testResultEvent("//sharded:test", buildeventstream.TestStatus_PASSED, time.Second),
+			testResultEvent("//b:test", buildeventstream.TestStatus_FAILED, 1500*time.Millisecond),
+			testSummaryEvent("//b:test", buildeventstream.TestStatus_FAILED, 1500*time.Millisecond),
+		} {
+			g.Expect(table.Callback(event, 0, bep.StreamInfo{})).To(Succeed())
+		}
+
+		g.Expect(table.lines()).To(Equal([]string{
+			"Tests: 1 passed, 1 failed, 1 flaky, 1 running",
+			"  FAILED   //b:test        1.5s",
+			"  PASSED   //a:test        1s",
+			"  FLAKY    //flaky:test    3s, 2 attempts",
+			"  RUNNING  //sharded:test  1s",
+		}))
+	})
+
+	t.Run("bounds the tests listed", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		table := New(progress.NewRegion(&bytes.Buffer{}))
+		for i := range maxRows + 2 {
+			table.Callback(testSummaryEvent("//:test"+strings.Repeat("_", i), buildeventstream.TestStatus_PASSED, time.Second), 0, bep.StreamInfo{})
+		}
+
+		lines := table.lines()
+		g.Expect(lines).To(HaveLen(1 + maxRows + 1))
+		g.Expect(lines[len(lines)-1]).To(Equal("  ... and 2 more tests"))
+	})
+
+	t.Run("prints the logs of the tests that failed once the build completes", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		log := filepath.Join(t.TempDir(), "test.log")
+		var contents strings.Builder
+		for i := range maxLogLines + 5 {
+			contents.WriteString(strings.Repeat("x", i) + "\n")
+		}
+		g.Expect(os.WriteFile(log, []byte(contents.String()), 0o644)).To(Succeed())
+
+		var out bytes.Buffer
+		table := New(progress.NewRegion(&out))
+		table.Callback(testResultEvent("//a:test", buildeventstream.TestStatus_PASSED, time.Second), 0, bep.StreamInfo{})
+		table.Callback(testSummaryEvent("//a:test", buildeventstream.TestStatus_PASSED, time.Second), 0, bep.StreamInfo{})
+		table.Callback(testResultEvent("//b:test", buildeventstream.TestStatus_FAILED, time.Second,
+			&buildeventstream.File{Name: "test.xml", File: &buildeventstream.File_Uri{Uri: "file:///test.xml"}},
+			&buildeventstream.File{Name: "test.log", File: &buildeventstream.File_Uri{Uri: "file://" + log}},
+		), 0, bep.StreamInfo{})
+		last := testSummaryEvent("//b:test", buildeventstream.TestStatus_FAILED, time.Second)
+		last.LastMessage = true
+		table.Callback(last, 0, bep.StreamInfo{})
+
+		table.Finish(&out)
+
+		lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+		g.Expect(lines).To(HaveLen(1 + maxLogLines + 1))
+		g.Expect(lines[0]).To(Equal("FAILED //b:test (1s)"))
+		g.Expect(lines[1]).To(Equal("  " + strings.Repeat("x", 5)))
+		g.Expect(lines[len(lines)-1]).To(Equal("  See " + log))
+	})
+
+	t.Run("doesn't wait when no build events were received", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var out bytes.Buffer
+		table := New(progress.NewRegion(&out))
+		table.Finish(&out)
+
+		g.Expect(out.String()).To(BeEmpty())
+	})
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"context"
+	"os"
+	"path"
+	"time"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+)
+
+// PreemptibleFlag lets the next bazel command preempt the one it is set on
+// instead of waiting for it to complete.
+const PreemptibleFlag = "--preemptible"
+
+// SupportsPreemptible returns true if the bazel command accepts --preemptible.
+func SupportsPreemptible(bzl bazel.Bazel, command string) bool {
+	ok, err := bzl.IsBazelFlag(command, "preemptible")
+	return err == nil && ok
+}
+
+// preemptedGrace is how long the client of a preemptible command is given to
+// exit on its own once its cycle is preempted before it is killed.
+const preemptedGrace = 500 * time.Millisecond
+
+// RunBazel runs the bazel command of a cycle until it completes or ctx is done.
+//
+// When ctx is done, the client of a preemptible command is killed unless it
+// exits shortly, which leaves the command to be preempted by the next one on
+// the bazel server, while older bazels are interrupted, which cancels the
+// command. RunBazel waits for the client to exit, so that its output doesn't
+// mix with the one of the next command, and returns the error of ctx.
+func RunBazel(ctx context.Context, bzl bazel.Bazel, streams ioutils.Streams, preemptible bool, args ...string) error {
+	cmd, err := bzl.MakeBazelCommand(context.Background(), args, streams, nil, nil)
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	if preemptible {
+		select {
+		case <-done:
+			return ctx.Err()
+		case <-time.After(preemptedGrace):
+		}
+		cmd.Process.Kill()
+	} else if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		cmd.Process.Kill()
+	}
+	<-done
+	return ctx.Err()
+}
+
+// IsBuildDefinition returns whether the file defines the targets of the
+// workspace rather than being a source of them.
+func IsBuildDefinition(p string) bool {
+	switch path.Base(p) {
+	case "BUILD", "BUILD.bazel", "MODULE.bazel", "WORKSPACE", "WORKSPACE.bazel", "WORKSPACE.bzlmod", ".bazelrc", ".bazelversion":
+		return true
+	}
+	return path.Ext(p) == ".bzl"
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	bazel_mock "github.com/aspect-build/aspect-cli-legacy/pkg/bazel/mock"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/golang/mock/gomock"
+)
+
+func TestRunBazel(t *testing.T) {
+	// runPreempted runs a bazel client with the given script and returns it
+	// once RunBazel returned for its preempted cycle.
+	runPreempted := func(t *testing.T, preemptible bool, script string) *exec.Cmd {
+		ctrl := gomock.NewController(t)
+		bzl := bazel_mock.NewMockBazel(ctrl)
+		cmd := exec.Command("sh", "-c", script)
+		started := make(chan struct{})
+		cmd.Stdout = writerFunc(func(p []byte) (int, error) {
+			close(started)
+			return len(p), nil
+		})
+		bzl.
+			EXPECT().
+			MakeBazelCommand(gomock.Any(), []string{"build"}, gomock.Any(), nil, nil).
+			Return(cmd, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		result := make(chan error, 1)
+		go func() {
+			result <- RunBazel(ctx, bzl, ioutils.Streams{}, preemptible, "build")
+		}()
+		<-started
+		cancel()
+
+		select {
+		case err := <-result:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected the error of the context, got %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected RunBazel to return within 5s")
+		}
+		return cmd
+	}
+
+	t.Run("kills the client of a preemptible command that doesn't exit", func(t *testing.T) {
+		cmd := runPreempted(t, true, "trap '' INT; echo started; exec sleep 10")
+		if cmd.ProcessState == nil {
+			t.Errorf("Expected the client to have exited")
+		}
+	})
+
+	t.Run("interrupts the client of a command that isn't preemptible", func(t *testing.T) {
+		cmd := runPreempted(t, false, "echo started; exec sleep 10")
+		if cmd.ProcessState == nil {
+			t.Errorf("Expected the client to have exited")
+		}
+	})
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
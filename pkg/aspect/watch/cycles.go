@@ -0,0 +1,313 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	logger "github.com/aspect-build/aspect-gazelle/common/logger"
+	"github.com/aspect-build/aspect-gazelle/runner/pkg/watchman"
+	"github.com/fatih/color"
+	"github.com/spf13/viper"
+)
+
+// PreemptKey is the key of the Aspect CLI config that turns on the preemption
+// of the cycles of --watch by newer changes when set to true.
+const PreemptKey = "watch_preempt"
+
+// PreemptEnabled returns true if the preemption of the cycles is turned on in
+// the Aspect CLI config.
+func PreemptEnabled() bool {
+	return viper.GetBool(PreemptKey)
+}
+
+// CycleFunc runs the cycle of a change set. Its context is cancelled when the
+// cycle is preempted or when the session is quit.
+type CycleFunc func(ctx context.Context, cs *watchman.ChangeSet) error
+
+// CycleOptions are the options of Cycles.
+type CycleOptions struct {
+	// State is the watchman state the cycles run in, see watchman.DeferState.
+	State string
+
+	// Ignore are the patterns of the changes that don't trigger a cycle.
+	Ignore Ignore
+
+	// Preempt cancels the cycle that is running when another change arrives,
+	// so that the next cycle starts from the latest state of the sources.
+	Preempt bool
+
+	// Keys are the keys pressed in the terminal, see ReadKeys.
+	Keys <-chan rune
+
+	// Tests binds KeyTests to rerun the tests.
+	Tests bool
+
+	// Dashboard shows the cycles, and their messages, if not nil.
+	Dashboard *Dashboard
+
+	// Notifier notifies the results of the cycles, if not nil.
+	Notifier *Notifier
+}
+
+// Cycles runs a cycle for each change set of the watcher until ctx is done,
+// the watcher is closed or KeyQuit is pressed.
+//
+// Without Preempt, the changes made while a cycle runs are deferred until it
+// completes. With Preempt, they cancel the context of the running cycle, and
+// the next cycle gets all the changes that arrived meanwhile. The changes to
+// files that were last modified before the cycle started, such as the access
+// time changing when bazel reads them, are dropped: they neither preempt the
+// cycle nor trigger another one.
+func Cycles(ctx context.Context, w Watcher, opts CycleOptions, cycle CycleFunc) error {
+	type event struct {
+		cs  *watchman.ChangeSet
+		err error
+	}
+
+	subscribeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Without preemption, let watchman hold the changes made while a cycle runs.
+	var subscribeOpts []watchman.SubscribeOptions
+	if !opts.Preempt {
+		subscribeOpts = append(subscribeOpts, watchman.DeferState{DeferWithinState: opts.State})
+	}
+
+	// Pull the change sets in the background so that the keys are read, and
+	// the changes arrive, while a cycle runs.
+	events := make(chan event)
+	go func() {
+		defer close(events)
+		for cs, err := range w.Subscribe(subscribeCtx, subscribeOpts...) {
+			select {
+			case events <- event{cs, err}:
+			case <-subscribeCtx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	// receive returns the change set of an event, or nil once the subscription
+	// ended.
+	receive := func(e event, ok bool) (*watchman.ChangeSet, error) {
+		if !ok {
+			return nil, nil
+		}
+		if e.err != nil {
+			// Stop if the context is done or if the watcher is closed.
+			if errors.Is(e.err, context.Canceled) || errors.Is(e.err, net.ErrClosed) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to get next event: %w", e.err)
+		}
+		return e.cs, nil
+	}
+
+	// The messages of the cycles are shown in the dashboard, if any.
+	var out io.Writer = os.Stdout
+	if opts.Dashboard != nil {
+		out = opts.Dashboard
+	}
+
+	if opts.Keys != nil {
+		if opts.Dashboard != nil {
+			opts.Dashboard.setKeys(keysHelp(opts.Tests))
+		} else {
+			fmt.Fprintf(out, "%s Press %s.\n", color.GreenString("INFO:"), keysHelp(opts.Tests))
+		}
+	}
+
+	var (
+		// The changes, and the key, of the next cycle.
+		pending    *watchman.ChangeSet
+		pendingKey rune
+		paused     bool
+
+		// The cycle that is running, if any.
+		running *runningCycle
+	)
+
+	// stop cancels the running cycle and waits for it to return.
+	stop := func() {
+		if running != nil {
+			running.cancel()
+			<-running.done
+		}
+	}
+
+	// preempt cancels the running cycle if it can be preempted.
+	preempt := func(reason string) {
+		if running != nil && opts.Preempt && running.ctx.Err() == nil {
+			fmt.Fprintf(out, "%s %s\n", color.GreenString("INFO:"), reason)
+			running.cancel()
+		}
+	}
+
+	for {
+		// Start the next cycle once the previous one completed, unless watching
+		// is paused and it wasn't requested with a key.
+		if running == nil && pending != nil && (!paused || pendingKey != 0) {
+			// Enter into the build state to discard spurious changes caused by Bazel reading the
+			// inputs which leads to their atime to change.
+			if !opts.Preempt {
+				if err := w.StateEnter(opts.State); err != nil {
+					return fmt.Errorf("failed to enter build state: %w", err)
+				}
+			}
+
+			opts.Dashboard.cycleStarted(pending)
+			opts.Notifier.cycleStarted()
+			running = startCycle(withKey(ctx, pendingKey), pending, cycle)
+			pending, pendingKey = nil, 0
+		}
+
+		// The channel of the running cycle, which blocks while idle.
+		var done chan error
+		if running != nil {
+			done = running.done
+		}
+
+		select {
+		case err := <-done:
+			// Only the cycles that weren't preempted have a result.
+			if running.ctx.Err() == nil {
+				opts.Notifier.Result(err)
+				opts.Notifier.cycleDone()
+			}
+			running.cancel()
+			running = nil
+			opts.Dashboard.cycleDone()
+			if err != nil {
+				return err
+			}
+
+			// Leave the build state and fast forward the subscription clock.
+			if !opts.Preempt {
+				if err := w.StateLeave(opts.State); err != nil {
+					return fmt.Errorf("failed to leave build state: %w", err)
+				}
+			}
+		case e, ok := <-events:
+			cs, err := receive(e, ok)
+			if err != nil || cs == nil {
+				stop()
+				return err
+			}
+			if opts.Ignore.skip(cs) {
+				continue
+			}
+			// Without the build state of watchman, the changes that don't look
+			// newer than the running cycle, such as bazel reading the inputs,
+			// are dropped so that they don't start the cycles over and over.
+			if running != nil && opts.Preempt && !cs.IsFreshInstance {
+				paths := changedSince(cs.Root, cs.Paths, running.started)
+				if len(paths) == 0 {
+					logger.Debugf("dropped the changes older than the cycle: %v", cs.Paths)
+					continue
+				}
+				changed := *cs
+				changed.Paths = paths
+				cs = &changed
+			}
+			if !paused {
+				preempt("Changes detected while building, starting over with the latest sources.")
+			}
+			pending = merge(pending, cs)
+		case key := <-opts.Keys:
+			switch {
+			case key == KeyQuit:
+				stop()
+				return nil
+			case key == KeyPause:
+				paused = !paused
+				opts.Dashboard.setPaused(paused)
+				if paused {
+					fmt.Fprintf(out, "%s Paused watching, press %c to resume.\n", color.GreenString("INFO:"), KeyPause)
+				} else {
+					fmt.Fprintf(out, "%s Resumed watching.\n", color.GreenString("INFO:"))
+				}
+			case key == KeyRebuild || key == KeyTests && opts.Tests:
+				if pendingKey != KeyTests {
+					pendingKey = key
+				}
+				if pending == nil {
+					pending = &watchman.ChangeSet{Paths: []string{}}
+				}
+				preempt("Starting over as requested.")
+			}
+		}
+	}
+}
+
+// runningCycle is a cycle running in the background.
+type runningCycle struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	started time.Time
+	done    chan error
+}
+
+// startCycle runs the cycle of the change set in the background.
+func startCycle(ctx context.Context, cs *watchman.ChangeSet, cycle CycleFunc) *runningCycle {
+	ctx, cancel := context.WithCancel(ctx)
+	c := &runningCycle{ctx: ctx, cancel: cancel, started: time.Now(), done: make(chan error, 1)}
+	go func() {
+		c.done <- cycle(ctx, cs)
+	}()
+	return c
+}
+
+// changedSince returns the files, relative to root, that were modified,
+// created or removed after t.
+func changedSince(root string, paths []string, t time.Time) []string {
+	var changed []string
+	for _, p := range paths {
+		info, err := os.Lstat(filepath.Join(root, p))
+		if err != nil || !info.ModTime().Before(t) {
+			changed = append(changed, p)
+		}
+	}
+	return changed
+}
+
+// merge returns the change set of the changes of both change sets.
+func merge(a, b *watchman.ChangeSet) *watchman.ChangeSet {
+	if a == nil {
+		return b
+	}
+	merged := *b
+	merged.IsFreshInstance = a.IsFreshInstance || b.IsFreshInstance
+	merged.Paths = append(slices.Clone(a.Paths), b.Paths...)
+	slices.Sort(merged.Paths)
+	merged.Paths = slices.Compact(merged.Paths)
+	if merged.IsFreshInstance {
+		merged.Paths = []string{}
+	}
+	return &merged
+}
@@ -0,0 +1,351 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aspect-build/aspect-gazelle/runner/pkg/watchman"
+)
+
+// fakeWatcher reports the change sets sent to it and records the states it
+// entered.
+type fakeWatcher struct {
+	changes chan *watchman.ChangeSet
+	states  []string
+}
+
+func newFakeWatcher() *fakeWatcher {
+	return &fakeWatcher{changes: make(chan *watchman.ChangeSet)}
+}
+
+func (w *fakeWatcher) Start() error { return nil }
+
+func (w *fakeWatcher) Subscribe(ctx context.Context, _ ...watchman.SubscribeOptions) iter.Seq2[*watchman.ChangeSet, error] {
+	return func(yield func(*watchman.ChangeSet, error) bool) {
+		for {
+			select {
+			case cs, ok := <-w.changes:
+				if !ok || !yield(cs, nil) {
+					return
+				}
+			case <-ctx.Done():
+				yield(nil, ctx.Err())
+				return
+			}
+		}
+	}
+}
+
+func (w *fakeWatcher) StateEnter(name string) error {
+	w.states = append(w.states, "enter "+name)
+	return nil
+}
+
+func (w *fakeWatcher) StateLeave(name string) error {
+	w.states = append(w.states, "leave "+name)
+	return nil
+}
+
+func (w *fakeWatcher) Close() error { return nil }
+
+// cycle is a call of the cycle function.
+type cycle struct {
+	ctx context.Context
+	cs  *watchman.ChangeSet
+	// done completes the cycle.
+	done chan struct{}
+}
+
+// runCycles runs Cycles in the background and returns its cycles, which block
+// until they are done, even when preempted.
+func runCycles(t *testing.T, w Watcher, opts CycleOptions) <-chan cycle {
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := make(chan struct{})
+	cycles := make(chan cycle)
+	result := make(chan error, 1)
+	go func() {
+		result <- Cycles(ctx, w, opts, func(ctx context.Context, cs *watchman.ChangeSet) error {
+			c := cycle{ctx, cs, make(chan struct{})}
+			select {
+			case cycles <- c:
+			case <-stop:
+				return nil
+			}
+			select {
+			case <-c.done:
+			case <-stop:
+			}
+			return nil
+		})
+	}()
+	t.Cleanup(func() {
+		close(stop)
+		cancel()
+		if err := <-result; err != nil {
+			t.Errorf("Expected the cycles to stop without an error, got %v", err)
+		}
+	})
+	return cycles
+}
+
+func nextCycle(t *testing.T, cycles <-chan cycle) cycle {
+	select {
+	case c := <-cycles:
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected a cycle within 5s")
+	}
+	return cycle{}
+}
+
+func TestCycles(t *testing.T) {
+	t.Run("defers the changes made during a cycle without preemption", func(t *testing.T) {
+		w := newFakeWatcher()
+		cycles := runCycles(t, w, CycleOptions{State: "watch-state"})
+
+		w.changes <- &watchman.ChangeSet{Paths: []string{"a.go"}}
+		c := nextCycle(t, cycles)
+		if !slices.Equal(c.cs.Paths, []string{"a.go"}) {
+			t.Errorf("Expected a.go to change, got %v", c.cs.Paths)
+		}
+		close(c.done)
+
+		w.changes <- &watchman.ChangeSet{Paths: []string{"b.go"}}
+		c = nextCycle(t, cycles)
+		if !slices.Equal(w.states, []string{"enter watch-state", "leave watch-state", "enter watch-state"}) {
+			t.Errorf("Expected the cycles to run in the watch state, got %v", w.states)
+		}
+		close(c.done)
+	})
+
+	t.Run("skips the change sets of ignored files", func(t *testing.T) {
+		w := newFakeWatcher()
+		cycles := runCycles(t, w, CycleOptions{Ignore: Ignore{"**/*.swp"}, Preempt: true})
+
+		w.changes <- &watchman.ChangeSet{Paths: []string{"src/.a.go.swp"}}
+		w.changes <- &watchman.ChangeSet{Paths: []string{"src/.a.go.swp", "src/a.go"}}
+		c := nextCycle(t, cycles)
+		if !slices.Equal(c.cs.Paths, []string{"src/a.go"}) {
+			t.Errorf("Expected only src/a.go to change, got %v", c.cs.Paths)
+		}
+		close(c.done)
+	})
+
+	t.Run("preempts the cycle with the newer changes", func(t *testing.T) {
+		dir := t.TempDir()
+		w := newFakeWatcher()
+		cycles := runCycles(t, w, CycleOptions{Preempt: true})
+
+		w.changes <- &watchman.ChangeSet{Root: dir, Paths: []string{"a.go"}}
+		c := nextCycle(t, cycles)
+
+		writeFile(t, path.Join(dir, "b.go"))
+		w.changes <- &watchman.ChangeSet{Root: dir, Paths: []string{"b.go"}}
+		select {
+		case <-c.ctx.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected the cycle to be preempted within 5s")
+		}
+
+		// The changes that arrive until the cycle returns are merged. Sending
+		// another change set makes sure that the loop received the first one.
+		w.changes <- &watchman.ChangeSet{Root: dir, Paths: []string{"c.go", "b.go"}}
+		w.changes <- &watchman.ChangeSet{Root: dir, Paths: []string{"c.go"}}
+		close(c.done)
+		c = nextCycle(t, cycles)
+		if !slices.Equal(c.cs.Paths, []string{"b.go", "c.go"}) {
+			t.Errorf("Expected b.go and c.go to change, got %v", c.cs.Paths)
+		}
+		close(c.done)
+	})
+
+	t.Run("drops the older changes instead of preempting the cycle", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, path.Join(dir, "a.go"))
+		old := time.Now().Add(-time.Hour)
+		if err := os.Chtimes(path.Join(dir, "a.go"), old, old); err != nil {
+			t.Fatal(err)
+		}
+		w := newFakeWatcher()
+		cycles := runCycles(t, w, CycleOptions{Preempt: true})
+
+		w.changes <- &watchman.ChangeSet{Root: dir, Paths: []string{"a.go"}}
+		c := nextCycle(t, cycles)
+
+		// Bazel reading a.go changes its access time only. Sending other
+		// change sets makes sure that the loop handled the first one.
+		w.changes <- &watchman.ChangeSet{Root: dir, Paths: []string{"a.go"}}
+		w.changes <- &watchman.ChangeSet{Root: dir, Paths: []string{}}
+		w.changes <- &watchman.ChangeSet{Root: dir, Paths: []string{}}
+		if c.ctx.Err() != nil {
+			t.Errorf("Expected the cycle not to be preempted")
+		}
+		close(c.done)
+
+		// The access time changes don't trigger another cycle.
+		select {
+		case c := <-cycles:
+			t.Fatalf("Expected no cycle for the older changes, got one for %v", c.cs.Paths)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("only keeps the newer changes of a change set", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, path.Join(dir, "a.go"))
+		old := time.Now().Add(-time.Hour)
+		if err := os.Chtimes(path.Join(dir, "a.go"), old, old); err != nil {
+			t.Fatal(err)
+		}
+		w := newFakeWatcher()
+		cycles := runCycles(t, w, CycleOptions{Preempt: true})
+
+		w.changes <- &watchman.ChangeSet{Root: dir, Paths: []string{"a.go"}}
+		c := nextCycle(t, cycles)
+
+		writeFile(t, path.Join(dir, "b.go"))
+		w.changes <- &watchman.ChangeSet{Root: dir, Paths: []string{"a.go", "b.go"}}
+		select {
+		case <-c.ctx.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected the cycle to be preempted within 5s")
+		}
+		close(c.done)
+
+		c = nextCycle(t, cycles)
+		if !slices.Equal(c.cs.Paths, []string{"b.go"}) {
+			t.Errorf("Expected only b.go to change, got %v", c.cs.Paths)
+		}
+		close(c.done)
+	})
+
+	t.Run("starts the cycles requested with the keys", func(t *testing.T) {
+		w := newFakeWatcher()
+		keys := make(chan rune)
+		cycles := runCycles(t, w, CycleOptions{Keys: keys, Tests: true})
+
+		keys <- KeyRebuild
+		c := nextCycle(t, cycles)
+		if !Forced(c.ctx) || RerunTests(c.ctx) {
+			t.Errorf("Expected a forced cycle that doesn't rerun the tests")
+		}
+		close(c.done)
+
+		keys <- KeyTests
+		c = nextCycle(t, cycles)
+		if !Forced(c.ctx) || !RerunTests(c.ctx) {
+			t.Errorf("Expected a forced cycle that reruns the tests")
+		}
+		close(c.done)
+
+		w.changes <- &watchman.ChangeSet{Paths: []string{"a.go"}}
+		c = nextCycle(t, cycles)
+		if Forced(c.ctx) {
+			t.Errorf("Expected the cycle of the changes not to be forced")
+		}
+		close(c.done)
+	})
+
+	t.Run("holds the changes while paused", func(t *testing.T) {
+		w := newFakeWatcher()
+		keys := make(chan rune)
+		cycles := runCycles(t, w, CycleOptions{Keys: keys})
+
+		keys <- KeyPause
+		w.changes <- &watchman.ChangeSet{Paths: []string{"a.go"}}
+		w.changes <- &watchman.ChangeSet{Paths: []string{"b.go"}}
+		select {
+		case c := <-cycles:
+			t.Fatalf("Expected no cycle while paused, got one for %v", c.cs.Paths)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		keys <- KeyPause
+		c := nextCycle(t, cycles)
+		if !slices.Equal(c.cs.Paths, []string{"a.go", "b.go"}) {
+			t.Errorf("Expected a.go and b.go to change, got %v", c.cs.Paths)
+		}
+		close(c.done)
+	})
+
+	t.Run("cancels the running cycle on quit", func(t *testing.T) {
+		w := newFakeWatcher()
+		keys := make(chan rune)
+		started := make(chan struct{})
+		result := make(chan error, 1)
+		go func() {
+			result <- Cycles(context.Background(), w, CycleOptions{Keys: keys}, func(ctx context.Context, cs *watchman.ChangeSet) error {
+				close(started)
+				<-ctx.Done()
+				return nil
+			})
+		}()
+
+		w.changes <- &watchman.ChangeSet{Paths: []string{"a.go"}}
+		<-started
+		keys <- KeyQuit
+		select {
+		case err := <-result:
+			if err != nil {
+				t.Errorf("Expected the cycles to stop without an error, got %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected the cycles to stop within 5s")
+		}
+	})
+	t.Run("notifies the results of the cycles that complete", func(t *testing.T) {
+		dir := t.TempDir()
+		notifications := make(chan string, 10)
+		notifier := NewNotifier("aspect build --watch")
+		notifier.send = func(title, message string) {
+			notifications <- message
+		}
+		w := newFakeWatcher()
+		cycles := runCycles(t, w, CycleOptions{Preempt: true, Notifier: notifier})
+
+		w.changes <- &watchman.ChangeSet{Root: dir, Paths: []string{"a.go"}}
+		c := nextCycle(t, cycles)
+		w.changes <- &watchman.ChangeSet{Root: dir, Paths: []string{"b.go"}}
+		<-c.ctx.Done()
+		close(c.done)
+
+		c = nextCycle(t, cycles)
+		notifier.Result(errors.New("exit status 1"))
+		close(c.done)
+
+		select {
+		case message := <-notifications:
+			if !strings.HasPrefix(message, "Failed in ") {
+				t.Errorf("Expected the failure of the last cycle, got %q", message)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected a notification within 5s")
+		}
+		if len(notifications) != 0 {
+			t.Errorf("Expected the preempted cycle not to be notified, got %q", <-notifications)
+		}
+	})
+}
@@ -0,0 +1,357 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/aspect-build/aspect-gazelle/runner/pkg/watchman"
+	"golang.org/x/term"
+)
+
+const (
+	// maxDashboardLines is the number of lines of output kept by the dashboard.
+	maxDashboardLines = 1000
+	// maxDashboardChanges is the number of changed files kept by the dashboard.
+	maxDashboardChanges = 100
+	// dashboardRefresh is the interval at which the dashboard is drawn again
+	// when it changed or when the terminal was resized.
+	dashboardRefresh = 50 * time.Millisecond
+)
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// Dashboard is the full-screen view of --watch in a terminal, with the status
+// of the cycles, the result of the last one, the recently changed files and
+// the output of the processes in separate panes, rather than interleaved.
+type Dashboard struct {
+	mu  sync.Mutex
+	out *os.File
+
+	title string
+	keys  string
+	// The state of the cycles.
+	building bool
+	paused   bool
+	cycle    int
+	started  time.Time
+	// result is the result of the last cycle, and failed is set once a
+	// failure is reported for the running cycle.
+	result string
+	failed bool
+	// changes are the recently changed files, the most recent first.
+	changes []string
+	// lines are the lines of the output, and partial is its incomplete line.
+	lines   []string
+	partial string
+
+	// The size of the terminal when the dashboard was last drawn, or 0 when
+	// it needs to be drawn again.
+	width, height int
+	done          chan struct{}
+	closed        sync.WaitGroup
+}
+
+// NewDashboard shows the dashboard in the terminal of stdout until it is
+// closed. It fails if stdout is not a terminal.
+func NewDashboard(title string) (*Dashboard, error) {
+	out := os.Stdout
+	if !term.IsTerminal(int(out.Fd())) {
+		return nil, fmt.Errorf("the dashboard of --watch requires a terminal")
+	}
+
+	d := &Dashboard{
+		out:      out,
+		title:    title,
+		building: true,
+		started:  time.Now(),
+		done:     make(chan struct{}),
+	}
+
+	// Switch to the alternate screen, which is restored on Close, and hide
+	// the cursor.
+	io.WriteString(out, "\x1b[?1049h\x1b[?25l")
+
+	d.closed.Add(1)
+	go func() {
+		defer d.closed.Done()
+		ticker := time.NewTicker(dashboardRefresh)
+		defer ticker.Stop()
+		for {
+			d.draw()
+			select {
+			case <-ticker.C:
+			case <-d.done:
+				return
+			}
+		}
+	}()
+	return d, nil
+}
+
+// Close restores the screen of the terminal, on which the last lines of the
+// output are written so that they outlive the dashboard, such as the errors of
+// a failing build. It does nothing if d is nil, as do the other methods of
+// Dashboard.
+func (d *Dashboard) Close() {
+	if d == nil {
+		return
+	}
+	close(d.done)
+	d.closed.Wait()
+	io.WriteString(d.out, "\x1b[?25h\x1b[?1049l")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	lines := d.lines
+	if d.partial != "" {
+		lines = append(slices.Clone(lines), d.partial)
+	}
+	if d.height > 0 {
+		lines = lines[max(len(lines)-d.height, 0):]
+	}
+	for _, line := range lines {
+		fmt.Fprintln(d.out, line)
+	}
+}
+
+// Streams returns the streams of the processes, whose output is shown in the
+// output pane. Their stdin is empty since the terminal reads the keys.
+func (d *Dashboard) Streams() ioutils.Streams {
+	return ioutils.Streams{Stdout: d, Stderr: d}
+}
+
+// Write appends p to the output pane.
+func (d *Dashboard) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	text := d.partial + strings.ReplaceAll(ansiEscape.ReplaceAllString(string(p), ""), "\t", "    ")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		// Keep what was written after the last carriage return, as a
+		// terminal would.
+		if j := strings.LastIndexByte(line, '\r'); j >= 0 {
+			lines[i] = line[j+1:]
+		}
+	}
+	d.partial = lines[len(lines)-1]
+	d.lines = append(d.lines, lines[:len(lines)-1]...)
+	if len(d.lines) > maxDashboardLines {
+		d.lines = slices.Clone(d.lines[len(d.lines)-maxDashboardLines:])
+	}
+	d.width = 0
+	return len(p), nil
+}
+
+// Result reports the result of the running cycle, or of the initial build. A
+// failure sticks until the next cycle, so that the cycles of several targets
+// report any that failed.
+func (d *Dashboard) Result(err error) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// The initial build completes before the first cycle.
+	if d.cycle == 0 {
+		d.building = false
+	}
+
+	elapsed := time.Since(d.started).Round(100 * time.Millisecond)
+	at := time.Now().Format(time.TimeOnly)
+	switch {
+	case err != nil:
+		d.failed = true
+		d.result = fmt.Sprintf("✗ failed in %v at %s: %v", elapsed, at, err)
+	case !d.failed:
+		d.result = fmt.Sprintf("✓ succeeded in %v at %s", elapsed, at)
+	}
+	d.width = 0
+}
+
+// cycleStarted shows the cycle of the change set as running.
+func (d *Dashboard) cycleStarted(cs *watchman.ChangeSet) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.building = true
+	d.cycle++
+	d.started = time.Now()
+	d.failed = false
+
+	paths := cs.Paths
+	if cs.IsFreshInstance {
+		paths = []string{"(all files)"}
+	}
+	for _, p := range paths {
+		d.changes = slices.DeleteFunc(d.changes, func(c string) bool { return c == p })
+	}
+	d.changes = append(slices.Clone(paths), d.changes...)
+	if len(d.changes) > maxDashboardChanges {
+		d.changes = d.changes[:maxDashboardChanges]
+	}
+	d.width = 0
+}
+
+// cycleDone shows the cycles as waiting for changes.
+func (d *Dashboard) cycleDone() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.building = false
+	d.width = 0
+}
+
+// setKeys shows the keybindings.
+func (d *Dashboard) setKeys(keys string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.keys = keys
+	d.width = 0
+}
+
+// setPaused shows whether watching is paused.
+func (d *Dashboard) setPaused(paused bool) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.paused = paused
+	d.width = 0
+}
+
+// draw draws the dashboard if it changed or if the terminal was resized.
+func (d *Dashboard) draw() {
+	width, height, err := term.GetSize(int(d.out.Fd()))
+	if err != nil {
+		return
+	}
+
+	d.mu.Lock()
+	if width == d.width && height == d.height {
+		d.mu.Unlock()
+		return
+	}
+	d.width, d.height = width, height
+	frame := d.render(width, height)
+	d.mu.Unlock()
+
+	io.WriteString(d.out, frame)
+}
+
+// render returns the frame of the dashboard for a terminal of the size.
+func (d *Dashboard) render(width, height int) string {
+	status := "Watching for changes"
+	if d.building {
+		status = fmt.Sprintf("Building (cycle %d) since %s", d.cycle, d.started.Format(time.TimeOnly))
+		if d.cycle == 0 {
+			status = "Initial build since " + d.started.Format(time.TimeOnly)
+		}
+	}
+	if d.paused {
+		status += ", paused"
+	}
+	result := d.result
+	if result == "" {
+		result = "none yet"
+	}
+
+	// The changed files are on the left of the output, which shows its last
+	// lines.
+	left := min(max(width/4, 20), 40)
+	right := max(width-left-4, 0)
+	rows := max(height-5, 0)
+	output := d.lines
+	if d.partial != "" {
+		output = append(slices.Clone(output), d.partial)
+	}
+	output = output[max(len(output)-rows, 0):]
+
+	// Each line is erased after its text, in place of the previous frame.
+	var b strings.Builder
+	line := func(s string) {
+		b.WriteString(s)
+		b.WriteString("\x1b[K")
+	}
+	b.WriteString("\x1b[H")
+	line("\x1b[7m" + pad(fit(" "+d.title, width), width) + "\x1b[0m")
+	b.WriteString("\n")
+	line(fit(" Status: "+status, width))
+	b.WriteString("\n")
+	line(fit(" Last:   "+strings.ReplaceAll(result, "\n", " "), width))
+	b.WriteString("\n")
+	line(fit(pad("─ Changed files ", left+2, '─')+"┬"+pad("─ Output ", right+1, '─'), width))
+	for i := range rows {
+		var change, out string
+		if i < len(d.changes) {
+			change = d.changes[i]
+		}
+		if i < len(output) {
+			out = output[i]
+		}
+		b.WriteString("\n")
+		line(fit(" "+pad(fit(change, left), left)+" │ "+fit(out, right), width))
+	}
+	b.WriteString("\n")
+	if d.keys != "" {
+		line("\x1b[2m" + fit(" Press "+d.keys+".", width) + "\x1b[0m")
+	}
+	return b.String()
+}
+
+// fit shortens s to width runes.
+func fit(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	if width <= 0 {
+		return ""
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// pad fills s up to width runes, with spaces unless another filler is given.
+func pad(s string, width int, filler ...rune) string {
+	f := ' '
+	if len(filler) > 0 {
+		f = filler[0]
+	}
+	if n := width - len([]rune(s)); n > 0 {
+		return s + strings.Repeat(string(f), n)
+	}
+	return s
+}
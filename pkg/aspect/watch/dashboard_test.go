@@ -0,0 +1,90 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aspect-build/aspect-gazelle/runner/pkg/watchman"
+)
+
+func TestDashboard(t *testing.T) {
+	t.Run("keeps the lines of the output as a terminal shows them", func(t *testing.T) {
+		d := &Dashboard{}
+		d.Write([]byte("\x1b[32mINFO:\x1b[0m Build completed\nBuilding 1/3\rBuilding 2/3"))
+		d.Write([]byte("\rBuilding 3/3\nTa\tb\n"))
+
+		expected := []string{"INFO: Build completed", "Building 3/3", "Ta    b"}
+		if !slices.Equal(d.lines, expected) || d.partial != "" {
+			t.Errorf("Expected the lines %q, got %q and %q", expected, d.lines, d.partial)
+		}
+	})
+
+	t.Run("keeps a failure until the next cycle", func(t *testing.T) {
+		d := &Dashboard{}
+		d.cycleStarted(&watchman.ChangeSet{Paths: []string{"a.go"}})
+		d.Result(errors.New("//app:server: exit status 1"))
+		d.Result(nil)
+		if !strings.Contains(d.result, "failed") || !strings.Contains(d.result, "//app:server") {
+			t.Errorf("Expected the failure of //app:server, got %q", d.result)
+		}
+
+		d.cycleStarted(&watchman.ChangeSet{Paths: []string{"a.go"}})
+		d.Result(nil)
+		if !strings.Contains(d.result, "succeeded") {
+			t.Errorf("Expected a success, got %q", d.result)
+		}
+	})
+
+	t.Run("renders the status, the changed files and the last lines of the output", func(t *testing.T) {
+		d := &Dashboard{title: "aspect build --watch"}
+		d.cycleStarted(&watchman.ChangeSet{Paths: []string{"src/a.go"}})
+		d.cycleDone()
+		d.cycleStarted(&watchman.ChangeSet{Paths: []string{"src/b.go", "src/a.go"}})
+		d.setKeys(keysHelp(false))
+		for i := range 20 {
+			d.Write([]byte(strings.Repeat("x", i) + "\n"))
+		}
+		d.Write([]byte("last"))
+
+		frame := d.render(80, 10)
+		rows := strings.Split(frame, "\n")
+		if len(rows) != 10 {
+			t.Fatalf("Expected 10 rows, got %d: %q", len(rows), rows)
+		}
+		for _, row := range rows {
+			if n := len([]rune(ansiEscape.ReplaceAllString(row, ""))); n > 80 {
+				t.Errorf("Expected the rows to fit in 80 columns, got %d for %q", n, row)
+			}
+		}
+		if !strings.Contains(rows[0], "aspect build --watch") || !strings.Contains(rows[1], "Building (cycle 2)") {
+			t.Errorf("Expected the title and the status, got %q", rows[:2])
+		}
+		if !strings.Contains(rows[4], "src/b.go") || !strings.Contains(rows[5], "src/a.go") {
+			t.Errorf("Expected the changed files, the most recent first, got %q", rows[4:6])
+		}
+		if !strings.Contains(rows[8], "│ last") || !strings.Contains(rows[4], "│ "+strings.Repeat("x", 16)) {
+			t.Errorf("Expected the last lines of the output, got %q", rows[4:9])
+		}
+		if !strings.Contains(rows[9], "r to rebuild") || strings.Contains(rows[9], "rerun the tests") {
+			t.Errorf("Expected the keybindings, got %q", rows[9])
+		}
+	})
+}
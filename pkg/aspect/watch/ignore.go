@@ -0,0 +1,102 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"fmt"
+
+	logger "github.com/aspect-build/aspect-gazelle/common/logger"
+	"github.com/aspect-build/aspect-gazelle/runner/pkg/watchman"
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/spf13/viper"
+)
+
+// IgnoreKey is the key of the Aspect CLI config with the glob patterns of the
+// files, relative to the workspace, whose changes don't trigger a cycle of
+// --watch. They add to defaultIgnore, e.g.
+//
+//	watch_ignore:
+//	  - "**/*.log"
+//	  - "docs/**"
+const IgnoreKey = "watch_ignore"
+
+// defaultIgnore are the patterns of the files that bazel and the editors
+// write next to the sources.
+var defaultIgnore = []string{
+	// The convenience symlinks of bazel.
+	"bazel-*/**",
+	// The swap files of vim, and the file it writes to check that a
+	// directory is writable.
+	"**/.*.sw[a-p]",
+	"**/4913",
+	// The backup, autosave and lock files of emacs and others.
+	"**/*~",
+	"**/#*#",
+	"**/.#*",
+	"**/.DS_Store",
+}
+
+// Ignore are the glob patterns of the changes that --watch ignores.
+type Ignore []string
+
+// LoadIgnore returns the default patterns and those of IgnoreKey.
+func LoadIgnore() (Ignore, error) {
+	patterns := append(Ignore{}, defaultIgnore...)
+	for _, pattern := range viper.GetStringSlice(IgnoreKey) {
+		if !doublestar.ValidatePattern(pattern) {
+			return nil, fmt.Errorf("invalid %s pattern %q", IgnoreKey, pattern)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// Match returns true if the path, relative to the workspace, is ignored.
+func (ignore Ignore) Match(p string) bool {
+	for _, pattern := range ignore {
+		if doublestar.MatchUnvalidated(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the paths that are not ignored.
+func (ignore Ignore) Filter(paths []string) []string {
+	kept := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if !ignore.Match(p) {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// skip returns true if only ignored files changed in the change set, in which
+// case it doesn't trigger a cycle.
+func (ignore Ignore) skip(cs *watchman.ChangeSet) bool {
+	if cs.IsFreshInstance || len(cs.Paths) == 0 {
+		return false
+	}
+	paths := ignore.Filter(cs.Paths)
+	if len(paths) == 0 {
+		logger.Debugf("ignored changes: %v", cs.Paths)
+		return true
+	}
+	cs.Paths = paths
+	return false
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestIgnore(t *testing.T) {
+	t.Run("ignores the files of bazel and the editors by default", func(t *testing.T) {
+		ignore, err := LoadIgnore()
+		if err != nil {
+			t.Fatalf("Failed to load the watch ignore patterns: %v", err)
+		}
+
+		paths := []string{
+			"bazel-bin",
+			"bazel-out/k8-fastbuild/bin/app",
+			"src/.main.go.swp",
+			"src/4913",
+			"src/main.go~",
+			"src/#main.go#",
+			"src/.#main.go",
+			".DS_Store",
+			"src/main.go",
+			"bazel/defs.bzl",
+			"src/app.log",
+		}
+		if kept := ignore.Filter(paths); !slices.Equal(kept, []string{"src/main.go", "bazel/defs.bzl", "src/app.log"}) {
+			t.Errorf("Expected only the sources to be kept, got %v", kept)
+		}
+	})
+
+	t.Run("adds the patterns of the config", func(t *testing.T) {
+		viper.Set(IgnoreKey, []string{"**/*.log", "docs/**"})
+		t.Cleanup(viper.Reset)
+
+		ignore, err := LoadIgnore()
+		if err != nil {
+			t.Fatalf("Failed to load the watch ignore patterns: %v", err)
+		}
+
+		paths := []string{"src/app.log", "docs/index.md", "src/main.go", "src/.main.go.swp"}
+		if kept := ignore.Filter(paths); !slices.Equal(kept, []string{"src/main.go"}) {
+			t.Errorf("Expected only src/main.go to be kept, got %v", kept)
+		}
+	})
+
+	t.Run("rejects invalid patterns", func(t *testing.T) {
+		viper.Set(IgnoreKey, []string{"src/[a-"})
+		t.Cleanup(viper.Reset)
+
+		if _, err := LoadIgnore(); err == nil || err.Error() != `invalid watch_ignore pattern "src/[a-"` {
+			t.Errorf("Expected an invalid pattern error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+
+	logger "github.com/aspect-build/aspect-gazelle/common/logger"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+// KeysKey is the key of the Aspect CLI config that turns off the keybindings
+// of --watch when set to false, such as for programs reading their stdin.
+const KeysKey = "watch_keys"
+
+// The keybindings of --watch.
+const (
+	// KeyRebuild starts a cycle, which restarts the programs of aspect run.
+	KeyRebuild = 'r'
+	// KeyTests starts a cycle that reruns the tests, even the cached ones.
+	KeyTests = 't'
+	// KeyPause pauses, or resumes, watching the changes.
+	KeyPause = 'p'
+	// KeyQuit ends the session.
+	KeyQuit = 'q'
+)
+
+// KeysEnabled returns true unless the keybindings are turned off in the Aspect
+// CLI config.
+func KeysEnabled() bool {
+	return !viper.IsSet(KeysKey) || viper.GetBool(KeysKey)
+}
+
+// ReadKeys reads the keys pressed in the terminal until stop is called, which
+// restores the terminal. The keys are nil if the keybindings are turned off or
+// if stdin is not a terminal.
+func ReadKeys() (keys <-chan rune, stop func()) {
+	fd := int(os.Stdin.Fd())
+	if !KeysEnabled() || !term.IsTerminal(fd) {
+		return nil, func() {}
+	}
+
+	// Read the keys as they are pressed, without echoing them, while Ctrl-C
+	// still interrupts the session.
+	restore, err := cbreak(fd)
+	if err != nil {
+		logger.Debugf("failed to read the keys of the terminal: %v", err)
+		return nil, func() {}
+	}
+
+	ch := make(chan rune)
+	done := make(chan struct{})
+	go func() {
+		in := bufio.NewReader(os.Stdin)
+		for {
+			key, _, err := in.ReadRune()
+			if err != nil {
+				return
+			}
+			select {
+			case ch <- unicode.ToLower(key):
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			close(done)
+			if err := restore(); err != nil {
+				logger.Debugf("failed to restore the terminal: %v", err)
+			}
+		})
+	}
+}
+
+// keysHelp describes the keybindings.
+func keysHelp(tests bool) string {
+	bindings := []string{fmt.Sprintf("%c to rebuild", KeyRebuild)}
+	if tests {
+		bindings = append(bindings, fmt.Sprintf("%c to rerun the tests", KeyTests))
+	}
+	bindings = append(bindings, fmt.Sprintf("%c to pause", KeyPause))
+	return fmt.Sprintf("%s or %c to quit", strings.Join(bindings, ", "), KeyQuit)
+}
+
+type keyContextKey struct{}
+
+// withKey returns the context of a cycle started with the key, if not 0.
+func withKey(ctx context.Context, key rune) context.Context {
+	if key == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, keyContextKey{}, key)
+}
+
+// Forced returns true if the cycle was started with a key rather than by
+// changes.
+func Forced(ctx context.Context) bool {
+	_, ok := ctx.Value(keyContextKey{}).(rune)
+	return ok
+}
+
+// RerunTests returns true if the cycle was started with KeyTests.
+func RerunTests(ctx context.Context) bool {
+	key, _ := ctx.Value(keyContextKey{}).(rune)
+	return key == KeyTests
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	logger "github.com/aspect-build/aspect-gazelle/common/logger"
+	"github.com/spf13/viper"
+)
+
+// NotifyFlag notifies the results of the cycles of --watch on the desktop.
+const NotifyFlag = "--notify"
+
+// NotifyKey is the key of the Aspect CLI config that notifies the results of
+// the cycles of --watch on the desktop when set to true, as NotifyFlag does.
+const NotifyKey = "watch_notify"
+
+// NotifyEnabled returns true if the results of the cycles are notified, with
+// NotifyFlag or in the Aspect CLI config.
+func NotifyEnabled(flag bool) bool {
+	return flag || viper.GetBool(NotifyKey)
+}
+
+// Notifier notifies the result of each cycle of --watch on the desktop, so
+// that it is seen while working in another window.
+type Notifier struct {
+	mu      sync.Mutex
+	title   string
+	started time.Time
+	// err is the first failure reported for the running cycle.
+	err error
+
+	// send shows the notification.
+	send func(title, message string)
+}
+
+// NewNotifier creates a Notifier whose notifications have the title.
+func NewNotifier(title string) *Notifier {
+	return &Notifier{title: title, started: time.Now(), send: notify}
+}
+
+// Result reports the result of the running cycle. A failure sticks until the
+// next cycle, so that the cycles of several targets notify any that failed. It
+// does nothing if n is nil, as do the other methods of Notifier.
+func (n *Notifier) Result(err error) {
+	if n == nil || err == nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.err == nil {
+		n.err = err
+	}
+}
+
+// cycleStarted starts timing a cycle.
+func (n *Notifier) cycleStarted() {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.started = time.Now()
+	n.err = nil
+}
+
+// cycleDone notifies the result of the cycle that completed.
+func (n *Notifier) cycleDone() {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	elapsed := time.Since(n.started).Round(100 * time.Millisecond)
+	message := fmt.Sprintf("Succeeded in %v", elapsed)
+	if n.err != nil {
+		message = fmt.Sprintf("Failed in %v: %v", elapsed, n.err)
+	}
+	n.send(n.title, message)
+}
+
+// notify shows a desktop notification in the background.
+func notify(title, message string) {
+	cmd := notifyCommand(runtime.GOOS, title, message)
+	if cmd == nil {
+		logger.Debugf("desktop notifications are not supported on %s", runtime.GOOS)
+		return
+	}
+	if err := cmd.Start(); err != nil {
+		logger.Debugf("failed to notify %q: %v", message, err)
+		return
+	}
+	go cmd.Wait()
+}
+
+// notifyCommand returns the command showing a desktop notification on the
+// platform, or nil if it's not supported. The title and the message are
+// passed as arguments, or in the environment, rather than in the scripts so
+// that they don't need to be escaped.
+func notifyCommand(goos, title, message string) *exec.Cmd {
+	switch goos {
+	case "darwin":
+		return exec.Command("osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			title, message)
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return exec.Command("notify-send", "--app-name=aspect", title, message)
+	case "windows":
+		cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", strings.Join([]string{
+			"Add-Type -AssemblyName System.Windows.Forms",
+			"$n = New-Object System.Windows.Forms.NotifyIcon",
+			"$n.Icon = [System.Drawing.SystemIcons]::Information",
+			"$n.Visible = $true",
+			"$n.ShowBalloonTip(5000, $env:ASPECT_NOTIFY_TITLE, $env:ASPECT_NOTIFY_MESSAGE, 'None')",
+			"Start-Sleep -Seconds 5",
+			"$n.Dispose()",
+		}, "; "))
+		cmd.Env = append(os.Environ(), "ASPECT_NOTIFY_TITLE="+title, "ASPECT_NOTIFY_MESSAGE="+message)
+		return cmd
+	}
+	return nil
+}
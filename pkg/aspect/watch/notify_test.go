@@ -0,0 +1,89 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestNotifier(t *testing.T) {
+	t.Run("notifies the first failure of a cycle", func(t *testing.T) {
+		var messages []string
+		n := NewNotifier("aspect run --watch")
+		n.send = func(title, message string) {
+			if title != "aspect run --watch" {
+				t.Errorf("Expected the title of the session, got %q", title)
+			}
+			messages = append(messages, message)
+		}
+
+		n.cycleStarted()
+		n.Result(errors.New("//app:server: exit status 1"))
+		n.Result(nil)
+		n.Result(errors.New("//app:worker: exit status 1"))
+		n.cycleDone()
+
+		n.cycleStarted()
+		n.Result(nil)
+		n.cycleDone()
+
+		if len(messages) != 2 {
+			t.Fatalf("Expected 2 notifications, got %q", messages)
+		}
+		if !strings.HasPrefix(messages[0], "Failed in ") || !strings.HasSuffix(messages[0], ": //app:server: exit status 1") {
+			t.Errorf("Expected the failure of //app:server, got %q", messages[0])
+		}
+		if !strings.HasPrefix(messages[1], "Succeeded in ") {
+			t.Errorf("Expected a success, got %q", messages[1])
+		}
+	})
+
+	t.Run("is enabled with the flag or in the config", func(t *testing.T) {
+		t.Cleanup(viper.Reset)
+		if NotifyEnabled(false) || !NotifyEnabled(true) {
+			t.Errorf("Expected the notifications to follow the flag")
+		}
+		viper.Set(NotifyKey, true)
+		if !NotifyEnabled(false) {
+			t.Errorf("Expected the notifications to be enabled in the config")
+		}
+	})
+
+	t.Run("passes the title and the message as arguments", func(t *testing.T) {
+		title, message := "aspect build --watch", `Failed in 1s: "quoted" \ message`
+		for _, goos := range []string{"darwin", "linux"} {
+			cmd := notifyCommand(goos, title, message)
+			if cmd == nil || !slices.Equal(cmd.Args[len(cmd.Args)-2:], []string{title, message}) {
+				t.Errorf("Expected the title and the message as the last arguments on %s, got %v", goos, cmd)
+			}
+		}
+
+		cmd := notifyCommand("windows", title, message)
+		if cmd == nil || !slices.Contains(cmd.Env, "ASPECT_NOTIFY_MESSAGE="+message) {
+			t.Errorf("Expected the message in the environment on windows, got %v", cmd)
+		}
+
+		if cmd := notifyCommand("plan9", title, message); cmd != nil {
+			t.Errorf("Expected no notifications on plan9, got %v", cmd.Args)
+		}
+	})
+}
@@ -0,0 +1,247 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aspect-build/aspect-gazelle/runner/pkg/watchman"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/root/flags"
+)
+
+// PollFlag watches the workspace by scanning it periodically rather than with
+// watchman or the file events of the OS, which never arrive on NFS mounts, on
+// the file shares of Docker Desktop or in remote devcontainers. It scans every
+// DefaultPollInterval, or every <duration> when given as --poll=<duration>.
+const PollFlag = "--poll"
+
+// DefaultPollInterval is how often PollFlag scans the workspace by default.
+const DefaultPollInterval = time.Second
+
+// RemovePollFlag removes PollFlag from the bazel portion of args, and returns
+// the interval to scan the workspace at, or 0 when it is not set.
+func RemovePollFlag(args []string) (time.Duration, []string, error) {
+	poll, args := flags.RemoveFlag(args, PollFlag)
+	value, args := flags.RemoveFlagValue(args, PollFlag)
+	if value == "" {
+		if poll {
+			return DefaultPollInterval, args, nil
+		}
+		return 0, args, nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid %s %q: %w", PollFlag, value, err)
+	}
+	if interval <= 0 {
+		return 0, nil, fmt.Errorf("invalid %s %q: must be positive", PollFlag, value)
+	}
+	return interval, args, nil
+}
+
+// pollWatcher watches the workspace by comparing the size and the modification
+// time of its files every interval, which works on any filesystem but costs a
+// scan of the whole workspace each time.
+//
+// Like the other watchers, it skips the directories of the .bazelignore and
+// the VCS directories, and doesn't follow symlinks. The changes made between
+// two change sets are reported with the next one, so the changes made while in
+// a state are held until the subscriber is ready for them.
+type pollWatcher struct {
+	workspaceDir string
+	interval     time.Duration
+
+	mu      sync.Mutex
+	ignored []string
+	// files is the state of the files of the last scan, relative to the
+	// workspace, or nil before Start.
+	files     map[string]pollStat
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// pollStat is what tells the versions of a file apart.
+type pollStat struct {
+	size    int64
+	modTime int64
+	typ     fs.FileMode
+}
+
+func newPollWatcher(workspaceDir string, interval time.Duration) *pollWatcher {
+	return &pollWatcher{workspaceDir: workspaceDir, interval: interval, closed: make(chan struct{})}
+}
+
+// Start scans the workspace for the first time.
+//
+// Calling start multiple times will not scan it again.
+func (w *pollWatcher) Start() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.files != nil {
+		return nil
+	}
+
+	ignored, err := loadIgnored(w.workspaceDir)
+	if err != nil {
+		return err
+	}
+	w.ignored = ignored
+
+	files, err := w.scan()
+	if err != nil {
+		return err
+	}
+	w.files = files
+	return nil
+}
+
+// Close stops watching the workspace and ends the subscriptions.
+func (w *pollWatcher) Close() error {
+	w.closeOnce.Do(func() { close(w.closed) })
+	return nil
+}
+
+// StateEnter is a no-op, the changes are found by the next scan once the
+// subscriber asks for the next change set.
+func (w *pollWatcher) StateEnter(name string) error {
+	return nil
+}
+
+// StateLeave is a no-op, see StateEnter.
+func (w *pollWatcher) StateLeave(name string) error {
+	return nil
+}
+
+// Subscribe reports the files that changed, relative to the workspace. The
+// first ChangeSet has no changes, as with watchman. The options are ignored.
+func (w *pollWatcher) Subscribe(ctx context.Context, options ...watchman.SubscribeOptions) iter.Seq2[*watchman.ChangeSet, error] {
+	return func(yield func(*watchman.ChangeSet, error) bool) {
+		w.mu.Lock()
+		started := w.files != nil
+		w.mu.Unlock()
+		if !started {
+			yield(nil, fmt.Errorf("watcher not started, call Start() first"))
+			return
+		}
+
+		if !yield(&watchman.ChangeSet{Paths: []string{}, Root: w.workspaceDir}, nil) {
+			return
+		}
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				yield(nil, ctx.Err())
+				return
+			case <-w.closed:
+				return
+			case <-ticker.C:
+			}
+
+			paths, err := w.poll()
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if len(paths) == 0 {
+				continue
+			}
+			if !yield(&watchman.ChangeSet{Paths: paths, Root: w.workspaceDir}, nil) {
+				return
+			}
+		}
+	}
+}
+
+// poll scans the workspace and returns the files that were created, modified
+// or removed since the last scan, sorted.
+func (w *pollWatcher) poll() ([]string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	files, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{}
+	for p, stat := range files {
+		if last, ok := w.files[p]; !ok || last != stat {
+			paths = append(paths, p)
+		}
+	}
+	for p := range w.files {
+		if _, ok := files[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	slices.Sort(paths)
+	w.files = files
+	return paths, nil
+}
+
+// scan returns the state of the files of the workspace, relative to it.
+// Symlinks are not followed, so the bazel convenience symlinks are not
+// scanned. w.mu must be held.
+func (w *pollWatcher) scan() (map[string]pollStat, error) {
+	files := make(map[string]pollStat, len(w.files))
+	err := filepath.WalkDir(w.workspaceDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Files may be removed while they are walked.
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if p == w.workspaceDir {
+			return nil
+		}
+		rel, ok := workspacePath(w.workspaceDir, w.ignored, p)
+		if !ok {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		files[rel] = pollStat{size: info.Size(), modTime: info.ModTime().UnixNano(), typ: info.Mode().Type()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan the workspace: %w", err)
+	}
+	return files, nil
+}
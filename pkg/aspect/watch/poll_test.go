@@ -0,0 +1,104 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"os"
+	"path"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRemovePollFlag(t *testing.T) {
+	for _, tc := range []struct {
+		args     []string
+		interval time.Duration
+		rest     []string
+	}{
+		{[]string{"build", "//..."}, 0, []string{"build", "//..."}},
+		{[]string{"build", "--poll", "//..."}, DefaultPollInterval, []string{"build", "//..."}},
+		{[]string{"build", "--poll=250ms", "//..."}, 250 * time.Millisecond, []string{"build", "//..."}},
+		{[]string{"run", "//app", "--", "--poll"}, 0, []string{"run", "//app", "--", "--poll"}},
+	} {
+		interval, rest, err := RemovePollFlag(slices.Clone(tc.args))
+		if err != nil || interval != tc.interval || !slices.Equal(rest, tc.rest) {
+			t.Errorf("RemovePollFlag(%v) = %v, %v, %v, expected %v, %v", tc.args, interval, rest, err, tc.interval, tc.rest)
+		}
+	}
+
+	for _, value := range []string{"--poll=fast", "--poll=0s"} {
+		if _, _, err := RemovePollFlag([]string{"build", value}); err == nil {
+			t.Errorf("Expected %s to be invalid", value)
+		}
+	}
+}
+
+func TestPollWatcher(t *testing.T) {
+	t.Run("reports the created, modified and removed files", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, path.Join(dir, "pkg/a.txt"))
+		writeFile(t, path.Join(dir, "pkg/b.txt"))
+		changes := subscribe(t, newPollWatcher(dir, 10*time.Millisecond))
+
+		if err := os.WriteFile(path.Join(dir, "pkg/a.txt"), []byte("modified"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Remove(path.Join(dir, "pkg/b.txt")); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, path.Join(dir, "new/deep/c.txt"))
+
+		var paths []string
+		for len(paths) < 3 {
+			cs := next(t, changes)
+			if cs.Root != dir {
+				t.Errorf("Expected the root %q, got %q", dir, cs.Root)
+			}
+			paths = append(paths, cs.Paths...)
+		}
+		slices.Sort(paths)
+		if !slices.Equal(paths, []string{"new/deep/c.txt", "pkg/a.txt", "pkg/b.txt"}) {
+			t.Errorf("Expected the created, modified and removed files, got %v", paths)
+		}
+	})
+
+	t.Run("skips the ignored directories and the attribute changes", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(path.Join(dir, ".bazelignore"), []byte("node_modules\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, path.Join(dir, "node_modules/pkg/index.js"))
+		writeFile(t, path.Join(dir, "src/main.go"))
+		changes := subscribe(t, newPollWatcher(dir, 10*time.Millisecond))
+
+		writeFile(t, path.Join(dir, "node_modules/other/index.js"))
+		writeFile(t, path.Join(dir, ".git/HEAD"))
+		if err := os.Chmod(path.Join(dir, "src/main.go"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, path.Join(dir, "src/last.go"))
+
+		var paths []string
+		for !slices.Contains(paths, "src/last.go") {
+			paths = append(paths, next(t, changes).Paths...)
+		}
+		if !slices.Equal(paths, []string{"src/last.go"}) {
+			t.Errorf("Expected only src/last.go to change, got %v", paths)
+		}
+	})
+}
@@ -0,0 +1,33 @@
+//go:build !darwin && !linux
+
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"errors"
+)
+
+// cbreak is not supported on this platform, where the keybindings are off.
+func cbreak(fd int) (func() error, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// RawTerminal is not supported on this platform.
+func RawTerminal(fd int, keepSignals bool) (func() error, error) {
+	return nil, errors.ErrUnsupported
+}
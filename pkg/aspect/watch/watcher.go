@@ -0,0 +1,310 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package watch watches the workspace for the --watch mode of aspect build,
+// aspect run and aspect test.
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aspect-build/aspect-gazelle/common/bazel"
+	"github.com/aspect-build/aspect-gazelle/runner/pkg/watchman"
+	"github.com/fatih/color"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher reports the changes to the files of the workspace that trigger the
+// cycles of --watch.
+type Watcher interface {
+	Start() error
+	Subscribe(ctx context.Context, options ...watchman.SubscribeOptions) iter.Seq2[*watchman.ChangeSet, error]
+	StateEnter(name string) error
+	StateLeave(name string) error
+	Close() error
+}
+
+var (
+	_ Watcher = (*watchman.WatchmanWatcher)(nil)
+	_ Watcher = (*fsnotifyWatcher)(nil)
+	_ Watcher = (*pollWatcher)(nil)
+)
+
+// New returns a watchman watcher of the workspace, or a fsnotifyWatcher if
+// watchman is not installed. A positive poll interval returns a pollWatcher
+// instead, see PollFlag.
+func New(workspaceDir string, poll time.Duration) Watcher {
+	if poll > 0 {
+		return newPollWatcher(workspaceDir, poll)
+	}
+	if _, err := exec.LookPath("watchman"); err != nil {
+		fmt.Printf("%s watchman is not installed, falling back to the built-in file watcher which scales worse "+
+			"with the size of the workspace. See https://facebook.github.io/watchman/docs/install to install watchman.\n",
+			color.GreenString("INFO:"))
+		return newFsnotifyWatcher(workspaceDir)
+	}
+	return watchman.NewWatchman(workspaceDir)
+}
+
+// fsnotifySettle is how long the fsnotifyWatcher waits for further changes
+// before it reports a change set, which matches the default settle period of
+// watchman.
+const fsnotifySettle = 20 * time.Millisecond
+
+// fsnotifyWatcher watches the workspace with fsnotify, i.e. inotify, kqueue or
+// ReadDirectoryChangesW. Each directory of the workspace is watched on its own,
+// and with kqueue each file too, so it may run out of watches or file
+// descriptors on large workspaces where watchman does not.
+//
+// Like watchman, it skips the directories of the .bazelignore and the VCS
+// directories. The changes made while in a state are reported once the
+// subscriber is ready for them, as with watchman.DeferState.
+type fsnotifyWatcher struct {
+	workspaceDir string
+
+	mu      sync.Mutex
+	watcher *fsnotify.Watcher
+	ignored []string
+}
+
+func newFsnotifyWatcher(workspaceDir string) *fsnotifyWatcher {
+	return &fsnotifyWatcher{workspaceDir: workspaceDir}
+}
+
+// Start watches the directories of the workspace.
+//
+// Calling start multiple times will not start multiple watches.
+func (w *fsnotifyWatcher) Start() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.watcher != nil {
+		return nil
+	}
+
+	ignored, err := loadIgnored(w.workspaceDir)
+	if err != nil {
+		return err
+	}
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create the file watcher: %w", err)
+	}
+	w.watcher = fw
+	w.ignored = ignored
+
+	if _, err := w.watchTree(w.workspaceDir); err != nil {
+		fw.Close()
+		w.watcher = nil
+		return err
+	}
+	return nil
+}
+
+// Close stops watching the workspace and ends the subscriptions.
+func (w *fsnotifyWatcher) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.watcher == nil {
+		return nil
+	}
+	return w.watcher.Close()
+}
+
+// StateEnter is a no-op, the changes are queued until the subscriber asks for
+// the next change set.
+func (w *fsnotifyWatcher) StateEnter(name string) error {
+	return nil
+}
+
+// StateLeave is a no-op, see StateEnter.
+func (w *fsnotifyWatcher) StateLeave(name string) error {
+	return nil
+}
+
+// Subscribe reports the files that changed, relative to the workspace. The
+// first ChangeSet has no changes, as with watchman. The options are ignored.
+func (w *fsnotifyWatcher) Subscribe(ctx context.Context, options ...watchman.SubscribeOptions) iter.Seq2[*watchman.ChangeSet, error] {
+	return func(yield func(*watchman.ChangeSet, error) bool) {
+		w.mu.Lock()
+		fw := w.watcher
+		w.mu.Unlock()
+		if fw == nil {
+			yield(nil, fmt.Errorf("watcher not started, call Start() first"))
+			return
+		}
+
+		if !yield(&watchman.ChangeSet{Paths: []string{}, Root: w.workspaceDir}, nil) {
+			return
+		}
+
+		changed := map[string]struct{}{}
+		fresh := false
+		var settled <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				yield(nil, ctx.Err())
+				return
+			case event, ok := <-fw.Events:
+				if !ok {
+					return
+				}
+				paths, err := w.changedPaths(event)
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+				for _, p := range paths {
+					changed[p] = struct{}{}
+				}
+			case err, ok := <-fw.Errors:
+				if !ok {
+					return
+				}
+				if !errors.Is(err, fsnotify.ErrEventOverflow) {
+					yield(nil, fmt.Errorf("file watcher error: %w", err))
+					return
+				}
+				// Changes were lost, so the subscriber has to start over as
+				// on a watchman fresh instance.
+				fresh = true
+			case <-settled:
+				cs := &watchman.ChangeSet{Paths: []string{}, Root: w.workspaceDir, IsFreshInstance: fresh}
+				if !fresh {
+					for p := range changed {
+						cs.Paths = append(cs.Paths, p)
+					}
+					slices.Sort(cs.Paths)
+				}
+				clear(changed)
+				fresh = false
+				settled = nil
+				if !yield(cs, nil) {
+					return
+				}
+				continue
+			}
+			if settled == nil && (fresh || len(changed) > 0) {
+				settled = time.After(fsnotifySettle)
+			}
+		}
+	}
+}
+
+// changedPaths returns the files of the workspace that changed with the event,
+// relative to the workspace, and watches the directories it created.
+func (w *fsnotifyWatcher) changedPaths(event fsnotify.Event) ([]string, error) {
+	// Attribute changes, such as the access time changing when bazel reads
+	// the inputs, don't change the files.
+	if event.Op == fsnotify.Chmod {
+		return nil, nil
+	}
+	rel, ok := w.relPath(event.Name)
+	if !ok {
+		return nil, nil
+	}
+	if event.Has(fsnotify.Create) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		// The files created in a new directory before it was watched have no
+		// events of their own.
+		return w.watchTree(event.Name)
+	}
+	return []string{rel}, nil
+}
+
+// relPath returns the path relative to the workspace, and false if it is
+// ignored.
+func (w *fsnotifyWatcher) relPath(p string) (string, bool) {
+	return workspacePath(w.workspaceDir, w.ignored, p)
+}
+
+// loadIgnored returns the directories the watchers skip, those of the
+// .bazelignore and the VCS directories, as watchman does.
+func loadIgnored(workspaceDir string) ([]string, error) {
+	ignored, err := bazel.LoadBazelIgnore(workspaceDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the .bazelignore: %w", err)
+	}
+	return append(ignored, ".git", ".hg", ".svn"), nil
+}
+
+// workspacePath returns the path relative to the workspace, and false if it
+// is ignored.
+func workspacePath(workspaceDir string, ignored []string, p string) (string, bool) {
+	rel, err := filepath.Rel(workspaceDir, p)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, dir := range ignored {
+		if rel == dir || strings.HasPrefix(rel, dir+"/") {
+			return "", false
+		}
+	}
+	return rel, true
+}
+
+// watchTree watches root and the directories below it, and returns the files
+// below it, or root itself if it is a file, relative to the workspace.
+// Symlinks are not followed, so the bazel convenience symlinks are not
+// watched. w.mu must be held.
+func (w *fsnotifyWatcher) watchTree(root string) ([]string, error) {
+	files := []string{}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Files may be removed while they are walked.
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if p != w.workspaceDir {
+			rel, ok := w.relPath(p)
+			if !ok {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.IsDir() {
+				files = append(files, rel)
+				return nil
+			}
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if err := w.watcher.Add(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to watch %s: %w", p, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
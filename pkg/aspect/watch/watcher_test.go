@@ -0,0 +1,145 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"context"
+	"os"
+	"path"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aspect-build/aspect-gazelle/runner/pkg/watchman"
+)
+
+// subscribe starts the watcher and returns the change sets of the
+// subscription, after the initial empty one.
+func subscribe(t *testing.T, w Watcher) <-chan *watchman.ChangeSet {
+	if err := w.Start(); err != nil {
+		t.Fatalf("Failed to start the watcher: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		w.Close()
+	})
+
+	changes := make(chan *watchman.ChangeSet, 10)
+	go func() {
+		defer close(changes)
+		for cs, err := range w.Subscribe(ctx) {
+			if err != nil {
+				return
+			}
+			changes <- cs
+		}
+	}()
+
+	if cs := next(t, changes); len(cs.Paths) != 0 || cs.IsFreshInstance {
+		t.Fatalf("Expected an initial empty change set, got %+v", cs)
+	}
+	return changes
+}
+
+func next(t *testing.T, changes <-chan *watchman.ChangeSet) *watchman.ChangeSet {
+	select {
+	case cs, ok := <-changes:
+		if !ok {
+			t.Fatalf("Expected a change set, the subscription ended")
+		}
+		return cs
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected a change set within 5s")
+	}
+	return nil
+}
+
+func writeFile(t *testing.T, p string) {
+	if err := os.MkdirAll(path.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(p), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFsnotifyWatcher(t *testing.T) {
+	t.Run("reports the changed files relative to the workspace", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, path.Join(dir, "pkg/a.txt"))
+		changes := subscribe(t, newFsnotifyWatcher(dir))
+
+		writeFile(t, path.Join(dir, "pkg/a.txt"))
+		writeFile(t, path.Join(dir, "b.txt"))
+
+		cs := next(t, changes)
+		for !slices.Contains(cs.Paths, "b.txt") {
+			cs = next(t, changes)
+		}
+		if cs.Root != dir {
+			t.Errorf("Expected the root %q, got %q", dir, cs.Root)
+		}
+		if !slices.Equal(cs.Paths, []string{"b.txt", "pkg/a.txt"}) {
+			t.Errorf("Expected b.txt and pkg/a.txt to change, got %v", cs.Paths)
+		}
+	})
+
+	t.Run("reports the files of new directories", func(t *testing.T) {
+		dir := t.TempDir()
+		changes := subscribe(t, newFsnotifyWatcher(dir))
+
+		writeFile(t, path.Join(dir, "new/deep/c.txt"))
+		cs := next(t, changes)
+		for !slices.Contains(cs.Paths, "new/deep/c.txt") {
+			cs = next(t, changes)
+		}
+
+		// The new directories are watched too.
+		writeFile(t, path.Join(dir, "new/deep/d.txt"))
+		cs = next(t, changes)
+		for !slices.Contains(cs.Paths, "new/deep/d.txt") {
+			cs = next(t, changes)
+		}
+	})
+
+	t.Run("skips the ignored directories and the attribute changes", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(path.Join(dir, ".bazelignore"), []byte("node_modules\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, path.Join(dir, "node_modules/pkg/index.js"))
+		writeFile(t, path.Join(dir, "src/main.go"))
+		changes := subscribe(t, newFsnotifyWatcher(dir))
+
+		writeFile(t, path.Join(dir, "node_modules/pkg/index.js"))
+		writeFile(t, path.Join(dir, "node_modules/other/index.js"))
+		writeFile(t, path.Join(dir, ".git/HEAD"))
+		if err := os.Chmod(path.Join(dir, "src/main.go"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, path.Join(dir, "src/last.go"))
+
+		var paths []string
+		for !slices.Contains(paths, "src/last.go") {
+			paths = append(paths, next(t, changes).Paths...)
+		}
+		if !slices.Equal(paths, []string{"src/last.go"}) {
+			t.Errorf("Expected only src/last.go to change, got %v", paths)
+		}
+	})
+}
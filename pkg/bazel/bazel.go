@@ -34,6 +34,7 @@ import (
 
 	"github.com/aspect-build/aspect-cli-legacy/bazel/analysis"
 	"github.com/aspect-build/aspect-cli-legacy/bazel/flags"
+	"github.com/aspect-build/aspect-cli-legacy/bazel/query"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/aspecterrors"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel/workspace"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
@@ -64,6 +65,8 @@ var startupFlags []string
 type Bazel interface {
 	WithEnv(env []string) Bazel
 	AQuery(expr string, bazelFlags []string) (*analysis.ActionGraphContainer, error)
+	Query(expr string, bazelFlags []string) (*query.QueryResult, error)
+	CQuery(expr string, bazelFlags []string) (*analysis.CqueryResult, error)
 	BazelDashDashVersion() (string, error)
 	GetBazelInstallation() (*BazelInstallation, error)
 	BazelFlagsAsProto() ([]byte, error)
@@ -246,6 +249,11 @@ func InitializeStartupFlags(args []string) ([]string, []string, error) {
 	return nonFlags, flags, nil
 }
 
+// StartupFlags returns the start-up flags initialized by InitializeStartupFlags.
+func StartupFlags() []string {
+	return startupFlags
+}
+
 // Flags fetches the metadata for Bazel's command line flag via `bazel help flags-as-proto`
 func (b *bazel) Flags() (map[string]*flags.FlagInfo, error) {
 	if allFlags != nil {
@@ -334,6 +342,34 @@ func (b *bazel) Flags() (map[string]*flags.FlagInfo, error) {
 
 // AQuery runs a `bazel aquery` command and returns the resulting parsed proto data.
 func (b *bazel) AQuery(query string, bazelFlags []string) (*analysis.ActionGraphContainer, error) {
+	agc := &analysis.ActionGraphContainer{}
+	if err := b.protoQuery("aquery", query, bazelFlags, agc); err != nil {
+		return nil, err
+	}
+	return agc, nil
+}
+
+// Query runs a `bazel query` command and returns the resulting parsed proto data.
+func (b *bazel) Query(expr string, bazelFlags []string) (*query.QueryResult, error) {
+	result := &query.QueryResult{}
+	if err := b.protoQuery("query", expr, bazelFlags, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CQuery runs a `bazel cquery` command and returns the resulting parsed proto data.
+func (b *bazel) CQuery(expr string, bazelFlags []string) (*analysis.CqueryResult, error) {
+	result := &analysis.CqueryResult{}
+	if err := b.protoQuery("cquery", expr, bazelFlags, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// protoQuery runs the given query command with --output=proto and parses its
+// output into result.
+func (b *bazel) protoQuery(command string, expr string, bazelFlags []string, result proto.Message) error {
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
 	streams := ioutils.Streams{
@@ -342,46 +378,29 @@ func (b *bazel) AQuery(query string, bazelFlags []string) (*analysis.ActionGraph
 		Stderr: &stderr,
 	}
 
-	bazelErrs := make(chan error, 1)
-	defer close(bazelErrs)
-
-	go func() {
-		cmd := []string{"aquery"}
-		cmd = append(cmd, bazelFlags...)
-		cmd = append(cmd, "--output=proto")
+	cmd := []string{command}
+	cmd = append(cmd, bazelFlags...)
+	cmd = append(cmd, "--output=proto")
 
-		if query != "" {
-			cmd = append(cmd, "--")
-			cmd = append(cmd, query)
-		}
-		err := b.RunCommand(streams, nil, cmd...)
-		bazelErrs <- err
-	}()
-
-	err := <-bazelErrs
+	if expr != "" {
+		cmd = append(cmd, "--")
+		cmd = append(cmd, expr)
+	}
 
-	if err != nil {
+	if err := b.RunCommand(streams, nil, cmd...); err != nil {
 		var exitErr *aspecterrors.ExitError
 		if errors.As(err, &exitErr) {
 			// Dump the `stderr` when Bazel executed and exited non-zero
-			return nil, fmt.Errorf("failed to run aquery: %w\nstderr:\n%s", err, stderr.String())
+			return fmt.Errorf("failed to run %s: %w\nstderr:\n%s", command, err, stderr.String())
 		} else {
-			return nil, fmt.Errorf("failed to run aquery: %w", err)
+			return fmt.Errorf("failed to run %s: %w", command, err)
 		}
 	}
 
-	agc := &analysis.ActionGraphContainer{}
-
-	protoBytes, err := io.ReadAll(&stdout)
-	if err != nil {
-		return nil, fmt.Errorf("failed to run aquery: %w", err)
-	}
-
-	proto.Unmarshal(protoBytes, agc)
-	if err := proto.Unmarshal(protoBytes, agc); err != nil {
-		return nil, fmt.Errorf("failed to run Bazel aquery: parsing ActionGraphContainer: %w", err)
+	if err := proto.Unmarshal(stdout.Bytes(), result); err != nil {
+		return fmt.Errorf("failed to run Bazel %s: parsing %s: %w", command, result.ProtoReflect().Descriptor().Name(), err)
 	}
-	return agc, nil
+	return nil
 }
 
 // Calls `bazel --version` and returns the result
@@ -481,6 +481,45 @@ func SeparateBazelFlags(command string, args []string) ([]string, []string, erro
 	return otherArgs, flagsArgs, nil
 }
 
+// CanonicalizeBazelFlags returns the bazel flags separated by SeparateBazelFlags
+// for the given command in a canonical form: shorthands are replaced by flag
+// names and values are joined to their flag as in '--flag=arg'.
+func CanonicalizeBazelFlags(command string, flagsArgs []string) []string {
+	flags := bazelFlagSets[command]
+	canonical := make([]string, 0, len(flagsArgs))
+
+	for len(flagsArgs) > 0 {
+		s := flagsArgs[0]
+		flagsArgs = flagsArgs[1:]
+		if flags == nil || len(s) < 2 || s[0] != '-' {
+			canonical = append(canonical, s)
+			continue
+		}
+
+		if s[1] != '-' {
+			// short arg
+			if len(s) == 2 {
+				if flag := flags.ShorthandLookup(s[1:]); flag != nil {
+					s = "--" + flag.Name
+				}
+			}
+			canonical = append(canonical, s)
+			continue
+		}
+
+		// long arg
+		name, _, hasArg := strings.Cut(s[2:], "=")
+		if flag := flags.Lookup(name); flag != nil && !hasArg && flag.NoOptDefVal == "" && len(flagsArgs) > 0 {
+			// '--flag arg'
+			s = s + "=" + flagsArgs[0]
+			flagsArgs = flagsArgs[1:]
+		}
+		canonical = append(canonical, s)
+	}
+
+	return canonical
+}
+
 func isExpando(flag string) bool {
 	_, ok := expandoFlags[flag]
 	return ok
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package bazel
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/spf13/pflag"
+
+	rootFlags "github.com/aspect-build/aspect-cli-legacy/pkg/aspect/root/flags"
+)
+
+func TestCanonicalizeBazelFlags(t *testing.T) {
+	flagSet := pflag.NewFlagSet("build", pflag.ContinueOnError)
+	rootFlags.RegisterNoableBoolP(flagSet, "keep_going", "k", false, "")
+	flagSet.StringP("compilation_mode", "c", "", "")
+	flagSet.Var(&rootFlags.MultiString{}, "copt", "")
+	bazelFlagSets["build"] = flagSet
+	t.Cleanup(func() { delete(bazelFlagSets, "build") })
+
+	t.Run("joins flag values and replaces shorthands", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		args := []string{"-k", "--compilation_mode", "opt", "--copt=-O2", "--copt", "-g", "--nokeep_going"}
+		_, flagsArgs, err := SeparateBazelFlags("build", append([]string{"//..."}, args...))
+		g.Expect(err).ToNot(HaveOccurred())
+
+		g.Expect(CanonicalizeBazelFlags("build", flagsArgs)).To(Equal([]string{
+			"--keep_going",
+			"--compilation_mode=opt",
+			"--copt=-O2",
+			"--copt=-g",
+			"--nokeep_going",
+		}))
+	})
+
+	t.Run("leaves flags of unknown commands unchanged", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		g.Expect(CanonicalizeBazelFlags("unknown", []string{"--foo", "bar"})).To(Equal([]string{"--foo", "bar"}))
+	})
+}
@@ -56,6 +56,15 @@ func NewBazelisk(workspaceRoot string, allowReenter bool) *Bazelisk {
 	}
 }
 
+// RequestedVersion returns the Bazel version requested for the workspace at
+// workspaceRoot through USE_BAZEL_VERSION, .bazeliskrc or .bazelversion,
+// without downloading or starting bazel. The version may be a label such as
+// "latest" or "7.x" rather than an exact release.
+func RequestedVersion(workspaceRoot string) (string, error) {
+	version, _, err := NewBazelisk(workspaceRoot, false).getBazelVersionAndUrl()
+	return version, err
+}
+
 // Run runs the main Bazelisk logic for the given arguments and Bazel repositories.
 func (bazelisk *Bazelisk) Run(args []string, repos *core.Repositories, streams ioutils.Streams, env []string, config bazeliskConfig.Config, wd *string) error {
 	httputil.UserAgent = getUserAgent(config)
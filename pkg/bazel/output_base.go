@@ -0,0 +1,121 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package bazel
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// OutputBase returns the output base of the workspace at workspaceRoot without
+// starting the bazel server. It honors the --output_base and --output_user_root
+// start-up flags given on the command line, but not those set in a .bazelrc.
+func OutputBase(workspaceRoot string) (string, error) {
+	if outputBase := startupFlagValue(startupFlags, "output_base"); outputBase != "" {
+		return outputBase, nil
+	}
+
+	outputUserRoot := startupFlagValue(startupFlags, "output_user_root")
+	if outputUserRoot == "" {
+		var err error
+		if outputUserRoot, err = defaultOutputUserRoot(); err != nil {
+			return "", err
+		}
+	}
+
+	// Bazel names the output base after the md5 of the physical path of the
+	// workspace.
+	if resolved, err := filepath.EvalSymlinks(workspaceRoot); err == nil {
+		workspaceRoot = resolved
+	}
+	sum := md5.Sum([]byte(workspaceRoot))
+	return filepath.Join(outputUserRoot, hex.EncodeToString(sum[:])), nil
+}
+
+// workspaceNamePattern matches the name given to the workspace() call of a
+// WORKSPACE file.
+var workspaceNamePattern = regexp.MustCompile(`(?m)^\s*workspace\(\s*name\s*=\s*["']([^"']+)["']`)
+
+// ExecutionRoot returns the execution root of the workspace at workspaceRoot
+// under outputBase without starting the bazel server. The execution root is
+// named _main unless the workspace has no MODULE.bazel and names itself in
+// its WORKSPACE file.
+func ExecutionRoot(workspaceRoot, outputBase string) string {
+	name := "_main"
+	if _, err := os.Stat(filepath.Join(workspaceRoot, "MODULE.bazel")); err != nil {
+		for _, file := range []string{"WORKSPACE.bazel", "WORKSPACE"} {
+			content, err := os.ReadFile(filepath.Join(workspaceRoot, file))
+			if err != nil {
+				continue
+			}
+			if m := workspaceNamePattern.FindSubmatch(content); m != nil {
+				name = string(m[1])
+			}
+			break
+		}
+	}
+	return filepath.Join(outputBase, "execroot", name)
+}
+
+// startupFlagValue returns the value of the last occurrence of a start-up flag
+// given as --name=value or --name value.
+func startupFlagValue(flags []string, name string) string {
+	value := ""
+	for i, flag := range flags {
+		if v, ok := strings.CutPrefix(flag, "--"+name+"="); ok {
+			value = v
+		} else if flag == "--"+name && i+1 < len(flags) {
+			value = flags[i+1]
+		}
+	}
+	return value
+}
+
+// defaultOutputUserRoot returns the output user root bazel uses by default on
+// this platform.
+func defaultOutputUserRoot() (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine the output user root: %w", err)
+	}
+	username := u.Username
+	if i := strings.LastIndex(username, "\\"); i >= 0 {
+		// Windows user names are qualified by their domain.
+		username = username[i+1:]
+	}
+
+	var outputRoot string
+	switch runtime.GOOS {
+	case "darwin":
+		outputRoot = "/private/var/tmp"
+	case "windows":
+		outputRoot = u.HomeDir
+	default:
+		if cacheHome := os.Getenv("XDG_CACHE_HOME"); cacheHome != "" {
+			outputRoot = filepath.Join(cacheHome, "bazel")
+		} else {
+			outputRoot = filepath.Join(u.HomeDir, ".cache", "bazel")
+		}
+	}
+	return filepath.Join(outputRoot, "_bazel_"+username), nil
+}
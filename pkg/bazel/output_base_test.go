@@ -0,0 +1,99 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package bazel
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func withStartupFlags(t *testing.T, flags ...string) {
+	previous := startupFlags
+	startupFlags = flags
+	t.Cleanup(func() { startupFlags = previous })
+}
+
+func TestOutputBase(t *testing.T) {
+	workspaceRoot := t.TempDir()
+	resolved, err := filepath.EvalSymlinks(workspaceRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := md5.Sum([]byte(resolved))
+	hash := hex.EncodeToString(sum[:])
+
+	t.Run("uses the --output_base start-up flag", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		withStartupFlags(t, "--output_base=/tmp/output_base")
+
+		g.Expect(OutputBase(workspaceRoot)).To(Equal("/tmp/output_base"))
+	})
+
+	t.Run("hashes the workspace under the --output_user_root start-up flag", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		withStartupFlags(t, "--output_user_root", "/tmp/root")
+
+		g.Expect(OutputBase(workspaceRoot)).To(Equal(filepath.Join("/tmp/root", hash)))
+	})
+
+	t.Run("hashes the workspace under the default output user root", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		withStartupFlags(t)
+
+		outputBase, err := OutputBase(workspaceRoot)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(filepath.Base(outputBase)).To(Equal(hash))
+		g.Expect(filepath.Base(filepath.Dir(outputBase))).To(HavePrefix("_bazel_"))
+	})
+}
+
+func TestExecutionRoot(t *testing.T) {
+	writeFile := func(t *testing.T, dir, name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("names the execution root _main in a bzlmod workspace", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		workspaceRoot := t.TempDir()
+		writeFile(t, workspaceRoot, "MODULE.bazel", "module(name = \"foo\")\n")
+		writeFile(t, workspaceRoot, "WORKSPACE", "workspace(name = \"bar\")\n")
+
+		g.Expect(ExecutionRoot(workspaceRoot, "/out")).To(Equal(filepath.Join("/out", "execroot", "_main")))
+	})
+
+	t.Run("names the execution root after the WORKSPACE name", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		workspaceRoot := t.TempDir()
+		writeFile(t, workspaceRoot, "WORKSPACE.bazel", "workspace(\n    name = 'bar',\n)\n")
+
+		g.Expect(ExecutionRoot(workspaceRoot, "/out")).To(Equal(filepath.Join("/out", "execroot", "bar")))
+	})
+
+	t.Run("defaults to _main for an unnamed workspace", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		workspaceRoot := t.TempDir()
+		writeFile(t, workspaceRoot, "WORKSPACE", "")
+
+		g.Expect(ExecutionRoot(workspaceRoot, "/out")).To(Equal(filepath.Join("/out", "execroot", "_main")))
+	})
+}
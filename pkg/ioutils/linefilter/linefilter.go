@@ -0,0 +1,134 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package linefilter passes the output of a command through a filter line by
+// line, so that the CLI can rewrite the output of bazel before printing it.
+package linefilter
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+)
+
+// Stream is the stream a line was written to.
+type Stream int
+
+const (
+	Stdout Stream = iota
+	Stderr
+)
+
+// Filter returns the lines printed in place of a line written to stream,
+// without their trailing newlines. The line is suppressed when it returns
+// none.
+type Filter func(stream Stream, line string) []string
+
+type filterKeyType string
+
+const filterKey filterKeyType = "aspect:lineFilter"
+
+// Inject injects the given Filter into the context.
+func Inject(ctx context.Context, filter Filter) context.Context {
+	return context.WithValue(ctx, filterKey, filter)
+}
+
+// FromContext returns the Filter injected into the context, if any.
+func FromContext(ctx context.Context) (Filter, bool) {
+	filter, ok := ctx.Value(filterKey).(Filter)
+	return filter, ok
+}
+
+// Streams returns streams whose stdout and stderr pass each line written to
+// them through the Filter injected into ctx before writing it to the given
+// streams, along with a function that flushes a last line that doesn't end in
+// a newline. The streams are returned as they are when ctx has no Filter.
+func Streams(ctx context.Context, streams ioutils.Streams) (ioutils.Streams, func()) {
+	filter, ok := FromContext(ctx)
+	if !ok {
+		return streams, func() {}
+	}
+	return Apply(streams, filter)
+}
+
+// Apply returns streams whose stdout and stderr pass each line written to them
+// through filter before writing it to the given streams, along with a function
+// that flushes a last line that doesn't end in a newline.
+func Apply(streams ioutils.Streams, filter Filter) (ioutils.Streams, func()) {
+	stdout := &writer{w: streams.Stdout, stream: Stdout, filter: filter}
+	stderr := &writer{w: streams.Stderr, stream: Stderr, filter: filter}
+	filtered := ioutils.Streams{
+		Stdin:  streams.Stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	}
+	return filtered, func() {
+		stdout.flush()
+		stderr.flush()
+	}
+}
+
+// writer buffers the output written to it until it has a complete line.
+type writer struct {
+	mu     sync.Mutex
+	w      io.Writer
+	stream Stream
+	filter Filter
+	buf    []byte
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := string(w.buf[:i])
+		w.buf = w.buf[i+1:]
+		if err := w.writeLines(line); err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (w *writer) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		line := string(w.buf)
+		w.buf = nil
+		w.writeLines(line)
+	}
+}
+
+func (w *writer) writeLines(line string) error {
+	var out []byte
+	for _, l := range w.filter(w.stream, line) {
+		out = append(append(out, l...), '\n')
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	_, err := w.w.Write(out)
+	return err
+}
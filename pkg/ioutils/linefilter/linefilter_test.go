@@ -0,0 +1,67 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package linefilter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+)
+
+func TestStreams(t *testing.T) {
+	t.Run("returns the streams as they are without a filter", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var stdout strings.Builder
+		streams := ioutils.Streams{Stdout: &stdout, Stderr: &stdout}
+
+		filtered, flush := Streams(context.Background(), streams)
+		flush()
+
+		g.Expect(filtered).To(Equal(streams))
+	})
+
+	t.Run("passes each line through the filter", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var stdout, stderr strings.Builder
+		ctx := Inject(context.Background(), func(stream Stream, line string) []string {
+			switch {
+			case strings.HasPrefix(line, "WARNING:"):
+				return nil
+			case stream == Stderr:
+				return []string{"err: " + line}
+			case strings.HasPrefix(line, "ERROR:"):
+				return []string{line, "  see https://example.com"}
+			}
+			return []string{line}
+		})
+
+		filtered, flush := Streams(ctx, ioutils.Streams{Stdout: &stdout, Stderr: &stderr})
+		fmt.Fprint(filtered.Stdout, "INFO: Analyzed\nWARNING: noisy\nERR")
+		fmt.Fprint(filtered.Stdout, "OR: failed\nlast")
+		fmt.Fprintln(filtered.Stderr, "Loading")
+		flush()
+
+		g.Expect(stdout.String()).To(Equal("INFO: Analyzed\nERROR: failed\n  see https://example.com\nlast\n"))
+		g.Expect(stderr.String()).To(Equal("err: Loading\n"))
+	})
+}
@@ -13,15 +13,30 @@
  * See the License for the specific language governing permissions and
  * limitations under the License.
  */
-
 package prompt
 
-import "github.com/manifoldco/promptui"
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/manifoldco/promptui"
+)
 
 // PromptRunner is the interface that wraps the promptui.Prompt and makes a call
 // to it from the aspect CLI Core.
 type PromptRunner interface {
 	Run(prompt promptui.Prompt) (string, error)
+	// Select asks the user to choose one of items and returns its index.
+	Select(label string, items []string, defaultIndex int) (int, error)
+	// MultiSelect asks the user to choose any number of items and returns their
+	// indices in ascending order.
+	MultiSelect(label string, items []string, selected []int) ([]int, error)
+	// Secret asks for input that is not echoed, such as a password or a token.
+	Secret(label string) (string, error)
+	// Confirm asks a yes or no question. defaultYes is the answer when the user
+	// only presses <Enter>.
+	Confirm(label string, defaultYes bool) (bool, error)
 }
 
 // promptRunner implements a default PromptRunner.
@@ -36,3 +51,95 @@ func NewPromptRunner() PromptRunner {
 func (pr *promptRunner) Run(prompt promptui.Prompt) (string, error) {
 	return prompt.Run()
 }
+
+// Select runs a promptui.Select over items.
+func (pr *promptRunner) Select(label string, items []string, defaultIndex int) (int, error) {
+	if len(items) == 0 {
+		return 0, fmt.Errorf("%s: no items to select from", label)
+	}
+	prompt := promptui.Select{
+		Label:     label,
+		Items:     items,
+		CursorPos: max(0, min(defaultIndex, len(items)-1)),
+	}
+	i, _, err := prompt.Run()
+	return i, err
+}
+
+// multiSelectDone is the last item of a multi-select prompt, which the user
+// chooses to confirm the selection.
+const multiSelectDone = "Done"
+
+// MultiSelect runs a promptui.Select in which choosing an item toggles it,
+// until the user chooses multiSelectDone.
+func (pr *promptRunner) MultiSelect(label string, items []string, selected []int) ([]int, error) {
+	selected = slices.Clone(selected)
+	cursor := 0
+	for {
+		prompt := promptui.Select{
+			Label:        label,
+			Items:        multiSelectItems(items, selected),
+			CursorPos:    cursor,
+			HideSelected: true,
+		}
+		i, _, err := prompt.Run()
+		if err != nil {
+			return nil, err
+		}
+		if i == len(items) {
+			slices.Sort(selected)
+			return selected, nil
+		}
+		selected = toggle(selected, i)
+		cursor = i
+	}
+}
+
+// multiSelectItems returns the items of a multi-select prompt, each marked
+// with whether it is selected, followed by multiSelectDone.
+func multiSelectItems(items []string, selected []int) []string {
+	marked := make([]string, 0, len(items)+1)
+	for i, item := range items {
+		if slices.Contains(selected, i) {
+			marked = append(marked, "[x] "+item)
+		} else {
+			marked = append(marked, "[ ] "+item)
+		}
+	}
+	return append(marked, multiSelectDone)
+}
+
+// toggle adds i to the selected indices, or removes it if it is selected
+// already.
+func toggle(selected []int, i int) []int {
+	if j := slices.Index(selected, i); j >= 0 {
+		return slices.Delete(selected, j, j+1)
+	}
+	return append(selected, i)
+}
+
+// Secret runs a prompt that masks the input and hides it once entered.
+func (pr *promptRunner) Secret(label string) (string, error) {
+	prompt := promptui.Prompt{
+		Label:       label,
+		Mask:        '*',
+		HideEntered: true,
+	}
+	return prompt.Run()
+}
+
+// Confirm runs a confirmation prompt. Answering no is not an error.
+func (pr *promptRunner) Confirm(label string, defaultYes bool) (bool, error) {
+	prompt := promptui.Prompt{
+		Label:     label,
+		IsConfirm: true,
+	}
+	if defaultYes {
+		prompt.Default = "y"
+	}
+	_, err := prompt.Run()
+	if errors.Is(err, promptui.ErrAbort) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,43 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package client
+
+import (
+	"github.com/aspect-build/aspect-cli-legacy/bazel/analysis"
+	"github.com/aspect-build/aspect-cli-legacy/bazel/query"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel"
+	v1alpha5plugin "github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha5/plugin"
+)
+
+// pluginBazel runs the queries of a v1alpha5 plugin with the bazel of the
+// workspace the CLI runs in.
+type pluginBazel struct {
+	bzl bazel.Bazel
+}
+
+var _ v1alpha5plugin.Bazel = (*pluginBazel)(nil)
+
+func newPluginBazel(bzl bazel.Bazel) *pluginBazel {
+	return &pluginBazel{bzl: bzl}
+}
+
+func (b *pluginBazel) Query(expression string, flags ...string) (*query.QueryResult, error) {
+	return b.bzl.Query(expression, flags)
+}
+
+func (b *pluginBazel) CQuery(expression string, flags ...string) (*analysis.CqueryResult, error) {
+	return b.bzl.CQuery(expression, flags)
+}
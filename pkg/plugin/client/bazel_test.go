@@ -0,0 +1,61 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package client
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/aspect-build/aspect-cli-legacy/bazel/analysis"
+	"github.com/aspect-build/aspect-cli-legacy/bazel/query"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel"
+)
+
+// queryRecorder records the queries run through the bazel wrapper.
+type queryRecorder struct {
+	bazel.Bazel
+	queries [][]string
+}
+
+func (r *queryRecorder) Query(expr string, bazelFlags []string) (*query.QueryResult, error) {
+	r.queries = append(r.queries, append([]string{"query", expr}, bazelFlags...))
+	return &query.QueryResult{}, nil
+}
+
+func (r *queryRecorder) CQuery(expr string, bazelFlags []string) (*analysis.CqueryResult, error) {
+	r.queries = append(r.queries, append([]string{"cquery", expr}, bazelFlags...))
+	return &analysis.CqueryResult{}, nil
+}
+
+func TestPluginBazel(t *testing.T) {
+	t.Run("runs the queries of the plugin with the bazel wrapper", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		recorder := &queryRecorder{}
+		b := newPluginBazel(recorder)
+
+		_, err := b.Query("deps(//foo)")
+		g.Expect(err).ToNot(HaveOccurred())
+		_, err = b.CQuery("//foo", "--config=release", "--keep_going")
+		g.Expect(err).ToNot(HaveOccurred())
+
+		g.Expect(recorder.queries).To(Equal([][]string{
+			{"query", "deps(//foo)"},
+			{"cquery", "//foo", "--config=release", "--keep_going"},
+		}))
+	})
+}
@@ -17,23 +17,32 @@
 package client
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"hash"
 	"io"
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
 	hclog "github.com/hashicorp/go-hclog"
 	goplugin "github.com/hashicorp/go-plugin"
 
+	"github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/config"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/plugin"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/proto"
+	v1alpha5config "github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha5/config"
+	v1alpha5plugin "github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha5/plugin"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/types"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/telemetry"
 )
 
 // A Factory class for constructing plugin instances.
@@ -42,7 +51,9 @@ type Factory interface {
 }
 
 func NewFactory() Factory {
-	return &clientFactory{}
+	return &clientFactory{
+		lock: NewWorkspaceLock(),
+	}
 }
 
 // CustomCommandExecutor requires the Plugin implementations to provide the
@@ -53,32 +64,81 @@ type CustomCommandExecutor interface {
 }
 
 type clientFactory struct {
+	lock *Lock
 }
 
 // New calls the goplugin.NewClient with the given config.
 func (c *clientFactory) New(aspectplugin types.PluginConfig, streams ioutils.Streams) (*PluginInstance, error) {
-	logLevel := hclog.LevelFromString(aspectplugin.LogLevel)
-	if logLevel == hclog.NoLevel {
-		logLevel = hclog.Warn
+	resolved, err := Resolve(aspectplugin, c.lock)
+	if errors.Is(err, ErrPluginNotFound) {
+		newPluginLogger(aspectplugin).Warn(fmt.Sprintf("skipping install for plugin: %v.", err))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := truncatePluginLog(aspectplugin.Name); err != nil {
+		newPluginLogger(aspectplugin).Warn(err.Error())
 	}
-	pluginLogger := hclog.New(&hclog.LoggerOptions{
-		Name:  aspectplugin.Name,
-		Level: logLevel,
-	})
+
+	launch := func() (*PluginInstance, error) {
+		return Launch(resolved, streams)
+	}
+	instance, err := launch()
+	if err != nil {
+		return nil, err
+	}
+	return withRestartPolicy(aspectplugin, instance, launch), nil
+}
+
+// ErrPluginNotFound is returned by Resolve when a local plugin doesn't exist.
+var ErrPluginNotFound = errors.New("plugin does not exist")
+
+// Versions of the plugin SDK a plugin may be launched with.
+const (
+	SDKv1alpha4 = "v1alpha4"
+	SDKv1alpha5 = "v1alpha5"
+	// SDKStdio is the newline-delimited JSON protocol of stdio plugins.
+	SDKStdio = "stdio"
+)
+
+// ResolvedPlugin is a plugin binary on the local disk whose checksum has been
+// verified.
+type ResolvedPlugin struct {
+	// Config is the plugin config with From set to the path of the binary.
+	Config   types.PluginConfig
+	Checksum []byte
+}
+
+// Resolve downloads a remote plugin and verifies the checksum of the plugin
+// binary against the one published next to it, pinned in the config and
+// recorded in the lockfile. The lockfile is skipped when lock is nil.
+func Resolve(aspectplugin types.PluginConfig, lock *Lock) (*ResolvedPlugin, error) {
+	pluginLogger := newPluginLogger(aspectplugin)
 
 	var checksum []byte
-	var hash hash.Hash
-	if strings.HasPrefix(aspectplugin.From, "github.com/") {
-		// Syntax sugar:
-		//   from: github.com/org/repo
-		// is the same as
-		//   from: https://github.com/org/repo/releases/download
-		// Example release URL:
-		//   https://github.com/aspect-build/aspect-cli-plugin-template/releases/download/v0.1.0/plugin-plugin-linux_amd64
-		aspectplugin.From = fmt.Sprintf("https://%s/releases/download", aspectplugin.From)
+
+	aspectplugin.From = ResolvePluginURL(aspectplugin.From)
+	if isWasmPlugin(aspectplugin.From) {
+		aspectplugin.Runtime = types.RuntimeWasm
 	}
 
-	if strings.HasPrefix(aspectplugin.From, "http://") || strings.HasPrefix(aspectplugin.From, "https://") {
+	remote := IsRemotePlugin(aspectplugin.From)
+	resolvedURL := aspectplugin.From
+
+	if remote && IsOCIPlugin(aspectplugin.From) {
+		// The tag of the image defaults to the version:
+		//   from:    oci://ghcr.io/org/plugin
+		//   version: v1.2.3
+		pluginLogger.Info(fmt.Sprintf("pulling %s plugin from %s", aspectplugin.Name, aspectplugin.From))
+
+		pulledPath, err := PullOCIPlugin(aspectplugin.From, aspectplugin.Name, aspectplugin.Version, aspectplugin.Runtime)
+		if err != nil {
+			return nil, err
+		}
+		aspectplugin.From = pulledPath
+	} else if remote {
 		// Example release URL:
 		//   from:          https://static.aspect.build/aspect
 		//   versioned url: https://static.aspect.build/aspect/1.2.3/foo-darwin_amd64
@@ -86,33 +146,40 @@ func (c *clientFactory) New(aspectplugin types.PluginConfig, streams ioutils.Str
 			return nil, fmt.Errorf("cannot download plugin %q: the version field is required", aspectplugin.Name)
 		}
 
+		// The binary is taken from the shared plugin store when the checksum
+		// pinned in the config or locked in the workspace is already there.
+		platform, err := PluginPlatform(aspectplugin.Runtime)
+		if err != nil {
+			return nil, err
+		}
+		expectedSHA256 := aspectplugin.SHA256[platform]
+		if expectedSHA256 == "" && lock != nil {
+			if expectedSHA256, err = lock.Locked(aspectplugin.Name, resolvedURL, aspectplugin.Version, platform); err != nil {
+				return nil, err
+			}
+		}
+
 		pluginLogger.Info(fmt.Sprintf("downloading %s plugin from %s", aspectplugin.Name, aspectplugin.From))
 
-		downloadedPath, err := DownloadPlugin(aspectplugin.From, aspectplugin.Name, aspectplugin.Version)
+		downloadedPath, err := DownloadPlugin(aspectplugin.From, aspectplugin.Name, aspectplugin.Version, aspectplugin.Runtime, expectedSHA256)
 		if err != nil {
 			return nil, err
 		}
 		aspectplugin.From = downloadedPath
 	} else if _, err := os.Stat(aspectplugin.From); err != nil {
-		pluginLogger.Warn(fmt.Sprintf("skipping install for plugin: does not exist at path %q.", aspectplugin.From))
-		return nil, nil
+		return nil, fmt.Errorf("%w at path %q", ErrPluginNotFound, aspectplugin.From)
+	}
+
+	digest, err := fileDigest(aspectplugin.From)
+	if err != nil {
+		return nil, err
 	}
 
 	checksumFile := fmt.Sprintf("%s.sha256", aspectplugin.From)
-	hash = sha256.New()
 
 	if _, err := os.Stat(checksumFile); err != nil {
 		// We calculate the hashsum in case it was not provided by the remote server.
-		f, err := os.Open(aspectplugin.From)
-		if err != nil {
-			return nil, fmt.Errorf("failed to calculate hash for %q: %w", aspectplugin.From, err)
-		}
-		defer f.Close()
-		if _, err := io.Copy(hash, f); err != nil {
-			return nil, fmt.Errorf("failed to calculate hash for %q: %w", aspectplugin.From, err)
-		}
-		checksum = hash.Sum(nil)
-		hash.Reset()
+		checksum = digest
 		if err := os.WriteFile(checksumFile, []byte(hex.EncodeToString(checksum)), 0400); err != nil {
 			return nil, fmt.Errorf("failed to calculate hash for %q: %w", aspectplugin.From, err)
 		}
@@ -128,19 +195,111 @@ func (c *clientFactory) New(aspectplugin types.PluginConfig, streams ioutils.Str
 		checksum = decoded
 	}
 
+	// A checksum pinned in the config, e.g. by `aspect plugin install`, takes
+	// precedence over the one published next to the binary so that a tampered
+	// download is refused.
+	if len(aspectplugin.SHA256) > 0 {
+		platform, err := PluginPlatform(aspectplugin.Runtime)
+		if err != nil {
+			return nil, err
+		}
+		pinned, ok := aspectplugin.SHA256[platform]
+		if !ok {
+			return nil, fmt.Errorf("plugin %q does not pin a sha256 for platform %s", aspectplugin.Name, platform)
+		}
+		checksum, err = hex.DecodeString(pinned)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sha256 pinned for plugin %q: %w", aspectplugin.Name, err)
+		}
+	}
+
+	if !bytes.Equal(digest, checksum) {
+		return nil, fmt.Errorf("checksum mismatch for plugin %q: %s has sha256 %x, expected %x", aspectplugin.Name, aspectplugin.From, digest, checksum)
+	}
+
+	// Remote plugins are pinned in the workspace lockfile so that every developer
+	// runs the same binary. Local plugins are typically built from source and
+	// change too often to be locked.
+	if remote && lock != nil {
+		platform, err := PluginPlatform(aspectplugin.Runtime)
+		if err != nil {
+			return nil, err
+		}
+		if err := lock.Verify(aspectplugin.Name, resolvedURL, aspectplugin.Version, platform, hex.EncodeToString(digest)); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ResolvedPlugin{
+		Config:   aspectplugin,
+		Checksum: checksum,
+	}, nil
+}
+
+// Launch starts a resolved plugin, performing the go-plugin handshake for
+// subprocess plugins, instantiating the module of WebAssembly plugins or
+// running the executable of stdio plugins.
+func Launch(resolved *ResolvedPlugin, streams ioutils.Streams) (*PluginInstance, error) {
+	aspectplugin := resolved.Config
+	pluginLogger := newPluginLogger(aspectplugin)
+
 	pluginLogger.Info(fmt.Sprintf("running %s plugin from %s", aspectplugin.Name, aspectplugin.From))
 
+	// A plugin that can't be logged still runs.
+	var logWriter io.Writer
+	logFile, err := openPluginLog(aspectplugin)
+	if err != nil {
+		pluginLogger.Warn(fmt.Sprintf("not logging the output of %s plugin: %v", aspectplugin.Name, err))
+	} else if logFile != nil {
+		logWriter = logFile
+	}
+	streams = pluginStreams(aspectplugin, streams, logWriter)
+
+	var instance *PluginInstance
+	switch aspectplugin.Runtime {
+	case types.RuntimeWasm:
+		instance, err = newWasmPluginInstance(aspectplugin, streams)
+	case types.RuntimeStdio:
+		instance, err = newStdioPluginInstance(aspectplugin, streams)
+	default:
+		instance, err = newSubprocessPluginInstance(resolved, streams, logWriter, pluginLogger)
+	}
+	if err != nil {
+		if logFile != nil {
+			logFile.Close()
+		}
+		return nil, err
+	}
+	if logFile != nil {
+		instance.closers = append(instance.closers, logFile)
+	}
+	return instance, nil
+}
+
+// newSubprocessPluginInstance runs the plugin binary and performs the go-plugin
+// handshake. The raw stderr of the process, which holds its logs and crash
+// output, is written to stderr.
+func newSubprocessPluginInstance(resolved *ResolvedPlugin, streams ioutils.Streams, stderr io.Writer, pluginLogger hclog.Logger) (*PluginInstance, error) {
+	aspectplugin := resolved.Config
+
+	// go-plugin verifies the checksum again right before running the binary.
 	secureConfig := &goplugin.SecureConfig{
-		Checksum: checksum,
-		Hash:     hash,
+		Checksum: resolved.Checksum,
+		Hash:     sha256.New(),
 	}
 	clientConfig := &goplugin.ClientConfig{
-		HandshakeConfig:  config.Handshake,
-		Plugins:          config.PluginMap,
-		Cmd:              exec.Command(aspectplugin.From),
+		HandshakeConfig: config.Handshake,
+		// The plugin picks the most recent SDK version it supports.
+		VersionedPlugins: map[int]goplugin.PluginSet{
+			int(config.Handshake.ProtocolVersion):         config.PluginMap,
+			int(v1alpha5config.Handshake.ProtocolVersion): v1alpha5config.PluginMap,
+		},
+		Cmd:              pluginCmd(aspectplugin),
+		SkipHostEnv:      true,
 		AllowedProtocols: []goplugin.Protocol{goplugin.ProtocolGRPC},
 		SyncStdout:       streams.Stdout,
 		SyncStderr:       streams.Stderr,
+		Stderr:           stderr,
 		Logger:           pluginLogger,
 		SecureConfig:     secureConfig,
 	}
@@ -154,23 +313,111 @@ func (c *clientFactory) New(aspectplugin types.PluginConfig, streams ioutils.Str
 
 	rawplugin, err := rpcClient.Dispense(config.DefaultPluginName)
 	if err != nil {
+		goclient.Kill()
 		return nil, fmt.Errorf("failed to dispense plugin client: %w", err)
 	}
 
 	res := &PluginInstance{
-		Plugin:           rawplugin.(plugin.Plugin),
-		Provider:         goclient,
-		MultiThreaded:    aspectplugin.MultiThreadedBuildEvents,
-		DisableBESEvents: aspectplugin.DisableBESEvents,
+		Plugin:               rawplugin.(plugin.Plugin),
+		Name:                 aspectplugin.Name,
+		SDK:                  SDKv1alpha4,
+		Provider:             goclient,
+		MultiThreaded:        aspectplugin.MultiThreadedBuildEvents,
+		DisableBESEvents:     aspectplugin.DisableBESEvents,
+		TeardownTimeout:      aspectplugin.TeardownTimeout,
+		AllowCommandOverride: aspectplugin.AllowCommandOverride,
+	}
+	res.setBuildEventDelivery(aspectplugin)
+
+	// Build events are streamed to v1alpha5 plugins in order, which apply their
+	// own flow control.
+	if goclient.NegotiatedVersion() == int(v1alpha5config.Handshake.ProtocolVersion) {
+		res.SDK = SDKv1alpha5
+		res.MultiThreaded = false
 	}
 
 	if customCommandExecutor, ok := rawplugin.(CustomCommandExecutor); ok {
 		res.CustomCommandExecutor = customCommandExecutor
 	}
 
+	// v1alpha5 plugins may report metrics into the telemetry session.
+	if telemetryPlugin, ok := rawplugin.(interface {
+		StartTelemetry(v1alpha5plugin.Telemetry) error
+	}); ok {
+		pluginTelemetry := telemetry.NewPluginTelemetry(aspectplugin.Name)
+		if err := telemetryPlugin.StartTelemetry(pluginTelemetry); err != nil {
+			pluginLogger.Warn(fmt.Sprintf("not recording the metrics of %s plugin: %v", aspectplugin.Name, err))
+		} else {
+			res.closers = append(res.closers, pluginTelemetry)
+		}
+	}
+
+	// v1alpha5 plugins may run bazel queries through the CLI.
+	if bazelPlugin, ok := rawplugin.(interface {
+		StartBazel(v1alpha5plugin.Bazel) error
+	}); ok {
+		if err := bazelPlugin.StartBazel(newPluginBazel(bazel.WorkspaceFromWd)); err != nil {
+			pluginLogger.Warn(fmt.Sprintf("not running the queries of %s plugin: %v", aspectplugin.Name, err))
+		}
+	}
+
 	return res, nil
 }
 
+// pluginCmd returns the command running a subprocess plugin, with the
+// environment allowed by its config.
+func pluginCmd(aspectplugin types.PluginConfig) *exec.Cmd {
+	cmd := exec.Command(aspectplugin.From)
+	cmd.Env = PluginEnv(aspectplugin, os.Environ())
+	return cmd
+}
+
+func newPluginLogger(aspectplugin types.PluginConfig) hclog.Logger {
+	logLevel := hclog.LevelFromString(aspectplugin.LogLevel)
+	if logLevel == hclog.NoLevel {
+		logLevel = hclog.Warn
+	}
+	return hclog.New(&hclog.LoggerOptions{
+		Name:  aspectplugin.Name,
+		Level: logLevel,
+	})
+}
+
+func newWasmPluginInstance(aspectplugin types.PluginConfig, streams ioutils.Streams) (*PluginInstance, error) {
+	wasmplugin, err := newWasmPlugin(aspectplugin.Name, aspectplugin.From, PluginEnv(aspectplugin, os.Environ()), streams)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &PluginInstance{
+		Plugin:                wasmplugin,
+		Name:                  aspectplugin.Name,
+		SDK:                   SDKv1alpha4,
+		Provider:              wasmplugin,
+		CustomCommandExecutor: wasmplugin,
+		MultiThreaded:         aspectplugin.MultiThreadedBuildEvents,
+		DisableBESEvents:      aspectplugin.DisableBESEvents,
+		TeardownTimeout:       aspectplugin.TeardownTimeout,
+		AllowCommandOverride:  aspectplugin.AllowCommandOverride,
+	}
+	res.setBuildEventDelivery(aspectplugin)
+	return res, nil
+}
+
+// fileDigest returns the sha256 of the file at the given path.
+func fileDigest(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate hash for %q: %w", path, err)
+	}
+	defer f.Close()
+	digest := sha256.New()
+	if _, err := io.Copy(digest, f); err != nil {
+		return nil, fmt.Errorf("failed to calculate hash for %q: %w", path, err)
+	}
+	return digest.Sum(nil), nil
+}
+
 // Provider is an interface for goplugin.Client returned by
 // goplugin.NewClient.
 type Provider interface {
@@ -182,10 +429,77 @@ type Provider interface {
 // as any associated objects or metadata.
 type PluginInstance struct {
 	plugin.Plugin
-	MultiThreaded    bool
-	DisableBESEvents bool
+	Name string
+	// SDK is the version of the plugin SDK the plugin was launched with, e.g.
+	// v1alpha5.
+	SDK           string
+	MultiThreaded bool
+	// OrderedBuildEvents delivers build events to a multi-threaded plugin one
+	// at a time in order, otherwise from BuildEventWorkers workers.
+	OrderedBuildEvents bool
+	BuildEventWorkers  int
+	// BuildEventQueueSize and BuildEventQueuePolicy bound the build events
+	// queued for a multi-threaded plugin.
+	BuildEventQueueSize   int
+	BuildEventQueuePolicy string
+	DisableBESEvents      bool
+	TeardownTimeout       time.Duration
+	// AllowCommandOverride lets the custom commands of the plugin override
+	// built-in commands.
+	AllowCommandOverride bool
 	Provider
 	CustomCommandExecutor
+
+	// closers are closed once the plugin is killed, e.g. its log file.
+	closers []io.Closer
+}
+
+// setBuildEventDelivery sets how build events are delivered to the plugin when
+// they are multi-threaded.
+func (p *PluginInstance) setBuildEventDelivery(aspectplugin types.PluginConfig) {
+	p.OrderedBuildEvents = aspectplugin.BuildEventDelivery != types.BuildEventDeliveryUnordered
+	p.BuildEventWorkers = aspectplugin.BuildEventWorkers
+	p.BuildEventQueueSize = aspectplugin.BuildEventQueueSize
+	p.BuildEventQueuePolicy = aspectplugin.BuildEventQueuePolicy
+}
+
+// BEPStreamEventCallback passes a build event to the plugin along with the
+// stream it belongs to, or with only its invocation ID when the client of the
+// plugin doesn't pass the stream.
+func (p *PluginInstance) BEPStreamEventCallback(event *buildeventstream.BuildEvent, sn int64, stream *proto.BEPStream) error {
+	if streamCallback, ok := p.Plugin.(plugin.BEPStreamCallback); ok {
+		return streamCallback.BEPStreamEventCallback(event, sn, stream)
+	}
+	return p.Plugin.BEPEventCallback(event, sn, stream.GetInvocationId())
+}
+
+// Kill stops the plugin and closes its log file and telemetry.
+func (p *PluginInstance) Kill() {
+	p.Provider.Kill()
+	for _, closer := range p.closers {
+		closer.Close()
+	}
+}
+
+// ForceKill kills the plugin process with SIGKILL, for plugins that don't exit
+// when killed gracefully. WebAssembly plugins run in-process and are stopped
+// by Kill.
+func (p *PluginInstance) ForceKill() {
+	if forceKiller, ok := p.Provider.(interface{ ForceKill() }); ok {
+		forceKiller.ForceKill()
+		return
+	}
+	goclient, ok := p.Provider.(*goplugin.Client)
+	if !ok || goclient.Exited() {
+		return
+	}
+	reattach := goclient.ReattachConfig()
+	if reattach == nil || reattach.Pid == 0 {
+		return
+	}
+	if process, err := os.FindProcess(reattach.Pid); err == nil {
+		process.Kill()
+	}
 }
 
 // NoOpHash is a hash.Hash that does nothing. It's used for plugins that are
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bgentry/go-netrc/netrc"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel/workspace"
+)
+
+// CredentialHelperKey is the key of the Aspect CLI config that names the
+// credential helper to authenticate plugin downloads with. Paths starting with
+// %workspace% are relative to the workspace root.
+const CredentialHelperKey = "plugin_credential_helper"
+
+// credentialHelperTimeout bounds how long a credential helper may take to
+// return credentials, since git credential helpers may wait for the user.
+const credentialHelperTimeout = 30 * time.Second
+
+// downloadCredentials returns the headers that authenticate a download of the
+// URL, from the first of the configured credential helper, the .netrc file and
+// the git credential helpers that has credentials for it. It returns nil when
+// none do.
+func downloadCredentials(rawURL string) (http.Header, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if helper := viper.GetString(CredentialHelperKey); helper != "" {
+		headers, err := credentialHelperHeaders(helper, rawURL)
+		if err != nil {
+			return nil, err
+		}
+		if len(headers) > 0 {
+			return headers, nil
+		}
+	}
+
+	if headers := netrcHeaders(u); headers != nil {
+		return headers, nil
+	}
+
+	return gitCredentialHeaders(u), nil
+}
+
+// credentialHelperHeaders runs a credential helper that implements the bazel
+// credential helper protocol, so that the helper configured for bazel with
+// --credential_helper can be reused:
+// https://github.com/EngFlow/credential-helper-spec/blob/main/spec.md
+func credentialHelperHeaders(helper string, rawURL string) (http.Header, error) {
+	if rest, ok := strings.CutPrefix(helper, "%workspace%"); ok {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		workspaceRoot, err := workspace.DefaultFinder.Find(wd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s %q: %w", CredentialHelperKey, helper, err)
+		}
+		helper = filepath.Join(workspaceRoot, rest)
+	}
+
+	req, err := json.Marshal(map[string]string{"uri": rawURL})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), credentialHelperTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, helper, "get")
+	cmd.Stdin = bytes.NewReader(req)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("credential helper %s failed for %s: %w", helper, rawURL, err)
+	}
+
+	var res struct {
+		Headers map[string][]string `json:"headers"`
+	}
+	if err := json.Unmarshal(out, &res); err != nil {
+		return nil, fmt.Errorf("credential helper %s returned invalid credentials for %s: %w", helper, rawURL, err)
+	}
+
+	headers := http.Header{}
+	for name, values := range res.Headers {
+		for _, value := range values {
+			headers.Add(name, value)
+		}
+	}
+	return headers, nil
+}
+
+// netrcHeaders returns basic auth credentials for the host of the URL from the
+// file named by $NETRC or ~/.netrc.
+func netrcHeaders(u *url.URL) http.Header {
+	file := os.Getenv("NETRC")
+	if file == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return nil
+		}
+		file = filepath.Join(home, ".netrc")
+	}
+
+	n, err := netrc.ParseFile(file)
+	if err != nil {
+		return nil
+	}
+	m := n.FindMachine(u.Hostname())
+	if m == nil || m.Login == "" {
+		return nil
+	}
+	return basicAuthHeaders(m.Login, m.Password)
+}
+
+// gitCredentialHeaders returns basic auth credentials for the URL from the git
+// credential helpers, e.g. the ones set up by 'gh auth setup-git'. Git is
+// never allowed to prompt for credentials.
+func gitCredentialHeaders(u *url.URL) http.Header {
+	git, err := exec.LookPath("git")
+	if err != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), credentialHelperTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, git, "credential", "fill")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("protocol=%s\nhost=%s\npath=%s\n\n", u.Scheme, u.Host, strings.TrimPrefix(u.Path, "/")))
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=never")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	var username, password string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), "=")
+		switch key {
+		case "username":
+			username = value
+		case "password":
+			password = value
+		}
+	}
+	if password == "" {
+		return nil
+	}
+	return basicAuthHeaders(username, password)
+}
+
+func basicAuthHeaders(username string, password string) http.Header {
+	token := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return http.Header{"Authorization": []string{"Basic " + token}}
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package client
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+)
+
+// newPrivateServer serves a plugin that is reported missing unless the request
+// has basic auth credentials user:token.
+func newPrivateServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if username, password, ok := req.BasicAuth(); !ok || username != "user" || password != "token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("plugin binary"))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// setupCredentialsTest isolates the test from the credentials of the user.
+func setupCredentialsTest(t *testing.T) string {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("NETRC", filepath.Join(dir, "netrc"))
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, "gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	return dir
+}
+
+func readBody(g *WithT, res *http.Response, err error) string {
+	g.Expect(err).ToNot(HaveOccurred())
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	g.Expect(err).ToNot(HaveOccurred())
+	return string(b)
+}
+
+func TestGetWithCredentials(t *testing.T) {
+	t.Run("fails without credentials", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setupCredentialsTest(t)
+		server := newPrivateServer(t)
+
+		_, err := getWithCredentials(server.URL + "/plugin")
+		g.Expect(err).To(MatchError(ContainSubstring("failed with error 404")))
+	})
+
+	t.Run("retries with the credentials from .netrc", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		dir := setupCredentialsTest(t)
+		server := newPrivateServer(t)
+		netrc := "machine 127.0.0.1 login user password token\n"
+		g.Expect(os.WriteFile(filepath.Join(dir, "netrc"), []byte(netrc), 0600)).To(Succeed())
+
+		res, err := getWithCredentials(server.URL + "/plugin")
+		g.Expect(readBody(g, res, err)).To(Equal("plugin binary"))
+	})
+
+	t.Run("retries with the headers from the configured credential helper", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		dir := setupCredentialsTest(t)
+		server := newPrivateServer(t)
+		helper := filepath.Join(dir, "helper.sh")
+		script := "#!/bin/sh\ncat > /dev/null\necho '{\"headers\":{\"Authorization\":[\"Basic dXNlcjp0b2tlbg==\"]}}'\n"
+		g.Expect(os.WriteFile(helper, []byte(script), 0755)).To(Succeed())
+		viper.Set(CredentialHelperKey, helper)
+		t.Cleanup(func() { viper.Set(CredentialHelperKey, "") })
+
+		res, err := getWithCredentials(server.URL + "/plugin")
+		g.Expect(readBody(g, res, err)).To(Equal("plugin binary"))
+	})
+
+	t.Run("retries with the credentials from git", func(t *testing.T) {
+		if _, err := exec.LookPath("git"); err != nil {
+			t.Skip("git is not installed")
+		}
+		g := NewGomegaWithT(t)
+		dir := setupCredentialsTest(t)
+		server := newPrivateServer(t)
+		gitconfig := "[credential]\n\thelper = \"!f() { echo username=user; echo password=token; }; f\"\n"
+		g.Expect(os.WriteFile(filepath.Join(dir, "gitconfig"), []byte(gitconfig), 0600)).To(Succeed())
+
+		res, err := getWithCredentials(server.URL + "/plugin")
+		g.Expect(readBody(g, res, err)).To(Equal("plugin binary"))
+	})
+}
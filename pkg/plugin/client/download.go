@@ -19,52 +19,154 @@
 package client
 
 import (
+	"encoding/hex"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
-	"path/filepath"
 	"runtime"
+	"strings"
 
-	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils/cache"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/types"
 	"github.com/bazelbuild/bazelisk/config"
 	"github.com/bazelbuild/bazelisk/httputil"
+	"github.com/bazelbuild/bazelisk/httputil/progress"
 	"github.com/fatih/color"
 )
 
 var faint = color.New(color.Faint)
 
-func DownloadPlugin(url string, name string, version string) (string, error) {
-	aspectCacheDir, err := cache.AspectCacheDir()
-	if err != nil {
-		return "", err
-	}
+// WasmPlatform is the platform key used for WebAssembly plugins, which are
+// published once for every OS and architecture.
+const WasmPlatform = "wasm"
 
-	pluginsCache := filepath.Join(aspectCacheDir, "plugins", name, version)
-	err = os.MkdirAll(pluginsCache, 0755)
-	if err != nil {
-		return "", fmt.Errorf("could not create directory %s: %v", pluginsCache, err)
+// SupportedPlatforms lists the os_arch pairs native plugins may be published for.
+var SupportedPlatforms = []string{
+	"darwin_amd64",
+	"darwin_arm64",
+	"linux_amd64",
+	"linux_arm64",
+	"windows_amd64",
+	"windows_arm64",
+}
+
+// ResolvePluginURL expands the github.com/org/repo syntax sugar of the plugin
+// 'from' attribute into a release download URL. Other values are returned
+// unchanged.
+func ResolvePluginURL(from string) string {
+	if strings.HasPrefix(from, "github.com/") {
+		// Syntax sugar:
+		//   from: github.com/org/repo
+		// is the same as
+		//   from: https://github.com/org/repo/releases/download
+		// Example release URL:
+		//   https://github.com/aspect-build/aspect-cli-plugin-template/releases/download/v0.1.0/plugin-plugin-linux_amd64
+		return fmt.Sprintf("https://%s/releases/download", from)
 	}
+	return from
+}
+
+// IsRemotePlugin returns true if the plugin 'from' attribute refers to a
+// plugin that is downloaded or pulled from an OCI registry rather than a local
+// path.
+func IsRemotePlugin(from string) bool {
+	from = ResolvePluginURL(from)
+	return strings.HasPrefix(from, "http://") || strings.HasPrefix(from, "https://") || IsOCIPlugin(from)
+}
 
-	filename, err := determinePluginFilename(name)
+// DownloadPlugin returns the path of the plugin binary for this machine in the
+// plugin store, downloading it if needed. The binary must match the given hex
+// encoded sha256 or, when it is empty, the checksum published alongside the
+// release asset, if any.
+func DownloadPlugin(url string, name string, version string, pluginRuntime string, expectedSHA256 string) (string, error) {
+	platform, err := PluginPlatform(pluginRuntime)
 	if err != nil {
 		return "", fmt.Errorf("unable to determine filename to fetch: %v", err)
 	}
+	filename := PluginFilename(name, platform)
 
 	versionedURL := fmt.Sprintf("%s/%s/%s", url, version, filename)
 
-	pluginfile, err := downloadBinary(versionedURL, pluginsCache, filename)
+	sha256Hex := expectedSHA256
+	if sha256Hex == "" {
+		if sha256Hex, err = indexedPlugin(versionedURL); err != nil {
+			return "", err
+		}
+	}
+	if sha256Hex != "" {
+		if path, ok, err := storedPlugin(sha256Hex, platform); err != nil || ok {
+			return path, err
+		}
+	}
+
+	if expectedSHA256 == "" {
+		// We don't care if this errors. We have logic to do Trust on first use (TOFU).
+		expectedSHA256, _ = fetchChecksum(versionedURL + ".sha256")
+		if expectedSHA256 != "" {
+			if path, ok, err := storedPlugin(expectedSHA256, platform); err != nil || ok {
+				return path, err
+			}
+		}
+	}
+
+	storeDir, err := pluginsCacheDir(pluginStoreDir)
+	if err != nil {
+		return "", err
+	}
+	tmpfile, err := downloadBinary(versionedURL, storeDir)
 	if err != nil {
 		return "", fmt.Errorf("unable to fetch remote plugin from %s: %v", url, err)
 	}
+	defer os.Remove(tmpfile)
 
-	// We don't care if this errors. We have logic to do Trust on first use (TOFU).
-	downloadBinarySha(versionedURL, pluginsCache, filename)
+	digest, err := fileDigest(tmpfile)
+	if err != nil {
+		return "", err
+	}
+	if expectedSHA256 != "" && !strings.EqualFold(hex.EncodeToString(digest), expectedSHA256) {
+		return "", fmt.Errorf("checksum mismatch for plugin %q: %s has sha256 %x, expected %s", name, versionedURL, digest, expectedSHA256)
+	}
 
+	pluginfile, _, err := storePlugin(tmpfile, platform)
+	if err != nil {
+		return "", err
+	}
+	if err := indexPlugin(versionedURL, digest); err != nil {
+		return "", err
+	}
 	return pluginfile, nil
 }
 
-// determineBazelFilename returns the correct file name of a local Bazel binary.
-// The logic produces the same naming as our /release/release.bzl gives to our aspect-cli binaries.
-func determinePluginFilename(pluginName string) (string, error) {
+// FetchPluginChecksum returns the hex encoded sha256 published alongside the
+// plugin release asset for the given platform.
+func FetchPluginChecksum(from string, name string, version string, platform string) (string, error) {
+	return fetchChecksum(fmt.Sprintf("%s/%s/%s.sha256", ResolvePluginURL(from), version, PluginFilename(name, platform)))
+}
+
+func fetchChecksum(sha256URL string) (string, error) {
+	res, err := getWithCredentials(sha256URL)
+	if err != nil {
+		return "", fmt.Errorf("unable to fetch plugin checksum from %s: %w", sha256URL, err)
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", fmt.Errorf("unable to fetch plugin checksum from %s: %w", sha256URL, err)
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty plugin checksum at %s", sha256URL)
+	}
+	return fields[0], nil
+}
+
+// PluginPlatform returns the platform key of the plugin binary to run on this
+// machine, which is WasmPlatform for WebAssembly plugins.
+func PluginPlatform(pluginRuntime string) (string, error) {
+	if pluginRuntime == types.RuntimeWasm {
+		return WasmPlatform, nil
+	}
+
 	var machineName string
 	switch runtime.GOARCH {
 	case "amd64", "arm64":
@@ -81,31 +183,97 @@ func determinePluginFilename(pluginName string) (string, error) {
 		return "", fmt.Errorf("unsupported operating system \"%s\", must be Linux, macOS or Windows", runtime.GOOS)
 	}
 
+	return fmt.Sprintf("%s_%s", osName, machineName), nil
+}
+
+// PluginFilename returns the file name of a plugin release asset for the given platform.
+// The logic produces the same naming as our /release/release.bzl gives to our aspect-cli binaries.
+func PluginFilename(pluginName string, platform string) string {
+	if platform == WasmPlatform {
+		return fmt.Sprintf("%s.wasm", pluginName)
+	}
+
 	filenameSuffix := ""
-	if runtime.GOOS == "windows" {
+	if strings.HasPrefix(platform, "windows_") {
 		filenameSuffix = ".exe"
 	}
 
-	return fmt.Sprintf("%s-%s_%s%s", pluginName, osName, machineName, filenameSuffix), nil
+	return fmt.Sprintf("%s-%s%s", pluginName, platform, filenameSuffix)
 }
 
-func downloadBinary(originURL, destDir, destFile string) (string, error) {
-	return httputil.DownloadBinary(originURL, destDir, destFile, config.FromEnv())
-}
+// downloadBinary downloads the URL to a temporary file in destDir and returns
+// its path.
+func downloadBinary(originURL, destDir string) (string, error) {
+	res, err := getWithCredentials(originURL)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
 
-func downloadBinarySha(versionedURL, destDir, destFile string) (string, error) {
-	sha256URL := fmt.Sprintf("%s.sha256", versionedURL)
-	sha256Filename := fmt.Sprintf("%s.sha256", destFile)
+	tmpfile, err := os.CreateTemp(destDir, "download")
+	if err != nil {
+		return "", fmt.Errorf("could not create temporary file: %v", err)
+	}
+	defer tmpfile.Close()
+
+	bazeliskConfig := config.FromEnv()
+	_, err = io.Copy(progress.Writer(tmpfile, "Downloading", res.ContentLength, bazeliskConfig), res.Body)
+	progress.Finish(bazeliskConfig)
+	if err == nil {
+		err = tmpfile.Close()
+	}
+	if err != nil {
+		os.Remove(tmpfile.Name())
+		return "", fmt.Errorf("could not copy from %s to %s: %v", originURL, tmpfile.Name(), err)
+	}
+	return tmpfile.Name(), nil
+}
 
-	// Use DownloadBinary() to ensure the same HTTP auth/header logic is used
-	p, err := httputil.DownloadBinary(sha256URL, destDir, sha256Filename, config.FromEnv())
+// getWithCredentials sends a GET request for the URL. Private plugins are
+// typically refused or reported missing to anonymous requests, in which case
+// the request is retried with the credentials found for the URL, if any.
+func getWithCredentials(rawURL string) (*http.Response, error) {
+	res, err := get(rawURL, nil)
 	if err != nil {
-		return p, err
+		return nil, err
 	}
 
-	if err := os.Chmod(p, 0400); err != nil {
-		return p, err
+	switch res.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		headers, err := downloadCredentials(rawURL)
+		if err != nil {
+			res.Body.Close()
+			return nil, err
+		}
+		if headers != nil {
+			res.Body.Close()
+			if res, err = get(rawURL, headers); err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	return p, nil
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("HTTP GET %s failed with error %v", rawURL, res.StatusCode)
+	}
+	return res, nil
+}
+
+func get(rawURL string, headers http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create request: %v", err)
+	}
+	for name, values := range headers {
+		req.Header[name] = values
+	}
+	req.Header.Set("User-Agent", httputil.UserAgent)
+
+	client := &http.Client{Transport: httputil.DefaultTransport}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP GET %s failed: %v", rawURL, err)
+	}
+	return res, nil
 }
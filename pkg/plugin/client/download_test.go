@@ -0,0 +1,117 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// newReleaseServer serves plugin release assets and their published checksums,
+// counting the downloads of assets.
+func newReleaseServer(t *testing.T, binary string, published string) (*httptest.Server, *int) {
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if strings.HasSuffix(req.URL.Path, ".sha256") {
+			if published == "" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(published))
+			return
+		}
+		downloads++
+		w.Write([]byte(binary))
+	}))
+	t.Cleanup(server.Close)
+	return server, &downloads
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDownloadPlugin(t *testing.T) {
+	t.Run("stores the binary by its checksum", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setupCredentialsTest(t)
+		t.Setenv("XDG_CACHE_HOME", t.TempDir())
+		server, downloads := newReleaseServer(t, "plugin binary", "")
+
+		path, err := DownloadPlugin(server.URL, "plugin", "v1.0.0", "wasm", "")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(path).To(HaveSuffix("/plugins/sha256/" + sha256Hex("plugin binary") + ".wasm"))
+		g.Expect(os.ReadFile(path)).To(Equal([]byte("plugin binary")))
+
+		// The binary is not downloaded again.
+		cached, err := DownloadPlugin(server.URL, "plugin", "v1.0.0", "wasm", "")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(cached).To(Equal(path))
+		g.Expect(*downloads).To(Equal(1))
+	})
+
+	t.Run("shares the binary across plugins and versions", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setupCredentialsTest(t)
+		t.Setenv("XDG_CACHE_HOME", t.TempDir())
+		server, downloads := newReleaseServer(t, "plugin binary", sha256Hex("plugin binary"))
+
+		path, err := DownloadPlugin(server.URL, "plugin", "v1.0.0", "wasm", "")
+		g.Expect(err).ToNot(HaveOccurred())
+
+		other, err := DownloadPlugin(server.URL, "other", "v2.0.0", "wasm", "")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(other).To(Equal(path))
+		g.Expect(*downloads).To(Equal(1))
+	})
+
+	t.Run("uses the expected checksum without contacting the server", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setupCredentialsTest(t)
+		t.Setenv("XDG_CACHE_HOME", t.TempDir())
+		server, _ := newReleaseServer(t, "plugin binary", "")
+
+		path, err := DownloadPlugin(server.URL, "plugin", "v1.0.0", "wasm", "")
+		g.Expect(err).ToNot(HaveOccurred())
+		server.Close()
+
+		cached, err := DownloadPlugin(server.URL, "plugin", "v1.1.0", "wasm", sha256Hex("plugin binary"))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(cached).To(Equal(path))
+	})
+
+	t.Run("refuses a binary that doesn't match the published checksum", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setupCredentialsTest(t)
+		t.Setenv("XDG_CACHE_HOME", t.TempDir())
+		server, _ := newReleaseServer(t, "tampered binary", sha256Hex("plugin binary"))
+
+		_, err := DownloadPlugin(server.URL, "plugin", "v1.0.0", "wasm", "")
+		g.Expect(err).To(MatchError(ContainSubstring("checksum mismatch for plugin \"plugin\"")))
+
+		entries, err := os.ReadDir(os.Getenv("XDG_CACHE_HOME") + "/aspect/plugins/sha256")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(entries).To(BeEmpty())
+	})
+}
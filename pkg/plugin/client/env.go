@@ -0,0 +1,67 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package client
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/types"
+)
+
+// PluginEnv returns the environment of a plugin as KEY=value pairs. Plugins
+// inherit the environment of the Core, or only the variables matching its
+// env_allowlist when the config sets one, so that third-party plugins can be
+// kept from reading credentials present in the environment. The variables set
+// by the env of its config are added on top.
+func PluginEnv(aspectplugin types.PluginConfig, environ []string) []string {
+	env := make([]string, 0, len(environ)+len(aspectplugin.Env))
+	for _, kv := range environ {
+		k, _, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		if _, ok := aspectplugin.Env[k]; ok {
+			continue
+		}
+		if aspectplugin.EnvAllowlist != nil && !envAllowed(aspectplugin.EnvAllowlist, k) {
+			continue
+		}
+		env = append(env, kv)
+	}
+
+	keys := make([]string, 0, len(aspectplugin.Env))
+	for k := range aspectplugin.Env {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+aspectplugin.Env[k])
+	}
+	return env
+}
+
+// envAllowed reports whether the variable matches one of the names or glob
+// patterns, e.g. CI_*, of an env_allowlist.
+func envAllowed(allowlist []string, key string) bool {
+	for _, pattern := range allowlist {
+		if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
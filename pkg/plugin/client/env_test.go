@@ -0,0 +1,63 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package client
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/types"
+)
+
+var environ = []string{
+	"PATH=/usr/bin",
+	"HOME=/home/user",
+	"AWS_SECRET_ACCESS_KEY=secret",
+	"CI_COMMIT=abc",
+	"CI_BRANCH=main",
+}
+
+func TestPluginEnv(t *testing.T) {
+	t.Run("inherits the whole environment by default", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		g.Expect(PluginEnv(types.PluginConfig{}, environ)).To(Equal(environ))
+	})
+
+	t.Run("only inherits the allowed variables", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		env := PluginEnv(types.PluginConfig{EnvAllowlist: []string{"PATH", "CI_*"}}, environ)
+		g.Expect(env).To(Equal([]string{"PATH=/usr/bin", "CI_COMMIT=abc", "CI_BRANCH=main"}))
+	})
+
+	t.Run("inherits nothing with an empty allowlist", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		g.Expect(PluginEnv(types.PluginConfig{EnvAllowlist: []string{}}, environ)).To(BeEmpty())
+	})
+
+	t.Run("sets the configured variables", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		env := PluginEnv(types.PluginConfig{
+			EnvAllowlist: []string{"PATH", "HOME"},
+			Env:          map[string]string{"HOME": "/tmp/plugin", "NO_COLOR": "1"},
+		}, environ)
+		g.Expect(env).To(Equal([]string{"PATH=/usr/bin", "HOME=/tmp/plugin", "NO_COLOR=1"}))
+	})
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/root/config"
+)
+
+// LockFile is the name of the plugin lockfile in the workspace Aspect CLI
+// config folder.
+const LockFile = "plugins.lock"
+
+const lockFileHeader = `# This file is generated by the Aspect CLI plugin system. Do not edit.
+# Check it into version control so that every developer runs the same plugin binaries.
+`
+
+// LockEntry pins a remote plugin to the URL and version it was resolved from and
+// the sha256 of the binary downloaded for each platform.
+type LockEntry struct {
+	URL     string            `yaml:"url"`
+	Version string            `yaml:"version"`
+	SHA256  map[string]string `yaml:"sha256"`
+}
+
+type lockFile struct {
+	Plugins map[string]LockEntry `yaml:"plugins"`
+}
+
+// Lock is the plugin lockfile. It is safe for concurrent use since plugins are
+// configured in parallel.
+type Lock struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewLock returns the lockfile at the given path. A lock with an empty path is
+// disabled and verifies nothing.
+func NewLock(path string) *Lock {
+	return &Lock{path: path}
+}
+
+// NewWorkspaceLock returns the lockfile in the workspace Aspect CLI config
+// folder. Outside of a workspace the returned lock is disabled.
+func NewWorkspaceLock() *Lock {
+	configFolder, err := config.WorkspaceConfigFolder()
+	if err != nil {
+		return NewLock("")
+	}
+	return NewLock(filepath.Join(configFolder, LockFile))
+}
+
+// Verify checks the sha256 of a plugin binary for the given platform against
+// the lockfile. Plugins that are not locked yet, or whose URL or version changed
+// in the config, are (re)locked. A platform missing from an existing entry is
+// added on first use.
+func (l *Lock) Verify(name string, url string, version string, platform string, digest string) error {
+	if l.path == "" {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock, err := l.read()
+	if err != nil {
+		return err
+	}
+
+	entry, ok := lock.Plugins[name]
+	if !ok || entry.URL != url || entry.Version != version {
+		entry = LockEntry{
+			URL:     url,
+			Version: version,
+			SHA256:  map[string]string{},
+		}
+	} else if locked, ok := entry.SHA256[platform]; ok {
+		if locked != digest {
+			return fmt.Errorf("refusing to run plugin %q: sha256 %s of %s %s for %s doesn't match %s locked in %s", name, digest, url, version, platform, locked, l.path)
+		}
+		return nil
+	}
+
+	if entry.SHA256 == nil {
+		entry.SHA256 = map[string]string{}
+	}
+	entry.SHA256[platform] = digest
+	lock.Plugins[name] = entry
+
+	return l.write(lock)
+}
+
+// Locked returns the sha256 locked for a plugin binary on the given platform,
+// or an empty string when it is not locked yet or its URL or version changed in
+// the config.
+func (l *Lock) Locked(name string, url string, version string, platform string) (string, error) {
+	if l.path == "" {
+		return "", nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock, err := l.read()
+	if err != nil {
+		return "", err
+	}
+
+	entry, ok := lock.Plugins[name]
+	if !ok || entry.URL != url || entry.Version != version {
+		return "", nil
+	}
+	return entry.SHA256[platform], nil
+}
+
+func (l *Lock) read() (*lockFile, error) {
+	lock := &lockFile{}
+	b, err := os.ReadFile(l.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read %s: %w", l.path, err)
+	}
+	if err := yaml.Unmarshal(b, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", l.path, err)
+	}
+	if lock.Plugins == nil {
+		lock.Plugins = map[string]LockEntry{}
+	}
+	return lock, nil
+}
+
+func (l *Lock) write(lock *lockFile) error {
+	b, err := yaml.Marshal(lock)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", l.path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to write %s: %w", l.path, err)
+	}
+	if err := os.WriteFile(l.path, append([]byte(lockFileHeader), b...), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", l.path, err)
+	}
+	return nil
+}
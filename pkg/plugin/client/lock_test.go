@@ -0,0 +1,94 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/client"
+)
+
+const pluginURL = "https://github.com/org/repo/releases/download"
+
+func TestLock(t *testing.T) {
+	t.Run("locks a plugin on first use", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		lockFile := filepath.Join(t.TempDir(), client.LockFile)
+		lock := client.NewLock(lockFile)
+
+		g.Expect(lock.Verify("foo", pluginURL, "v1.0.0", "linux_amd64", "aaaa")).To(Succeed())
+
+		b, err := os.ReadFile(lockFile)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(string(b)).To(ContainSubstring("url: " + pluginURL))
+		g.Expect(string(b)).To(ContainSubstring("version: v1.0.0"))
+		g.Expect(string(b)).To(ContainSubstring("linux_amd64: aaaa"))
+	})
+
+	t.Run("refuses a binary whose digest doesn't match", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		lockFile := filepath.Join(t.TempDir(), client.LockFile)
+
+		g.Expect(client.NewLock(lockFile).Verify("foo", pluginURL, "v1.0.0", "linux_amd64", "aaaa")).To(Succeed())
+
+		lock := client.NewLock(lockFile)
+		g.Expect(lock.Verify("foo", pluginURL, "v1.0.0", "linux_amd64", "aaaa")).To(Succeed())
+		g.Expect(lock.Verify("foo", pluginURL, "v1.0.0", "linux_amd64", "bbbb")).To(MatchError(ContainSubstring("refusing to run plugin \"foo\"")))
+	})
+
+	t.Run("adds missing platforms to an existing entry", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		lock := client.NewLock(filepath.Join(t.TempDir(), client.LockFile))
+
+		g.Expect(lock.Verify("foo", pluginURL, "v1.0.0", "linux_amd64", "aaaa")).To(Succeed())
+		g.Expect(lock.Verify("foo", pluginURL, "v1.0.0", "darwin_arm64", "bbbb")).To(Succeed())
+		g.Expect(lock.Verify("foo", pluginURL, "v1.0.0", "linux_amd64", "cccc")).ToNot(Succeed())
+		g.Expect(lock.Verify("foo", pluginURL, "v1.0.0", "darwin_arm64", "cccc")).ToNot(Succeed())
+	})
+
+	t.Run("re-locks a plugin when its version changes", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		lock := client.NewLock(filepath.Join(t.TempDir(), client.LockFile))
+
+		g.Expect(lock.Verify("foo", pluginURL, "v1.0.0", "linux_amd64", "aaaa")).To(Succeed())
+		g.Expect(lock.Verify("foo", pluginURL, "v1.1.0", "linux_amd64", "bbbb")).To(Succeed())
+		g.Expect(lock.Verify("foo", pluginURL, "v1.1.0", "linux_amd64", "aaaa")).ToNot(Succeed())
+	})
+
+	t.Run("returns the locked checksum of a platform", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		lock := client.NewLock(filepath.Join(t.TempDir(), client.LockFile))
+
+		g.Expect(lock.Locked("foo", pluginURL, "v1.0.0", "linux_amd64")).To(BeEmpty())
+		g.Expect(lock.Verify("foo", pluginURL, "v1.0.0", "linux_amd64", "aaaa")).To(Succeed())
+		g.Expect(lock.Locked("foo", pluginURL, "v1.0.0", "linux_amd64")).To(Equal("aaaa"))
+		g.Expect(lock.Locked("foo", pluginURL, "v1.0.0", "darwin_arm64")).To(BeEmpty())
+		g.Expect(lock.Locked("foo", pluginURL, "v1.1.0", "linux_amd64")).To(BeEmpty())
+	})
+
+	t.Run("verifies nothing when disabled", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		lock := client.NewLock("")
+
+		g.Expect(lock.Verify("foo", pluginURL, "v1.0.0", "linux_amd64", "aaaa")).To(Succeed())
+		g.Expect(lock.Verify("foo", pluginURL, "v1.0.0", "linux_amd64", "bbbb")).To(Succeed())
+	})
+}
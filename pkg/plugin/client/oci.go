@@ -0,0 +1,595 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package client
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// OCIScheme prefixes the plugin 'from' attribute of plugins that are pulled
+// from an OCI registry, e.g. oci://ghcr.io/org/plugin:v1.0.0.
+const OCIScheme = "oci://"
+
+// The media types of the manifests accepted from OCI registries.
+const (
+	ociIndexMediaType           = "application/vnd.oci.image.index.v1+json"
+	ociManifestMediaType        = "application/vnd.oci.image.manifest.v1+json"
+	dockerManifestListMediaType = "application/vnd.docker.distribution.manifest.list.v2+json"
+	dockerManifestMediaType     = "application/vnd.docker.distribution.manifest.v2+json"
+)
+
+const (
+	// ociImageTitleAnnotation names the file held by a layer.
+	ociImageTitleAnnotation = "org.opencontainers.image.title"
+	maxManifestSize         = 4 << 20
+
+	dockerHubRegistry          = "docker.io"
+	dockerHubRegistryAPIHost   = "registry-1.docker.io"
+	dockerHubOfficialNamespace = "library"
+)
+
+// ociHTTPClient is the client used to talk to OCI registries.
+var ociHTTPClient = http.DefaultClient
+
+// IsOCIPlugin returns true if the plugin 'from' attribute refers to an image in
+// an OCI registry.
+func IsOCIPlugin(from string) bool {
+	return strings.HasPrefix(from, OCIScheme)
+}
+
+// ociReference is a parsed oci://registry/repository[:tag][@digest] reference.
+type ociReference struct {
+	registry   string
+	repository string
+	tag        string
+	digest     string
+}
+
+// parseOCIReference parses the 'from' attribute of an OCI plugin. The version
+// of the plugin is used as the tag when the reference has neither a tag nor a
+// digest.
+func parseOCIReference(from string, version string) (*ociReference, error) {
+	ref := &ociReference{}
+	rest := strings.TrimPrefix(from, OCIScheme)
+
+	if i := strings.Index(rest, "@"); i >= 0 {
+		rest, ref.digest = rest[:i], rest[i+1:]
+		if !strings.HasPrefix(ref.digest, "sha256:") {
+			return nil, fmt.Errorf("invalid OCI reference %q: only sha256 digests are supported", from)
+		}
+	}
+	if i := strings.LastIndex(rest, ":"); i > strings.LastIndex(rest, "/") {
+		rest, ref.tag = rest[:i], rest[i+1:]
+	}
+
+	registry, repository, ok := strings.Cut(rest, "/")
+	if !ok || registry == "" || repository == "" {
+		return nil, fmt.Errorf("invalid OCI reference %q: expected oci://registry/repository:tag", from)
+	}
+	ref.registry = registry
+	ref.repository = repository
+	if ref.registry == dockerHubRegistry && !strings.Contains(ref.repository, "/") {
+		ref.repository = path.Join(dockerHubOfficialNamespace, ref.repository)
+	}
+
+	if ref.tag == "" && ref.digest == "" {
+		if version == "" {
+			return nil, fmt.Errorf("invalid OCI reference %q: a tag, a digest or the version field is required", from)
+		}
+		ref.tag = version
+	}
+	return ref, nil
+}
+
+// manifestReference returns the tag or digest to fetch the manifest with.
+func (ref *ociReference) manifestReference() string {
+	if ref.digest != "" {
+		return ref.digest
+	}
+	return ref.tag
+}
+
+// baseURL returns the URL of the registry API. Registries on the local machine
+// are spoken to over plain HTTP, like the docker CLI does.
+func (ref *ociReference) baseURL() string {
+	host := ref.registry
+	if host == dockerHubRegistry {
+		host = dockerHubRegistryAPIHost
+	}
+	scheme := "https"
+	hostname := host
+	if h, _, ok := strings.Cut(host, ":"); ok && !strings.HasPrefix(host, "[") {
+		hostname = h
+	}
+	if hostname == "localhost" || hostname == "127.0.0.1" || strings.HasPrefix(host, "[::1]") {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/v2/%s", scheme, host, ref.repository)
+}
+
+// ociDescriptor is an OCI content descriptor.
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Platform    *struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+	} `json:"platform,omitempty"`
+}
+
+// ociManifest is an image index or an image manifest.
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Manifests []ociDescriptor `json:"manifests"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+// PullOCIPlugin pulls the binary of the plugin for this machine out of the
+// image at the given oci:// reference into the plugin store and returns its
+// path. The binary is the layer of the image whose title annotation is the
+// release asset name of the plugin, the only layer of the image, or the file
+// with that name in a tar layer. Multi-platform images are resolved to the
+// manifest of this machine's platform.
+func PullOCIPlugin(from string, name string, version string, pluginRuntime string) (string, error) {
+	ref, err := parseOCIReference(from, version)
+	if err != nil {
+		return "", err
+	}
+	platform, err := PluginPlatform(pluginRuntime)
+	if err != nil {
+		return "", fmt.Errorf("unable to determine platform to pull: %v", err)
+	}
+	filename := PluginFilename(name, platform)
+
+	registry := &ociRegistry{ref: ref}
+	manifest, err := registry.manifest(ref.manifestReference(), ref.digest)
+	if err != nil {
+		return "", fmt.Errorf("unable to pull remote plugin from %s: %w", from, err)
+	}
+	if len(manifest.Manifests) > 0 {
+		descriptor, err := platformManifest(manifest, platform)
+		if err != nil {
+			return "", fmt.Errorf("unable to pull remote plugin from %s: %w", from, err)
+		}
+		if manifest, err = registry.manifest(descriptor.Digest, descriptor.Digest); err != nil {
+			return "", fmt.Errorf("unable to pull remote plugin from %s: %w", from, err)
+		}
+	}
+
+	layer, err := pluginLayer(manifest, name, filename)
+	if err != nil {
+		return "", fmt.Errorf("unable to pull remote plugin from %s: %w", from, err)
+	}
+
+	// Binaries pulled from a layer are indexed by the layer digest, since the
+	// binary in a tar layer has a digest of its own.
+	sha256Hex, err := indexedPlugin(layer.Digest)
+	if err != nil {
+		return "", err
+	}
+	if sha256Hex != "" {
+		if pluginfile, ok, err := storedPlugin(sha256Hex, platform); err != nil || ok {
+			return pluginfile, err
+		}
+	}
+
+	storeDir, err := pluginsCacheDir(pluginStoreDir)
+	if err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(storeDir, "pull-*")
+	if err != nil {
+		return "", fmt.Errorf("could not create temporary file: %v", err)
+	}
+	tmp.Close()
+	tmpfile := tmp.Name()
+	defer os.Remove(tmpfile)
+	if err := registry.pullLayer(layer, name, filename, tmpfile); err != nil {
+		return "", fmt.Errorf("unable to pull remote plugin from %s: %w", from, err)
+	}
+
+	pluginfile, digest, err := storePlugin(tmpfile, platform)
+	if err != nil {
+		return "", err
+	}
+	if err := indexPlugin(layer.Digest, digest); err != nil {
+		return "", err
+	}
+	return pluginfile, nil
+}
+
+// platformManifest returns the descriptor of the manifest for the given plugin
+// platform from an image index.
+func platformManifest(index *ociManifest, platform string) (*ociDescriptor, error) {
+	wantOS, wantArch := "wasip1", "wasm"
+	if platform != WasmPlatform {
+		wantOS, wantArch, _ = strings.Cut(platform, "_")
+	}
+	available := make([]string, 0, len(index.Manifests))
+	for i, m := range index.Manifests {
+		if m.Platform == nil {
+			continue
+		}
+		if m.Platform.OS == wantOS && m.Platform.Architecture == wantArch {
+			return &index.Manifests[i], nil
+		}
+		available = append(available, m.Platform.OS+"/"+m.Platform.Architecture)
+	}
+	return nil, fmt.Errorf("the image has no manifest for platform %s/%s, only for %s", wantOS, wantArch, strings.Join(available, ", "))
+}
+
+// pluginLayer returns the layer of an image manifest that holds the plugin.
+func pluginLayer(manifest *ociManifest, name string, filename string) (*ociDescriptor, error) {
+	for i, layer := range manifest.Layers {
+		if title := layer.Annotations[ociImageTitleAnnotation]; title == filename || title == name {
+			return &manifest.Layers[i], nil
+		}
+	}
+	if len(manifest.Layers) == 1 {
+		return &manifest.Layers[0], nil
+	}
+	return nil, fmt.Errorf("the image has %d layers and none of them is titled %q", len(manifest.Layers), filename)
+}
+
+// ociRegistry fetches content of a repository from an OCI registry, requesting
+// a bearer token from the registry's auth service when challenged.
+type ociRegistry struct {
+	ref           *ociReference
+	authorization string
+}
+
+func (r *ociRegistry) manifest(reference string, digest string) (*ociManifest, error) {
+	res, err := r.get(fmt.Sprintf("%s/manifests/%s", r.ref.baseURL(), reference),
+		ociIndexMediaType, dockerManifestListMediaType, ociManifestMediaType, dockerManifestMediaType)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(io.LimitReader(res.Body, maxManifestSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", reference, err)
+	}
+	if digest != "" {
+		if actual := fmt.Sprintf("sha256:%x", sha256.Sum256(b)); actual != digest {
+			return nil, fmt.Errorf("manifest %s has digest %s", digest, actual)
+		}
+	}
+
+	manifest := &ociManifest{}
+	if err := json.Unmarshal(b, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", reference, err)
+	}
+	return manifest, nil
+}
+
+// pullLayer downloads a layer, verifies its digest and writes the plugin from it
+// to dest.
+func (r *ociRegistry) pullLayer(layer *ociDescriptor, name string, filename string, dest string) error {
+	res, err := r.get(fmt.Sprintf("%s/blobs/%s", r.ref.baseURL(), layer.Digest))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	blob, err := os.CreateTemp(filepath.Dir(dest), "blob-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(blob.Name())
+	defer blob.Close()
+
+	digest := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(blob, digest), res.Body); err != nil {
+		return fmt.Errorf("failed to download layer %s: %w", layer.Digest, err)
+	}
+	if actual := "sha256:" + hex.EncodeToString(digest.Sum(nil)); actual != layer.Digest {
+		return fmt.Errorf("layer %s has digest %s", layer.Digest, actual)
+	}
+
+	if !strings.Contains(layer.MediaType, "tar") {
+		if err := blob.Close(); err != nil {
+			return err
+		}
+		if err := os.Chmod(blob.Name(), 0755); err != nil {
+			return err
+		}
+		return os.Rename(blob.Name(), dest)
+	}
+
+	if _, err := blob.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	var layerReader io.Reader = blob
+	if strings.Contains(layer.MediaType, "gzip") {
+		gz, err := gzip.NewReader(blob)
+		if err != nil {
+			return fmt.Errorf("failed to decompress layer %s: %w", layer.Digest, err)
+		}
+		defer gz.Close()
+		layerReader = gz
+	}
+	return extractPlugin(tar.NewReader(layerReader), name, filename, dest)
+}
+
+// extractPlugin writes the file named after the plugin or its release asset in
+// a tar layer to dest.
+func extractPlugin(tr *tar.Reader, name string, filename string, dest string) error {
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return fmt.Errorf("the layer has no file named %q or %q", filename, name)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read layer: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if base := path.Base(header.Name); base != filename && base != name {
+			continue
+		}
+
+		f, err := os.CreateTemp(filepath.Dir(dest), "plugin-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		if _, err := io.Copy(f, tr); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to extract %s: %w", header.Name, err)
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		if err := os.Chmod(f.Name(), 0755); err != nil {
+			return err
+		}
+		return os.Rename(f.Name(), dest)
+	}
+}
+
+// get sends a GET request to the registry. When the registry challenges the
+// request, it authenticates with the credentials of the docker config and
+// retries.
+func (r *ociRegistry) get(url string, accept ...string) (*http.Response, error) {
+	res, err := r.do(url, accept)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusUnauthorized && r.authorization == "" {
+		challenge := res.Header.Get("WWW-Authenticate")
+		res.Body.Close()
+		if err := r.authenticate(challenge); err != nil {
+			return nil, err
+		}
+		if res, err = r.do(url, accept); err != nil {
+			return nil, err
+		}
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", url, res.Status)
+	}
+	return res, nil
+}
+
+func (r *ociRegistry) do(url string, accept []string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(accept) > 0 {
+		req.Header.Set("Accept", strings.Join(accept, ", "))
+	}
+	if r.authorization != "" {
+		req.Header.Set("Authorization", r.authorization)
+	}
+	return ociHTTPClient.Do(req)
+}
+
+// authenticate sets the authorization for the requests to the registry from
+// a WWW-Authenticate challenge.
+func (r *ociRegistry) authenticate(challenge string) error {
+	username, password, err := dockerCredentials(r.ref.registry)
+	if err != nil {
+		return err
+	}
+
+	scheme, params, _ := strings.Cut(challenge, " ")
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if username == "" {
+			return fmt.Errorf("registry %s requires credentials, run 'docker login %s'", r.ref.registry, r.ref.registry)
+		}
+		r.authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+		return nil
+	case "bearer":
+		token, err := r.token(parseChallengeParams(params), username, password)
+		if err != nil {
+			return err
+		}
+		r.authorization = "Bearer " + token
+		return nil
+	}
+	return fmt.Errorf("registry %s requested unsupported authentication %q", r.ref.registry, challenge)
+}
+
+// token requests a bearer token to pull the repository from the auth service
+// of the registry.
+func (r *ociRegistry) token(params map[string]string, username string, password string) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("registry %s sent an invalid auth realm %q", r.ref.registry, params["realm"])
+	}
+	query := realm.Query()
+	if service, ok := params["service"]; ok {
+		query.Set("service", service)
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", r.ref.repository)
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	res, err := ociHTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to authenticate to registry %s: %w", r.ref.registry, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to authenticate to registry %s: %s", r.ref.registry, res.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to authenticate to registry %s: %w", r.ref.registry, err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", fmt.Errorf("failed to authenticate to registry %s: no token was returned", r.ref.registry)
+}
+
+// parseChallengeParams parses the comma separated key="value" parameters of a
+// WWW-Authenticate challenge.
+func parseChallengeParams(params string) map[string]string {
+	res := map[string]string{}
+	for params != "" {
+		var key, value string
+		key, params, _ = strings.Cut(params, "=")
+		key = strings.TrimSpace(key)
+		if strings.HasPrefix(params, "\"") {
+			value, params, _ = strings.Cut(params[1:], "\"")
+			_, params, _ = strings.Cut(params, ",")
+		} else {
+			value, params, _ = strings.Cut(params, ",")
+		}
+		res[strings.ToLower(key)] = value
+	}
+	return res
+}
+
+// dockerConfig is the part of the docker CLI config file that holds registry
+// credentials.
+type dockerConfig struct {
+	Auths map[string]struct {
+		Auth string `json:"auth"`
+	} `json:"auths"`
+	CredHelpers map[string]string `json:"credHelpers"`
+	CredsStore  string            `json:"credsStore"`
+}
+
+// dockerCredentials returns the credentials for the registry stored by
+// 'docker login', or empty credentials to pull anonymously.
+func dockerCredentials(registry string) (string, string, error) {
+	configDir := os.Getenv("DOCKER_CONFIG")
+	if configDir == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return "", "", nil
+		}
+		configDir = filepath.Join(home, ".docker")
+	}
+	b, err := os.ReadFile(filepath.Join(configDir, "config.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read docker config: %w", err)
+	}
+	config := &dockerConfig{}
+	if err := json.Unmarshal(b, config); err != nil {
+		return "", "", fmt.Errorf("failed to parse docker config: %w", err)
+	}
+
+	if helper, ok := config.CredHelpers[registry]; ok {
+		return credentialHelper(helper, registry)
+	}
+	for _, key := range []string{registry, "https://" + registry, "https://index.docker.io/v1/"} {
+		if key == "https://index.docker.io/v1/" && registry != dockerHubRegistry {
+			continue
+		}
+		if auth, ok := config.Auths[key]; ok && auth.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return "", "", fmt.Errorf("invalid docker credentials for registry %s: %w", registry, err)
+			}
+			username, password, _ := strings.Cut(string(decoded), ":")
+			return username, password, nil
+		}
+	}
+	if config.CredsStore != "" {
+		// The store has no credentials for registries the user never logged
+		// in to, which are pulled anonymously.
+		if username, password, err := credentialHelper(config.CredsStore, registry); err == nil {
+			return username, password, nil
+		}
+	}
+	return "", "", nil
+}
+
+// credentialHelper gets the credentials for the registry from a docker
+// credential helper.
+func credentialHelper(helper string, registry string) (string, string, error) {
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(registry)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get credentials for registry %s from docker-credential-%s: %w", registry, helper, err)
+	}
+	var credentials struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(out, &credentials); err != nil {
+		return "", "", fmt.Errorf("failed to parse credentials from docker-credential-%s: %w", helper, err)
+	}
+	return credentials.Username, credentials.Secret, nil
+}
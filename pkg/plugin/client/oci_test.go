@@ -0,0 +1,219 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// fakeRegistry serves the manifests and blobs of a single repository, requiring
+// a bearer token for every request.
+type fakeRegistry struct {
+	*httptest.Server
+	manifests map[string][]byte
+	blobs     map[string][]byte
+	pulls     int
+}
+
+func newFakeRegistry(t *testing.T) *fakeRegistry {
+	r := &fakeRegistry{manifests: map[string][]byte{}, blobs: map[string][]byte{}}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/token" {
+			if req.URL.Query().Get("scope") != "repository:org/plugin:pull" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"token": "secret"})
+			return
+		}
+		if req.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:org/plugin:pull"`, r.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if ref, ok := strings.CutPrefix(req.URL.Path, "/v2/org/plugin/manifests/"); ok && r.manifests[ref] != nil {
+			w.Write(r.manifests[ref])
+			return
+		}
+		if digest, ok := strings.CutPrefix(req.URL.Path, "/v2/org/plugin/blobs/"); ok && r.blobs[digest] != nil {
+			r.pulls++
+			w.Write(r.blobs[digest])
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *fakeRegistry) reference(tag string) string {
+	return OCIScheme + strings.TrimPrefix(r.URL, "http://") + "/org/plugin:" + tag
+}
+
+func (r *fakeRegistry) addBlob(b []byte) string {
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(b))
+	r.blobs[digest] = b
+	return digest
+}
+
+func (r *fakeRegistry) addManifest(ref string, manifest any) string {
+	b, _ := json.Marshal(manifest)
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(b))
+	r.manifests[digest] = b
+	if ref != "" {
+		r.manifests[ref] = b
+	}
+	return digest
+}
+
+// addImage adds a multi-platform image with the given layer for this machine's
+// platform and another platform under the tag.
+func (r *fakeRegistry) addImage(tag string, layer ociDescriptor) {
+	platform, _ := PluginPlatform("")
+	goos, goarch, _ := strings.Cut(platform, "_")
+	otherLayer := ociDescriptor{MediaType: "application/octet-stream", Digest: r.addBlob([]byte("other"))}
+
+	index := map[string]any{"mediaType": ociIndexMediaType, "manifests": []map[string]any{
+		{
+			"mediaType": ociManifestMediaType,
+			"digest":    r.addManifest("", ociManifest{MediaType: ociManifestMediaType, Layers: []ociDescriptor{otherLayer}}),
+			"platform":  map[string]string{"os": "plan9", "architecture": goarch},
+		},
+		{
+			"mediaType": ociManifestMediaType,
+			"digest":    r.addManifest("", ociManifest{MediaType: ociManifestMediaType, Layers: []ociDescriptor{layer}}),
+			"platform":  map[string]string{"os": goos, "architecture": goarch},
+		},
+	}}
+	r.addManifest(tag, index)
+}
+
+func setupOCITest(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+}
+
+func TestPullOCIPlugin(t *testing.T) {
+	t.Run("pulls the binary for this platform from a multi-platform image", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setupOCITest(t)
+		registry := newFakeRegistry(t)
+		registry.addImage("v1.0.0", ociDescriptor{
+			MediaType: "application/octet-stream",
+			Digest:    registry.addBlob([]byte("plugin binary")),
+		})
+
+		path, err := PullOCIPlugin(registry.reference("v1.0.0"), "plugin", "", "")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(os.ReadFile(path)).To(Equal([]byte("plugin binary")))
+
+		// The binary is cached.
+		cached, err := PullOCIPlugin(registry.reference("v1.0.0"), "plugin", "", "")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(cached).To(Equal(path))
+		g.Expect(registry.pulls).To(Equal(1))
+	})
+
+	t.Run("uses the version as the tag", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setupOCITest(t)
+		registry := newFakeRegistry(t)
+		registry.addImage("v1.0.0", ociDescriptor{
+			MediaType: "application/octet-stream",
+			Digest:    registry.addBlob([]byte("plugin binary")),
+		})
+
+		from := strings.TrimSuffix(registry.reference("v1.0.0"), ":v1.0.0")
+		path, err := PullOCIPlugin(from, "plugin", "v1.0.0", "")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(os.ReadFile(path)).To(Equal([]byte("plugin binary")))
+	})
+
+	t.Run("extracts the binary from a tar layer", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setupOCITest(t)
+		registry := newFakeRegistry(t)
+
+		var layer bytes.Buffer
+		gz := gzip.NewWriter(&layer)
+		tw := tar.NewWriter(gz)
+		for name, content := range map[string]string{"README.md": "readme", "bin/plugin": "plugin binary"} {
+			g.Expect(tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg})).To(Succeed())
+			_, err := tw.Write([]byte(content))
+			g.Expect(err).ToNot(HaveOccurred())
+		}
+		g.Expect(tw.Close()).To(Succeed())
+		g.Expect(gz.Close()).To(Succeed())
+		registry.addImage("v1.0.0", ociDescriptor{
+			MediaType: "application/vnd.oci.image.layer.v1.tar+gzip",
+			Digest:    registry.addBlob(layer.Bytes()),
+		})
+
+		path, err := PullOCIPlugin(registry.reference("v1.0.0"), "plugin", "", "")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(os.ReadFile(path)).To(Equal([]byte("plugin binary")))
+	})
+
+	t.Run("refuses a layer that doesn't match its digest", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setupOCITest(t)
+		registry := newFakeRegistry(t)
+		digest := registry.addBlob([]byte("plugin binary"))
+		registry.blobs[digest] = []byte("tampered binary")
+		registry.addImage("v1.0.0", ociDescriptor{MediaType: "application/octet-stream", Digest: digest})
+
+		_, err := PullOCIPlugin(registry.reference("v1.0.0"), "plugin", "", "")
+		g.Expect(err).To(MatchError(ContainSubstring("layer " + digest + " has digest")))
+	})
+}
+
+func TestParseOCIReference(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	ref, err := parseOCIReference("oci://ghcr.io/org/plugin:v1.0.0", "")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(*ref).To(Equal(ociReference{registry: "ghcr.io", repository: "org/plugin", tag: "v1.0.0"}))
+	g.Expect(ref.baseURL()).To(Equal("https://ghcr.io/v2/org/plugin"))
+
+	ref, err = parseOCIReference("oci://localhost:5000/plugin@sha256:abcd", "v1.0.0")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(*ref).To(Equal(ociReference{registry: "localhost:5000", repository: "plugin", digest: "sha256:abcd"}))
+	g.Expect(ref.baseURL()).To(Equal("http://localhost:5000/v2/plugin"))
+
+	ref, err = parseOCIReference("oci://docker.io/plugin", "v1.0.0")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(*ref).To(Equal(ociReference{registry: "docker.io", repository: "library/plugin", tag: "v1.0.0"}))
+	g.Expect(ref.baseURL()).To(Equal("https://registry-1.docker.io/v2/library/plugin"))
+
+	_, err = parseOCIReference("oci://ghcr.io/org/plugin", "")
+	g.Expect(err).To(HaveOccurred())
+
+	_, err = parseOCIReference("oci://plugin:v1.0.0", "")
+	g.Expect(err).To(HaveOccurred())
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/types"
+)
+
+// PluginLogDir is the directory of the output base that holds the log file of
+// each plugin, named after the plugin.
+const PluginLogDir = "aspect-plugins"
+
+// PluginLogPath returns the path of the log file of the named plugin, or an
+// empty string outside of a workspace.
+func PluginLogPath(name string) (string, error) {
+	workspaceRoot := bazel.WorkspaceFromWd.WorkspaceRoot()
+	if workspaceRoot == "" {
+		return "", nil
+	}
+	outputBase, err := bazel.OutputBase(workspaceRoot)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(outputBase, PluginLogDir, name+".log"), nil
+}
+
+// truncatePluginLog empties the log file of the named plugin, so that it only
+// holds the output of the current invocation of the CLI.
+func truncatePluginLog(name string) error {
+	path, err := PluginLogPath(name)
+	if err != nil || path == "" {
+		return err
+	}
+	if err := os.Truncate(path, 0); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to truncate plugin log %s: %w", path, err)
+	}
+	return nil
+}
+
+// openPluginLog opens the log file of a plugin for appending, or returns nil
+// outside of a workspace. Each launch of the plugin is marked in the log, so
+// that the output of a plugin that was restarted can be told apart.
+func openPluginLog(aspectplugin types.PluginConfig) (*os.File, error) {
+	path, err := PluginLogPath(aspectplugin.Name)
+	if err != nil || path == "" {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create plugin log %s: %w", path, err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plugin log %s: %w", path, err)
+	}
+	fmt.Fprintf(f, "--- %s: running %s plugin from %s\n", time.Now().Format(time.RFC3339), aspectplugin.Name, aspectplugin.From)
+	return f, nil
+}
+
+// pluginStreams returns the streams the output of a plugin is written to. What
+// the plugin writes to stderr is copied to its log file, if any, and the lines
+// it writes are prefixed with its name when its config sets prefix_output.
+func pluginStreams(aspectplugin types.PluginConfig, streams ioutils.Streams, logFile io.Writer) ioutils.Streams {
+	stdout, stderr := streams.Stdout, streams.Stderr
+	if aspectplugin.PrefixOutput {
+		prefix := fmt.Sprintf("[%s] ", aspectplugin.Name)
+		stdout = newPrefixWriter(stdout, prefix)
+		stderr = newPrefixWriter(stderr, prefix)
+	}
+	if logFile != nil {
+		stderr = io.MultiWriter(stderr, logFile)
+	}
+	return ioutils.Streams{
+		Stdin:  streams.Stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	}
+}
+
+// prefixWriter writes a prefix at the start of every line written through it.
+type prefixWriter struct {
+	w      io.Writer
+	prefix []byte
+
+	mu sync.Mutex
+	// midLine is set when the last write didn't end with a newline.
+	midLine bool
+}
+
+func newPrefixWriter(w io.Writer, prefix string) *prefixWriter {
+	return &prefixWriter{w: w, prefix: []byte(prefix)}
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var buf bytes.Buffer
+	for rest := b; len(rest) > 0; {
+		if !p.midLine {
+			buf.Write(p.prefix)
+		}
+		line := rest
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+			line = rest[:i+1]
+		}
+		buf.Write(line)
+		rest = rest[len(line):]
+		p.midLine = line[len(line)-1] != '\n'
+	}
+	if _, err := p.w.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
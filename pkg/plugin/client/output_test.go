@@ -0,0 +1,77 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package client
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/types"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	t.Run("prefixes every line", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		var out strings.Builder
+		w := newPrefixWriter(&out, "[lint] ")
+
+		fmt.Fprint(w, "first\nsecond\n")
+		g.Expect(out.String()).To(Equal("[lint] first\n[lint] second\n"))
+	})
+
+	t.Run("prefixes lines written in several parts once", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		var out strings.Builder
+		w := newPrefixWriter(&out, "[lint] ")
+
+		fmt.Fprint(w, "fir")
+		fmt.Fprint(w, "st\nsec")
+		fmt.Fprint(w, "ond\n")
+		g.Expect(out.String()).To(Equal("[lint] first\n[lint] second\n"))
+	})
+}
+
+func TestPluginStreams(t *testing.T) {
+	t.Run("copies stderr to the log file", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		var stdout, stderr, log strings.Builder
+
+		streams := pluginStreams(types.PluginConfig{Name: "lint"}, ioutils.Streams{Stdout: &stdout, Stderr: &stderr}, &log)
+		fmt.Fprintln(streams.Stdout, "out")
+		fmt.Fprintln(streams.Stderr, "err")
+
+		g.Expect(stdout.String()).To(Equal("out\n"))
+		g.Expect(stderr.String()).To(Equal("err\n"))
+		g.Expect(log.String()).To(Equal("err\n"))
+	})
+
+	t.Run("prefixes the output with the plugin name", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		var stdout, stderr, log strings.Builder
+
+		streams := pluginStreams(types.PluginConfig{Name: "lint", PrefixOutput: true}, ioutils.Streams{Stdout: &stdout, Stderr: &stderr}, &log)
+		fmt.Fprintln(streams.Stdout, "out")
+		fmt.Fprintln(streams.Stderr, "err")
+
+		g.Expect(stdout.String()).To(Equal("[lint] out\n"))
+		g.Expect(stderr.String()).To(Equal("[lint] err\n"))
+		g.Expect(log.String()).To(Equal("err\n"))
+	})
+}
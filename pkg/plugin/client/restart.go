@@ -0,0 +1,325 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	goplugin "github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils/prompt"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/plugin"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/proto"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/types"
+)
+
+// exitTimeout is how long to wait for the process of a plugin whose call failed
+// as unavailable to be seen as exited.
+const exitTimeout = time.Second
+
+// restartingPlugin relaunches a plugin whose process exited unexpectedly and
+// retries the call that failed, replaying its setup and the registration of
+// its custom commands first. Build events are acknowledged by a successful
+// BEPEventCallback, so delivery resumes at the first unacknowledged event.
+type restartingPlugin struct {
+	name        string
+	maxRestarts int
+	launch      func() (*PluginInstance, error)
+	logger      hclog.Logger
+
+	mu          sync.Mutex
+	current     *PluginInstance
+	restarts    int
+	killed      bool
+	setupConfig *plugin.SetupConfig
+	commands    bool
+}
+
+var _ plugin.Plugin = (*restartingPlugin)(nil)
+var _ plugin.BEPStreamCallback = (*restartingPlugin)(nil)
+var _ Provider = (*restartingPlugin)(nil)
+var _ CustomCommandExecutor = (*restartingPlugin)(nil)
+
+// withRestartPolicy returns the plugin instance wrapped so that it is
+// restarted according to the restart policy of the plugin config.
+func withRestartPolicy(aspectplugin types.PluginConfig, instance *PluginInstance, launch func() (*PluginInstance, error)) *PluginInstance {
+	if aspectplugin.Restart != types.RestartOnFailure {
+		return instance
+	}
+
+	maxRestarts := aspectplugin.MaxRestarts
+	if maxRestarts == 0 {
+		maxRestarts = types.DefaultMaxRestarts
+	}
+
+	r := &restartingPlugin{
+		name:        aspectplugin.Name,
+		maxRestarts: maxRestarts,
+		launch:      launch,
+		logger:      newPluginLogger(aspectplugin),
+		current:     instance,
+	}
+
+	res := *instance
+	res.Plugin = r
+	res.Provider = r
+	if instance.CustomCommandExecutor != nil {
+		res.CustomCommandExecutor = r
+	}
+	return &res
+}
+
+// instance returns the running plugin, restarting it if its process exited.
+func (r *restartingPlugin) instance() (*PluginInstance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.killed || !exited(r.current) {
+		return r.current, nil
+	}
+	if r.restarts >= r.maxRestarts {
+		return nil, fmt.Errorf("plugin %q exited unexpectedly and was already restarted %d times", r.name, r.restarts)
+	}
+
+	r.restarts++
+	r.logger.Warn(fmt.Sprintf("plugin exited unexpectedly, restarting it (%d of %d)", r.restarts, r.maxRestarts))
+
+	// Release what is left of the exited plugin, such as its connection.
+	r.current.Kill()
+
+	instance, err := r.launch()
+	if err != nil {
+		return nil, fmt.Errorf("failed to restart plugin %q: %w", r.name, err)
+	}
+	if r.setupConfig != nil {
+		if err := instance.Setup(r.setupConfig); err != nil {
+			instance.Kill()
+			return nil, fmt.Errorf("failed to restart plugin %q: %w", r.name, err)
+		}
+	}
+	if r.commands {
+		// The plugin only runs the custom commands it returned before.
+		if _, err := instance.CustomCommands(); err != nil {
+			instance.Kill()
+			return nil, fmt.Errorf("failed to restart plugin %q: %w", r.name, err)
+		}
+	}
+
+	r.current = instance
+	return instance, nil
+}
+
+// call runs fn on the running plugin, retrying it on a restarted plugin if the
+// plugin process exited.
+func (r *restartingPlugin) call(fn func(*PluginInstance) error) error {
+	for {
+		instance, err := r.instance()
+		if err != nil {
+			return err
+		}
+		err = fn(instance)
+		if err == nil || !crashed(instance, err) {
+			return err
+		}
+	}
+}
+
+// exited returns true if the process of the plugin exited.
+func exited(instance *PluginInstance) bool {
+	goclient, ok := instance.Provider.(interface{ Exited() bool })
+	return ok && goclient.Exited()
+}
+
+// crashed returns true if the call to the plugin failed because its process
+// exited.
+func crashed(instance *PluginInstance, err error) bool {
+	if status.Code(err) != codes.Unavailable {
+		return false
+	}
+	// The call may fail before the exit of the process is noticed.
+	deadline := time.Now().Add(exitTimeout)
+	for !exited(instance) {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
+}
+
+// BEPEventCallback satisfies plugin.Plugin.
+func (r *restartingPlugin) BEPEventCallback(event *buildeventstream.BuildEvent, sn int64, invocationId string) error {
+	return r.call(func(instance *PluginInstance) error {
+		return instance.BEPEventCallback(event, sn, invocationId)
+	})
+}
+
+// BEPStreamEventCallback satisfies plugin.BEPStreamCallback.
+func (r *restartingPlugin) BEPStreamEventCallback(event *buildeventstream.BuildEvent, sn int64, stream *proto.BEPStream) error {
+	return r.call(func(instance *PluginInstance) error {
+		return instance.BEPStreamEventCallback(event, sn, stream)
+	})
+}
+
+// BEPEventTypes satisfies plugin.Plugin.
+func (r *restartingPlugin) BEPEventTypes() ([]string, error) {
+	var eventTypes []string
+	err := r.call(func(instance *PluginInstance) (err error) {
+		eventTypes, err = instance.BEPEventTypes()
+		return err
+	})
+	return eventTypes, err
+}
+
+// CustomCommands satisfies plugin.Plugin.
+func (r *restartingPlugin) CustomCommands() ([]*plugin.Command, error) {
+	var commands []*plugin.Command
+	err := r.call(func(instance *PluginInstance) (err error) {
+		commands, err = instance.CustomCommands()
+		return err
+	})
+	if err == nil {
+		r.mu.Lock()
+		r.commands = true
+		r.mu.Unlock()
+	}
+	return commands, err
+}
+
+// Daemons satisfies plugin.Plugin.
+func (r *restartingPlugin) Daemons() ([]*proto.Daemon, error) {
+	var daemons []*proto.Daemon
+	err := r.call(func(instance *PluginInstance) (err error) {
+		daemons, err = instance.Daemons()
+		return err
+	})
+	return daemons, err
+}
+
+// FiltersOutput satisfies plugin.Plugin.
+func (r *restartingPlugin) FiltersOutput() (bool, error) {
+	var filtersOutput bool
+	err := r.call(func(instance *PluginInstance) (err error) {
+		filtersOutput, err = instance.FiltersOutput()
+		return err
+	})
+	return filtersOutput, err
+}
+
+// FilterOutputLine satisfies plugin.Plugin.
+func (r *restartingPlugin) FilterOutputLine(stream proto.OutputStream, line string) ([]string, error) {
+	var lines []string
+	err := r.call(func(instance *PluginInstance) (err error) {
+		lines, err = instance.FilterOutputLine(stream, line)
+		return err
+	})
+	return lines, err
+}
+
+// PostBuildHook satisfies plugin.Plugin.
+func (r *restartingPlugin) PostBuildHook(invocation *proto.InvocationContext, promptRunner prompt.PromptRunner) error {
+	return r.call(func(instance *PluginInstance) error {
+		return instance.PostBuildHook(invocation, promptRunner)
+	})
+}
+
+// PostTestHook satisfies plugin.Plugin.
+func (r *restartingPlugin) PostTestHook(invocation *proto.InvocationContext, promptRunner prompt.PromptRunner) error {
+	return r.call(func(instance *PluginInstance) error {
+		return instance.PostTestHook(invocation, promptRunner)
+	})
+}
+
+// PostRunHook satisfies plugin.Plugin.
+func (r *restartingPlugin) PostRunHook(invocation *proto.InvocationContext, promptRunner prompt.PromptRunner) error {
+	return r.call(func(instance *PluginInstance) error {
+		return instance.PostRunHook(invocation, promptRunner)
+	})
+}
+
+// PropertiesSchema satisfies plugin.Plugin.
+func (r *restartingPlugin) PropertiesSchema() ([]*proto.Property, error) {
+	var properties []*proto.Property
+	err := r.call(func(instance *PluginInstance) (err error) {
+		properties, err = instance.PropertiesSchema()
+		return err
+	})
+	return properties, err
+}
+
+// RewriteArgs satisfies plugin.Plugin.
+func (r *restartingPlugin) RewriteArgs(command string, args []string) ([]string, error) {
+	var rewritten []string
+	err := r.call(func(instance *PluginInstance) (err error) {
+		rewritten, err = instance.RewriteArgs(command, args)
+		return err
+	})
+	return rewritten, err
+}
+
+// Setup satisfies plugin.Plugin. The config is replayed when the plugin is
+// restarted.
+func (r *restartingPlugin) Setup(config *plugin.SetupConfig) error {
+	err := r.call(func(instance *PluginInstance) error {
+		return instance.Setup(config)
+	})
+	if err == nil {
+		r.mu.Lock()
+		r.setupConfig = config
+		r.mu.Unlock()
+	}
+	return err
+}
+
+// ExecuteCustomCommand satisfies CustomCommandExecutor.
+func (r *restartingPlugin) ExecuteCustomCommand(cmdName string, ctx context.Context, args []string, bazelStartupArgs []string) error {
+	return r.call(func(instance *PluginInstance) error {
+		return instance.ExecuteCustomCommand(cmdName, ctx, args, bazelStartupArgs)
+	})
+}
+
+// Client satisfies Provider.
+func (r *restartingPlugin) Client() (goplugin.ClientProtocol, error) {
+	instance, err := r.instance()
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client()
+}
+
+// Kill satisfies Provider. The plugin isn't restarted once killed.
+func (r *restartingPlugin) Kill() {
+	r.mu.Lock()
+	r.killed = true
+	instance := r.current
+	r.mu.Unlock()
+	instance.Kill()
+}
+
+// ForceKill kills the running plugin with SIGKILL.
+func (r *restartingPlugin) ForceKill() {
+	r.mu.Lock()
+	instance := r.current
+	r.mu.Unlock()
+	instance.ForceKill()
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package client
+
+import (
+	"testing"
+
+	goplugin "github.com/hashicorp/go-plugin"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/plugin"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/types"
+)
+
+type fakeProvider struct {
+	exited bool
+	killed bool
+}
+
+func (p *fakeProvider) Client() (goplugin.ClientProtocol, error) { return nil, nil }
+func (p *fakeProvider) Kill()                                    { p.killed = true }
+func (p *fakeProvider) Exited() bool                             { return p.exited }
+
+// fakePlugin crashes its provider on the BEP event with the sequence number
+// crashAt.
+type fakePlugin struct {
+	plugin.Base
+	provider    *fakeProvider
+	crashAt     int64
+	setupConfig *plugin.SetupConfig
+	events      []int64
+}
+
+func (p *fakePlugin) Setup(config *plugin.SetupConfig) error {
+	p.setupConfig = config
+	return nil
+}
+
+func (p *fakePlugin) BEPEventCallback(_ *buildeventstream.BuildEvent, sn int64, _ string) error {
+	if sn == p.crashAt {
+		p.provider.exited = true
+		return status.Error(codes.Unavailable, "connection closed")
+	}
+	p.events = append(p.events, sn)
+	return nil
+}
+
+func newFakeInstance(crashAt int64) (*PluginInstance, *fakePlugin) {
+	p := &fakePlugin{provider: &fakeProvider{}, crashAt: crashAt}
+	return &PluginInstance{Plugin: p, Name: "fake", Provider: p.provider}, p
+}
+
+func TestRestartPolicy(t *testing.T) {
+	t.Run("doesn't wrap plugins without the on-failure policy", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		instance, _ := newFakeInstance(0)
+
+		for _, restart := range []string{"", types.RestartNever} {
+			res := withRestartPolicy(types.PluginConfig{Name: "fake", Restart: restart}, instance, nil)
+			g.Expect(res).To(BeIdenticalTo(instance))
+		}
+	})
+
+	t.Run("restarts a crashed plugin, replays setup and retries the event", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		first, firstPlugin := newFakeInstance(2)
+		second, secondPlugin := newFakeInstance(0)
+		launches := 0
+		launch := func() (*PluginInstance, error) {
+			launches++
+			return second, nil
+		}
+
+		instance := withRestartPolicy(types.PluginConfig{Name: "fake", Restart: types.RestartOnFailure}, first, launch)
+
+		setupConfig := &plugin.SetupConfig{}
+		g.Expect(instance.Setup(setupConfig)).To(Succeed())
+		for sn := int64(1); sn <= 3; sn++ {
+			g.Expect(instance.BEPEventCallback(nil, sn, "invocation")).To(Succeed())
+		}
+
+		g.Expect(launches).To(Equal(1))
+		g.Expect(firstPlugin.provider.killed).To(BeTrue())
+		g.Expect(firstPlugin.events).To(Equal([]int64{1}))
+		g.Expect(secondPlugin.setupConfig).To(BeIdenticalTo(setupConfig))
+		g.Expect(secondPlugin.events).To(Equal([]int64{2, 3}))
+	})
+
+	t.Run("gives up after the maximum number of restarts", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		first, _ := newFakeInstance(1)
+		launches := 0
+		launch := func() (*PluginInstance, error) {
+			launches++
+			instance, _ := newFakeInstance(1)
+			return instance, nil
+		}
+
+		instance := withRestartPolicy(types.PluginConfig{Name: "fake", Restart: types.RestartOnFailure, MaxRestarts: 2}, first, launch)
+
+		err := instance.BEPEventCallback(nil, 1, "invocation")
+		g.Expect(err).To(MatchError(`plugin "fake" exited unexpectedly and was already restarted 2 times`))
+		g.Expect(launches).To(Equal(2))
+	})
+
+	t.Run("doesn't restart a killed plugin", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		first, firstPlugin := newFakeInstance(0)
+		launch := func() (*PluginInstance, error) {
+			t.Fatal("killed plugin was restarted")
+			return nil, nil
+		}
+
+		instance := withRestartPolicy(types.PluginConfig{Name: "fake", Restart: types.RestartOnFailure}, first, launch)
+		instance.Kill()
+		firstPlugin.provider.exited = true
+
+		g.Expect(firstPlugin.provider.killed).To(BeTrue())
+		g.Expect(instance.BEPEventCallback(nil, 1, "invocation")).To(Succeed())
+	})
+}
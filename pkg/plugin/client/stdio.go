@@ -0,0 +1,365 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+
+	goplugin "github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"gopkg.in/yaml.v3"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils/prompt"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/plugin"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/proto"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/stdio"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/types"
+)
+
+// stdioPlugin runs a plain executable that speaks the newline-delimited JSON
+// protocol of the stdio package over its stdin and stdout. It satisfies
+// plugin.Plugin and Provider so it can be used in place of a go-plugin
+// subprocess. The methods the plugin doesn't handle behave like plugin.Base.
+type stdioPlugin struct {
+	name    string
+	stdin   io.WriteCloser
+	process *os.Process
+
+	// mu serializes requests, the protocol allows one in flight at a time.
+	mu     sync.Mutex
+	nextID int64
+
+	responses chan *stdio.Response
+	exited    chan struct{}
+
+	// methods and eventTypes are returned by the plugin on setup.
+	methods    []string
+	eventTypes []string
+}
+
+var _ plugin.Plugin = (*stdioPlugin)(nil)
+var _ plugin.BEPStreamCallback = (*stdioPlugin)(nil)
+var _ Provider = (*stdioPlugin)(nil)
+
+// newStdioPlugin runs the plugin executable. What it writes to stderr is
+// written to stderr, and the lines it writes to stdout that aren't responses
+// to stdout.
+func newStdioPlugin(aspectplugin types.PluginConfig, streams ioutils.Streams) (*stdioPlugin, error) {
+	cmd := pluginCmd(aspectplugin)
+	cmd.Stderr = streams.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run plugin %q: %w", aspectplugin.Name, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run plugin %q: %w", aspectplugin.Name, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to run plugin %q: %w", aspectplugin.Name, err)
+	}
+
+	p := serveStdioPlugin(aspectplugin.Name, stdin, stdout, streams.Stdout, cmd.Wait)
+	p.process = cmd.Process
+	return p, nil
+}
+
+// serveStdioPlugin returns a stdioPlugin writing requests to stdin and reading
+// responses from stdout until it is closed, after which wait is called.
+func serveStdioPlugin(name string, stdin io.WriteCloser, stdout io.Reader, output io.Writer, wait func() error) *stdioPlugin {
+	p := &stdioPlugin{
+		name:      name,
+		stdin:     stdin,
+		responses: make(chan *stdio.Response, 16),
+		exited:    make(chan struct{}),
+	}
+	go p.read(stdout, output, wait)
+	return p
+}
+
+func (p *stdioPlugin) read(stdout io.Reader, output io.Writer, wait func() error) {
+	defer close(p.exited)
+
+	reader := bufio.NewReader(stdout)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			res := &stdio.Response{}
+			if json.Unmarshal(line, res) == nil && res.ID != nil {
+				// A response nobody waits for is dropped rather than blocking the
+				// output of the plugin.
+				select {
+				case p.responses <- res:
+				default:
+				}
+			} else {
+				output.Write(line)
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	wait()
+}
+
+// call sends a request to the plugin and decodes the result of its response
+// into result, unless result is nil. The call fails as unavailable when the
+// plugin exits before responding, so that it may be restarted.
+func (p *stdioPlugin) call(method string, params any, result any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	b, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	p.nextID++
+	id := p.nextID
+	req, err := json.Marshal(&stdio.Request{ID: id, Method: method, Params: b})
+	if err != nil {
+		return err
+	}
+	if _, err := p.stdin.Write(append(req, '\n')); err != nil {
+		return status.Errorf(codes.Unavailable, "plugin %q exited: %v", p.name, err)
+	}
+
+	for {
+		var res *stdio.Response
+		select {
+		case res = <-p.responses:
+		case <-p.exited:
+			// The response may have been read right before the plugin exited.
+			select {
+			case res = <-p.responses:
+			default:
+				return status.Errorf(codes.Unavailable, "plugin %q exited without responding to %s", p.name, method)
+			}
+		}
+
+		// Responses to earlier requests that timed out or were answered twice.
+		if *res.ID != id {
+			continue
+		}
+		if res.Error != "" {
+			return errors.New(res.Error)
+		}
+		if result == nil || len(res.Result) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(res.Result, result); err != nil {
+			return fmt.Errorf("invalid response of plugin %q to %s: %w", p.name, method, err)
+		}
+		return nil
+	}
+}
+
+func (p *stdioPlugin) handles(method string) bool {
+	return slices.Contains(p.methods, method)
+}
+
+// Setup satisfies plugin.Plugin.
+func (p *stdioPlugin) Setup(config *plugin.SetupConfig) error {
+	properties := map[string]any{}
+	if err := yaml.Unmarshal(config.Properties, &properties); err != nil {
+		return fmt.Errorf("failed to decode the properties of plugin %q: %w", p.name, err)
+	}
+
+	params := &stdio.SetupParams{
+		ProtocolVersion: stdio.ProtocolVersion,
+		Properties:      properties,
+	}
+	if w := config.Workspace; w != nil {
+		params.Workspace = &stdio.Workspace{
+			Root:          w.Root,
+			OutputBase:    w.OutputBase,
+			ExecutionRoot: w.ExecutionRoot,
+			BazelVersion:  w.BazelVersion,
+			CLIVersion:    w.CLIVersion,
+		}
+	}
+	result := &stdio.SetupResult{}
+	if err := p.call(stdio.MethodSetup, params, result); err != nil {
+		return err
+	}
+	p.methods = result.Methods
+	p.eventTypes = result.EventTypes
+	return nil
+}
+
+// BEPEventCallback satisfies plugin.Plugin.
+func (p *stdioPlugin) BEPEventCallback(event *buildeventstream.BuildEvent, sn int64, invocationId string) error {
+	return p.BEPStreamEventCallback(event, sn, &proto.BEPStream{InvocationId: invocationId})
+}
+
+// BEPStreamEventCallback satisfies plugin.BEPStreamCallback.
+func (p *stdioPlugin) BEPStreamEventCallback(event *buildeventstream.BuildEvent, sn int64, stream *proto.BEPStream) error {
+	if !p.handles(stdio.MethodBEPEvent) {
+		return nil
+	}
+	b, err := protojson.Marshal(event)
+	if err != nil {
+		return err
+	}
+	params := &stdio.BEPEventParams{
+		Event:          b,
+		SequenceNumber: sn,
+		InvocationID:   stream.GetInvocationId(),
+		BuildID:        stream.GetBuildId(),
+		Command:        stream.GetCommand(),
+	}
+	return p.call(stdio.MethodBEPEvent, params, nil)
+}
+
+// BEPEventTypes satisfies plugin.Plugin.
+func (p *stdioPlugin) BEPEventTypes() ([]string, error) {
+	return p.eventTypes, nil
+}
+
+// CustomCommands satisfies plugin.Plugin. The protocol has no custom commands.
+func (p *stdioPlugin) CustomCommands() ([]*plugin.Command, error) {
+	return nil, nil
+}
+
+// Daemons satisfies plugin.Plugin. The protocol has no daemons.
+func (p *stdioPlugin) Daemons() ([]*proto.Daemon, error) {
+	return nil, nil
+}
+
+// FiltersOutput satisfies plugin.Plugin. The protocol has no output filters.
+func (p *stdioPlugin) FiltersOutput() (bool, error) {
+	return false, nil
+}
+
+// FilterOutputLine satisfies plugin.Plugin.
+func (p *stdioPlugin) FilterOutputLine(stream proto.OutputStream, line string) ([]string, error) {
+	return []string{line}, nil
+}
+
+// PostBuildHook satisfies plugin.Plugin.
+func (p *stdioPlugin) PostBuildHook(invocation *proto.InvocationContext, promptRunner prompt.PromptRunner) error {
+	return p.callHook(stdio.MethodPostBuildHook, invocation)
+}
+
+// PostTestHook satisfies plugin.Plugin.
+func (p *stdioPlugin) PostTestHook(invocation *proto.InvocationContext, promptRunner prompt.PromptRunner) error {
+	return p.callHook(stdio.MethodPostTestHook, invocation)
+}
+
+// PostRunHook satisfies plugin.Plugin.
+func (p *stdioPlugin) PostRunHook(invocation *proto.InvocationContext, promptRunner prompt.PromptRunner) error {
+	return p.callHook(stdio.MethodPostRunHook, invocation)
+}
+
+// callHook calls a hook of the plugin and returns its result as a
+// plugin.HookResult. Plugins can't prompt the user, as their stdin is taken by
+// the protocol.
+func (p *stdioPlugin) callHook(method string, invocation *proto.InvocationContext) error {
+	if !p.handles(method) {
+		return nil
+	}
+	b, err := protojson.Marshal(invocation)
+	if err != nil {
+		return err
+	}
+	result := &stdio.HookResult{}
+	if err := p.call(method, &stdio.HookParams{Invocation: b}, result); err != nil {
+		return err
+	}
+
+	switch result.Outcome {
+	case "":
+		return nil
+	case stdio.OutcomeFail:
+		return plugin.FailCommand(result.Message)
+	case stdio.OutcomeExitCode:
+		return plugin.ExitWithCode(result.ExitCode, result.Message)
+	case stdio.OutcomeWarn:
+		return plugin.DowngradeToWarning(result.Message)
+	}
+	return fmt.Errorf("plugin %q returned unknown outcome %q from %s", p.name, result.Outcome, method)
+}
+
+// PropertiesSchema satisfies plugin.Plugin. The properties of stdio plugins
+// are not validated.
+func (p *stdioPlugin) PropertiesSchema() ([]*proto.Property, error) {
+	return nil, nil
+}
+
+// RewriteArgs satisfies plugin.Plugin. The protocol doesn't rewrite args.
+func (p *stdioPlugin) RewriteArgs(command string, args []string) ([]string, error) {
+	return args, nil
+}
+
+// Client satisfies Provider. Stdio plugins don't speak the go-plugin protocol.
+func (p *stdioPlugin) Client() (goplugin.ClientProtocol, error) {
+	return nil, errors.New("stdio plugins do not have a go-plugin client")
+}
+
+// Kill satisfies Provider by closing the stdin of the plugin and waiting for
+// it to exit.
+func (p *stdioPlugin) Kill() {
+	p.stdin.Close()
+	<-p.exited
+}
+
+// ForceKill kills the plugin process with SIGKILL.
+func (p *stdioPlugin) ForceKill() {
+	if p.process != nil {
+		p.process.Kill()
+	}
+}
+
+// Exited reports whether the plugin process exited.
+func (p *stdioPlugin) Exited() bool {
+	select {
+	case <-p.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+func newStdioPluginInstance(aspectplugin types.PluginConfig, streams ioutils.Streams) (*PluginInstance, error) {
+	stdioplugin, err := newStdioPlugin(aspectplugin, streams)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &PluginInstance{
+		Plugin:           stdioplugin,
+		Name:             aspectplugin.Name,
+		SDK:              SDKStdio,
+		Provider:         stdioplugin,
+		MultiThreaded:    aspectplugin.MultiThreadedBuildEvents,
+		DisableBESEvents: aspectplugin.DisableBESEvents,
+		TeardownTimeout:  aspectplugin.TeardownTimeout,
+	}
+	res.setBuildEventDelivery(aspectplugin)
+	return res, nil
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/plugin"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/proto"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/stdio"
+)
+
+// fakeStdioPlugin answers the requests of a stdioPlugin with the responses
+// returned by handle until handle returns nil, which makes it exit.
+func fakeStdioPlugin(handle func(req *stdio.Request) []string) (*stdioPlugin, *bytes.Buffer) {
+	stdinReader, stdinWriter := io.Pipe()
+	stdoutReader, stdoutWriter := io.Pipe()
+	var output bytes.Buffer
+
+	go func() {
+		defer stdoutWriter.Close()
+		scanner := bufio.NewScanner(stdinReader)
+		for scanner.Scan() {
+			req := &stdio.Request{}
+			if err := json.Unmarshal(scanner.Bytes(), req); err != nil {
+				panic(err)
+			}
+			lines := handle(req)
+			if lines == nil {
+				stdinReader.Close()
+				return
+			}
+			for _, line := range lines {
+				io.WriteString(stdoutWriter, line+"\n")
+			}
+		}
+	}()
+
+	return serveStdioPlugin("fake", stdinWriter, stdoutReader, &output, func() error { return nil }), &output
+}
+
+func TestStdioPlugin(t *testing.T) {
+	t.Run("only calls the methods the plugin handles", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var requests []*stdio.Request
+		p, output := fakeStdioPlugin(func(req *stdio.Request) []string {
+			requests = append(requests, req)
+			switch req.Method {
+			case stdio.MethodSetup:
+				return []string{"setting up", `{"id":1,"result":{"methods":["bep_event"],"event_types":["started"]}}`}
+			default:
+				return []string{`{"id":2,"result":{}}`}
+			}
+		})
+		defer p.Kill()
+
+		g.Expect(p.Setup(plugin.NewSetupConfig([]byte("greeting: hello\n"), &plugin.Workspace{Root: "/ws", BazelVersion: "7.4.1"}))).To(Succeed())
+		g.Expect(p.BEPEventTypes()).To(Equal([]string{"started"}))
+		g.Expect(output.String()).To(Equal("setting up\n"))
+
+		event := &buildeventstream.BuildEvent{
+			Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_Started{Started: &buildeventstream.BuildEventId_BuildStartedId{}}},
+		}
+		stream := &proto.BEPStream{BuildId: "build", InvocationId: "invocation", Command: "test"}
+		g.Expect(p.BEPStreamEventCallback(event, 7, stream)).To(Succeed())
+		g.Expect(p.PostBuildHook(&proto.InvocationContext{Command: "build"}, nil)).To(Succeed())
+
+		g.Expect(requests).To(HaveLen(2))
+		setup := &stdio.SetupParams{}
+		g.Expect(json.Unmarshal(requests[0].Params, setup)).To(Succeed())
+		g.Expect(setup.ProtocolVersion).To(Equal(stdio.ProtocolVersion))
+		g.Expect(setup.Properties).To(Equal(map[string]any{"greeting": "hello"}))
+		g.Expect(setup.Workspace).To(Equal(&stdio.Workspace{Root: "/ws", BazelVersion: "7.4.1"}))
+
+		g.Expect(requests[1].Method).To(Equal(stdio.MethodBEPEvent))
+		bepEvent := &stdio.BEPEventParams{}
+		g.Expect(json.Unmarshal(requests[1].Params, bepEvent)).To(Succeed())
+		g.Expect(bepEvent.SequenceNumber).To(Equal(int64(7)))
+		g.Expect(bepEvent.InvocationID).To(Equal("invocation"))
+		g.Expect(bepEvent.BuildID).To(Equal("build"))
+		g.Expect(bepEvent.Command).To(Equal("test"))
+		g.Expect(string(bepEvent.Event)).To(ContainSubstring(`"started"`))
+	})
+
+	t.Run("maps the outcome of hooks to a hook result", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		p, _ := fakeStdioPlugin(func(req *stdio.Request) []string {
+			switch req.Method {
+			case stdio.MethodSetup:
+				return []string{`{"id":1,"result":{"methods":["post_build_hook","post_test_hook","post_run_hook"]}}`}
+			case stdio.MethodPostBuildHook:
+				return []string{`{"id":2,"result":{"outcome":"exit_code","exit_code":3,"message":"flaky"}}`}
+			case stdio.MethodPostTestHook:
+				return []string{`{"id":3,"result":{"outcome":"warn","message":"tolerated"}}`}
+			default:
+				return []string{`{"id":4,"error":"boom"}`}
+			}
+		})
+		defer p.Kill()
+
+		g.Expect(p.Setup(plugin.NewSetupConfig(nil, nil))).To(Succeed())
+		g.Expect(p.PostBuildHook(&proto.InvocationContext{}, nil)).To(Equal(plugin.ExitWithCode(3, "flaky")))
+		g.Expect(p.PostTestHook(&proto.InvocationContext{}, nil)).To(Equal(plugin.DowngradeToWarning("tolerated")))
+		g.Expect(p.PostRunHook(&proto.InvocationContext{}, nil)).To(MatchError("boom"))
+	})
+
+	t.Run("fails as unavailable when the plugin exits", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		p, _ := fakeStdioPlugin(func(req *stdio.Request) []string {
+			return nil
+		})
+		defer p.Kill()
+
+		err := p.Setup(plugin.NewSetupConfig(nil, nil))
+		g.Expect(status.Code(err)).To(Equal(codes.Unavailable))
+		g.Expect(p.Exited()).To(BeTrue())
+	})
+}
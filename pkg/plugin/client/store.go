@@ -0,0 +1,141 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils/cache"
+)
+
+// The plugin store is the content-addressed cache of plugin binaries, shared by
+// every workspace and version of a plugin. A binary is stored once under
+// <cache>/aspect/plugins/sha256/<sha256>, no matter how many plugin names,
+// versions or URLs it is downloaded from. The index maps the sources binaries
+// were downloaded from to their sha256, so that they are not downloaded again.
+const (
+	pluginStoreDir = "sha256"
+	pluginIndexDir = "index"
+)
+
+// pluginsCacheDir returns the given directory of the plugins cache, creating it
+// if needed.
+func pluginsCacheDir(dir string) (string, error) {
+	aspectCacheDir, err := cache.AspectCacheDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(aspectCacheDir, "plugins", dir)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return "", fmt.Errorf("could not create directory %s: %v", path, err)
+	}
+	return path, nil
+}
+
+// storedPluginPath returns the path of the binary with the given sha256 for the
+// platform in the plugin store. Binaries keep the extension of their platform,
+// since Windows only runs .exe files and WebAssembly plugins are recognized by
+// their .wasm extension.
+func storedPluginPath(storeDir string, sha256Hex string, platform string) string {
+	ext := ""
+	switch {
+	case platform == WasmPlatform:
+		ext = ".wasm"
+	case strings.HasPrefix(platform, "windows_"):
+		ext = ".exe"
+	}
+	return filepath.Join(storeDir, strings.ToLower(sha256Hex)+ext)
+}
+
+// storedPlugin returns the path of the binary with the given sha256 in the
+// plugin store, and false when the store doesn't have it.
+func storedPlugin(sha256Hex string, platform string) (string, bool, error) {
+	storeDir, err := pluginsCacheDir(pluginStoreDir)
+	if err != nil {
+		return "", false, err
+	}
+	path := storedPluginPath(storeDir, sha256Hex, platform)
+	if _, err := os.Stat(path); err != nil {
+		return "", false, nil
+	}
+	return path, true, nil
+}
+
+// storePlugin moves the binary at path, which must be in the plugin store
+// directory, to its place in the store and returns its new path and sha256.
+func storePlugin(path string, platform string) (string, []byte, error) {
+	digest, err := fileDigest(path)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := os.Chmod(path, 0755); err != nil {
+		return "", nil, fmt.Errorf("could not chmod file %s: %v", path, err)
+	}
+	stored := storedPluginPath(filepath.Dir(path), hex.EncodeToString(digest), platform)
+	if err := os.Rename(path, stored); err != nil {
+		return "", nil, fmt.Errorf("could not move %s to %s: %v", path, stored, err)
+	}
+	return stored, digest, nil
+}
+
+// indexedPlugin returns the sha256 of the binary last downloaded from the given
+// source, or an empty string when it was never downloaded.
+func indexedPlugin(source string) (string, error) {
+	indexDir, err := pluginsCacheDir(pluginIndexDir)
+	if err != nil {
+		return "", err
+	}
+	b, err := os.ReadFile(filepath.Join(indexDir, indexKey(source)))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// indexPlugin records the sha256 of the binary downloaded from the given source.
+func indexPlugin(source string, digest []byte) error {
+	indexDir, err := pluginsCacheDir(pluginIndexDir)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(indexDir, "index-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(hex.EncodeToString(digest)); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(indexDir, indexKey(source)))
+}
+
+func indexKey(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,71 @@
+//go:build wasip1
+
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// wasmplugin is the guest that wasm_test.go loads through the ABI of the wasm
+// SDK. It reports what it is called with on stdout.
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/proto"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/wasm"
+)
+
+type testPlugin struct {
+	wasm.Base
+	properties string
+}
+
+func (p *testPlugin) Setup(properties []byte, workspace *proto.Workspace) error {
+	if string(properties) == "fail" {
+		return errors.New("setup failed")
+	}
+	p.properties = string(properties)
+	fmt.Printf("setup %s %s\n", p.properties, workspace.GetRoot())
+	return nil
+}
+
+func (p *testPlugin) BEPEventTypes() ([]string, error) {
+	return []string{"started"}, nil
+}
+
+func (p *testPlugin) BEPEventCallback(event *buildeventstream.BuildEvent, sn int64, invocationId string) error {
+	fmt.Printf("event %d %s %s\n", sn, invocationId, event.GetStarted().GetCommand())
+	return nil
+}
+
+func (p *testPlugin) PostBuildHook(invocation *proto.InvocationContext, promptRunner wasm.PromptRunner) error {
+	name, err := promptRunner.Run(&proto.PromptRunReq{Label: "Name"})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("hello %s from %s\n", name, p.properties)
+	if !invocation.GetIsInteractiveMode() {
+		return wasm.FailCommand("not interactive")
+	}
+	return nil
+}
+
+func init() {
+	wasm.Serve(&testPlugin{})
+}
+
+func main() {}
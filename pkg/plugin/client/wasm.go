@@ -0,0 +1,438 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/manifoldco/promptui"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	protobuf "google.golang.org/protobuf/proto"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils/cache"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils/prompt"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/plugin"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/proto"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/wasm"
+)
+
+// isWasmPlugin reports whether the plugin binary at path is a WebAssembly
+// module that should run in-process.
+func isWasmPlugin(path string) bool {
+	return strings.HasSuffix(path, ".wasm")
+}
+
+// wasmPlugin runs a plugin compiled to WebAssembly in-process using wazero.
+// It satisfies plugin.Plugin, CustomCommandExecutor and Provider so it can be
+// used in place of a go-plugin subprocess.
+type wasmPlugin struct {
+	// mu serializes calls into the guest, which is single threaded.
+	mu sync.Mutex
+
+	runtime wazero.Runtime
+	module  api.Module
+	malloc  api.Function
+	free    api.Function
+	call    api.Function
+
+	// promptRunner and promptResult are only set while a hook is running.
+	promptRunner prompt.PromptRunner
+	promptResult []byte
+}
+
+var _ plugin.Plugin = (*wasmPlugin)(nil)
+var _ plugin.BEPStreamCallback = (*wasmPlugin)(nil)
+var _ CustomCommandExecutor = (*wasmPlugin)(nil)
+var _ Provider = (*wasmPlugin)(nil)
+
+// newWasmPlugin compiles and instantiates the WebAssembly plugin at path with
+// the given environment.
+func newWasmPlugin(name string, path string, env []string, streams ioutils.Streams) (*wasmPlugin, error) {
+	ctx := context.Background()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wasm plugin %q: %w", path, err)
+	}
+
+	// Closing the runtime on Kill also interrupts any function that is running.
+	runtimeConfig := wazero.NewRuntimeConfig().WithCloseOnContextDone(true)
+	// Compiling a module is the bulk of the startup cost, so share compiled
+	// modules across invocations through the aspect cache dir when possible.
+	if aspectCacheDir, err := cache.AspectCacheDir(); err == nil {
+		if compilationCache, err := wazero.NewCompilationCacheWithDir(filepath.Join(aspectCacheDir, "plugins", "wasm")); err == nil {
+			runtimeConfig = runtimeConfig.WithCompilationCache(compilationCache)
+		}
+	}
+
+	p := &wasmPlugin{
+		runtime: wazero.NewRuntimeWithConfig(ctx, runtimeConfig),
+	}
+
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, p.runtime); err != nil {
+		p.Kill()
+		return nil, fmt.Errorf("failed to instantiate wasi for plugin %q: %w", name, err)
+	}
+
+	_, err = p.runtime.NewHostModuleBuilder(wasm.HostModule).
+		NewFunctionBuilder().WithFunc(p.hostPromptRun).Export(wasm.PromptRunImport).
+		NewFunctionBuilder().WithFunc(p.hostPromptResult).Export(wasm.PromptResultImport).
+		Instantiate(ctx)
+	if err != nil {
+		p.Kill()
+		return nil, fmt.Errorf("failed to instantiate host module for plugin %q: %w", name, err)
+	}
+
+	compiled, err := p.runtime.CompileModule(ctx, b)
+	if err != nil {
+		p.Kill()
+		return nil, fmt.Errorf("failed to compile wasm plugin %q: %w", name, err)
+	}
+
+	// Plugins run with the same privileges as a subprocess plugin would, so
+	// they see the host filesystem and the environment allowed by their config.
+	moduleConfig := wazero.NewModuleConfig().
+		WithName(name).
+		WithArgs(name).
+		WithStdout(streams.Stdout).
+		WithStderr(streams.Stderr).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithSysNanosleep().
+		WithRandSource(rand.Reader).
+		WithFSConfig(wazero.NewFSConfig().WithDirMount("/", "/")).
+		// Plugins are reactor modules built with -buildmode=c-shared.
+		WithStartFunctions("_initialize")
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			moduleConfig = moduleConfig.WithEnv(k, v)
+		}
+	}
+
+	p.module, err = p.runtime.InstantiateModule(ctx, compiled, moduleConfig)
+	if err != nil {
+		p.Kill()
+		return nil, fmt.Errorf("failed to instantiate wasm plugin %q: %w", name, err)
+	}
+
+	p.malloc = p.module.ExportedFunction(wasm.MallocExport)
+	p.free = p.module.ExportedFunction(wasm.FreeExport)
+	p.call = p.module.ExportedFunction(wasm.CallExport)
+	if p.malloc == nil || p.free == nil || p.call == nil {
+		p.Kill()
+		return nil, fmt.Errorf("wasm plugin %q does not export the aspect plugin functions; was it built with the wasm SDK?", name)
+	}
+
+	return p, nil
+}
+
+// invoke encodes req into the guest memory, calls the given method and decodes
+// the result into res. Callers must hold p.mu.
+func (p *wasmPlugin) invoke(method wasm.Method, req protobuf.Message, res protobuf.Message) error {
+	ctx := context.Background()
+
+	b, err := protobuf.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	ret, err := p.malloc.Call(ctx, uint64(len(b)))
+	if err != nil {
+		return err
+	}
+	reqPtr := uint32(ret[0])
+	if !p.module.Memory().Write(reqPtr, b) {
+		return errors.New("wasm plugin request is out of range of the guest memory")
+	}
+
+	// The guest frees the request buffer.
+	ret, err = p.call.Call(ctx, uint64(method), uint64(reqPtr), uint64(len(b)))
+	if err != nil {
+		return err
+	}
+	resPtr, resSize := wasm.Unpack(ret[0])
+	out, ok := p.module.Memory().Read(resPtr, resSize)
+	if !ok || resSize == 0 {
+		return errors.New("wasm plugin result is out of range of the guest memory")
+	}
+	// Read returns a view of the guest memory, copy it before freeing.
+	out = append([]byte(nil), out...)
+	if _, err := p.free.Call(ctx, uint64(resPtr)); err != nil {
+		return err
+	}
+
+	if wasm.Status(out[0]) != wasm.StatusOK {
+		return errors.New(string(out[1:]))
+	}
+	return protobuf.Unmarshal(out[1:], res)
+}
+
+// hostPromptRun runs a prompt on behalf of the guest and returns the size of
+// the encoded proto.PromptRunRes, which the guest fetches with
+// hostPromptResult.
+func (p *wasmPlugin) hostPromptRun(ctx context.Context, m api.Module, ptr uint32, size uint32) uint32 {
+	res := &proto.PromptRunRes{}
+	if err := p.runPrompt(m, ptr, size, res); err != nil {
+		res.Error = &proto.PromptRunRes_Error{
+			Happened: true,
+			Message:  err.Error(),
+		}
+	}
+
+	b, err := protobuf.Marshal(res)
+	if err != nil {
+		panic(fmt.Errorf("failed to marshal prompt result: %w", err))
+	}
+	p.promptResult = b
+	return uint32(len(b))
+}
+
+func (p *wasmPlugin) runPrompt(m api.Module, ptr uint32, size uint32, res *proto.PromptRunRes) error {
+	if p.promptRunner == nil {
+		return errors.New("prompts are only available from within plugin hooks")
+	}
+
+	b, ok := m.Memory().Read(ptr, size)
+	if !ok {
+		return errors.New("prompt request is out of range of the guest memory")
+	}
+	req := &proto.PromptRunReq{}
+	if err := protobuf.Unmarshal(b, req); err != nil {
+		return err
+	}
+
+	prompt := promptui.Prompt{
+		Label:       req.GetLabel(),
+		Default:     req.GetDefault(),
+		AllowEdit:   req.GetAllowEdit(),
+		HideEntered: req.GetHideEntered(),
+		IsConfirm:   req.GetIsConfirm(),
+		IsVimMode:   req.GetIsVimMode(),
+	}
+	if mask := []rune(req.GetMask()); len(mask) > 0 {
+		prompt.Mask = mask[0]
+	}
+
+	result, err := p.promptRunner.Run(prompt)
+	if err != nil {
+		return err
+	}
+	res.Result = result
+	return nil
+}
+
+// hostPromptResult copies the pending prompt result into the guest buffer at
+// ptr, which must be at least the size returned by hostPromptRun.
+func (p *wasmPlugin) hostPromptResult(ctx context.Context, m api.Module, ptr uint32) {
+	if !m.Memory().Write(ptr, p.promptResult) {
+		panic(errors.New("prompt result is out of range of the guest memory"))
+	}
+	p.promptResult = nil
+}
+
+// BEPEventCallback satisfies plugin.Plugin.
+func (p *wasmPlugin) BEPEventCallback(event *buildeventstream.BuildEvent, sn int64, invocationId string) error {
+	return p.BEPStreamEventCallback(event, sn, &proto.BEPStream{InvocationId: invocationId})
+}
+
+// BEPStreamEventCallback satisfies plugin.BEPStreamCallback.
+func (p *wasmPlugin) BEPStreamEventCallback(event *buildeventstream.BuildEvent, sn int64, stream *proto.BEPStream) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	req := &proto.BEPEventCallbackReq{
+		Event:          event,
+		SequenceNumber: sn,
+		InvocationId:   stream.GetInvocationId(),
+		Stream:         stream,
+	}
+	return p.invoke(wasm.MethodBEPEventCallback, req, &proto.BEPEventCallbackRes{})
+}
+
+// BEPEventTypes satisfies plugin.Plugin.
+func (p *wasmPlugin) BEPEventTypes() ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	res := &proto.BEPEventTypesRes{}
+	if err := p.invoke(wasm.MethodBEPEventTypes, &proto.BEPEventTypesReq{}, res); err != nil {
+		return nil, err
+	}
+	return res.EventTypes, nil
+}
+
+// Setup satisfies plugin.Plugin.
+func (p *wasmPlugin) Setup(config *plugin.SetupConfig) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	req := &proto.SetupReq{
+		Properties: config.Properties,
+		Workspace:  plugin.WorkspaceToProto(config.Workspace),
+	}
+	return p.invoke(wasm.MethodSetup, req, &proto.SetupRes{})
+}
+
+// CustomCommands satisfies plugin.Plugin.
+func (p *wasmPlugin) CustomCommands() ([]*plugin.Command, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	res := &proto.CustomCommandsRes{}
+	if err := p.invoke(wasm.MethodCustomCommands, &proto.CustomCommandsReq{}, res); err != nil {
+		return nil, err
+	}
+
+	customCommands := make([]*plugin.Command, 0, len(res.Commands))
+	for _, pbCommand := range res.Commands {
+		customCommands = append(customCommands, &plugin.Command{Command: pbCommand})
+	}
+	return customCommands, nil
+}
+
+// ExecuteCustomCommand satisfies CustomCommandExecutor.
+func (p *wasmPlugin) ExecuteCustomCommand(customCommand string, ctx context.Context, args []string, bazelStartupArgs []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	req := &proto.ExecuteCustomCommandReq{
+		CustomCommand:    customCommand,
+		Ctx:              &proto.Context{},
+		Args:             args,
+		BazelStartupArgs: bazelStartupArgs,
+		Flags:            plugin.FlagsFromContext(ctx),
+	}
+	res := &proto.ExecuteCustomCommandRes{}
+	if err := p.invoke(wasm.MethodExecuteCustomCommand, req, res); err != nil {
+		return err
+	}
+	if res.DelegateToBuiltin {
+		return &plugin.BuiltinDelegation{Args: res.BuiltinArgs}
+	}
+	return nil
+}
+
+// Daemons satisfies plugin.Plugin.
+func (p *wasmPlugin) Daemons() ([]*proto.Daemon, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	res := &proto.DaemonsRes{}
+	if err := p.invoke(wasm.MethodDaemons, &proto.DaemonsReq{}, res); err != nil {
+		return nil, err
+	}
+	return res.Daemons, nil
+}
+
+// FiltersOutput satisfies plugin.Plugin.
+func (p *wasmPlugin) FiltersOutput() (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	res := &proto.FiltersOutputRes{}
+	if err := p.invoke(wasm.MethodFiltersOutput, &proto.FiltersOutputReq{}, res); err != nil {
+		return false, err
+	}
+	return res.FiltersOutput, nil
+}
+
+// FilterOutputLine satisfies plugin.Plugin.
+func (p *wasmPlugin) FilterOutputLine(stream proto.OutputStream, line string) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	res := &proto.FilterOutputLineRes{}
+	if err := p.invoke(wasm.MethodFilterOutputLine, &proto.FilterOutputLineReq{Stream: stream, Line: line}, res); err != nil {
+		return nil, err
+	}
+	return res.Lines, nil
+}
+
+// PostBuildHook satisfies plugin.Plugin.
+func (p *wasmPlugin) PostBuildHook(invocation *proto.InvocationContext, promptRunner prompt.PromptRunner) error {
+	req := &proto.PostBuildHookReq{IsInteractiveMode: invocation.GetIsInteractiveMode(), Invocation: invocation}
+	res := &proto.PostBuildHookRes{}
+	if err := p.invokeHook(wasm.MethodPostBuildHook, promptRunner, req, res); err != nil {
+		return err
+	}
+	return plugin.HookResultFromProto(res.GetResult())
+}
+
+// PostTestHook satisfies plugin.Plugin.
+func (p *wasmPlugin) PostTestHook(invocation *proto.InvocationContext, promptRunner prompt.PromptRunner) error {
+	req := &proto.PostTestHookReq{IsInteractiveMode: invocation.GetIsInteractiveMode(), Invocation: invocation}
+	res := &proto.PostTestHookRes{}
+	if err := p.invokeHook(wasm.MethodPostTestHook, promptRunner, req, res); err != nil {
+		return err
+	}
+	return plugin.HookResultFromProto(res.GetResult())
+}
+
+// PostRunHook satisfies plugin.Plugin.
+func (p *wasmPlugin) PostRunHook(invocation *proto.InvocationContext, promptRunner prompt.PromptRunner) error {
+	req := &proto.PostRunHookReq{IsInteractiveMode: invocation.GetIsInteractiveMode(), Invocation: invocation}
+	res := &proto.PostRunHookRes{}
+	if err := p.invokeHook(wasm.MethodPostRunHook, promptRunner, req, res); err != nil {
+		return err
+	}
+	return plugin.HookResultFromProto(res.GetResult())
+}
+
+// PropertiesSchema satisfies plugin.Plugin.
+func (p *wasmPlugin) PropertiesSchema() ([]*proto.Property, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	res := &proto.PropertiesSchemaRes{}
+	if err := p.invoke(wasm.MethodPropertiesSchema, &proto.PropertiesSchemaReq{}, res); err != nil {
+		return nil, err
+	}
+	return res.Properties, nil
+}
+
+// RewriteArgs satisfies plugin.Plugin.
+func (p *wasmPlugin) RewriteArgs(command string, args []string) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	res := &proto.RewriteArgsRes{}
+	if err := p.invoke(wasm.MethodRewriteArgs, &proto.RewriteArgsReq{Command: command, Args: args}, res); err != nil {
+		return nil, err
+	}
+	return res.Args, nil
+}
+
+func (p *wasmPlugin) invokeHook(method wasm.Method, promptRunner prompt.PromptRunner, req protobuf.Message, res protobuf.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.promptRunner = promptRunner
+	defer func() { p.promptRunner = nil }()
+	return p.invoke(method, req, res)
+}
+
+// Client satisfies Provider. WebAssembly plugins run in-process so there is no
+// go-plugin client protocol.
+func (p *wasmPlugin) Client() (goplugin.ClientProtocol, error) {
+	return nil, errors.New("wasm plugins do not have a go-plugin client")
+}
+
+// Kill satisfies Provider by closing the WebAssembly runtime.
+func (p *wasmPlugin) Kill() {
+	p.runtime.Close(context.Background())
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/rules_go/go/runfiles"
+	"github.com/manifoldco/promptui"
+	. "github.com/onsi/gomega"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils/prompt"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/plugin"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/proto"
+)
+
+// wasmPluginPath returns the path of the guest in testdata/wasmplugin, which bazel
+// builds for wasip1 and passes to the test through its runfiles.
+func wasmPluginPath(t *testing.T) string {
+	t.Helper()
+	rlocation := os.Getenv("WASM_PLUGIN")
+	if rlocation == "" {
+		t.Skip("the wasm plugin is built by bazel, run the test with bazel test")
+	}
+	path, err := runfiles.Rlocation(rlocation)
+	if err != nil {
+		t.Fatalf("failed to find the wasm plugin in the runfiles: %v", err)
+	}
+	return path
+}
+
+// answerPromptRunner answers every prompt with answer.
+type answerPromptRunner struct {
+	prompt.PromptRunner
+	answer string
+	labels []string
+}
+
+func (r *answerPromptRunner) Run(p promptui.Prompt) (string, error) {
+	r.labels = append(r.labels, p.Label.(string))
+	return r.answer, nil
+}
+
+func TestWasmPlugin(t *testing.T) {
+	path := wasmPluginPath(t)
+	// Keep the compilation cache of wazero out of the user cache dir.
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	newPlugin := func(t *testing.T) (*wasmPlugin, *bytes.Buffer) {
+		var stdout bytes.Buffer
+		p, err := newWasmPlugin("wasmplugin", path, nil, ioutils.Streams{Stdout: &stdout, Stderr: &stdout})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(p.Kill)
+		return p, &stdout
+	}
+
+	t.Run("recognizes wasm plugins by their extension", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		g.Expect(isWasmPlugin(path)).To(BeTrue())
+		g.Expect(isWasmPlugin(strings.TrimSuffix(path, ".wasm"))).To(BeFalse())
+	})
+
+	t.Run("passes the properties and the workspace to Setup", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		p, stdout := newPlugin(t)
+
+		err := p.Setup(plugin.NewSetupConfig([]byte("props"), &plugin.Workspace{Root: "/workspace"}))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(stdout.String()).To(Equal("setup props /workspace\n"))
+	})
+
+	t.Run("returns the errors of the guest", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		p, _ := newPlugin(t)
+
+		err := p.Setup(plugin.NewSetupConfig([]byte("fail"), nil))
+		g.Expect(err).To(MatchError("setup failed"))
+	})
+
+	t.Run("delivers the build events of the subscribed types", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		p, stdout := newPlugin(t)
+
+		eventTypes, err := p.BEPEventTypes()
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(eventTypes).To(Equal([]string{"started"}))
+
+		for sn := int64(1); sn <= 2; sn++ {
+			event := &buildeventstream.BuildEvent{
+				Payload: &buildeventstream.BuildEvent_Started{Started: &buildeventstream.BuildStarted{Command: "build"}},
+			}
+			g.Expect(p.BEPEventCallback(event, sn, "abc")).To(Succeed())
+		}
+		g.Expect(stdout.String()).To(Equal("event 1 abc build\nevent 2 abc build\n"))
+	})
+
+	t.Run("runs the prompts of a hook on the host and returns its result", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		p, stdout := newPlugin(t)
+		g.Expect(p.Setup(plugin.NewSetupConfig([]byte("props"), nil))).To(Succeed())
+		stdout.Reset()
+
+		promptRunner := &answerPromptRunner{answer: "aspect"}
+		err := p.PostBuildHook(&proto.InvocationContext{IsInteractiveMode: true}, promptRunner)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(promptRunner.labels).To(Equal([]string{"Name"}))
+		g.Expect(stdout.String()).To(Equal("hello aspect from props\n"))
+
+		err = p.PostBuildHook(&proto.InvocationContext{}, promptRunner)
+		var result *plugin.HookResult
+		g.Expect(errors.As(err, &result)).To(BeTrue())
+		g.Expect(result.Outcome).To(Equal(proto.HookResult_FAIL))
+		g.Expect(result.Message).To(Equal("not interactive"))
+	})
+
+	t.Run("does not run prompts outside of hooks", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		p, _ := newPlugin(t)
+
+		res := &proto.PromptRunRes{}
+		g.Expect(p.runPrompt(p.module, 0, 0, res)).To(MatchError("prompts are only available from within plugin hooks"))
+	})
+}
@@ -0,0 +1,219 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package daemon runs the long-lived background processes declared by plugins
+// and keeps track of them through pidfiles, so that a daemon started by one
+// invocation of the CLI is reused by the next ones.
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel"
+)
+
+// Dir is the directory of the output base that holds a directory per plugin
+// with the pidfile and the log file of each of its daemons.
+const Dir = "aspect-plugins/daemons"
+
+// Daemon is a daemon of a plugin that was started by the CLI.
+type Daemon struct {
+	Plugin string
+	Name   string
+	Pid    int
+	// Running is false when the daemon exited since it was started.
+	Running bool
+	LogPath string
+
+	// startTime tells the process of the daemon apart from the processes
+	// that reuse its pid once it exited.
+	startTime string
+}
+
+// Root returns the directory that holds the daemons of the workspace, or an
+// empty string outside of a workspace.
+func Root() (string, error) {
+	workspaceRoot := bazel.WorkspaceFromWd.WorkspaceRoot()
+	if workspaceRoot == "" {
+		return "", nil
+	}
+	outputBase, err := bazel.OutputBase(workspaceRoot)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(outputBase, Dir), nil
+}
+
+// Start starts the daemon of a plugin with the given command line and
+// environment, unless it is running already. The daemon runs in dir, detached
+// from the CLI so that it outlives it, with its output written to its log
+// file. It returns whether the daemon was started.
+func Start(root string, plugin string, name string, args []string, env []string, dir string) (bool, error) {
+	if err := validName(name); err != nil {
+		return false, err
+	}
+	if len(args) == 0 {
+		return false, fmt.Errorf("daemon %q of plugin %q has no command", name, plugin)
+	}
+
+	d, err := load(root, plugin, name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	if d != nil && d.Running {
+		return false, nil
+	}
+
+	pluginDir := filepath.Join(root, plugin)
+	if err := os.MkdirAll(pluginDir, 0755); err != nil {
+		return false, fmt.Errorf("failed to create daemon dir %s: %w", pluginDir, err)
+	}
+	logFile, err := os.OpenFile(filepath.Join(pluginDir, name+".log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return false, fmt.Errorf("failed to open the log of daemon %q of plugin %q: %w", name, plugin, err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = env
+	cmd.Dir = dir
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	Detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return false, fmt.Errorf("failed to start daemon %q of plugin %q: %w", name, plugin, err)
+	}
+	pid := cmd.Process.Pid
+	// The pid can't be reused before the process is waited on.
+	startTime, err := StartTime(pid)
+	if err != nil {
+		go cmd.Wait()
+		return true, fmt.Errorf("failed to get the start time of daemon %q of plugin %q: %w", name, plugin, err)
+	}
+
+	pidfile := fmt.Sprintf("%d\n%s\n", pid, startTime)
+	if err := os.WriteFile(pidPath(root, plugin, name), []byte(pidfile), 0644); err != nil {
+		go cmd.Wait()
+		return true, fmt.Errorf("failed to write the pidfile of daemon %q of plugin %q: %w", name, plugin, err)
+	}
+
+	// The daemon is reparented once the CLI exits. Until then, it is reaped
+	// when it exits, e.g. during a long --watch session, so that it doesn't
+	// linger as a zombie that looks like it's running and is never restarted.
+	go func() {
+		cmd.Wait()
+		removePidfile(root, plugin, name, pid, startTime)
+	}()
+	return true, nil
+}
+
+// removePidfile removes the pidfile of a daemon that exited, unless it was
+// replaced by the pidfile of another process since.
+func removePidfile(root string, plugin string, name string, pid int, startTime string) {
+	d, err := load(root, plugin, name)
+	if err != nil || d.Pid != pid || d.startTime != startTime {
+		return
+	}
+	os.Remove(pidPath(root, plugin, name))
+}
+
+// List returns the daemons that were started in root, sorted by plugin and
+// name, including the ones that exited since.
+func List(root string) ([]*Daemon, error) {
+	pidfiles, err := filepath.Glob(filepath.Join(root, "*", "*.pid"))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(pidfiles)
+
+	daemons := make([]*Daemon, 0, len(pidfiles))
+	for _, pidfile := range pidfiles {
+		plugin := filepath.Base(filepath.Dir(pidfile))
+		name := strings.TrimSuffix(filepath.Base(pidfile), ".pid")
+		d, err := load(root, plugin, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			// Stopped in the meantime.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		daemons = append(daemons, d)
+	}
+	return daemons, nil
+}
+
+// Stop terminates the daemon if it is running and removes its pidfile.
+func Stop(root string, d *Daemon) error {
+	if d.Running && IsRunning(d.Pid, d.startTime) {
+		if err := Terminate(d.Pid); err != nil {
+			return fmt.Errorf("failed to stop daemon %q of plugin %q: %w", d.Name, d.Plugin, err)
+		}
+	}
+	if err := os.Remove(pidPath(root, d.Plugin, d.Name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// load reads the pidfile of a daemon and checks whether its process is alive.
+func load(root string, plugin string, name string) (*Daemon, error) {
+	b, err := os.ReadFile(pidPath(root, plugin, name))
+	if err != nil {
+		return nil, err
+	}
+	pidLine, startTime, _ := strings.Cut(strings.TrimSpace(string(b)), "\n")
+	pid, err := strconv.Atoi(pidLine)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pidfile of daemon %q of plugin %q: %w", name, plugin, err)
+	}
+	return &Daemon{
+		Plugin:    plugin,
+		Name:      name,
+		Pid:       pid,
+		Running:   IsRunning(pid, startTime),
+		LogPath:   filepath.Join(root, plugin, name+".log"),
+		startTime: startTime,
+	}, nil
+}
+
+// IsRunning reports whether the process with the given pid is running and is
+// the one that started at startTime, rather than a process that reused its
+// pid, e.g. after a reboot.
+func IsRunning(pid int, startTime string) bool {
+	current, err := StartTime(pid)
+	return err == nil && current == startTime
+}
+
+func pidPath(root string, plugin string, name string) string {
+	return filepath.Join(root, plugin, name+".pid")
+}
+
+// validName reports whether the name of a daemon can be used in the name of
+// its pidfile.
+func validName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid daemon name %q", name)
+	}
+	return nil
+}
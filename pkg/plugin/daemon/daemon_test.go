@@ -0,0 +1,142 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestDaemon(t *testing.T) {
+	t.Run("starts a daemon once and reuses it", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		root := t.TempDir()
+
+		started, err := Start(root, "fake", "sleeper", []string{"sleep", "60"}, os.Environ(), root)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(started).To(BeTrue())
+
+		daemons, err := List(root)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(daemons).To(HaveLen(1))
+		g.Expect(daemons[0].Plugin).To(Equal("fake"))
+		g.Expect(daemons[0].Name).To(Equal("sleeper"))
+		g.Expect(daemons[0].Running).To(BeTrue())
+		g.Expect(daemons[0].LogPath).To(Equal(filepath.Join(root, "fake", "sleeper.log")))
+
+		started, err = Start(root, "fake", "sleeper", []string{"sleep", "60"}, os.Environ(), root)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(started).To(BeFalse())
+
+		g.Expect(Stop(root, daemons[0])).To(Succeed())
+		g.Expect(List(root)).To(BeEmpty())
+	})
+
+	t.Run("restarts a daemon whose process exited", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		root := t.TempDir()
+
+		g.Expect(os.MkdirAll(filepath.Join(root, "fake"), 0755)).To(Succeed())
+		// No process can have the largest pid.
+		g.Expect(os.WriteFile(filepath.Join(root, "fake", "sleeper.pid"), []byte("2147483647\n1\n"), 0644)).To(Succeed())
+
+		daemons, err := List(root)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(daemons).To(HaveLen(1))
+		g.Expect(daemons[0].Running).To(BeFalse())
+
+		started, err := Start(root, "fake", "sleeper", []string{"sleep", "60"}, os.Environ(), root)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(started).To(BeTrue())
+
+		daemons, err = List(root)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(daemons[0].Running).To(BeTrue())
+		g.Expect(Stop(root, daemons[0])).To(Succeed())
+	})
+
+	t.Run("restarts a daemon that exited while the CLI is running", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		root := t.TempDir()
+
+		started, err := Start(root, "fake", "crasher", []string{"sh", "-c", "exit 1"}, os.Environ(), root)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(started).To(BeTrue())
+
+		// The daemon is reaped rather than left as a zombie that looks alive.
+		g.Eventually(func() ([]*Daemon, error) { return List(root) }, 5*time.Second, 10*time.Millisecond).Should(BeEmpty())
+
+		started, err = Start(root, "fake", "crasher", []string{"sleep", "60"}, os.Environ(), root)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(started).To(BeTrue())
+
+		daemons, err := List(root)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(daemons).To(HaveLen(1))
+		g.Expect(daemons[0].Running).To(BeTrue())
+		g.Expect(Stop(root, daemons[0])).To(Succeed())
+	})
+
+	t.Run("does not stop a process that reused the pid", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		root := t.TempDir()
+
+		other := exec.Command("sleep", "60")
+		g.Expect(other.Start()).To(Succeed())
+		defer other.Wait()
+		defer other.Process.Kill()
+
+		// The daemon started at another time with the pid of the process.
+		g.Expect(os.MkdirAll(filepath.Join(root, "fake"), 0755)).To(Succeed())
+		pidfile := fmt.Sprintf("%d\n1\n", other.Process.Pid)
+		g.Expect(os.WriteFile(filepath.Join(root, "fake", "sleeper.pid"), []byte(pidfile), 0644)).To(Succeed())
+
+		daemons, err := List(root)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(daemons).To(HaveLen(1))
+		g.Expect(daemons[0].Running).To(BeFalse())
+
+		daemons[0].Running = true
+		g.Expect(Stop(root, daemons[0])).To(Succeed())
+		g.Expect(other.Process.Signal(syscall.Signal(0))).To(Succeed())
+	})
+
+	t.Run("tells a process apart by its start time", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		startTime, err := StartTime(os.Getpid())
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(IsRunning(os.Getpid(), startTime)).To(BeTrue())
+		g.Expect(IsRunning(os.Getpid(), startTime+"0")).To(BeFalse())
+
+		_, err = StartTime(2147483647)
+		g.Expect(err).To(HaveOccurred())
+	})
+
+	t.Run("rejects names that aren't file names", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		_, err := Start(t.TempDir(), "fake", "../sleeper", []string{"sleep", "60"}, nil, "")
+		g.Expect(err).To(MatchError(`invalid daemon name "../sleeper"`))
+	})
+}
@@ -0,0 +1,35 @@
+//go:build !windows
+
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package daemon
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Detach runs the command in a session of its own, so that it doesn't receive
+// the signals sent to the terminal of the CLI, e.g. on Ctrl-C.
+func Detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+// Terminate asks the process with the given pid to exit.
+func Terminate(pid int) error {
+	return syscall.Kill(pid, syscall.SIGTERM)
+}
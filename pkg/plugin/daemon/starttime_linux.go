@@ -0,0 +1,54 @@
+//go:build linux
+
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// StartTime returns when the process with the given pid started, which tells
+// it apart from the processes that reuse its pid later. It fails when the
+// process isn't running.
+func StartTime(pid int) (string, error) {
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return "", err
+	}
+	// The command name in parentheses may contain spaces, the fields after it
+	// start with the 3rd one, and the start time is the 22nd one, in clock
+	// ticks since the boot.
+	stat := string(b)
+	fields := strings.Fields(stat[strings.LastIndexByte(stat, ')')+1:])
+	if len(fields) < 20 {
+		return "", fmt.Errorf("invalid stat of process %d", pid)
+	}
+	if _, err := strconv.ParseUint(fields[19], 10, 64); err != nil {
+		return "", fmt.Errorf("invalid start time of process %d: %w", pid, err)
+	}
+
+	// The clock restarts on boot, so the start time is qualified by the boot.
+	bootId, err := os.ReadFile("/proc/sys/kernel/random/boot_id")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(bootId)) + "/" + fields[19], nil
+}
@@ -0,0 +1,37 @@
+//go:build !darwin && !linux && !windows
+
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package daemon
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// StartTime returns when the process with the given pid started, which tells
+// it apart from the processes that reuse its pid later. It fails when the
+// process isn't running.
+//
+// The start time isn't known on this platform, so a process that reuses the
+// pid can't be told apart.
+func StartTime(pid int) (string, error) {
+	if err := syscall.Kill(pid, 0); err != nil {
+		return "", fmt.Errorf("process %d is not running: %w", pid, err)
+	}
+	return "", nil
+}
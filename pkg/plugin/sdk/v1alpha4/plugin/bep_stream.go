@@ -0,0 +1,49 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package plugin
+
+import (
+	"sync"
+
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/proto"
+)
+
+// BEPStreamTracker passes the build event stream to a plugin that implements
+// BEPStreamReceiver whenever it changes. It is safe for concurrent use, since
+// multi-threaded plugins receive build events concurrently.
+type BEPStreamTracker struct {
+	mu      sync.Mutex
+	current *proto.BEPStream
+}
+
+// Update calls the SetBEPStream of impl, if it implements BEPStreamReceiver,
+// when stream differs from the one it was last called with. The stream is nil
+// when sent by a Core that predates it, and is then ignored.
+func (t *BEPStreamTracker) Update(impl any, stream *proto.BEPStream) {
+	receiver, ok := impl.(BEPStreamReceiver)
+	if !ok || stream == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.current != nil && protobuf.Equal(t.current, stream) {
+		return
+	}
+	t.current = stream
+	receiver.SetBEPStream(stream)
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/proto"
+)
+
+// StringFlag declares a string flag on the command.
+func (c *Command) StringFlag(name string, shorthand string, value string, usage string) *Command {
+	return c.addFlag(proto.Flag_STRING, name, shorthand, value, usage)
+}
+
+// BoolFlag declares a bool flag on the command.
+func (c *Command) BoolFlag(name string, shorthand string, value bool, usage string) *Command {
+	return c.addFlag(proto.Flag_BOOL, name, shorthand, strconv.FormatBool(value), usage)
+}
+
+// IntFlag declares an int flag on the command.
+func (c *Command) IntFlag(name string, shorthand string, value int, usage string) *Command {
+	return c.addFlag(proto.Flag_INT, name, shorthand, strconv.Itoa(value), usage)
+}
+
+func (c *Command) addFlag(flagType proto.Flag_Type, name string, shorthand string, value string, usage string) *Command {
+	c.Flags = append(c.Flags, &proto.Flag{
+		Name:         name,
+		Shorthand:    shorthand,
+		Usage:        usage,
+		Type:         flagType,
+		DefaultValue: value,
+	})
+	return c
+}
+
+// Flags holds the values of the flags declared by a custom command, keyed by
+// flag name.
+type Flags map[string]string
+
+type flagsContextKey struct{}
+
+// ContextWithFlags returns a copy of ctx carrying the flag values of a custom
+// command.
+func ContextWithFlags(ctx context.Context, flags Flags) context.Context {
+	return context.WithValue(ctx, flagsContextKey{}, flags)
+}
+
+// FlagsFromContext returns the flag values passed to a custom command.
+func FlagsFromContext(ctx context.Context) Flags {
+	flags, _ := ctx.Value(flagsContextKey{}).(Flags)
+	return flags
+}
+
+// GetString returns the value of a string flag.
+func (f Flags) GetString(name string) (string, error) {
+	value, ok := f[name]
+	if !ok {
+		return "", fmt.Errorf("flag %q is not declared by the command", name)
+	}
+	return value, nil
+}
+
+// GetBool returns the value of a bool flag.
+func (f Flags) GetBool(name string) (bool, error) {
+	value, err := f.GetString(name)
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(value)
+}
+
+// GetInt returns the value of an int flag.
+func (f Flags) GetInt(name string) (int, error) {
+	value, err := f.GetString(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(value)
+}
@@ -28,6 +28,8 @@ import (
 	goplugin "github.com/hashicorp/go-plugin"
 	"github.com/manifoldco/promptui"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils/prompt"
@@ -63,6 +65,7 @@ type GRPCServer struct {
 	Impl           Plugin
 	broker         *goplugin.GRPCBroker
 	commandManager CommandManager
+	bepStream      BEPStreamTracker
 }
 
 // BEPEventCallback translates the gRPC call to the Plugin BEPEventCallback
@@ -71,15 +74,29 @@ func (m *GRPCServer) BEPEventCallback(
 	ctx context.Context,
 	req *proto.BEPEventCallbackReq,
 ) (*proto.BEPEventCallbackRes, error) {
+	m.bepStream.Update(m.Impl, req.Stream)
 	return &proto.BEPEventCallbackRes{}, m.Impl.BEPEventCallback(req.Event, req.SequenceNumber, req.InvocationId)
 }
 
+// BEPEventTypes translates the gRPC call to the Plugin BEPEventTypes
+// implementation.
+func (m *GRPCServer) BEPEventTypes(
+	ctx context.Context,
+	req *proto.BEPEventTypesReq,
+) (*proto.BEPEventTypesRes, error) {
+	eventTypes, err := m.Impl.BEPEventTypes()
+	if err != nil {
+		return nil, err
+	}
+	return &proto.BEPEventTypesRes{EventTypes: eventTypes}, nil
+}
+
 // Setup translates the gRPC call to the Plugin Setup implementation.
 func (m *GRPCServer) Setup(
 	ctx context.Context,
 	req *proto.SetupReq,
 ) (*proto.SetupRes, error) {
-	config := NewSetupConfig(req.Properties)
+	config := NewSetupConfig(req.Properties, WorkspaceFromProto(req.Workspace))
 	return &proto.SetupRes{}, m.Impl.Setup(config)
 }
 
@@ -95,7 +112,9 @@ func (m *GRPCServer) CustomCommands(
 		return nil, err
 	}
 
-	m.commandManager.Save(customCommands)
+	if err := m.commandManager.Save(customCommands); err != nil {
+		return nil, err
+	}
 
 	pbCommands := make([]*proto.Command, 0, len(customCommands))
 	for _, command := range customCommands {
@@ -115,10 +134,52 @@ func (m *GRPCServer) ExecuteCustomCommand(
 	_ context.Context,
 	req *proto.ExecuteCustomCommandReq,
 ) (*proto.ExecuteCustomCommandRes, error) {
-	ctx := context.Background()
+	ctx := ContextWithFlags(context.Background(), req.Flags)
 
-	return &proto.ExecuteCustomCommandRes{},
-		m.commandManager.Execute(req.CustomCommand, ctx, req.Args, req.BazelStartupArgs)
+	err := m.commandManager.Execute(req.CustomCommand, ctx, req.Args, req.BazelStartupArgs)
+	var delegation *BuiltinDelegation
+	if errors.As(err, &delegation) {
+		return &proto.ExecuteCustomCommandRes{DelegateToBuiltin: true, BuiltinArgs: delegation.Args}, nil
+	}
+	return &proto.ExecuteCustomCommandRes{}, err
+}
+
+// Daemons translates the gRPC call to the Plugin Daemons implementation.
+func (m *GRPCServer) Daemons(
+	ctx context.Context,
+	req *proto.DaemonsReq,
+) (*proto.DaemonsRes, error) {
+	daemons, err := m.Impl.Daemons()
+	if err != nil {
+		return nil, err
+	}
+	return &proto.DaemonsRes{Daemons: daemons}, nil
+}
+
+// FiltersOutput translates the gRPC call to the Plugin FiltersOutput
+// implementation.
+func (m *GRPCServer) FiltersOutput(
+	ctx context.Context,
+	req *proto.FiltersOutputReq,
+) (*proto.FiltersOutputRes, error) {
+	filtersOutput, err := m.Impl.FiltersOutput()
+	if err != nil {
+		return nil, err
+	}
+	return &proto.FiltersOutputRes{FiltersOutput: filtersOutput}, nil
+}
+
+// FilterOutputLine translates the gRPC call to the Plugin FilterOutputLine
+// implementation.
+func (m *GRPCServer) FilterOutputLine(
+	ctx context.Context,
+	req *proto.FilterOutputLineReq,
+) (*proto.FilterOutputLineRes, error) {
+	lines, err := m.Impl.FilterOutputLine(req.Stream, req.Line)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.FilterOutputLineRes{Lines: lines}, nil
 }
 
 // PostBuildHook translates the gRPC call to the Plugin PostBuildHook
@@ -136,8 +197,10 @@ func (m *GRPCServer) PostBuildHook(
 
 	client := proto.NewPrompterClient(conn)
 	prompter := &PrompterGRPCClient{client: client}
-	return &proto.PostBuildHookRes{},
-		m.Impl.PostBuildHook(req.IsInteractiveMode, prompter)
+	result, err := hookResultToProto(
+		m.Impl.PostBuildHook(invocationContext(req.Invocation, req.IsInteractiveMode), prompter),
+	)
+	return &proto.PostBuildHookRes{Result: result}, err
 }
 
 // PostTestHook translates the gRPC call to the Plugin PostTestHook
@@ -155,8 +218,10 @@ func (m *GRPCServer) PostTestHook(
 
 	client := proto.NewPrompterClient(conn)
 	prompter := &PrompterGRPCClient{client: client}
-	return &proto.PostTestHookRes{},
-		m.Impl.PostTestHook(req.IsInteractiveMode, prompter)
+	result, err := hookResultToProto(
+		m.Impl.PostTestHook(invocationContext(req.Invocation, req.IsInteractiveMode), prompter),
+	)
+	return &proto.PostTestHookRes{Result: result}, err
 }
 
 // PostRunHook translates the gRPC call to the Plugin PostRunHook
@@ -174,8 +239,44 @@ func (m *GRPCServer) PostRunHook(
 
 	client := proto.NewPrompterClient(conn)
 	prompter := &PrompterGRPCClient{client: client}
-	return &proto.PostRunHookRes{},
-		m.Impl.PostRunHook(req.IsInteractiveMode, prompter)
+	result, err := hookResultToProto(
+		m.Impl.PostRunHook(invocationContext(req.Invocation, req.IsInteractiveMode), prompter),
+	)
+	return &proto.PostRunHookRes{Result: result}, err
+}
+
+// PropertiesSchema translates the gRPC call to the Plugin PropertiesSchema
+// implementation.
+func (m *GRPCServer) PropertiesSchema(
+	ctx context.Context,
+	req *proto.PropertiesSchemaReq,
+) (*proto.PropertiesSchemaRes, error) {
+	properties, err := m.Impl.PropertiesSchema()
+	if err != nil {
+		return nil, err
+	}
+	return &proto.PropertiesSchemaRes{Properties: properties}, nil
+}
+
+// RewriteArgs translates the gRPC call to the Plugin RewriteArgs implementation.
+func (m *GRPCServer) RewriteArgs(
+	ctx context.Context,
+	req *proto.RewriteArgsReq,
+) (*proto.RewriteArgsRes, error) {
+	args, err := m.Impl.RewriteArgs(req.Command, req.Args)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.RewriteArgsRes{Args: args}, nil
+}
+
+// invocationContext returns the invocation context of a hook request. Only
+// is_interactive_mode is set by a Core that predates the invocation context.
+func invocationContext(invocation *proto.InvocationContext, isInteractiveMode bool) *proto.InvocationContext {
+	if invocation == nil {
+		return &proto.InvocationContext{IsInteractiveMode: isInteractiveMode}
+	}
+	return invocation
 }
 
 // GRPCClient implements the gRPC client that is used by the Core to communicate
@@ -186,14 +287,39 @@ type GRPCClient struct {
 }
 
 var _ Plugin = (*GRPCClient)(nil)
+var _ BEPStreamCallback = (*GRPCClient)(nil)
 
 // BEPEventCallback is called from the Core to execute the Plugin
 // BEPEventCallback.
 func (m *GRPCClient) BEPEventCallback(event *buildeventstream.BuildEvent, sn int64, invocationId string) error {
-	_, err := m.client.BEPEventCallback(context.Background(), &proto.BEPEventCallbackReq{Event: event, SequenceNumber: sn, InvocationId: invocationId})
+	return m.BEPStreamEventCallback(event, sn, &proto.BEPStream{InvocationId: invocationId})
+}
+
+// BEPStreamEventCallback is called from the Core to execute the Plugin
+// BEPEventCallback with the stream the build event belongs to.
+func (m *GRPCClient) BEPStreamEventCallback(event *buildeventstream.BuildEvent, sn int64, stream *proto.BEPStream) error {
+	_, err := m.client.BEPEventCallback(context.Background(), &proto.BEPEventCallbackReq{
+		Event:          event,
+		SequenceNumber: sn,
+		InvocationId:   stream.GetInvocationId(),
+		Stream:         stream,
+	})
 	return err
 }
 
+// BEPEventTypes is called from the Core to execute the Plugin BEPEventTypes.
+// Plugins built with an SDK that predates BEPEventTypes receive all events.
+func (m *GRPCClient) BEPEventTypes() ([]string, error) {
+	res, err := m.client.BEPEventTypes(context.Background(), &proto.BEPEventTypesReq{})
+	if status.Code(err) == codes.Unimplemented {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res.EventTypes, nil
+}
+
 // Setup is called from the Core to execute the Plugin Setup.
 func (m *GRPCClient) Setup(config *SetupConfig) error {
 	file := &proto.File{Path: ""}
@@ -205,6 +331,7 @@ func (m *GRPCClient) Setup(config *SetupConfig) error {
 	req := &proto.SetupReq{
 		Properties: config.Properties,
 		File:       file,
+		Workspace:  WorkspaceToProto(config.Workspace),
 	}
 	_, err := m.client.Setup(context.Background(), req)
 	return err
@@ -234,27 +361,109 @@ func (m *GRPCClient) ExecuteCustomCommand(customCommand string, ctx context.Cont
 		Ctx:              pbContext,
 		Args:             args,
 		BazelStartupArgs: bazelStartupArgs,
+		Flags:            FlagsFromContext(ctx),
 	}
-	_, err := m.client.ExecuteCustomCommand(context.Background(), req)
-	return err
+	res, err := m.client.ExecuteCustomCommand(context.Background(), req)
+	if err != nil {
+		return err
+	}
+	if res.DelegateToBuiltin {
+		return &BuiltinDelegation{Args: res.BuiltinArgs}
+	}
+	return nil
+}
+
+// Daemons is called from the Core to execute the Plugin Daemons. Plugins built
+// with an SDK that predates Daemons run no daemons.
+func (m *GRPCClient) Daemons() ([]*proto.Daemon, error) {
+	res, err := m.client.Daemons(context.Background(), &proto.DaemonsReq{})
+	if status.Code(err) == codes.Unimplemented {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res.Daemons, nil
+}
+
+// FiltersOutput is called from the Core to execute the Plugin FiltersOutput.
+// Plugins built with an SDK that predates FiltersOutput don't filter output.
+func (m *GRPCClient) FiltersOutput() (bool, error) {
+	res, err := m.client.FiltersOutput(context.Background(), &proto.FiltersOutputReq{})
+	if status.Code(err) == codes.Unimplemented {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return res.FiltersOutput, nil
+}
+
+// FilterOutputLine is called from the Core to execute the Plugin
+// FilterOutputLine.
+func (m *GRPCClient) FilterOutputLine(stream proto.OutputStream, line string) ([]string, error) {
+	res, err := m.client.FilterOutputLine(context.Background(), &proto.FilterOutputLineReq{Stream: stream, Line: line})
+	if err != nil {
+		return nil, err
+	}
+	return res.Lines, nil
 }
 
 // PostBuildHook is called from the Core to execute the Plugin PostBuildHook. It
 // starts the prompt runner server with the provided PromptRunner.
-func (m *GRPCClient) PostBuildHook(isInteractiveMode bool, promptRunner prompt.PromptRunner) error {
-	return callClientHook(m.broker, m.client.PostBuildHook, isInteractiveMode, promptRunner)
+func (m *GRPCClient) PostBuildHook(invocation *proto.InvocationContext, promptRunner prompt.PromptRunner) error {
+	res, err := callClientHook(m.broker, m.client.PostBuildHook, invocation, promptRunner)
+	if err != nil {
+		return err
+	}
+	return HookResultFromProto(res.GetResult())
 }
 
 // PostTestHook is called from the Core to execute the Plugin PostTestHook. It
 // starts the prompt runner server with the provided PromptRunner.
-func (m *GRPCClient) PostTestHook(isInteractiveMode bool, promptRunner prompt.PromptRunner) error {
-	return callClientHook(m.broker, m.client.PostTestHook, isInteractiveMode, promptRunner)
+func (m *GRPCClient) PostTestHook(invocation *proto.InvocationContext, promptRunner prompt.PromptRunner) error {
+	res, err := callClientHook(m.broker, m.client.PostTestHook, invocation, promptRunner)
+	if err != nil {
+		return err
+	}
+	return HookResultFromProto(res.GetResult())
 }
 
 // PostRunHook is called from the Core to execute the Plugin PostRunHook. It
 // starts the prompt runner server with the provided PromptRunner.
-func (m *GRPCClient) PostRunHook(isInteractiveMode bool, promptRunner prompt.PromptRunner) error {
-	return callClientHook(m.broker, m.client.PostRunHook, isInteractiveMode, promptRunner)
+func (m *GRPCClient) PostRunHook(invocation *proto.InvocationContext, promptRunner prompt.PromptRunner) error {
+	res, err := callClientHook(m.broker, m.client.PostRunHook, invocation, promptRunner)
+	if err != nil {
+		return err
+	}
+	return HookResultFromProto(res.GetResult())
+}
+
+// PropertiesSchema is called from the Core to execute the Plugin
+// PropertiesSchema. Plugins built with an SDK that predates PropertiesSchema
+// declare no properties.
+func (m *GRPCClient) PropertiesSchema() ([]*proto.Property, error) {
+	res, err := m.client.PropertiesSchema(context.Background(), &proto.PropertiesSchemaReq{})
+	if status.Code(err) == codes.Unimplemented {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res.Properties, nil
+}
+
+// RewriteArgs is called from the Core to execute the Plugin RewriteArgs.
+// Plugins built with an SDK that predates RewriteArgs leave the args unchanged.
+func (m *GRPCClient) RewriteArgs(command string, args []string) ([]string, error) {
+	res, err := m.client.RewriteArgs(context.Background(), &proto.RewriteArgsReq{Command: command, Args: args})
+	if status.Code(err) == codes.Unimplemented {
+		return args, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res.Args, nil
 }
 
 func callClientHook[
@@ -263,9 +472,9 @@ func callClientHook[
 ](
 	broker *goplugin.GRPCBroker,
 	callFn func(context.Context, *ReqT, ...grpc.CallOption) (*ResT, error),
-	isInteractiveMode bool,
+	invocation *proto.InvocationContext,
 	promptRunner prompt.PromptRunner,
-) error {
+) (*ResT, error) {
 	prompterServer := &PrompterGRPCServer{promptRunner: promptRunner}
 	var s *grpc.Server
 	var wg sync.WaitGroup
@@ -278,14 +487,17 @@ func callClientHook[
 	}
 	brokerID := broker.NextId()
 	go broker.AcceptAndServe(brokerID, serverFunc)
+	// is_interactive_mode is still set for plugins built with an SDK that
+	// predates the invocation context.
 	req := &ReqT{
 		BrokerId:          brokerID,
-		IsInteractiveMode: isInteractiveMode,
+		IsInteractiveMode: invocation.GetIsInteractiveMode(),
+		Invocation:        invocation,
 	}
 	wg.Wait()
-	_, err := callFn(context.Background(), req)
+	res, err := callFn(context.Background(), req)
 	s.Stop()
-	return err
+	return res, err
 }
 
 // PrompterGRPCServer implements the gRPC server that runs on the Core and is
@@ -310,17 +522,70 @@ func (p *PrompterGRPCServer) Run(
 	}
 
 	result, err := p.promptRunner.Run(prompt)
-	res := &proto.PromptRunRes{Result: result}
-	if err != nil {
-		res.Error = &proto.PromptRunRes_Error{
-			Happened: true,
-			Message:  err.Error(),
-		}
-	}
+	return &proto.PromptRunRes{Result: result, Error: promptErrorToProto(err)}, nil
+}
+
+// Select translates the gRPC call to perform a prompt Select on the Core.
+func (p *PrompterGRPCServer) Select(
+	ctx context.Context,
+	req *proto.PromptSelectReq,
+) (*proto.PromptSelectRes, error) {
+	index, err := p.promptRunner.Select(req.GetLabel(), req.GetItems(), int(req.GetDefaultIndex()))
+	return &proto.PromptSelectRes{Index: int32(index), Error: promptErrorToProto(err)}, nil
+}
 
+// MultiSelect translates the gRPC call to perform a prompt MultiSelect on the
+// Core.
+func (p *PrompterGRPCServer) MultiSelect(
+	ctx context.Context,
+	req *proto.PromptMultiSelectReq,
+) (*proto.PromptMultiSelectRes, error) {
+	selected := make([]int, 0, len(req.GetSelected()))
+	for _, i := range req.GetSelected() {
+		selected = append(selected, int(i))
+	}
+	selected, err := p.promptRunner.MultiSelect(req.GetLabel(), req.GetItems(), selected)
+	res := &proto.PromptMultiSelectRes{Error: promptErrorToProto(err)}
+	for _, i := range selected {
+		res.Selected = append(res.Selected, int32(i))
+	}
 	return res, nil
 }
 
+// Confirm translates the gRPC call to perform a prompt Confirm on the Core.
+func (p *PrompterGRPCServer) Confirm(
+	ctx context.Context,
+	req *proto.PromptConfirmReq,
+) (*proto.PromptConfirmRes, error) {
+	confirmed, err := p.promptRunner.Confirm(req.GetLabel(), req.GetDefaultYes())
+	return &proto.PromptConfirmRes{Confirmed: confirmed, Error: promptErrorToProto(err)}, nil
+}
+
+func promptErrorToProto(err error) *proto.PromptRunRes_Error {
+	if err == nil {
+		return nil
+	}
+	return &proto.PromptRunRes_Error{
+		Happened: true,
+		Message:  err.Error(),
+	}
+}
+
+// promptErrorFromProto returns the error of a prompt run on the Core. The
+// promptui errors for interrupting and aborting a prompt are preserved so that
+// plugins can compare against them.
+func promptErrorFromProto(err *proto.PromptRunRes_Error) error {
+	if !err.GetHappened() {
+		return nil
+	}
+	for _, known := range []error{promptui.ErrInterrupt, promptui.ErrAbort, promptui.ErrEOF} {
+		if err.GetMessage() == known.Error() {
+			return known
+		}
+	}
+	return errors.New(err.GetMessage())
+}
+
 // PrompterGRPCClient implements the gRPC client that is used by the Plugin
 // instance to communicate with the Core to request prompt actions from the
 // user.
@@ -348,8 +613,83 @@ func (p *PrompterGRPCClient) Run(prompt promptui.Prompt) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	if res.Error != nil && res.Error.Happened {
-		return "", errors.New(res.Error.Message)
+	if err := promptErrorFromProto(res.Error); err != nil {
+		return "", err
 	}
 	return res.Result, nil
 }
+
+// Select is called from the Plugin to request the Core to ask the user to
+// choose one of items.
+func (p *PrompterGRPCClient) Select(label string, items []string, defaultIndex int) (int, error) {
+	req := &proto.PromptSelectReq{Label: label, Items: items, DefaultIndex: int32(defaultIndex)}
+	res, err := p.client.Select(context.Background(), req)
+	if status.Code(err) == codes.Unimplemented {
+		return 0, errUnsupportedPrompt("select")
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := promptErrorFromProto(res.Error); err != nil {
+		return 0, err
+	}
+	return int(res.Index), nil
+}
+
+// MultiSelect is called from the Plugin to request the Core to ask the user to
+// choose any number of items.
+func (p *PrompterGRPCClient) MultiSelect(label string, items []string, selected []int) ([]int, error) {
+	req := &proto.PromptMultiSelectReq{Label: label, Items: items}
+	for _, i := range selected {
+		req.Selected = append(req.Selected, int32(i))
+	}
+	res, err := p.client.MultiSelect(context.Background(), req)
+	if status.Code(err) == codes.Unimplemented {
+		return nil, errUnsupportedPrompt("multi-select")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := promptErrorFromProto(res.Error); err != nil {
+		return nil, err
+	}
+	result := make([]int, 0, len(res.Selected))
+	for _, i := range res.Selected {
+		result = append(result, int(i))
+	}
+	return result, nil
+}
+
+// Secret is called from the Plugin to request the Core to ask the user for
+// input that is not echoed.
+func (p *PrompterGRPCClient) Secret(label string) (string, error) {
+	return p.Run(promptui.Prompt{Label: label, Mask: '*', HideEntered: true})
+}
+
+// Confirm is called from the Plugin to request the Core to ask the user a yes
+// or no question. Cores that predate Confirm run a confirmation prompt instead.
+func (p *PrompterGRPCClient) Confirm(label string, defaultYes bool) (bool, error) {
+	res, err := p.client.Confirm(context.Background(), &proto.PromptConfirmReq{Label: label, DefaultYes: defaultYes})
+	if status.Code(err) == codes.Unimplemented {
+		prompt := promptui.Prompt{Label: label, IsConfirm: true}
+		if defaultYes {
+			prompt.Default = "y"
+		}
+		_, err := p.Run(prompt)
+		if errors.Is(err, promptui.ErrAbort) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := promptErrorFromProto(res.Error); err != nil {
+		return false, err
+	}
+	return res.Confirmed, nil
+}
+
+func errUnsupportedPrompt(kind string) error {
+	return fmt.Errorf("%s prompts are not supported by this version of the aspect CLI", kind)
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package plugin
+
+import (
+	"errors"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/proto"
+)
+
+// HookResult can be returned as the error of PostBuildHook, PostTestHook and
+// PostRunHook to control the exit code of the command the hook runs after.
+// Any other error only sets the exit code to 1 when the command failed
+// already.
+type HookResult struct {
+	Outcome  proto.HookResult_Outcome
+	ExitCode int
+	Message  string
+}
+
+// Error satisfies the error interface.
+func (r *HookResult) Error() string {
+	return r.Message
+}
+
+// FailCommand fails the command with exit code 1, even when bazel succeeded.
+func FailCommand(message string) *HookResult {
+	return &HookResult{Outcome: proto.HookResult_FAIL, ExitCode: 1, Message: message}
+}
+
+// ExitWithCode makes the command exit with the given exit code. An exit code
+// of 0 makes a failed command succeed.
+func ExitWithCode(code int, message string) *HookResult {
+	return &HookResult{Outcome: proto.HookResult_EXIT_CODE, ExitCode: code, Message: message}
+}
+
+// DowngradeToWarning turns a failure of the command into a warning, so that it
+// exits with code 0. It has no effect when the command succeeded.
+func DowngradeToWarning(message string) *HookResult {
+	return &HookResult{Outcome: proto.HookResult_WARN, Message: message}
+}
+
+// hookResultToProto splits the error returned by a hook into the HookResult
+// sent to the Core and the error that is still returned as a gRPC error.
+func hookResultToProto(err error) (*proto.HookResult, error) {
+	var result *HookResult
+	if !errors.As(err, &result) {
+		return nil, err
+	}
+	return &proto.HookResult{
+		Outcome:  result.Outcome,
+		ExitCode: int32(result.ExitCode),
+		Message:  result.Message,
+	}, nil
+}
+
+// HookResultFromProto returns the HookResult sent by a plugin as an error, or
+// nil when the hook did not set one.
+func HookResultFromProto(result *proto.HookResult) error {
+	if result.GetOutcome() == proto.HookResult_UNSPECIFIED {
+		return nil
+	}
+	return &HookResult{
+		Outcome:  result.GetOutcome(),
+		ExitCode: int(result.GetExitCode()),
+		Message:  result.GetMessage(),
+	}
+}
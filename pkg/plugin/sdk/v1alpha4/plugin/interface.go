@@ -29,36 +29,131 @@ import (
 // Plugin determines how an aspect Plugin should be implemented.
 type Plugin interface {
 	BEPEventCallback(event *buildeventstream.BuildEvent, sn int64, invocationId string) error
+	// BEPEventTypes returns the types of the build events passed to
+	// BEPEventCallback, which are the names of the fields of the BuildEventId
+	// oneof, e.g. "test_result" or "target_completed". All events are passed
+	// when it returns none.
+	BEPEventTypes() ([]string, error)
 	CustomCommands() ([]*Command, error)
+	// Daemons returns the long-lived processes the plugin runs in the
+	// background, e.g. a log uploader or a cache warmer. The CLI starts each
+	// daemon that isn't running yet once the plugin is set up, and reuses it
+	// across invocations until it is stopped with 'aspect plugin daemon stop'.
+	Daemons() ([]*proto.Daemon, error)
+	// FiltersOutput reports whether the plugin filters the output of bazel. It
+	// is called before the bazel build, test, coverage or run command runs.
+	FiltersOutput() (bool, error)
+	// FilterOutputLine is called with each line bazel writes to stdout or
+	// stderr, without its trailing newline, when FiltersOutput returns true. It
+	// returns the lines printed in its place: none to suppress the line, or
+	// rewritten or additional lines. Plugins are called in the order they are
+	// configured, each receiving the lines returned by the previous one.
+	FilterOutputLine(stream proto.OutputStream, line string) ([]string, error)
+	// PostBuildHook, PostTestHook and PostRunHook are called after the bazel
+	// build, test or coverage and run commands with the context of their
+	// invocation.
 	PostBuildHook(
-		isInteractiveMode bool,
+		invocation *proto.InvocationContext,
 		promptRunner prompt.PromptRunner,
 	) error
 	PostTestHook(
-		isInteractiveMode bool,
+		invocation *proto.InvocationContext,
 		promptRunner prompt.PromptRunner,
 	) error
 	PostRunHook(
-		isInteractiveMode bool,
+		invocation *proto.InvocationContext,
 		promptRunner prompt.PromptRunner,
 	) error
+	// PropertiesSchema returns the properties the plugin accepts in its config.
+	// `aspect plugin doctor` validates the configured properties against it. The
+	// properties are not validated when it returns none.
+	PropertiesSchema() ([]*proto.Property, error)
+	// RewriteArgs is called before the bazel build, test, coverage or run command
+	// is invoked and returns the arguments to run it with. Plugins are called in
+	// the order they are configured, each receiving the args returned by the
+	// previous one.
+	RewriteArgs(command string, args []string) ([]string, error)
 	Setup(config *SetupConfig) error
 }
 
+// BEPStreamReceiver is implemented by plugins that correlate the build events
+// with other systems. SetBEPStream is called with the build ID, invocation ID
+// and bazel command of a build event stream before BEPEventCallback receives
+// its first build event.
+type BEPStreamReceiver interface {
+	SetBEPStream(stream *proto.BEPStream)
+}
+
+// BEPStreamCallback is implemented by the clients the Core calls the plugins
+// with, to pass the stream the build events belong to along with them.
+type BEPStreamCallback interface {
+	BEPStreamEventCallback(event *buildeventstream.BuildEvent, sn int64, stream *proto.BEPStream) error
+}
+
 // SetupConfig represents a plugin configuration parsed from the aspectplugins
 // file.
 type SetupConfig struct {
 	File       *AspectPluginFile
 	Properties []byte
+	Workspace  *Workspace
 }
 
-// NewSetupConfig creates a new SetupConfig.
+// NewSetupConfig creates a new SetupConfig. A nil workspace is replaced with
+// an empty one.
 func NewSetupConfig(
 	properties []byte,
+	workspace *Workspace,
 ) *SetupConfig {
+	if workspace == nil {
+		workspace = &Workspace{}
+	}
 	return &SetupConfig{
 		File:       &AspectPluginFile{Path: ""},
 		Properties: properties,
+		Workspace:  workspace,
+	}
+}
+
+// Workspace describes the Bazel workspace the CLI is running in, so that
+// plugins don't need to run `bazel info` during Setup. The fields are
+// determined without starting the Bazel server and are empty when the CLI
+// runs outside of a workspace.
+type Workspace struct {
+	// Root is the absolute path of the workspace root.
+	Root string
+	// OutputBase is the output base of the workspace.
+	OutputBase string
+	// ExecutionRoot is the execution root of the workspace.
+	ExecutionRoot string
+	// BazelVersion is the Bazel version requested by the workspace, e.g.
+	// through .bazelversion. It may be a label such as "7.x".
+	BazelVersion string
+	// CLIVersion is the version of the Aspect CLI.
+	CLIVersion string
+}
+
+// WorkspaceFromProto converts the workspace sent by the CLI.
+func WorkspaceFromProto(workspace *proto.Workspace) *Workspace {
+	return &Workspace{
+		Root:          workspace.GetRoot(),
+		OutputBase:    workspace.GetOutputBase(),
+		ExecutionRoot: workspace.GetExecutionRoot(),
+		BazelVersion:  workspace.GetBazelVersion(),
+		CLIVersion:    workspace.GetCliVersion(),
+	}
+}
+
+// WorkspaceToProto converts the workspace sent to a plugin.
+func WorkspaceToProto(w *Workspace) *proto.Workspace {
+	if w == nil {
+		return nil
+	}
+	return &proto.Workspace{
+		Root:          w.Root,
+		OutputBase:    w.OutputBase,
+		ExecutionRoot: w.ExecutionRoot,
+		BazelVersion:  w.BazelVersion,
+		CliVersion:    w.CLIVersion,
 	}
 }
 
@@ -92,34 +187,86 @@ func (*Base) BEPEventCallback(*buildeventstream.BuildEvent, int64, string) error
 	return nil
 }
 
+// BEPEventTypes satisfies Plugin.BEPEventTypes.
+func (*Base) BEPEventTypes() ([]string, error) {
+	return nil, nil
+}
+
 // CustomCommands satisfies Plugin.BEPEventCallback.
 func (*Base) CustomCommands() ([]*Command, error) {
 	return nil, nil
 }
 
+// Daemons satisfies Plugin.Daemons.
+func (*Base) Daemons() ([]*proto.Daemon, error) {
+	return nil, nil
+}
+
+// FiltersOutput satisfies Plugin.FiltersOutput.
+func (*Base) FiltersOutput() (bool, error) {
+	return false, nil
+}
+
+// FilterOutputLine satisfies Plugin.FilterOutputLine.
+func (*Base) FilterOutputLine(_ proto.OutputStream, line string) ([]string, error) {
+	return []string{line}, nil
+}
+
 // PostBuildHook satisfies Plugin.PostBuildHook.
-func (*Base) PostBuildHook(bool, prompt.PromptRunner) error {
+func (*Base) PostBuildHook(*proto.InvocationContext, prompt.PromptRunner) error {
 	return nil
 }
 
 // PostTestHook satisfies Plugin.PostTestHook.
-func (*Base) PostTestHook(bool, prompt.PromptRunner) error {
+func (*Base) PostTestHook(*proto.InvocationContext, prompt.PromptRunner) error {
 	return nil
 }
 
 // PostRunHook satisfies Plugin.PostRunHook.
-func (*Base) PostRunHook(bool, prompt.PromptRunner) error {
+func (*Base) PostRunHook(*proto.InvocationContext, prompt.PromptRunner) error {
 	return nil
 }
 
+// PropertiesSchema satisfies Plugin.PropertiesSchema.
+func (*Base) PropertiesSchema() ([]*proto.Property, error) {
+	return nil, nil
+}
+
+// RewriteArgs satisfies Plugin.RewriteArgs.
+func (*Base) RewriteArgs(_ string, args []string) ([]string, error) {
+	return args, nil
+}
+
 // CustomCommandFn defines the parameters of that the Run functions will be called with.
 type CustomCommandFn (func(ctx context.Context, args []string, bazelStartupArgs []string) error)
 
+// BuiltinDelegation is returned by a custom command that overrides a built-in
+// command of the CLI, which plugins are allowed to with the
+// allow_command_override config, to run the built-in command with Args once
+// it is done.
+type BuiltinDelegation struct {
+	Args []string
+}
+
+// Error satisfies error.
+func (*BuiltinDelegation) Error() string {
+	return "delegating to the built-in command"
+}
+
+// DelegateToBuiltin returns the error that a custom command overriding a
+// built-in command returns to run the built-in command with the given args,
+// e.g. the args it was called with.
+func DelegateToBuiltin(args []string) error {
+	return &BuiltinDelegation{Args: args}
+}
+
 // Command defines the information needed to create a custom command that will be callable when
 // running the CLI.
 type Command struct {
 	*proto.Command
 	Run CustomCommandFn
+
+	subcommands []*Command
 }
 
 // NewCommand is a wrapper around Command. Designed to be used as a cleaner way to make a Command
@@ -140,6 +287,18 @@ func NewCommand(
 	}
 }
 
+// AddSubcommands nests the given commands under the command, so that a plugin
+// can namespace its commands such as `aspect mycompany deploy staging`. A
+// command with subcommands only groups them: its Run is not called and it
+// cannot declare flags.
+func (c *Command) AddSubcommands(subcommands ...*Command) *Command {
+	for _, subcommand := range subcommands {
+		c.subcommands = append(c.subcommands, subcommand)
+		c.Command.Subcommands = append(c.Command.Subcommands, subcommand.Command)
+	}
+	return c
+}
+
 // CommandManager is internal to the SDK and is used to manage custom commands that
 // are provided by plugins.
 type CommandManager interface {
@@ -153,22 +312,47 @@ type PluginCommandManager struct {
 	commands map[string]CustomCommandFn
 }
 
-// Save satisfies CommandManager.
+// Save satisfies CommandManager. It replaces the previously saved commands.
 func (cm *PluginCommandManager) Save(commands []*Command) error {
+	saved := make(map[string]CustomCommandFn)
+	if err := saveCommands(saved, "", commands); err != nil {
+		return err
+	}
+	cm.commands = saved
+	return nil
+}
+
+// saveCommands saves the Run functions of the commands keyed by their path of
+// command names, e.g. "mycompany deploy staging", which the Core executes them
+// by.
+func saveCommands(saved map[string]CustomCommandFn, parent string, commands []*Command) error {
 	for _, cmd := range commands {
-		cmdName := strings.SplitN(cmd.Use, " ", 2)[0]
-		if _, exists := cm.commands[cmdName]; exists {
-			return fmt.Errorf("command %q is declared more than once by plugin", cmdName)
+		path := strings.SplitN(cmd.Use, " ", 2)[0]
+		if parent != "" {
+			path = parent + " " + path
+		}
+		if _, exists := saved[path]; exists {
+			return fmt.Errorf("command %q is declared more than once by plugin", path)
+		}
+		if len(cmd.subcommands) > 0 {
+			saved[path] = nil
+			if err := saveCommands(saved, path, cmd.subcommands); err != nil {
+				return err
+			}
+			continue
 		}
-		cm.commands[cmdName] = cmd.Run
+		saved[path] = cmd.Run
 	}
-
 	return nil
 }
 
 // Execute satisfies CommandManager.
 func (cm *PluginCommandManager) Execute(command string, ctx context.Context, args []string, bazelStartupArgs []string) error {
-	return cm.commands[command](ctx, args, bazelStartupArgs)
+	run := cm.commands[command]
+	if run == nil {
+		return fmt.Errorf("unknown custom command %q", command)
+	}
+	return run(ctx, args, bazelStartupArgs)
 }
 
 var _ CommandManager = (*PluginCommandManager)(nil)
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package plugin
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+)
+
+// NamedSets indexes the NamedSetOfFiles build events of an invocation, so that
+// the files of a TargetComplete event can be resolved when they are needed.
+// Bazel sends the files of a target as named sets that may nest other named
+// sets, which are shared between targets and sent only once. Passing every
+// build event to Add in order ensures that the sets a TargetComplete event
+// refers to are known by the time it is received. It is safe for concurrent
+// use.
+type NamedSets struct {
+	mu   sync.RWMutex
+	sets map[string]*buildeventstream.NamedSetOfFiles
+}
+
+// NewNamedSets returns an empty NamedSets.
+func NewNamedSets() *NamedSets {
+	return &NamedSets{sets: make(map[string]*buildeventstream.NamedSetOfFiles)}
+}
+
+// Add indexes event if it is a NamedSetOfFiles event. The index is cleared
+// when the BuildStarted event of another invocation is added, since named set
+// IDs are only unique within an invocation.
+func (n *NamedSets) Add(event *buildeventstream.BuildEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if event.GetStarted() != nil {
+		clear(n.sets)
+		return
+	}
+	if set := event.GetNamedSetOfFiles(); set != nil {
+		n.sets[event.GetId().GetNamedSet().GetId()] = set
+	}
+}
+
+// Files returns the files of the named sets and of the sets nested in them, in
+// the order bazel lists them. A set nested in several sets is only resolved
+// once. It returns an error if a set hasn't been added.
+func (n *NamedSets) Files(ids ...*buildeventstream.BuildEventId_NamedSetOfFilesId) ([]*buildeventstream.File, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	var files []*buildeventstream.File
+	visited := make(map[string]bool)
+	// The sets are visited depth first without recursion, since they can be
+	// nested deeply.
+	var stack []string
+	for i := len(ids) - 1; i >= 0; i-- {
+		stack = append(stack, ids[i].GetId())
+	}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		set, ok := n.sets[id]
+		if !ok {
+			return nil, fmt.Errorf("named set of files %q was not received", id)
+		}
+		files = append(files, set.GetFiles()...)
+		nested := set.GetFileSets()
+		for i := len(nested) - 1; i >= 0; i-- {
+			stack = append(stack, nested[i].GetId())
+		}
+	}
+	return files, nil
+}
+
+// OutputFiles returns the files of the output groups of a TargetComplete event
+// with the given names, or of all its output groups if none are given.
+func (n *NamedSets) OutputFiles(completed *buildeventstream.TargetComplete, groups ...string) ([]*buildeventstream.File, error) {
+	var ids []*buildeventstream.BuildEventId_NamedSetOfFilesId
+	for _, group := range completed.GetOutputGroup() {
+		if len(groups) > 0 && !slices.Contains(groups, group.GetName()) {
+			continue
+		}
+		ids = append(ids, group.GetFileSets()...)
+	}
+	return n.Files(ids...)
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package plugin
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+)
+
+func namedSetEvent(id string, files []string, nested ...string) *buildeventstream.BuildEvent {
+	set := &buildeventstream.NamedSetOfFiles{}
+	for _, name := range files {
+		set.Files = append(set.Files, &buildeventstream.File{Name: name})
+	}
+	for _, n := range nested {
+		set.FileSets = append(set.FileSets, &buildeventstream.BuildEventId_NamedSetOfFilesId{Id: n})
+	}
+	return &buildeventstream.BuildEvent{
+		Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_NamedSet{
+			NamedSet: &buildeventstream.BuildEventId_NamedSetOfFilesId{Id: id},
+		}},
+		Payload: &buildeventstream.BuildEvent_NamedSetOfFiles{NamedSetOfFiles: set},
+	}
+}
+
+func fileNames(files []*buildeventstream.File) []string {
+	var names []string
+	for _, file := range files {
+		names = append(names, file.GetName())
+	}
+	return names
+}
+
+func TestNamedSets(t *testing.T) {
+	completed := &buildeventstream.TargetComplete{
+		OutputGroup: []*buildeventstream.OutputGroup{
+			{Name: "default", FileSets: []*buildeventstream.BuildEventId_NamedSetOfFilesId{{Id: "2"}}},
+			{Name: "report", FileSets: []*buildeventstream.BuildEventId_NamedSetOfFilesId{{Id: "3"}}},
+		},
+	}
+
+	t.Run("resolves the files of nested sets once", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		sets := NewNamedSets()
+		sets.Add(namedSetEvent("0", []string{"shared.h"}))
+		sets.Add(namedSetEvent("1", []string{"lib.a"}, "0"))
+		sets.Add(namedSetEvent("2", []string{"bin"}, "1", "0"))
+		sets.Add(namedSetEvent("3", []string{"report.txt"}))
+
+		files, err := sets.OutputFiles(completed)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(fileNames(files)).To(Equal([]string{"bin", "lib.a", "shared.h", "report.txt"}))
+
+		files, err = sets.OutputFiles(completed, "report")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(fileNames(files)).To(Equal([]string{"report.txt"}))
+	})
+
+	t.Run("reports the sets that were not received", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		sets := NewNamedSets()
+		sets.Add(namedSetEvent("2", []string{"bin"}, "1"))
+
+		_, err := sets.OutputFiles(completed, "default")
+		g.Expect(err).To(MatchError(`named set of files "1" was not received`))
+	})
+
+	t.Run("forgets the sets of earlier invocations", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		sets := NewNamedSets()
+		sets.Add(namedSetEvent("3", []string{"report.txt"}))
+		sets.Add(&buildeventstream.BuildEvent{
+			Payload: &buildeventstream.BuildEvent_Started{Started: &buildeventstream.BuildStarted{}},
+		})
+
+		_, err := sets.OutputFiles(completed, "report")
+		g.Expect(err).To(HaveOccurred())
+	})
+}
@@ -26,11 +26,217 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type OutputStream int32
+
+const (
+	OutputStream_STDOUT OutputStream = 0
+	OutputStream_STDERR OutputStream = 1
+)
+
+// Enum value maps for OutputStream.
+var (
+	OutputStream_name = map[int32]string{
+		0: "STDOUT",
+		1: "STDERR",
+	}
+	OutputStream_value = map[string]int32{
+		"STDOUT": 0,
+		"STDERR": 1,
+	}
+)
+
+func (x OutputStream) Enum() *OutputStream {
+	p := new(OutputStream)
+	*p = x
+	return p
+}
+
+func (x OutputStream) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OutputStream) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_enumTypes[0].Descriptor()
+}
+
+func (OutputStream) Type() protoreflect.EnumType {
+	return &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_enumTypes[0]
+}
+
+func (x OutputStream) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OutputStream.Descriptor instead.
+func (OutputStream) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{0}
+}
+
+type Property_Type int32
+
+const (
+	Property_STRING Property_Type = 0
+	Property_BOOL   Property_Type = 1
+	Property_INT    Property_Type = 2
+	Property_FLOAT  Property_Type = 3
+	Property_LIST   Property_Type = 4
+	Property_MAP    Property_Type = 5
+)
+
+// Enum value maps for Property_Type.
+var (
+	Property_Type_name = map[int32]string{
+		0: "STRING",
+		1: "BOOL",
+		2: "INT",
+		3: "FLOAT",
+		4: "LIST",
+		5: "MAP",
+	}
+	Property_Type_value = map[string]int32{
+		"STRING": 0,
+		"BOOL":   1,
+		"INT":    2,
+		"FLOAT":  3,
+		"LIST":   4,
+		"MAP":    5,
+	}
+)
+
+func (x Property_Type) Enum() *Property_Type {
+	p := new(Property_Type)
+	*p = x
+	return p
+}
+
+func (x Property_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Property_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_enumTypes[1].Descriptor()
+}
+
+func (Property_Type) Type() protoreflect.EnumType {
+	return &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_enumTypes[1]
+}
+
+func (x Property_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Property_Type.Descriptor instead.
+func (Property_Type) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{10, 0}
+}
+
+type HookResult_Outcome int32
+
+const (
+	HookResult_UNSPECIFIED HookResult_Outcome = 0
+	HookResult_FAIL        HookResult_Outcome = 1
+	HookResult_EXIT_CODE   HookResult_Outcome = 2
+	HookResult_WARN        HookResult_Outcome = 3
+)
+
+// Enum value maps for HookResult_Outcome.
+var (
+	HookResult_Outcome_name = map[int32]string{
+		0: "UNSPECIFIED",
+		1: "FAIL",
+		2: "EXIT_CODE",
+		3: "WARN",
+	}
+	HookResult_Outcome_value = map[string]int32{
+		"UNSPECIFIED": 0,
+		"FAIL":        1,
+		"EXIT_CODE":   2,
+		"WARN":        3,
+	}
+)
+
+func (x HookResult_Outcome) Enum() *HookResult_Outcome {
+	p := new(HookResult_Outcome)
+	*p = x
+	return p
+}
+
+func (x HookResult_Outcome) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (HookResult_Outcome) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_enumTypes[2].Descriptor()
+}
+
+func (HookResult_Outcome) Type() protoreflect.EnumType {
+	return &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_enumTypes[2]
+}
+
+func (x HookResult_Outcome) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use HookResult_Outcome.Descriptor instead.
+func (HookResult_Outcome) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{21, 0}
+}
+
+type Flag_Type int32
+
+const (
+	Flag_STRING Flag_Type = 0
+	Flag_BOOL   Flag_Type = 1
+	Flag_INT    Flag_Type = 2
+)
+
+// Enum value maps for Flag_Type.
+var (
+	Flag_Type_name = map[int32]string{
+		0: "STRING",
+		1: "BOOL",
+		2: "INT",
+	}
+	Flag_Type_value = map[string]int32{
+		"STRING": 0,
+		"BOOL":   1,
+		"INT":    2,
+	}
+)
+
+func (x Flag_Type) Enum() *Flag_Type {
+	p := new(Flag_Type)
+	*p = x
+	return p
+}
+
+func (x Flag_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Flag_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_enumTypes[3].Descriptor()
+}
+
+func (Flag_Type) Type() protoreflect.EnumType {
+	return &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_enumTypes[3]
+}
+
+func (x Flag_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Flag_Type.Descriptor instead.
+func (Flag_Type) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{24, 0}
+}
+
 type BEPEventCallbackReq struct {
 	state          protoimpl.MessageState       `protogen:"open.v1"`
 	Event          *buildeventstream.BuildEvent `protobuf:"bytes,1,opt,name=event,proto3" json:"event,omitempty"`
 	SequenceNumber int64                        `protobuf:"varint,2,opt,name=sequence_number,json=sequenceNumber,proto3" json:"sequence_number,omitempty"`
 	InvocationId   string                       `protobuf:"bytes,3,opt,name=invocation_id,json=invocationId,proto3" json:"invocation_id,omitempty"`
+	Stream         *BEPStream                   `protobuf:"bytes,4,opt,name=stream,proto3" json:"stream,omitempty"`
 	unknownFields  protoimpl.UnknownFields
 	sizeCache      protoimpl.SizeCache
 }
@@ -86,26 +292,36 @@ func (x *BEPEventCallbackReq) GetInvocationId() string {
 	return ""
 }
 
-type BEPEventCallbackRes struct {
+func (x *BEPEventCallbackReq) GetStream() *BEPStream {
+	if x != nil {
+		return x.Stream
+	}
+	return nil
+}
+
+type BEPStream struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	BuildId       string                 `protobuf:"bytes,1,opt,name=build_id,json=buildId,proto3" json:"build_id,omitempty"`
+	InvocationId  string                 `protobuf:"bytes,2,opt,name=invocation_id,json=invocationId,proto3" json:"invocation_id,omitempty"`
+	Command       string                 `protobuf:"bytes,3,opt,name=command,proto3" json:"command,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *BEPEventCallbackRes) Reset() {
-	*x = BEPEventCallbackRes{}
+func (x *BEPStream) Reset() {
+	*x = BEPStream{}
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[1]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *BEPEventCallbackRes) String() string {
+func (x *BEPStream) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*BEPEventCallbackRes) ProtoMessage() {}
+func (*BEPStream) ProtoMessage() {}
 
-func (x *BEPEventCallbackRes) ProtoReflect() protoreflect.Message {
+func (x *BEPStream) ProtoReflect() protoreflect.Message {
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[1]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -117,34 +333,52 @@ func (x *BEPEventCallbackRes) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use BEPEventCallbackRes.ProtoReflect.Descriptor instead.
-func (*BEPEventCallbackRes) Descriptor() ([]byte, []int) {
+// Deprecated: Use BEPStream.ProtoReflect.Descriptor instead.
+func (*BEPStream) Descriptor() ([]byte, []int) {
 	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{1}
 }
 
-type SetupReq struct {
-	state      protoimpl.MessageState `protogen:"open.v1"`
-	Properties []byte                 `protobuf:"bytes,1,opt,name=properties,proto3" json:"properties,omitempty"`
-	// Deprecated: Marked as deprecated in pkg/plugin/sdk/v1alpha4/proto/plugin.proto.
-	File          *File `protobuf:"bytes,2,opt,name=file,proto3" json:"file,omitempty"`
+func (x *BEPStream) GetBuildId() string {
+	if x != nil {
+		return x.BuildId
+	}
+	return ""
+}
+
+func (x *BEPStream) GetInvocationId() string {
+	if x != nil {
+		return x.InvocationId
+	}
+	return ""
+}
+
+func (x *BEPStream) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+type BEPEventCallbackRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SetupReq) Reset() {
-	*x = SetupReq{}
+func (x *BEPEventCallbackRes) Reset() {
+	*x = BEPEventCallbackRes{}
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SetupReq) String() string {
+func (x *BEPEventCallbackRes) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SetupReq) ProtoMessage() {}
+func (*BEPEventCallbackRes) ProtoMessage() {}
 
-func (x *SetupReq) ProtoReflect() protoreflect.Message {
+func (x *BEPEventCallbackRes) ProtoReflect() protoreflect.Message {
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -156,47 +390,31 @@ func (x *SetupReq) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SetupReq.ProtoReflect.Descriptor instead.
-func (*SetupReq) Descriptor() ([]byte, []int) {
+// Deprecated: Use BEPEventCallbackRes.ProtoReflect.Descriptor instead.
+func (*BEPEventCallbackRes) Descriptor() ([]byte, []int) {
 	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{2}
 }
 
-func (x *SetupReq) GetProperties() []byte {
-	if x != nil {
-		return x.Properties
-	}
-	return nil
-}
-
-// Deprecated: Marked as deprecated in pkg/plugin/sdk/v1alpha4/proto/plugin.proto.
-func (x *SetupReq) GetFile() *File {
-	if x != nil {
-		return x.File
-	}
-	return nil
-}
-
-type File struct {
+type BEPEventTypesReq struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *File) Reset() {
-	*x = File{}
+func (x *BEPEventTypesReq) Reset() {
+	*x = BEPEventTypesReq{}
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *File) String() string {
+func (x *BEPEventTypesReq) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*File) ProtoMessage() {}
+func (*BEPEventTypesReq) ProtoMessage() {}
 
-func (x *File) ProtoReflect() protoreflect.Message {
+func (x *BEPEventTypesReq) ProtoReflect() protoreflect.Message {
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -208,38 +426,32 @@ func (x *File) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use File.ProtoReflect.Descriptor instead.
-func (*File) Descriptor() ([]byte, []int) {
+// Deprecated: Use BEPEventTypesReq.ProtoReflect.Descriptor instead.
+func (*BEPEventTypesReq) Descriptor() ([]byte, []int) {
 	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *File) GetPath() string {
-	if x != nil {
-		return x.Path
-	}
-	return ""
-}
-
-type SetupRes struct {
+type BEPEventTypesRes struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	EventTypes    []string               `protobuf:"bytes,1,rep,name=event_types,json=eventTypes,proto3" json:"event_types,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SetupRes) Reset() {
-	*x = SetupRes{}
+func (x *BEPEventTypesRes) Reset() {
+	*x = BEPEventTypesRes{}
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SetupRes) String() string {
+func (x *BEPEventTypesRes) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SetupRes) ProtoMessage() {}
+func (*BEPEventTypesRes) ProtoMessage() {}
 
-func (x *SetupRes) ProtoReflect() protoreflect.Message {
+func (x *BEPEventTypesRes) ProtoReflect() protoreflect.Message {
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -251,33 +463,38 @@ func (x *SetupRes) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SetupRes.ProtoReflect.Descriptor instead.
-func (*SetupRes) Descriptor() ([]byte, []int) {
+// Deprecated: Use BEPEventTypesRes.ProtoReflect.Descriptor instead.
+func (*BEPEventTypesRes) Descriptor() ([]byte, []int) {
 	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{4}
 }
 
-type PostBuildHookReq struct {
-	state             protoimpl.MessageState `protogen:"open.v1"`
-	BrokerId          uint32                 `protobuf:"varint,1,opt,name=broker_id,json=brokerId,proto3" json:"broker_id,omitempty"`
-	IsInteractiveMode bool                   `protobuf:"varint,2,opt,name=is_interactive_mode,json=isInteractiveMode,proto3" json:"is_interactive_mode,omitempty"`
-	unknownFields     protoimpl.UnknownFields
-	sizeCache         protoimpl.SizeCache
+func (x *BEPEventTypesRes) GetEventTypes() []string {
+	if x != nil {
+		return x.EventTypes
+	}
+	return nil
 }
 
-func (x *PostBuildHookReq) Reset() {
-	*x = PostBuildHookReq{}
+type DaemonsReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DaemonsReq) Reset() {
+	*x = DaemonsReq{}
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PostBuildHookReq) String() string {
+func (x *DaemonsReq) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PostBuildHookReq) ProtoMessage() {}
+func (*DaemonsReq) ProtoMessage() {}
 
-func (x *PostBuildHookReq) ProtoReflect() protoreflect.Message {
+func (x *DaemonsReq) ProtoReflect() protoreflect.Message {
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -289,45 +506,32 @@ func (x *PostBuildHookReq) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PostBuildHookReq.ProtoReflect.Descriptor instead.
-func (*PostBuildHookReq) Descriptor() ([]byte, []int) {
+// Deprecated: Use DaemonsReq.ProtoReflect.Descriptor instead.
+func (*DaemonsReq) Descriptor() ([]byte, []int) {
 	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{5}
 }
 
-func (x *PostBuildHookReq) GetBrokerId() uint32 {
-	if x != nil {
-		return x.BrokerId
-	}
-	return 0
-}
-
-func (x *PostBuildHookReq) GetIsInteractiveMode() bool {
-	if x != nil {
-		return x.IsInteractiveMode
-	}
-	return false
-}
-
-type PostBuildHookRes struct {
+type DaemonsRes struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	Daemons       []*Daemon              `protobuf:"bytes,1,rep,name=daemons,proto3" json:"daemons,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PostBuildHookRes) Reset() {
-	*x = PostBuildHookRes{}
+func (x *DaemonsRes) Reset() {
+	*x = DaemonsRes{}
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PostBuildHookRes) String() string {
+func (x *DaemonsRes) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PostBuildHookRes) ProtoMessage() {}
+func (*DaemonsRes) ProtoMessage() {}
 
-func (x *PostBuildHookRes) ProtoReflect() protoreflect.Message {
+func (x *DaemonsRes) ProtoReflect() protoreflect.Message {
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -339,34 +543,41 @@ func (x *PostBuildHookRes) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PostBuildHookRes.ProtoReflect.Descriptor instead.
-func (*PostBuildHookRes) Descriptor() ([]byte, []int) {
+// Deprecated: Use DaemonsRes.ProtoReflect.Descriptor instead.
+func (*DaemonsRes) Descriptor() ([]byte, []int) {
 	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{6}
 }
 
-type Command struct {
+func (x *DaemonsRes) GetDaemons() []*Daemon {
+	if x != nil {
+		return x.Daemons
+	}
+	return nil
+}
+
+type Daemon struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Use           string                 `protobuf:"bytes,1,opt,name=use,proto3" json:"use,omitempty"`
-	ShortDesc     string                 `protobuf:"bytes,2,opt,name=short_desc,json=shortDesc,proto3" json:"short_desc,omitempty"`
-	LongDesc      string                 `protobuf:"bytes,3,opt,name=long_desc,json=longDesc,proto3" json:"long_desc,omitempty"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Args          []string               `protobuf:"bytes,2,rep,name=args,proto3" json:"args,omitempty"`
+	Env           map[string]string      `protobuf:"bytes,3,rep,name=env,proto3" json:"env,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Command) Reset() {
-	*x = Command{}
+func (x *Daemon) Reset() {
+	*x = Daemon{}
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Command) String() string {
+func (x *Daemon) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Command) ProtoMessage() {}
+func (*Daemon) ProtoMessage() {}
 
-func (x *Command) ProtoReflect() protoreflect.Message {
+func (x *Daemon) ProtoReflect() protoreflect.Message {
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -378,52 +589,52 @@ func (x *Command) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Command.ProtoReflect.Descriptor instead.
-func (*Command) Descriptor() ([]byte, []int) {
+// Deprecated: Use Daemon.ProtoReflect.Descriptor instead.
+func (*Daemon) Descriptor() ([]byte, []int) {
 	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{7}
 }
 
-func (x *Command) GetUse() string {
+func (x *Daemon) GetName() string {
 	if x != nil {
-		return x.Use
+		return x.Name
 	}
 	return ""
 }
 
-func (x *Command) GetShortDesc() string {
+func (x *Daemon) GetArgs() []string {
 	if x != nil {
-		return x.ShortDesc
+		return x.Args
 	}
-	return ""
+	return nil
 }
 
-func (x *Command) GetLongDesc() string {
+func (x *Daemon) GetEnv() map[string]string {
 	if x != nil {
-		return x.LongDesc
+		return x.Env
 	}
-	return ""
+	return nil
 }
 
-type CustomCommandsReq struct {
+type PropertiesSchemaReq struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CustomCommandsReq) Reset() {
-	*x = CustomCommandsReq{}
+func (x *PropertiesSchemaReq) Reset() {
+	*x = PropertiesSchemaReq{}
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CustomCommandsReq) String() string {
+func (x *PropertiesSchemaReq) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CustomCommandsReq) ProtoMessage() {}
+func (*PropertiesSchemaReq) ProtoMessage() {}
 
-func (x *CustomCommandsReq) ProtoReflect() protoreflect.Message {
+func (x *PropertiesSchemaReq) ProtoReflect() protoreflect.Message {
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -435,32 +646,32 @@ func (x *CustomCommandsReq) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CustomCommandsReq.ProtoReflect.Descriptor instead.
-func (*CustomCommandsReq) Descriptor() ([]byte, []int) {
+// Deprecated: Use PropertiesSchemaReq.ProtoReflect.Descriptor instead.
+func (*PropertiesSchemaReq) Descriptor() ([]byte, []int) {
 	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{8}
 }
 
-type CustomCommandsRes struct {
+type PropertiesSchemaRes struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Commands      []*Command             `protobuf:"bytes,1,rep,name=commands,proto3" json:"commands,omitempty"`
+	Properties    []*Property            `protobuf:"bytes,1,rep,name=properties,proto3" json:"properties,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CustomCommandsRes) Reset() {
-	*x = CustomCommandsRes{}
+func (x *PropertiesSchemaRes) Reset() {
+	*x = PropertiesSchemaRes{}
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CustomCommandsRes) String() string {
+func (x *PropertiesSchemaRes) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CustomCommandsRes) ProtoMessage() {}
+func (*PropertiesSchemaRes) ProtoMessage() {}
 
-func (x *CustomCommandsRes) ProtoReflect() protoreflect.Message {
+func (x *PropertiesSchemaRes) ProtoReflect() protoreflect.Message {
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -472,39 +683,42 @@ func (x *CustomCommandsRes) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CustomCommandsRes.ProtoReflect.Descriptor instead.
-func (*CustomCommandsRes) Descriptor() ([]byte, []int) {
+// Deprecated: Use PropertiesSchemaRes.ProtoReflect.Descriptor instead.
+func (*PropertiesSchemaRes) Descriptor() ([]byte, []int) {
 	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{9}
 }
 
-func (x *CustomCommandsRes) GetCommands() []*Command {
+func (x *PropertiesSchemaRes) GetProperties() []*Property {
 	if x != nil {
-		return x.Commands
+		return x.Properties
 	}
 	return nil
 }
 
-type Context struct {
+type Property struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	WorkspaceRoot string                 `protobuf:"bytes,1,opt,name=workspaceRoot,proto3" json:"workspaceRoot,omitempty"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type          Property_Type          `protobuf:"varint,2,opt,name=type,proto3,enum=proto.Property_Type" json:"type,omitempty"`
+	Required      bool                   `protobuf:"varint,3,opt,name=required,proto3" json:"required,omitempty"`
+	Description   string                 `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Context) Reset() {
-	*x = Context{}
+func (x *Property) Reset() {
+	*x = Property{}
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Context) String() string {
+func (x *Property) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Context) ProtoMessage() {}
+func (*Property) ProtoMessage() {}
 
-func (x *Context) ProtoReflect() protoreflect.Message {
+func (x *Property) ProtoReflect() protoreflect.Message {
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -516,42 +730,63 @@ func (x *Context) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Context.ProtoReflect.Descriptor instead.
-func (*Context) Descriptor() ([]byte, []int) {
+// Deprecated: Use Property.ProtoReflect.Descriptor instead.
+func (*Property) Descriptor() ([]byte, []int) {
 	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{10}
 }
 
-func (x *Context) GetWorkspaceRoot() string {
+func (x *Property) GetName() string {
 	if x != nil {
-		return x.WorkspaceRoot
+		return x.Name
 	}
 	return ""
 }
 
-type ExecuteCustomCommandReq struct {
-	state            protoimpl.MessageState `protogen:"open.v1"`
-	CustomCommand    string                 `protobuf:"bytes,1,opt,name=customCommand,proto3" json:"customCommand,omitempty"`
-	Ctx              *Context               `protobuf:"bytes,2,opt,name=ctx,proto3" json:"ctx,omitempty"`
-	Args             []string               `protobuf:"bytes,3,rep,name=args,proto3" json:"args,omitempty"`
-	BazelStartupArgs []string               `protobuf:"bytes,4,rep,name=bazelStartupArgs,proto3" json:"bazelStartupArgs,omitempty"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+func (x *Property) GetType() Property_Type {
+	if x != nil {
+		return x.Type
+	}
+	return Property_STRING
 }
 
-func (x *ExecuteCustomCommandReq) Reset() {
-	*x = ExecuteCustomCommandReq{}
+func (x *Property) GetRequired() bool {
+	if x != nil {
+		return x.Required
+	}
+	return false
+}
+
+func (x *Property) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+type SetupReq struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Properties []byte                 `protobuf:"bytes,1,opt,name=properties,proto3" json:"properties,omitempty"`
+	// Deprecated: Marked as deprecated in pkg/plugin/sdk/v1alpha4/proto/plugin.proto.
+	File          *File      `protobuf:"bytes,2,opt,name=file,proto3" json:"file,omitempty"`
+	Workspace     *Workspace `protobuf:"bytes,3,opt,name=workspace,proto3" json:"workspace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetupReq) Reset() {
+	*x = SetupReq{}
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ExecuteCustomCommandReq) String() string {
+func (x *SetupReq) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ExecuteCustomCommandReq) ProtoMessage() {}
+func (*SetupReq) ProtoMessage() {}
 
-func (x *ExecuteCustomCommandReq) ProtoReflect() protoreflect.Message {
+func (x *SetupReq) ProtoReflect() protoreflect.Message {
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -563,59 +798,58 @@ func (x *ExecuteCustomCommandReq) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ExecuteCustomCommandReq.ProtoReflect.Descriptor instead.
-func (*ExecuteCustomCommandReq) Descriptor() ([]byte, []int) {
+// Deprecated: Use SetupReq.ProtoReflect.Descriptor instead.
+func (*SetupReq) Descriptor() ([]byte, []int) {
 	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{11}
 }
 
-func (x *ExecuteCustomCommandReq) GetCustomCommand() string {
-	if x != nil {
-		return x.CustomCommand
-	}
-	return ""
-}
-
-func (x *ExecuteCustomCommandReq) GetCtx() *Context {
+func (x *SetupReq) GetProperties() []byte {
 	if x != nil {
-		return x.Ctx
+		return x.Properties
 	}
 	return nil
 }
 
-func (x *ExecuteCustomCommandReq) GetArgs() []string {
+// Deprecated: Marked as deprecated in pkg/plugin/sdk/v1alpha4/proto/plugin.proto.
+func (x *SetupReq) GetFile() *File {
 	if x != nil {
-		return x.Args
+		return x.File
 	}
 	return nil
 }
 
-func (x *ExecuteCustomCommandReq) GetBazelStartupArgs() []string {
+func (x *SetupReq) GetWorkspace() *Workspace {
 	if x != nil {
-		return x.BazelStartupArgs
+		return x.Workspace
 	}
 	return nil
 }
 
-type ExecuteCustomCommandRes struct {
+type Workspace struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	Root          string                 `protobuf:"bytes,1,opt,name=root,proto3" json:"root,omitempty"`
+	OutputBase    string                 `protobuf:"bytes,2,opt,name=output_base,json=outputBase,proto3" json:"output_base,omitempty"`
+	ExecutionRoot string                 `protobuf:"bytes,3,opt,name=execution_root,json=executionRoot,proto3" json:"execution_root,omitempty"`
+	BazelVersion  string                 `protobuf:"bytes,4,opt,name=bazel_version,json=bazelVersion,proto3" json:"bazel_version,omitempty"`
+	CliVersion    string                 `protobuf:"bytes,5,opt,name=cli_version,json=cliVersion,proto3" json:"cli_version,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ExecuteCustomCommandRes) Reset() {
-	*x = ExecuteCustomCommandRes{}
+func (x *Workspace) Reset() {
+	*x = Workspace{}
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ExecuteCustomCommandRes) String() string {
+func (x *Workspace) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ExecuteCustomCommandRes) ProtoMessage() {}
+func (*Workspace) ProtoMessage() {}
 
-func (x *ExecuteCustomCommandRes) ProtoReflect() protoreflect.Message {
+func (x *Workspace) ProtoReflect() protoreflect.Message {
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -627,33 +861,67 @@ func (x *ExecuteCustomCommandRes) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ExecuteCustomCommandRes.ProtoReflect.Descriptor instead.
-func (*ExecuteCustomCommandRes) Descriptor() ([]byte, []int) {
+// Deprecated: Use Workspace.ProtoReflect.Descriptor instead.
+func (*Workspace) Descriptor() ([]byte, []int) {
 	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{12}
 }
 
-type PostTestHookReq struct {
-	state             protoimpl.MessageState `protogen:"open.v1"`
-	BrokerId          uint32                 `protobuf:"varint,1,opt,name=broker_id,json=brokerId,proto3" json:"broker_id,omitempty"`
-	IsInteractiveMode bool                   `protobuf:"varint,2,opt,name=is_interactive_mode,json=isInteractiveMode,proto3" json:"is_interactive_mode,omitempty"`
-	unknownFields     protoimpl.UnknownFields
-	sizeCache         protoimpl.SizeCache
+func (x *Workspace) GetRoot() string {
+	if x != nil {
+		return x.Root
+	}
+	return ""
 }
 
-func (x *PostTestHookReq) Reset() {
-	*x = PostTestHookReq{}
+func (x *Workspace) GetOutputBase() string {
+	if x != nil {
+		return x.OutputBase
+	}
+	return ""
+}
+
+func (x *Workspace) GetExecutionRoot() string {
+	if x != nil {
+		return x.ExecutionRoot
+	}
+	return ""
+}
+
+func (x *Workspace) GetBazelVersion() string {
+	if x != nil {
+		return x.BazelVersion
+	}
+	return ""
+}
+
+func (x *Workspace) GetCliVersion() string {
+	if x != nil {
+		return x.CliVersion
+	}
+	return ""
+}
+
+type File struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *File) Reset() {
+	*x = File{}
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PostTestHookReq) String() string {
+func (x *File) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PostTestHookReq) ProtoMessage() {}
+func (*File) ProtoMessage() {}
 
-func (x *PostTestHookReq) ProtoReflect() protoreflect.Message {
+func (x *File) ProtoReflect() protoreflect.Message {
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -665,45 +933,38 @@ func (x *PostTestHookReq) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PostTestHookReq.ProtoReflect.Descriptor instead.
-func (*PostTestHookReq) Descriptor() ([]byte, []int) {
+// Deprecated: Use File.ProtoReflect.Descriptor instead.
+func (*File) Descriptor() ([]byte, []int) {
 	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{13}
 }
 
-func (x *PostTestHookReq) GetBrokerId() uint32 {
-	if x != nil {
-		return x.BrokerId
-	}
-	return 0
-}
-
-func (x *PostTestHookReq) GetIsInteractiveMode() bool {
+func (x *File) GetPath() string {
 	if x != nil {
-		return x.IsInteractiveMode
+		return x.Path
 	}
-	return false
+	return ""
 }
 
-type PostTestHookRes struct {
+type SetupRes struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PostTestHookRes) Reset() {
-	*x = PostTestHookRes{}
+func (x *SetupRes) Reset() {
+	*x = SetupRes{}
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PostTestHookRes) String() string {
+func (x *SetupRes) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PostTestHookRes) ProtoMessage() {}
+func (*SetupRes) ProtoMessage() {}
 
-func (x *PostTestHookRes) ProtoReflect() protoreflect.Message {
+func (x *SetupRes) ProtoReflect() protoreflect.Message {
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -715,33 +976,33 @@ func (x *PostTestHookRes) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PostTestHookRes.ProtoReflect.Descriptor instead.
-func (*PostTestHookRes) Descriptor() ([]byte, []int) {
+// Deprecated: Use SetupRes.ProtoReflect.Descriptor instead.
+func (*SetupRes) Descriptor() ([]byte, []int) {
 	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{14}
 }
 
-type PostRunHookReq struct {
-	state             protoimpl.MessageState `protogen:"open.v1"`
-	BrokerId          uint32                 `protobuf:"varint,1,opt,name=broker_id,json=brokerId,proto3" json:"broker_id,omitempty"`
-	IsInteractiveMode bool                   `protobuf:"varint,2,opt,name=is_interactive_mode,json=isInteractiveMode,proto3" json:"is_interactive_mode,omitempty"`
-	unknownFields     protoimpl.UnknownFields
-	sizeCache         protoimpl.SizeCache
+type FilterOutputLineReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Stream        OutputStream           `protobuf:"varint,1,opt,name=stream,proto3,enum=proto.OutputStream" json:"stream,omitempty"`
+	Line          string                 `protobuf:"bytes,2,opt,name=line,proto3" json:"line,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PostRunHookReq) Reset() {
-	*x = PostRunHookReq{}
+func (x *FilterOutputLineReq) Reset() {
+	*x = FilterOutputLineReq{}
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PostRunHookReq) String() string {
+func (x *FilterOutputLineReq) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PostRunHookReq) ProtoMessage() {}
+func (*FilterOutputLineReq) ProtoMessage() {}
 
-func (x *PostRunHookReq) ProtoReflect() protoreflect.Message {
+func (x *FilterOutputLineReq) ProtoReflect() protoreflect.Message {
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -753,45 +1014,46 @@ func (x *PostRunHookReq) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PostRunHookReq.ProtoReflect.Descriptor instead.
-func (*PostRunHookReq) Descriptor() ([]byte, []int) {
+// Deprecated: Use FilterOutputLineReq.ProtoReflect.Descriptor instead.
+func (*FilterOutputLineReq) Descriptor() ([]byte, []int) {
 	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{15}
 }
 
-func (x *PostRunHookReq) GetBrokerId() uint32 {
+func (x *FilterOutputLineReq) GetStream() OutputStream {
 	if x != nil {
-		return x.BrokerId
+		return x.Stream
 	}
-	return 0
+	return OutputStream_STDOUT
 }
 
-func (x *PostRunHookReq) GetIsInteractiveMode() bool {
+func (x *FilterOutputLineReq) GetLine() string {
 	if x != nil {
-		return x.IsInteractiveMode
+		return x.Line
 	}
-	return false
+	return ""
 }
 
-type PostRunHookRes struct {
+type FilterOutputLineRes struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	Lines         []string               `protobuf:"bytes,1,rep,name=lines,proto3" json:"lines,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PostRunHookRes) Reset() {
-	*x = PostRunHookRes{}
+func (x *FilterOutputLineRes) Reset() {
+	*x = FilterOutputLineRes{}
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PostRunHookRes) String() string {
+func (x *FilterOutputLineRes) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PostRunHookRes) ProtoMessage() {}
+func (*FilterOutputLineRes) ProtoMessage() {}
 
-func (x *PostRunHookRes) ProtoReflect() protoreflect.Message {
+func (x *FilterOutputLineRes) ProtoReflect() protoreflect.Message {
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -803,38 +1065,38 @@ func (x *PostRunHookRes) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PostRunHookRes.ProtoReflect.Descriptor instead.
-func (*PostRunHookRes) Descriptor() ([]byte, []int) {
+// Deprecated: Use FilterOutputLineRes.ProtoReflect.Descriptor instead.
+func (*FilterOutputLineRes) Descriptor() ([]byte, []int) {
 	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{16}
 }
 
-type PromptRunReq struct {
+func (x *FilterOutputLineRes) GetLines() []string {
+	if x != nil {
+		return x.Lines
+	}
+	return nil
+}
+
+type FiltersOutputReq struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Label         string                 `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
-	Default       string                 `protobuf:"bytes,2,opt,name=default,proto3" json:"default,omitempty"`
-	AllowEdit     bool                   `protobuf:"varint,3,opt,name=allow_edit,json=allowEdit,proto3" json:"allow_edit,omitempty"`
-	Mask          string                 `protobuf:"bytes,5,opt,name=mask,proto3" json:"mask,omitempty"`
-	HideEntered   bool                   `protobuf:"varint,6,opt,name=hide_entered,json=hideEntered,proto3" json:"hide_entered,omitempty"`
-	IsConfirm     bool                   `protobuf:"varint,8,opt,name=is_confirm,json=isConfirm,proto3" json:"is_confirm,omitempty"`
-	IsVimMode     bool                   `protobuf:"varint,9,opt,name=is_vim_mode,json=isVimMode,proto3" json:"is_vim_mode,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PromptRunReq) Reset() {
-	*x = PromptRunReq{}
+func (x *FiltersOutputReq) Reset() {
+	*x = FiltersOutputReq{}
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PromptRunReq) String() string {
+func (x *FiltersOutputReq) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PromptRunReq) ProtoMessage() {}
+func (*FiltersOutputReq) ProtoMessage() {}
 
-func (x *PromptRunReq) ProtoReflect() protoreflect.Message {
+func (x *FiltersOutputReq) ProtoReflect() protoreflect.Message {
 	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -846,83 +1108,1454 @@ func (x *PromptRunReq) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PromptRunReq.ProtoReflect.Descriptor instead.
-func (*PromptRunReq) Descriptor() ([]byte, []int) {
+// Deprecated: Use FiltersOutputReq.ProtoReflect.Descriptor instead.
+func (*FiltersOutputReq) Descriptor() ([]byte, []int) {
 	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{17}
 }
 
-func (x *PromptRunReq) GetLabel() string {
+type FiltersOutputRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FiltersOutput bool                   `protobuf:"varint,1,opt,name=filters_output,json=filtersOutput,proto3" json:"filters_output,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FiltersOutputRes) Reset() {
+	*x = FiltersOutputRes{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FiltersOutputRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FiltersOutputRes) ProtoMessage() {}
+
+func (x *FiltersOutputRes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[18]
 	if x != nil {
-		return x.Label
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *PromptRunReq) GetDefault() string {
+// Deprecated: Use FiltersOutputRes.ProtoReflect.Descriptor instead.
+func (*FiltersOutputRes) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *FiltersOutputRes) GetFiltersOutput() bool {
 	if x != nil {
-		return x.Default
+		return x.FiltersOutput
 	}
-	return ""
+	return false
+}
+
+type PostBuildHookReq struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	BrokerId          uint32                 `protobuf:"varint,1,opt,name=broker_id,json=brokerId,proto3" json:"broker_id,omitempty"`
+	IsInteractiveMode bool                   `protobuf:"varint,2,opt,name=is_interactive_mode,json=isInteractiveMode,proto3" json:"is_interactive_mode,omitempty"`
+	Invocation        *InvocationContext     `protobuf:"bytes,3,opt,name=invocation,proto3" json:"invocation,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *PostBuildHookReq) Reset() {
+	*x = PostBuildHookReq{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PostBuildHookReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PostBuildHookReq) ProtoMessage() {}
+
+func (x *PostBuildHookReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PostBuildHookReq.ProtoReflect.Descriptor instead.
+func (*PostBuildHookReq) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *PostBuildHookReq) GetBrokerId() uint32 {
+	if x != nil {
+		return x.BrokerId
+	}
+	return 0
+}
+
+func (x *PostBuildHookReq) GetIsInteractiveMode() bool {
+	if x != nil {
+		return x.IsInteractiveMode
+	}
+	return false
+}
+
+func (x *PostBuildHookReq) GetInvocation() *InvocationContext {
+	if x != nil {
+		return x.Invocation
+	}
+	return nil
+}
+
+type PostBuildHookRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Result        *HookResult            `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PostBuildHookRes) Reset() {
+	*x = PostBuildHookRes{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PostBuildHookRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PostBuildHookRes) ProtoMessage() {}
+
+func (x *PostBuildHookRes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PostBuildHookRes.ProtoReflect.Descriptor instead.
+func (*PostBuildHookRes) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *PostBuildHookRes) GetResult() *HookResult {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+type HookResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Outcome       HookResult_Outcome     `protobuf:"varint,1,opt,name=outcome,proto3,enum=proto.HookResult_Outcome" json:"outcome,omitempty"`
+	ExitCode      int32                  `protobuf:"varint,2,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HookResult) Reset() {
+	*x = HookResult{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HookResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HookResult) ProtoMessage() {}
+
+func (x *HookResult) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HookResult.ProtoReflect.Descriptor instead.
+func (*HookResult) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *HookResult) GetOutcome() HookResult_Outcome {
+	if x != nil {
+		return x.Outcome
+	}
+	return HookResult_UNSPECIFIED
+}
+
+func (x *HookResult) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *HookResult) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type InvocationContext struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Command           string                 `protobuf:"bytes,1,opt,name=command,proto3" json:"command,omitempty"`
+	TargetPatterns    []string               `protobuf:"bytes,2,rep,name=target_patterns,json=targetPatterns,proto3" json:"target_patterns,omitempty"`
+	StartupArgs       []string               `protobuf:"bytes,3,rep,name=startup_args,json=startupArgs,proto3" json:"startup_args,omitempty"`
+	Flags             []string               `protobuf:"bytes,4,rep,name=flags,proto3" json:"flags,omitempty"`
+	WorkspaceRoot     string                 `protobuf:"bytes,5,opt,name=workspace_root,json=workspaceRoot,proto3" json:"workspace_root,omitempty"`
+	IsInteractiveMode bool                   `protobuf:"varint,6,opt,name=is_interactive_mode,json=isInteractiveMode,proto3" json:"is_interactive_mode,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *InvocationContext) Reset() {
+	*x = InvocationContext{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InvocationContext) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InvocationContext) ProtoMessage() {}
+
+func (x *InvocationContext) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InvocationContext.ProtoReflect.Descriptor instead.
+func (*InvocationContext) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *InvocationContext) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+func (x *InvocationContext) GetTargetPatterns() []string {
+	if x != nil {
+		return x.TargetPatterns
+	}
+	return nil
+}
+
+func (x *InvocationContext) GetStartupArgs() []string {
+	if x != nil {
+		return x.StartupArgs
+	}
+	return nil
+}
+
+func (x *InvocationContext) GetFlags() []string {
+	if x != nil {
+		return x.Flags
+	}
+	return nil
+}
+
+func (x *InvocationContext) GetWorkspaceRoot() string {
+	if x != nil {
+		return x.WorkspaceRoot
+	}
+	return ""
+}
+
+func (x *InvocationContext) GetIsInteractiveMode() bool {
+	if x != nil {
+		return x.IsInteractiveMode
+	}
+	return false
+}
+
+type Command struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Use           string                 `protobuf:"bytes,1,opt,name=use,proto3" json:"use,omitempty"`
+	ShortDesc     string                 `protobuf:"bytes,2,opt,name=short_desc,json=shortDesc,proto3" json:"short_desc,omitempty"`
+	LongDesc      string                 `protobuf:"bytes,3,opt,name=long_desc,json=longDesc,proto3" json:"long_desc,omitempty"`
+	Flags         []*Flag                `protobuf:"bytes,4,rep,name=flags,proto3" json:"flags,omitempty"`
+	Subcommands   []*Command             `protobuf:"bytes,5,rep,name=subcommands,proto3" json:"subcommands,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Command) Reset() {
+	*x = Command{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Command) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Command) ProtoMessage() {}
+
+func (x *Command) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Command.ProtoReflect.Descriptor instead.
+func (*Command) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *Command) GetUse() string {
+	if x != nil {
+		return x.Use
+	}
+	return ""
+}
+
+func (x *Command) GetShortDesc() string {
+	if x != nil {
+		return x.ShortDesc
+	}
+	return ""
+}
+
+func (x *Command) GetLongDesc() string {
+	if x != nil {
+		return x.LongDesc
+	}
+	return ""
+}
+
+func (x *Command) GetFlags() []*Flag {
+	if x != nil {
+		return x.Flags
+	}
+	return nil
+}
+
+func (x *Command) GetSubcommands() []*Command {
+	if x != nil {
+		return x.Subcommands
+	}
+	return nil
+}
+
+type Flag struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Shorthand     string                 `protobuf:"bytes,2,opt,name=shorthand,proto3" json:"shorthand,omitempty"`
+	Usage         string                 `protobuf:"bytes,3,opt,name=usage,proto3" json:"usage,omitempty"`
+	Type          Flag_Type              `protobuf:"varint,4,opt,name=type,proto3,enum=proto.Flag_Type" json:"type,omitempty"`
+	DefaultValue  string                 `protobuf:"bytes,5,opt,name=default_value,json=defaultValue,proto3" json:"default_value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Flag) Reset() {
+	*x = Flag{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Flag) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Flag) ProtoMessage() {}
+
+func (x *Flag) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Flag.ProtoReflect.Descriptor instead.
+func (*Flag) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *Flag) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Flag) GetShorthand() string {
+	if x != nil {
+		return x.Shorthand
+	}
+	return ""
+}
+
+func (x *Flag) GetUsage() string {
+	if x != nil {
+		return x.Usage
+	}
+	return ""
+}
+
+func (x *Flag) GetType() Flag_Type {
+	if x != nil {
+		return x.Type
+	}
+	return Flag_STRING
+}
+
+func (x *Flag) GetDefaultValue() string {
+	if x != nil {
+		return x.DefaultValue
+	}
+	return ""
+}
+
+type CustomCommandsReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CustomCommandsReq) Reset() {
+	*x = CustomCommandsReq{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CustomCommandsReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CustomCommandsReq) ProtoMessage() {}
+
+func (x *CustomCommandsReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CustomCommandsReq.ProtoReflect.Descriptor instead.
+func (*CustomCommandsReq) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{25}
+}
+
+type CustomCommandsRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Commands      []*Command             `protobuf:"bytes,1,rep,name=commands,proto3" json:"commands,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CustomCommandsRes) Reset() {
+	*x = CustomCommandsRes{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CustomCommandsRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CustomCommandsRes) ProtoMessage() {}
+
+func (x *CustomCommandsRes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CustomCommandsRes.ProtoReflect.Descriptor instead.
+func (*CustomCommandsRes) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *CustomCommandsRes) GetCommands() []*Command {
+	if x != nil {
+		return x.Commands
+	}
+	return nil
+}
+
+type Context struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WorkspaceRoot string                 `protobuf:"bytes,1,opt,name=workspaceRoot,proto3" json:"workspaceRoot,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Context) Reset() {
+	*x = Context{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Context) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Context) ProtoMessage() {}
+
+func (x *Context) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Context.ProtoReflect.Descriptor instead.
+func (*Context) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *Context) GetWorkspaceRoot() string {
+	if x != nil {
+		return x.WorkspaceRoot
+	}
+	return ""
+}
+
+type ExecuteCustomCommandReq struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	CustomCommand    string                 `protobuf:"bytes,1,opt,name=customCommand,proto3" json:"customCommand,omitempty"`
+	Ctx              *Context               `protobuf:"bytes,2,opt,name=ctx,proto3" json:"ctx,omitempty"`
+	Args             []string               `protobuf:"bytes,3,rep,name=args,proto3" json:"args,omitempty"`
+	BazelStartupArgs []string               `protobuf:"bytes,4,rep,name=bazelStartupArgs,proto3" json:"bazelStartupArgs,omitempty"`
+	Flags            map[string]string      `protobuf:"bytes,5,rep,name=flags,proto3" json:"flags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *ExecuteCustomCommandReq) Reset() {
+	*x = ExecuteCustomCommandReq{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecuteCustomCommandReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecuteCustomCommandReq) ProtoMessage() {}
+
+func (x *ExecuteCustomCommandReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecuteCustomCommandReq.ProtoReflect.Descriptor instead.
+func (*ExecuteCustomCommandReq) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *ExecuteCustomCommandReq) GetCustomCommand() string {
+	if x != nil {
+		return x.CustomCommand
+	}
+	return ""
+}
+
+func (x *ExecuteCustomCommandReq) GetCtx() *Context {
+	if x != nil {
+		return x.Ctx
+	}
+	return nil
+}
+
+func (x *ExecuteCustomCommandReq) GetArgs() []string {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+func (x *ExecuteCustomCommandReq) GetBazelStartupArgs() []string {
+	if x != nil {
+		return x.BazelStartupArgs
+	}
+	return nil
+}
+
+func (x *ExecuteCustomCommandReq) GetFlags() map[string]string {
+	if x != nil {
+		return x.Flags
+	}
+	return nil
+}
+
+type ExecuteCustomCommandRes struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	DelegateToBuiltin bool                   `protobuf:"varint,1,opt,name=delegate_to_builtin,json=delegateToBuiltin,proto3" json:"delegate_to_builtin,omitempty"`
+	BuiltinArgs       []string               `protobuf:"bytes,2,rep,name=builtin_args,json=builtinArgs,proto3" json:"builtin_args,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *ExecuteCustomCommandRes) Reset() {
+	*x = ExecuteCustomCommandRes{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecuteCustomCommandRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecuteCustomCommandRes) ProtoMessage() {}
+
+func (x *ExecuteCustomCommandRes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecuteCustomCommandRes.ProtoReflect.Descriptor instead.
+func (*ExecuteCustomCommandRes) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *ExecuteCustomCommandRes) GetDelegateToBuiltin() bool {
+	if x != nil {
+		return x.DelegateToBuiltin
+	}
+	return false
+}
+
+func (x *ExecuteCustomCommandRes) GetBuiltinArgs() []string {
+	if x != nil {
+		return x.BuiltinArgs
+	}
+	return nil
+}
+
+type PostTestHookReq struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	BrokerId          uint32                 `protobuf:"varint,1,opt,name=broker_id,json=brokerId,proto3" json:"broker_id,omitempty"`
+	IsInteractiveMode bool                   `protobuf:"varint,2,opt,name=is_interactive_mode,json=isInteractiveMode,proto3" json:"is_interactive_mode,omitempty"`
+	Invocation        *InvocationContext     `protobuf:"bytes,3,opt,name=invocation,proto3" json:"invocation,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *PostTestHookReq) Reset() {
+	*x = PostTestHookReq{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PostTestHookReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PostTestHookReq) ProtoMessage() {}
+
+func (x *PostTestHookReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PostTestHookReq.ProtoReflect.Descriptor instead.
+func (*PostTestHookReq) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *PostTestHookReq) GetBrokerId() uint32 {
+	if x != nil {
+		return x.BrokerId
+	}
+	return 0
+}
+
+func (x *PostTestHookReq) GetIsInteractiveMode() bool {
+	if x != nil {
+		return x.IsInteractiveMode
+	}
+	return false
+}
+
+func (x *PostTestHookReq) GetInvocation() *InvocationContext {
+	if x != nil {
+		return x.Invocation
+	}
+	return nil
+}
+
+type PostTestHookRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Result        *HookResult            `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PostTestHookRes) Reset() {
+	*x = PostTestHookRes{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PostTestHookRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PostTestHookRes) ProtoMessage() {}
+
+func (x *PostTestHookRes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PostTestHookRes.ProtoReflect.Descriptor instead.
+func (*PostTestHookRes) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *PostTestHookRes) GetResult() *HookResult {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+type PostRunHookReq struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	BrokerId          uint32                 `protobuf:"varint,1,opt,name=broker_id,json=brokerId,proto3" json:"broker_id,omitempty"`
+	IsInteractiveMode bool                   `protobuf:"varint,2,opt,name=is_interactive_mode,json=isInteractiveMode,proto3" json:"is_interactive_mode,omitempty"`
+	Invocation        *InvocationContext     `protobuf:"bytes,3,opt,name=invocation,proto3" json:"invocation,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *PostRunHookReq) Reset() {
+	*x = PostRunHookReq{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PostRunHookReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PostRunHookReq) ProtoMessage() {}
+
+func (x *PostRunHookReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PostRunHookReq.ProtoReflect.Descriptor instead.
+func (*PostRunHookReq) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *PostRunHookReq) GetBrokerId() uint32 {
+	if x != nil {
+		return x.BrokerId
+	}
+	return 0
+}
+
+func (x *PostRunHookReq) GetIsInteractiveMode() bool {
+	if x != nil {
+		return x.IsInteractiveMode
+	}
+	return false
+}
+
+func (x *PostRunHookReq) GetInvocation() *InvocationContext {
+	if x != nil {
+		return x.Invocation
+	}
+	return nil
+}
+
+type PostRunHookRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Result        *HookResult            `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PostRunHookRes) Reset() {
+	*x = PostRunHookRes{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PostRunHookRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PostRunHookRes) ProtoMessage() {}
+
+func (x *PostRunHookRes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PostRunHookRes.ProtoReflect.Descriptor instead.
+func (*PostRunHookRes) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *PostRunHookRes) GetResult() *HookResult {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+type RewriteArgsReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Command       string                 `protobuf:"bytes,1,opt,name=command,proto3" json:"command,omitempty"`
+	Args          []string               `protobuf:"bytes,2,rep,name=args,proto3" json:"args,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RewriteArgsReq) Reset() {
+	*x = RewriteArgsReq{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RewriteArgsReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RewriteArgsReq) ProtoMessage() {}
+
+func (x *RewriteArgsReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RewriteArgsReq.ProtoReflect.Descriptor instead.
+func (*RewriteArgsReq) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *RewriteArgsReq) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+func (x *RewriteArgsReq) GetArgs() []string {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+type RewriteArgsRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Args          []string               `protobuf:"bytes,1,rep,name=args,proto3" json:"args,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RewriteArgsRes) Reset() {
+	*x = RewriteArgsRes{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RewriteArgsRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RewriteArgsRes) ProtoMessage() {}
+
+func (x *RewriteArgsRes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RewriteArgsRes.ProtoReflect.Descriptor instead.
+func (*RewriteArgsRes) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *RewriteArgsRes) GetArgs() []string {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+type PromptRunReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Label         string                 `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	Default       string                 `protobuf:"bytes,2,opt,name=default,proto3" json:"default,omitempty"`
+	AllowEdit     bool                   `protobuf:"varint,3,opt,name=allow_edit,json=allowEdit,proto3" json:"allow_edit,omitempty"`
+	Mask          string                 `protobuf:"bytes,5,opt,name=mask,proto3" json:"mask,omitempty"`
+	HideEntered   bool                   `protobuf:"varint,6,opt,name=hide_entered,json=hideEntered,proto3" json:"hide_entered,omitempty"`
+	IsConfirm     bool                   `protobuf:"varint,8,opt,name=is_confirm,json=isConfirm,proto3" json:"is_confirm,omitempty"`
+	IsVimMode     bool                   `protobuf:"varint,9,opt,name=is_vim_mode,json=isVimMode,proto3" json:"is_vim_mode,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PromptRunReq) Reset() {
+	*x = PromptRunReq{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PromptRunReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PromptRunReq) ProtoMessage() {}
+
+func (x *PromptRunReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PromptRunReq.ProtoReflect.Descriptor instead.
+func (*PromptRunReq) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *PromptRunReq) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *PromptRunReq) GetDefault() string {
+	if x != nil {
+		return x.Default
+	}
+	return ""
+}
+
+func (x *PromptRunReq) GetAllowEdit() bool {
+	if x != nil {
+		return x.AllowEdit
+	}
+	return false
+}
+
+func (x *PromptRunReq) GetMask() string {
+	if x != nil {
+		return x.Mask
+	}
+	return ""
+}
+
+func (x *PromptRunReq) GetHideEntered() bool {
+	if x != nil {
+		return x.HideEntered
+	}
+	return false
+}
+
+func (x *PromptRunReq) GetIsConfirm() bool {
+	if x != nil {
+		return x.IsConfirm
+	}
+	return false
+}
+
+func (x *PromptRunReq) GetIsVimMode() bool {
+	if x != nil {
+		return x.IsVimMode
+	}
+	return false
+}
+
+type PromptRunRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Result        string                 `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	Error         *PromptRunRes_Error    `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PromptRunRes) Reset() {
+	*x = PromptRunRes{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PromptRunRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PromptRunRes) ProtoMessage() {}
+
+func (x *PromptRunRes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PromptRunRes.ProtoReflect.Descriptor instead.
+func (*PromptRunRes) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *PromptRunRes) GetResult() string {
+	if x != nil {
+		return x.Result
+	}
+	return ""
+}
+
+func (x *PromptRunRes) GetError() *PromptRunRes_Error {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type PromptSelectReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Label         string                 `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	Items         []string               `protobuf:"bytes,2,rep,name=items,proto3" json:"items,omitempty"`
+	DefaultIndex  int32                  `protobuf:"varint,3,opt,name=default_index,json=defaultIndex,proto3" json:"default_index,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PromptSelectReq) Reset() {
+	*x = PromptSelectReq{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PromptSelectReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PromptSelectReq) ProtoMessage() {}
+
+func (x *PromptSelectReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PromptSelectReq.ProtoReflect.Descriptor instead.
+func (*PromptSelectReq) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *PromptSelectReq) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *PromptSelectReq) GetItems() []string {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *PromptSelectReq) GetDefaultIndex() int32 {
+	if x != nil {
+		return x.DefaultIndex
+	}
+	return 0
+}
+
+type PromptSelectRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Error         *PromptRunRes_Error    `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PromptSelectRes) Reset() {
+	*x = PromptSelectRes{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PromptSelectRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PromptSelectRes) ProtoMessage() {}
+
+func (x *PromptSelectRes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PromptSelectRes.ProtoReflect.Descriptor instead.
+func (*PromptSelectRes) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *PromptSelectRes) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *PromptSelectRes) GetError() *PromptRunRes_Error {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type PromptMultiSelectReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Label         string                 `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	Items         []string               `protobuf:"bytes,2,rep,name=items,proto3" json:"items,omitempty"`
+	Selected      []int32                `protobuf:"varint,3,rep,packed,name=selected,proto3" json:"selected,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PromptMultiSelectReq) Reset() {
+	*x = PromptMultiSelectReq{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PromptMultiSelectReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PromptMultiSelectReq) ProtoMessage() {}
+
+func (x *PromptMultiSelectReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PromptMultiSelectReq.ProtoReflect.Descriptor instead.
+func (*PromptMultiSelectReq) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *PromptMultiSelectReq) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *PromptMultiSelectReq) GetItems() []string {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *PromptMultiSelectReq) GetSelected() []int32 {
+	if x != nil {
+		return x.Selected
+	}
+	return nil
+}
+
+type PromptMultiSelectRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Selected      []int32                `protobuf:"varint,1,rep,packed,name=selected,proto3" json:"selected,omitempty"`
+	Error         *PromptRunRes_Error    `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PromptMultiSelectRes) Reset() {
+	*x = PromptMultiSelectRes{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PromptMultiSelectRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PromptMultiSelectRes) ProtoMessage() {}
+
+func (x *PromptMultiSelectRes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PromptMultiSelectRes.ProtoReflect.Descriptor instead.
+func (*PromptMultiSelectRes) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *PromptMultiSelectRes) GetSelected() []int32 {
+	if x != nil {
+		return x.Selected
+	}
+	return nil
+}
+
+func (x *PromptMultiSelectRes) GetError() *PromptRunRes_Error {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type PromptConfirmReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Label         string                 `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	DefaultYes    bool                   `protobuf:"varint,2,opt,name=default_yes,json=defaultYes,proto3" json:"default_yes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PromptConfirmReq) Reset() {
+	*x = PromptConfirmReq{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PromptConfirmReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func (x *PromptRunReq) GetAllowEdit() bool {
-	if x != nil {
-		return x.AllowEdit
-	}
-	return false
-}
+func (*PromptConfirmReq) ProtoMessage() {}
 
-func (x *PromptRunReq) GetMask() string {
+func (x *PromptConfirmReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[42]
 	if x != nil {
-		return x.Mask
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *PromptRunReq) GetHideEntered() bool {
-	if x != nil {
-		return x.HideEntered
-	}
-	return false
+// Deprecated: Use PromptConfirmReq.ProtoReflect.Descriptor instead.
+func (*PromptConfirmReq) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{42}
 }
 
-func (x *PromptRunReq) GetIsConfirm() bool {
+func (x *PromptConfirmReq) GetLabel() string {
 	if x != nil {
-		return x.IsConfirm
+		return x.Label
 	}
-	return false
+	return ""
 }
 
-func (x *PromptRunReq) GetIsVimMode() bool {
+func (x *PromptConfirmReq) GetDefaultYes() bool {
 	if x != nil {
-		return x.IsVimMode
+		return x.DefaultYes
 	}
 	return false
 }
 
-type PromptRunRes struct {
+type PromptConfirmRes struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Result        string                 `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	Confirmed     bool                   `protobuf:"varint,1,opt,name=confirmed,proto3" json:"confirmed,omitempty"`
 	Error         *PromptRunRes_Error    `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PromptRunRes) Reset() {
-	*x = PromptRunRes{}
-	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[18]
+func (x *PromptConfirmRes) Reset() {
+	*x = PromptConfirmRes{}
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[43]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PromptRunRes) String() string {
+func (x *PromptConfirmRes) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PromptRunRes) ProtoMessage() {}
+func (*PromptConfirmRes) ProtoMessage() {}
 
-func (x *PromptRunRes) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[18]
+func (x *PromptConfirmRes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[43]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -933,19 +2566,19 @@ func (x *PromptRunRes) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PromptRunRes.ProtoReflect.Descriptor instead.
-func (*PromptRunRes) Descriptor() ([]byte, []int) {
-	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{18}
+// Deprecated: Use PromptConfirmRes.ProtoReflect.Descriptor instead.
+func (*PromptConfirmRes) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{43}
 }
 
-func (x *PromptRunRes) GetResult() string {
+func (x *PromptConfirmRes) GetConfirmed() bool {
 	if x != nil {
-		return x.Result
+		return x.Confirmed
 	}
-	return ""
+	return false
 }
 
-func (x *PromptRunRes) GetError() *PromptRunRes_Error {
+func (x *PromptConfirmRes) GetError() *PromptRunRes_Error {
 	if x != nil {
 		return x.Error
 	}
@@ -962,7 +2595,7 @@ type PromptRunRes_Error struct {
 
 func (x *PromptRunRes_Error) Reset() {
 	*x = PromptRunRes_Error{}
-	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[19]
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[46]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -974,7 +2607,7 @@ func (x *PromptRunRes_Error) String() string {
 func (*PromptRunRes_Error) ProtoMessage() {}
 
 func (x *PromptRunRes_Error) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[19]
+	mi := &file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes[46]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -987,7 +2620,7 @@ func (x *PromptRunRes_Error) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PromptRunRes_Error.ProtoReflect.Descriptor instead.
 func (*PromptRunRes_Error) Descriptor() ([]byte, []int) {
-	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{18, 0}
+	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP(), []int{37, 0}
 }
 
 func (x *PromptRunRes_Error) GetHappened() bool {
@@ -1008,49 +2641,159 @@ var File_pkg_plugin_sdk_v1alpha4_proto_plugin_proto protoreflect.FileDescriptor
 
 const file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDesc = "" +
 	"\n" +
-	"*pkg/plugin/sdk/v1alpha4/proto/plugin.proto\x12\x05proto\x1a/bazel/buildeventstream/build_event_stream.proto\"\x99\x01\n" +
+	"*pkg/plugin/sdk/v1alpha4/proto/plugin.proto\x12\x05proto\x1a/bazel/buildeventstream/build_event_stream.proto\"\xc3\x01\n" +
 	"\x13BEPEventCallbackReq\x124\n" +
 	"\x05event\x18\x01 \x01(\v2\x1e.build_event_stream.BuildEventR\x05event\x12'\n" +
 	"\x0fsequence_number\x18\x02 \x01(\x03R\x0esequenceNumber\x12#\n" +
-	"\rinvocation_id\x18\x03 \x01(\tR\finvocationId\"\x15\n" +
-	"\x13BEPEventCallbackRes\"O\n" +
+	"\rinvocation_id\x18\x03 \x01(\tR\finvocationId\x12(\n" +
+	"\x06stream\x18\x04 \x01(\v2\x10.proto.BEPStreamR\x06stream\"e\n" +
+	"\tBEPStream\x12\x19\n" +
+	"\bbuild_id\x18\x01 \x01(\tR\abuildId\x12#\n" +
+	"\rinvocation_id\x18\x02 \x01(\tR\finvocationId\x12\x18\n" +
+	"\acommand\x18\x03 \x01(\tR\acommand\"\x15\n" +
+	"\x13BEPEventCallbackRes\"\x12\n" +
+	"\x10BEPEventTypesReq\"3\n" +
+	"\x10BEPEventTypesRes\x12\x1f\n" +
+	"\vevent_types\x18\x01 \x03(\tR\n" +
+	"eventTypes\"\f\n" +
+	"\n" +
+	"DaemonsReq\"5\n" +
+	"\n" +
+	"DaemonsRes\x12'\n" +
+	"\adaemons\x18\x01 \x03(\v2\r.proto.DaemonR\adaemons\"\x92\x01\n" +
+	"\x06Daemon\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04args\x18\x02 \x03(\tR\x04args\x12(\n" +
+	"\x03env\x18\x03 \x03(\v2\x16.proto.Daemon.EnvEntryR\x03env\x1a6\n" +
+	"\bEnvEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x15\n" +
+	"\x13PropertiesSchemaReq\"F\n" +
+	"\x13PropertiesSchemaRes\x12/\n" +
+	"\n" +
+	"properties\x18\x01 \x03(\v2\x0f.proto.PropertyR\n" +
+	"properties\"\xcb\x01\n" +
+	"\bProperty\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12(\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x14.proto.Property.TypeR\x04type\x12\x1a\n" +
+	"\brequired\x18\x03 \x01(\bR\brequired\x12 \n" +
+	"\vdescription\x18\x04 \x01(\tR\vdescription\"C\n" +
+	"\x04Type\x12\n" +
+	"\n" +
+	"\x06STRING\x10\x00\x12\b\n" +
+	"\x04BOOL\x10\x01\x12\a\n" +
+	"\x03INT\x10\x02\x12\t\n" +
+	"\x05FLOAT\x10\x03\x12\b\n" +
+	"\x04LIST\x10\x04\x12\a\n" +
+	"\x03MAP\x10\x05\"\x7f\n" +
 	"\bSetupReq\x12\x1e\n" +
 	"\n" +
 	"properties\x18\x01 \x01(\fR\n" +
 	"properties\x12#\n" +
-	"\x04file\x18\x02 \x01(\v2\v.proto.FileB\x02\x18\x01R\x04file\"\x1a\n" +
+	"\x04file\x18\x02 \x01(\v2\v.proto.FileB\x02\x18\x01R\x04file\x12.\n" +
+	"\tworkspace\x18\x03 \x01(\v2\x10.proto.WorkspaceR\tworkspace\"\xad\x01\n" +
+	"\tWorkspace\x12\x12\n" +
+	"\x04root\x18\x01 \x01(\tR\x04root\x12\x1f\n" +
+	"\voutput_base\x18\x02 \x01(\tR\n" +
+	"outputBase\x12%\n" +
+	"\x0eexecution_root\x18\x03 \x01(\tR\rexecutionRoot\x12#\n" +
+	"\rbazel_version\x18\x04 \x01(\tR\fbazelVersion\x12\x1f\n" +
+	"\vcli_version\x18\x05 \x01(\tR\n" +
+	"cliVersion\"\x1a\n" +
 	"\x04File\x12\x12\n" +
 	"\x04path\x18\x01 \x01(\tR\x04path\"\n" +
 	"\n" +
-	"\bSetupRes\"_\n" +
+	"\bSetupRes\"V\n" +
+	"\x13FilterOutputLineReq\x12+\n" +
+	"\x06stream\x18\x01 \x01(\x0e2\x13.proto.OutputStreamR\x06stream\x12\x12\n" +
+	"\x04line\x18\x02 \x01(\tR\x04line\"+\n" +
+	"\x13FilterOutputLineRes\x12\x14\n" +
+	"\x05lines\x18\x01 \x03(\tR\x05lines\"\x12\n" +
+	"\x10FiltersOutputReq\"9\n" +
+	"\x10FiltersOutputRes\x12%\n" +
+	"\x0efilters_output\x18\x01 \x01(\bR\rfiltersOutput\"\x99\x01\n" +
 	"\x10PostBuildHookReq\x12\x1b\n" +
 	"\tbroker_id\x18\x01 \x01(\rR\bbrokerId\x12.\n" +
-	"\x13is_interactive_mode\x18\x02 \x01(\bR\x11isInteractiveMode\"\x12\n" +
-	"\x10PostBuildHookRes\"W\n" +
+	"\x13is_interactive_mode\x18\x02 \x01(\bR\x11isInteractiveMode\x128\n" +
+	"\n" +
+	"invocation\x18\x03 \x01(\v2\x18.proto.InvocationContextR\n" +
+	"invocation\"=\n" +
+	"\x10PostBuildHookRes\x12)\n" +
+	"\x06result\x18\x01 \x01(\v2\x11.proto.HookResultR\x06result\"\xb7\x01\n" +
+	"\n" +
+	"HookResult\x123\n" +
+	"\aoutcome\x18\x01 \x01(\x0e2\x19.proto.HookResult.OutcomeR\aoutcome\x12\x1b\n" +
+	"\texit_code\x18\x02 \x01(\x05R\bexitCode\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\"=\n" +
+	"\aOutcome\x12\x0f\n" +
+	"\vUNSPECIFIED\x10\x00\x12\b\n" +
+	"\x04FAIL\x10\x01\x12\r\n" +
+	"\tEXIT_CODE\x10\x02\x12\b\n" +
+	"\x04WARN\x10\x03\"\xe6\x01\n" +
+	"\x11InvocationContext\x12\x18\n" +
+	"\acommand\x18\x01 \x01(\tR\acommand\x12'\n" +
+	"\x0ftarget_patterns\x18\x02 \x03(\tR\x0etargetPatterns\x12!\n" +
+	"\fstartup_args\x18\x03 \x03(\tR\vstartupArgs\x12\x14\n" +
+	"\x05flags\x18\x04 \x03(\tR\x05flags\x12%\n" +
+	"\x0eworkspace_root\x18\x05 \x01(\tR\rworkspaceRoot\x12.\n" +
+	"\x13is_interactive_mode\x18\x06 \x01(\bR\x11isInteractiveMode\"\xac\x01\n" +
 	"\aCommand\x12\x10\n" +
 	"\x03use\x18\x01 \x01(\tR\x03use\x12\x1d\n" +
 	"\n" +
 	"short_desc\x18\x02 \x01(\tR\tshortDesc\x12\x1b\n" +
-	"\tlong_desc\x18\x03 \x01(\tR\blongDesc\"\x13\n" +
+	"\tlong_desc\x18\x03 \x01(\tR\blongDesc\x12!\n" +
+	"\x05flags\x18\x04 \x03(\v2\v.proto.FlagR\x05flags\x120\n" +
+	"\vsubcommands\x18\x05 \x03(\v2\x0e.proto.CommandR\vsubcommands\"\xc0\x01\n" +
+	"\x04Flag\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1c\n" +
+	"\tshorthand\x18\x02 \x01(\tR\tshorthand\x12\x14\n" +
+	"\x05usage\x18\x03 \x01(\tR\x05usage\x12$\n" +
+	"\x04type\x18\x04 \x01(\x0e2\x10.proto.Flag.TypeR\x04type\x12#\n" +
+	"\rdefault_value\x18\x05 \x01(\tR\fdefaultValue\"%\n" +
+	"\x04Type\x12\n" +
+	"\n" +
+	"\x06STRING\x10\x00\x12\b\n" +
+	"\x04BOOL\x10\x01\x12\a\n" +
+	"\x03INT\x10\x02\"\x13\n" +
 	"\x11CustomCommandsReq\"?\n" +
 	"\x11CustomCommandsRes\x12*\n" +
 	"\bcommands\x18\x01 \x03(\v2\x0e.proto.CommandR\bcommands\"/\n" +
 	"\aContext\x12$\n" +
-	"\rworkspaceRoot\x18\x01 \x01(\tR\rworkspaceRoot\"\xa1\x01\n" +
+	"\rworkspaceRoot\x18\x01 \x01(\tR\rworkspaceRoot\"\x9c\x02\n" +
 	"\x17ExecuteCustomCommandReq\x12$\n" +
 	"\rcustomCommand\x18\x01 \x01(\tR\rcustomCommand\x12 \n" +
 	"\x03ctx\x18\x02 \x01(\v2\x0e.proto.ContextR\x03ctx\x12\x12\n" +
 	"\x04args\x18\x03 \x03(\tR\x04args\x12*\n" +
-	"\x10bazelStartupArgs\x18\x04 \x03(\tR\x10bazelStartupArgs\"\x19\n" +
-	"\x17ExecuteCustomCommandRes\"^\n" +
+	"\x10bazelStartupArgs\x18\x04 \x03(\tR\x10bazelStartupArgs\x12?\n" +
+	"\x05flags\x18\x05 \x03(\v2).proto.ExecuteCustomCommandReq.FlagsEntryR\x05flags\x1a8\n" +
+	"\n" +
+	"FlagsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"l\n" +
+	"\x17ExecuteCustomCommandRes\x12.\n" +
+	"\x13delegate_to_builtin\x18\x01 \x01(\bR\x11delegateToBuiltin\x12!\n" +
+	"\fbuiltin_args\x18\x02 \x03(\tR\vbuiltinArgs\"\x98\x01\n" +
 	"\x0fPostTestHookReq\x12\x1b\n" +
 	"\tbroker_id\x18\x01 \x01(\rR\bbrokerId\x12.\n" +
-	"\x13is_interactive_mode\x18\x02 \x01(\bR\x11isInteractiveMode\"\x11\n" +
-	"\x0fPostTestHookRes\"]\n" +
+	"\x13is_interactive_mode\x18\x02 \x01(\bR\x11isInteractiveMode\x128\n" +
+	"\n" +
+	"invocation\x18\x03 \x01(\v2\x18.proto.InvocationContextR\n" +
+	"invocation\"<\n" +
+	"\x0fPostTestHookRes\x12)\n" +
+	"\x06result\x18\x01 \x01(\v2\x11.proto.HookResultR\x06result\"\x97\x01\n" +
 	"\x0ePostRunHookReq\x12\x1b\n" +
 	"\tbroker_id\x18\x01 \x01(\rR\bbrokerId\x12.\n" +
-	"\x13is_interactive_mode\x18\x02 \x01(\bR\x11isInteractiveMode\"\x10\n" +
-	"\x0ePostRunHookRes\"\xd3\x01\n" +
+	"\x13is_interactive_mode\x18\x02 \x01(\bR\x11isInteractiveMode\x128\n" +
+	"\n" +
+	"invocation\x18\x03 \x01(\v2\x18.proto.InvocationContextR\n" +
+	"invocation\";\n" +
+	"\x0ePostRunHookRes\x12)\n" +
+	"\x06result\x18\x01 \x01(\v2\x11.proto.HookResultR\x06result\">\n" +
+	"\x0eRewriteArgsReq\x12\x18\n" +
+	"\acommand\x18\x01 \x01(\tR\acommand\x12\x12\n" +
+	"\x04args\x18\x02 \x03(\tR\x04args\"$\n" +
+	"\x0eRewriteArgsRes\x12\x12\n" +
+	"\x04args\x18\x01 \x03(\tR\x04args\"\xd3\x01\n" +
 	"\fPromptRunReq\x12\x14\n" +
 	"\x05label\x18\x01 \x01(\tR\x05label\x12\x18\n" +
 	"\adefault\x18\x02 \x01(\tR\adefault\x12\x1d\n" +
@@ -1066,17 +2809,52 @@ const file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDesc = "" +
 	"\x05error\x18\x02 \x01(\v2\x19.proto.PromptRunRes.ErrorR\x05error\x1a=\n" +
 	"\x05Error\x12\x1a\n" +
 	"\bhappened\x18\x01 \x01(\bR\bhappened\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage2\xdd\x03\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"b\n" +
+	"\x0fPromptSelectReq\x12\x14\n" +
+	"\x05label\x18\x01 \x01(\tR\x05label\x12\x14\n" +
+	"\x05items\x18\x02 \x03(\tR\x05items\x12#\n" +
+	"\rdefault_index\x18\x03 \x01(\x05R\fdefaultIndex\"X\n" +
+	"\x0fPromptSelectRes\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12/\n" +
+	"\x05error\x18\x02 \x01(\v2\x19.proto.PromptRunRes.ErrorR\x05error\"^\n" +
+	"\x14PromptMultiSelectReq\x12\x14\n" +
+	"\x05label\x18\x01 \x01(\tR\x05label\x12\x14\n" +
+	"\x05items\x18\x02 \x03(\tR\x05items\x12\x1a\n" +
+	"\bselected\x18\x03 \x03(\x05R\bselected\"c\n" +
+	"\x14PromptMultiSelectRes\x12\x1a\n" +
+	"\bselected\x18\x01 \x03(\x05R\bselected\x12/\n" +
+	"\x05error\x18\x02 \x01(\v2\x19.proto.PromptRunRes.ErrorR\x05error\"I\n" +
+	"\x10PromptConfirmReq\x12\x14\n" +
+	"\x05label\x18\x01 \x01(\tR\x05label\x12\x1f\n" +
+	"\vdefault_yes\x18\x02 \x01(\bR\n" +
+	"defaultYes\"a\n" +
+	"\x10PromptConfirmRes\x12\x1c\n" +
+	"\tconfirmed\x18\x01 \x01(\bR\tconfirmed\x12/\n" +
+	"\x05error\x18\x02 \x01(\v2\x19.proto.PromptRunRes.ErrorR\x05error*&\n" +
+	"\fOutputStream\x12\n" +
+	"\n" +
+	"\x06STDOUT\x10\x00\x12\n" +
+	"\n" +
+	"\x06STDERR\x10\x012\xe9\x06\n" +
 	"\x06Plugin\x12J\n" +
-	"\x10BEPEventCallback\x12\x1a.proto.BEPEventCallbackReq\x1a\x1a.proto.BEPEventCallbackRes\x12D\n" +
-	"\x0eCustomCommands\x12\x18.proto.CustomCommandsReq\x1a\x18.proto.CustomCommandsRes\x12V\n" +
-	"\x14ExecuteCustomCommand\x12\x1e.proto.ExecuteCustomCommandReq\x1a\x1e.proto.ExecuteCustomCommandRes\x12A\n" +
+	"\x10BEPEventCallback\x12\x1a.proto.BEPEventCallbackReq\x1a\x1a.proto.BEPEventCallbackRes\x12A\n" +
+	"\rBEPEventTypes\x12\x17.proto.BEPEventTypesReq\x1a\x17.proto.BEPEventTypesRes\x12D\n" +
+	"\x0eCustomCommands\x12\x18.proto.CustomCommandsReq\x1a\x18.proto.CustomCommandsRes\x12/\n" +
+	"\aDaemons\x12\x11.proto.DaemonsReq\x1a\x11.proto.DaemonsRes\x12V\n" +
+	"\x14ExecuteCustomCommand\x12\x1e.proto.ExecuteCustomCommandReq\x1a\x1e.proto.ExecuteCustomCommandRes\x12J\n" +
+	"\x10FilterOutputLine\x12\x1a.proto.FilterOutputLineReq\x1a\x1a.proto.FilterOutputLineRes\x12A\n" +
+	"\rFiltersOutput\x12\x17.proto.FiltersOutputReq\x1a\x17.proto.FiltersOutputRes\x12A\n" +
 	"\rPostBuildHook\x12\x17.proto.PostBuildHookReq\x1a\x17.proto.PostBuildHookRes\x12>\n" +
 	"\fPostTestHook\x12\x16.proto.PostTestHookReq\x1a\x16.proto.PostTestHookRes\x12;\n" +
-	"\vPostRunHook\x12\x15.proto.PostRunHookReq\x1a\x15.proto.PostRunHookRes\x12)\n" +
-	"\x05Setup\x12\x0f.proto.SetupReq\x1a\x0f.proto.SetupRes2;\n" +
+	"\vPostRunHook\x12\x15.proto.PostRunHookReq\x1a\x15.proto.PostRunHookRes\x12J\n" +
+	"\x10PropertiesSchema\x12\x1a.proto.PropertiesSchemaReq\x1a\x1a.proto.PropertiesSchemaRes\x12;\n" +
+	"\vRewriteArgs\x12\x15.proto.RewriteArgsReq\x1a\x15.proto.RewriteArgsRes\x12)\n" +
+	"\x05Setup\x12\x0f.proto.SetupReq\x1a\x0f.proto.SetupRes2\xfb\x01\n" +
 	"\bPrompter\x12/\n" +
-	"\x03Run\x12\x13.proto.PromptRunReq\x1a\x13.proto.PromptRunResBIZGgithub.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/protob\x06proto3"
+	"\x03Run\x12\x13.proto.PromptRunReq\x1a\x13.proto.PromptRunRes\x128\n" +
+	"\x06Select\x12\x16.proto.PromptSelectReq\x1a\x16.proto.PromptSelectRes\x12G\n" +
+	"\vMultiSelect\x12\x1b.proto.PromptMultiSelectReq\x1a\x1b.proto.PromptMultiSelectRes\x12;\n" +
+	"\aConfirm\x12\x17.proto.PromptConfirmReq\x1a\x17.proto.PromptConfirmResBIZGgithub.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/protob\x06proto3"
 
 var (
 	file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescOnce sync.Once
@@ -1090,57 +2868,128 @@ func file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescGZIP() []byte {
 	return file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDescData
 }
 
-var file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
+var file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
+var file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes = make([]protoimpl.MessageInfo, 47)
 var file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_goTypes = []any{
-	(*BEPEventCallbackReq)(nil),         // 0: proto.BEPEventCallbackReq
-	(*BEPEventCallbackRes)(nil),         // 1: proto.BEPEventCallbackRes
-	(*SetupReq)(nil),                    // 2: proto.SetupReq
-	(*File)(nil),                        // 3: proto.File
-	(*SetupRes)(nil),                    // 4: proto.SetupRes
-	(*PostBuildHookReq)(nil),            // 5: proto.PostBuildHookReq
-	(*PostBuildHookRes)(nil),            // 6: proto.PostBuildHookRes
-	(*Command)(nil),                     // 7: proto.Command
-	(*CustomCommandsReq)(nil),           // 8: proto.CustomCommandsReq
-	(*CustomCommandsRes)(nil),           // 9: proto.CustomCommandsRes
-	(*Context)(nil),                     // 10: proto.Context
-	(*ExecuteCustomCommandReq)(nil),     // 11: proto.ExecuteCustomCommandReq
-	(*ExecuteCustomCommandRes)(nil),     // 12: proto.ExecuteCustomCommandRes
-	(*PostTestHookReq)(nil),             // 13: proto.PostTestHookReq
-	(*PostTestHookRes)(nil),             // 14: proto.PostTestHookRes
-	(*PostRunHookReq)(nil),              // 15: proto.PostRunHookReq
-	(*PostRunHookRes)(nil),              // 16: proto.PostRunHookRes
-	(*PromptRunReq)(nil),                // 17: proto.PromptRunReq
-	(*PromptRunRes)(nil),                // 18: proto.PromptRunRes
-	(*PromptRunRes_Error)(nil),          // 19: proto.PromptRunRes.Error
-	(*buildeventstream.BuildEvent)(nil), // 20: build_event_stream.BuildEvent
+	(OutputStream)(0),                   // 0: proto.OutputStream
+	(Property_Type)(0),                  // 1: proto.Property.Type
+	(HookResult_Outcome)(0),             // 2: proto.HookResult.Outcome
+	(Flag_Type)(0),                      // 3: proto.Flag.Type
+	(*BEPEventCallbackReq)(nil),         // 4: proto.BEPEventCallbackReq
+	(*BEPStream)(nil),                   // 5: proto.BEPStream
+	(*BEPEventCallbackRes)(nil),         // 6: proto.BEPEventCallbackRes
+	(*BEPEventTypesReq)(nil),            // 7: proto.BEPEventTypesReq
+	(*BEPEventTypesRes)(nil),            // 8: proto.BEPEventTypesRes
+	(*DaemonsReq)(nil),                  // 9: proto.DaemonsReq
+	(*DaemonsRes)(nil),                  // 10: proto.DaemonsRes
+	(*Daemon)(nil),                      // 11: proto.Daemon
+	(*PropertiesSchemaReq)(nil),         // 12: proto.PropertiesSchemaReq
+	(*PropertiesSchemaRes)(nil),         // 13: proto.PropertiesSchemaRes
+	(*Property)(nil),                    // 14: proto.Property
+	(*SetupReq)(nil),                    // 15: proto.SetupReq
+	(*Workspace)(nil),                   // 16: proto.Workspace
+	(*File)(nil),                        // 17: proto.File
+	(*SetupRes)(nil),                    // 18: proto.SetupRes
+	(*FilterOutputLineReq)(nil),         // 19: proto.FilterOutputLineReq
+	(*FilterOutputLineRes)(nil),         // 20: proto.FilterOutputLineRes
+	(*FiltersOutputReq)(nil),            // 21: proto.FiltersOutputReq
+	(*FiltersOutputRes)(nil),            // 22: proto.FiltersOutputRes
+	(*PostBuildHookReq)(nil),            // 23: proto.PostBuildHookReq
+	(*PostBuildHookRes)(nil),            // 24: proto.PostBuildHookRes
+	(*HookResult)(nil),                  // 25: proto.HookResult
+	(*InvocationContext)(nil),           // 26: proto.InvocationContext
+	(*Command)(nil),                     // 27: proto.Command
+	(*Flag)(nil),                        // 28: proto.Flag
+	(*CustomCommandsReq)(nil),           // 29: proto.CustomCommandsReq
+	(*CustomCommandsRes)(nil),           // 30: proto.CustomCommandsRes
+	(*Context)(nil),                     // 31: proto.Context
+	(*ExecuteCustomCommandReq)(nil),     // 32: proto.ExecuteCustomCommandReq
+	(*ExecuteCustomCommandRes)(nil),     // 33: proto.ExecuteCustomCommandRes
+	(*PostTestHookReq)(nil),             // 34: proto.PostTestHookReq
+	(*PostTestHookRes)(nil),             // 35: proto.PostTestHookRes
+	(*PostRunHookReq)(nil),              // 36: proto.PostRunHookReq
+	(*PostRunHookRes)(nil),              // 37: proto.PostRunHookRes
+	(*RewriteArgsReq)(nil),              // 38: proto.RewriteArgsReq
+	(*RewriteArgsRes)(nil),              // 39: proto.RewriteArgsRes
+	(*PromptRunReq)(nil),                // 40: proto.PromptRunReq
+	(*PromptRunRes)(nil),                // 41: proto.PromptRunRes
+	(*PromptSelectReq)(nil),             // 42: proto.PromptSelectReq
+	(*PromptSelectRes)(nil),             // 43: proto.PromptSelectRes
+	(*PromptMultiSelectReq)(nil),        // 44: proto.PromptMultiSelectReq
+	(*PromptMultiSelectRes)(nil),        // 45: proto.PromptMultiSelectRes
+	(*PromptConfirmReq)(nil),            // 46: proto.PromptConfirmReq
+	(*PromptConfirmRes)(nil),            // 47: proto.PromptConfirmRes
+	nil,                                 // 48: proto.Daemon.EnvEntry
+	nil,                                 // 49: proto.ExecuteCustomCommandReq.FlagsEntry
+	(*PromptRunRes_Error)(nil),          // 50: proto.PromptRunRes.Error
+	(*buildeventstream.BuildEvent)(nil), // 51: build_event_stream.BuildEvent
 }
 var file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_depIdxs = []int32{
-	20, // 0: proto.BEPEventCallbackReq.event:type_name -> build_event_stream.BuildEvent
-	3,  // 1: proto.SetupReq.file:type_name -> proto.File
-	7,  // 2: proto.CustomCommandsRes.commands:type_name -> proto.Command
-	10, // 3: proto.ExecuteCustomCommandReq.ctx:type_name -> proto.Context
-	19, // 4: proto.PromptRunRes.error:type_name -> proto.PromptRunRes.Error
-	0,  // 5: proto.Plugin.BEPEventCallback:input_type -> proto.BEPEventCallbackReq
-	8,  // 6: proto.Plugin.CustomCommands:input_type -> proto.CustomCommandsReq
-	11, // 7: proto.Plugin.ExecuteCustomCommand:input_type -> proto.ExecuteCustomCommandReq
-	5,  // 8: proto.Plugin.PostBuildHook:input_type -> proto.PostBuildHookReq
-	13, // 9: proto.Plugin.PostTestHook:input_type -> proto.PostTestHookReq
-	15, // 10: proto.Plugin.PostRunHook:input_type -> proto.PostRunHookReq
-	2,  // 11: proto.Plugin.Setup:input_type -> proto.SetupReq
-	17, // 12: proto.Prompter.Run:input_type -> proto.PromptRunReq
-	1,  // 13: proto.Plugin.BEPEventCallback:output_type -> proto.BEPEventCallbackRes
-	9,  // 14: proto.Plugin.CustomCommands:output_type -> proto.CustomCommandsRes
-	12, // 15: proto.Plugin.ExecuteCustomCommand:output_type -> proto.ExecuteCustomCommandRes
-	6,  // 16: proto.Plugin.PostBuildHook:output_type -> proto.PostBuildHookRes
-	14, // 17: proto.Plugin.PostTestHook:output_type -> proto.PostTestHookRes
-	16, // 18: proto.Plugin.PostRunHook:output_type -> proto.PostRunHookRes
-	4,  // 19: proto.Plugin.Setup:output_type -> proto.SetupRes
-	18, // 20: proto.Prompter.Run:output_type -> proto.PromptRunRes
-	13, // [13:21] is the sub-list for method output_type
-	5,  // [5:13] is the sub-list for method input_type
-	5,  // [5:5] is the sub-list for extension type_name
-	5,  // [5:5] is the sub-list for extension extendee
-	0,  // [0:5] is the sub-list for field type_name
+	51, // 0: proto.BEPEventCallbackReq.event:type_name -> build_event_stream.BuildEvent
+	5,  // 1: proto.BEPEventCallbackReq.stream:type_name -> proto.BEPStream
+	11, // 2: proto.DaemonsRes.daemons:type_name -> proto.Daemon
+	48, // 3: proto.Daemon.env:type_name -> proto.Daemon.EnvEntry
+	14, // 4: proto.PropertiesSchemaRes.properties:type_name -> proto.Property
+	1,  // 5: proto.Property.type:type_name -> proto.Property.Type
+	17, // 6: proto.SetupReq.file:type_name -> proto.File
+	16, // 7: proto.SetupReq.workspace:type_name -> proto.Workspace
+	0,  // 8: proto.FilterOutputLineReq.stream:type_name -> proto.OutputStream
+	26, // 9: proto.PostBuildHookReq.invocation:type_name -> proto.InvocationContext
+	25, // 10: proto.PostBuildHookRes.result:type_name -> proto.HookResult
+	2,  // 11: proto.HookResult.outcome:type_name -> proto.HookResult.Outcome
+	28, // 12: proto.Command.flags:type_name -> proto.Flag
+	27, // 13: proto.Command.subcommands:type_name -> proto.Command
+	3,  // 14: proto.Flag.type:type_name -> proto.Flag.Type
+	27, // 15: proto.CustomCommandsRes.commands:type_name -> proto.Command
+	31, // 16: proto.ExecuteCustomCommandReq.ctx:type_name -> proto.Context
+	49, // 17: proto.ExecuteCustomCommandReq.flags:type_name -> proto.ExecuteCustomCommandReq.FlagsEntry
+	26, // 18: proto.PostTestHookReq.invocation:type_name -> proto.InvocationContext
+	25, // 19: proto.PostTestHookRes.result:type_name -> proto.HookResult
+	26, // 20: proto.PostRunHookReq.invocation:type_name -> proto.InvocationContext
+	25, // 21: proto.PostRunHookRes.result:type_name -> proto.HookResult
+	50, // 22: proto.PromptRunRes.error:type_name -> proto.PromptRunRes.Error
+	50, // 23: proto.PromptSelectRes.error:type_name -> proto.PromptRunRes.Error
+	50, // 24: proto.PromptMultiSelectRes.error:type_name -> proto.PromptRunRes.Error
+	50, // 25: proto.PromptConfirmRes.error:type_name -> proto.PromptRunRes.Error
+	4,  // 26: proto.Plugin.BEPEventCallback:input_type -> proto.BEPEventCallbackReq
+	7,  // 27: proto.Plugin.BEPEventTypes:input_type -> proto.BEPEventTypesReq
+	29, // 28: proto.Plugin.CustomCommands:input_type -> proto.CustomCommandsReq
+	9,  // 29: proto.Plugin.Daemons:input_type -> proto.DaemonsReq
+	32, // 30: proto.Plugin.ExecuteCustomCommand:input_type -> proto.ExecuteCustomCommandReq
+	19, // 31: proto.Plugin.FilterOutputLine:input_type -> proto.FilterOutputLineReq
+	21, // 32: proto.Plugin.FiltersOutput:input_type -> proto.FiltersOutputReq
+	23, // 33: proto.Plugin.PostBuildHook:input_type -> proto.PostBuildHookReq
+	34, // 34: proto.Plugin.PostTestHook:input_type -> proto.PostTestHookReq
+	36, // 35: proto.Plugin.PostRunHook:input_type -> proto.PostRunHookReq
+	12, // 36: proto.Plugin.PropertiesSchema:input_type -> proto.PropertiesSchemaReq
+	38, // 37: proto.Plugin.RewriteArgs:input_type -> proto.RewriteArgsReq
+	15, // 38: proto.Plugin.Setup:input_type -> proto.SetupReq
+	40, // 39: proto.Prompter.Run:input_type -> proto.PromptRunReq
+	42, // 40: proto.Prompter.Select:input_type -> proto.PromptSelectReq
+	44, // 41: proto.Prompter.MultiSelect:input_type -> proto.PromptMultiSelectReq
+	46, // 42: proto.Prompter.Confirm:input_type -> proto.PromptConfirmReq
+	6,  // 43: proto.Plugin.BEPEventCallback:output_type -> proto.BEPEventCallbackRes
+	8,  // 44: proto.Plugin.BEPEventTypes:output_type -> proto.BEPEventTypesRes
+	30, // 45: proto.Plugin.CustomCommands:output_type -> proto.CustomCommandsRes
+	10, // 46: proto.Plugin.Daemons:output_type -> proto.DaemonsRes
+	33, // 47: proto.Plugin.ExecuteCustomCommand:output_type -> proto.ExecuteCustomCommandRes
+	20, // 48: proto.Plugin.FilterOutputLine:output_type -> proto.FilterOutputLineRes
+	22, // 49: proto.Plugin.FiltersOutput:output_type -> proto.FiltersOutputRes
+	24, // 50: proto.Plugin.PostBuildHook:output_type -> proto.PostBuildHookRes
+	35, // 51: proto.Plugin.PostTestHook:output_type -> proto.PostTestHookRes
+	37, // 52: proto.Plugin.PostRunHook:output_type -> proto.PostRunHookRes
+	13, // 53: proto.Plugin.PropertiesSchema:output_type -> proto.PropertiesSchemaRes
+	39, // 54: proto.Plugin.RewriteArgs:output_type -> proto.RewriteArgsRes
+	18, // 55: proto.Plugin.Setup:output_type -> proto.SetupRes
+	41, // 56: proto.Prompter.Run:output_type -> proto.PromptRunRes
+	43, // 57: proto.Prompter.Select:output_type -> proto.PromptSelectRes
+	45, // 58: proto.Prompter.MultiSelect:output_type -> proto.PromptMultiSelectRes
+	47, // 59: proto.Prompter.Confirm:output_type -> proto.PromptConfirmRes
+	43, // [43:60] is the sub-list for method output_type
+	26, // [26:43] is the sub-list for method input_type
+	26, // [26:26] is the sub-list for extension type_name
+	26, // [26:26] is the sub-list for extension extendee
+	0,  // [0:26] is the sub-list for field type_name
 }
 
 func init() { file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_init() }
@@ -1153,13 +3002,14 @@ func file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDesc), len(file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   20,
+			NumEnums:      4,
+			NumMessages:   47,
 			NumExtensions: 0,
 			NumServices:   2,
 		},
 		GoTypes:           file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_goTypes,
 		DependencyIndexes: file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_depIdxs,
+		EnumInfos:         file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_enumTypes,
 		MessageInfos:      file_pkg_plugin_sdk_v1alpha4_proto_plugin_proto_msgTypes,
 	}.Build()
 	File_pkg_plugin_sdk_v1alpha4_proto_plugin_proto = out.File
@@ -1180,11 +3030,17 @@ const _ = grpc.SupportPackageIsVersion6
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type PluginClient interface {
 	BEPEventCallback(ctx context.Context, in *BEPEventCallbackReq, opts ...grpc.CallOption) (*BEPEventCallbackRes, error)
+	BEPEventTypes(ctx context.Context, in *BEPEventTypesReq, opts ...grpc.CallOption) (*BEPEventTypesRes, error)
 	CustomCommands(ctx context.Context, in *CustomCommandsReq, opts ...grpc.CallOption) (*CustomCommandsRes, error)
+	Daemons(ctx context.Context, in *DaemonsReq, opts ...grpc.CallOption) (*DaemonsRes, error)
 	ExecuteCustomCommand(ctx context.Context, in *ExecuteCustomCommandReq, opts ...grpc.CallOption) (*ExecuteCustomCommandRes, error)
+	FilterOutputLine(ctx context.Context, in *FilterOutputLineReq, opts ...grpc.CallOption) (*FilterOutputLineRes, error)
+	FiltersOutput(ctx context.Context, in *FiltersOutputReq, opts ...grpc.CallOption) (*FiltersOutputRes, error)
 	PostBuildHook(ctx context.Context, in *PostBuildHookReq, opts ...grpc.CallOption) (*PostBuildHookRes, error)
 	PostTestHook(ctx context.Context, in *PostTestHookReq, opts ...grpc.CallOption) (*PostTestHookRes, error)
 	PostRunHook(ctx context.Context, in *PostRunHookReq, opts ...grpc.CallOption) (*PostRunHookRes, error)
+	PropertiesSchema(ctx context.Context, in *PropertiesSchemaReq, opts ...grpc.CallOption) (*PropertiesSchemaRes, error)
+	RewriteArgs(ctx context.Context, in *RewriteArgsReq, opts ...grpc.CallOption) (*RewriteArgsRes, error)
 	Setup(ctx context.Context, in *SetupReq, opts ...grpc.CallOption) (*SetupRes, error)
 }
 
@@ -1205,6 +3061,15 @@ func (c *pluginClient) BEPEventCallback(ctx context.Context, in *BEPEventCallbac
 	return out, nil
 }
 
+func (c *pluginClient) BEPEventTypes(ctx context.Context, in *BEPEventTypesReq, opts ...grpc.CallOption) (*BEPEventTypesRes, error) {
+	out := new(BEPEventTypesRes)
+	err := c.cc.Invoke(ctx, "/proto.Plugin/BEPEventTypes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *pluginClient) CustomCommands(ctx context.Context, in *CustomCommandsReq, opts ...grpc.CallOption) (*CustomCommandsRes, error) {
 	out := new(CustomCommandsRes)
 	err := c.cc.Invoke(ctx, "/proto.Plugin/CustomCommands", in, out, opts...)
@@ -1214,6 +3079,15 @@ func (c *pluginClient) CustomCommands(ctx context.Context, in *CustomCommandsReq
 	return out, nil
 }
 
+func (c *pluginClient) Daemons(ctx context.Context, in *DaemonsReq, opts ...grpc.CallOption) (*DaemonsRes, error) {
+	out := new(DaemonsRes)
+	err := c.cc.Invoke(ctx, "/proto.Plugin/Daemons", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *pluginClient) ExecuteCustomCommand(ctx context.Context, in *ExecuteCustomCommandReq, opts ...grpc.CallOption) (*ExecuteCustomCommandRes, error) {
 	out := new(ExecuteCustomCommandRes)
 	err := c.cc.Invoke(ctx, "/proto.Plugin/ExecuteCustomCommand", in, out, opts...)
@@ -1223,6 +3097,24 @@ func (c *pluginClient) ExecuteCustomCommand(ctx context.Context, in *ExecuteCust
 	return out, nil
 }
 
+func (c *pluginClient) FilterOutputLine(ctx context.Context, in *FilterOutputLineReq, opts ...grpc.CallOption) (*FilterOutputLineRes, error) {
+	out := new(FilterOutputLineRes)
+	err := c.cc.Invoke(ctx, "/proto.Plugin/FilterOutputLine", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pluginClient) FiltersOutput(ctx context.Context, in *FiltersOutputReq, opts ...grpc.CallOption) (*FiltersOutputRes, error) {
+	out := new(FiltersOutputRes)
+	err := c.cc.Invoke(ctx, "/proto.Plugin/FiltersOutput", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *pluginClient) PostBuildHook(ctx context.Context, in *PostBuildHookReq, opts ...grpc.CallOption) (*PostBuildHookRes, error) {
 	out := new(PostBuildHookRes)
 	err := c.cc.Invoke(ctx, "/proto.Plugin/PostBuildHook", in, out, opts...)
@@ -1250,6 +3142,24 @@ func (c *pluginClient) PostRunHook(ctx context.Context, in *PostRunHookReq, opts
 	return out, nil
 }
 
+func (c *pluginClient) PropertiesSchema(ctx context.Context, in *PropertiesSchemaReq, opts ...grpc.CallOption) (*PropertiesSchemaRes, error) {
+	out := new(PropertiesSchemaRes)
+	err := c.cc.Invoke(ctx, "/proto.Plugin/PropertiesSchema", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pluginClient) RewriteArgs(ctx context.Context, in *RewriteArgsReq, opts ...grpc.CallOption) (*RewriteArgsRes, error) {
+	out := new(RewriteArgsRes)
+	err := c.cc.Invoke(ctx, "/proto.Plugin/RewriteArgs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *pluginClient) Setup(ctx context.Context, in *SetupReq, opts ...grpc.CallOption) (*SetupRes, error) {
 	out := new(SetupRes)
 	err := c.cc.Invoke(ctx, "/proto.Plugin/Setup", in, out, opts...)
@@ -1262,11 +3172,17 @@ func (c *pluginClient) Setup(ctx context.Context, in *SetupReq, opts ...grpc.Cal
 // PluginServer is the server API for Plugin service.
 type PluginServer interface {
 	BEPEventCallback(context.Context, *BEPEventCallbackReq) (*BEPEventCallbackRes, error)
+	BEPEventTypes(context.Context, *BEPEventTypesReq) (*BEPEventTypesRes, error)
 	CustomCommands(context.Context, *CustomCommandsReq) (*CustomCommandsRes, error)
+	Daemons(context.Context, *DaemonsReq) (*DaemonsRes, error)
 	ExecuteCustomCommand(context.Context, *ExecuteCustomCommandReq) (*ExecuteCustomCommandRes, error)
+	FilterOutputLine(context.Context, *FilterOutputLineReq) (*FilterOutputLineRes, error)
+	FiltersOutput(context.Context, *FiltersOutputReq) (*FiltersOutputRes, error)
 	PostBuildHook(context.Context, *PostBuildHookReq) (*PostBuildHookRes, error)
 	PostTestHook(context.Context, *PostTestHookReq) (*PostTestHookRes, error)
 	PostRunHook(context.Context, *PostRunHookReq) (*PostRunHookRes, error)
+	PropertiesSchema(context.Context, *PropertiesSchemaReq) (*PropertiesSchemaRes, error)
+	RewriteArgs(context.Context, *RewriteArgsReq) (*RewriteArgsRes, error)
 	Setup(context.Context, *SetupReq) (*SetupRes, error)
 }
 
@@ -1277,12 +3193,24 @@ type UnimplementedPluginServer struct {
 func (*UnimplementedPluginServer) BEPEventCallback(context.Context, *BEPEventCallbackReq) (*BEPEventCallbackRes, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method BEPEventCallback not implemented")
 }
+func (*UnimplementedPluginServer) BEPEventTypes(context.Context, *BEPEventTypesReq) (*BEPEventTypesRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BEPEventTypes not implemented")
+}
 func (*UnimplementedPluginServer) CustomCommands(context.Context, *CustomCommandsReq) (*CustomCommandsRes, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CustomCommands not implemented")
 }
+func (*UnimplementedPluginServer) Daemons(context.Context, *DaemonsReq) (*DaemonsRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Daemons not implemented")
+}
 func (*UnimplementedPluginServer) ExecuteCustomCommand(context.Context, *ExecuteCustomCommandReq) (*ExecuteCustomCommandRes, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ExecuteCustomCommand not implemented")
 }
+func (*UnimplementedPluginServer) FilterOutputLine(context.Context, *FilterOutputLineReq) (*FilterOutputLineRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FilterOutputLine not implemented")
+}
+func (*UnimplementedPluginServer) FiltersOutput(context.Context, *FiltersOutputReq) (*FiltersOutputRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FiltersOutput not implemented")
+}
 func (*UnimplementedPluginServer) PostBuildHook(context.Context, *PostBuildHookReq) (*PostBuildHookRes, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method PostBuildHook not implemented")
 }
@@ -1292,6 +3220,12 @@ func (*UnimplementedPluginServer) PostTestHook(context.Context, *PostTestHookReq
 func (*UnimplementedPluginServer) PostRunHook(context.Context, *PostRunHookReq) (*PostRunHookRes, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method PostRunHook not implemented")
 }
+func (*UnimplementedPluginServer) PropertiesSchema(context.Context, *PropertiesSchemaReq) (*PropertiesSchemaRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PropertiesSchema not implemented")
+}
+func (*UnimplementedPluginServer) RewriteArgs(context.Context, *RewriteArgsReq) (*RewriteArgsRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RewriteArgs not implemented")
+}
 func (*UnimplementedPluginServer) Setup(context.Context, *SetupReq) (*SetupRes, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Setup not implemented")
 }
@@ -1318,6 +3252,24 @@ func _Plugin_BEPEventCallback_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Plugin_BEPEventTypes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BEPEventTypesReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginServer).BEPEventTypes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Plugin/BEPEventTypes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginServer).BEPEventTypes(ctx, req.(*BEPEventTypesReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Plugin_CustomCommands_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(CustomCommandsReq)
 	if err := dec(in); err != nil {
@@ -1336,6 +3288,24 @@ func _Plugin_CustomCommands_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Plugin_Daemons_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DaemonsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginServer).Daemons(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Plugin/Daemons",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginServer).Daemons(ctx, req.(*DaemonsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Plugin_ExecuteCustomCommand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ExecuteCustomCommandReq)
 	if err := dec(in); err != nil {
@@ -1354,6 +3324,42 @@ func _Plugin_ExecuteCustomCommand_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Plugin_FilterOutputLine_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FilterOutputLineReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginServer).FilterOutputLine(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Plugin/FilterOutputLine",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginServer).FilterOutputLine(ctx, req.(*FilterOutputLineReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Plugin_FiltersOutput_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FiltersOutputReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginServer).FiltersOutput(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Plugin/FiltersOutput",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginServer).FiltersOutput(ctx, req.(*FiltersOutputReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Plugin_PostBuildHook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(PostBuildHookReq)
 	if err := dec(in); err != nil {
@@ -1408,6 +3414,42 @@ func _Plugin_PostRunHook_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Plugin_PropertiesSchema_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PropertiesSchemaReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginServer).PropertiesSchema(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Plugin/PropertiesSchema",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginServer).PropertiesSchema(ctx, req.(*PropertiesSchemaReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Plugin_RewriteArgs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RewriteArgsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginServer).RewriteArgs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Plugin/RewriteArgs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginServer).RewriteArgs(ctx, req.(*RewriteArgsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Plugin_Setup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(SetupReq)
 	if err := dec(in); err != nil {
@@ -1434,14 +3476,30 @@ var _Plugin_serviceDesc = grpc.ServiceDesc{
 			MethodName: "BEPEventCallback",
 			Handler:    _Plugin_BEPEventCallback_Handler,
 		},
+		{
+			MethodName: "BEPEventTypes",
+			Handler:    _Plugin_BEPEventTypes_Handler,
+		},
 		{
 			MethodName: "CustomCommands",
 			Handler:    _Plugin_CustomCommands_Handler,
 		},
+		{
+			MethodName: "Daemons",
+			Handler:    _Plugin_Daemons_Handler,
+		},
 		{
 			MethodName: "ExecuteCustomCommand",
 			Handler:    _Plugin_ExecuteCustomCommand_Handler,
 		},
+		{
+			MethodName: "FilterOutputLine",
+			Handler:    _Plugin_FilterOutputLine_Handler,
+		},
+		{
+			MethodName: "FiltersOutput",
+			Handler:    _Plugin_FiltersOutput_Handler,
+		},
 		{
 			MethodName: "PostBuildHook",
 			Handler:    _Plugin_PostBuildHook_Handler,
@@ -1454,6 +3512,14 @@ var _Plugin_serviceDesc = grpc.ServiceDesc{
 			MethodName: "PostRunHook",
 			Handler:    _Plugin_PostRunHook_Handler,
 		},
+		{
+			MethodName: "PropertiesSchema",
+			Handler:    _Plugin_PropertiesSchema_Handler,
+		},
+		{
+			MethodName: "RewriteArgs",
+			Handler:    _Plugin_RewriteArgs_Handler,
+		},
 		{
 			MethodName: "Setup",
 			Handler:    _Plugin_Setup_Handler,
@@ -1468,6 +3534,9 @@ var _Plugin_serviceDesc = grpc.ServiceDesc{
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type PrompterClient interface {
 	Run(ctx context.Context, in *PromptRunReq, opts ...grpc.CallOption) (*PromptRunRes, error)
+	Select(ctx context.Context, in *PromptSelectReq, opts ...grpc.CallOption) (*PromptSelectRes, error)
+	MultiSelect(ctx context.Context, in *PromptMultiSelectReq, opts ...grpc.CallOption) (*PromptMultiSelectRes, error)
+	Confirm(ctx context.Context, in *PromptConfirmReq, opts ...grpc.CallOption) (*PromptConfirmRes, error)
 }
 
 type prompterClient struct {
@@ -1487,9 +3556,39 @@ func (c *prompterClient) Run(ctx context.Context, in *PromptRunReq, opts ...grpc
 	return out, nil
 }
 
+func (c *prompterClient) Select(ctx context.Context, in *PromptSelectReq, opts ...grpc.CallOption) (*PromptSelectRes, error) {
+	out := new(PromptSelectRes)
+	err := c.cc.Invoke(ctx, "/proto.Prompter/Select", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *prompterClient) MultiSelect(ctx context.Context, in *PromptMultiSelectReq, opts ...grpc.CallOption) (*PromptMultiSelectRes, error) {
+	out := new(PromptMultiSelectRes)
+	err := c.cc.Invoke(ctx, "/proto.Prompter/MultiSelect", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *prompterClient) Confirm(ctx context.Context, in *PromptConfirmReq, opts ...grpc.CallOption) (*PromptConfirmRes, error) {
+	out := new(PromptConfirmRes)
+	err := c.cc.Invoke(ctx, "/proto.Prompter/Confirm", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // PrompterServer is the server API for Prompter service.
 type PrompterServer interface {
 	Run(context.Context, *PromptRunReq) (*PromptRunRes, error)
+	Select(context.Context, *PromptSelectReq) (*PromptSelectRes, error)
+	MultiSelect(context.Context, *PromptMultiSelectReq) (*PromptMultiSelectRes, error)
+	Confirm(context.Context, *PromptConfirmReq) (*PromptConfirmRes, error)
 }
 
 // UnimplementedPrompterServer can be embedded to have forward compatible implementations.
@@ -1499,6 +3598,15 @@ type UnimplementedPrompterServer struct {
 func (*UnimplementedPrompterServer) Run(context.Context, *PromptRunReq) (*PromptRunRes, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Run not implemented")
 }
+func (*UnimplementedPrompterServer) Select(context.Context, *PromptSelectReq) (*PromptSelectRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Select not implemented")
+}
+func (*UnimplementedPrompterServer) MultiSelect(context.Context, *PromptMultiSelectReq) (*PromptMultiSelectRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MultiSelect not implemented")
+}
+func (*UnimplementedPrompterServer) Confirm(context.Context, *PromptConfirmReq) (*PromptConfirmRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Confirm not implemented")
+}
 
 func RegisterPrompterServer(s *grpc.Server, srv PrompterServer) {
 	s.RegisterService(&_Prompter_serviceDesc, srv)
@@ -1522,6 +3630,60 @@ func _Prompter_Run_Handler(srv interface{}, ctx context.Context, dec func(interf
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Prompter_Select_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PromptSelectReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PrompterServer).Select(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Prompter/Select",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PrompterServer).Select(ctx, req.(*PromptSelectReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Prompter_MultiSelect_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PromptMultiSelectReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PrompterServer).MultiSelect(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Prompter/MultiSelect",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PrompterServer).MultiSelect(ctx, req.(*PromptMultiSelectReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Prompter_Confirm_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PromptConfirmReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PrompterServer).Confirm(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Prompter/Confirm",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PrompterServer).Confirm(ctx, req.(*PromptConfirmReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Prompter_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "proto.Prompter",
 	HandlerType: (*PrompterServer)(nil),
@@ -1530,6 +3692,18 @@ var _Prompter_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Run",
 			Handler:    _Prompter_Run_Handler,
 		},
+		{
+			MethodName: "Select",
+			Handler:    _Prompter_Select_Handler,
+		},
+		{
+			MethodName: "MultiSelect",
+			Handler:    _Prompter_MultiSelect_Handler,
+		},
+		{
+			MethodName: "Confirm",
+			Handler:    _Prompter_Confirm_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "pkg/plugin/sdk/v1alpha4/proto/plugin.proto",
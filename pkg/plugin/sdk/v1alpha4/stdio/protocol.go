@@ -0,0 +1,137 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package stdio describes the protocol spoken by plugins that run as plain
+// executables and exchange newline-delimited JSON with the Core over their
+// stdin and stdout, without go-plugin or gRPC. It lets plugins be written in
+// any language, e.g. Python, Node.js or shell.
+//
+// The Core writes one Request per line to the stdin of the plugin, and waits
+// for the plugin to write one Response with the same id per line to its
+// stdout before sending the next request:
+//
+//	{"id":1,"method":"setup","params":{"protocol_version":1,"properties":{}}}
+//	{"id":1,"result":{"methods":["bep_event","post_build_hook"],"event_types":["build_finished"]}}
+//
+// The first request is always MethodSetup. Its result lists the other methods
+// the plugin handles; the Core doesn't send it any other. Lines the plugin
+// writes to stdout that aren't a JSON object with an id are printed to the
+// user, and what it writes to stderr is printed and logged like the output
+// of any other plugin. The plugin is expected to exit once its stdin is
+// closed.
+//
+// Build events and invocation contexts are encoded with the canonical JSON
+// mapping of their protobuf messages, the same one bazel uses for
+// --build_event_json_file.
+package stdio
+
+import "encoding/json"
+
+// ProtocolVersion is the version of the protocol described by this package.
+const ProtocolVersion = 1
+
+// Methods of the Core the plugin may handle.
+const (
+	// MethodSetup is sent with SetupParams and expects a SetupResult.
+	MethodSetup = "setup"
+	// MethodBEPEvent is sent with BEPEventParams for each build event of the
+	// types returned in SetupResult.EventTypes, or every build event when it
+	// lists none. Its result is ignored.
+	MethodBEPEvent = "bep_event"
+	// MethodPostBuildHook, MethodPostTestHook and MethodPostRunHook are sent
+	// with HookParams after the bazel build, test or coverage and run commands
+	// and expect a HookResult.
+	MethodPostBuildHook = "post_build_hook"
+	MethodPostTestHook  = "post_test_hook"
+	MethodPostRunHook   = "post_run_hook"
+)
+
+// Request is a line the Core writes to the stdin of the plugin.
+type Request struct {
+	ID     int64           `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// Response is a line the plugin writes to its stdout in reply to the Request
+// with the same ID. The request failed when Error is set.
+type Response struct {
+	ID     *int64          `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// SetupParams are the params of MethodSetup.
+type SetupParams struct {
+	ProtocolVersion int            `json:"protocol_version"`
+	Properties      map[string]any `json:"properties"`
+	Workspace       *Workspace     `json:"workspace,omitempty"`
+}
+
+// Workspace describes the Bazel workspace the Core is running in. Its fields
+// are empty when the Core runs outside of a workspace.
+type Workspace struct {
+	Root          string `json:"root"`
+	OutputBase    string `json:"output_base"`
+	ExecutionRoot string `json:"execution_root"`
+	BazelVersion  string `json:"bazel_version"`
+	CLIVersion    string `json:"cli_version"`
+}
+
+// SetupResult is the result of MethodSetup.
+type SetupResult struct {
+	// Methods lists the methods the plugin handles besides MethodSetup.
+	Methods []string `json:"methods"`
+	// EventTypes are the types of the build events sent with MethodBEPEvent,
+	// which are the names of the fields of the BuildEventId oneof, e.g.
+	// "test_result".
+	EventTypes []string `json:"event_types"`
+}
+
+// BEPEventParams are the params of MethodBEPEvent.
+type BEPEventParams struct {
+	Event          json.RawMessage `json:"event"`
+	SequenceNumber int64           `json:"sequence_number"`
+	InvocationID   string          `json:"invocation_id"`
+	BuildID        string          `json:"build_id,omitempty"`
+	// Command is the bazel command of the invocation, e.g. "build".
+	Command string `json:"command,omitempty"`
+}
+
+// HookParams are the params of MethodPostBuildHook, MethodPostTestHook and
+// MethodPostRunHook.
+type HookParams struct {
+	Invocation json.RawMessage `json:"invocation"`
+}
+
+// Outcomes of a hook.
+const (
+	// OutcomeFail fails the command with exit code 1, even when bazel
+	// succeeded.
+	OutcomeFail = "fail"
+	// OutcomeExitCode makes the command exit with HookResult.ExitCode.
+	OutcomeExitCode = "exit_code"
+	// OutcomeWarn turns a failure of the command into a warning.
+	OutcomeWarn = "warn"
+)
+
+// HookResult is the result of MethodPostBuildHook, MethodPostTestHook and
+// MethodPostRunHook. The exit code of the command is left alone when Outcome
+// is empty.
+type HookResult struct {
+	Outcome  string `json:"outcome,omitempty"`
+	ExitCode int    `json:"exit_code,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
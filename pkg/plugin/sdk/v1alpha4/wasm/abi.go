@@ -0,0 +1,85 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package wasm is the SDK for plugins compiled to WebAssembly (GOOS=wasip1)
+// and executed in-process by the Core instead of as a go-plugin subprocess.
+//
+// The calling convention between the Core (host) and the Plugin (guest) is
+// intentionally small. Requests and responses are the same protobuf messages
+// used by the gRPC protocol, copied in and out of the guest linear memory:
+//
+//   - The guest exports MallocExport and FreeExport so the host can manage
+//     buffers in guest memory.
+//   - The guest exports CallExport(method, ptr, len) which decodes the request
+//     for the given Method, frees the request buffer and returns a packed
+//     pointer/length to a result buffer. The first byte of the result is a
+//     Status; the remaining bytes are either the encoded response message or
+//     an error message.
+//   - The host exports PromptRunImport and PromptResultImport in the
+//     HostModule module so that hooks can prompt the CLI user.
+package wasm
+
+// Names of the functions exported by the guest module.
+const (
+	MallocExport = "aspect_malloc"
+	FreeExport   = "aspect_free"
+	CallExport   = "aspect_call"
+)
+
+// Names of the host module and the functions it exports to the guest.
+const (
+	HostModule         = "aspect"
+	PromptRunImport    = "prompt_run"
+	PromptResultImport = "prompt_result"
+)
+
+// Method identifies the Plugin method a CallExport invocation is for.
+type Method uint32
+
+const (
+	MethodSetup Method = iota + 1
+	MethodBEPEventCallback
+	MethodCustomCommands
+	MethodExecuteCustomCommand
+	MethodPostBuildHook
+	MethodPostTestHook
+	MethodPostRunHook
+	MethodRewriteArgs
+	MethodBEPEventTypes
+	MethodPropertiesSchema
+	MethodFiltersOutput
+	MethodFilterOutputLine
+	MethodDaemons
+)
+
+// Status is the first byte of every CallExport result buffer.
+type Status byte
+
+const (
+	StatusOK Status = iota
+	StatusError
+)
+
+// Pack combines a pointer and a length in guest memory into a single value
+// that can be returned from an exported function.
+func Pack(ptr, size uint32) uint64 {
+	return uint64(ptr)<<32 | uint64(size)
+}
+
+// Unpack is the inverse of Pack.
+func Unpack(v uint64) (ptr, size uint32) {
+	return uint32(v >> 32), uint32(v)
+}
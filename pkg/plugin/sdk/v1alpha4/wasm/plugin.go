@@ -0,0 +1,188 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wasm
+
+import (
+	"context"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/proto"
+)
+
+// Plugin determines how an aspect Plugin compiled to WebAssembly should be
+// implemented. It mirrors plugin.Plugin, but avoids the terminal dependencies
+// that cannot be compiled for wasip1.
+type Plugin interface {
+	BEPEventCallback(event *buildeventstream.BuildEvent, sn int64, invocationId string) error
+	BEPEventTypes() ([]string, error)
+	CustomCommands() ([]*Command, error)
+	Daemons() ([]*proto.Daemon, error)
+	FiltersOutput() (bool, error)
+	FilterOutputLine(stream proto.OutputStream, line string) ([]string, error)
+	PostBuildHook(invocation *proto.InvocationContext, promptRunner PromptRunner) error
+	PostTestHook(invocation *proto.InvocationContext, promptRunner PromptRunner) error
+	PostRunHook(invocation *proto.InvocationContext, promptRunner PromptRunner) error
+	PropertiesSchema() ([]*proto.Property, error)
+	RewriteArgs(command string, args []string) ([]string, error)
+	Setup(properties []byte, workspace *proto.Workspace) error
+}
+
+// BEPStreamReceiver mirrors plugin.BEPStreamReceiver. SetBEPStream is called
+// with the build ID, invocation ID and bazel command of a build event stream
+// before BEPEventCallback receives its first build event.
+type BEPStreamReceiver interface {
+	SetBEPStream(stream *proto.BEPStream)
+}
+
+// PromptRunner asks the Core to prompt the CLI user. Select prompts are only
+// available to plugins that run as a subprocess.
+type PromptRunner interface {
+	Run(req *proto.PromptRunReq) (string, error)
+	// Secret asks for input that is not echoed, such as a password or a token.
+	Secret(label string) (string, error)
+	// Confirm asks a yes or no question. defaultYes is the answer when the user
+	// only presses <Enter>.
+	Confirm(label string, defaultYes bool) (bool, error)
+}
+
+// Base satisfies the Plugin interface. For plugins that only implement a subset
+// of the Plugin interface, using this as a base will give the advantage of not
+// needing to implement the empty methods.
+type Base struct{}
+
+var _ Plugin = (*Base)(nil)
+
+// Setup satisfies Plugin.Setup.
+func (*Base) Setup([]byte, *proto.Workspace) error {
+	return nil
+}
+
+// BEPEventCallback satisfies Plugin.BEPEventCallback.
+func (*Base) BEPEventCallback(*buildeventstream.BuildEvent, int64, string) error {
+	return nil
+}
+
+// BEPEventTypes satisfies Plugin.BEPEventTypes.
+func (*Base) BEPEventTypes() ([]string, error) {
+	return nil, nil
+}
+
+// CustomCommands satisfies Plugin.CustomCommands.
+func (*Base) CustomCommands() ([]*Command, error) {
+	return nil, nil
+}
+
+// Daemons satisfies Plugin.Daemons.
+func (*Base) Daemons() ([]*proto.Daemon, error) {
+	return nil, nil
+}
+
+// FiltersOutput satisfies Plugin.FiltersOutput.
+func (*Base) FiltersOutput() (bool, error) {
+	return false, nil
+}
+
+// FilterOutputLine satisfies Plugin.FilterOutputLine.
+func (*Base) FilterOutputLine(_ proto.OutputStream, line string) ([]string, error) {
+	return []string{line}, nil
+}
+
+// PostBuildHook satisfies Plugin.PostBuildHook.
+func (*Base) PostBuildHook(*proto.InvocationContext, PromptRunner) error {
+	return nil
+}
+
+// PostTestHook satisfies Plugin.PostTestHook.
+func (*Base) PostTestHook(*proto.InvocationContext, PromptRunner) error {
+	return nil
+}
+
+// PostRunHook satisfies Plugin.PostRunHook.
+func (*Base) PostRunHook(*proto.InvocationContext, PromptRunner) error {
+	return nil
+}
+
+// PropertiesSchema satisfies Plugin.PropertiesSchema.
+func (*Base) PropertiesSchema() ([]*proto.Property, error) {
+	return nil, nil
+}
+
+// RewriteArgs satisfies Plugin.RewriteArgs.
+func (*Base) RewriteArgs(_ string, args []string) ([]string, error) {
+	return args, nil
+}
+
+// CustomCommandFn defines the parameters of that the Run functions will be called with.
+type CustomCommandFn (func(ctx context.Context, args []string, bazelStartupArgs []string) error)
+
+// BuiltinDelegation is returned by a custom command that overrides a built-in
+// command of the CLI, which plugins are allowed to with the
+// allow_command_override config, to run the built-in command with Args once
+// it is done.
+type BuiltinDelegation struct {
+	Args []string
+}
+
+// Error satisfies error.
+func (*BuiltinDelegation) Error() string {
+	return "delegating to the built-in command"
+}
+
+// DelegateToBuiltin returns the error that a custom command overriding a
+// built-in command returns to run the built-in command with the given args,
+// e.g. the args it was called with.
+func DelegateToBuiltin(args []string) error {
+	return &BuiltinDelegation{Args: args}
+}
+
+// Command defines the information needed to create a custom command that will be callable when
+// running the CLI.
+type Command struct {
+	*proto.Command
+	Run CustomCommandFn
+
+	subcommands []*Command
+}
+
+// NewCommand creates a Command.
+func NewCommand(
+	use string,
+	shortDesc string,
+	longDesc string,
+	run CustomCommandFn,
+) *Command {
+	return &Command{
+		Command: &proto.Command{
+			Use:       use,
+			ShortDesc: shortDesc,
+			LongDesc:  longDesc,
+		},
+		Run: run,
+	}
+}
+
+// AddSubcommands nests the given commands under the command, so that a plugin
+// can namespace its commands such as `aspect mycompany deploy staging`. A
+// command with subcommands only groups them: its Run is not called and it
+// cannot declare flags.
+func (c *Command) AddSubcommands(subcommands ...*Command) *Command {
+	for _, subcommand := range subcommands {
+		c.subcommands = append(c.subcommands, subcommand)
+		c.Command.Subcommands = append(c.Command.Subcommands, subcommand.Command)
+	}
+	return c
+}
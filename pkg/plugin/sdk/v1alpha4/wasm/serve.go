@@ -0,0 +1,306 @@
+//go:build wasip1
+
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wasm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unsafe"
+
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/proto"
+)
+
+var (
+	impl     Plugin
+	commands = make(map[string]CustomCommandFn)
+
+	// allocations keeps buffers handed out to the host reachable until the
+	// host frees them.
+	allocations = make(map[uint32][]byte)
+
+	// bepStream is the build event stream last passed to SetBEPStream.
+	bepStream *proto.BEPStream
+)
+
+// Serve registers the Plugin implementation to be called by the Core. The
+// plugin must be built with `GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared`
+// and Serve must be called from an init function, since main is not run for
+// reactor modules.
+func Serve(p Plugin) {
+	impl = p
+}
+
+//go:wasmexport aspect_malloc
+func malloc(size uint32) uint32 {
+	if size == 0 {
+		size = 1
+	}
+	buf := make([]byte, size)
+	ptr := uint32(uintptr(unsafe.Pointer(&buf[0])))
+	allocations[ptr] = buf
+	return ptr
+}
+
+//go:wasmexport aspect_free
+func free(ptr uint32) {
+	delete(allocations, ptr)
+}
+
+//go:wasmexport aspect_call
+func call(method uint32, ptr uint32, size uint32) uint64 {
+	req := allocations[ptr][:size]
+	free(ptr)
+
+	res, err := dispatch(Method(method), req)
+	if err != nil {
+		return result(StatusError, []byte(err.Error()))
+	}
+	b, err := protobuf.Marshal(res)
+	if err != nil {
+		return result(StatusError, []byte(err.Error()))
+	}
+	return result(StatusOK, b)
+}
+
+func result(status Status, b []byte) uint64 {
+	ptr := malloc(uint32(len(b) + 1))
+	buf := allocations[ptr]
+	buf[0] = byte(status)
+	copy(buf[1:], b)
+	return Pack(ptr, uint32(len(buf)))
+}
+
+func dispatch(method Method, b []byte) (protobuf.Message, error) {
+	if impl == nil {
+		return nil, errors.New("wasm plugin did not call wasm.Serve")
+	}
+
+	switch method {
+	case MethodSetup:
+		req := &proto.SetupReq{}
+		if err := protobuf.Unmarshal(b, req); err != nil {
+			return nil, err
+		}
+		return &proto.SetupRes{}, impl.Setup(req.Properties, req.Workspace)
+	case MethodBEPEventCallback:
+		req := &proto.BEPEventCallbackReq{}
+		if err := protobuf.Unmarshal(b, req); err != nil {
+			return nil, err
+		}
+		if receiver, ok := impl.(BEPStreamReceiver); ok && req.Stream != nil && !protobuf.Equal(bepStream, req.Stream) {
+			bepStream = req.Stream
+			receiver.SetBEPStream(req.Stream)
+		}
+		return &proto.BEPEventCallbackRes{}, impl.BEPEventCallback(req.Event, req.SequenceNumber, req.InvocationId)
+	case MethodBEPEventTypes:
+		eventTypes, err := impl.BEPEventTypes()
+		if err != nil {
+			return nil, err
+		}
+		return &proto.BEPEventTypesRes{EventTypes: eventTypes}, nil
+	case MethodCustomCommands:
+		customCommands, err := impl.CustomCommands()
+		if err != nil {
+			return nil, err
+		}
+		saved := make(map[string]CustomCommandFn)
+		if err := saveCommands(saved, "", customCommands); err != nil {
+			return nil, err
+		}
+		commands = saved
+		res := &proto.CustomCommandsRes{}
+		for _, cmd := range customCommands {
+			res.Commands = append(res.Commands, cmd.Command)
+		}
+		return res, nil
+	case MethodExecuteCustomCommand:
+		req := &proto.ExecuteCustomCommandReq{}
+		if err := protobuf.Unmarshal(b, req); err != nil {
+			return nil, err
+		}
+		run := commands[req.CustomCommand]
+		if run == nil {
+			return nil, fmt.Errorf("unknown custom command %q", req.CustomCommand)
+		}
+		ctx := ContextWithFlags(context.Background(), req.Flags)
+		err := run(ctx, req.Args, req.BazelStartupArgs)
+		var delegation *BuiltinDelegation
+		if errors.As(err, &delegation) {
+			return &proto.ExecuteCustomCommandRes{DelegateToBuiltin: true, BuiltinArgs: delegation.Args}, nil
+		}
+		return &proto.ExecuteCustomCommandRes{}, err
+	case MethodDaemons:
+		daemons, err := impl.Daemons()
+		if err != nil {
+			return nil, err
+		}
+		return &proto.DaemonsRes{Daemons: daemons}, nil
+	case MethodFiltersOutput:
+		filtersOutput, err := impl.FiltersOutput()
+		if err != nil {
+			return nil, err
+		}
+		return &proto.FiltersOutputRes{FiltersOutput: filtersOutput}, nil
+	case MethodFilterOutputLine:
+		req := &proto.FilterOutputLineReq{}
+		if err := protobuf.Unmarshal(b, req); err != nil {
+			return nil, err
+		}
+		lines, err := impl.FilterOutputLine(req.Stream, req.Line)
+		if err != nil {
+			return nil, err
+		}
+		return &proto.FilterOutputLineRes{Lines: lines}, nil
+	case MethodPostBuildHook:
+		req := &proto.PostBuildHookReq{}
+		if err := protobuf.Unmarshal(b, req); err != nil {
+			return nil, err
+		}
+		result, err := hookResultToProto(impl.PostBuildHook(invocationContext(req.Invocation, req.IsInteractiveMode), hostPromptRunner{}))
+		return &proto.PostBuildHookRes{Result: result}, err
+	case MethodPostTestHook:
+		req := &proto.PostTestHookReq{}
+		if err := protobuf.Unmarshal(b, req); err != nil {
+			return nil, err
+		}
+		result, err := hookResultToProto(impl.PostTestHook(invocationContext(req.Invocation, req.IsInteractiveMode), hostPromptRunner{}))
+		return &proto.PostTestHookRes{Result: result}, err
+	case MethodPostRunHook:
+		req := &proto.PostRunHookReq{}
+		if err := protobuf.Unmarshal(b, req); err != nil {
+			return nil, err
+		}
+		result, err := hookResultToProto(impl.PostRunHook(invocationContext(req.Invocation, req.IsInteractiveMode), hostPromptRunner{}))
+		return &proto.PostRunHookRes{Result: result}, err
+	case MethodPropertiesSchema:
+		properties, err := impl.PropertiesSchema()
+		if err != nil {
+			return nil, err
+		}
+		return &proto.PropertiesSchemaRes{Properties: properties}, nil
+	case MethodRewriteArgs:
+		req := &proto.RewriteArgsReq{}
+		if err := protobuf.Unmarshal(b, req); err != nil {
+			return nil, err
+		}
+		args, err := impl.RewriteArgs(req.Command, req.Args)
+		if err != nil {
+			return nil, err
+		}
+		return &proto.RewriteArgsRes{Args: args}, nil
+	}
+
+	return nil, fmt.Errorf("unknown wasm plugin method %d", method)
+}
+
+//go:wasmimport aspect prompt_run
+func promptRun(ptr uint32, size uint32) uint32
+
+//go:wasmimport aspect prompt_result
+func promptResult(ptr uint32)
+
+// hostPromptRunner satisfies PromptRunner by calling into the Core.
+type hostPromptRunner struct{}
+
+func (hostPromptRunner) Run(req *proto.PromptRunReq) (string, error) {
+	b, err := protobuf.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	size := uint32(len(b))
+	if size == 0 {
+		// An empty request still needs a valid pointer.
+		b = []byte{0}
+	}
+
+	size = promptRun(uint32(uintptr(unsafe.Pointer(&b[0]))), size)
+	out := make([]byte, size)
+	if size > 0 {
+		promptResult(uint32(uintptr(unsafe.Pointer(&out[0]))))
+	}
+
+	res := &proto.PromptRunRes{}
+	if err := protobuf.Unmarshal(out, res); err != nil {
+		return "", err
+	}
+	if res.Error != nil && res.Error.Happened {
+		return "", errors.New(res.Error.Message)
+	}
+	return res.Result, nil
+}
+
+// Secret satisfies PromptRunner with a masked prompt.
+func (r hostPromptRunner) Secret(label string) (string, error) {
+	return r.Run(&proto.PromptRunReq{Label: label, Mask: "*", HideEntered: true})
+}
+
+// Confirm satisfies PromptRunner with a confirmation prompt. Answering no is not
+// an error.
+func (r hostPromptRunner) Confirm(label string, defaultYes bool) (bool, error) {
+	req := &proto.PromptRunReq{Label: label, IsConfirm: true}
+	if defaultYes {
+		req.Default = "y"
+	}
+	_, err := r.Run(req)
+	if err != nil && err.Error() == errPromptAborted {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// errPromptAborted is the empty message of promptui.ErrAbort, which is
+// returned when the user answers no to a confirmation prompt.
+const errPromptAborted = ""
+
+// invocationContext returns the invocation context of a hook request. Only
+// is_interactive_mode is set by a Core that predates the invocation context.
+func invocationContext(invocation *proto.InvocationContext, isInteractiveMode bool) *proto.InvocationContext {
+	if invocation == nil {
+		return &proto.InvocationContext{IsInteractiveMode: isInteractiveMode}
+	}
+	return invocation
+}
+
+// saveCommands saves the Run functions of the commands keyed by their path of
+// command names, e.g. "mycompany deploy staging", which the Core executes them
+// by.
+func saveCommands(saved map[string]CustomCommandFn, parent string, commands []*Command) error {
+	for _, cmd := range commands {
+		path := strings.SplitN(cmd.Use, " ", 2)[0]
+		if parent != "" {
+			path = parent + " " + path
+		}
+		if _, exists := saved[path]; exists {
+			return fmt.Errorf("command %q is declared more than once by plugin", path)
+		}
+		if len(cmd.subcommands) > 0 {
+			saved[path] = nil
+			if err := saveCommands(saved, path, cmd.subcommands); err != nil {
+				return err
+			}
+			continue
+		}
+		saved[path] = cmd.Run
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package plugin
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/aspect-build/aspect-cli-legacy/bazel/analysis"
+	"github.com/aspect-build/aspect-cli-legacy/bazel/query"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha5/proto"
+)
+
+// Bazel connects the Plugin to the Bazel service served by the Core and passes
+// it to the Plugin SetBazel implementation, if any. The connection stays open
+// for as long as the Plugin runs.
+func (m *GRPCServer) Bazel(
+	ctx context.Context,
+	req *proto.BazelReq,
+) (*proto.BazelRes, error) {
+	// The Core waits for the connection either way.
+	conn, err := m.broker.Dial(req.BrokerId)
+	if err != nil {
+		return nil, err
+	}
+	receiver, ok := m.Impl.(BazelReceiver)
+	if !ok {
+		conn.Close()
+		return &proto.BazelRes{}, nil
+	}
+	receiver.SetBazel(&bazelClient{client: proto.NewBazelClient(conn)})
+	return &proto.BazelRes{}, nil
+}
+
+// bazelClient runs the queries of the Plugin on the Core.
+type bazelClient struct {
+	client proto.BazelClient
+}
+
+var _ Bazel = (*bazelClient)(nil)
+
+func (b *bazelClient) Query(expression string, flags ...string) (*query.QueryResult, error) {
+	res, err := b.client.Query(context.Background(), &proto.QueryReq{
+		Expression: expression,
+		Flags:      flags,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.Result, nil
+}
+
+func (b *bazelClient) CQuery(expression string, flags ...string) (*analysis.CqueryResult, error) {
+	res, err := b.client.CQuery(context.Background(), &proto.QueryReq{
+		Expression: expression,
+		Flags:      flags,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.Result, nil
+}
+
+// StartBazel serves the Bazel service backed by bzl to the Plugin for as long
+// as it runs. Plugins built with a version of the SDK that doesn't run queries
+// are left alone.
+func (m *GRPCClient) StartBazel(bzl Bazel) error {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	serverFunc := func(opts []grpc.ServerOption) *grpc.Server {
+		server := grpc.NewServer(opts...)
+		proto.RegisterBazelServer(server, &bazelServer{impl: bzl})
+		defer wg.Done()
+		return server
+	}
+	brokerID := m.broker.NextId()
+	go m.broker.AcceptAndServe(brokerID, serverFunc)
+	wg.Wait()
+
+	_, err := m.client.Bazel(context.Background(), &proto.BazelReq{BrokerId: brokerID})
+	if status.Code(err) == codes.Unimplemented {
+		return nil
+	}
+	return err
+}
+
+// bazelServer implements the gRPC server that runs on the Core and runs the
+// queries of the Plugin.
+type bazelServer struct {
+	impl Bazel
+}
+
+func (s *bazelServer) Query(ctx context.Context, req *proto.QueryReq) (*proto.QueryRes, error) {
+	result, err := s.impl.Query(req.Expression, req.Flags...)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.QueryRes{Result: result}, nil
+}
+
+func (s *bazelServer) CQuery(ctx context.Context, req *proto.QueryReq) (*proto.CQueryRes, error) {
+	result, err := s.impl.CQuery(req.Expression, req.Flags...)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.CQueryRes{Result: result}, nil
+}
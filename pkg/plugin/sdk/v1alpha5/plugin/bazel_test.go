@@ -0,0 +1,112 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/aspect-build/aspect-cli-legacy/bazel/analysis"
+	"github.com/aspect-build/aspect-cli-legacy/bazel/query"
+)
+
+type bazelPlugin struct {
+	Base
+	bazel Bazel
+}
+
+func (p *bazelPlugin) SetBazel(bazel Bazel) {
+	p.bazel = bazel
+}
+
+// fakeBazel answers queries with a single target named after the expression
+// and flags of the query.
+type fakeBazel struct{}
+
+func (fakeBazel) Query(expression string, flags ...string) (*query.QueryResult, error) {
+	if expression == "" {
+		return nil, fmt.Errorf("failed to run query: no expression")
+	}
+	return &query.QueryResult{
+		Target: []*query.Target{{
+			Type: query.Target_RULE.Enum(),
+			Rule: &query.Rule{
+				Name:      protobuf.String(expression + " " + strings.Join(flags, " ")),
+				RuleClass: protobuf.String("go_library"),
+			},
+		}},
+	}, nil
+}
+
+func (fakeBazel) CQuery(expression string, flags ...string) (*analysis.CqueryResult, error) {
+	return &analysis.CqueryResult{
+		Results: []*analysis.ConfiguredTarget{{
+			Target: &query.Target{
+				Type: query.Target_RULE.Enum(),
+				Rule: &query.Rule{
+					Name:      protobuf.String(expression + " " + strings.Join(flags, " ")),
+					RuleClass: protobuf.String("go_library"),
+				},
+			},
+		}},
+	}, nil
+}
+
+func TestBazel(t *testing.T) {
+	t.Run("runs the queries of the plugin on the Core", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		impl := &bazelPlugin{}
+		c := dispense(t, impl)
+
+		g.Expect(c.StartBazel(fakeBazel{})).To(Succeed())
+		g.Expect(impl.bazel).ToNot(BeNil())
+
+		result, err := impl.bazel.Query("deps(//foo)", "--keep_going")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.Target).To(HaveLen(1))
+		g.Expect(result.Target[0].Rule.GetName()).To(Equal("deps(//foo) --keep_going"))
+
+		cresult, err := impl.bazel.CQuery("//foo", "--config=release")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(cresult.Results).To(HaveLen(1))
+		g.Expect(cresult.Results[0].Target.Rule.GetName()).To(Equal("//foo --config=release"))
+	})
+
+	t.Run("returns the errors of the queries to the plugin", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		impl := &bazelPlugin{}
+		c := dispense(t, impl)
+
+		g.Expect(c.StartBazel(fakeBazel{})).To(Succeed())
+
+		_, err := impl.bazel.Query("")
+		g.Expect(err).To(MatchError(ContainSubstring("failed to run query: no expression")))
+	})
+
+	t.Run("leaves plugins that don't run queries alone", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		c := dispense(t, &Base{})
+
+		g.Expect(c.StartBazel(fakeBazel{})).To(Succeed())
+	})
+}
@@ -0,0 +1,235 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// grpc.go hides all the complexity of doing the gRPC calls between the aspect
+// Core and a Plugin implementation by providing simple abstractions from the
+// point of view of Plugin maintainers. Every call but the delivery of build
+// events is served by the v1alpha4 Plugin service.
+package plugin
+
+import (
+	"context"
+	"math"
+	"sync"
+
+	goplugin "github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/plugin"
+	v1alpha4proto "github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/proto"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha5/proto"
+)
+
+// bepEventStreamBufferSize is how many build events are queued for a Plugin
+// before BEPEventCallback blocks.
+const bepEventStreamBufferSize = 1024
+
+// GRPCPlugin represents a Plugin that communicates over gRPC.
+type GRPCPlugin struct {
+	goplugin.Plugin
+	Impl Plugin
+}
+
+// GRPCServer registers an instance of the GRPCServer in the Plugin binary
+// along with the v1alpha4 Plugin service.
+func (p *GRPCPlugin) GRPCServer(broker *goplugin.GRPCBroker, s *grpc.Server) error {
+	v1alpha4Plugin := &plugin.GRPCPlugin{Impl: &v1alpha4Plugin{Plugin: p.Impl}}
+	if err := v1alpha4Plugin.GRPCServer(broker, s); err != nil {
+		return err
+	}
+	proto.RegisterPluginServer(s, &GRPCServer{
+		Impl:   p.Impl,
+		broker: broker,
+	})
+	return nil
+}
+
+// GRPCClient returns a client to perform the RPC calls to the Plugin
+// instance from the Core.
+func (p *GRPCPlugin) GRPCClient(ctx context.Context, broker *goplugin.GRPCBroker, c *grpc.ClientConn) (any, error) {
+	v1alpha4Client, err := (&plugin.GRPCPlugin{}).GRPCClient(ctx, broker, c)
+	if err != nil {
+		return nil, err
+	}
+	return &GRPCClient{
+		GRPCClient: v1alpha4Client.(*plugin.GRPCClient),
+		client:     proto.NewPluginClient(c),
+		broker:     broker,
+	}, nil
+}
+
+// v1alpha4Plugin serves a Plugin through the v1alpha4 Plugin service. Build
+// events are never sent to its BEPEventCallback.
+type v1alpha4Plugin struct {
+	Plugin
+}
+
+func (*v1alpha4Plugin) BEPEventCallback(*buildeventstream.BuildEvent, int64, string) error {
+	return nil
+}
+
+// GRPCServer implements the gRPC server that runs on the Plugin instances.
+type GRPCServer struct {
+	Impl      Plugin
+	broker    *goplugin.GRPCBroker
+	bepStream plugin.BEPStreamTracker
+}
+
+// BEPEvents streams the build events from the Core to the Plugin BEPEvents
+// implementation.
+func (m *GRPCServer) BEPEvents(
+	ctx context.Context,
+	req *proto.BEPEventsReq,
+) (*proto.BEPEventsRes, error) {
+	conn, err := m.broker.Dial(req.BrokerId)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Bazel doesn't seem to set a maximum build event size, therefore we match
+	// the default send message size for Go, which should be enough for all
+	// events sent by Bazel (roughly 2.14GB).
+	stream, err := proto.NewBEPEventStreamClient(conn).Stream(ctx, &proto.BEPEventStreamReq{}, grpc.MaxCallRecvMsgSize(math.MaxInt32))
+	if err != nil {
+		return nil, err
+	}
+	m.bepStream.Update(m.Impl, &v1alpha4proto.BEPStream{
+		BuildId:      req.BuildId,
+		InvocationId: req.InvocationId,
+		Command:      req.Command,
+	})
+	return &proto.BEPEventsRes{}, m.Impl.BEPEvents(req.InvocationId, stream)
+}
+
+// GRPCClient implements the gRPC client that is used by the Core to communicate
+// with the Plugin instances. It satisfies the v1alpha4 Plugin interface so that
+// the Core runs plugins of both SDK versions alike.
+type GRPCClient struct {
+	*plugin.GRPCClient
+	client proto.PluginClient
+	broker *goplugin.GRPCBroker
+
+	mu     sync.Mutex
+	stream *bepEventStream
+}
+
+var _ plugin.Plugin = (*GRPCClient)(nil)
+var _ plugin.BEPStreamCallback = (*GRPCClient)(nil)
+
+// BEPEventCallback satisfies Plugin.BEPEventCallback for callers that only
+// know the invocation ID of the build event stream.
+func (m *GRPCClient) BEPEventCallback(event *buildeventstream.BuildEvent, sn int64, invocationId string) error {
+	return m.BEPStreamEventCallback(event, sn, &v1alpha4proto.BEPStream{InvocationId: invocationId})
+}
+
+// BEPStreamEventCallback queues the build event on the stream of the current
+// invocation, which is started on its first event. After the last event of the
+// invocation it waits for the Plugin BEPEvents to return and returns its error.
+func (m *GRPCClient) BEPStreamEventCallback(event *buildeventstream.BuildEvent, sn int64, stream *v1alpha4proto.BEPStream) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stream == nil {
+		m.stream = startBEPEventStream(m.client, m.broker, stream)
+	}
+	m.stream.send(&proto.BEPEvent{Event: event, SequenceNumber: sn})
+
+	if event.GetLastMessage() {
+		stream := m.stream
+		m.stream = nil
+		return stream.close()
+	}
+	return nil
+}
+
+// bepEventStream implements the gRPC server that runs on the Core for the
+// duration of a bazel invocation and streams its build events to the Plugin.
+type bepEventStream struct {
+	events chan *proto.BEPEvent
+	// done is closed once the Plugin BEPEvents returned.
+	done   chan struct{}
+	err    error
+	server *grpc.Server
+}
+
+func startBEPEventStream(client proto.PluginClient, broker *goplugin.GRPCBroker, stream *v1alpha4proto.BEPStream) *bepEventStream {
+	s := &bepEventStream{
+		events: make(chan *proto.BEPEvent, bepEventStreamBufferSize),
+		done:   make(chan struct{}),
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	serverFunc := func(opts []grpc.ServerOption) *grpc.Server {
+		s.server = grpc.NewServer(opts...)
+		proto.RegisterBEPEventStreamServer(s.server, s)
+		defer wg.Done()
+		return s.server
+	}
+	brokerID := broker.NextId()
+	go broker.AcceptAndServe(brokerID, serverFunc)
+	wg.Wait()
+
+	go func() {
+		_, err := client.BEPEvents(context.Background(), &proto.BEPEventsReq{
+			BrokerId:     brokerID,
+			InvocationId: stream.GetInvocationId(),
+			BuildId:      stream.GetBuildId(),
+			Command:      stream.GetCommand(),
+		})
+		s.err = err
+		close(s.done)
+	}()
+
+	return s
+}
+
+// Stream sends the queued build events to the Plugin until the stream is
+// closed.
+func (s *bepEventStream) Stream(req *proto.BEPEventStreamReq, srv proto.BEPEventStream_StreamServer) error {
+	for {
+		select {
+		case event, ok := <-s.events:
+			if !ok {
+				return nil
+			}
+			if err := srv.Send(event); err != nil {
+				return err
+			}
+		case <-srv.Context().Done():
+			return srv.Context().Err()
+		}
+	}
+}
+
+// send queues the build event. Events are dropped once the Plugin stopped
+// receiving them.
+func (s *bepEventStream) send(event *proto.BEPEvent) {
+	select {
+	case s.events <- event:
+	case <-s.done:
+	}
+}
+
+// close ends the stream and returns the error of the Plugin BEPEvents once it
+// returned.
+func (s *bepEventStream) close() error {
+	close(s.events)
+	<-s.done
+	s.server.Stop()
+	return s.err
+}
@@ -0,0 +1,255 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/manifoldco/promptui"
+	. "github.com/onsi/gomega"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils/prompt"
+	v1alpha4proto "github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/proto"
+)
+
+type recordingPlugin struct {
+	Base
+	invocationId string
+	events       []int64
+	err          error
+}
+
+func (p *recordingPlugin) BEPEvents(invocationId string, stream BEPEventStream) error {
+	p.invocationId = invocationId
+	p.events = nil
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			return p.err
+		}
+		if err != nil {
+			return err
+		}
+		p.events = append(p.events, event.SequenceNumber)
+	}
+}
+
+type streamPlugin struct {
+	recordingPlugin
+	stream *v1alpha4proto.BEPStream
+}
+
+func (p *streamPlugin) SetBEPStream(stream *v1alpha4proto.BEPStream) {
+	p.stream = stream
+}
+
+func dispense(t *testing.T, impl Plugin) *GRPCClient {
+	client, server := goplugin.TestPluginGRPCConn(t, false, map[string]goplugin.Plugin{
+		"aspectplugin": &GRPCPlugin{Impl: impl},
+	})
+	t.Cleanup(func() {
+		// Closing the client asks the server to stop through the go-plugin
+		// controller, so stopping it here as well would race with that.
+		client.Close()
+		select {
+		case <-server.DoneCh:
+		case <-time.After(5 * time.Second):
+			t.Error("the plugin server did not stop after the client was closed")
+		}
+	})
+
+	raw, err := client.Dispense("aspectplugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw.(*GRPCClient)
+}
+
+func sendInvocation(c *GRPCClient, invocationId string, n int) error {
+	for sn := int64(1); sn <= int64(n); sn++ {
+		event := &buildeventstream.BuildEvent{LastMessage: sn == int64(n)}
+		if err := c.BEPEventCallback(event, sn, invocationId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestBEPEvents(t *testing.T) {
+	t.Run("streams the build events of each invocation in order", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		impl := &recordingPlugin{}
+		c := dispense(t, impl)
+
+		g.Expect(sendInvocation(c, "first", 3)).To(Succeed())
+		g.Expect(impl.invocationId).To(Equal("first"))
+		g.Expect(impl.events).To(Equal([]int64{1, 2, 3}))
+
+		g.Expect(sendInvocation(c, "second", 2)).To(Succeed())
+		g.Expect(impl.invocationId).To(Equal("second"))
+		g.Expect(impl.events).To(Equal([]int64{1, 2}))
+	})
+
+	t.Run("passes the build event stream to plugins that receive it", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		impl := &streamPlugin{}
+		c := dispense(t, impl)
+
+		stream := &v1alpha4proto.BEPStream{BuildId: "build", InvocationId: "invocation", Command: "test"}
+		event := &buildeventstream.BuildEvent{LastMessage: true}
+		g.Expect(c.BEPStreamEventCallback(event, 1, stream)).To(Succeed())
+		g.Expect(impl.stream.GetBuildId()).To(Equal("build"))
+		g.Expect(impl.stream.GetInvocationId()).To(Equal("invocation"))
+		g.Expect(impl.stream.GetCommand()).To(Equal("test"))
+		g.Expect(impl.invocationId).To(Equal("invocation"))
+	})
+
+	t.Run("returns the error of the plugin after the last build event", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		impl := &recordingPlugin{err: errors.New("upload failed")}
+		c := dispense(t, impl)
+
+		err := sendInvocation(c, "invocation", 3)
+		g.Expect(err).To(MatchError(ContainSubstring("upload failed")))
+	})
+
+	t.Run("drops build events once the plugin stopped receiving them", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		c := dispense(t, &Base{})
+
+		g.Expect(sendInvocation(c, "invocation", bepEventStreamBufferSize*2)).To(Succeed())
+	})
+
+	t.Run("serves the other calls through the v1alpha4 service", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		c := dispense(t, &Base{})
+
+		args, err := c.RewriteArgs("build", []string{"//..."})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(args).To(Equal([]string{"//..."}))
+	})
+}
+
+type hookPlugin struct {
+	Base
+	err error
+}
+
+func (p *hookPlugin) PostBuildHook(*v1alpha4proto.InvocationContext, prompt.PromptRunner) error {
+	return p.err
+}
+
+func TestHookResult(t *testing.T) {
+	t.Run("returns the hook result of the plugin", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		c := dispense(t, &hookPlugin{err: ExitWithCode(42, "policy violation")})
+
+		err := c.PostBuildHook(&v1alpha4proto.InvocationContext{}, nil)
+		var result *HookResult
+		g.Expect(errors.As(err, &result)).To(BeTrue())
+		g.Expect(*result).To(Equal(HookResult{
+			Outcome:  v1alpha4proto.HookResult_EXIT_CODE,
+			ExitCode: 42,
+			Message:  "policy violation",
+		}))
+	})
+
+	t.Run("returns other errors of the plugin as they are", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		c := dispense(t, &hookPlugin{err: errors.New("plugin error")})
+
+		err := c.PostBuildHook(&v1alpha4proto.InvocationContext{}, nil)
+		g.Expect(err).To(MatchError(ContainSubstring("plugin error")))
+		var result *HookResult
+		g.Expect(errors.As(err, &result)).To(BeFalse())
+	})
+}
+
+// scriptedPromptRunner answers the prompts of a plugin on the Core.
+type scriptedPromptRunner struct {
+	prompts []string
+}
+
+func (r *scriptedPromptRunner) Run(prompt promptui.Prompt) (string, error) {
+	r.prompts = append(r.prompts, fmt.Sprintf("run %v mask=%q", prompt.Label, prompt.Mask))
+	return "hunter2", nil
+}
+
+func (r *scriptedPromptRunner) Select(label string, items []string, defaultIndex int) (int, error) {
+	r.prompts = append(r.prompts, fmt.Sprintf("select %s %v %d", label, items, defaultIndex))
+	return 2, nil
+}
+
+func (r *scriptedPromptRunner) MultiSelect(label string, items []string, selected []int) ([]int, error) {
+	r.prompts = append(r.prompts, fmt.Sprintf("multi-select %s %v %v", label, items, selected))
+	return nil, promptui.ErrInterrupt
+}
+
+func (r *scriptedPromptRunner) Secret(label string) (string, error) {
+	return "", errors.New("secrets are prompted through Run")
+}
+
+func (r *scriptedPromptRunner) Confirm(label string, defaultYes bool) (bool, error) {
+	r.prompts = append(r.prompts, fmt.Sprintf("confirm %s %t", label, defaultYes))
+	return true, nil
+}
+
+type promptingPlugin struct {
+	Base
+	answers []any
+}
+
+func (p *promptingPlugin) PostBuildHook(_ *v1alpha4proto.InvocationContext, promptRunner prompt.PromptRunner) error {
+	env, err := promptRunner.Select("Environment", []string{"dev", "staging", "prod"}, 1)
+	if err != nil {
+		return err
+	}
+	_, err = promptRunner.MultiSelect("Regions", []string{"eu", "us"}, []int{1})
+	secret, _ := promptRunner.Secret("Token")
+	confirmed, _ := promptRunner.Confirm("Deploy?", true)
+	p.answers = []any{env, err, secret, confirmed}
+	return nil
+}
+
+func TestPrompts(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	impl := &promptingPlugin{}
+	c := dispense(t, impl)
+	promptRunner := &scriptedPromptRunner{}
+
+	g.Expect(c.PostBuildHook(&v1alpha4proto.InvocationContext{}, promptRunner)).To(Succeed())
+	g.Expect(promptRunner.prompts).To(Equal([]string{
+		"select Environment [dev staging prod] 1",
+		"multi-select Regions [eu us] [1]",
+		`run Token mask='*'`,
+		"confirm Deploy? true",
+	}))
+	g.Expect(impl.answers).To(Equal([]any{2, promptui.ErrInterrupt, "hunter2", true}))
+}
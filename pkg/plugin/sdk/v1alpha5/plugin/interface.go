@@ -0,0 +1,215 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package plugin
+
+import (
+	"time"
+
+	"github.com/aspect-build/aspect-cli-legacy/bazel/analysis"
+	"github.com/aspect-build/aspect-cli-legacy/bazel/query"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils/prompt"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/plugin"
+	v1alpha4proto "github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/proto"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha5/proto"
+)
+
+// Plugin determines how an aspect Plugin should be implemented. It is the
+// v1alpha4 Plugin, except that build events are streamed to BEPEvents rather
+// than passed to BEPEventCallback one call at a time.
+type Plugin interface {
+	// BEPEvents is called once per bazel invocation and receives the build
+	// events of the invocation from the stream until Recv returns io.EOF. The
+	// Core only sends events as fast as the plugin receives them, so a plugin
+	// may take its time processing an event without blocking the build.
+	BEPEvents(invocationId string, stream BEPEventStream) error
+	// BEPEventTypes returns the types of the build events sent to BEPEvents,
+	// which are the names of the fields of the BuildEventId oneof, e.g.
+	// "test_result" or "target_completed". All events are sent when it returns
+	// none.
+	BEPEventTypes() ([]string, error)
+	CustomCommands() ([]*Command, error)
+	// Daemons returns the long-lived processes the plugin runs in the
+	// background. The CLI starts each daemon that isn't running yet once the
+	// plugin is set up, and reuses it across invocations.
+	Daemons() ([]*v1alpha4proto.Daemon, error)
+	// FiltersOutput reports whether the plugin filters the output of bazel. It
+	// is called before the bazel build, test, coverage or run command runs.
+	FiltersOutput() (bool, error)
+	// FilterOutputLine is called with each line bazel writes to stdout or
+	// stderr, without its trailing newline, when FiltersOutput returns true. It
+	// returns the lines printed in its place: none to suppress the line, or
+	// rewritten or additional lines.
+	FilterOutputLine(stream v1alpha4proto.OutputStream, line string) ([]string, error)
+	// PostBuildHook, PostTestHook and PostRunHook are called after the bazel
+	// build, test or coverage and run commands with the context of their
+	// invocation.
+	PostBuildHook(
+		invocation *v1alpha4proto.InvocationContext,
+		promptRunner prompt.PromptRunner,
+	) error
+	PostTestHook(
+		invocation *v1alpha4proto.InvocationContext,
+		promptRunner prompt.PromptRunner,
+	) error
+	PostRunHook(
+		invocation *v1alpha4proto.InvocationContext,
+		promptRunner prompt.PromptRunner,
+	) error
+	// PropertiesSchema returns the properties the plugin accepts in its config.
+	// `aspect plugin doctor` validates the configured properties against it. The
+	// properties are not validated when it returns none.
+	PropertiesSchema() ([]*v1alpha4proto.Property, error)
+	// RewriteArgs is called before the bazel build, test, coverage or run command
+	// is invoked and returns the arguments to run it with. Plugins are called in
+	// the order they are configured, each receiving the args returned by the
+	// previous one.
+	RewriteArgs(command string, args []string) ([]string, error)
+	Setup(config *SetupConfig) error
+}
+
+// Telemetry records metrics of a Plugin in the telemetry session of the CLI,
+// next to the traces of the CLI itself. Metrics are dropped when telemetry is
+// not configured.
+type Telemetry interface {
+	// AddCounter adds value to the named counter.
+	AddCounter(name string, value float64, attributes map[string]string) error
+	// RecordTiming records that the named operation started at start and took
+	// duration.
+	RecordTiming(name string, start time.Time, duration time.Duration, attributes map[string]string) error
+}
+
+// TelemetryReceiver is implemented by plugins that report metrics.
+// SetTelemetry is called before Setup with the Telemetry the plugin reports
+// its metrics to for as long as it runs.
+type TelemetryReceiver interface {
+	SetTelemetry(telemetry Telemetry)
+}
+
+// Bazel runs bazel queries in the workspace of the CLI, with the bazel version
+// and startup flags of the CLI. A query waits for the bazel command the CLI is
+// running, if any, to release the bazel server.
+type Bazel interface {
+	// Query runs `bazel query` with the given expression and flags.
+	Query(expression string, flags ...string) (*query.QueryResult, error)
+	// CQuery runs `bazel cquery` with the given expression and flags.
+	CQuery(expression string, flags ...string) (*analysis.CqueryResult, error)
+}
+
+// BazelReceiver is implemented by plugins that run bazel queries. SetBazel is
+// called before Setup with the Bazel the plugin runs its queries with for as
+// long as it runs.
+type BazelReceiver interface {
+	SetBazel(bazel Bazel)
+}
+
+// BEPEventStream is the stream of the build events of a bazel invocation.
+type BEPEventStream interface {
+	// Recv returns the next build event. It returns io.EOF after the last one.
+	Recv() (*proto.BEPEvent, error)
+}
+
+// The types shared with the v1alpha4 SDK.
+type (
+	SetupConfig       = plugin.SetupConfig
+	AspectPluginFile  = plugin.AspectPluginFile
+	Workspace         = plugin.Workspace
+	Command           = plugin.Command
+	CustomCommandFn   = plugin.CustomCommandFn
+	BuiltinDelegation = plugin.BuiltinDelegation
+	Flags             = plugin.Flags
+	HookResult        = plugin.HookResult
+	BEPStreamReceiver = plugin.BEPStreamReceiver
+	NamedSets         = plugin.NamedSets
+)
+
+var (
+	NewSetupConfig     = plugin.NewSetupConfig
+	NewCommand         = plugin.NewCommand
+	ContextWithFlags   = plugin.ContextWithFlags
+	FlagsFromContext   = plugin.FlagsFromContext
+	FailCommand        = plugin.FailCommand
+	ExitWithCode       = plugin.ExitWithCode
+	DowngradeToWarning = plugin.DowngradeToWarning
+	DelegateToBuiltin  = plugin.DelegateToBuiltin
+	NewNamedSets       = plugin.NewNamedSets
+)
+
+// Base satisfies the Plugin interface. For plugins that only implement a subset
+// of the Plugin interface, using this as a base will give the advantage of not
+// needing to implement the empty methods.
+type Base struct{}
+
+var _ Plugin = (*Base)(nil)
+
+// Setup satisfies Plugin.Setup.
+func (*Base) Setup(*SetupConfig) error {
+	return nil
+}
+
+// BEPEvents satisfies Plugin.BEPEvents.
+func (*Base) BEPEvents(string, BEPEventStream) error {
+	return nil
+}
+
+// BEPEventTypes satisfies Plugin.BEPEventTypes.
+func (*Base) BEPEventTypes() ([]string, error) {
+	return nil, nil
+}
+
+// CustomCommands satisfies Plugin.CustomCommands.
+func (*Base) CustomCommands() ([]*Command, error) {
+	return nil, nil
+}
+
+// Daemons satisfies Plugin.Daemons.
+func (*Base) Daemons() ([]*v1alpha4proto.Daemon, error) {
+	return nil, nil
+}
+
+// FiltersOutput satisfies Plugin.FiltersOutput.
+func (*Base) FiltersOutput() (bool, error) {
+	return false, nil
+}
+
+// FilterOutputLine satisfies Plugin.FilterOutputLine.
+func (*Base) FilterOutputLine(_ v1alpha4proto.OutputStream, line string) ([]string, error) {
+	return []string{line}, nil
+}
+
+// PostBuildHook satisfies Plugin.PostBuildHook.
+func (*Base) PostBuildHook(*v1alpha4proto.InvocationContext, prompt.PromptRunner) error {
+	return nil
+}
+
+// PostTestHook satisfies Plugin.PostTestHook.
+func (*Base) PostTestHook(*v1alpha4proto.InvocationContext, prompt.PromptRunner) error {
+	return nil
+}
+
+// PostRunHook satisfies Plugin.PostRunHook.
+func (*Base) PostRunHook(*v1alpha4proto.InvocationContext, prompt.PromptRunner) error {
+	return nil
+}
+
+// PropertiesSchema satisfies Plugin.PropertiesSchema.
+func (*Base) PropertiesSchema() ([]*v1alpha4proto.Property, error) {
+	return nil, nil
+}
+
+// RewriteArgs satisfies Plugin.RewriteArgs.
+func (*Base) RewriteArgs(_ string, args []string) ([]string, error) {
+	return args, nil
+}
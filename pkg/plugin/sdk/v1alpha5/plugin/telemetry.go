@@ -0,0 +1,114 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package plugin
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha5/proto"
+)
+
+// Telemetry connects the Plugin to the Telemetry service served by the Core
+// and passes it to the Plugin SetTelemetry implementation, if any. The
+// connection stays open for as long as the Plugin runs.
+func (m *GRPCServer) Telemetry(
+	ctx context.Context,
+	req *proto.TelemetryReq,
+) (*proto.TelemetryRes, error) {
+	// The Core waits for the connection either way.
+	conn, err := m.broker.Dial(req.BrokerId)
+	if err != nil {
+		return nil, err
+	}
+	receiver, ok := m.Impl.(TelemetryReceiver)
+	if !ok {
+		conn.Close()
+		return &proto.TelemetryRes{}, nil
+	}
+	receiver.SetTelemetry(&telemetryClient{client: proto.NewTelemetryClient(conn)})
+	return &proto.TelemetryRes{}, nil
+}
+
+// telemetryClient reports the metrics of the Plugin to the Core.
+type telemetryClient struct {
+	client proto.TelemetryClient
+}
+
+var _ Telemetry = (*telemetryClient)(nil)
+
+func (t *telemetryClient) AddCounter(name string, value float64, attributes map[string]string) error {
+	_, err := t.client.AddCounter(context.Background(), &proto.AddCounterReq{
+		Name:       name,
+		Value:      value,
+		Attributes: attributes,
+	})
+	return err
+}
+
+func (t *telemetryClient) RecordTiming(name string, start time.Time, duration time.Duration, attributes map[string]string) error {
+	_, err := t.client.RecordTiming(context.Background(), &proto.RecordTimingReq{
+		Name:       name,
+		Start:      timestamppb.New(start),
+		Duration:   durationpb.New(duration),
+		Attributes: attributes,
+	})
+	return err
+}
+
+// StartTelemetry serves the Telemetry service backed by telemetry to the
+// Plugin for as long as it runs. Plugins built with a version of the SDK that
+// doesn't report metrics are left alone.
+func (m *GRPCClient) StartTelemetry(telemetry Telemetry) error {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	serverFunc := func(opts []grpc.ServerOption) *grpc.Server {
+		server := grpc.NewServer(opts...)
+		proto.RegisterTelemetryServer(server, &telemetryServer{impl: telemetry})
+		defer wg.Done()
+		return server
+	}
+	brokerID := m.broker.NextId()
+	go m.broker.AcceptAndServe(brokerID, serverFunc)
+	wg.Wait()
+
+	_, err := m.client.Telemetry(context.Background(), &proto.TelemetryReq{BrokerId: brokerID})
+	if status.Code(err) == codes.Unimplemented {
+		return nil
+	}
+	return err
+}
+
+// telemetryServer implements the gRPC server that runs on the Core and records
+// the metrics reported by the Plugin.
+type telemetryServer struct {
+	impl Telemetry
+}
+
+func (s *telemetryServer) AddCounter(ctx context.Context, req *proto.AddCounterReq) (*proto.AddCounterRes, error) {
+	return &proto.AddCounterRes{}, s.impl.AddCounter(req.Name, req.Value, req.Attributes)
+}
+
+func (s *telemetryServer) RecordTiming(ctx context.Context, req *proto.RecordTimingReq) (*proto.RecordTimingRes, error) {
+	return &proto.RecordTimingRes{}, s.impl.RecordTiming(req.Name, req.Start.AsTime(), req.Duration.AsDuration(), req.Attributes)
+}
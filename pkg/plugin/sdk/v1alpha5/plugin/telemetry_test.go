@@ -0,0 +1,93 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package plugin
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+type telemetryPlugin struct {
+	Base
+	telemetry Telemetry
+}
+
+func (p *telemetryPlugin) SetTelemetry(telemetry Telemetry) {
+	p.telemetry = telemetry
+}
+
+type recordedTiming struct {
+	name       string
+	start      time.Time
+	duration   time.Duration
+	attributes map[string]string
+}
+
+type recordingTelemetry struct {
+	mu       sync.Mutex
+	counters map[string]float64
+	timings  []recordedTiming
+}
+
+func (t *recordingTelemetry) AddCounter(name string, value float64, attributes map[string]string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.counters[name] += value
+	return nil
+}
+
+func (t *recordingTelemetry) RecordTiming(name string, start time.Time, duration time.Duration, attributes map[string]string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.timings = append(t.timings, recordedTiming{name, start, duration, attributes})
+	return nil
+}
+
+func TestTelemetry(t *testing.T) {
+	t.Run("records the metrics reported by the plugin on the Core", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		impl := &telemetryPlugin{}
+		c := dispense(t, impl)
+		recorder := &recordingTelemetry{counters: map[string]float64{}}
+
+		g.Expect(c.StartTelemetry(recorder)).To(Succeed())
+		g.Expect(impl.telemetry).ToNot(BeNil())
+
+		start := time.Unix(1700000000, 0)
+		g.Expect(impl.telemetry.AddCounter("files_linted", 3, nil)).To(Succeed())
+		g.Expect(impl.telemetry.AddCounter("files_linted", 2, nil)).To(Succeed())
+		g.Expect(impl.telemetry.RecordTiming("upload", start, 2*time.Second, map[string]string{"status": "ok"})).To(Succeed())
+
+		g.Expect(recorder.counters).To(Equal(map[string]float64{"files_linted": 5}))
+		g.Expect(recorder.timings).To(HaveLen(1))
+		g.Expect(recorder.timings[0].name).To(Equal("upload"))
+		g.Expect(recorder.timings[0].start.Equal(start)).To(BeTrue())
+		g.Expect(recorder.timings[0].duration).To(Equal(2 * time.Second))
+		g.Expect(recorder.timings[0].attributes).To(Equal(map[string]string{"status": "ok"}))
+	})
+
+	t.Run("leaves plugins that don't report metrics alone", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		c := dispense(t, &Base{})
+
+		g.Expect(c.StartTelemetry(&recordingTelemetry{})).To(Succeed())
+	})
+}
@@ -0,0 +1,1348 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v7.34.0
+// source: pkg/plugin/sdk/v1alpha5/proto/plugin.proto
+
+package proto
+
+import (
+	context "context"
+	analysis "github.com/aspect-build/aspect-cli-legacy/bazel/analysis"
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	query "github.com/aspect-build/aspect-cli-legacy/bazel/query"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type BEPEventsReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BrokerId      uint32                 `protobuf:"varint,1,opt,name=broker_id,json=brokerId,proto3" json:"broker_id,omitempty"`
+	InvocationId  string                 `protobuf:"bytes,2,opt,name=invocation_id,json=invocationId,proto3" json:"invocation_id,omitempty"`
+	BuildId       string                 `protobuf:"bytes,3,opt,name=build_id,json=buildId,proto3" json:"build_id,omitempty"`
+	Command       string                 `protobuf:"bytes,4,opt,name=command,proto3" json:"command,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BEPEventsReq) Reset() {
+	*x = BEPEventsReq{}
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BEPEventsReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BEPEventsReq) ProtoMessage() {}
+
+func (x *BEPEventsReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BEPEventsReq.ProtoReflect.Descriptor instead.
+func (*BEPEventsReq) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *BEPEventsReq) GetBrokerId() uint32 {
+	if x != nil {
+		return x.BrokerId
+	}
+	return 0
+}
+
+func (x *BEPEventsReq) GetInvocationId() string {
+	if x != nil {
+		return x.InvocationId
+	}
+	return ""
+}
+
+func (x *BEPEventsReq) GetBuildId() string {
+	if x != nil {
+		return x.BuildId
+	}
+	return ""
+}
+
+func (x *BEPEventsReq) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+type BEPEventsRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BEPEventsRes) Reset() {
+	*x = BEPEventsRes{}
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BEPEventsRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BEPEventsRes) ProtoMessage() {}
+
+func (x *BEPEventsRes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BEPEventsRes.ProtoReflect.Descriptor instead.
+func (*BEPEventsRes) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDescGZIP(), []int{1}
+}
+
+type BEPEventStreamReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BEPEventStreamReq) Reset() {
+	*x = BEPEventStreamReq{}
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BEPEventStreamReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BEPEventStreamReq) ProtoMessage() {}
+
+func (x *BEPEventStreamReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BEPEventStreamReq.ProtoReflect.Descriptor instead.
+func (*BEPEventStreamReq) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDescGZIP(), []int{2}
+}
+
+type BEPEvent struct {
+	state          protoimpl.MessageState       `protogen:"open.v1"`
+	Event          *buildeventstream.BuildEvent `protobuf:"bytes,1,opt,name=event,proto3" json:"event,omitempty"`
+	SequenceNumber int64                        `protobuf:"varint,2,opt,name=sequence_number,json=sequenceNumber,proto3" json:"sequence_number,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *BEPEvent) Reset() {
+	*x = BEPEvent{}
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BEPEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BEPEvent) ProtoMessage() {}
+
+func (x *BEPEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BEPEvent.ProtoReflect.Descriptor instead.
+func (*BEPEvent) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BEPEvent) GetEvent() *buildeventstream.BuildEvent {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *BEPEvent) GetSequenceNumber() int64 {
+	if x != nil {
+		return x.SequenceNumber
+	}
+	return 0
+}
+
+type TelemetryReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BrokerId      uint32                 `protobuf:"varint,1,opt,name=broker_id,json=brokerId,proto3" json:"broker_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TelemetryReq) Reset() {
+	*x = TelemetryReq{}
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TelemetryReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TelemetryReq) ProtoMessage() {}
+
+func (x *TelemetryReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TelemetryReq.ProtoReflect.Descriptor instead.
+func (*TelemetryReq) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *TelemetryReq) GetBrokerId() uint32 {
+	if x != nil {
+		return x.BrokerId
+	}
+	return 0
+}
+
+type TelemetryRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TelemetryRes) Reset() {
+	*x = TelemetryRes{}
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TelemetryRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TelemetryRes) ProtoMessage() {}
+
+func (x *TelemetryRes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TelemetryRes.ProtoReflect.Descriptor instead.
+func (*TelemetryRes) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDescGZIP(), []int{5}
+}
+
+type AddCounterReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value         float64                `protobuf:"fixed64,2,opt,name=value,proto3" json:"value,omitempty"`
+	Attributes    map[string]string      `protobuf:"bytes,3,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddCounterReq) Reset() {
+	*x = AddCounterReq{}
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddCounterReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddCounterReq) ProtoMessage() {}
+
+func (x *AddCounterReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddCounterReq.ProtoReflect.Descriptor instead.
+func (*AddCounterReq) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *AddCounterReq) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *AddCounterReq) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *AddCounterReq) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+type AddCounterRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddCounterRes) Reset() {
+	*x = AddCounterRes{}
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddCounterRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddCounterRes) ProtoMessage() {}
+
+func (x *AddCounterRes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddCounterRes.ProtoReflect.Descriptor instead.
+func (*AddCounterRes) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDescGZIP(), []int{7}
+}
+
+type RecordTimingReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Start         *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=start,proto3" json:"start,omitempty"`
+	Duration      *durationpb.Duration   `protobuf:"bytes,3,opt,name=duration,proto3" json:"duration,omitempty"`
+	Attributes    map[string]string      `protobuf:"bytes,4,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecordTimingReq) Reset() {
+	*x = RecordTimingReq{}
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecordTimingReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecordTimingReq) ProtoMessage() {}
+
+func (x *RecordTimingReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecordTimingReq.ProtoReflect.Descriptor instead.
+func (*RecordTimingReq) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *RecordTimingReq) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *RecordTimingReq) GetStart() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Start
+	}
+	return nil
+}
+
+func (x *RecordTimingReq) GetDuration() *durationpb.Duration {
+	if x != nil {
+		return x.Duration
+	}
+	return nil
+}
+
+func (x *RecordTimingReq) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+type RecordTimingRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecordTimingRes) Reset() {
+	*x = RecordTimingRes{}
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecordTimingRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecordTimingRes) ProtoMessage() {}
+
+func (x *RecordTimingRes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecordTimingRes.ProtoReflect.Descriptor instead.
+func (*RecordTimingRes) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDescGZIP(), []int{9}
+}
+
+type BazelReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BrokerId      uint32                 `protobuf:"varint,1,opt,name=broker_id,json=brokerId,proto3" json:"broker_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BazelReq) Reset() {
+	*x = BazelReq{}
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BazelReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BazelReq) ProtoMessage() {}
+
+func (x *BazelReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BazelReq.ProtoReflect.Descriptor instead.
+func (*BazelReq) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *BazelReq) GetBrokerId() uint32 {
+	if x != nil {
+		return x.BrokerId
+	}
+	return 0
+}
+
+type BazelRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BazelRes) Reset() {
+	*x = BazelRes{}
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BazelRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BazelRes) ProtoMessage() {}
+
+func (x *BazelRes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BazelRes.ProtoReflect.Descriptor instead.
+func (*BazelRes) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDescGZIP(), []int{11}
+}
+
+type QueryReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Expression    string                 `protobuf:"bytes,1,opt,name=expression,proto3" json:"expression,omitempty"`
+	Flags         []string               `protobuf:"bytes,2,rep,name=flags,proto3" json:"flags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryReq) Reset() {
+	*x = QueryReq{}
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryReq) ProtoMessage() {}
+
+func (x *QueryReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryReq.ProtoReflect.Descriptor instead.
+func (*QueryReq) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *QueryReq) GetExpression() string {
+	if x != nil {
+		return x.Expression
+	}
+	return ""
+}
+
+func (x *QueryReq) GetFlags() []string {
+	if x != nil {
+		return x.Flags
+	}
+	return nil
+}
+
+type QueryRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Result        *query.QueryResult     `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryRes) Reset() {
+	*x = QueryRes{}
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryRes) ProtoMessage() {}
+
+func (x *QueryRes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryRes.ProtoReflect.Descriptor instead.
+func (*QueryRes) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *QueryRes) GetResult() *query.QueryResult {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+type CQueryRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Result        *analysis.CqueryResult `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CQueryRes) Reset() {
+	*x = CQueryRes{}
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CQueryRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CQueryRes) ProtoMessage() {}
+
+func (x *CQueryRes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CQueryRes.ProtoReflect.Descriptor instead.
+func (*CQueryRes) Descriptor() ([]byte, []int) {
+	return file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *CQueryRes) GetResult() *analysis.CqueryResult {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+var File_pkg_plugin_sdk_v1alpha5_proto_plugin_proto protoreflect.FileDescriptor
+
+const file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDesc = "" +
+	"\n" +
+	"*pkg/plugin/sdk/v1alpha5/proto/plugin.proto\x12\bv1alpha5\x1a bazel/analysis/analysis_v2.proto\x1a/bazel/buildeventstream/build_event_stream.proto\x1a\x17bazel/query/build.proto\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x85\x01\n" +
+	"\fBEPEventsReq\x12\x1b\n" +
+	"\tbroker_id\x18\x01 \x01(\rR\bbrokerId\x12#\n" +
+	"\rinvocation_id\x18\x02 \x01(\tR\finvocationId\x12\x19\n" +
+	"\bbuild_id\x18\x03 \x01(\tR\abuildId\x12\x18\n" +
+	"\acommand\x18\x04 \x01(\tR\acommand\"\x0e\n" +
+	"\fBEPEventsRes\"\x13\n" +
+	"\x11BEPEventStreamReq\"i\n" +
+	"\bBEPEvent\x124\n" +
+	"\x05event\x18\x01 \x01(\v2\x1e.build_event_stream.BuildEventR\x05event\x12'\n" +
+	"\x0fsequence_number\x18\x02 \x01(\x03R\x0esequenceNumber\"+\n" +
+	"\fTelemetryReq\x12\x1b\n" +
+	"\tbroker_id\x18\x01 \x01(\rR\bbrokerId\"\x0e\n" +
+	"\fTelemetryRes\"\xc1\x01\n" +
+	"\rAddCounterReq\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value\x12G\n" +
+	"\n" +
+	"attributes\x18\x03 \x03(\v2'.v1alpha5.AddCounterReq.AttributesEntryR\n" +
+	"attributes\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x0f\n" +
+	"\rAddCounterRes\"\x98\x02\n" +
+	"\x0fRecordTimingReq\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x120\n" +
+	"\x05start\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x05start\x125\n" +
+	"\bduration\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\bduration\x12I\n" +
+	"\n" +
+	"attributes\x18\x04 \x03(\v2).v1alpha5.RecordTimingReq.AttributesEntryR\n" +
+	"attributes\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x11\n" +
+	"\x0fRecordTimingRes\"'\n" +
+	"\bBazelReq\x12\x1b\n" +
+	"\tbroker_id\x18\x01 \x01(\rR\bbrokerId\"\n" +
+	"\n" +
+	"\bBazelRes\"@\n" +
+	"\bQueryReq\x12\x1e\n" +
+	"\n" +
+	"expression\x18\x01 \x01(\tR\n" +
+	"expression\x12\x14\n" +
+	"\x05flags\x18\x02 \x03(\tR\x05flags\"J\n" +
+	"\bQueryRes\x12>\n" +
+	"\x06result\x18\x01 \x01(\v2&.blaze_query_aspect_mirror.QueryResultR\x06result\"8\n" +
+	"\tCQueryRes\x12+\n" +
+	"\x06result\x18\x01 \x01(\v2\x13.bazel.CqueryResultR\x06result2\xb3\x01\n" +
+	"\x06Plugin\x12;\n" +
+	"\tBEPEvents\x12\x16.v1alpha5.BEPEventsReq\x1a\x16.v1alpha5.BEPEventsRes\x12;\n" +
+	"\tTelemetry\x12\x16.v1alpha5.TelemetryReq\x1a\x16.v1alpha5.TelemetryRes\x12/\n" +
+	"\x05Bazel\x12\x12.v1alpha5.BazelReq\x1a\x12.v1alpha5.BazelRes2M\n" +
+	"\x0eBEPEventStream\x12;\n" +
+	"\x06Stream\x12\x1b.v1alpha5.BEPEventStreamReq\x1a\x12.v1alpha5.BEPEvent0\x012\x91\x01\n" +
+	"\tTelemetry\x12>\n" +
+	"\n" +
+	"AddCounter\x12\x17.v1alpha5.AddCounterReq\x1a\x17.v1alpha5.AddCounterRes\x12D\n" +
+	"\fRecordTiming\x12\x19.v1alpha5.RecordTimingReq\x1a\x19.v1alpha5.RecordTimingRes2k\n" +
+	"\x05Bazel\x12/\n" +
+	"\x05Query\x12\x12.v1alpha5.QueryReq\x1a\x12.v1alpha5.QueryRes\x121\n" +
+	"\x06CQuery\x12\x12.v1alpha5.QueryReq\x1a\x13.v1alpha5.CQueryResBIZGgithub.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha5/protob\x06proto3"
+
+var (
+	file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDescOnce sync.Once
+	file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDescData []byte
+)
+
+func file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDescGZIP() []byte {
+	file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDescOnce.Do(func() {
+		file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDesc), len(file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDesc)))
+	})
+	return file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDescData
+}
+
+var file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
+var file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_goTypes = []any{
+	(*BEPEventsReq)(nil),                // 0: v1alpha5.BEPEventsReq
+	(*BEPEventsRes)(nil),                // 1: v1alpha5.BEPEventsRes
+	(*BEPEventStreamReq)(nil),           // 2: v1alpha5.BEPEventStreamReq
+	(*BEPEvent)(nil),                    // 3: v1alpha5.BEPEvent
+	(*TelemetryReq)(nil),                // 4: v1alpha5.TelemetryReq
+	(*TelemetryRes)(nil),                // 5: v1alpha5.TelemetryRes
+	(*AddCounterReq)(nil),               // 6: v1alpha5.AddCounterReq
+	(*AddCounterRes)(nil),               // 7: v1alpha5.AddCounterRes
+	(*RecordTimingReq)(nil),             // 8: v1alpha5.RecordTimingReq
+	(*RecordTimingRes)(nil),             // 9: v1alpha5.RecordTimingRes
+	(*BazelReq)(nil),                    // 10: v1alpha5.BazelReq
+	(*BazelRes)(nil),                    // 11: v1alpha5.BazelRes
+	(*QueryReq)(nil),                    // 12: v1alpha5.QueryReq
+	(*QueryRes)(nil),                    // 13: v1alpha5.QueryRes
+	(*CQueryRes)(nil),                   // 14: v1alpha5.CQueryRes
+	nil,                                 // 15: v1alpha5.AddCounterReq.AttributesEntry
+	nil,                                 // 16: v1alpha5.RecordTimingReq.AttributesEntry
+	(*buildeventstream.BuildEvent)(nil), // 17: build_event_stream.BuildEvent
+	(*timestamppb.Timestamp)(nil),       // 18: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),         // 19: google.protobuf.Duration
+	(*query.QueryResult)(nil),           // 20: blaze_query_aspect_mirror.QueryResult
+	(*analysis.CqueryResult)(nil),       // 21: bazel.CqueryResult
+}
+var file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_depIdxs = []int32{
+	17, // 0: v1alpha5.BEPEvent.event:type_name -> build_event_stream.BuildEvent
+	15, // 1: v1alpha5.AddCounterReq.attributes:type_name -> v1alpha5.AddCounterReq.AttributesEntry
+	18, // 2: v1alpha5.RecordTimingReq.start:type_name -> google.protobuf.Timestamp
+	19, // 3: v1alpha5.RecordTimingReq.duration:type_name -> google.protobuf.Duration
+	16, // 4: v1alpha5.RecordTimingReq.attributes:type_name -> v1alpha5.RecordTimingReq.AttributesEntry
+	20, // 5: v1alpha5.QueryRes.result:type_name -> blaze_query_aspect_mirror.QueryResult
+	21, // 6: v1alpha5.CQueryRes.result:type_name -> bazel.CqueryResult
+	0,  // 7: v1alpha5.Plugin.BEPEvents:input_type -> v1alpha5.BEPEventsReq
+	4,  // 8: v1alpha5.Plugin.Telemetry:input_type -> v1alpha5.TelemetryReq
+	10, // 9: v1alpha5.Plugin.Bazel:input_type -> v1alpha5.BazelReq
+	2,  // 10: v1alpha5.BEPEventStream.Stream:input_type -> v1alpha5.BEPEventStreamReq
+	6,  // 11: v1alpha5.Telemetry.AddCounter:input_type -> v1alpha5.AddCounterReq
+	8,  // 12: v1alpha5.Telemetry.RecordTiming:input_type -> v1alpha5.RecordTimingReq
+	12, // 13: v1alpha5.Bazel.Query:input_type -> v1alpha5.QueryReq
+	12, // 14: v1alpha5.Bazel.CQuery:input_type -> v1alpha5.QueryReq
+	1,  // 15: v1alpha5.Plugin.BEPEvents:output_type -> v1alpha5.BEPEventsRes
+	5,  // 16: v1alpha5.Plugin.Telemetry:output_type -> v1alpha5.TelemetryRes
+	11, // 17: v1alpha5.Plugin.Bazel:output_type -> v1alpha5.BazelRes
+	3,  // 18: v1alpha5.BEPEventStream.Stream:output_type -> v1alpha5.BEPEvent
+	7,  // 19: v1alpha5.Telemetry.AddCounter:output_type -> v1alpha5.AddCounterRes
+	9,  // 20: v1alpha5.Telemetry.RecordTiming:output_type -> v1alpha5.RecordTimingRes
+	13, // 21: v1alpha5.Bazel.Query:output_type -> v1alpha5.QueryRes
+	14, // 22: v1alpha5.Bazel.CQuery:output_type -> v1alpha5.CQueryRes
+	15, // [15:23] is the sub-list for method output_type
+	7,  // [7:15] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_init() }
+func file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_init() {
+	if File_pkg_plugin_sdk_v1alpha5_proto_plugin_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDesc), len(file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   17,
+			NumExtensions: 0,
+			NumServices:   4,
+		},
+		GoTypes:           file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_goTypes,
+		DependencyIndexes: file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_depIdxs,
+		MessageInfos:      file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_msgTypes,
+	}.Build()
+	File_pkg_plugin_sdk_v1alpha5_proto_plugin_proto = out.File
+	file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_goTypes = nil
+	file_pkg_plugin_sdk_v1alpha5_proto_plugin_proto_depIdxs = nil
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// PluginClient is the client API for Plugin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type PluginClient interface {
+	BEPEvents(ctx context.Context, in *BEPEventsReq, opts ...grpc.CallOption) (*BEPEventsRes, error)
+	Telemetry(ctx context.Context, in *TelemetryReq, opts ...grpc.CallOption) (*TelemetryRes, error)
+	Bazel(ctx context.Context, in *BazelReq, opts ...grpc.CallOption) (*BazelRes, error)
+}
+
+type pluginClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPluginClient(cc grpc.ClientConnInterface) PluginClient {
+	return &pluginClient{cc}
+}
+
+func (c *pluginClient) BEPEvents(ctx context.Context, in *BEPEventsReq, opts ...grpc.CallOption) (*BEPEventsRes, error) {
+	out := new(BEPEventsRes)
+	err := c.cc.Invoke(ctx, "/v1alpha5.Plugin/BEPEvents", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pluginClient) Telemetry(ctx context.Context, in *TelemetryReq, opts ...grpc.CallOption) (*TelemetryRes, error) {
+	out := new(TelemetryRes)
+	err := c.cc.Invoke(ctx, "/v1alpha5.Plugin/Telemetry", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pluginClient) Bazel(ctx context.Context, in *BazelReq, opts ...grpc.CallOption) (*BazelRes, error) {
+	out := new(BazelRes)
+	err := c.cc.Invoke(ctx, "/v1alpha5.Plugin/Bazel", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PluginServer is the server API for Plugin service.
+type PluginServer interface {
+	BEPEvents(context.Context, *BEPEventsReq) (*BEPEventsRes, error)
+	Telemetry(context.Context, *TelemetryReq) (*TelemetryRes, error)
+	Bazel(context.Context, *BazelReq) (*BazelRes, error)
+}
+
+// UnimplementedPluginServer can be embedded to have forward compatible implementations.
+type UnimplementedPluginServer struct {
+}
+
+func (*UnimplementedPluginServer) BEPEvents(context.Context, *BEPEventsReq) (*BEPEventsRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BEPEvents not implemented")
+}
+func (*UnimplementedPluginServer) Telemetry(context.Context, *TelemetryReq) (*TelemetryRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Telemetry not implemented")
+}
+func (*UnimplementedPluginServer) Bazel(context.Context, *BazelReq) (*BazelRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Bazel not implemented")
+}
+
+func RegisterPluginServer(s *grpc.Server, srv PluginServer) {
+	s.RegisterService(&_Plugin_serviceDesc, srv)
+}
+
+func _Plugin_BEPEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BEPEventsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginServer).BEPEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/v1alpha5.Plugin/BEPEvents",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginServer).BEPEvents(ctx, req.(*BEPEventsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Plugin_Telemetry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TelemetryReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginServer).Telemetry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/v1alpha5.Plugin/Telemetry",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginServer).Telemetry(ctx, req.(*TelemetryReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Plugin_Bazel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BazelReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginServer).Bazel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/v1alpha5.Plugin/Bazel",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginServer).Bazel(ctx, req.(*BazelReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Plugin_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "v1alpha5.Plugin",
+	HandlerType: (*PluginServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "BEPEvents",
+			Handler:    _Plugin_BEPEvents_Handler,
+		},
+		{
+			MethodName: "Telemetry",
+			Handler:    _Plugin_Telemetry_Handler,
+		},
+		{
+			MethodName: "Bazel",
+			Handler:    _Plugin_Bazel_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "pkg/plugin/sdk/v1alpha5/proto/plugin.proto",
+}
+
+// BEPEventStreamClient is the client API for BEPEventStream service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type BEPEventStreamClient interface {
+	Stream(ctx context.Context, in *BEPEventStreamReq, opts ...grpc.CallOption) (BEPEventStream_StreamClient, error)
+}
+
+type bEPEventStreamClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBEPEventStreamClient(cc grpc.ClientConnInterface) BEPEventStreamClient {
+	return &bEPEventStreamClient{cc}
+}
+
+func (c *bEPEventStreamClient) Stream(ctx context.Context, in *BEPEventStreamReq, opts ...grpc.CallOption) (BEPEventStream_StreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_BEPEventStream_serviceDesc.Streams[0], "/v1alpha5.BEPEventStream/Stream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &bEPEventStreamStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type BEPEventStream_StreamClient interface {
+	Recv() (*BEPEvent, error)
+	grpc.ClientStream
+}
+
+type bEPEventStreamStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *bEPEventStreamStreamClient) Recv() (*BEPEvent, error) {
+	m := new(BEPEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// BEPEventStreamServer is the server API for BEPEventStream service.
+type BEPEventStreamServer interface {
+	Stream(*BEPEventStreamReq, BEPEventStream_StreamServer) error
+}
+
+// UnimplementedBEPEventStreamServer can be embedded to have forward compatible implementations.
+type UnimplementedBEPEventStreamServer struct {
+}
+
+func (*UnimplementedBEPEventStreamServer) Stream(*BEPEventStreamReq, BEPEventStream_StreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method Stream not implemented")
+}
+
+func RegisterBEPEventStreamServer(s *grpc.Server, srv BEPEventStreamServer) {
+	s.RegisterService(&_BEPEventStream_serviceDesc, srv)
+}
+
+func _BEPEventStream_Stream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BEPEventStreamReq)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BEPEventStreamServer).Stream(m, &bEPEventStreamStreamServer{stream})
+}
+
+type BEPEventStream_StreamServer interface {
+	Send(*BEPEvent) error
+	grpc.ServerStream
+}
+
+type bEPEventStreamStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *bEPEventStreamStreamServer) Send(m *BEPEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _BEPEventStream_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "v1alpha5.BEPEventStream",
+	HandlerType: (*BEPEventStreamServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Stream",
+			Handler:       _BEPEventStream_Stream_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "pkg/plugin/sdk/v1alpha5/proto/plugin.proto",
+}
+
+// TelemetryClient is the client API for Telemetry service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type TelemetryClient interface {
+	AddCounter(ctx context.Context, in *AddCounterReq, opts ...grpc.CallOption) (*AddCounterRes, error)
+	RecordTiming(ctx context.Context, in *RecordTimingReq, opts ...grpc.CallOption) (*RecordTimingRes, error)
+}
+
+type telemetryClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTelemetryClient(cc grpc.ClientConnInterface) TelemetryClient {
+	return &telemetryClient{cc}
+}
+
+func (c *telemetryClient) AddCounter(ctx context.Context, in *AddCounterReq, opts ...grpc.CallOption) (*AddCounterRes, error) {
+	out := new(AddCounterRes)
+	err := c.cc.Invoke(ctx, "/v1alpha5.Telemetry/AddCounter", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *telemetryClient) RecordTiming(ctx context.Context, in *RecordTimingReq, opts ...grpc.CallOption) (*RecordTimingRes, error) {
+	out := new(RecordTimingRes)
+	err := c.cc.Invoke(ctx, "/v1alpha5.Telemetry/RecordTiming", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TelemetryServer is the server API for Telemetry service.
+type TelemetryServer interface {
+	AddCounter(context.Context, *AddCounterReq) (*AddCounterRes, error)
+	RecordTiming(context.Context, *RecordTimingReq) (*RecordTimingRes, error)
+}
+
+// UnimplementedTelemetryServer can be embedded to have forward compatible implementations.
+type UnimplementedTelemetryServer struct {
+}
+
+func (*UnimplementedTelemetryServer) AddCounter(context.Context, *AddCounterReq) (*AddCounterRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddCounter not implemented")
+}
+func (*UnimplementedTelemetryServer) RecordTiming(context.Context, *RecordTimingReq) (*RecordTimingRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RecordTiming not implemented")
+}
+
+func RegisterTelemetryServer(s *grpc.Server, srv TelemetryServer) {
+	s.RegisterService(&_Telemetry_serviceDesc, srv)
+}
+
+func _Telemetry_AddCounter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddCounterReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TelemetryServer).AddCounter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/v1alpha5.Telemetry/AddCounter",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TelemetryServer).AddCounter(ctx, req.(*AddCounterReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Telemetry_RecordTiming_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecordTimingReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TelemetryServer).RecordTiming(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/v1alpha5.Telemetry/RecordTiming",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TelemetryServer).RecordTiming(ctx, req.(*RecordTimingReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Telemetry_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "v1alpha5.Telemetry",
+	HandlerType: (*TelemetryServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddCounter",
+			Handler:    _Telemetry_AddCounter_Handler,
+		},
+		{
+			MethodName: "RecordTiming",
+			Handler:    _Telemetry_RecordTiming_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "pkg/plugin/sdk/v1alpha5/proto/plugin.proto",
+}
+
+// BazelClient is the client API for Bazel service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type BazelClient interface {
+	Query(ctx context.Context, in *QueryReq, opts ...grpc.CallOption) (*QueryRes, error)
+	CQuery(ctx context.Context, in *QueryReq, opts ...grpc.CallOption) (*CQueryRes, error)
+}
+
+type bazelClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBazelClient(cc grpc.ClientConnInterface) BazelClient {
+	return &bazelClient{cc}
+}
+
+func (c *bazelClient) Query(ctx context.Context, in *QueryReq, opts ...grpc.CallOption) (*QueryRes, error) {
+	out := new(QueryRes)
+	err := c.cc.Invoke(ctx, "/v1alpha5.Bazel/Query", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bazelClient) CQuery(ctx context.Context, in *QueryReq, opts ...grpc.CallOption) (*CQueryRes, error) {
+	out := new(CQueryRes)
+	err := c.cc.Invoke(ctx, "/v1alpha5.Bazel/CQuery", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BazelServer is the server API for Bazel service.
+type BazelServer interface {
+	Query(context.Context, *QueryReq) (*QueryRes, error)
+	CQuery(context.Context, *QueryReq) (*CQueryRes, error)
+}
+
+// UnimplementedBazelServer can be embedded to have forward compatible implementations.
+type UnimplementedBazelServer struct {
+}
+
+func (*UnimplementedBazelServer) Query(context.Context, *QueryReq) (*QueryRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Query not implemented")
+}
+func (*UnimplementedBazelServer) CQuery(context.Context, *QueryReq) (*CQueryRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CQuery not implemented")
+}
+
+func RegisterBazelServer(s *grpc.Server, srv BazelServer) {
+	s.RegisterService(&_Bazel_serviceDesc, srv)
+}
+
+func _Bazel_Query_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BazelServer).Query(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/v1alpha5.Bazel/Query",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BazelServer).Query(ctx, req.(*QueryReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Bazel_CQuery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BazelServer).CQuery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/v1alpha5.Bazel/CQuery",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BazelServer).CQuery(ctx, req.(*QueryReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Bazel_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "v1alpha5.Bazel",
+	HandlerType: (*BazelServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Query",
+			Handler:    _Bazel_Query_Handler,
+		},
+		{
+			MethodName: "CQuery",
+			Handler:    _Bazel_CQuery_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "pkg/plugin/sdk/v1alpha5/proto/plugin.proto",
+}
@@ -0,0 +1,264 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package bep
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	buildv1 "google.golang.org/genproto/googleapis/devtools/build/v1"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/besproxy"
+)
+
+// ackTracker is a BES proxy that tracks the acknowledgements of the build
+// events it forwards, so that it can be reported whether the backend received
+// the complete invocation. Backends acknowledge the build events in order, so
+// an acknowledgement that skips sequence numbers is a gap in the invocation.
+type ackTracker struct {
+	besproxy.BESProxy
+
+	mu sync.Mutex
+	// sent and acked are the highest sequence numbers sent to and
+	// acknowledged by the backend.
+	sent  int64
+	acked int64
+	// skipped counts the sequence numbers that were never acknowledged
+	// before a later one was.
+	skipped int64
+	// dropped counts the build events that weren't sent to the backend once
+	// it was taken out of rotation, and sendFailed is set when the last of
+	// them couldn't be sent, including when the stream couldn't be closed.
+	dropped    int64
+	sendFailed bool
+	// reconnectsBefore is how many times the proxy had resumed its stream
+	// before the invocation.
+	reconnectsBefore int
+	// closed is set once all the build events were sent, and caughtUp is
+	// closed when the backend acknowledged them or stopped acknowledging.
+	closed          bool
+	markedUnhealthy bool
+	recvEnded       bool
+	caughtUp        chan struct{}
+}
+
+// AckReport is how many of the build events sent to a BES backend it
+// acknowledged.
+type AckReport struct {
+	Host string
+	// Sent and Acknowledged are the number of build events sent to and
+	// acknowledged by the backend.
+	Sent         int64
+	Acknowledged int64
+	// Skipped is the number of build events that the backend didn't
+	// acknowledge although it acknowledged later ones.
+	Skipped int64
+	// Dropped is the number of build events that weren't sent to the backend
+	// because it was out of rotation.
+	Dropped int64
+	// Reconnects is the number of times the stream to the backend failed and
+	// was resumed.
+	Reconnects int
+	// Healthy is whether the backend stayed in rotation until the end of the
+	// invocation.
+	Healthy bool
+}
+
+// Complete returns whether the backend acknowledged every build event.
+func (r AckReport) Complete() bool {
+	return r.Acknowledged >= r.Sent && r.Skipped == 0 && r.Dropped == 0
+}
+
+// reconnector is implemented by the proxies that resume their failed streams.
+type reconnector interface {
+	Reconnects() int
+}
+
+func trackAcks(p besproxy.BESProxy) *ackTracker {
+	t := &ackTracker{BESProxy: p, caughtUp: make(chan struct{})}
+	t.reconnectsBefore = t.reconnects()
+	return t
+}
+
+// reconnects returns how many times the proxy resumed its stream.
+func (t *ackTracker) reconnects() int {
+	if r, ok := t.BESProxy.(reconnector); ok {
+		return r.Reconnects()
+	}
+	return 0
+}
+
+// reset forgets the build events of the previous invocation, before the
+// proxy streams those of the next one.
+func (t *ackTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sent, t.acked, t.skipped, t.dropped = 0, 0, 0, 0
+	t.closed, t.markedUnhealthy, t.recvEnded, t.sendFailed = false, false, false, false
+	t.reconnectsBefore = t.reconnects()
+	t.caughtUp = make(chan struct{})
+}
+
+// drop counts a build event that wasn't sent because the proxy is out of
+// rotation.
+func (t *ackTracker) drop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dropped++
+}
+
+func (t *ackTracker) Send(req *buildv1.PublishBuildToolEventStreamRequest) error {
+	t.mu.Lock()
+	t.sent = max(t.sent, req.GetOrderedBuildEvent().GetSequenceNumber())
+	t.mu.Unlock()
+	err := t.BESProxy.Send(req)
+	t.mu.Lock()
+	t.sendFailed = err != nil
+	t.mu.Unlock()
+	return err
+}
+
+func (t *ackTracker) Recv() (*buildv1.PublishBuildToolEventStreamResponse, error) {
+	resp, err := t.BESProxy.Recv()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err != nil {
+		t.recvEnded = true
+		t.checkCaughtUp()
+		return resp, err
+	}
+	if seqId := resp.GetSequenceNumber(); seqId > t.acked {
+		t.skipped += seqId - t.acked - 1
+		t.acked = seqId
+	}
+	t.checkCaughtUp()
+	return resp, nil
+}
+
+func (t *ackTracker) CloseSend() error {
+	t.mu.Lock()
+	t.closed = true
+	t.checkCaughtUp()
+	t.mu.Unlock()
+	err := t.BESProxy.CloseSend()
+	if err != nil {
+		t.mu.Lock()
+		t.sendFailed = true
+		t.mu.Unlock()
+	}
+	return err
+}
+
+// MarkUnhealthy stops expecting acknowledgements from the backend, unless it
+// stays in rotation while its stream is probed to be resumed.
+func (t *ackTracker) MarkUnhealthy() {
+	t.BESProxy.MarkUnhealthy()
+	if t.BESProxy.Healthy() {
+		return
+	}
+	t.mu.Lock()
+	t.markedUnhealthy = true
+	t.checkCaughtUp()
+	t.mu.Unlock()
+}
+
+// Healthy keeps a proxy whose stream was closed healthy until the backend
+// acknowledged all the build events, so that the acknowledgements are still
+// received.
+func (t *ackTracker) Healthy() bool {
+	if t.BESProxy.Healthy() {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.draining()
+}
+
+// draining returns whether all the build events were sent and the backend is
+// expected to acknowledge more of them. t.mu must be held.
+func (t *ackTracker) draining() bool {
+	return t.closed && !t.markedUnhealthy && !t.recvEnded && t.acked < t.sent
+}
+
+// checkCaughtUp closes caughtUp once no more acknowledgements are expected.
+// t.mu must be held.
+func (t *ackTracker) checkCaughtUp() {
+	if !t.closed && !t.markedUnhealthy && !t.recvEnded {
+		// More build events may still be sent.
+		return
+	}
+	if t.draining() {
+		return
+	}
+	select {
+	case <-t.caughtUp:
+	default:
+		close(t.caughtUp)
+	}
+}
+
+// report waits until the backend acknowledged all the build events or the
+// deadline passes, and returns what it acknowledged. It doesn't wait for a
+// backend whose stream wasn't closed, since it was taken out of rotation.
+func (t *ackTracker) report(deadline time.Time) AckReport {
+	t.mu.Lock()
+	closed, caughtUp := t.closed, t.caughtUp
+	t.mu.Unlock()
+	if closed {
+		select {
+		case <-caughtUp:
+		case <-time.After(time.Until(deadline)):
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return AckReport{
+		Host:         t.Host(),
+		Sent:         t.sent,
+		Acknowledged: min(t.acked, t.sent),
+		Skipped:      t.skipped,
+		Dropped:      t.dropped,
+		Reconnects:   t.reconnects() - t.reconnectsBefore,
+		Healthy:      !t.markedUnhealthy && !t.sendFailed && t.dropped == 0,
+	}
+}
+
+// reportAcks waits up to timeout for the backends to acknowledge the build
+// events sent to them, and reports those that didn't receive the complete
+// invocation.
+func reportAcks(w io.Writer, trackers []*ackTracker, timeout time.Duration) []AckReport {
+	deadline := time.Now().Add(timeout)
+	reports := make([]AckReport, 0, len(trackers))
+	for _, t := range trackers {
+		r := t.report(deadline)
+		reports = append(reports, r)
+		if r.Complete() {
+			continue
+		}
+		fmt.Fprintf(w, "BES backend %v acknowledged %d of %d build events", r.Host, r.Acknowledged, r.Sent)
+		if r.Skipped > 0 {
+			fmt.Fprintf(w, ", skipping %d", r.Skipped)
+		}
+		if r.Dropped > 0 {
+			fmt.Fprintf(w, ", and %d were not sent to it", r.Dropped)
+		}
+		fmt.Fprintf(w, "; the invocation it received may be incomplete\n")
+	}
+	return reports
+}
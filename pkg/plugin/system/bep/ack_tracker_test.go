@@ -0,0 +1,208 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package bep
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/gomega"
+	buildv1 "google.golang.org/genproto/googleapis/devtools/build/v1"
+
+	besproxy_mock "github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/besproxy/mock"
+)
+
+func TestAckTracker(t *testing.T) {
+	request := func(seqId int64) *buildv1.PublishBuildToolEventStreamRequest {
+		return &buildv1.PublishBuildToolEventStreamRequest{
+			OrderedBuildEvent: &buildv1.OrderedBuildEvent{SequenceNumber: seqId},
+		}
+	}
+	ack := func(seqId int64) *buildv1.PublishBuildToolEventStreamResponse {
+		return &buildv1.PublishBuildToolEventStreamResponse{SequenceNumber: seqId}
+	}
+
+	// sendAndClose sends the build events 1 to n through the tracker, receives
+	// the given acknowledgements and closes the stream.
+	sendAndClose := func(g *WithT, ctrl *gomock.Controller, n int64, acks ...*buildv1.PublishBuildToolEventStreamResponse) *ackTracker {
+		p := besproxy_mock.NewMockBESProxy(ctrl)
+		p.EXPECT().Send(gomock.Any()).Return(nil).Times(int(n))
+		var calls []*gomock.Call
+		for _, a := range acks {
+			calls = append(calls, p.EXPECT().Recv().Return(a, nil))
+		}
+		calls = append(calls, p.EXPECT().Recv().Return(nil, io.EOF))
+		gomock.InOrder(calls...)
+		p.EXPECT().CloseSend().Return(nil)
+		p.EXPECT().Host().Return("grpc://bes").AnyTimes()
+
+		tracker := trackAcks(p)
+		for seqId := int64(1); seqId <= n; seqId++ {
+			g.Expect(tracker.Send(request(seqId))).To(Succeed())
+		}
+		g.Expect(tracker.CloseSend()).To(Succeed())
+		for {
+			if _, err := tracker.Recv(); err != nil {
+				break
+			}
+		}
+		return tracker
+	}
+
+	t.Run("reports nothing when every build event was acknowledged", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+
+		tracker := sendAndClose(g, ctrl, 3, ack(1), ack(2), ack(3))
+
+		var out bytes.Buffer
+		reports := reportAcks(&out, []*ackTracker{tracker}, time.Second)
+		g.Expect(reports).To(Equal([]AckReport{{Host: "grpc://bes", Sent: 3, Acknowledged: 3, Healthy: true}}))
+		g.Expect(reports[0].Complete()).To(BeTrue())
+		g.Expect(out.String()).To(BeEmpty())
+	})
+
+	t.Run("reports the build events that were not acknowledged", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+
+		tracker := sendAndClose(g, ctrl, 3, ack(1))
+
+		var out bytes.Buffer
+		reports := reportAcks(&out, []*ackTracker{tracker}, time.Second)
+		g.Expect(reports).To(Equal([]AckReport{{Host: "grpc://bes", Sent: 3, Acknowledged: 1, Healthy: true}}))
+		g.Expect(out.String()).To(Equal("BES backend grpc://bes acknowledged 1 of 3 build events; the invocation it received may be incomplete\n"))
+	})
+
+	t.Run("reports the gaps in the acknowledgements", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+
+		tracker := sendAndClose(g, ctrl, 4, ack(1), ack(3), ack(3), ack(4))
+
+		var out bytes.Buffer
+		reports := reportAcks(&out, []*ackTracker{tracker}, time.Second)
+		g.Expect(reports).To(Equal([]AckReport{{Host: "grpc://bes", Sent: 4, Acknowledged: 4, Skipped: 1, Healthy: true}}))
+		g.Expect(out.String()).To(Equal("BES backend grpc://bes acknowledged 4 of 4 build events, skipping 1; the invocation it received may be incomplete\n"))
+	})
+
+	t.Run("stays healthy until the build events sent are acknowledged", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+
+		p := besproxy_mock.NewMockBESProxy(ctrl)
+		p.EXPECT().Send(gomock.Any()).Return(nil).Times(2)
+		p.EXPECT().CloseSend().Return(nil)
+		p.EXPECT().Healthy().Return(false).AnyTimes()
+		gomock.InOrder(
+			p.EXPECT().Recv().Return(ack(1), nil),
+			p.EXPECT().Recv().Return(ack(2), nil),
+		)
+
+		tracker := trackAcks(p)
+		g.Expect(tracker.Send(request(1))).To(Succeed())
+		g.Expect(tracker.Send(request(2))).To(Succeed())
+		g.Expect(tracker.CloseSend()).To(Succeed())
+
+		g.Expect(tracker.Healthy()).To(BeTrue())
+		g.Expect(tracker.Recv()).To(Equal(ack(1)))
+		g.Expect(tracker.Healthy()).To(BeTrue())
+		g.Expect(tracker.Recv()).To(Equal(ack(2)))
+		g.Expect(tracker.Healthy()).To(BeFalse())
+	})
+
+	t.Run("doesn't wait for a backend that was taken out of rotation", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+
+		p := besproxy_mock.NewMockBESProxy(ctrl)
+		p.EXPECT().Send(gomock.Any()).Return(nil)
+		p.EXPECT().Host().Return("grpc://bes")
+
+		tracker := trackAcks(p)
+		g.Expect(tracker.Send(request(1))).To(Succeed())
+
+		var out bytes.Buffer
+		reports := reportAcks(&out, []*ackTracker{tracker}, time.Hour)
+		g.Expect(reports).To(Equal([]AckReport{{Host: "grpc://bes", Sent: 1, Healthy: true}}))
+	})
+	t.Run("keeps waiting for a backend whose stream is probed to be resumed", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+
+		p := besproxy_mock.NewMockBESProxy(ctrl)
+		p.EXPECT().Send(gomock.Any()).Return(nil)
+		p.EXPECT().MarkUnhealthy()
+		p.EXPECT().Healthy().Return(true)
+
+		tracker := trackAcks(p)
+		g.Expect(tracker.Send(request(1))).To(Succeed())
+		tracker.MarkUnhealthy()
+
+		g.Expect(tracker.markedUnhealthy).To(BeFalse())
+	})
+
+	t.Run("reports the build events dropped once the backend was taken out of rotation", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+
+		p := besproxy_mock.NewMockBESProxy(ctrl)
+		p.EXPECT().Send(gomock.Any()).Return(nil)
+		p.EXPECT().MarkUnhealthy()
+		p.EXPECT().Healthy().Return(false)
+		p.EXPECT().Host().Return("grpc://bes")
+
+		tracker := trackAcks(p)
+		g.Expect(tracker.Send(request(1))).To(Succeed())
+		tracker.MarkUnhealthy()
+		tracker.drop()
+		tracker.drop()
+
+		var out bytes.Buffer
+		reports := reportAcks(&out, []*ackTracker{tracker}, time.Hour)
+		g.Expect(reports).To(Equal([]AckReport{{Host: "grpc://bes", Sent: 1, Dropped: 2}}))
+		g.Expect(out.String()).To(Equal("BES backend grpc://bes acknowledged 0 of 1 build events, and 2 were not sent to it; the invocation it received may be incomplete\n"))
+	})
+
+	t.Run("reports the reconnects of the invocation", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+
+		p := &reconnectingProxy{MockBESProxy: besproxy_mock.NewMockBESProxy(ctrl), reconnects: 2}
+		p.EXPECT().Host().Return("grpc://bes").AnyTimes()
+
+		tracker := trackAcks(p)
+		p.reconnects = 3
+		g.Expect(tracker.report(time.Now()).Reconnects).To(Equal(1))
+
+		tracker.reset()
+		g.Expect(tracker.report(time.Now()).Reconnects).To(BeZero())
+	})
+}
+
+// reconnectingProxy is a proxy that resumed its stream the given number of
+// times.
+type reconnectingProxy struct {
+	*besproxy_mock.MockBESProxy
+	reconnects int
+}
+
+func (p *reconnectingProxy) Reconnects() int {
+	return p.reconnects
+}
@@ -25,8 +25,11 @@ import (
 	"net"
 	"net/url"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fatih/color"
@@ -97,38 +100,120 @@ var _ BESBackend = (*besBackend)(nil)
 var _ buildv1.PublishBuildEventServer = (*besBackend)(nil)
 
 type besBackend struct {
-	besProxies    []besproxy.BESProxy
-	errors        *aspecterrors.ErrorList
-	errorsMutex   sync.RWMutex
-	once          sync.Once
-	grpcDialer    aspectgrpc.Dialer
-	grpcServer    aspectgrpc.Server
-	listener      net.Listener
-	netListen     func(network, address string) (net.Listener, error)
-	startServe    chan struct{}
-	ready         chan bool
-	subscribers   *subscriberList
-	mtSubscribers *subscriberList
+	besProxies []besproxy.BESProxy
+	// ackTrackers track the acknowledgements of the besProxies, and delivery
+	// adds up their reports.
+	ackTrackers []*ackTracker
+	delivery    deliveryReport
+	backends    []besproxy.Backend
+	// excludedEventTypes are the types of the build events that are not
+	// forwarded to the besProxies.
+	excludedEventTypes []string
+	errors             *aspecterrors.ErrorList
+	errorsMutex        sync.RWMutex
+	once               sync.Once
+	grpcDialer         aspectgrpc.Dialer
+	grpcServer         aspectgrpc.Server
+	listener           net.Listener
+	netListen          func(network, address string) (net.Listener, error)
+	startServe         chan struct{}
+	ready              chan bool
+	subscribers        *subscriberList
+	mtSubscribers      *subscriberList
+	timeouts           Timeouts
+	flowControl        besproxy.FlowControl
+	socketPath         string
+	command            string
+	// invocations counts the build event streams bazel opened, one per
+	// invocation.
+	invocations atomic.Int32
+	// lease hands the besProxies to one invocation at a time, and dial
+	// connects the invocations running concurrently to the backends.
+	lease streamLease
+	dial  dialFn
+
+	// sampling samples the build events forwarded to the besProxies.
+	sampling EventSampling
+
+	// listenAddress and listenPort are where the TCP port is bound.
+	listenAddress string
+	listenPort    int
+}
+
+// DefaultListenAddress is the address the BES backend is bound to by default.
+// It is never exposed to the network unless configured otherwise.
+const DefaultListenAddress = "127.0.0.1"
+
+// BESBackendOptions configures where the BES backend forwards the build events
+// to.
+type BESBackendOptions struct {
+	// Backends are the backends from the Aspect CLI config. The build events
+	// are forwarded to them as well as to those given with --bes_backend.
+	Backends []besproxy.Backend
+	// ExcludedEventTypes are the types of the build events that are not
+	// forwarded to the backends.
+	ExcludedEventTypes []string
+	// Sampling samples or aggregates the build events of high-volume types
+	// before they are forwarded to the backends.
+	Sampling EventSampling
+	// Timeouts bound how long to wait for the backends.
+	Timeouts Timeouts
+	// FlowControl tunes the streams to the backends.
+	FlowControl besproxy.FlowControl
+	// SocketPath is the Unix domain socket the backend is served on. It is
+	// served on a TCP port when empty.
+	SocketPath string
+	// ListenAddress is the IP address or host name the TCP port is bound to.
+	// DefaultListenAddress applies when empty.
+	ListenAddress string
+	// ListenPort is the TCP port the backend is served on. The OS assigns a
+	// free port when zero.
+	ListenPort int
+	// Command is the bazel command the build events are received from.
+	Command string
 }
 
 // NewBESBackend creates a new Build Event Protocol backend.
-func NewBESBackend() BESBackend {
+func NewBESBackend(opts BESBackendOptions) BESBackend {
 	return &besBackend{
-		besProxies:    []besproxy.BESProxy{},
-		errors:        &aspecterrors.ErrorList{},
-		grpcDialer:    aspectgrpc.NewDialer(),
-		netListen:     net.Listen,
-		startServe:    make(chan struct{}, 1),
-		ready:         make(chan bool, 1),
-		subscribers:   &subscriberList{},
-		mtSubscribers: &subscriberList{},
+		besProxies:         []besproxy.BESProxy{},
+		backends:           opts.Backends,
+		excludedEventTypes: opts.ExcludedEventTypes,
+		sampling:           opts.Sampling,
+		timeouts:           opts.Timeouts,
+		flowControl:        opts.FlowControl,
+		socketPath:         opts.SocketPath,
+		listenAddress:      opts.ListenAddress,
+		listenPort:         opts.ListenPort,
+		command:            opts.Command,
+		errors:             &aspecterrors.ErrorList{},
+		grpcDialer:         aspectgrpc.NewDialer(),
+		netListen:          net.Listen,
+		startServe:         make(chan struct{}, 1),
+		ready:              make(chan bool, 1),
+		subscribers:        &subscriberList{},
+		mtSubscribers:      &subscriberList{},
+		dial:               dialBackend,
 	}
 }
 
 // Setup sets up the gRPC server.
 func (bb *besBackend) Setup(opts ...grpc.ServerOption) error {
-	// Never expose this to the network.
-	lis, err := bb.netListen("tcp", "127.0.0.1:0")
+	var lis net.Listener
+	var err error
+	if bb.socketPath != "" {
+		// Remove the socket left behind by a process that was killed.
+		if err := os.Remove(bb.socketPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to setup BES backend: %w", err)
+		}
+		lis, err = bb.netListen("unix", bb.socketPath)
+	} else {
+		address := bb.listenAddress
+		if address == "" {
+			address = DefaultListenAddress
+		}
+		lis, err = bb.netListen("tcp", net.JoinHostPort(address, strconv.Itoa(bb.listenPort)))
+	}
 	if err != nil {
 		return fmt.Errorf("failed to setup BES backend: %w", err)
 	}
@@ -147,7 +232,10 @@ func (bb *besBackend) ServeWait(ctx context.Context) error {
 			errs <- err
 		}
 	}()
-	serverAddr := bb.listener.Addr().String()
+	serverAddr := bb.tcpAddr()
+	if bb.socketPath != "" {
+		serverAddr = bb.Addr()
+	}
 	for {
 		select {
 		case err := <-errs:
@@ -176,23 +264,51 @@ func (bb *besBackend) ServeWait(ctx context.Context) error {
 func (bb *besBackend) GracefulStop() {
 	defer bb.listener.Close()
 	bb.grpcServer.GracefulStop()
+	bb.delivery.print(os.Stderr)
 }
 
 // Addr returns the address for the gRPC server. Since the address is determined
-// by the OS based on an available port at the time the gRPC server starts, this
-// method returns the address to be used to construct the `bes_backend` flag
-// passed to the `bazel (build|test|run)` commands. The address includes the
-// scheme (protocol).
+// by the OS based on an available port at the time the gRPC server starts, unless
+// a port is configured, this method returns the address to be used to construct
+// the `bes_backend` flag passed to the `bazel (build|test|run)` commands. The
+// address includes the scheme (protocol), which is unix:// when served on a
+// Unix domain socket.
 func (bb *besBackend) Addr() string {
+	if bb.socketPath != "" {
+		url := url.URL{
+			Scheme: "unix",
+			Path:   bb.socketPath,
+		}
+		return url.String()
+	}
 	url := url.URL{
 		Scheme: "grpc",
-		Host:   bb.listener.Addr().String(),
+		Host:   bb.tcpAddr(),
 	}
 	return url.String()
 }
 
-func (bb *besBackend) Args() []string {
-	return []string{fmt.Sprintf("--bes_backend=%s", bb.Addr())}
+// tcpAddr returns the host and port bazel connects to, with an IPv6 host in
+// brackets. A backend bound to all interfaces is connected to on the loopback
+// interface.
+func (bb *besBackend) tcpAddr() string {
+	addr := bb.listener.Addr().String()
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		if ip.To4() != nil {
+			host = net.IPv4(127, 0, 0, 1).String()
+		} else {
+			host = net.IPv6loopback.String()
+		}
+	}
+	return net.JoinHostPort(host, port)
+}
+
+func (bb *besBackend) Args() ([]string, error) {
+	return []string{fmt.Sprintf("--bes_backend=%s", bb.Addr())}, nil
 }
 
 // Errors return the errors produced by the subscriber callback functions.
@@ -202,9 +318,18 @@ func (bb *besBackend) Errors() []error {
 	return bb.errors.Errors()
 }
 
+// DeliveryReports returns what was delivered to each BES backend over the
+// invocations streamed to the backend.
+func (bb *besBackend) DeliveryReports() []AckReport {
+	return bb.delivery.get()
+}
+
 // RegisterBesProxy registers a new build event stream proxy to send
 // Build Event Protocol events to.
 func (bb *besBackend) RegisterBesProxy(ctx context.Context, p besproxy.BESProxy) {
+	tracker := trackAcks(p)
+	bb.ackTrackers = append(bb.ackTrackers, tracker)
+	p = SampleEvents(FilterEvents(tracker, bb.excludedEventTypes), bb.sampling)
 	bb.besProxies = append(bb.besProxies, p)
 	err := p.PublishBuildToolEventStream(ctx, grpc.WaitForReady(false))
 	if err != nil {
@@ -213,17 +338,47 @@ func (bb *besBackend) RegisterBesProxy(ctx context.Context, p besproxy.BESProxy)
 	}
 }
 
+// acquireStreams returns the build event streams an invocation forwards its
+// build events on. The first one streams on the registered proxies, which are
+// reused by the next ones, as with --watch, keeping the connections to the
+// backends and their credentials. The invocations running concurrently with
+// the one holding them stream on connections of their own.
+func (bb *besBackend) acquireStreams(ctx context.Context, first bool) *invocationStreams {
+	if bb.lease.acquire() {
+		streams := &invocationStreams{proxies: bb.besProxies, trackers: bb.ackTrackers, leased: true}
+		// The streams of the first invocation were opened with the proxies.
+		if !first {
+			streams.open(ctx)
+		}
+		return streams
+	}
+	streams := dialInvocationStreams(bb.besProxies, bb.excludedEventTypes, bb.sampling, bb.dial)
+	streams.open(ctx)
+	return streams
+}
+
+// StreamInfo identifies the build event stream of a bazel invocation, so that
+// subscribers can correlate the build events with other systems.
+type StreamInfo struct {
+	BuildId      string
+	InvocationId string
+	// Command is the bazel command of the invocation, e.g. "build".
+	Command string
+}
+
 // CallbackFn is the signature for the callback function used by the subscribers
-// of the Build Event Protocol events.
-type CallbackFn func(*buildeventstream.BuildEvent, int64, string) error
+// of the Build Event Protocol events. It receives the build event, its sequence
+// number and the stream it belongs to.
+type CallbackFn func(*buildeventstream.BuildEvent, int64, StreamInfo) error
 
 // RegisterSubscriber registers a new subscriber callback function to the
-// Build Event Protocol events.
-func (bb *besBackend) RegisterSubscriber(callback CallbackFn, multiThreaded bool) {
-	if multiThreaded {
-		bb.mtSubscribers.Insert(callback)
+// Build Event Protocol events of the given types, or all events if none are
+// given.
+func (bb *besBackend) RegisterSubscriber(callback CallbackFn, opts SubscriberOptions, eventTypes ...string) {
+	if opts.MultiThreaded {
+		bb.mtSubscribers.Insert(callback, opts.Sampling, eventTypes...)
 	} else {
-		bb.subscribers.Insert(callback)
+		bb.subscribers.Insert(callback, opts.Sampling, eventTypes...)
 	}
 }
 
@@ -276,6 +431,9 @@ func (bb *besBackend) SendEventsToSubscribers(c <-chan *buildv1.PublishBuildTool
 		if subscribers.head == nil {
 			continue
 		}
+		if !subscribers.delivered.add(req) {
+			continue
+		}
 		event := req.GetOrderedBuildEvent().GetEvent()
 		if event != nil {
 			bazelEvent := event.GetBazelEvent()
@@ -286,15 +444,30 @@ func (bb *besBackend) SendEventsToSubscribers(c <-chan *buildv1.PublishBuildTool
 					fmt.Fprintf(os.Stderr, "Error unmarshaling build event %v: %s\n", req.GetOrderedBuildEvent().GetSequenceNumber(), err.Error())
 					continue
 				}
+				eventType := EventType(buildEvent)
+				streamId := req.GetOrderedBuildEvent().GetStreamId()
+				stream := StreamInfo{
+					BuildId:      streamId.GetBuildId(),
+					InvocationId: streamId.GetInvocationId(),
+					Command:      bb.command,
+				}
+				// The subscribers that are not multi-threaded receive the
+				// events of concurrent invocations one at a time.
+				if subscribers == bb.subscribers {
+					subscribers.calls.Lock()
+				}
 				s := subscribers.head
 				for s != nil {
-					if err := s.callback(buildEvent, req.GetOrderedBuildEvent().GetSequenceNumber(), req.GetOrderedBuildEvent().GetStreamId().GetInvocationId()); err != nil {
+					if err := s.receive(eventType, buildEvent, req.GetOrderedBuildEvent().GetSequenceNumber(), stream); err != nil {
 						bb.errorsMutex.Lock()
 						bb.errors.Insert(err)
 						bb.errorsMutex.Unlock()
 					}
 					s = s.next
 				}
+				if subscribers == bb.subscribers {
+					subscribers.calls.Unlock()
+				}
 			}
 		}
 	}
@@ -373,24 +546,36 @@ func (bb *besBackend) setupBesUpstreamBackends(ctx context.Context, optionsparse
 		)
 	}
 
-	if len(backends) > 0 {
+	flagBackends := make([]besproxy.Backend, 0, len(backends))
+	for _, backend := range backends {
+		headers := make(map[string]string)
+		maps.Copy(headers, globalRemoteHeaders)
+		if scoped, ok := scopedRemoteHeaders[backend]; ok {
+			maps.Copy(headers, scoped)
+		}
+		flagBackends = append(flagBackends, besproxy.Backend{URL: backend, Headers: headers})
+	}
+	upstreamBackends := MergeBackends(flagBackends, bb.backends)
+
+	if len(upstreamBackends) > 0 {
+		urls := make([]string, 0, len(upstreamBackends))
+		for _, backend := range upstreamBackends {
+			urls = append(urls, backend.URL)
+		}
 		fmt.Fprintf(
 			os.Stderr,
 			"%s BES backends: %s. Forwarding to all.\n",
 			color.GreenString("INFO:"),
-			strings.Join(backends, ", "),
+			strings.Join(urls, ", "),
 		)
 	}
 
-	for _, backend := range backends {
-		headers := make(map[string]string)
-		maps.Copy(headers, globalRemoteHeaders)
-		if scoped, ok := scopedRemoteHeaders[backend]; ok {
-			maps.Copy(headers, scoped)
-		}
-		besProxy := besproxy.NewBesProxy(backend, headers)
+	for _, backend := range upstreamBackends {
+		backend.Recovery = bb.timeouts.Recovery()
+		backend.FlowControl = bb.flowControl
+		besProxy := besproxy.NewBesProxyForBackend(backend)
 		if err := besProxy.Connect(); err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to connect to build event stream backend %s: %s", backend, err.Error())
+			fmt.Fprintf(os.Stderr, "Failed to connect to build event stream backend %s: %s", backend.URL, err.Error())
 		} else {
 			bb.RegisterBesProxy(ctx, besProxy)
 		}
@@ -398,6 +583,30 @@ func (bb *besBackend) setupBesUpstreamBackends(ctx context.Context, optionsparse
 	return nil
 }
 
+// MergeBackends adds the backends from the Aspect CLI config to those given
+// with --bes_backend. A configured backend with the same URL as a flag adds its
+// headers, which take precedence over those of --bes_header and
+// --remote_header, and its TLS settings and results URL to it.
+func MergeBackends(flagBackends []besproxy.Backend, configured []besproxy.Backend) []besproxy.Backend {
+	merged := slices.Clone(flagBackends)
+	for _, backend := range configured {
+		i := slices.IndexFunc(merged, func(b besproxy.Backend) bool { return b.URL == backend.URL })
+		if i < 0 {
+			merged = append(merged, backend)
+			continue
+		}
+		headers := maps.Clone(merged[i].Headers)
+		if headers == nil {
+			headers = map[string]string{}
+		}
+		maps.Copy(headers, backend.Headers)
+		merged[i].Headers = headers
+		merged[i].TLS = backend.TLS
+		merged[i].ResultsURL = backend.ResultsURL
+	}
+	return merged
+}
+
 // PublishBuildToolEventStream implements the gRPC PublishBuildToolEventStream
 // service.
 func (bb *besBackend) PublishBuildToolEventStream(
@@ -405,10 +614,22 @@ func (bb *besBackend) PublishBuildToolEventStream(
 ) error {
 	ctx := stream.Context()
 
+	// The backends are only set up once bazel reported its options in the
+	// first invocation, and the streams of each invocation once they are.
+	first := bb.invocations.Add(1) == 1
+	streams := sync.OnceValue(func() *invocationStreams {
+		<-bb.ready
+		return bb.acquireStreams(ctx, first)
+	})
+
 	eg, egCtx := errgroup.WithContext(ctx)
 
 	const numMultiSends = 10
 
+	// streamId identifies the stream bazel sends, for the links to the
+	// invocation printed once it ends.
+	var streamId *buildv1.StreamId
+
 	subChan := make(chan *buildv1.PublishBuildToolEventStreamRequest, 1000)
 	subMultiChan := make(chan *buildv1.PublishBuildToolEventStreamRequest, 1000)
 	fwdChan := make(chan *buildv1.PublishBuildToolEventStreamRequest, 1000)
@@ -435,6 +656,9 @@ func (bb *besBackend) PublishBuildToolEventStream(
 				// happen unless something has gone terribly wrong.
 				return fmt.Errorf("error receiving on build event stream from bazel server: %v", err.Error())
 			}
+			if streamId == nil {
+				streamId = req.GetOrderedBuildEvent().GetStreamId()
+			}
 			be := req.OrderedBuildEvent.Event.GetBazelEvent()
 			if be != nil {
 				var event *buildeventstream.BuildEvent = &buildeventstream.BuildEvent{}
@@ -493,10 +717,10 @@ func (bb *besBackend) PublishBuildToolEventStream(
 	}
 
 	eg.Go(func() error {
-		// Wait for ready event to start receiving acks from BES upstream proxies.
-		<-bb.ready
+		// The streams wait for the ready event to start receiving acks from BES
+		// upstream proxies.
 		// Goroutines to receive acks from BES proxies
-		for _, bp := range bb.besProxies {
+		for _, bp := range streams().proxies {
 			if !bp.Healthy() {
 				continue
 			}
@@ -530,10 +754,11 @@ func (bb *besBackend) PublishBuildToolEventStream(
 		for fwd := range fwdChanRead {
 			egFwd := errgroup.Group{}
 
-			for _, bp := range bb.besProxies {
+			for i, bp := range streams().proxies {
 				bp := bp // capture
 				egFwd.Go(func() error {
 					if !bp.Healthy() {
+						streams().trackers[i].drop()
 						return nil
 					}
 
@@ -554,7 +779,7 @@ func (bb *besBackend) PublishBuildToolEventStream(
 							bp.MarkUnhealthy()
 						}
 						return nil
-					case <-time.After(besSendTimeout):
+					case <-time.After(bb.timeouts.send()):
 						fmt.Fprintf(os.Stderr, "Timeout sending build event to %v: marking unhealthy\n", bp.Host())
 						bp.MarkUnhealthy()
 						return nil
@@ -566,7 +791,7 @@ func (bb *besBackend) PublishBuildToolEventStream(
 				// Optionally handle errors from sends, but since we log inside, perhaps no need to propagate
 			}
 		}
-		for _, bp := range bb.besProxies {
+		for _, bp := range streams().proxies {
 			if !bp.Healthy() {
 				continue
 			}
@@ -577,7 +802,16 @@ func (bb *besBackend) PublishBuildToolEventStream(
 		return nil
 	})
 
-	return eg.Wait()
+	err := eg.Wait()
+	trackers := streams().trackers
+	bb.lease.release(streams())
+	bb.delivery.add(reportAcks(os.Stderr, trackers, bb.timeouts.send()))
+	printResultsURLs(os.Stderr, trackers, StreamInfo{
+		BuildId:      streamId.GetBuildId(),
+		InvocationId: streamId.GetInvocationId(),
+		Command:      bb.command,
+	})
+	return err
 }
 
 // SubscriberList is a linked list for the Build Event Protocol event
@@ -585,12 +819,96 @@ func (bb *besBackend) PublishBuildToolEventStream(
 type subscriberList struct {
 	head *subscriberNode
 	tail *subscriberNode
+	// delivered are the build events the subscribers received.
+	delivered deliveredEvents
+	// calls serializes the calls to subscribers that are not multi-threaded.
+	calls sync.Mutex
+}
+
+// streamKey identifies the build event stream of an invocation.
+type streamKey struct {
+	buildId      string
+	invocationId string
+}
+
+// deliveredEvents tracks the build events delivered to subscribers. Bazel
+// sends the build events again with the same sequence numbers when it retries
+// an upload, and subscribers only receive them once.
+type deliveredEvents struct {
+	mu      sync.Mutex
+	streams map[streamKey]*deliveredStream
+}
+
+// deliveredStream holds the sequence numbers delivered from a build event
+// stream. Bazel numbers the events of a stream from 1 and resends them in
+// order, so the events up to through were all delivered. The multi-threaded
+// subscribers take the events concurrently, so the few that are delivered
+// ahead of the others are kept in ahead until the gap before them is filled.
+// Bazel closes the stream with a component stream finished event, numbered
+// finished, and the stream is forgotten once all the events up to it were
+// delivered.
+type deliveredStream struct {
+	through  int64
+	ahead    map[int64]struct{}
+	finished int64
+}
+
+// add records the build event of req as delivered, and returns false if it was
+// already delivered.
+func (d *deliveredEvents) add(req *buildv1.PublishBuildToolEventStreamRequest) bool {
+	event := req.GetOrderedBuildEvent()
+	key := streamKey{
+		buildId:      event.GetStreamId().GetBuildId(),
+		invocationId: event.GetStreamId().GetInvocationId(),
+	}
+	seqId := event.GetSequenceNumber()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.streams == nil {
+		d.streams = map[streamKey]*deliveredStream{}
+	}
+	stream := d.streams[key]
+	if stream == nil {
+		stream = &deliveredStream{}
+		d.streams[key] = stream
+	}
+	if seqId <= stream.through {
+		return false
+	}
+	if event.GetEvent().GetComponentStreamFinished() != nil {
+		stream.finished = seqId
+	}
+	if seqId != stream.through+1 {
+		if _, ok := stream.ahead[seqId]; ok {
+			return false
+		}
+		if stream.ahead == nil {
+			stream.ahead = map[int64]struct{}{}
+		}
+		stream.ahead[seqId] = struct{}{}
+		return true
+	}
+	stream.through = seqId
+	for {
+		if _, ok := stream.ahead[stream.through+1]; !ok {
+			break
+		}
+		delete(stream.ahead, stream.through+1)
+		stream.through++
+	}
+	if stream.finished != 0 && stream.through >= stream.finished {
+		delete(d.streams, key)
+	}
+	return true
 }
 
 // Insert inserts a new Build Event Protocol event callback into the linked
-// list.
-func (l *subscriberList) Insert(callback CallbackFn) {
-	node := &subscriberNode{callback: callback}
+// list. The callback only receives events of the given types, or all events if
+// none are given.
+func (l *subscriberList) Insert(callback CallbackFn, sampling EventSampling, eventTypes ...string) {
+	node := newSubscriberNode(callback, eventTypes...)
+	node.sampler = newEventSampler[StreamInfo](sampling)
 	if l.head == nil {
 		l.head = node
 	} else {
@@ -600,6 +918,52 @@ func (l *subscriberList) Insert(callback CallbackFn) {
 }
 
 type subscriberNode struct {
-	next     *subscriberNode
-	callback CallbackFn
+	next       *subscriberNode
+	callback   CallbackFn
+	eventTypes map[string]struct{}
+	// sampler samples the build events before they are delivered, if the
+	// subscriber has a sampling.
+	sampler *eventSampler[StreamInfo]
+}
+
+func newSubscriberNode(callback CallbackFn, eventTypes ...string) *subscriberNode {
+	node := &subscriberNode{callback: callback}
+	if len(eventTypes) > 0 {
+		node.eventTypes = make(map[string]struct{}, len(eventTypes))
+		for _, eventType := range eventTypes {
+			node.eventTypes[eventType] = struct{}{}
+		}
+	}
+	return node
+}
+
+// wants returns true if the subscriber receives events of the given type.
+func (n *subscriberNode) wants(eventType string) bool {
+	if n.eventTypes == nil {
+		return true
+	}
+	_, ok := n.eventTypes[eventType]
+	return ok
+}
+
+// receive delivers a build event of the given type to the subscriber if it
+// wants events of its type. A sampled subscriber receives the events that the
+// sampler forwards in place of the event instead.
+func (n *subscriberNode) receive(eventType string, event *buildeventstream.BuildEvent, seqId int64, stream StreamInfo) error {
+	if n.sampler == nil {
+		if !n.wants(eventType) {
+			return nil
+		}
+		return n.callback(event, seqId, stream)
+	}
+	var errs []error
+	for _, e := range n.sampler.sample(stream.InvocationId, event, seqId, stream) {
+		if !n.wants(EventType(e.event)) {
+			continue
+		}
+		if err := n.callback(e.event, e.seqId, e.carrier); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
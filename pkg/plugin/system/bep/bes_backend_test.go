@@ -21,7 +21,10 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/gomega"
@@ -65,6 +68,74 @@ func TestSetup(t *testing.T) {
 
 		g.Expect(err).To(BeNil())
 	})
+
+	t.Run("binds the configured address and port", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		for _, tt := range []struct {
+			address string
+			port    int
+			want    string
+		}{
+			{"", 0, "127.0.0.1:0"},
+			{"0.0.0.0", 8080, "0.0.0.0:8080"},
+			{"::1", 8080, "[::1]:8080"},
+		} {
+			var bound string
+			besBackend := NewBESBackend(BESBackendOptions{ListenAddress: tt.address, ListenPort: tt.port}).(*besBackend)
+			besBackend.netListen = func(network, address string) (net.Listener, error) {
+				bound = address
+				return nil, nil
+			}
+			g.Expect(besBackend.Setup()).To(Succeed())
+			g.Expect(bound).To(Equal(tt.want))
+		}
+	})
+
+	t.Run("passes the loopback address to bazel when bound to all interfaces", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+
+		for addr, want := range map[string]string{
+			"0.0.0.0:8080": "grpc://127.0.0.1:8080",
+			"[::]:8080":    "grpc://[::1]:8080",
+			"[::1]:8080":   "grpc://[::1]:8080",
+		} {
+			netAddr := stdlib_mock.NewMockNetAddr(ctrl)
+			netAddr.EXPECT().String().Return(addr)
+			listener := stdlib_mock.NewMockNetListener(ctrl)
+			listener.EXPECT().Addr().Return(netAddr)
+
+			besBackend := &besBackend{listener: listener}
+			g.Expect(besBackend.Addr()).To(Equal(want))
+		}
+	})
+
+	t.Run("serves on a Unix domain socket", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		// Unix domain socket paths are limited to about 100 characters, which
+		// t.TempDir() may exceed.
+		dir, err := os.MkdirTemp("", "bes")
+		g.Expect(err).ToNot(HaveOccurred())
+		t.Cleanup(func() { os.RemoveAll(dir) })
+		socketPath := filepath.Join(dir, "bes.sock")
+		// A socket left behind by a process that was killed.
+		g.Expect(os.WriteFile(socketPath, nil, 0o600)).To(Succeed())
+
+		besBackend := NewBESBackend(BESBackendOptions{SocketPath: socketPath})
+		g.Expect(besBackend.Setup()).To(Succeed())
+		g.Expect(besBackend.Addr()).To(Equal("unix://" + socketPath))
+		g.Expect(besBackend.Args()).To(Equal([]string{"--bes_backend=unix://" + socketPath}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		g.Expect(besBackend.ServeWait(ctx)).To(Succeed())
+		besBackend.GracefulStop()
+
+		_, err = os.Stat(socketPath)
+		g.Expect(os.IsNotExist(err)).To(BeTrue())
+	})
 }
 
 func TestServeWait(t *testing.T) {
@@ -398,29 +469,29 @@ func TestPublishBuildToolEventStream(t *testing.T) {
 		}
 		close(besBackend.ready)
 		var calledSubscriber1, calledSubscriber2, calledSubscriber3 bool
-		besBackend.RegisterSubscriber(func(evt *buildeventstream.BuildEvent, sn int64, invocationId string) error {
+		besBackend.RegisterSubscriber(func(evt *buildeventstream.BuildEvent, sn int64, stream StreamInfo) error {
 			// g.Expect(evt).To(Equal(buildEvent))
 			g.Expect(sn).To(Equal(orderedBuildEvent.SequenceNumber))
-			g.Expect(invocationId).To(Equal(orderedBuildEvent.StreamId.InvocationId))
+			g.Expect(stream.InvocationId).To(Equal(orderedBuildEvent.StreamId.InvocationId))
 			calledSubscriber1 = true
 			return nil
-		}, false)
+		}, SubscriberOptions{})
 		expectedSubscriber2Err := fmt.Errorf("error from subscriber 2")
-		besBackend.RegisterSubscriber(func(evt *buildeventstream.BuildEvent, sn int64, invocationId string) error {
+		besBackend.RegisterSubscriber(func(evt *buildeventstream.BuildEvent, sn int64, stream StreamInfo) error {
 			// g.Expect(evt).To(Equal(buildEvent))
 			g.Expect(sn).To(Equal(orderedBuildEvent.SequenceNumber))
-			g.Expect(invocationId).To(Equal(orderedBuildEvent.StreamId.InvocationId))
+			g.Expect(stream.InvocationId).To(Equal(orderedBuildEvent.StreamId.InvocationId))
 			calledSubscriber2 = true
 			return expectedSubscriber2Err
-		}, false)
+		}, SubscriberOptions{})
 		expectedSubscriber3Err := fmt.Errorf("error from subscriber 3")
-		besBackend.RegisterSubscriber(func(evt *buildeventstream.BuildEvent, sn int64, invocationId string) error {
+		besBackend.RegisterSubscriber(func(evt *buildeventstream.BuildEvent, sn int64, stream StreamInfo) error {
 			// g.Expect(evt).To(Equal(buildEvent))
 			g.Expect(sn).To(Equal(orderedBuildEvent.SequenceNumber))
-			g.Expect(invocationId).To(Equal(orderedBuildEvent.StreamId.InvocationId))
+			g.Expect(stream.InvocationId).To(Equal(orderedBuildEvent.StreamId.InvocationId))
 			calledSubscriber3 = true
 			return expectedSubscriber3Err
-		}, false)
+		}, SubscriberOptions{})
 
 		eventStream.
 			EXPECT().
@@ -523,6 +594,18 @@ func TestPublishBuildToolEventStream(t *testing.T) {
 			Recv().
 			Return(nil, io.EOF).
 			Times(1)
+		// The backend didn't acknowledge the build event, which is reported.
+		besProxy.
+			EXPECT().
+			Host().
+			Return("grpc://bes").
+			Times(1)
+		// The backend has no results URL to print the link to the invocation.
+		besProxy.
+			EXPECT().
+			Backend().
+			Return(besproxy.Backend{URL: "grpc://bes"}).
+			Times(1)
 		eventStream.
 			EXPECT().
 			Context().
@@ -533,3 +616,317 @@ func TestPublishBuildToolEventStream(t *testing.T) {
 		g.Expect(err).To(Not(HaveOccurred()))
 	})
 }
+
+func TestAcquireStreams(t *testing.T) {
+	t.Run("opens a new stream to the backends on their connections", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx := context.Background()
+		besBackend := &besBackend{
+			besProxies: []besproxy.BESProxy{},
+			errors:     &aspecterrors.ErrorList{},
+		}
+		besProxy := besproxy_mock.NewMockBESProxy(ctrl)
+		// The stream of the first invocation and that of the next one.
+		besProxy.
+			EXPECT().
+			PublishBuildToolEventStream(ctx, grpc.WaitForReady(false)).
+			Return(nil).
+			Times(2)
+		besBackend.RegisterBesProxy(ctx, besProxy)
+
+		// The first invocation was streamed completely.
+		streams := besBackend.acquireStreams(ctx, true)
+		tracker := besBackend.ackTrackers[0]
+		tracker.sent, tracker.acked, tracker.closed = 3, 3, true
+		besBackend.lease.release(streams)
+
+		streams = besBackend.acquireStreams(ctx, false)
+
+		g.Expect(streams.trackers).To(Equal([]*ackTracker{tracker}))
+		g.Expect(tracker.sent).To(BeZero())
+		g.Expect(tracker.acked).To(BeZero())
+		g.Expect(tracker.closed).To(BeFalse())
+	})
+
+	t.Run("connects the concurrent invocations to the backends", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx := context.Background()
+		backend := besproxy.Backend{URL: "grpcs://bes.example.com"}
+		registered := besproxy_mock.NewMockBESProxy(ctrl)
+		registered.EXPECT().PublishBuildToolEventStream(ctx, grpc.WaitForReady(false)).Return(nil)
+		registered.EXPECT().Backend().Return(backend)
+		dialed := besproxy_mock.NewMockBESProxy(ctrl)
+		dialed.EXPECT().PublishBuildToolEventStream(ctx, grpc.WaitForReady(false)).Return(nil)
+
+		besBackend := &besBackend{
+			besProxies: []besproxy.BESProxy{},
+			errors:     &aspecterrors.ErrorList{},
+			dial: func(b besproxy.Backend) (besproxy.BESProxy, error) {
+				g.Expect(b).To(Equal(backend))
+				return dialed, nil
+			},
+		}
+		besBackend.RegisterBesProxy(ctx, registered)
+
+		first := besBackend.acquireStreams(ctx, true)
+		concurrent := besBackend.acquireStreams(ctx, false)
+
+		g.Expect(first.leased).To(BeTrue())
+		g.Expect(concurrent.leased).To(BeFalse())
+		g.Expect(concurrent.trackers).To(HaveLen(1))
+		g.Expect(concurrent.trackers[0].BESProxy).To(BeIdenticalTo(dialed))
+	})
+}
+
+func TestSendEventsToSubscribers(t *testing.T) {
+	t.Run("only sends events of the types subscribed to", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		newRequest := func(sn int64, event *buildeventstream.BuildEvent) *buildv1.PublishBuildToolEventStreamRequest {
+			anyBuildEvent, err := anypb.New(event)
+			g.Expect(err).ToNot(HaveOccurred())
+			return &buildv1.PublishBuildToolEventStreamRequest{
+				OrderedBuildEvent: &buildv1.OrderedBuildEvent{
+					StreamId:       &buildv1.StreamId{InvocationId: "1"},
+					SequenceNumber: sn,
+					Event:          &buildv1.BuildEvent{Event: &buildv1.BuildEvent_BazelEvent{BazelEvent: anyBuildEvent}},
+				},
+			}
+		}
+
+		c := make(chan *buildv1.PublishBuildToolEventStreamRequest, 3)
+		c <- newRequest(1, &buildeventstream.BuildEvent{
+			Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_Progress{}},
+		})
+		c <- newRequest(2, &buildeventstream.BuildEvent{
+			Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_TestResult{}},
+		})
+		c <- newRequest(3, &buildeventstream.BuildEvent{
+			Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_BuildFinished{}},
+		})
+		close(c)
+
+		besBackend := &besBackend{
+			subscribers:   &subscriberList{},
+			mtSubscribers: &subscriberList{},
+			errors:        &aspecterrors.ErrorList{},
+		}
+		var all, filtered []int64
+		besBackend.RegisterSubscriber(func(evt *buildeventstream.BuildEvent, sn int64, stream StreamInfo) error {
+			all = append(all, sn)
+			return nil
+		}, SubscriberOptions{})
+		besBackend.RegisterSubscriber(func(evt *buildeventstream.BuildEvent, sn int64, stream StreamInfo) error {
+			filtered = append(filtered, sn)
+			return nil
+		}, SubscriberOptions{}, "test_result", "build_finished")
+
+		besBackend.SendEventsToSubscribers(c, besBackend.subscribers)
+
+		g.Expect(all).To(Equal([]int64{1, 2, 3}))
+		g.Expect(filtered).To(Equal([]int64{2, 3}))
+	})
+
+	t.Run("suppresses the events that were already delivered", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		newRequest := func(invocationId string, sn int64) *buildv1.PublishBuildToolEventStreamRequest {
+			anyBuildEvent, err := anypb.New(&buildeventstream.BuildEvent{
+				Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_Progress{}},
+			})
+			g.Expect(err).ToNot(HaveOccurred())
+			return &buildv1.PublishBuildToolEventStreamRequest{
+				OrderedBuildEvent: &buildv1.OrderedBuildEvent{
+					StreamId:       &buildv1.StreamId{BuildId: "build", InvocationId: invocationId},
+					SequenceNumber: sn,
+					Event:          &buildv1.BuildEvent{Event: &buildv1.BuildEvent_BazelEvent{BazelEvent: anyBuildEvent}},
+				},
+			}
+		}
+
+		besBackend := &besBackend{
+			subscribers:   &subscriberList{},
+			mtSubscribers: &subscriberList{},
+			errors:        &aspecterrors.ErrorList{},
+		}
+		type delivered struct {
+			invocationId string
+			sn           int64
+		}
+		var received []delivered
+		besBackend.RegisterSubscriber(func(evt *buildeventstream.BuildEvent, sn int64, stream StreamInfo) error {
+			received = append(received, delivered{stream.InvocationId, sn})
+			return nil
+		}, SubscriberOptions{})
+
+		// Bazel resends the events of a stream it retries.
+		first := make(chan *buildv1.PublishBuildToolEventStreamRequest, 2)
+		first <- newRequest("1", 1)
+		first <- newRequest("1", 2)
+		close(first)
+		besBackend.SendEventsToSubscribers(first, besBackend.subscribers)
+
+		retried := make(chan *buildv1.PublishBuildToolEventStreamRequest, 4)
+		retried <- newRequest("1", 1)
+		retried <- newRequest("1", 2)
+		retried <- newRequest("1", 3)
+		retried <- newRequest("2", 1)
+		close(retried)
+		besBackend.SendEventsToSubscribers(retried, besBackend.subscribers)
+
+		g.Expect(received).To(Equal([]delivered{{"1", 1}, {"1", 2}, {"1", 3}, {"2", 1}}))
+	})
+
+	t.Run("only keeps the sequence numbers delivered out of order", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		newRequest := func(sn int64) *buildv1.PublishBuildToolEventStreamRequest {
+			return &buildv1.PublishBuildToolEventStreamRequest{
+				OrderedBuildEvent: &buildv1.OrderedBuildEvent{
+					StreamId:       &buildv1.StreamId{BuildId: "build", InvocationId: "1"},
+					SequenceNumber: sn,
+				},
+			}
+		}
+
+		var d deliveredEvents
+		// The multi-threaded subscribers can take 3 before 2.
+		g.Expect(d.add(newRequest(1))).To(BeTrue())
+		g.Expect(d.add(newRequest(3))).To(BeTrue())
+		g.Expect(d.add(newRequest(3))).To(BeFalse())
+		g.Expect(d.add(newRequest(2))).To(BeTrue())
+
+		stream := d.streams[streamKey{buildId: "build", invocationId: "1"}]
+		g.Expect(stream.through).To(Equal(int64(3)))
+		g.Expect(stream.ahead).To(BeEmpty())
+
+		for sn := int64(1); sn <= 3; sn++ {
+			g.Expect(d.add(newRequest(sn))).To(BeFalse())
+		}
+		g.Expect(d.add(newRequest(4))).To(BeTrue())
+	})
+
+	t.Run("forgets the streams that finished", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		newRequest := func(invocationId string, sn int64) *buildv1.PublishBuildToolEventStreamRequest {
+			anyBuildEvent, err := anypb.New(&buildeventstream.BuildEvent{
+				Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_Progress{}},
+			})
+			g.Expect(err).ToNot(HaveOccurred())
+			return &buildv1.PublishBuildToolEventStreamRequest{
+				OrderedBuildEvent: &buildv1.OrderedBuildEvent{
+					StreamId:       &buildv1.StreamId{BuildId: "build", InvocationId: invocationId},
+					SequenceNumber: sn,
+					Event:          &buildv1.BuildEvent{Event: &buildv1.BuildEvent_BazelEvent{BazelEvent: anyBuildEvent}},
+				},
+			}
+		}
+		newFinished := func(invocationId string, sn int64) *buildv1.PublishBuildToolEventStreamRequest {
+			return &buildv1.PublishBuildToolEventStreamRequest{
+				OrderedBuildEvent: &buildv1.OrderedBuildEvent{
+					StreamId:       &buildv1.StreamId{BuildId: "build", InvocationId: invocationId},
+					SequenceNumber: sn,
+					Event: &buildv1.BuildEvent{Event: &buildv1.BuildEvent_ComponentStreamFinished{
+						ComponentStreamFinished: &buildv1.BuildEvent_BuildComponentStreamFinished{
+							Type: buildv1.BuildEvent_BuildComponentStreamFinished_FINISHED,
+						},
+					}},
+				},
+			}
+		}
+
+		besBackend := &besBackend{
+			subscribers:   &subscriberList{},
+			mtSubscribers: &subscriberList{},
+			errors:        &aspecterrors.ErrorList{},
+		}
+		var received []int64
+		besBackend.RegisterSubscriber(func(evt *buildeventstream.BuildEvent, sn int64, stream StreamInfo) error {
+			received = append(received, sn)
+			return nil
+		}, SubscriberOptions{})
+
+		for _, invocationId := range []string{"1", "2"} {
+			c := make(chan *buildv1.PublishBuildToolEventStreamRequest, 3)
+			c <- newRequest(invocationId, 1)
+			c <- newRequest(invocationId, 2)
+			c <- newFinished(invocationId, 3)
+			close(c)
+			besBackend.SendEventsToSubscribers(c, besBackend.subscribers)
+		}
+
+		g.Expect(received).To(Equal([]int64{1, 2, 1, 2}))
+		g.Expect(besBackend.subscribers.delivered.streams).To(BeEmpty())
+	})
+
+	t.Run("forgets a stream that finished ahead of its other events", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		newRequest := func(sn int64, event *buildv1.BuildEvent) *buildv1.PublishBuildToolEventStreamRequest {
+			return &buildv1.PublishBuildToolEventStreamRequest{
+				OrderedBuildEvent: &buildv1.OrderedBuildEvent{
+					StreamId:       &buildv1.StreamId{BuildId: "build", InvocationId: "1"},
+					SequenceNumber: sn,
+					Event:          event,
+				},
+			}
+		}
+		finished := &buildv1.BuildEvent{Event: &buildv1.BuildEvent_ComponentStreamFinished{
+			ComponentStreamFinished: &buildv1.BuildEvent_BuildComponentStreamFinished{},
+		}}
+
+		var d deliveredEvents
+		g.Expect(d.add(newRequest(1, nil))).To(BeTrue())
+		g.Expect(d.add(newRequest(3, finished))).To(BeTrue())
+		g.Expect(d.streams).To(HaveLen(1))
+		g.Expect(d.add(newRequest(2, nil))).To(BeTrue())
+		g.Expect(d.streams).To(BeEmpty())
+	})
+}
+
+func TestEventType(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(EventType(&buildeventstream.BuildEvent{
+		Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_TargetCompleted{}},
+	})).To(Equal("target_completed"))
+	g.Expect(EventType(&buildeventstream.BuildEvent{})).To(Equal(""))
+
+	g.Expect(IsEventType("test_result")).To(BeTrue())
+	g.Expect(IsEventType("TestResult")).To(BeFalse())
+}
+
+func TestMergeBackends(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	flagBackends := []besproxy.Backend{
+		{URL: "grpcs://bes.example.com", Headers: map[string]string{"x-api-key": "flag", "x-build": "1"}},
+		{URL: "grpc://localhost:1985", Headers: map[string]string{}},
+	}
+	configured := []besproxy.Backend{
+		{
+			URL:     "grpcs://bes.example.com",
+			Headers: map[string]string{"x-api-key": "config"},
+			TLS:     besproxy.TLSConfig{ServerName: "bes.internal"},
+		},
+		{URL: "grpcs://other.example.com", Headers: map[string]string{"x-org-id": "42"}},
+	}
+
+	g.Expect(MergeBackends(flagBackends, configured)).To(Equal([]besproxy.Backend{
+		{
+			URL:     "grpcs://bes.example.com",
+			Headers: map[string]string{"x-api-key": "config", "x-build": "1"},
+			TLS:     besproxy.TLSConfig{ServerName: "bes.internal"},
+		},
+		{URL: "grpc://localhost:1985", Headers: map[string]string{}},
+		{URL: "grpcs://other.example.com", Headers: map[string]string{"x-org-id": "42"}},
+	}))
+	g.Expect(flagBackends[0].Headers["x-api-key"]).To(Equal("flag"))
+}
@@ -31,14 +31,13 @@ import (
 	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/aspecterrors"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/besproxy"
+	"github.com/google/uuid"
 	"golang.org/x/sync/errgroup"
-	buildv1 "google.golang.org/genproto/googleapis/devtools/build/v1"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
-	"google.golang.org/protobuf/encoding/protodelim"
-	"google.golang.org/protobuf/types/known/anypb"
-	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
 )
 
 type BESPipeInterceptor interface {
@@ -47,53 +46,319 @@ type BESPipeInterceptor interface {
 }
 
 const besEventGlobalTimeoutDuration = 5 * time.Minute
-const besEventThrottleDuration = 50 * time.Millisecond
 const besSendTimeout = 1 * time.Minute
+const besProbeInterval = 30 * time.Second
+const besOutageTimeout = 10 * time.Minute
+
+// Timeouts bound how long to wait for bazel and the BES backends. Durations
+// that are not set use the defaults.
+type Timeouts struct {
+	// Event is how long to wait for the next build event from bazel before
+	// giving up on the stream. It defaults to 5 minutes.
+	Event time.Duration
+	// Send is how long to wait for a backend to accept a build event before
+	// marking it unhealthy. It defaults to 1 minute.
+	Send time.Duration
+	// Probe is how often a backend marked unhealthy is probed to resume its
+	// stream. It defaults to 30 seconds.
+	Probe time.Duration
+	// Outage is how long a backend marked unhealthy is probed before it is
+	// taken out of rotation. It defaults to 10 minutes.
+	Outage time.Duration
+}
+
+func (t Timeouts) event() time.Duration {
+	if t.Event > 0 {
+		return t.Event
+	}
+	return besEventGlobalTimeoutDuration
+}
+
+func (t Timeouts) send() time.Duration {
+	if t.Send > 0 {
+		return t.Send
+	}
+	return besSendTimeout
+}
+
+// Recovery returns how the streams to the backends are resumed after they
+// were marked unhealthy.
+func (t Timeouts) Recovery() besproxy.Recovery {
+	recovery := besproxy.Recovery{ProbeInterval: besProbeInterval, Timeout: besOutageTimeout}
+	if t.Probe > 0 {
+		recovery.ProbeInterval = t.Probe
+	}
+	if t.Outage > 0 {
+		recovery.Timeout = t.Outage
+	}
+	return recovery
+}
+
+// UnhealthyPolicy is what the BES pipe does once every BES backend is
+// unhealthy.
+type UnhealthyPolicy string
+
+const (
+	// UnhealthyAbort unlinks the pipe bazel writes the build events to, so that
+	// bazel aborts the upload. This is the default.
+	UnhealthyAbort UnhealthyPolicy = "abort"
+	// UnhealthyContinue keeps reading the build events for the plugins and lets
+	// the build finish locally, without uploading the rest of them.
+	UnhealthyContinue UnhealthyPolicy = "continue-without-bes"
+	// UnhealthySpool keeps reading the build events and spools them to the
+	// deferred uploads directory, to upload them later.
+	UnhealthySpool UnhealthyPolicy = "spool"
+)
+
+// ParseUnhealthyPolicy returns the UnhealthyPolicy named policy, and
+// UnhealthyAbort if it is empty.
+func ParseUnhealthyPolicy(policy string) (UnhealthyPolicy, error) {
+	switch p := UnhealthyPolicy(policy); p {
+	case "":
+		return UnhealthyAbort, nil
+	case UnhealthyAbort, UnhealthyContinue, UnhealthySpool:
+		return p, nil
+	}
+	return "", fmt.Errorf("expected the policy when all BES backends are unhealthy to be one of %s, %s or %s: %q", UnhealthyAbort, UnhealthyContinue, UnhealthySpool, policy)
+}
+
+// UploadMode is the --build_event_binary_file_upload_mode bazel writes the
+// build events to the BES pipe with, which decides whether bazel waits for
+// them to be read before the command ends.
+type UploadMode string
+
+const (
+	// UploadWait waits for the build events to be uploaded before the command
+	// ends, so that the backends receive the complete invocation.
+	UploadWait UploadMode = "wait_for_upload_complete"
+	// UploadNoWait waits for the upload at the start of the next command
+	// rather than at the end of this one.
+	UploadNoWait UploadMode = "nowait_for_upload_complete"
+	// UploadFullyAsync doesn't wait for the upload at all.
+	UploadFullyAsync UploadMode = "fully_async"
+)
+
+// ParseUploadMode returns the UploadMode named mode. It returns an empty mode
+// if mode is empty, for bazel's default.
+func ParseUploadMode(mode string) (UploadMode, error) {
+	switch m := UploadMode(mode); m {
+	case "", UploadWait, UploadNoWait, UploadFullyAsync:
+		return m, nil
+	}
+	return "", fmt.Errorf("expected the upload mode of the BES pipe to be one of %s, %s or %s: %q", UploadWait, UploadNoWait, UploadFullyAsync, mode)
+}
+
+// BESPipeOptions configures what the BES pipe does with the build events
+// besides passing them to the plugins and backends.
+type BESPipeOptions struct {
+	// Spool is a copy of the build event stream written to disk.
+	Spool Spool
+	// UnhealthyPolicy is what happens once all backends are unhealthy. With
+	// UnhealthySpool, the build events that couldn't be uploaded to a backend
+	// are kept in the deferred uploads directory, and the pending uploads are
+	// flushed by the next commands that use the BES pipe, and by
+	// `aspect bep flush`. UnhealthyAbort applies when empty.
+	UnhealthyPolicy UnhealthyPolicy
+	// ExcludedEventTypes are the types of the build events that are not
+	// forwarded to the backends.
+	ExcludedEventTypes []string
+	// Sampling samples or aggregates the build events of high-volume types
+	// before they are forwarded to the backends.
+	Sampling EventSampling
+	// Timeouts bound how long to wait for bazel and the backends.
+	Timeouts Timeouts
+	// MaxEventSize bounds the size of a build event in bytes.
+	// DefaultMaxEventSize applies when zero.
+	MaxEventSize int
+	// UploadMode is how bazel waits for the build events to be read. When
+	// empty, UploadWait applies if ASPECT_BEP_USE_PIPE is set, and bazel's
+	// default otherwise.
+	UploadMode UploadMode
+	// Keywords are the notification keywords sent with the first build
+	// event of each invocation, as bazel does with --bes_keywords.
+	Keywords []string
+	// Command is the bazel command writing the build events.
+	Command string
+}
 
-func NewBESPipe(buildId, invocationId string) (BESPipeInterceptor, error) {
+func NewBESPipe(buildId, invocationId string, opts BESPipeOptions) (BESPipeInterceptor, error) {
 	return &besPipe{
-		bepBinPath:  path.Join(os.TempDir(), fmt.Sprintf("aspect-cli-%v-bes.bin", os.Getpid())),
-		errors:      &aspecterrors.ErrorList{},
-		subscribers: &subscriberList{},
+		bepBinPath:     path.Join(os.TempDir(), fmt.Sprintf("aspect-cli-%v-bes.bin", os.Getpid())),
+		spool:          opts.Spool,
+		deferredUpload: opts.UnhealthyPolicy == UnhealthySpool,
+		unhealthy:      opts.UnhealthyPolicy,
+		errors:         &aspecterrors.ErrorList{},
+		subscribers:    &subscriberList{},
+
+		excludedEventTypes: opts.ExcludedEventTypes,
+		sampling:           opts.Sampling,
+		timeouts:           opts.Timeouts,
+		maxEventSize:       opts.MaxEventSize,
+		uploadMode:         opts.UploadMode,
+		keywords:           opts.Keywords,
 
 		besBuildId:      buildId,
 		besInvocationId: invocationId,
-		wg:              &sync.WaitGroup{},
+		command:         opts.Command,
+		enqueued:        time.Now(),
+		invocationIds:   map[string]bool{},
+		dial:            dialBackend,
 	}, nil
 }
 
 type besPipe struct {
-	bepBinPath   string
-	bepBinOpened bool
+	bepBinPath string
+	spool      Spool
+	timeouts   Timeouts
+
+	deferredUpload bool
+	// unhealthy is what happens once all the backends of an invocation are
+	// unhealthy.
+	unhealthy UnhealthyPolicy
+	// flushed is closed once the deferred uploads of earlier commands have
+	// been flushed.
+	flushed chan struct{}
 
 	errors      *aspecterrors.ErrorList
 	errorsMutex sync.RWMutex
 	subscribers *subscriberList
+	// subscriberPools deliver build events to the multi-threaded subscribers.
+	subscriberPools      []*subscriberPool
+	closeSubscriberPools sync.Once
+	// submitMu serializes the submissions to the subscriberPools of the
+	// concurrent invocations.
+	submitMu sync.Mutex
 
 	besBuildId      string
 	besInvocationId string
+	command         string
 	besProxies      []besproxy.BESProxy
+	// ackTrackers track the acknowledgements of the besProxies, and delivery
+	// adds up their reports.
+	ackTrackers []*ackTracker
+	delivery    deliveryReport
+	// excludedEventTypes are the types of the build events that are not
+	// forwarded to the besProxies.
+	excludedEventTypes []string
+	// sampling samples the build events forwarded to the besProxies.
+	sampling EventSampling
+	// enqueued is when the first invocation started.
+	enqueued time.Time
+	// maxEventSize bounds the size of a build event.
+	maxEventSize int
+	// uploadMode is the upload mode bazel writes the build events with.
+	uploadMode UploadMode
+	// keywords are the notification keywords of the invocations.
+	keywords []string
+
+	// lease hands the besProxies to one invocation at a time, and dial
+	// connects the invocations running concurrently to the backends.
+	lease streamLease
+	dial  dialFn
+
+	// mu guards the fields below.
+	mu sync.Mutex
+	// fifos are the named pipes bazel writes the build events to: the one
+	// created by Setup, and one per further bazel command given Args.
+	fifos []*pipeFifo
+	// first is the invocation bazel writes first to the pipe created by
+	// Setup. Its streams to the backends are opened as they are registered.
+	first *pipeInvocation
+	// invocationIds are the IDs of the invocations, which are unique.
+	invocationIds map[string]bool
+	// argsCalls counts the calls to Args.
+	argsCalls int
+	// ctx is the context of ServeWait, once it was called, and stopping is set
+	// once GracefulStop was called.
+	ctx      context.Context
+	stopping bool
+
+	// userSpool is the copy of the build events of all the invocations written
+	// to spool.Path, opened by the first pipe bazel writes to.
+	userSpool     *spoolWriter
+	userSpoolOnce sync.Once
+	userSpoolMu   sync.Mutex
+}
+
+// pipeFifo is a named pipe bazel writes the build events of one or more
+// invocations to, one after the other, as with --watch.
+type pipeFifo struct {
+	path string
+	// invocationId is the ID given to bazel with Args, which the first
+	// invocation written to the pipe has.
+	invocationId string
+	// invocations counts the invocations read from the pipe.
+	invocations int
+	// conn is the read end of the pipe once bazel opened it, and idle is set
+	// between two invocations.
+	conn *os.File
+	idle bool
+	// aborted tracks whether the pipe was unlinked due to backend failure.
+	aborted sync.Once
+	// done is closed once the build events were read from the pipe.
+	done chan struct{}
+}
 
-	// Track whether we have already unlinked the pipe due to backend failure
-	pipeAborted sync.Once
+func newPipeFifo(path, invocationId string) *pipeFifo {
+	return &pipeFifo{path: path, invocationId: invocationId, done: make(chan struct{})}
+}
 
-	wg *sync.WaitGroup
+// pipeInvocation is the build event stream of an invocation read from a pipe.
+type pipeInvocation struct {
+	fifo         *pipeFifo
+	buildId      string
+	invocationId string
+	// enqueued is when the invocation started and buildFinished is its
+	// BuildFinished build event, which the lifecycle events report.
+	enqueued      time.Time
+	buildFinished *buildeventstream.BuildFinished
+	// streams are the build event streams of the invocation to the backends.
+	streams *invocationStreams
+	// seqId is the sequence number of the last build event of the invocation.
+	seqId int64
+
+	// deferredSpool keeps the build events of the invocation in dir for the
+	// backends that can't receive them, and undelivered are the backends that
+	// were unhealthy once all the build events were streamed.
+	deferredSpool *spoolWriter
+	dir           string
+	undelivered   []besproxy.Backend
 }
 
 var _ BESPipeInterceptor = (*besPipe)(nil)
 
 func (bb *besPipe) Setup() error {
-	err := syscall.Mknod(bb.bepBinPath, syscall.S_IFIFO|0o666, 0)
-	if err != nil {
-		return fmt.Errorf("failed to create BES pipe %s: %w", bb.bepBinPath, err)
+	if err := mkfifo(bb.bepBinPath); err != nil {
+		return err
 	}
+	fifo := newPipeFifo(bb.bepBinPath, bb.besInvocationId)
+	bb.fifos = []*pipeFifo{fifo}
+	bb.first = &pipeInvocation{
+		fifo:         fifo,
+		buildId:      bb.besBuildId,
+		invocationId: bb.besInvocationId,
+		enqueued:     bb.enqueued,
+		streams:      &invocationStreams{leased: true},
+	}
+	// The first invocation holds the registered proxies until it ends.
+	bb.lease.acquire()
 	return nil
 }
 
 func (bb *besPipe) RegisterBesProxy(ctx context.Context, p besproxy.BESProxy) {
+	tracker := trackAcks(p)
+	bb.ackTrackers = append(bb.ackTrackers, tracker)
+	p = SampleEvents(FilterEvents(tracker, bb.excludedEventTypes), bb.sampling)
 	bb.besProxies = append(bb.besProxies, p)
 
-	bb.sendInitialLifecycleEvents(ctx, p)
+	inv := bb.first
+	inv.streams.proxies, inv.streams.trackers = bb.besProxies, bb.ackTrackers
+	bb.openStream(ctx, inv, p)
+}
+
+// openStream opens the build event stream of an invocation to a backend.
+func (bb *besPipe) openStream(ctx context.Context, inv *pipeInvocation, p besproxy.BESProxy) {
+	sendInitialLifecycleEvents(ctx, p, inv.buildId, inv.invocationId, inv.enqueued)
 
 	err := p.PublishBuildToolEventStream(ctx, grpc.WaitForReady(false))
 	if err != nil {
@@ -122,95 +387,85 @@ func (bb *besPipe) RegisterBesProxy(ctx context.Context, p besproxy.BESProxy) {
 			}
 		}
 		// When the ACK goroutine exits (usually because of error), check if we should abort the pipe
-		bb.maybeAbortPipeBecauseNoHealthyBackends()
+		bb.maybeAbortPipeBecauseNoHealthyBackends(inv)
 	}()
 }
 
-func (bb *besPipe) sendInitialLifecycleEvents(ctx context.Context, p besproxy.BESProxy) {
-	// https://github.com/bazelbuild/bazel/blob/198c4c8aae1b5ef3d202f602932a99ce19707fc4/src/main/java/com/google/devtools/build/lib/buildeventservice/client/BuildEventServiceProtoUtil.java#L73
-	p.PublishLifecycleEvent(ctx, lifecycleRequest(bb.besBuildId, bb.besInvocationId, 1, &buildv1.BuildEvent{
-		Event: &buildv1.BuildEvent_BuildEnqueued_{},
-	}))
-
-	// https://github.com/bazelbuild/bazel/blob/198c4c8aae1b5ef3d202f602932a99ce19707fc4/src/main/java/com/google/devtools/build/lib/buildeventservice/client/BuildEventServiceProtoUtil.java#L95
-	p.PublishLifecycleEvent(ctx, lifecycleRequest(bb.besBuildId, bb.besInvocationId, 2, &buildv1.BuildEvent{
-		Event: &buildv1.BuildEvent_InvocationAttemptStarted_{},
-	}))
-}
-
-func (bb *besPipe) sendFinalLifecycleEvents(ctx context.Context, p besproxy.BESProxy) {
-	// https://github.com/bazelbuild/bazel/blob/198c4c8aae1b5ef3d202f602932a99ce19707fc4/src/main/java/com/google/devtools/build/lib/buildeventservice/client/BuildEventServiceProtoUtil.java#L84
-	p.PublishLifecycleEvent(ctx, lifecycleRequest(bb.besBuildId, bb.besInvocationId, 2, &buildv1.BuildEvent{
-		Event: &buildv1.BuildEvent_InvocationAttemptFinished_{},
-	}))
-}
+func (bb *besPipe) ServeWait(ctx context.Context) error {
+	if bb.deferredUpload {
+		bb.flushed = make(chan struct{})
+		go func() {
+			defer close(bb.flushed)
+			flushPendingUploads(ctx)
+		}()
+	}
 
-func lifecycleRequest(buildId, invocationId string, sequenceNumber int64, event *buildv1.BuildEvent) *buildv1.PublishLifecycleEventRequest {
-	return &buildv1.PublishLifecycleEventRequest{
-		ServiceLevel: buildv1.PublishLifecycleEventRequest_INTERACTIVE,
-		BuildEvent: &buildv1.OrderedBuildEvent{
-			SequenceNumber: sequenceNumber,
-			StreamId: &buildv1.StreamId{
-				BuildId:      buildId,
-				InvocationId: invocationId,
-			},
-			Event: event,
-		},
+	bb.mu.Lock()
+	defer bb.mu.Unlock()
+	bb.ctx = ctx
+	for _, fifo := range bb.fifos {
+		go bb.serveFifo(ctx, fifo)
 	}
+	return nil
 }
 
-func (bb *besPipe) ServeWait(ctx context.Context) error {
-	bb.wg.Add(1)
-	go func() {
-		defer bb.wg.Done()
+// serveFifo reads the build events of the invocations bazel writes to a pipe.
+func (bb *besPipe) serveFifo(ctx context.Context, fifo *pipeFifo) {
+	defer close(fifo.done)
 
-		// This is a BLOCKING call that will wait for the file to have readable data.
-		// If no bazel process is launched or the bazel process does not write to the
-		// pipe, this will block indefinitely.
-		conn, err := os.OpenFile(bb.bepBinPath, os.O_RDONLY, os.ModeNamedPipe)
-		if err != nil {
-			bb.errorsMutex.Lock()
-			defer bb.errorsMutex.Unlock()
-			bb.errors.Insert(fmt.Errorf("failed to accept connection on BES pipe %s: %w", bb.bepBinPath, err))
-			return
-		}
-		defer conn.Close()
+	// This is a BLOCKING call that will wait for the file to have readable data.
+	// If no bazel process is launched or the bazel process does not write to the
+	// pipe, this will block indefinitely.
+	conn, err := os.OpenFile(fifo.path, os.O_RDONLY, os.ModeNamedPipe)
+	if err != nil {
+		bb.insertError(fmt.Errorf("failed to accept connection on BES pipe %s: %w", fifo.path, err))
+		return
+	}
+	defer conn.Close()
 
-		// Mark that the pipe has been opened to ensure shutdown waits for writes to finish
-		bb.bepBinOpened = true
+	// Mark that the pipe has been opened to ensure shutdown waits for writes to finish
+	bb.mu.Lock()
+	fifo.conn = conn
+	bb.mu.Unlock()
 
-		if err := bb.streamBesEvents(ctx, conn); err != nil {
-			bb.errorsMutex.Lock()
-			defer bb.errorsMutex.Unlock()
-			bb.errors.Insert(fmt.Errorf("failed to stream BES events: %w", err))
-			return
-		}
+	// Keep a write end of the pipe open until the last build event is read,
+	// so that reads block until bazel writes instead of returning EOF.
+	writer, err := os.OpenFile(fifo.path, os.O_WRONLY, os.ModeNamedPipe)
+	if err != nil {
+		bb.insertError(fmt.Errorf("failed to open BES pipe %s for writing: %w", fifo.path, err))
+	} else {
+		defer writer.Close()
+	}
 
-		// Normal completion path
-		for _, p := range bb.besProxies {
-			if !p.Healthy() {
-				continue
+	if bb.spool.Path != "" {
+		bb.userSpoolOnce.Do(func() {
+			// A spool that can't be written is reported, but doesn't keep the
+			// events from the plugins and backends.
+			spool, err := openSpool(bb.spool)
+			if err != nil {
+				bb.insertError(err)
+				return
 			}
+			bb.userSpool = spool
+		})
+	}
 
-			bb.sendFinalLifecycleEvents(context.Background(), p)
-
-			if err := p.CloseSend(); err != nil {
-				fmt.Fprintf(os.Stderr, "Error closing build event stream to %v: %s\n", p.Host(), err.Error())
-			}
-		}
-	}()
-	return nil
+	if err := bb.streamInvocations(ctx, fifo, conn); err != nil {
+		bb.insertError(fmt.Errorf("failed to stream BES events: %w", err))
+	}
 }
 
-// maybeAbortPipeBecauseNoHealthyBackends unlinks the FIFO if all backends are unhealthy.
-// This gives Bazel a broken pipe → it aborts the upload and exits.
-func (bb *besPipe) maybeAbortPipeBecauseNoHealthyBackends() {
-	if len(bb.besProxies) == 0 {
+// maybeAbortPipeBecauseNoHealthyBackends unlinks the FIFO of an invocation if
+// all its backends are unhealthy. This gives Bazel a broken pipe → it aborts
+// the upload and exits. The other unhealthy policies keep reading the build
+// events instead.
+func (bb *besPipe) maybeAbortPipeBecauseNoHealthyBackends(inv *pipeInvocation) {
+	if len(inv.streams.proxies) == 0 {
 		return
 	}
 
 	var anyHealthy bool
-	for _, p := range bb.besProxies {
+	for _, p := range inv.streams.proxies {
 		if p.Healthy() {
 			anyHealthy = true
 			break
@@ -220,105 +475,267 @@ func (bb *besPipe) maybeAbortPipeBecauseNoHealthyBackends() {
 		return
 	}
 
-	bb.pipeAborted.Do(func() {
-		fmt.Fprintf(os.Stderr, "All BES backends are unhealthy — unlinking pipe %s\n", bb.bepBinPath)
-		if err := syscall.Unlink(bb.bepBinPath); err != nil && !os.IsNotExist(err) {
-			fmt.Fprintf(os.Stderr, "failed to unlink BES pipe %s: %v\n", bb.bepBinPath, err)
+	inv.fifo.aborted.Do(func() {
+		switch bb.unhealthy {
+		case UnhealthySpool:
+			fmt.Fprintf(os.Stderr, "All BES backends are unhealthy — deferring the upload of the build events\n")
+			return
+		case UnhealthyContinue:
+			fmt.Fprintf(os.Stderr, "All BES backends are unhealthy — continuing the build without uploading the build events\n")
+			return
+		}
+		fmt.Fprintf(os.Stderr, "All BES backends are unhealthy — unlinking pipe %s\n", inv.fifo.path)
+		if err := syscall.Unlink(inv.fifo.path); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "failed to unlink BES pipe %s: %v\n", inv.fifo.path, err)
 		}
 	})
 }
 
-func (bb *besPipe) streamBesEvents(ctx context.Context, conn *os.File) error {
+// streamInvocations reads the build events of the invocations written to a
+// pipe one after the other, until GracefulStop is called between two of them.
+func (bb *besPipe) streamInvocations(ctx context.Context, fifo *pipeFifo, conn *os.File) error {
 	reader := bufio.NewReader(conn)
 
-	// Manually manage a sequence ID for the events
-	seqId := int64(0)
+	var inv *pipeInvocation
+	defer func() {
+		if inv != nil {
+			bb.abandonInvocation(inv)
+		}
+	}()
 
-	besEventGlobalTimeout := time.After(besEventGlobalTimeoutDuration)
 	for {
-		event := buildeventstream.BuildEvent{}
-
-		opts := protodelim.UnmarshalOptions{
-			MaxSize: 32 * 1024 * 1024, // 32 MB max; we have observed 17 MB BES events in the wild
+		// Reads block until bazel writes the next event, for at most the event
+		// timeout. Between two invocations, they block until the next one or
+		// until the pipe is stopped.
+		bb.mu.Lock()
+		fifo.idle = inv == nil && fifo.invocations > 0
+		if fifo.idle && bb.stopping {
+			bb.mu.Unlock()
+			return nil
+		}
+		var deadline time.Time
+		if !fifo.idle {
+			deadline = time.Now().Add(bb.timeouts.event())
+		}
+		err := conn.SetReadDeadline(deadline)
+		bb.mu.Unlock()
+		if err != nil {
+			return fmt.Errorf("failed to set the read deadline of the BES pipe: %w", err)
 		}
 
-		if err := opts.UnmarshalFrom(reader, &event); err != nil {
+		// The serialized build event is forwarded to the backends as it is, and
+		// only decoded for the plugins.
+		raw, err := readBuildEvent(reader, bb.maxEventSize)
+		if err != nil {
 			if errors.Is(err, os.ErrDeadlineExceeded) {
+				bb.mu.Lock()
+				stopped := fifo.idle && bb.stopping
+				bb.mu.Unlock()
+				if stopped {
+					return nil
+				}
 				return fmt.Errorf("timeout reached while waiting for BES events")
 			}
 			if errors.Is(err, io.EOF) {
-				select {
-				case <-besEventGlobalTimeout:
-					return fmt.Errorf("timeout reached while waiting for BES events")
-				case <-time.After(besEventThrottleDuration):
-					// throttle the reading of the BES file when no new data is available
-					continue
+				// Only when the write end of the pipe couldn't be kept open.
+				if inv == nil && fifo.invocations > 0 {
+					return nil
 				}
+				return fmt.Errorf("BES pipe closed before the last BES event")
+			}
+			var tooLarge *EventTooLargeError
+			if errors.As(err, &tooLarge) {
+				return fmt.Errorf("%w; the limit can be raised with bes_max_event_size_mb in the Aspect CLI config or ASPECT_BEP_MAX_EVENT_SIZE_MB", err)
 			}
+			return fmt.Errorf("failed to read BES event: %w", err)
+		}
+		event := buildeventstream.BuildEvent{}
+		if err := proto.Unmarshal(raw, &event); err != nil {
 			return fmt.Errorf("failed to parse BES event: %w", err)
 		}
 
-		// Reset the global timeout on each received event
-		besEventGlobalTimeout = time.After(besEventGlobalTimeoutDuration)
+		if inv == nil {
+			inv = bb.startInvocation(ctx, fifo, &event)
+		}
+		bb.spoolEvent(inv, raw)
+
+		// Sequence numbers start over with each invocation.
+		inv.seqId++
 
-		seqId++
+		if finished := event.GetFinished(); finished != nil {
+			inv.buildFinished = finished
+		}
 
-		if err := bb.publishBesEvent(seqId, &event); err != nil {
+		if err := bb.publishBesEvent(inv, inv.seqId, &event, raw); err != nil {
 			return fmt.Errorf("failed to publish BES event: %w", err)
 		}
 
 		if event.LastMessage {
-			break
+			bb.finishInvocation(inv)
+			inv = nil
 		}
 	}
+}
 
-	// Clear read deadline
-	conn.SetReadDeadline(time.Time{})
+// startInvocation starts the invocation whose first build event was read from
+// a pipe. The first invocation of a pipe has the ID given to bazel with Args,
+// and the next ones that of their BuildStarted event, unless it was already
+// used.
+func (bb *besPipe) startInvocation(ctx context.Context, fifo *pipeFifo, event *buildeventstream.BuildEvent) *pipeInvocation {
+	bb.mu.Lock()
+	invocationId := fifo.invocationId
+	first := fifo.invocations == 0 && fifo == bb.first.fifo
+	if fifo.invocations > 0 {
+		invocationId = event.GetStarted().GetUuid()
+		if invocationId == "" || bb.invocationIds[invocationId] {
+			invocationId = uuid.NewString()
+		}
+	}
+	fifo.invocations++
+	bb.invocationIds[invocationId] = true
+	bb.mu.Unlock()
+
+	inv := bb.first
+	if !first {
+		inv = &pipeInvocation{
+			fifo:         fifo,
+			buildId:      bb.besBuildId,
+			invocationId: invocationId,
+			enqueued:     time.Now(),
+		}
+		if bb.lease.acquire() {
+			inv.streams = &invocationStreams{proxies: bb.besProxies, trackers: bb.ackTrackers, leased: true}
+			for _, t := range inv.streams.trackers {
+				t.reset()
+			}
+		} else {
+			inv.streams = dialInvocationStreams(bb.besProxies, bb.excludedEventTypes, bb.sampling, bb.dial)
+		}
+		for _, p := range inv.streams.proxies {
+			bb.openStream(ctx, inv, p)
+		}
+	}
 
-	return nil
+	if bb.deferredUpload {
+		spool, dir, err := bb.openDeferredSpool(inv.invocationId)
+		if err != nil {
+			bb.insertError(err)
+		} else {
+			inv.deferredSpool, inv.dir = spool, dir
+		}
+	}
+	return inv
 }
 
-func (bb *besPipe) publishBesEvent(seqId int64, event *buildeventstream.BuildEvent) error {
-	eg := errgroup.Group{}
+// spoolEvent writes a build event, delimited by its size, to the spools.
+func (bb *besPipe) spoolEvent(inv *pipeInvocation, raw []byte) {
+	delimited := append(protowire.AppendVarint(nil, uint64(len(raw))), raw...)
+	if inv.deferredSpool != nil {
+		inv.deferredSpool.Write(delimited)
+	}
+	if bb.userSpool != nil {
+		// The invocations written concurrently are interleaved one build
+		// event at a time.
+		bb.userSpoolMu.Lock()
+		defer bb.userSpoolMu.Unlock()
+		bb.userSpool.Write(delimited)
+	}
+}
 
-	for s := bb.subscribers.head; s != nil; s = s.next {
-		cb := s.callback
-		eg.Go(
-			func() error {
-				var invocationId string
-				if os.Getenv("ASPECT_BEP_WRITE_LAST_VIA_PIPE") != "" {
-					invocationId = bb.besInvocationId
-				}
-				return cb(event, seqId, invocationId)
-			},
-		)
+// finishInvocation ends the streams of an invocation whose last build event
+// was read, and waits for the multi-threaded subscribers to receive its build
+// events.
+func (bb *besPipe) finishInvocation(inv *pipeInvocation) {
+	for _, p := range inv.streams.proxies {
+		if !p.Healthy() {
+			inv.undelivered = append(inv.undelivered, p.Backend())
+			continue
+		}
+
+		sendFinalLifecycleEvents(context.Background(), p, inv.buildId, inv.invocationId, inv.buildFinished)
+
+		if err := p.CloseSend(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error closing build event stream to %v: %s\n", p.Host(), err.Error())
+			if errors.Is(err, besproxy.ErrNotResumed) {
+				inv.undelivered = append(inv.undelivered, p.Backend())
+			}
+		}
+	}
+	bb.delivery.add(reportAcks(os.Stderr, inv.streams.trackers, bb.timeouts.send()))
+	printResultsURLs(os.Stderr, inv.streams.trackers, bb.stream(inv))
+	bb.abandonInvocation(inv)
+
+	bb.submitMu.Lock()
+	defer bb.submitMu.Unlock()
+	for _, p := range bb.subscriberPools {
+		p.flush()
 	}
+}
 
-	if len(bb.besProxies) > 0 {
-		marshaledEvent, err := anypb.New(event)
-		if err != nil {
-			return fmt.Errorf("failed to marshal BES event: %w", err)
+// abandonInvocation releases the streams and the deferred spool of an
+// invocation, which was streamed completely or not.
+func (bb *besPipe) abandonInvocation(inv *pipeInvocation) {
+	if inv.deferredSpool != nil {
+		if err := bb.deferUndeliveredBackends(inv); err != nil {
+			bb.insertError(err)
 		}
+		inv.deferredSpool = nil
+	}
+	bb.lease.release(inv.streams)
+}
 
-		// Wrap the event in the gRPC message
-		grpcEvent := &buildv1.PublishBuildToolEventStreamRequest{
-			OrderedBuildEvent: &buildv1.OrderedBuildEvent{
-				SequenceNumber: seqId,
-				StreamId: &buildv1.StreamId{
-					BuildId:      bb.besBuildId,
-					InvocationId: bb.besInvocationId,
-				},
-				Event: &buildv1.BuildEvent{
-					EventTime: timestamppb.Now(),
-					Event:     &buildv1.BuildEvent_BazelEvent{BazelEvent: marshaledEvent},
-				},
-			},
+// stream returns the build event stream of an invocation.
+func (bb *besPipe) stream(inv *pipeInvocation) StreamInfo {
+	return StreamInfo{
+		BuildId:      inv.buildId,
+		InvocationId: inv.invocationId,
+		Command:      bb.command,
+	}
+}
+
+// publishBesEvent passes a build event of an invocation to the subscribers,
+// and its serialized bytes to the backends.
+func (bb *besPipe) publishBesEvent(inv *pipeInvocation, seqId int64, event *buildeventstream.BuildEvent, raw []byte) error {
+	eg := errgroup.Group{}
+
+	stream := bb.stream(inv)
+
+	eventType := EventType(event)
+
+	// Subscribers that are not multi-threaded are called one after the other
+	// in the order they were registered in, while the multi-threaded ones
+	// receive the event from their own workers. The events of concurrent
+	// invocations are passed to them one at a time.
+	eg.Go(func() error {
+		bb.subscribers.calls.Lock()
+		defer bb.subscribers.calls.Unlock()
+		var errs []error
+		for s := bb.subscribers.head; s != nil; s = s.next {
+			if err := s.receive(eventType, event, seqId, stream); err != nil {
+				errs = append(errs, err)
+			}
 		}
+		return errors.Join(errs...)
+	})
 
-		for _, p := range bb.besProxies {
+	bb.submitMu.Lock()
+	for _, p := range bb.subscriberPools {
+		p.submit(eventType, subscriberEvent{event: event, seqId: seqId, stream: stream})
+	}
+	bb.submitMu.Unlock()
+
+	if len(inv.streams.proxies) > 0 {
+		// All the backends share the request.
+		grpcEvent := buildToolEventRequest(inv.buildId, inv.invocationId, seqId, raw)
+		if seqId == 1 {
+			grpcEvent.NotificationKeywords = bb.keywords
+		}
+
+		for i, p := range inv.streams.proxies {
 			p := p // capture
 			eg.Go(func() error {
 				if !p.Healthy() {
+					inv.streams.trackers[i].drop()
 					return nil
 				}
 
@@ -336,12 +753,12 @@ func (bb *besPipe) publishBesEvent(seqId int64, event *buildeventstream.BuildEve
 				case err := <-sendCh:
 					if err != nil {
 						p.MarkUnhealthy()
-						bb.maybeAbortPipeBecauseNoHealthyBackends()
+						bb.maybeAbortPipeBecauseNoHealthyBackends(inv)
 					}
 					return nil
-				case <-time.After(besSendTimeout):
+				case <-time.After(bb.timeouts.send()):
 					p.MarkUnhealthy()
-					bb.maybeAbortPipeBecauseNoHealthyBackends()
+					bb.maybeAbortPipeBecauseNoHealthyBackends(inv)
 					return nil
 				}
 			})
@@ -351,24 +768,155 @@ func (bb *besPipe) publishBesEvent(seqId int64, event *buildeventstream.BuildEve
 	return eg.Wait()
 }
 
-func (bb *besPipe) Args() []string {
+// Args returns the flags of a bazel command writing its build events to the
+// pipe. The first command writes to the pipe created by Setup, and each next
+// one, which may run concurrently, to a pipe and with an invocation ID of its
+// own.
+func (bb *besPipe) Args() ([]string, error) {
+	fifo, err := bb.nextFifo()
+	if err != nil {
+		return nil, err
+	}
 	args := []string{
 		"--build_event_binary_file",
-		bb.bepBinPath,
-		"--invocation_id=" + bb.besInvocationId,
+		fifo.path,
+		"--invocation_id=" + fifo.invocationId,
 	}
 
-	// Also add wait_for_upload_complete flag if the bes pipe was explicitly requested.
+	// Default to wait_for_upload_complete if the bes pipe was explicitly requested.
 	// NOTE: this is explicitly not the default behavior to avoid breaking changes in bazel6
-	if os.Getenv("ASPECT_BEP_USE_PIPE") != "" {
-		args = append(args, "--build_event_binary_file_upload_mode=wait_for_upload_complete")
+	mode := bb.uploadMode
+	if mode == "" && os.Getenv("ASPECT_BEP_USE_PIPE") != "" {
+		mode = UploadWait
+	}
+	if mode != "" {
+		args = append(args, "--build_event_binary_file_upload_mode="+string(mode))
+	}
+
+	return args, nil
+}
+
+// nextFifo returns the pipe of the next bazel command, creating it and
+// serving it once ServeWait was called unless it is the first one.
+func (bb *besPipe) nextFifo() (*pipeFifo, error) {
+	bb.mu.Lock()
+	defer bb.mu.Unlock()
+	bb.argsCalls++
+	if bb.argsCalls == 1 {
+		return bb.fifos[0], nil
+	}
+
+	// The pipe of a command that is still running can't be shared, since the
+	// events of both commands would be written to it with the same invocation
+	// ID.
+	fifoPath := fmt.Sprintf("%s.%d", bb.bepBinPath, len(bb.fifos))
+	if err := mkfifo(fifoPath); err != nil {
+		return nil, err
+	}
+	invocationId := uuid.NewString()
+	bb.invocationIds[invocationId] = true
+	fifo := newPipeFifo(fifoPath, invocationId)
+	bb.fifos = append(bb.fifos, fifo)
+	if bb.ctx != nil {
+		go bb.serveFifo(bb.ctx, fifo)
+	}
+	return fifo, nil
+}
+
+// mkfifo creates a pipe for bazel to write the build events to.
+func mkfifo(path string) error {
+	if err := syscall.Mknod(path, syscall.S_IFIFO|0o666, 0); err != nil {
+		return fmt.Errorf("failed to create BES pipe %s: %w", path, err)
 	}
+	return nil
+}
 
-	return args
+func (bb *besPipe) RegisterSubscriber(callback CallbackFn, opts SubscriberOptions, eventTypes ...string) {
+	if !opts.MultiThreaded {
+		bb.subscribers.Insert(callback, opts.Sampling, eventTypes...)
+		return
+	}
+	subscriber := newSubscriberNode(callback, eventTypes...)
+	subscriber.sampler = newEventSampler[StreamInfo](opts.Sampling)
+	bb.subscriberPools = append(bb.subscriberPools, newSubscriberPool(subscriber, opts, bb.insertError))
+}
+
+// openDeferredSpool opens the spool the build events of an invocation are kept
+// in for the backends that can't receive them.
+func (bb *besPipe) openDeferredSpool(invocationId string) (*spoolWriter, string, error) {
+	dir, err := DeferredUploadsDir()
+	if err != nil {
+		return nil, "", err
+	}
+	spool, err := openSpool(Spool{Path: deferredEventsPath(dir, invocationId), Compression: CompressionZstd})
+	if err != nil {
+		return nil, "", err
+	}
+	return spool, dir, nil
 }
 
-func (bb *besPipe) RegisterSubscriber(callback CallbackFn, multiThreaded bool) {
-	bb.subscribers.Insert(callback)
+// deferUndeliveredBackends closes the deferred spool of an invocation and
+// keeps it for the backends that didn't receive all the build events. It is
+// removed when they all did, or the events weren't streamed completely.
+func (bb *besPipe) deferUndeliveredBackends(inv *pipeInvocation) error {
+	path := inv.deferredSpool.file.Name()
+	if err := inv.deferredSpool.Close(); err != nil {
+		os.Remove(path)
+		return err
+	}
+	if len(inv.undelivered) == 0 {
+		return os.Remove(path)
+	}
+
+	upload := deferredUpload{
+		BuildID:            inv.buildId,
+		InvocationID:       inv.invocationId,
+		Backends:           inv.undelivered,
+		ExcludedEventTypes: bb.excludedEventTypes,
+		Sampling:           bb.sampling,
+		Enqueued:           inv.enqueued,
+		MaxEventSize:       bb.maxEventSize,
+		Keywords:           bb.keywords,
+	}
+	if err := writeDeferredUpload(inv.dir, upload); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "The build events of invocation %s were not uploaded to %d BES backend(s); they will be uploaded by the next build or with 'aspect bep flush'\n", inv.invocationId, len(inv.undelivered))
+	return nil
+}
+
+// flushPendingUploads tries to upload the build events of earlier commands
+// that couldn't be uploaded, reporting the uploads that still fail.
+func flushPendingUploads(ctx context.Context) {
+	dir, err := DeferredUploadsDir()
+	if err != nil {
+		return
+	}
+	results, err := FlushDeferredUploads(ctx, dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to flush deferred BES uploads: %s\n", err.Error())
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(os.Stderr, "Deferred upload of invocation %s to %s failed again: %s\n", r.InvocationID, r.Backend, r.Err.Error())
+		}
+	}
+}
+
+func (bb *besPipe) insertError(err error) {
+	bb.errorsMutex.Lock()
+	defer bb.errorsMutex.Unlock()
+	bb.errors.Insert(err)
+}
+
+// drainSubscriberPools waits for the multi-threaded subscribers to receive the
+// build events queued for them.
+func (bb *besPipe) drainSubscriberPools() {
+	bb.closeSubscriberPools.Do(func() {
+		for _, p := range bb.subscriberPools {
+			p.close()
+		}
+	})
 }
 
 func (bb *besPipe) Errors() []error {
@@ -377,10 +925,46 @@ func (bb *besPipe) Errors() []error {
 	return bb.errors.Errors()
 }
 
+// DeliveryReports returns what was delivered to each BES backend over the
+// invocations read from the pipes.
+func (bb *besPipe) DeliveryReports() []AckReport {
+	return bb.delivery.get()
+}
+
+// GracefulStop waits for the build events written to the pipes bazel opened,
+// and stops waiting for further invocations on them.
 func (bb *besPipe) GracefulStop() {
-	if bb.bepBinOpened {
-		bb.wg.Wait()
+	bb.mu.Lock()
+	bb.stopping = true
+	var opened []*pipeFifo
+	for _, fifo := range bb.fifos {
+		if fifo.conn == nil {
+			continue
+		}
+		opened = append(opened, fifo)
+		if fifo.idle {
+			// Unblocks the read of the next invocation.
+			fifo.conn.SetReadDeadline(time.Now())
+		}
+	}
+	fifos := bb.fifos
+	bb.mu.Unlock()
+
+	for _, fifo := range opened {
+		<-fifo.done
+	}
+	bb.drainSubscriberPools()
+	if bb.userSpool != nil {
+		if err := bb.userSpool.Close(); err != nil {
+			bb.insertError(err)
+		}
 	}
+	if bb.flushed != nil {
+		<-bb.flushed
+	}
+	bb.delivery.print(os.Stderr)
 
-	os.Remove(bb.bepBinPath)
+	for _, fifo := range fifos {
+		os.Remove(fifo.path)
+	}
 }
@@ -0,0 +1,248 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package bep
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/encoding/protodelim"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/besproxy"
+	besproxy_mock "github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/besproxy/mock"
+)
+
+func newTestBESPipe(t *testing.T, timeouts Timeouts) *besPipe {
+	g := NewGomegaWithT(t)
+	pipe, err := NewBESPipe("build", "invocation", BESPipeOptions{Timeouts: timeouts})
+	g.Expect(err).ToNot(HaveOccurred())
+	bb := pipe.(*besPipe)
+	bb.bepBinPath = filepath.Join(t.TempDir(), "bes.bin")
+	g.Expect(bb.Setup()).To(Succeed())
+	return bb
+}
+
+func writeBuildEvent(g *WithT, path string, event *buildeventstream.BuildEvent) {
+	w, err := os.OpenFile(path, os.O_WRONLY, os.ModeNamedPipe)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer w.Close()
+	_, err = protodelim.MarshalTo(w, event)
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func TestBESPipe(t *testing.T) {
+	t.Run("reads the build events until the last message", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		bb := newTestBESPipe(t, Timeouts{Event: 10 * time.Second})
+
+		var mu sync.Mutex
+		var received []string
+		bb.RegisterSubscriber(func(event *buildeventstream.BuildEvent, _ int64, _ StreamInfo) error {
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, EventType(event))
+			return nil
+		}, SubscriberOptions{})
+		g.Expect(bb.ServeWait(context.Background())).To(Succeed())
+
+		// Closing the pipe between the build events doesn't end the stream.
+		writeBuildEvent(g, bb.bepBinPath, &buildeventstream.BuildEvent{
+			Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_Started{}},
+		})
+		writeBuildEvent(g, bb.bepBinPath, &buildeventstream.BuildEvent{
+			Id:          &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_BuildFinished{}},
+			LastMessage: true,
+		})
+		g.Eventually(func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return received
+		}).Should(Equal([]string{"started", "build_finished"}))
+		bb.GracefulStop()
+
+		g.Expect(bb.Errors()).To(BeEmpty())
+	})
+
+	t.Run("streams the invocations written one after the other", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		bb := newTestBESPipe(t, Timeouts{Event: 10 * time.Second})
+
+		type received struct {
+			seqId        int64
+			invocationId string
+		}
+		var mu sync.Mutex
+		var events []received
+		bb.RegisterSubscriber(func(_ *buildeventstream.BuildEvent, seqId int64, stream StreamInfo) error {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, received{seqId, stream.InvocationId})
+			return nil
+		}, SubscriberOptions{})
+		g.Expect(bb.ServeWait(context.Background())).To(Succeed())
+
+		// As with --watch, the invocation ID of the first invocation is reused
+		// by bazel for the next ones.
+		for range 2 {
+			writeBuildEvent(g, bb.bepBinPath, &buildeventstream.BuildEvent{
+				Id:      &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_Started{}},
+				Payload: &buildeventstream.BuildEvent_Started{Started: &buildeventstream.BuildStarted{Uuid: "invocation"}},
+			})
+			writeBuildEvent(g, bb.bepBinPath, &buildeventstream.BuildEvent{
+				Id:          &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_BuildFinished{}},
+				LastMessage: true,
+			})
+		}
+		g.Eventually(func() []received {
+			mu.Lock()
+			defer mu.Unlock()
+			return events
+		}).Should(HaveLen(4))
+		bb.GracefulStop()
+
+		g.Expect(bb.Errors()).To(BeEmpty())
+		g.Expect(events[:2]).To(Equal([]received{{1, "invocation"}, {2, "invocation"}}))
+		next := events[2].invocationId
+		g.Expect(next).ToNot(Equal("invocation"))
+		g.Expect(events[2:]).To(Equal([]received{{1, next}, {2, next}}))
+	})
+
+	t.Run("passes the upload mode to bazel", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Setenv("ASPECT_BEP_USE_PIPE", "")
+		bb := newTestBESPipe(t, Timeouts{})
+		g.Expect(bb.Args()).To(HaveLen(3))
+
+		t.Setenv("ASPECT_BEP_USE_PIPE", "1")
+		g.Expect(bb.Args()).To(ContainElement("--build_event_binary_file_upload_mode=wait_for_upload_complete"))
+
+		bb.uploadMode = UploadFullyAsync
+		g.Expect(bb.Args()).To(ContainElement("--build_event_binary_file_upload_mode=fully_async"))
+	})
+
+	t.Run("gives each further command a pipe of its own", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		bb := newTestBESPipe(t, Timeouts{Event: 10 * time.Second})
+
+		var mu sync.Mutex
+		var invocationIds []string
+		bb.RegisterSubscriber(func(_ *buildeventstream.BuildEvent, _ int64, stream StreamInfo) error {
+			mu.Lock()
+			defer mu.Unlock()
+			invocationIds = append(invocationIds, stream.InvocationId)
+			return nil
+		}, SubscriberOptions{})
+		g.Expect(bb.ServeWait(context.Background())).To(Succeed())
+
+		args, err := bb.Args()
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(args[:3]).To(Equal([]string{"--build_event_binary_file", bb.bepBinPath, "--invocation_id=invocation"}))
+		args, err = bb.Args()
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(args[1]).To(Equal(bb.bepBinPath + ".1"))
+		g.Expect(args[2]).ToNot(Equal("--invocation_id=invocation"))
+
+		writeBuildEvent(g, args[1], &buildeventstream.BuildEvent{
+			Id:          &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_BuildFinished{}},
+			LastMessage: true,
+		})
+		g.Eventually(func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return invocationIds
+		}).Should(HaveLen(1))
+		bb.GracefulStop()
+
+		g.Expect(bb.Errors()).To(BeEmpty())
+		g.Expect("--invocation_id=" + invocationIds[0]).To(Equal(args[2]))
+		g.Expect(args[1]).ToNot(BeAnExistingFile())
+	})
+
+	t.Run("fails when the pipe of a further command can't be created", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		bb := newTestBESPipe(t, Timeouts{})
+		g.Expect(os.WriteFile(bb.bepBinPath+".1", nil, 0644)).To(Succeed())
+
+		_, err := bb.Args()
+		g.Expect(err).ToNot(HaveOccurred())
+		_, err = bb.Args()
+		g.Expect(err).To(MatchError(ContainSubstring("failed to create BES pipe " + bb.bepBinPath + ".1")))
+	})
+
+	t.Run("gives up when bazel doesn't write the next build event in time", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		bb := newTestBESPipe(t, Timeouts{Event: 100 * time.Millisecond})
+		g.Expect(bb.ServeWait(context.Background())).To(Succeed())
+
+		writeBuildEvent(g, bb.bepBinPath, &buildeventstream.BuildEvent{
+			Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_Started{}},
+		})
+		g.Eventually(bb.Errors).ShouldNot(BeEmpty())
+		bb.GracefulStop()
+
+		g.Expect(bb.Errors()).To(ConsistOf(MatchError(ContainSubstring("timeout reached while waiting for BES events"))))
+	})
+}
+
+func TestUnhealthyPolicy(t *testing.T) {
+	withUnhealthyBackend := func(t *testing.T, policy UnhealthyPolicy) *besPipe {
+		ctrl := gomock.NewController(t)
+		besProxy := besproxy_mock.NewMockBESProxy(ctrl)
+		besProxy.EXPECT().Healthy().Return(false).AnyTimes()
+
+		bb := newTestBESPipe(t, Timeouts{})
+		bb.unhealthy = policy
+		bb.first.streams.proxies = []besproxy.BESProxy{besProxy}
+		return bb
+	}
+
+	t.Run("unlinks the pipe to abort the upload", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		bb := withUnhealthyBackend(t, UnhealthyAbort)
+
+		bb.maybeAbortPipeBecauseNoHealthyBackends(bb.first)
+
+		g.Expect(bb.bepBinPath).ToNot(BeAnExistingFile())
+	})
+
+	for _, policy := range []UnhealthyPolicy{UnhealthyContinue, UnhealthySpool} {
+		t.Run("keeps the pipe with "+string(policy), func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			bb := withUnhealthyBackend(t, policy)
+
+			bb.maybeAbortPipeBecauseNoHealthyBackends(bb.first)
+
+			g.Expect(bb.bepBinPath).To(BeAnExistingFile())
+		})
+	}
+
+	t.Run("parses the policies", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		g.Expect(ParseUnhealthyPolicy("")).To(Equal(UnhealthyAbort))
+		g.Expect(ParseUnhealthyPolicy("continue-without-bes")).To(Equal(UnhealthyContinue))
+		g.Expect(ParseUnhealthyPolicy("spool")).To(Equal(UnhealthySpool))
+		_, err := ParseUnhealthyPolicy("retry")
+		g.Expect(err).To(MatchError(`expected the policy when all BES backends are unhealthy to be one of abort, continue-without-bes or spool: "retry"`))
+	})
+}
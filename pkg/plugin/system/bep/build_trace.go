@@ -0,0 +1,233 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package bep
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/telemetry"
+)
+
+// BuildTrace is a subscriber that converts the build events of the
+// invocations into the spans of the telemetry session, for flame graphs of the
+// builds. Each invocation is a span, with child spans for its loading and
+// analysis and execution phases, its targets and the actions and test attempts
+// reported for them. Bazel only reports the actions that failed unless
+// --build_event_publish_all_actions is set.
+type BuildTrace struct {
+	tracer trace.Tracer
+	parent context.Context
+	now    func() time.Time
+
+	mu         sync.Mutex
+	ctx        context.Context
+	invocation trace.Span
+	started    time.Time
+	finished   *buildeventstream.BuildFinished
+	// targets are the spans of the targets of the invocation, by label and
+	// aspect, which remain the parents of their test attempts once ended.
+	targets map[string]*targetSpan
+}
+
+type targetSpan struct {
+	ctx   context.Context
+	span  trace.Span
+	ended bool
+}
+
+// NewBuildTrace returns a BuildTrace whose invocation spans are children of
+// the span of ctx, if any.
+func NewBuildTrace(ctx context.Context) *BuildTrace {
+	return &BuildTrace{
+		tracer: otel.Tracer("aspect-bep"),
+		parent: ctx,
+		now:    time.Now,
+	}
+}
+
+// Callback converts a build event into spans. It is a CallbackFn.
+func (b *BuildTrace) Callback(event *buildeventstream.BuildEvent, _ int64, stream StreamInfo) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if started := event.GetStarted(); started != nil {
+		b.startInvocation(started, stream)
+	}
+	if b.invocation == nil {
+		return nil
+	}
+
+	switch payload := event.GetPayload().(type) {
+	case *buildeventstream.BuildEvent_Configured:
+		id := event.GetId().GetTargetConfigured()
+		key := targetKey(id.GetLabel(), id.GetAspect())
+		if _, ok := b.targets[key]; !ok {
+			ctx, span := b.tracer.Start(b.ctx, "target "+id.GetLabel(),
+				trace.WithTimestamp(b.now()),
+				trace.WithAttributes(telemetry.BazelTargetKey.String(id.GetLabel())))
+			b.targets[key] = &targetSpan{ctx: ctx, span: span}
+		}
+	case *buildeventstream.BuildEvent_Completed:
+		id := event.GetId().GetTargetCompleted()
+		// A target built in several configurations ends with the first one.
+		if target, ok := b.targets[targetKey(id.GetLabel(), id.GetAspect())]; ok && !target.ended {
+			if !payload.Completed.GetSuccess() {
+				target.span.SetStatus(codes.Error, "target failed")
+			}
+			target.span.End(trace.WithTimestamp(b.now()))
+			target.ended = true
+		}
+	case *buildeventstream.BuildEvent_Action:
+		b.recordAction(event.GetId().GetActionCompleted(), payload.Action)
+	case *buildeventstream.BuildEvent_TestResult:
+		b.recordTestAttempt(event.GetId().GetTestResult(), payload.TestResult)
+	case *buildeventstream.BuildEvent_BuildMetrics:
+		b.recordPhases(payload.BuildMetrics.GetTimingMetrics())
+	case *buildeventstream.BuildEvent_Finished:
+		b.finished = payload.Finished
+	}
+
+	if event.LastMessage {
+		b.endInvocation()
+	}
+	return nil
+}
+
+// Close ends the spans of an invocation whose last build event wasn't
+// received.
+func (b *BuildTrace) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.invocation != nil {
+		b.endInvocation()
+	}
+}
+
+func (b *BuildTrace) startInvocation(started *buildeventstream.BuildStarted, stream StreamInfo) {
+	if b.invocation != nil {
+		b.endInvocation()
+	}
+	b.started = timeOr(started.GetStartTime(), b.now())
+	b.finished = nil
+	b.targets = make(map[string]*targetSpan)
+	b.ctx, b.invocation = b.tracer.Start(b.parent, "bazel "+started.GetCommand(),
+		trace.WithTimestamp(b.started),
+		trace.WithAttributes(
+			telemetry.BazelCommandKey.String(started.GetCommand()),
+			telemetry.BazelInvocationId(stream.InvocationId),
+		))
+}
+
+func (b *BuildTrace) endInvocation() {
+	end := b.now()
+	for _, target := range b.targets {
+		if !target.ended {
+			target.span.End(trace.WithTimestamp(end))
+		}
+	}
+	if b.finished != nil {
+		end = timeOr(b.finished.GetFinishTime(), end)
+		exitCode := b.finished.GetExitCode()
+		b.invocation.SetAttributes(telemetry.BazelExitCodeKey.Int(int(exitCode.GetCode())))
+		if exitCode.GetCode() != 0 {
+			b.invocation.SetStatus(codes.Error, exitCode.GetName())
+		}
+	}
+	b.invocation.End(trace.WithTimestamp(end))
+	b.invocation = nil
+	b.targets = nil
+}
+
+// recordAction records an action that reported when it ran.
+func (b *BuildTrace) recordAction(id *buildeventstream.BuildEventId_ActionCompletedId, action *buildeventstream.ActionExecuted) {
+	if action.GetStartTime() == nil || action.GetEndTime() == nil {
+		return
+	}
+	_, span := b.tracer.Start(b.targetContext(id.GetLabel()), fmt.Sprintf("action %s %s", action.GetType(), id.GetPrimaryOutput()),
+		trace.WithTimestamp(action.GetStartTime().AsTime()),
+		trace.WithAttributes(
+			telemetry.BazelTargetKey.String(id.GetLabel()),
+			telemetry.BazelMnemonicKey.String(action.GetType()),
+			telemetry.BazelExitCodeKey.Int(int(action.GetExitCode())),
+		))
+	if !action.GetSuccess() {
+		span.SetStatus(codes.Error, "action failed")
+	}
+	span.End(trace.WithTimestamp(action.GetEndTime().AsTime()))
+}
+
+// recordTestAttempt records a test attempt that reported when it ran.
+func (b *BuildTrace) recordTestAttempt(id *buildeventstream.BuildEventId_TestResultId, result *buildeventstream.TestResult) {
+	if result.GetTestAttemptStart() == nil || result.GetTestAttemptDuration() == nil {
+		return
+	}
+	start := result.GetTestAttemptStart().AsTime()
+	_, span := b.tracer.Start(b.targetContext(id.GetLabel()), fmt.Sprintf("test %s run %d shard %d attempt %d", id.GetLabel(), id.GetRun(), id.GetShard(), id.GetAttempt()),
+		trace.WithTimestamp(start),
+		trace.WithAttributes(
+			telemetry.BazelTargetKey.String(id.GetLabel()),
+			telemetry.BazelTestStatusKey.String(result.GetStatus().String()),
+		))
+	if result.GetStatus() != buildeventstream.TestStatus_PASSED {
+		span.SetStatus(codes.Error, result.GetStatus().String())
+	}
+	span.End(trace.WithTimestamp(start.Add(result.GetTestAttemptDuration().AsDuration())))
+}
+
+// recordPhases records the loading and analysis phase, from the start of the
+// invocation to the start of the execution of the actions, and the execution
+// phase.
+func (b *BuildTrace) recordPhases(timing *buildeventstream.BuildMetrics_TimingMetrics) {
+	if timing.GetActionsExecutionStartInMs() <= 0 {
+		return
+	}
+	executionStart := b.started.Add(time.Duration(timing.GetActionsExecutionStartInMs()) * time.Millisecond)
+	_, analysis := b.tracer.Start(b.ctx, "loading and analysis", trace.WithTimestamp(b.started))
+	analysis.End(trace.WithTimestamp(executionStart))
+	if timing.GetExecutionPhaseTimeInMs() > 0 {
+		_, execution := b.tracer.Start(b.ctx, "execution", trace.WithTimestamp(executionStart))
+		execution.End(trace.WithTimestamp(executionStart.Add(time.Duration(timing.GetExecutionPhaseTimeInMs()) * time.Millisecond)))
+	}
+}
+
+// targetContext returns the context of the span of the target with label, or
+// of the invocation if the target wasn't configured.
+func (b *BuildTrace) targetContext(label string) context.Context {
+	if target, ok := b.targets[targetKey(label, "")]; ok {
+		return target.ctx
+	}
+	return b.ctx
+}
+
+func targetKey(label, aspect string) string {
+	return label + "\x00" + aspect
+}
+
+func timeOr(t *timestamppb.Timestamp, fallback time.Time) time.Time {
+	if t == nil {
+		return fallback
+	}
+	return t.AsTime()
+}
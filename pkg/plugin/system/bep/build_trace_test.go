@@ -0,0 +1,122 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package bep
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+)
+
+func TestBuildTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	g := NewGomegaWithT(t)
+	start := time.Unix(1700000000, 0).UTC()
+	now := start
+	buildTrace := NewBuildTrace(context.Background())
+	buildTrace.now = func() time.Time { return now }
+
+	target := "//app:test"
+	events := []*buildeventstream.BuildEvent{
+		{
+			Id:      &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_Started{}},
+			Payload: &buildeventstream.BuildEvent_Started{Started: &buildeventstream.BuildStarted{Command: "test", StartTime: timestamppb.New(start)}},
+		},
+		{
+			Id:      &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_TargetConfigured{TargetConfigured: &buildeventstream.BuildEventId_TargetConfiguredId{Label: target}}},
+			Payload: &buildeventstream.BuildEvent_Configured{Configured: &buildeventstream.TargetConfigured{}},
+		},
+		{
+			Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_ActionCompleted{ActionCompleted: &buildeventstream.BuildEventId_ActionCompletedId{Label: target, PrimaryOutput: "bazel-out/app/test"}}},
+			Payload: &buildeventstream.BuildEvent_Action{Action: &buildeventstream.ActionExecuted{
+				Type:      "GoLink",
+				ExitCode:  1,
+				StartTime: timestamppb.New(start.Add(time.Second)),
+				EndTime:   timestamppb.New(start.Add(2 * time.Second)),
+			}},
+		},
+		{
+			Id:      &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_TargetCompleted{TargetCompleted: &buildeventstream.BuildEventId_TargetCompletedId{Label: target}}},
+			Payload: &buildeventstream.BuildEvent_Completed{Completed: &buildeventstream.TargetComplete{Success: true}},
+		},
+		{
+			Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_TestResult{TestResult: &buildeventstream.BuildEventId_TestResultId{Label: target, Run: 1, Shard: 1, Attempt: 1}}},
+			Payload: &buildeventstream.BuildEvent_TestResult{TestResult: &buildeventstream.TestResult{
+				Status:              buildeventstream.TestStatus_PASSED,
+				TestAttemptStart:    timestamppb.New(start.Add(3 * time.Second)),
+				TestAttemptDuration: durationpb.New(time.Second),
+			}},
+		},
+		{
+			Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_BuildMetrics{}},
+			Payload: &buildeventstream.BuildEvent_BuildMetrics{BuildMetrics: &buildeventstream.BuildMetrics{
+				TimingMetrics: &buildeventstream.BuildMetrics_TimingMetrics{ActionsExecutionStartInMs: 1000, ExecutionPhaseTimeInMs: 3000},
+			}},
+		},
+		{
+			Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_BuildFinished{}},
+			Payload: &buildeventstream.BuildEvent_Finished{Finished: &buildeventstream.BuildFinished{
+				ExitCode:   &buildeventstream.BuildFinished_ExitCode{Name: "SUCCESS"},
+				FinishTime: timestamppb.New(start.Add(5 * time.Second)),
+			}},
+			LastMessage: true,
+		},
+	}
+	for _, event := range events {
+		now = now.Add(500 * time.Millisecond)
+		g.Expect(buildTrace.Callback(event, 0, StreamInfo{InvocationId: "inv"})).To(Succeed())
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	g.Expect(spans).To(HaveLen(6))
+
+	invocation := spans["bazel test"]
+	g.Expect(invocation.StartTime()).To(Equal(start))
+	g.Expect(invocation.EndTime()).To(Equal(start.Add(5 * time.Second)))
+	g.Expect(invocation.Status().Code).ToNot(Equal(codes.Error))
+
+	targetSpan := spans["target //app:test"]
+	g.Expect(targetSpan.Parent().SpanID()).To(Equal(invocation.SpanContext().SpanID()))
+	g.Expect(targetSpan.EndTime().Sub(targetSpan.StartTime())).To(Equal(time.Second))
+
+	action := spans["action GoLink bazel-out/app/test"]
+	g.Expect(action.Parent().SpanID()).To(Equal(targetSpan.SpanContext().SpanID()))
+	g.Expect(action.Status().Code).To(Equal(codes.Error))
+
+	test := spans["test //app:test run 1 shard 1 attempt 1"]
+	g.Expect(test.Parent().SpanID()).To(Equal(targetSpan.SpanContext().SpanID()))
+	g.Expect(test.EndTime()).To(Equal(start.Add(4 * time.Second)))
+
+	g.Expect(spans["loading and analysis"].EndTime()).To(Equal(start.Add(time.Second)))
+	g.Expect(spans["execution"].EndTime()).To(Equal(start.Add(4 * time.Second)))
+}
@@ -0,0 +1,280 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package bep
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils/cache"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/besproxy"
+)
+
+// The build events of an invocation that couldn't be uploaded to some of the
+// BES backends are kept in the deferred uploads directory, in
+// <invocation id>.bin.zst, until they are. <invocation id>.json lists the
+// backends that haven't received them yet, including their headers, so both
+// files are only readable by the user.
+const (
+	deferredUploadExt = ".json"
+	deferredEventsExt = ".bin.zst"
+	// deferredUploadClaimExt marks a deferred upload that is being flushed,
+	// so that concurrent flushes don't upload it twice.
+	deferredUploadClaimExt = ".flushing"
+)
+
+// deferredUpload describes the build events of an invocation that are yet to
+// be uploaded to the backends.
+type deferredUpload struct {
+	BuildID      string             `json:"build_id"`
+	InvocationID string             `json:"invocation_id"`
+	Backends     []besproxy.Backend `json:"backends"`
+	// ExcludedEventTypes are the types of the build events that are not
+	// uploaded.
+	ExcludedEventTypes []string `json:"excluded_event_types,omitempty"`
+	// Sampling samples the build events that are uploaded.
+	Sampling EventSampling `json:"sampling,omitempty"`
+	// Enqueued is when the invocation started, which is reported to the
+	// backends in place of the time of the upload.
+	Enqueued time.Time `json:"enqueued,omitzero"`
+	// MaxEventSize is the maximum size of the build events that were spooled.
+	MaxEventSize int `json:"max_event_size,omitempty"`
+	// Keywords are the notification keywords sent with the first build event.
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+// FlushResult is the outcome of uploading the build events of an invocation
+// to a backend. Err is nil when the upload succeeded.
+type FlushResult struct {
+	InvocationID string
+	Backend      string
+	Err          error
+}
+
+// DeferredUploadsDir returns the directory the deferred uploads are kept in,
+// creating it if needed.
+func DeferredUploadsDir() (string, error) {
+	aspectCacheDir, err := cache.AspectCacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(aspectCacheDir, "bes", "deferred")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("could not create directory %s: %v", dir, err)
+	}
+	return dir, nil
+}
+
+func deferredEventsPath(dir, invocationId string) string {
+	return filepath.Join(dir, invocationId+deferredEventsExt)
+}
+
+func deferredUploadPath(dir, invocationId string) string {
+	return filepath.Join(dir, invocationId+deferredUploadExt)
+}
+
+// writeDeferredUpload records that the build events of the invocation still
+// need to be uploaded to the backends of the upload.
+func writeDeferredUpload(dir string, upload deferredUpload) error {
+	b, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	path := deferredUploadPath(dir, upload.InvocationID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return fmt.Errorf("failed to write deferred upload %s: %w", path, err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// PendingDeferredUploads returns the invocation ids of the deferred uploads in
+// dir.
+func PendingDeferredUploads(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var invocationIds []string
+	for _, entry := range entries {
+		if invocationId, ok := strings.CutSuffix(entry.Name(), deferredUploadExt); ok {
+			invocationIds = append(invocationIds, invocationId)
+		}
+	}
+	return invocationIds, nil
+}
+
+// FlushDeferredUploads uploads the build events of the deferred uploads in
+// dir to the backends that haven't received them yet. The uploads that fail
+// are kept to be flushed again later.
+func FlushDeferredUploads(ctx context.Context, dir string) ([]FlushResult, error) {
+	invocationIds, err := PendingDeferredUploads(dir)
+	if err != nil {
+		return nil, err
+	}
+	var results []FlushResult
+	for _, invocationId := range invocationIds {
+		r, err := flushDeferredUpload(ctx, dir, invocationId)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, r...)
+	}
+	return results, nil
+}
+
+func flushDeferredUpload(ctx context.Context, dir, invocationId string) ([]FlushResult, error) {
+	path := deferredUploadPath(dir, invocationId)
+	claimed := path + deferredUploadClaimExt
+	if err := os.Rename(path, claimed); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// Another invocation of the CLI is flushing it.
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	b, err := os.ReadFile(claimed)
+	if err != nil {
+		return nil, err
+	}
+	var upload deferredUpload
+	if err := json.Unmarshal(b, &upload); err != nil {
+		return nil, fmt.Errorf("failed to read deferred upload %s: %w", path, err)
+	}
+
+	var results []FlushResult
+	var remaining []besproxy.Backend
+	for _, backend := range upload.Backends {
+		err := replayEvents(ctx, backend, upload, deferredEventsPath(dir, invocationId))
+		results = append(results, FlushResult{InvocationID: invocationId, Backend: backend.URL, Err: err})
+		if err != nil {
+			remaining = append(remaining, backend)
+		}
+	}
+
+	if len(remaining) > 0 {
+		upload.Backends = remaining
+		if err := writeDeferredUpload(dir, upload); err != nil {
+			return results, err
+		}
+	} else if err := os.Remove(deferredEventsPath(dir, invocationId)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return results, err
+	}
+	return results, os.Remove(claimed)
+}
+
+// replayEvents uploads the build events in eventsPath to the backend, as bazel
+// would have: wrapped in the lifecycle events of the invocation, and waiting
+// for the backend to acknowledge them all.
+func replayEvents(ctx context.Context, backend besproxy.Backend, upload deferredUpload, eventsPath string) error {
+	f, err := os.Open(eventsPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	decoder, err := zstd.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer decoder.Close()
+	reader := bufio.NewReader(decoder)
+
+	ctx, cancel := context.WithTimeout(ctx, besEventGlobalTimeoutDuration)
+	defer cancel()
+
+	p := SampleEvents(FilterEvents(besproxy.NewBesProxyForBackend(backend), upload.ExcludedEventTypes), upload.Sampling)
+	if err := p.Connect(); err != nil {
+		return err
+	}
+	enqueued := upload.Enqueued
+	if enqueued.IsZero() {
+		enqueued = time.Now()
+	}
+	sendInitialLifecycleEvents(ctx, p, upload.BuildID, upload.InvocationID, enqueued)
+	if err := p.PublishBuildToolEventStream(ctx, grpc.WaitForReady(false)); err != nil {
+		return err
+	}
+
+	acked := make(chan error, 1)
+	go func() {
+		for {
+			if _, err := p.Recv(); err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				acked <- err
+				return
+			}
+		}
+	}()
+
+	var finished *buildeventstream.BuildFinished
+	for seqId := int64(1); ; seqId++ {
+		raw, err := readBuildEvent(reader, upload.MaxEventSize)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("failed to read deferred build events %s: %w", eventsPath, err)
+		}
+		// Only the BuildFinished event is decoded further, for the status of
+		// the invocation.
+		header, err := buildEventHeader(raw)
+		if err != nil {
+			return fmt.Errorf("failed to read deferred build events %s: %w", eventsPath, err)
+		}
+		if EventType(header) == "build_finished" {
+			event := &buildeventstream.BuildEvent{}
+			if err := proto.Unmarshal(raw, event); err != nil {
+				return fmt.Errorf("failed to read deferred build events %s: %w", eventsPath, err)
+			}
+			finished = event.GetFinished()
+		}
+		req := buildToolEventRequest(upload.BuildID, upload.InvocationID, seqId, raw)
+		if seqId == 1 {
+			req.NotificationKeywords = upload.Keywords
+		}
+		if err := p.Send(req); err != nil {
+			return err
+		}
+		if header.LastMessage {
+			break
+		}
+	}
+
+	if err := p.CloseSend(); err != nil {
+		return err
+	}
+	if err := <-acked; err != nil {
+		return fmt.Errorf("failed to receive the build event acknowledgements: %w", err)
+	}
+	sendFinalLifecycleEvents(ctx, p, upload.BuildID, upload.InvocationID, finished)
+	return nil
+}
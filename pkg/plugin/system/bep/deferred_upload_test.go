@@ -0,0 +1,180 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bep
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	buildv1 "google.golang.org/genproto/googleapis/devtools/build/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/besproxy"
+)
+
+// recordingBES is a BES backend that acknowledges and records the build events
+// it receives.
+type recordingBES struct {
+	buildv1.UnimplementedPublishBuildEventServer
+
+	mu        sync.Mutex
+	lifecycle []*buildv1.PublishLifecycleEventRequest
+	events    []*buildeventstream.BuildEvent
+	// keywords are the notification keywords of the build events.
+	keywords [][]string
+}
+
+func (s *recordingBES) PublishLifecycleEvent(ctx context.Context, req *buildv1.PublishLifecycleEventRequest) (*emptypb.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lifecycle = append(s.lifecycle, req)
+	return &emptypb.Empty{}, nil
+}
+
+func (s *recordingBES) PublishBuildToolEventStream(stream buildv1.PublishBuildEvent_PublishBuildToolEventStreamServer) error {
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		event := &buildeventstream.BuildEvent{}
+		if err := req.OrderedBuildEvent.Event.GetBazelEvent().UnmarshalTo(event); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		s.events = append(s.events, event)
+		s.keywords = append(s.keywords, req.NotificationKeywords)
+		s.mu.Unlock()
+		if err := stream.Send(&buildv1.PublishBuildToolEventStreamResponse{
+			StreamId:       req.OrderedBuildEvent.StreamId,
+			SequenceNumber: req.OrderedBuildEvent.SequenceNumber,
+		}); err != nil {
+			return err
+		}
+	}
+}
+
+func startRecordingBES(t *testing.T) (*recordingBES, string) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &recordingBES{}
+	s := grpc.NewServer()
+	buildv1.RegisterPublishBuildEventServer(s, srv)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return srv, "grpc://" + lis.Addr().String()
+}
+
+func writeDeferredEvents(t *testing.T, dir, invocationId string, events ...*buildeventstream.BuildEvent) {
+	w, err := openSpool(Spool{Path: deferredEventsPath(dir, invocationId), Compression: CompressionZstd})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range events {
+		if _, err := protodelim.MarshalTo(w, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFlushDeferredUploads(t *testing.T) {
+	events := []*buildeventstream.BuildEvent{
+		{Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_Started{}}},
+		{Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_BuildFinished{}}, LastMessage: true},
+	}
+
+	t.Run("uploads the build events and removes the deferred upload", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		dir := t.TempDir()
+		srv, url := startRecordingBES(t)
+
+		writeDeferredEvents(t, dir, "inv", events...)
+		g.Expect(writeDeferredUpload(dir, deferredUpload{
+			BuildID:      "build",
+			InvocationID: "inv",
+			Backends:     []besproxy.Backend{{URL: url}},
+			Keywords:     []string{"branch=main"},
+		})).To(Succeed())
+
+		results, err := FlushDeferredUploads(context.Background(), dir)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(results).To(ConsistOf(FlushResult{InvocationID: "inv", Backend: url}))
+
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		g.Expect(srv.events).To(HaveLen(2))
+		g.Expect(srv.events[1].LastMessage).To(BeTrue())
+		g.Expect(srv.keywords).To(Equal([][]string{{"branch=main"}, nil}))
+		g.Expect(srv.lifecycle).To(HaveLen(4))
+
+		g.Expect(PendingDeferredUploads(dir)).To(BeEmpty())
+		_, err = os.Stat(deferredEventsPath(dir, "inv"))
+		g.Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	t.Run("keeps the backends that are still unreachable", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		dir := t.TempDir()
+		_, url := startRecordingBES(t)
+		unreachable := "unix://" + dir + "/missing.sock"
+
+		writeDeferredEvents(t, dir, "inv", events...)
+		g.Expect(writeDeferredUpload(dir, deferredUpload{
+			BuildID:      "build",
+			InvocationID: "inv",
+			Backends:     []besproxy.Backend{{URL: url}, {URL: unreachable}},
+		})).To(Succeed())
+
+		results, err := FlushDeferredUploads(context.Background(), dir)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(results).To(HaveLen(2))
+		g.Expect(results[0].Err).ToNot(HaveOccurred())
+		g.Expect(results[1].Backend).To(Equal(unreachable))
+		g.Expect(results[1].Err).To(HaveOccurred())
+
+		g.Expect(PendingDeferredUploads(dir)).To(ConsistOf("inv"))
+		_, err = os.Stat(deferredEventsPath(dir, "inv"))
+		g.Expect(err).ToNot(HaveOccurred())
+	})
+
+	t.Run("skips uploads claimed by another flush", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		dir := t.TempDir()
+
+		g.Expect(os.WriteFile(deferredUploadPath(dir, "inv")+deferredUploadClaimExt, []byte("{}"), 0o600)).To(Succeed())
+
+		results, err := FlushDeferredUploads(context.Background(), dir)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(results).To(BeEmpty())
+	})
+}
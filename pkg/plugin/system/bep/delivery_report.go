@@ -0,0 +1,105 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package bep
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// deliveryReport adds up the AckReports of the invocations of a command per
+// BES backend, so that what was delivered to them is summarized once the
+// command ends rather than only in the output of each invocation.
+type deliveryReport struct {
+	mu      sync.Mutex
+	reports []AckReport
+}
+
+// add adds the AckReports of an invocation. The health of a backend is that
+// of the last invocation streamed to it.
+func (d *deliveryReport) add(reports []AckReport) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, r := range reports {
+		i := d.index(r.Host)
+		if i < 0 {
+			d.reports = append(d.reports, r)
+			continue
+		}
+		total := &d.reports[i]
+		total.Sent += r.Sent
+		total.Acknowledged += r.Acknowledged
+		total.Skipped += r.Skipped
+		total.Dropped += r.Dropped
+		total.Reconnects += r.Reconnects
+		total.Healthy = r.Healthy
+	}
+}
+
+// index returns the index of the report of host, or -1. d.mu must be held.
+func (d *deliveryReport) index(host string) int {
+	for i, r := range d.reports {
+		if r.Host == host {
+			return i
+		}
+	}
+	return -1
+}
+
+// get returns the report of each backend, in the order they were first
+// streamed to.
+func (d *deliveryReport) get() []AckReport {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]AckReport(nil), d.reports...)
+}
+
+// print writes the build events delivered to each backend, unless all of
+// them received every build event without their streams failing.
+func (d *deliveryReport) print(w io.Writer) {
+	reports := d.get()
+	troubled := false
+	for _, r := range reports {
+		if !r.Complete() || r.Reconnects > 0 || !r.Healthy {
+			troubled = true
+		}
+	}
+	if !troubled {
+		return
+	}
+	fmt.Fprintf(w, "BES delivery report:\n")
+	for _, r := range reports {
+		health := "healthy"
+		if !r.Healthy {
+			health = "unhealthy"
+		}
+		fmt.Fprintf(w, "  %v: %d sent, %d acknowledged, %d skipped, %d dropped, %d reconnects, %s\n",
+			r.Host, r.Sent, r.Acknowledged, r.Skipped, r.Dropped, r.Reconnects, health)
+	}
+}
+
+// Undelivered returns the reports of the backends that didn't acknowledge
+// every build event, or whose streams failed for good.
+func Undelivered(reports []AckReport) []AckReport {
+	var undelivered []AckReport
+	for _, r := range reports {
+		if !r.Complete() || !r.Healthy {
+			undelivered = append(undelivered, r)
+		}
+	}
+	return undelivered
+}
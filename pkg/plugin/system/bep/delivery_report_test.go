@@ -0,0 +1,97 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package bep
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestDeliveryReport(t *testing.T) {
+	t.Run("adds up the invocations of each backend", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var d deliveryReport
+		d.add([]AckReport{
+			{Host: "grpc://a", Sent: 3, Acknowledged: 3, Healthy: true},
+			{Host: "grpc://b", Sent: 3, Acknowledged: 1, Dropped: 2, Healthy: false},
+		})
+		d.add([]AckReport{
+			{Host: "grpc://a", Sent: 4, Acknowledged: 3, Skipped: 1, Reconnects: 1, Healthy: true},
+			{Host: "grpc://b", Sent: 4, Acknowledged: 4, Healthy: true},
+		})
+
+		g.Expect(d.get()).To(Equal([]AckReport{
+			{Host: "grpc://a", Sent: 7, Acknowledged: 6, Skipped: 1, Reconnects: 1, Healthy: true},
+			{Host: "grpc://b", Sent: 7, Acknowledged: 5, Dropped: 2, Healthy: true},
+		}))
+	})
+
+	t.Run("prints nothing when every backend received every build event", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var d deliveryReport
+		d.add([]AckReport{{Host: "grpc://a", Sent: 3, Acknowledged: 3, Healthy: true}})
+
+		var out bytes.Buffer
+		d.print(&out)
+		g.Expect(out.String()).To(BeEmpty())
+	})
+
+	t.Run("prints every backend when one of them had trouble", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var d deliveryReport
+		d.add([]AckReport{
+			{Host: "grpc://a", Sent: 3, Acknowledged: 3, Healthy: true},
+			{Host: "grpc://b", Sent: 1, Dropped: 2},
+		})
+
+		var out bytes.Buffer
+		d.print(&out)
+		g.Expect(out.String()).To(Equal("BES delivery report:\n" +
+			"  grpc://a: 3 sent, 3 acknowledged, 0 skipped, 0 dropped, 0 reconnects, healthy\n" +
+			"  grpc://b: 1 sent, 0 acknowledged, 0 skipped, 2 dropped, 0 reconnects, unhealthy\n"))
+	})
+
+	t.Run("prints the backends whose streams were resumed", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var d deliveryReport
+		d.add([]AckReport{{Host: "grpc://a", Sent: 3, Acknowledged: 3, Reconnects: 1, Healthy: true}})
+
+		var out bytes.Buffer
+		d.print(&out)
+		g.Expect(out.String()).To(ContainSubstring("grpc://a: 3 sent, 3 acknowledged, 0 skipped, 0 dropped, 1 reconnects, healthy"))
+	})
+}
+
+func TestUndelivered(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(Undelivered([]AckReport{
+		{Host: "grpc://a", Sent: 3, Acknowledged: 3, Reconnects: 1, Healthy: true},
+		{Host: "grpc://b", Sent: 3, Acknowledged: 2, Healthy: true},
+		{Host: "grpc://c", Sent: 3, Acknowledged: 3},
+		{Host: "grpc://d", Sent: 1, Acknowledged: 1, Dropped: 2, Healthy: true},
+	})).To(Equal([]AckReport{
+		{Host: "grpc://b", Sent: 3, Acknowledged: 2, Healthy: true},
+		{Host: "grpc://c", Sent: 3, Acknowledged: 3},
+		{Host: "grpc://d", Sent: 1, Acknowledged: 1, Dropped: 2, Healthy: true},
+	}))
+}
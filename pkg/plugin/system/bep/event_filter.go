@@ -0,0 +1,90 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bep
+
+import (
+	"sync"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/besproxy"
+	buildv1 "google.golang.org/genproto/googleapis/devtools/build/v1"
+)
+
+// eventFilter is a BES proxy that drops the build events of the excluded types
+// instead of forwarding them. Backends expect the sequence numbers of a stream
+// to be consecutive, so the events it forwards are renumbered.
+type eventFilter struct {
+	besproxy.BESProxy
+	excluded map[string]bool
+
+	mu    sync.Mutex
+	seqId int64
+}
+
+// FilterEvents returns a proxy that forwards the build events to p, except
+// those of the given types. The last message of the stream is always
+// forwarded so that the backend knows that the stream is complete.
+func FilterEvents(p besproxy.BESProxy, eventTypes []string) besproxy.BESProxy {
+	if len(eventTypes) == 0 {
+		return p
+	}
+	excluded := make(map[string]bool, len(eventTypes))
+	for _, eventType := range eventTypes {
+		excluded[eventType] = true
+	}
+	return &eventFilter{BESProxy: p, excluded: excluded}
+}
+
+func (f *eventFilter) Send(req *buildv1.PublishBuildToolEventStreamRequest) error {
+	if f.drops(req) {
+		return nil
+	}
+
+	// The request may be sent to other backends too, so it is not renumbered
+	// in place.
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seqId++
+	event := req.GetOrderedBuildEvent()
+	return f.BESProxy.Send(&buildv1.PublishBuildToolEventStreamRequest{
+		OrderedBuildEvent: &buildv1.OrderedBuildEvent{
+			StreamId:       event.GetStreamId(),
+			SequenceNumber: f.seqId,
+			Event:          event.GetEvent(),
+		},
+		NotificationKeywords:                 req.NotificationKeywords,
+		ProjectId:                            req.ProjectId,
+		CheckPrecedingLifecycleEventsPresent: req.CheckPrecedingLifecycleEventsPresent,
+	})
+}
+
+func (f *eventFilter) drops(req *buildv1.PublishBuildToolEventStreamRequest) bool {
+	bazelEvent := req.GetOrderedBuildEvent().GetEvent().GetBazelEvent()
+	if bazelEvent == nil {
+		return false
+	}
+	// Only the id of the build event is decoded, as the event is dropped or
+	// forwarded as it is.
+	if !bazelEvent.MessageIs((*buildeventstream.BuildEvent)(nil)) {
+		return false
+	}
+	event, err := buildEventHeader(bazelEvent.GetValue())
+	if err != nil {
+		return false
+	}
+	return !event.LastMessage && f.excluded[EventType(event)]
+}
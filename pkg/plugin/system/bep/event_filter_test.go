@@ -0,0 +1,94 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bep
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/gomega"
+	buildv1 "google.golang.org/genproto/googleapis/devtools/build/v1"
+	"google.golang.org/protobuf/proto"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	besproxy_mock "github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/besproxy/mock"
+)
+
+func TestFilterEvents(t *testing.T) {
+	progress := &buildeventstream.BuildEvent{Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_Progress{}}}
+	started := &buildeventstream.BuildEvent{Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_Started{}}}
+	lastProgress := &buildeventstream.BuildEvent{Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_Progress{}}, LastMessage: true}
+
+	request := func(g *WithT, seqId int64, event *buildeventstream.BuildEvent) *buildv1.PublishBuildToolEventStreamRequest {
+		raw, err := proto.Marshal(event)
+		g.Expect(err).ToNot(HaveOccurred())
+		return buildToolEventRequest("build", "inv", seqId, raw)
+	}
+
+	t.Run("returns the proxy when no event types are excluded", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+
+		p := besproxy_mock.NewMockBESProxy(ctrl)
+		g.Expect(FilterEvents(p, nil)).To(BeIdenticalTo(p))
+	})
+
+	t.Run("drops the excluded events and renumbers the others", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+
+		var sent []int64
+		p := besproxy_mock.NewMockBESProxy(ctrl)
+		p.EXPECT().Send(gomock.Any()).DoAndReturn(func(req *buildv1.PublishBuildToolEventStreamRequest) error {
+			sent = append(sent, req.OrderedBuildEvent.SequenceNumber)
+			return nil
+		}).Times(3)
+
+		filter := FilterEvents(p, []string{"progress"})
+		reqs := []*buildv1.PublishBuildToolEventStreamRequest{
+			request(g, 1, progress),
+			request(g, 2, started),
+			request(g, 3, progress),
+			request(g, 4, started),
+			request(g, 5, lastProgress),
+		}
+		for _, req := range reqs {
+			g.Expect(filter.Send(req)).To(Succeed())
+		}
+
+		g.Expect(sent).To(Equal([]int64{1, 2, 3}))
+		// The requests may be sent to other backends, so they are left as is.
+		g.Expect(reqs[3].OrderedBuildEvent.SequenceNumber).To(Equal(int64(4)))
+	})
+
+	t.Run("forwards events that are not build events", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+
+		p := besproxy_mock.NewMockBESProxy(ctrl)
+		p.EXPECT().Send(gomock.Any()).Return(nil).Times(1)
+
+		g.Expect(FilterEvents(p, []string{"progress"}).Send(&buildv1.PublishBuildToolEventStreamRequest{
+			OrderedBuildEvent: &buildv1.OrderedBuildEvent{
+				SequenceNumber: 1,
+				Event: &buildv1.BuildEvent{
+					Event: &buildv1.BuildEvent_ComponentStreamFinished{},
+				},
+			},
+		})).To(Succeed())
+	})
+}
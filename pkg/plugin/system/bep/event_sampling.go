@@ -0,0 +1,302 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package bep
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	buildv1 "google.golang.org/genproto/googleapis/devtools/build/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/besproxy"
+)
+
+// EventSampling maps the types of high-volume build events to the number of
+// events of the type that are reduced to one before they are forwarded. The
+// events of most types are sampled, e.g. {"action_completed": 100} forwards
+// one in every hundred ActionExecuted events. Progress events are aggregated
+// instead: every n of them are forwarded as one event holding their output, so
+// that no console output is lost. The ActionExecuted events of failed actions
+// and the last message of a stream are always forwarded.
+type EventSampling map[string]int
+
+// samples returns true if the events of the given type are sampled or
+// aggregated.
+func (s EventSampling) samples(eventType string) bool {
+	return s[eventType] > 1
+}
+
+// sampledEvent is a build event forwarded by an eventSampler, along with the
+// sequence number and the carrier it was passed with. The carrier of an
+// aggregated event is that of the last event merged into it.
+type sampledEvent[T any] struct {
+	event   *buildeventstream.BuildEvent
+	seqId   int64
+	carrier T
+	// aggregated is set when the event merges several events, in which case it
+	// is not one of the events passed to the sampler.
+	aggregated bool
+}
+
+// eventSampler applies an EventSampling to the build events of one or more
+// streams. It is safe for concurrent use.
+type eventSampler[T any] struct {
+	sampling EventSampling
+
+	mu      sync.Mutex
+	streams map[string]*sampledStream[T]
+}
+
+// sampledStream is the state of an eventSampler for a stream.
+type sampledStream[T any] struct {
+	// counts are the numbers of events of the sampled types received.
+	counts map[string]int
+	// progress aggregates the progress events received since the last one was
+	// forwarded, of which there are merged.
+	progress *sampledEvent[T]
+	merged   int
+}
+
+// newEventSampler returns a sampler, or nil if sampling samples no event type.
+func newEventSampler[T any](sampling EventSampling) *eventSampler[T] {
+	for eventType := range sampling {
+		if sampling.samples(eventType) {
+			return &eventSampler[T]{sampling: sampling, streams: make(map[string]*sampledStream[T])}
+		}
+	}
+	return nil
+}
+
+// sample passes a build event of the given stream to the sampler, and returns
+// the events to forward in its place, in order: none if it was sampled out or
+// is being aggregated, and otherwise the pending aggregated progress event if
+// any followed by the event itself.
+func (s *eventSampler[T]) sample(stream string, event *buildeventstream.BuildEvent, seqId int64, carrier T) []sampledEvent[T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.streams[stream]
+	if st == nil {
+		st = &sampledStream[T]{counts: make(map[string]int)}
+		s.streams[stream] = st
+	}
+
+	eventType := EventType(event)
+	n := s.sampling[eventType]
+	if eventType == "progress" && n > 1 && !event.LastMessage {
+		st.aggregate(event, seqId, carrier)
+		if st.merged < n {
+			return nil
+		}
+		return st.flush(nil)
+	}
+
+	forwarded := st.flush(nil)
+	if event.LastMessage {
+		delete(s.streams, stream)
+	} else if n > 1 && !alwaysForwarded(event) {
+		count := st.counts[eventType]
+		st.counts[eventType]++
+		if count%n != 0 {
+			return forwarded
+		}
+	}
+	return append(forwarded, sampledEvent[T]{event: event, seqId: seqId, carrier: carrier})
+}
+
+// flush returns the pending aggregated progress event of a stream, if any, and
+// forgets the stream.
+func (s *eventSampler[T]) flush(stream string) []sampledEvent[T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.streams[stream]
+	if st == nil {
+		return nil
+	}
+	delete(s.streams, stream)
+	return st.flush(nil)
+}
+
+// aggregate merges a progress event into the pending one. The events passed
+// to the sampler are not modified, since they may be shared.
+func (st *sampledStream[T]) aggregate(event *buildeventstream.BuildEvent, seqId int64, carrier T) {
+	st.merged++
+	if st.progress == nil {
+		st.progress = &sampledEvent[T]{event: event, seqId: seqId, carrier: carrier}
+		return
+	}
+	merged := st.progress.event
+	if !st.progress.aggregated {
+		merged = proto.Clone(merged).(*buildeventstream.BuildEvent)
+	}
+	progress := merged.GetProgress()
+	if progress == nil {
+		progress = &buildeventstream.Progress{}
+		merged.Payload = &buildeventstream.BuildEvent_Progress{Progress: progress}
+	}
+	progress.Stdout += event.GetProgress().GetStdout()
+	progress.Stderr += event.GetProgress().GetStderr()
+	// The merged event takes the place of the event it announced, and
+	// announces what that event announced.
+	merged.Children = slices.DeleteFunc(merged.Children, func(id *buildeventstream.BuildEventId) bool {
+		return proto.Equal(id, event.Id)
+	})
+	merged.Children = append(merged.Children, event.Children...)
+	merged.Id = event.Id
+	st.progress = &sampledEvent[T]{event: merged, seqId: seqId, carrier: carrier, aggregated: true}
+}
+
+// flush appends the pending aggregated progress event, if any, to forwarded.
+func (st *sampledStream[T]) flush(forwarded []sampledEvent[T]) []sampledEvent[T] {
+	if st.progress == nil {
+		return forwarded
+	}
+	forwarded = append(forwarded, *st.progress)
+	st.progress = nil
+	st.merged = 0
+	return forwarded
+}
+
+// alwaysForwarded returns true if an event is forwarded whatever the sampling
+// of its type.
+func alwaysForwarded(event *buildeventstream.BuildEvent) bool {
+	action := event.GetAction()
+	return action != nil && !action.GetSuccess()
+}
+
+// eventSamplingProxy is a BES proxy that applies an EventSampling to the build
+// events before forwarding them. Like the eventFilter, it renumbers the events
+// it forwards so that their sequence numbers stay consecutive.
+type eventSamplingProxy struct {
+	besproxy.BESProxy
+	sampling EventSampling
+
+	mu           sync.Mutex
+	sampler      *eventSampler[*buildv1.PublishBuildToolEventStreamRequest]
+	invocationId string
+	seqId        int64
+}
+
+// SampleEvents returns a proxy that forwards the build events to p once
+// sampled, or p itself if sampling samples no event type.
+func SampleEvents(p besproxy.BESProxy, sampling EventSampling) besproxy.BESProxy {
+	sampler := newEventSampler[*buildv1.PublishBuildToolEventStreamRequest](sampling)
+	if sampler == nil {
+		return p
+	}
+	return &eventSamplingProxy{BESProxy: p, sampling: sampling, sampler: sampler}
+}
+
+func (s *eventSamplingProxy) PublishBuildToolEventStream(ctx context.Context, opts ...grpc.CallOption) error {
+	// The proxy streams the invocations one after the other.
+	s.mu.Lock()
+	s.sampler = newEventSampler[*buildv1.PublishBuildToolEventStreamRequest](s.sampling)
+	s.invocationId = ""
+	s.seqId = 0
+	s.mu.Unlock()
+	return s.BESProxy.PublishBuildToolEventStream(ctx, opts...)
+}
+
+func (s *eventSamplingProxy) Send(req *buildv1.PublishBuildToolEventStreamRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	streamId := req.GetOrderedBuildEvent().GetStreamId()
+	s.invocationId = streamId.GetInvocationId()
+	event, err := s.decode(req)
+	if err != nil || event == nil {
+		return s.send(req, nil)
+	}
+	for _, e := range s.sampler.sample(s.invocationId, event, req.GetOrderedBuildEvent().GetSequenceNumber(), req) {
+		if !e.aggregated {
+			err = s.send(e.carrier, nil)
+		} else {
+			err = s.send(e.carrier, e.event)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *eventSamplingProxy) CloseSend() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A stream closed before its last message may hold an aggregated event.
+	for _, e := range s.sampler.flush(s.invocationId) {
+		if err := s.send(e.carrier, e.event); err != nil {
+			return err
+		}
+	}
+	return s.BESProxy.CloseSend()
+}
+
+// decode returns the build event of a request, or nil if it holds none. Only
+// the header of an event whose type is not sampled is decoded, as it is
+// forwarded as it is.
+func (s *eventSamplingProxy) decode(req *buildv1.PublishBuildToolEventStreamRequest) (*buildeventstream.BuildEvent, error) {
+	bazelEvent := req.GetOrderedBuildEvent().GetEvent().GetBazelEvent()
+	if bazelEvent == nil || !bazelEvent.MessageIs((*buildeventstream.BuildEvent)(nil)) {
+		return nil, nil
+	}
+	header, err := buildEventHeader(bazelEvent.GetValue())
+	if err != nil || !s.sampling.samples(EventType(header)) {
+		return header, err
+	}
+	event := &buildeventstream.BuildEvent{}
+	if err := proto.Unmarshal(bazelEvent.GetValue(), event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// send forwards a request with the next sequence number, replacing its build
+// event with event if it isn't nil.
+func (s *eventSamplingProxy) send(req *buildv1.PublishBuildToolEventStreamRequest, event *buildeventstream.BuildEvent) error {
+	orderedEvent := req.GetOrderedBuildEvent()
+	buildEvent := orderedEvent.GetEvent()
+	if event != nil {
+		bazelEvent, err := anypb.New(event)
+		if err != nil {
+			return err
+		}
+		buildEvent = &buildv1.BuildEvent{
+			EventTime: buildEvent.GetEventTime(),
+			Event:     &buildv1.BuildEvent_BazelEvent{BazelEvent: bazelEvent},
+		}
+	}
+
+	// The request may be sent to other backends too, so it is not renumbered
+	// in place.
+	s.seqId++
+	return s.BESProxy.Send(&buildv1.PublishBuildToolEventStreamRequest{
+		OrderedBuildEvent: &buildv1.OrderedBuildEvent{
+			StreamId:       orderedEvent.GetStreamId(),
+			SequenceNumber: s.seqId,
+			Event:          buildEvent,
+		},
+		NotificationKeywords:                 req.NotificationKeywords,
+		ProjectId:                            req.ProjectId,
+		CheckPrecedingLifecycleEventsPresent: req.CheckPrecedingLifecycleEventsPresent,
+	})
+}
@@ -0,0 +1,211 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package bep
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/gomega"
+	buildv1 "google.golang.org/genproto/googleapis/devtools/build/v1"
+	"google.golang.org/protobuf/proto"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	besproxy_mock "github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/besproxy/mock"
+)
+
+func progressId(count int32) *buildeventstream.BuildEventId {
+	return &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_Progress{Progress: &buildeventstream.BuildEventId_ProgressId{OpaqueCount: count}}}
+}
+
+func progressEvent(count int32, stderr string) *buildeventstream.BuildEvent {
+	return &buildeventstream.BuildEvent{
+		Id:       progressId(count),
+		Children: []*buildeventstream.BuildEventId{progressId(count + 1)},
+		Payload:  &buildeventstream.BuildEvent_Progress{Progress: &buildeventstream.Progress{Stderr: stderr}},
+	}
+}
+
+func actionEvent(success bool) *buildeventstream.BuildEvent {
+	return &buildeventstream.BuildEvent{
+		Id:      &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_ActionCompleted{ActionCompleted: &buildeventstream.BuildEventId_ActionCompletedId{}}},
+		Payload: &buildeventstream.BuildEvent_Action{Action: &buildeventstream.ActionExecuted{Success: success}},
+	}
+}
+
+func TestEventSampler(t *testing.T) {
+	started := &buildeventstream.BuildEvent{Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_Started{}}}
+
+	t.Run("samples nothing without a sampling", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		g.Expect(newEventSampler[int](nil)).To(BeNil())
+		g.Expect(newEventSampler[int](EventSampling{"progress": 1})).To(BeNil())
+	})
+
+	t.Run("aggregates the progress events", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		s := newEventSampler[int](EventSampling{"progress": 2})
+		events := []*buildeventstream.BuildEvent{
+			progressEvent(0, "a"),
+			progressEvent(1, "b"),
+			progressEvent(2, "c"),
+			started,
+		}
+		var forwarded []sampledEvent[int]
+		for i, event := range events {
+			forwarded = append(forwarded, s.sample("inv", event, int64(i+1), i)...)
+		}
+
+		g.Expect(forwarded).To(HaveLen(3))
+		g.Expect(forwarded[0].aggregated).To(BeTrue())
+		g.Expect(forwarded[0].seqId).To(Equal(int64(2)))
+		g.Expect(forwarded[0].carrier).To(Equal(1))
+		g.Expect(forwarded[0].event.GetProgress().GetStderr()).To(Equal("ab"))
+		g.Expect(proto.Equal(forwarded[0].event.Id, progressId(1))).To(BeTrue())
+		g.Expect(forwarded[0].event.Children).To(HaveLen(1))
+		g.Expect(proto.Equal(forwarded[0].event.Children[0], progressId(2))).To(BeTrue())
+		// The pending progress event is forwarded before the next event.
+		g.Expect(forwarded[1].aggregated).To(BeFalse())
+		g.Expect(forwarded[1].event).To(BeIdenticalTo(events[2]))
+		g.Expect(forwarded[2].event).To(BeIdenticalTo(started))
+		// The events passed to the sampler are left as they are.
+		g.Expect(events[0].GetProgress().GetStderr()).To(Equal("a"))
+	})
+
+	t.Run("samples the successful actions", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		s := newEventSampler[int](EventSampling{"action_completed": 3})
+		events := []*buildeventstream.BuildEvent{
+			actionEvent(true),
+			actionEvent(true),
+			actionEvent(false),
+			actionEvent(true),
+			actionEvent(true),
+		}
+		var forwarded []*buildeventstream.BuildEvent
+		for i, event := range events {
+			for _, e := range s.sample("inv", event, int64(i+1), i) {
+				forwarded = append(forwarded, e.event)
+			}
+		}
+
+		g.Expect(forwarded).To(HaveExactElements(
+			BeIdenticalTo(events[0]),
+			BeIdenticalTo(events[2]),
+			BeIdenticalTo(events[4]),
+		))
+	})
+
+	t.Run("forwards the last message and forgets the stream", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		s := newEventSampler[int](EventSampling{"progress": 10})
+		last := progressEvent(1, "b")
+		last.LastMessage = true
+
+		g.Expect(s.sample("inv", progressEvent(0, "a"), 1, 0)).To(BeEmpty())
+		g.Expect(s.sample("other", progressEvent(0, "c"), 1, 0)).To(BeEmpty())
+		forwarded := s.sample("inv", last, 2, 0)
+		g.Expect(forwarded).To(HaveLen(2))
+		g.Expect(forwarded[0].event.GetProgress().GetStderr()).To(Equal("a"))
+		g.Expect(forwarded[1].event).To(BeIdenticalTo(last))
+		g.Expect(s.streams).To(HaveKey("other"))
+		g.Expect(s.streams).NotTo(HaveKey("inv"))
+
+		forwarded = s.flush("other")
+		g.Expect(forwarded).To(HaveLen(1))
+		g.Expect(forwarded[0].event.GetProgress().GetStderr()).To(Equal("c"))
+		g.Expect(s.streams).To(BeEmpty())
+	})
+
+	t.Run("delivers the sampled events a subscriber wants", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var received []string
+		node := newSubscriberNode(func(event *buildeventstream.BuildEvent, seqId int64, stream StreamInfo) error {
+			received = append(received, event.GetProgress().GetStderr())
+			return nil
+		}, "progress")
+		node.sampler = newEventSampler[StreamInfo](EventSampling{"progress": 2})
+
+		stream := StreamInfo{InvocationId: "inv"}
+		for i, event := range []*buildeventstream.BuildEvent{progressEvent(0, "a"), progressEvent(1, "b"), progressEvent(2, "c"), started} {
+			g.Expect(node.receive(EventType(event), event, int64(i+1), stream)).To(Succeed())
+		}
+
+		g.Expect(received).To(Equal([]string{"ab", "c"}))
+	})
+}
+
+func TestSampleEvents(t *testing.T) {
+	request := func(g *WithT, seqId int64, event *buildeventstream.BuildEvent) *buildv1.PublishBuildToolEventStreamRequest {
+		raw, err := proto.Marshal(event)
+		g.Expect(err).ToNot(HaveOccurred())
+		return buildToolEventRequest("build", "inv", seqId, raw)
+	}
+
+	t.Run("returns the proxy when no event types are sampled", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+
+		p := besproxy_mock.NewMockBESProxy(ctrl)
+		g.Expect(SampleEvents(p, nil)).To(BeIdenticalTo(p))
+	})
+
+	t.Run("forwards the aggregated events renumbered", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+
+		var sent []*buildv1.PublishBuildToolEventStreamRequest
+		p := besproxy_mock.NewMockBESProxy(ctrl)
+		p.EXPECT().PublishBuildToolEventStream(gomock.Any()).Return(nil)
+		p.EXPECT().Send(gomock.Any()).DoAndReturn(func(req *buildv1.PublishBuildToolEventStreamRequest) error {
+			sent = append(sent, req)
+			return nil
+		}).Times(3)
+		p.EXPECT().CloseSend().Return(nil)
+
+		sampler := SampleEvents(p, EventSampling{"progress": 2})
+		g.Expect(sampler.PublishBuildToolEventStream(t.Context())).To(Succeed())
+		started := request(g, 1, &buildeventstream.BuildEvent{Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_Started{}}})
+		for _, req := range []*buildv1.PublishBuildToolEventStreamRequest{
+			started,
+			request(g, 2, progressEvent(0, "a")),
+			request(g, 3, progressEvent(1, "b")),
+			request(g, 4, progressEvent(2, "c")),
+		} {
+			g.Expect(sampler.Send(req)).To(Succeed())
+		}
+		g.Expect(sampler.CloseSend()).To(Succeed())
+
+		g.Expect(sent).To(HaveLen(3))
+		var seqIds []int64
+		var stderr []string
+		for _, req := range sent {
+			seqIds = append(seqIds, req.OrderedBuildEvent.SequenceNumber)
+			event := &buildeventstream.BuildEvent{}
+			g.Expect(req.OrderedBuildEvent.Event.GetBazelEvent().UnmarshalTo(event)).To(Succeed())
+			stderr = append(stderr, event.GetProgress().GetStderr())
+		}
+		g.Expect(seqIds).To(Equal([]int64{1, 2, 3}))
+		g.Expect(stderr).To(Equal([]string{"", "ab", "c"}))
+		g.Expect(sent[0].OrderedBuildEvent.Event).To(BeIdenticalTo(started.OrderedBuildEvent.Event))
+		g.Expect(started.OrderedBuildEvent.SequenceNumber).To(Equal(int64(1)))
+	})
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bep
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+)
+
+var buildEventIdOneof = (&buildeventstream.BuildEventId{}).ProtoReflect().Descriptor().Oneofs().ByName("id")
+
+// EventType returns the type of a build event, which is the name of the field
+// set in its BuildEventId, e.g. "test_result" or "target_completed".
+func EventType(event *buildeventstream.BuildEvent) string {
+	id := event.GetId()
+	if id == nil {
+		return ""
+	}
+	field := id.ProtoReflect().WhichOneof(buildEventIdOneof)
+	if field == nil {
+		return ""
+	}
+	return string(field.Name())
+}
+
+// IsEventType returns true if name is a valid build event type.
+func IsEventType(name string) bool {
+	return buildEventIdOneof.Fields().ByName(protoreflect.Name(name)) != nil
+}
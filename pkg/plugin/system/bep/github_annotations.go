@@ -0,0 +1,196 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bep
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+)
+
+// GitHubAnnotationEventTypes are the build events GitHubAnnotations subscribes
+// to.
+var GitHubAnnotationEventTypes = []string{"started", "progress", "action_completed", "test_summary"}
+
+// maxActionStderrSize bounds how much of the stderr of a failed action is read
+// for diagnostics.
+const maxActionStderrSize = 1024 * 1024
+
+var (
+	ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+	// bazelError matches the errors bazel reports at a location of a BUILD
+	// or .bzl file, e.g. analysis errors and the actions of a target that
+	// failed.
+	bazelError = regexp.MustCompile(`^ERROR: ([^:\s]+):(\d+):(\d+): (.+)$`)
+	// diagnostic matches the path:line[:column]: message diagnostics printed
+	// by most compilers.
+	diagnostic = regexp.MustCompile(`^([\w./@+-]+\.\w+):(\d+):(?:(\d+):)? *(?:(?:fatal )?error: *)?(.+)$`)
+)
+
+// IsGitHubActions returns true when running in a GitHub Actions workflow.
+func IsGitHubActions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+type annotation struct {
+	level   string
+	file    string
+	line    string
+	col     string
+	title   string
+	message string
+}
+
+// GitHubAnnotations is a subscriber that reports the failures of a build as
+// GitHub Actions workflow annotations, so that they are shown on the pull
+// request: the errors bazel reports in BUILD and .bzl files, the diagnostics of
+// the actions that failed and the tests that failed or were flaky.
+type GitHubAnnotations struct {
+	mu        sync.Mutex
+	w         io.Writer
+	workspace string
+	seen      map[string]bool
+}
+
+// NewGitHubAnnotations creates a subscriber that writes the workflow commands
+// of the annotations to w, which must be the stdout of the workflow step.
+func NewGitHubAnnotations(w io.Writer) *GitHubAnnotations {
+	return &GitHubAnnotations{
+		w:    w,
+		seen: map[string]bool{},
+	}
+}
+
+// Callback annotates the failures reported by a build event. It is a
+// CallbackFn.
+func (a *GitHubAnnotations) Callback(event *buildeventstream.BuildEvent, _ int64, _ StreamInfo) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	switch {
+	case event.GetStarted() != nil:
+		a.workspace = event.GetStarted().GetWorkspaceDirectory()
+	case event.GetProgress() != nil:
+		for _, line := range strings.Split(ansiEscape.ReplaceAllString(event.GetProgress().GetStderr(), ""), "\n") {
+			if m := bazelError.FindStringSubmatch(strings.TrimRight(line, "\r")); m != nil {
+				a.annotate(annotation{level: "error", file: a.relativePath(m[1]), line: m[2], col: m[3], message: m[4]})
+			}
+		}
+	case event.GetAction() != nil:
+		action := event.GetAction()
+		if action.GetSuccess() {
+			return nil
+		}
+		title := fmt.Sprintf("%s failed", action.GetType())
+		if label := event.GetId().GetActionCompleted().GetLabel(); label != "" {
+			title = fmt.Sprintf("%s failed for %s", action.GetType(), label)
+		}
+		for _, line := range strings.Split(ansiEscape.ReplaceAllString(readStderr(action.GetStderr()), ""), "\n") {
+			m := diagnostic.FindStringSubmatch(strings.TrimRight(line, "\r"))
+			if m == nil || strings.HasPrefix(m[4], "warning:") || strings.HasPrefix(m[4], "note:") {
+				continue
+			}
+			a.annotate(annotation{level: "error", file: a.relativePath(m[1]), line: m[2], col: m[3], title: title, message: m[4]})
+		}
+	case event.GetTestSummary() != nil:
+		label := event.GetId().GetTestSummary().GetLabel()
+		switch status := event.GetTestSummary().GetOverallStatus(); status {
+		case buildeventstream.TestStatus_FAILED,
+			buildeventstream.TestStatus_TIMEOUT,
+			buildeventstream.TestStatus_INCOMPLETE,
+			buildeventstream.TestStatus_REMOTE_FAILURE:
+			a.annotate(annotation{level: "error", title: "Test failed", message: fmt.Sprintf("%s %s", label, status)})
+		case buildeventstream.TestStatus_FLAKY:
+			a.annotate(annotation{level: "warning", title: "Test is flaky", message: fmt.Sprintf("%s %s", label, status)})
+		}
+	}
+	return nil
+}
+
+// annotate writes the workflow command of an annotation, unless the same
+// annotation was already written.
+func (a *GitHubAnnotations) annotate(an annotation) {
+	var props []string
+	for _, p := range [][2]string{{"file", an.file}, {"line", an.line}, {"col", an.col}, {"title", an.title}} {
+		if p[1] != "" {
+			props = append(props, p[0]+"="+escapeProperty(p[1]))
+		}
+	}
+	command := "::" + an.level
+	if len(props) > 0 {
+		command += " " + strings.Join(props, ",")
+	}
+	command += "::" + escapeData(an.message)
+	if a.seen[command] {
+		return
+	}
+	a.seen[command] = true
+	fmt.Fprintln(a.w, command)
+}
+
+// relativePath returns the path of a file relative to the workspace, which is
+// the root of the repository GitHub resolves the annotations against. Paths
+// in the execution root of the workspace are relative to it already.
+func (a *GitHubAnnotations) relativePath(path string) string {
+	if _, rel, ok := strings.Cut(path, "/execroot/"); ok {
+		if _, rel, ok := strings.Cut(rel, "/"); ok {
+			return rel
+		}
+	}
+	if a.workspace != "" && filepath.IsAbs(path) {
+		if rel, err := filepath.Rel(a.workspace, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return path
+}
+
+// readStderr returns the stderr of an action, if it is inlined in the build
+// event or written to a local file.
+func readStderr(f *buildeventstream.File) string {
+	if contents := f.GetContents(); contents != nil {
+		return string(contents)
+	}
+	u, err := url.Parse(f.GetUri())
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	file, err := os.Open(u.Path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	b, _ := io.ReadAll(io.LimitReader(file, maxActionStderrSize))
+	return string(b)
+}
+
+// escapeData and escapeProperty escape the message and the properties of a
+// workflow command.
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bep
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+)
+
+func TestGitHubAnnotations(t *testing.T) {
+	started := &buildeventstream.BuildEvent{
+		Payload: &buildeventstream.BuildEvent_Started{Started: &buildeventstream.BuildStarted{WorkspaceDirectory: "/home/runner/work/repo"}},
+	}
+	testSummary := func(label string, status buildeventstream.TestStatus) *buildeventstream.BuildEvent {
+		return &buildeventstream.BuildEvent{
+			Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_TestSummary{
+				TestSummary: &buildeventstream.BuildEventId_TestSummaryId{Label: label},
+			}},
+			Payload: &buildeventstream.BuildEvent_TestSummary{TestSummary: &buildeventstream.TestSummary{OverallStatus: status}},
+		}
+	}
+	failedAction := func(stderr *buildeventstream.File) *buildeventstream.BuildEvent {
+		return &buildeventstream.BuildEvent{
+			Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_ActionCompleted{
+				ActionCompleted: &buildeventstream.BuildEventId_ActionCompletedId{Label: "//pkg:lib"},
+			}},
+			Payload: &buildeventstream.BuildEvent_Action{Action: &buildeventstream.ActionExecuted{
+				Type:   "GoCompilePkg",
+				Stderr: stderr,
+			}},
+		}
+	}
+
+	annotate := func(g *WithT, events ...*buildeventstream.BuildEvent) []string {
+		var out strings.Builder
+		a := NewGitHubAnnotations(&out)
+		for _, event := range events {
+			g.Expect(a.Callback(event, 0, StreamInfo{})).To(Succeed())
+		}
+		return strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	}
+
+	t.Run("annotates the errors bazel reports in BUILD files", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		g.Expect(annotate(g, started, &buildeventstream.BuildEvent{
+			Payload: &buildeventstream.BuildEvent_Progress{Progress: &buildeventstream.Progress{
+				Stderr: "\x1b[32mINFO: \x1b[0mAnalyzed 3 targets\n\x1b[31m\x1b[1mERROR: \x1b[0m/home/runner/work/repo/pkg/BUILD.bazel:3:11: no such target '//other:dep': 50% done\n",
+			}},
+		})).To(Equal([]string{
+			"::error file=pkg/BUILD.bazel,line=3,col=11::no such target '//other:dep': 50%25 done",
+		}))
+	})
+
+	t.Run("annotates the diagnostics of failed actions", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		stderr := "pkg/lib.go:12:3: undefined: x\npkg/lib.c:4:1: warning: unused variable\n"
+		g.Expect(annotate(g, started, failedAction(&buildeventstream.File{
+			File: &buildeventstream.File_Contents{Contents: []byte(stderr)},
+		}))).To(Equal([]string{
+			"::error file=pkg/lib.go,line=12,col=3,title=GoCompilePkg failed for //pkg%3Alib::undefined: x",
+		}))
+	})
+
+	t.Run("reads the stderr of failed actions from local files", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		path := filepath.Join(t.TempDir(), "stderr")
+		g.Expect(os.WriteFile(path, []byte("/tmp/_bazel/execroot/_main/pkg/lib.cc:7: error: expected ';'\n"), 0o644)).To(Succeed())
+		g.Expect(annotate(g, started, failedAction(&buildeventstream.File{
+			File: &buildeventstream.File_Uri{Uri: "file://" + path},
+		}))).To(Equal([]string{
+			"::error file=pkg/lib.cc,line=7,title=GoCompilePkg failed for //pkg%3Alib::expected ';'",
+		}))
+	})
+
+	t.Run("annotates failed and flaky tests once", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		g.Expect(annotate(g,
+			testSummary("//pkg:passing_test", buildeventstream.TestStatus_PASSED),
+			testSummary("//pkg:failing_test", buildeventstream.TestStatus_FAILED),
+			testSummary("//pkg:failing_test", buildeventstream.TestStatus_FAILED),
+			testSummary("//pkg:flaky_test", buildeventstream.TestStatus_FLAKY),
+		)).To(Equal([]string{
+			"::error title=Test failed:://pkg:failing_test FAILED",
+			"::warning title=Test is flaky:://pkg:flaky_test FLAKY",
+		}))
+	})
+
+	t.Run("subscribes to known event types", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		for _, eventType := range GitHubAnnotationEventTypes {
+			g.Expect(IsEventType(eventType)).To(BeTrue(), eventType)
+		}
+	})
+}
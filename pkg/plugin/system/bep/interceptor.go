@@ -30,11 +30,18 @@ type BESInterceptor interface {
 	GracefulStop()
 
 	// Args added to the bazel command line.
-	Args() []string
+	Args() ([]string, error)
 
 	Errors() []error
 
+	// DeliveryReports returns what was delivered to each BES backend over the
+	// invocations of the command.
+	DeliveryReports() []AckReport
+
 	RegisterBesProxy(ctx context.Context, p besproxy.BESProxy)
 
-	RegisterSubscriber(callback CallbackFn, multiThreaded bool)
+	// RegisterSubscriber registers a callback for the build events of the given
+	// types, see EventType. The callback receives every event when no types are
+	// given.
+	RegisterSubscriber(callback CallbackFn, opts SubscriberOptions, eventTypes ...string)
 }
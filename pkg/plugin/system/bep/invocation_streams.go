@@ -0,0 +1,108 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bep
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/besproxy"
+	"google.golang.org/grpc"
+)
+
+// invocationStreams are the build event streams of one invocation to the BES
+// backends, and the trackers of their acknowledgements.
+type invocationStreams struct {
+	proxies  []besproxy.BESProxy
+	trackers []*ackTracker
+	// leased is set when the streams are on the registered proxies, which are
+	// returned to their streamLease once the invocation ends.
+	leased bool
+}
+
+// streamLease hands the registered proxies to one invocation at a time. The
+// invocations that run concurrently with it stream to the backends on
+// connections of their own.
+type streamLease struct {
+	mu   sync.Mutex
+	busy bool
+}
+
+// acquire takes the lease, and returns false if another invocation holds it.
+func (l *streamLease) acquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.busy {
+		return false
+	}
+	l.busy = true
+	return true
+}
+
+// release returns the lease of the streams if they hold it.
+func (l *streamLease) release(streams *invocationStreams) {
+	if !streams.leased {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.busy = false
+}
+
+// dialFn connects a new proxy to a BES backend.
+type dialFn func(backend besproxy.Backend) (besproxy.BESProxy, error)
+
+// dialBackend connects a new proxy to backend.
+func dialBackend(backend besproxy.Backend) (besproxy.BESProxy, error) {
+	p := besproxy.NewBesProxyForBackend(backend)
+	if err := p.Connect(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// dialInvocationStreams connects new proxies to the backends of the registered
+// proxies, for an invocation that runs concurrently with the one holding them.
+// The backends that can't be connected to are left out. The streams are not
+// opened yet.
+func dialInvocationStreams(registered []besproxy.BESProxy, excludedEventTypes []string, sampling EventSampling, dial dialFn) *invocationStreams {
+	streams := &invocationStreams{}
+	for _, r := range registered {
+		p, err := dial(r.Backend())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error connecting to BES backend %v: %s\n", r.Host(), err.Error())
+			continue
+		}
+		tracker := trackAcks(p)
+		streams.trackers = append(streams.trackers, tracker)
+		streams.proxies = append(streams.proxies, SampleEvents(FilterEvents(tracker, excludedEventTypes), sampling))
+	}
+	return streams
+}
+
+// open opens a new build event stream on each proxy, resetting the trackers
+// of the acknowledgements of the earlier stream.
+func (s *invocationStreams) open(ctx context.Context) {
+	for i, p := range s.proxies {
+		s.trackers[i].reset()
+		if err := p.PublishBuildToolEventStream(ctx, grpc.WaitForReady(false)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating build event stream to %v: %s\n", p.Host(), err.Error())
+		}
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bep
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+)
+
+// JSONFile is a subscriber that writes the build events to a file in the
+// format of --build_event_json_file: one JSON object per line, in the order
+// the events were received. Like a spool, it stops writing after the first
+// error instead of failing the subscription, and reports the error when it is
+// closed.
+type JSONFile struct {
+	mu   sync.Mutex
+	file *os.File
+	w    *bufio.Writer
+	err  error
+}
+
+// CreateJSONFile creates the file at path for the build events to be written
+// to, truncating it if it exists.
+func CreateJSONFile(path string) (*JSONFile, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create build event JSON file: %w", err)
+	}
+	return &JSONFile{file: file, w: bufio.NewWriter(file)}, nil
+}
+
+// Callback writes a build event to the file. It is a CallbackFn.
+func (f *JSONFile) Callback(event *buildeventstream.BuildEvent, _ int64, _ StreamInfo) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil
+	}
+	b, err := protojson.Marshal(event)
+	if err != nil {
+		f.err = err
+		return nil
+	}
+	if _, err := f.w.Write(b); err != nil {
+		f.err = err
+		return nil
+	}
+	f.err = f.w.WriteByte('\n')
+	return nil
+}
+
+// Close flushes the build events to the file and closes it.
+func (f *JSONFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	err := f.err
+	if err == nil {
+		err = f.w.Flush()
+	}
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write build event JSON file %s: %w", f.file.Name(), err)
+	}
+	return nil
+}
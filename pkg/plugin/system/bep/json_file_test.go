@@ -0,0 +1,69 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bep
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+)
+
+func TestJSONFile(t *testing.T) {
+	t.Run("writes a build event per line", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		path := filepath.Join(t.TempDir(), "bep.json")
+
+		events := []*buildeventstream.BuildEvent{
+			{Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_Started{}}},
+			{Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_BuildFinished{}}, LastMessage: true},
+		}
+
+		f, err := CreateJSONFile(path)
+		g.Expect(err).ToNot(HaveOccurred())
+		for i, event := range events {
+			g.Expect(f.Callback(event, int64(i+1), StreamInfo{InvocationId: "inv"})).To(Succeed())
+		}
+		g.Expect(f.Close()).To(Succeed())
+
+		file, err := os.Open(path)
+		g.Expect(err).ToNot(HaveOccurred())
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		var read []*buildeventstream.BuildEvent
+		for scanner.Scan() {
+			event := &buildeventstream.BuildEvent{}
+			g.Expect(protojson.Unmarshal(scanner.Bytes(), event)).To(Succeed())
+			read = append(read, event)
+		}
+		g.Expect(read).To(HaveLen(2))
+		g.Expect(EventType(read[0])).To(Equal("started"))
+		g.Expect(read[1].LastMessage).To(BeTrue())
+	})
+
+	t.Run("fails to create a file in a missing directory", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		_, err := CreateJSONFile(filepath.Join(t.TempDir(), "missing", "bep.json"))
+		g.Expect(err).To(MatchError(ContainSubstring("failed to create build event JSON file")))
+	})
+}
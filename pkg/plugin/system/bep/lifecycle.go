@@ -0,0 +1,122 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package bep
+
+import (
+	"context"
+	"time"
+
+	buildv1 "google.golang.org/genproto/googleapis/devtools/build/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/besproxy"
+)
+
+// The lifecycle events are sent as bazel sends them: BuildEnqueued and
+// BuildFinished on the stream of the build, InvocationAttemptStarted and
+// InvocationAttemptFinished on the stream of the invocation.
+// https://github.com/bazelbuild/bazel/blob/198c4c8aae1b5ef3d202f602932a99ce19707fc4/src/main/java/com/google/devtools/build/lib/buildeventservice/client/BuildEventServiceProtoUtil.java
+
+// invocationAttempt is the attempt number of the invocations the CLI streams,
+// which bazel never retries.
+const invocationAttempt = 1
+
+func sendInitialLifecycleEvents(ctx context.Context, p besproxy.BESProxy, buildId, invocationId string, enqueued time.Time) {
+	eventTime := timestamppb.New(enqueued)
+	p.PublishLifecycleEvent(ctx, lifecycleRequest(buildId, "", 1, &buildv1.BuildEvent{
+		EventTime: eventTime,
+		Event:     &buildv1.BuildEvent_BuildEnqueued_{BuildEnqueued: &buildv1.BuildEvent_BuildEnqueued{}},
+	}))
+	p.PublishLifecycleEvent(ctx, lifecycleRequest(buildId, invocationId, 1, &buildv1.BuildEvent{
+		EventTime: eventTime,
+		Event: &buildv1.BuildEvent_InvocationAttemptStarted_{InvocationAttemptStarted: &buildv1.BuildEvent_InvocationAttemptStarted{
+			AttemptNumber: invocationAttempt,
+		}},
+	}))
+}
+
+// sendFinalLifecycleEvents reports the status of the invocation from its
+// BuildFinished build event, which is nil when bazel didn't send it.
+func sendFinalLifecycleEvents(ctx context.Context, p besproxy.BESProxy, buildId, invocationId string, finished *buildeventstream.BuildFinished) {
+	eventTime := timestamppb.Now()
+	if finished.GetFinishTime() != nil {
+		eventTime = finished.GetFinishTime()
+	}
+	status := invocationStatus(finished)
+	p.PublishLifecycleEvent(ctx, lifecycleRequest(buildId, invocationId, 2, &buildv1.BuildEvent{
+		EventTime: eventTime,
+		Event: &buildv1.BuildEvent_InvocationAttemptFinished_{InvocationAttemptFinished: &buildv1.BuildEvent_InvocationAttemptFinished{
+			InvocationStatus: status,
+		}},
+	}))
+	p.PublishLifecycleEvent(ctx, lifecycleRequest(buildId, "", 2, &buildv1.BuildEvent{
+		EventTime: eventTime,
+		Event:     &buildv1.BuildEvent_BuildFinished_{BuildFinished: &buildv1.BuildEvent_BuildFinished{Status: status}},
+	}))
+}
+
+// invocationStatus maps the exit code bazel reported in the BuildFinished
+// build event to the result of the invocation.
+func invocationStatus(finished *buildeventstream.BuildFinished) *buildv1.BuildStatus {
+	if finished.GetExitCode() == nil {
+		return &buildv1.BuildStatus{Result: buildv1.BuildStatus_UNKNOWN_STATUS}
+	}
+	code := finished.GetExitCode().GetCode()
+	status := &buildv1.BuildStatus{BuildToolExitCode: wrapperspb.Int32(code)}
+	switch code {
+	case 0:
+		status.Result = buildv1.BuildStatus_COMMAND_SUCCEEDED
+	case 2:
+		// COMMAND_LINE_ERROR
+		status.Result = buildv1.BuildStatus_USER_ERROR
+	case 8:
+		// INTERRUPTED
+		status.Result = buildv1.BuildStatus_CANCELLED
+	case 33:
+		// OOM_ERROR
+		status.Result = buildv1.BuildStatus_RESOURCE_EXHAUSTED
+	case 32, 34, 36, 37, 38, 45:
+		// The infrastructure failures: REMOTE_ENVIRONMENTAL_ERROR,
+		// REMOTE_ERROR, LOCAL_ENVIRONMENTAL_ERROR, BLAZE_INTERNAL_ERROR,
+		// PUBLISH_ERROR and PERSISTENT_BUILD_EVENT_SERVICE_UPLOAD_ERROR.
+		status.Result = buildv1.BuildStatus_SYSTEM_ERROR
+	default:
+		status.Result = buildv1.BuildStatus_COMMAND_FAILED
+	}
+	if code != 0 {
+		status.ErrorMessage = finished.GetExitCode().GetName()
+	}
+	return status
+}
+
+// lifecycleRequest wraps a lifecycle event of the stream of the invocation, or
+// of the build when invocationId is empty.
+func lifecycleRequest(buildId, invocationId string, sequenceNumber int64, event *buildv1.BuildEvent) *buildv1.PublishLifecycleEventRequest {
+	return &buildv1.PublishLifecycleEventRequest{
+		ServiceLevel: buildv1.PublishLifecycleEventRequest_INTERACTIVE,
+		BuildEvent: &buildv1.OrderedBuildEvent{
+			SequenceNumber: sequenceNumber,
+			StreamId: &buildv1.StreamId{
+				BuildId:      buildId,
+				InvocationId: invocationId,
+				Component:    buildv1.StreamId_CONTROLLER,
+			},
+			Event: event,
+		},
+	}
+}
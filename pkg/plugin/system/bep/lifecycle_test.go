@@ -0,0 +1,97 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package bep
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	buildv1 "google.golang.org/genproto/googleapis/devtools/build/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/besproxy"
+)
+
+func TestLifecycleEvents(t *testing.T) {
+	t.Run("reports the invocation and its status as bazel does", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		srv, url := startRecordingBES(t)
+
+		p := besproxy.NewBesProxyForBackend(besproxy.Backend{URL: url})
+		g.Expect(p.Connect()).To(Succeed())
+
+		enqueued := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+		finishTime := timestamppb.New(enqueued.Add(time.Minute))
+		sendInitialLifecycleEvents(context.Background(), p, "build", "inv", enqueued)
+		sendFinalLifecycleEvents(context.Background(), p, "build", "inv", &buildeventstream.BuildFinished{
+			ExitCode:   &buildeventstream.BuildFinished_ExitCode{Name: "BUILD_FAILURE", Code: 1},
+			FinishTime: finishTime,
+		})
+
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		g.Expect(srv.lifecycle).To(HaveLen(4))
+
+		enqueuedEvent := srv.lifecycle[0].GetBuildEvent()
+		g.Expect(enqueuedEvent.GetEvent().GetBuildEnqueued()).ToNot(BeNil())
+		g.Expect(enqueuedEvent.GetEvent().GetEventTime().AsTime()).To(Equal(enqueued))
+		g.Expect(enqueuedEvent.GetStreamId().GetInvocationId()).To(BeEmpty())
+		g.Expect(enqueuedEvent.GetSequenceNumber()).To(Equal(int64(1)))
+
+		startedEvent := srv.lifecycle[1].GetBuildEvent()
+		g.Expect(startedEvent.GetEvent().GetInvocationAttemptStarted().GetAttemptNumber()).To(Equal(int64(1)))
+		g.Expect(startedEvent.GetStreamId().GetInvocationId()).To(Equal("inv"))
+		g.Expect(startedEvent.GetSequenceNumber()).To(Equal(int64(1)))
+
+		attemptFinished := srv.lifecycle[2].GetBuildEvent()
+		status := attemptFinished.GetEvent().GetInvocationAttemptFinished().GetInvocationStatus()
+		g.Expect(status.GetResult()).To(Equal(buildv1.BuildStatus_COMMAND_FAILED))
+		g.Expect(status.GetBuildToolExitCode().GetValue()).To(Equal(int32(1)))
+		g.Expect(status.GetErrorMessage()).To(Equal("BUILD_FAILURE"))
+		g.Expect(attemptFinished.GetEvent().GetEventTime().AsTime()).To(Equal(finishTime.AsTime()))
+		g.Expect(attemptFinished.GetStreamId().GetInvocationId()).To(Equal("inv"))
+		g.Expect(attemptFinished.GetSequenceNumber()).To(Equal(int64(2)))
+
+		buildFinished := srv.lifecycle[3].GetBuildEvent()
+		g.Expect(buildFinished.GetEvent().GetBuildFinished().GetStatus().GetResult()).To(Equal(buildv1.BuildStatus_COMMAND_FAILED))
+		g.Expect(buildFinished.GetStreamId().GetInvocationId()).To(BeEmpty())
+		g.Expect(buildFinished.GetSequenceNumber()).To(Equal(int64(2)))
+	})
+}
+
+func TestInvocationStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		finished *buildeventstream.BuildFinished
+		want     buildv1.BuildStatus_Result
+	}{
+		{"unknown without a BuildFinished event", nil, buildv1.BuildStatus_UNKNOWN_STATUS},
+		{"succeeded", &buildeventstream.BuildFinished{ExitCode: &buildeventstream.BuildFinished_ExitCode{Name: "SUCCESS"}}, buildv1.BuildStatus_COMMAND_SUCCEEDED},
+		{"failed tests", &buildeventstream.BuildFinished{ExitCode: &buildeventstream.BuildFinished_ExitCode{Name: "TESTS_FAILED", Code: 3}}, buildv1.BuildStatus_COMMAND_FAILED},
+		{"a command line error", &buildeventstream.BuildFinished{ExitCode: &buildeventstream.BuildFinished_ExitCode{Name: "COMMAND_LINE_ERROR", Code: 2}}, buildv1.BuildStatus_USER_ERROR},
+		{"an interrupted build", &buildeventstream.BuildFinished{ExitCode: &buildeventstream.BuildFinished_ExitCode{Name: "INTERRUPTED", Code: 8}}, buildv1.BuildStatus_CANCELLED},
+		{"a remote error", &buildeventstream.BuildFinished{ExitCode: &buildeventstream.BuildFinished_ExitCode{Name: "REMOTE_ERROR", Code: 34}}, buildv1.BuildStatus_SYSTEM_ERROR},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			g.Expect(invocationStatus(tc.finished).GetResult()).To(Equal(tc.want))
+		})
+	}
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package bep
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	buildv1 "google.golang.org/genproto/googleapis/devtools/build/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+)
+
+// DefaultMaxEventSize bounds the size of a serialized build event unless
+// configured; we have observed 17 MB BES events in the wild.
+const DefaultMaxEventSize = 32 * 1024 * 1024
+
+// EventTooLargeError is returned for a build event that exceeds the maximum
+// size. Type is the type of the build event when it could be read.
+type EventTooLargeError struct {
+	Type    string
+	Size    uint64
+	MaxSize int
+}
+
+func (e *EventTooLargeError) Error() string {
+	event := "build event"
+	if e.Type != "" {
+		event = fmt.Sprintf("%s build event", e.Type)
+	}
+	return fmt.Sprintf("%s of %d bytes exceeds the maximum build event size of %d bytes", event, e.Size, e.MaxSize)
+}
+
+var (
+	buildEventDescriptor = (&buildeventstream.BuildEvent{}).ProtoReflect().Descriptor()
+	// buildEventTypeURL is the type URL of the Any wrapping a build event, as
+	// anypb.New sets it.
+	buildEventTypeURL      = "type.googleapis.com/" + string(buildEventDescriptor.FullName())
+	buildEventIdField      = buildEventDescriptor.Fields().ByName("id").Number()
+	buildEventLastMsgField = buildEventDescriptor.Fields().ByName("last_message").Number()
+)
+
+// readBuildEvent reads the next length-delimited build event from r, as
+// bazel writes them with --build_event_binary_file, and returns its serialized
+// bytes. It returns io.EOF when r ends before the next build event, and an
+// EventTooLargeError when it exceeds maxSize, or DefaultMaxEventSize if zero.
+func readBuildEvent(r *bufio.Reader, maxSize int) ([]byte, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxEventSize
+	}
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if size > uint64(maxSize) {
+		return nil, &EventTooLargeError{Type: peekEventType(r, size), Size: size, MaxSize: maxSize}
+	}
+	raw := make([]byte, size)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return raw, nil
+}
+
+// buildToolEventRequest wraps a serialized build event in the gRPC message
+// sent to the BES backends. The bytes are forwarded as they are rather than
+// marshaled again, and the request can be shared by all the backends.
+func buildToolEventRequest(buildId, invocationId string, seqId int64, raw []byte) *buildv1.PublishBuildToolEventStreamRequest {
+	return &buildv1.PublishBuildToolEventStreamRequest{
+		OrderedBuildEvent: &buildv1.OrderedBuildEvent{
+			SequenceNumber: seqId,
+			StreamId: &buildv1.StreamId{
+				BuildId:      buildId,
+				InvocationId: invocationId,
+			},
+			Event: &buildv1.BuildEvent{
+				EventTime: timestamppb.Now(),
+				Event:     &buildv1.BuildEvent_BazelEvent{BazelEvent: &anypb.Any{TypeUrl: buildEventTypeURL, Value: raw}},
+			},
+		},
+	}
+}
+
+// buildEventHeader decodes the id and last_message of a serialized build event
+// without decoding its payload, which can be large.
+func buildEventHeader(raw []byte) (*buildeventstream.BuildEvent, error) {
+	header := &buildeventstream.BuildEvent{}
+	for len(raw) > 0 {
+		num, typ, n := protowire.ConsumeTag(raw)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		raw = raw[n:]
+		switch {
+		case num == buildEventIdField && typ == protowire.BytesType:
+			id, n := protowire.ConsumeBytes(raw)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			if header.Id == nil {
+				header.Id = &buildeventstream.BuildEventId{}
+			}
+			// Like proto.Unmarshal, repeated occurrences of a message are merged.
+			if err := (proto.UnmarshalOptions{Merge: true}).Unmarshal(id, header.Id); err != nil {
+				return nil, err
+			}
+			raw = raw[n:]
+		case num == buildEventLastMsgField && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(raw)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			header.LastMessage = protowire.DecodeBool(v)
+			raw = raw[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, raw)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			raw = raw[n:]
+		}
+	}
+	return header, nil
+}
+
+// peekEventType returns the type of the build event of the given size that r
+// is at, without reading it, or "" if its id isn't within the buffer of r.
+// Bazel writes the id of a build event first.
+func peekEventType(r *bufio.Reader, size uint64) string {
+	prefix, _ := r.Peek(int(min(size, uint64(r.Size()))))
+	num, typ, n := protowire.ConsumeTag(prefix)
+	if n < 0 || num != buildEventIdField || typ != protowire.BytesType {
+		return ""
+	}
+	id, n := protowire.ConsumeBytes(prefix[n:])
+	if n < 0 {
+		return ""
+	}
+	event := &buildeventstream.BuildEvent{Id: &buildeventstream.BuildEventId{}}
+	if err := proto.Unmarshal(id, event.Id); err != nil {
+		return ""
+	}
+	return EventType(event)
+}
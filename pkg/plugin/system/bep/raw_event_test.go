@@ -0,0 +1,108 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package bep
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+)
+
+func TestRawBuildEvents(t *testing.T) {
+	finished := &buildeventstream.BuildEvent{
+		Id: &buildeventstream.BuildEventId{Id: &buildeventstream.BuildEventId_BuildFinished{}},
+		Payload: &buildeventstream.BuildEvent_Finished{Finished: &buildeventstream.BuildFinished{
+			ExitCode: &buildeventstream.BuildFinished_ExitCode{Name: "SUCCESS"},
+		}},
+		LastMessage: true,
+	}
+
+	t.Run("reads the serialized build events", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		var buf bytes.Buffer
+		_, err := protodelim.MarshalTo(&buf, finished)
+		g.Expect(err).ToNot(HaveOccurred())
+		reader := bufio.NewReader(&buf)
+
+		raw, err := readBuildEvent(reader, 0)
+		g.Expect(err).ToNot(HaveOccurred())
+		event := &buildeventstream.BuildEvent{}
+		g.Expect(proto.Unmarshal(raw, event)).To(Succeed())
+		g.Expect(proto.Equal(event, finished)).To(BeTrue())
+
+		_, err = readBuildEvent(reader, 0)
+		g.Expect(err).To(MatchError(io.EOF))
+	})
+
+	t.Run("rejects truncated build events", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		var buf bytes.Buffer
+		_, err := protodelim.MarshalTo(&buf, finished)
+		g.Expect(err).ToNot(HaveOccurred())
+		buf.Truncate(buf.Len() - 1)
+
+		_, err = readBuildEvent(bufio.NewReader(&buf), 0)
+		g.Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+	})
+
+	t.Run("names the type of the build events that are too large", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		var buf bytes.Buffer
+		_, err := protodelim.MarshalTo(&buf, finished)
+		g.Expect(err).ToNot(HaveOccurred())
+
+		_, err = readBuildEvent(bufio.NewReader(&buf), 10)
+		var tooLarge *EventTooLargeError
+		g.Expect(errors.As(err, &tooLarge)).To(BeTrue())
+		g.Expect(tooLarge.Type).To(Equal("build_finished"))
+		g.Expect(err).To(MatchError("build_finished build event of 20 bytes exceeds the maximum build event size of 10 bytes"))
+	})
+
+	t.Run("forwards the serialized build event as anypb would wrap it", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		raw, err := proto.Marshal(finished)
+		g.Expect(err).ToNot(HaveOccurred())
+		wrapped, err := anypb.New(finished)
+		g.Expect(err).ToNot(HaveOccurred())
+
+		req := buildToolEventRequest("build", "inv", 3, raw)
+		bazelEvent := req.GetOrderedBuildEvent().GetEvent().GetBazelEvent()
+		g.Expect(bazelEvent.GetTypeUrl()).To(Equal(wrapped.GetTypeUrl()))
+		// The bytes are not copied.
+		g.Expect(&bazelEvent.GetValue()[0]).To(BeIdenticalTo(&raw[0]))
+	})
+
+	t.Run("decodes only the header of a build event", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		raw, err := proto.Marshal(finished)
+		g.Expect(err).ToNot(HaveOccurred())
+
+		header, err := buildEventHeader(raw)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(EventType(header)).To(Equal("build_finished"))
+		g.Expect(header.LastMessage).To(BeTrue())
+		g.Expect(header.GetFinished()).To(BeNil())
+	})
+}
@@ -0,0 +1,34 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package bep
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/fatih/color"
+)
+
+// printResultsURLs prints the link to the invocation in the UI of each backend
+// that has a results URL. Bazel only prints the link of --bes_results_url,
+// since it doesn't see the backends the build events are forwarded to.
+func printResultsURLs(w io.Writer, trackers []*ackTracker, stream StreamInfo) {
+	for _, t := range trackers {
+		if url := t.Backend().InvocationURL(stream.BuildId, stream.InvocationId); url != "" {
+			fmt.Fprintf(w, "%s Streaming build results to: %s\n", color.GreenString("INFO:"), url)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package bep
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/besproxy"
+)
+
+func TestPrintResultsURLs(t *testing.T) {
+	t.Run("prints the link to the invocation of the backends with a results url", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		trackers := []*ackTracker{
+			trackAcks(besproxy.NewBesProxyForBackend(besproxy.Backend{URL: "grpcs://remote.buildbuddy.io", ResultsURL: "https://app.buildbuddy.io/invocation/"})),
+			trackAcks(besproxy.NewBesProxyForBackend(besproxy.Backend{URL: "grpc://localhost:1985"})),
+			trackAcks(besproxy.NewBesProxyForBackend(besproxy.Backend{URL: "grpcs://bes.example.com", ResultsURL: "https://bes.example.com/{build_id}/{invocation_id}"})),
+		}
+
+		var out bytes.Buffer
+		printResultsURLs(&out, trackers, StreamInfo{BuildId: "build-1", InvocationId: "inv-1", Command: "build"})
+		g.Expect(out.String()).To(Equal(
+			"INFO: Streaming build results to: https://app.buildbuddy.io/invocation/inv-1\n" +
+				"INFO: Streaming build results to: https://bes.example.com/build-1/inv-1\n",
+		))
+	})
+}
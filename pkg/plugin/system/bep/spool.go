@@ -0,0 +1,118 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package bep
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is the compression of a build event spool.
+type Compression string
+
+const (
+	CompressionNone Compression = "none"
+	CompressionGzip Compression = "gzip"
+	CompressionZstd Compression = "zstd"
+)
+
+// ParseCompression parses the compression of a build event spool. An empty
+// compression is inferred from the extension of the spool path, and is none
+// unless the path ends in .gz or .zst.
+func ParseCompression(compression string, path string) (Compression, error) {
+	switch Compression(compression) {
+	case CompressionNone, CompressionGzip, CompressionZstd:
+		return Compression(compression), nil
+	case "":
+		switch {
+		case strings.HasSuffix(path, ".gz"):
+			return CompressionGzip, nil
+		case strings.HasSuffix(path, ".zst"):
+			return CompressionZstd, nil
+		default:
+			return CompressionNone, nil
+		}
+	default:
+		return "", fmt.Errorf("unknown build event spool compression %q: must be one of %q, %q or %q", compression, CompressionNone, CompressionGzip, CompressionZstd)
+	}
+}
+
+// Spool configures a copy of the build event stream that the BES pipe writes
+// to disk as it reads it from bazel, in the format of
+// --build_event_binary_file. The zero value doesn't write a spool.
+type Spool struct {
+	Path        string
+	Compression Compression
+}
+
+// spoolWriter writes the build event stream to a spool. It stops writing
+// after the first error instead of failing the stream, and reports the error
+// when it is closed.
+type spoolWriter struct {
+	file       *os.File
+	compressor io.WriteCloser
+	err        error
+}
+
+func openSpool(spool Spool) (*spoolWriter, error) {
+	file, err := os.Create(spool.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create build event spool: %w", err)
+	}
+	w := &spoolWriter{file: file}
+	switch spool.Compression {
+	case CompressionGzip:
+		w.compressor = gzip.NewWriter(file)
+	case CompressionZstd:
+		encoder, err := zstd.NewWriter(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to create build event spool: %w", err)
+		}
+		w.compressor = encoder
+	}
+	return w, nil
+}
+
+func (w *spoolWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return len(p), nil
+	}
+	if w.compressor != nil {
+		_, w.err = w.compressor.Write(p)
+	} else {
+		_, w.err = w.file.Write(p)
+	}
+	return len(p), nil
+}
+
+func (w *spoolWriter) Close() error {
+	err := w.err
+	if w.compressor != nil {
+		err = errors.Join(err, w.compressor.Close())
+	}
+	err = errors.Join(err, w.file.Close())
+	if err != nil {
+		return fmt.Errorf("failed to write build event spool %s: %w", w.file.Name(), err)
+	}
+	return nil
+}
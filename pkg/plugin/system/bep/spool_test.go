@@ -0,0 +1,95 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package bep
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	. "github.com/onsi/gomega"
+)
+
+func TestParseCompression(t *testing.T) {
+	t.Run("accepts the known compressions", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		g.Expect(ParseCompression("none", "bep.bin.zst")).To(Equal(CompressionNone))
+		g.Expect(ParseCompression("gzip", "bep.bin")).To(Equal(CompressionGzip))
+		g.Expect(ParseCompression("zstd", "bep.bin")).To(Equal(CompressionZstd))
+	})
+
+	t.Run("infers the compression from the extension of the path", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		g.Expect(ParseCompression("", "bep.bin.gz")).To(Equal(CompressionGzip))
+		g.Expect(ParseCompression("", "bep.bin.zst")).To(Equal(CompressionZstd))
+		g.Expect(ParseCompression("", "bep.bin")).To(Equal(CompressionNone))
+	})
+
+	t.Run("rejects unknown compressions", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		_, err := ParseCompression("brotli", "bep.bin")
+		g.Expect(err).To(MatchError(ContainSubstring(`unknown build event spool compression "brotli"`)))
+	})
+}
+
+func TestSpoolWriter(t *testing.T) {
+	stream := []byte("the build event stream")
+
+	for _, tc := range []struct {
+		compression Compression
+		decompress  func(io.Reader) (io.Reader, error)
+	}{
+		{CompressionNone, func(r io.Reader) (io.Reader, error) { return r, nil }},
+		{CompressionGzip, func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{CompressionZstd, func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) }},
+	} {
+		t.Run(string(tc.compression), func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			path := filepath.Join(t.TempDir(), "bep.bin")
+
+			w, err := openSpool(Spool{Path: path, Compression: tc.compression})
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(w.Write(stream[:9])).To(Equal(9))
+			g.Expect(w.Write(stream[9:])).To(Equal(len(stream) - 9))
+			g.Expect(w.Close()).To(Succeed())
+
+			f, err := os.Open(path)
+			g.Expect(err).ToNot(HaveOccurred())
+			defer f.Close()
+			r, err := tc.decompress(f)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(io.ReadAll(r)).To(Equal(stream))
+		})
+	}
+
+	t.Run("reports a failed write when closed", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		path := filepath.Join(t.TempDir(), "bep.bin")
+
+		w, err := openSpool(Spool{Path: path, Compression: CompressionNone})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(w.file.Close()).To(Succeed())
+
+		g.Expect(w.Write(stream)).To(Equal(len(stream)))
+		g.Expect(w.Close()).To(MatchError(ContainSubstring("failed to write build event spool")))
+	})
+}
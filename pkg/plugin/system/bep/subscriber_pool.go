@@ -0,0 +1,232 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bep
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/fatih/color"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+)
+
+// DefaultSubscriberWorkers is the number of workers calling the callback of a
+// multi-threaded subscriber with unordered delivery unless configured.
+const DefaultSubscriberWorkers = 4
+
+// DefaultSubscriberQueueSize bounds the build events queued for a
+// multi-threaded subscriber unless configured.
+const DefaultSubscriberQueueSize = 1024
+
+// QueuePolicy is what happens to a build event for a multi-threaded subscriber
+// whose queue is full.
+type QueuePolicy string
+
+const (
+	// QueueBlock waits for the subscriber to make room in its queue, which
+	// holds up reading the build events. This is the default.
+	QueueBlock QueuePolicy = "block"
+	// QueueDropOldest drops the oldest build event queued for the subscriber to
+	// make room for the new one.
+	QueueDropOldest QueuePolicy = "drop-oldest"
+	// QueueFail fails the subscriber, which receives no more build events.
+	QueueFail QueuePolicy = "fail"
+)
+
+// SubscriberOptions configures how build events are delivered to a subscriber.
+type SubscriberOptions struct {
+	// MultiThreaded delivers build events to the subscriber from its own pool of
+	// workers, concurrently with the other subscribers, rather than on the
+	// goroutine reading them.
+	MultiThreaded bool
+	// Workers is the number of workers of a multi-threaded subscriber with
+	// unordered delivery. DefaultSubscriberWorkers applies when zero.
+	Workers int
+	// Ordered delivers build events to a multi-threaded subscriber one at a time
+	// in the order they are read, using a single worker.
+	Ordered bool
+	// QueueSize is the number of build events queued for a multi-threaded
+	// subscriber. DefaultSubscriberQueueSize applies when zero.
+	QueueSize int
+	// QueuePolicy applies when the queue of a multi-threaded subscriber is
+	// full. QueueBlock applies when empty.
+	QueuePolicy QueuePolicy
+	// Name identifies the subscriber in warnings and errors, e.g. the name of
+	// a plugin.
+	Name string
+	// Sampling samples or aggregates the build events of high-volume types
+	// before they are delivered to the subscriber, or queued for it when it
+	// is multi-threaded.
+	Sampling EventSampling
+}
+
+type subscriberEvent struct {
+	event  *buildeventstream.BuildEvent
+	seqId  int64
+	stream StreamInfo
+}
+
+// subscriberPool delivers the build events queued for a multi-threaded
+// subscriber from a fixed number of workers.
+type subscriberPool struct {
+	subscriber *subscriberNode
+	queue      chan subscriberEvent
+	wg         sync.WaitGroup
+	onError    func(error)
+	name       string
+	policy     QueuePolicy
+	// dropped counts the build events dropped under QueueDropOldest and failed
+	// is set once the queue overflowed under QueueFail. Both are only accessed
+	// by submit and close, which are not called concurrently.
+	dropped int
+	failed  bool
+
+	// pending counts the build events submitted that were neither delivered
+	// nor dropped, and delivered is signaled when it drops to zero.
+	mu        sync.Mutex
+	pending   int
+	delivered *sync.Cond
+}
+
+// newSubscriberPool starts the workers of a subscriber. Errors returned by its
+// callback are passed to onError, which must be safe for concurrent use.
+func newSubscriberPool(subscriber *subscriberNode, opts SubscriberOptions, onError func(error)) *subscriberPool {
+	workers := opts.Workers
+	if opts.Ordered {
+		workers = 1
+	} else if workers <= 0 {
+		workers = DefaultSubscriberWorkers
+	}
+
+	queueSize := opts.QueueSize
+	if queueSize <= 0 {
+		queueSize = DefaultSubscriberQueueSize
+	}
+	name := opts.Name
+	if name == "" {
+		name = "subscriber"
+	}
+
+	p := &subscriberPool{
+		subscriber: subscriber,
+		queue:      make(chan subscriberEvent, queueSize),
+		onError:    onError,
+		name:       name,
+		policy:     opts.QueuePolicy,
+	}
+	p.delivered = sync.NewCond(&p.mu)
+	p.wg.Add(workers)
+	for range workers {
+		go p.work()
+	}
+	return p
+}
+
+func (p *subscriberPool) work() {
+	defer p.wg.Done()
+	for e := range p.queue {
+		if err := p.subscriber.callback(e.event, e.seqId, e.stream); err != nil {
+			p.onError(err)
+		}
+		p.done()
+	}
+}
+
+// done records that a submitted event was delivered or dropped.
+func (p *subscriberPool) done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending--
+	if p.pending == 0 {
+		p.delivered.Broadcast()
+	}
+}
+
+// flush waits for the events submitted so far to be delivered, while the
+// pool keeps accepting events.
+func (p *subscriberPool) flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.pending > 0 {
+		p.delivered.Wait()
+	}
+}
+
+// submit queues an event for the subscriber if it wants events of the given
+// type. A sampled subscriber is queued the events that the sampler forwards in
+// place of the event instead.
+func (p *subscriberPool) submit(eventType string, e subscriberEvent) {
+	if p.subscriber.sampler == nil {
+		p.enqueue(eventType, e)
+		return
+	}
+	for _, s := range p.subscriber.sampler.sample(e.stream.InvocationId, e.event, e.seqId, e.stream) {
+		p.enqueue(EventType(s.event), subscriberEvent{event: s.event, seqId: s.seqId, stream: s.carrier})
+	}
+}
+
+// enqueue queues an event for the subscriber if it wants events of the given
+// type. When the queue is full, the policy of the subscriber decides whether it
+// blocks, drops the oldest queued event or fails the subscriber.
+func (p *subscriberPool) enqueue(eventType string, e subscriberEvent) {
+	if p.failed || !p.subscriber.wants(eventType) {
+		return
+	}
+	p.mu.Lock()
+	p.pending++
+	p.mu.Unlock()
+	switch p.policy {
+	case QueueDropOldest:
+		for {
+			select {
+			case p.queue <- e:
+				return
+			default:
+			}
+			// The workers may have made room in the meantime, in which case
+			// nothing is dropped.
+			select {
+			case <-p.queue:
+				p.dropped++
+				p.done()
+			default:
+			}
+		}
+	case QueueFail:
+		select {
+		case p.queue <- e:
+		default:
+			p.done()
+			p.failed = true
+			p.onError(fmt.Errorf("the build event queue of %s is full: it no longer receives build events", p.name))
+		}
+	default:
+		p.queue <- e
+	}
+}
+
+// close stops the pool from accepting events and waits for the queued ones to
+// be delivered.
+func (p *subscriberPool) close() {
+	close(p.queue)
+	p.wg.Wait()
+	if p.dropped > 0 {
+		fmt.Fprintf(os.Stderr, "%s dropped %d build events for %s whose queue was full\n", color.YellowString("WARNING:"), p.dropped, p.name)
+	}
+}
@@ -0,0 +1,207 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bep
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspecterrors"
+)
+
+func TestSubscriberPool(t *testing.T) {
+	t.Run("delivers build events in order", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var received []int64
+		subscriber := newSubscriberNode(func(evt *buildeventstream.BuildEvent, sn int64, stream StreamInfo) error {
+			received = append(received, sn)
+			return nil
+		})
+		p := newSubscriberPool(subscriber, SubscriberOptions{MultiThreaded: true, Workers: 8, Ordered: true}, func(error) {})
+		for sn := int64(1); sn <= 100; sn++ {
+			p.submit("progress", subscriberEvent{seqId: sn})
+		}
+		p.close()
+
+		expected := make([]int64, 0, 100)
+		for sn := int64(1); sn <= 100; sn++ {
+			expected = append(expected, sn)
+		}
+		g.Expect(received).To(Equal(expected))
+	})
+
+	t.Run("flushes the build events submitted so far", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var received atomic.Int64
+		subscriber := newSubscriberNode(func(evt *buildeventstream.BuildEvent, sn int64, stream StreamInfo) error {
+			time.Sleep(time.Millisecond)
+			received.Add(1)
+			return nil
+		})
+		p := newSubscriberPool(subscriber, SubscriberOptions{MultiThreaded: true, Workers: 4}, func(error) {})
+		for sn := int64(1); sn <= 20; sn++ {
+			p.submit("progress", subscriberEvent{seqId: sn})
+		}
+		p.flush()
+		g.Expect(received.Load()).To(BeEquivalentTo(20))
+
+		// The pool keeps accepting build events.
+		p.submit("progress", subscriberEvent{seqId: 21})
+		p.close()
+		g.Expect(received.Load()).To(BeEquivalentTo(21))
+	})
+
+	t.Run("delivers build events from concurrent workers", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		// Every callback waits for the others to start, which only returns if
+		// all workers run at once.
+		var started sync.WaitGroup
+		started.Add(3)
+		var mu sync.Mutex
+		var received []int64
+		subscriber := newSubscriberNode(func(evt *buildeventstream.BuildEvent, sn int64, stream StreamInfo) error {
+			started.Done()
+			started.Wait()
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, sn)
+			return nil
+		})
+		p := newSubscriberPool(subscriber, SubscriberOptions{MultiThreaded: true, Workers: 3}, func(error) {})
+		for sn := int64(1); sn <= 3; sn++ {
+			p.submit("progress", subscriberEvent{seqId: sn})
+		}
+		p.close()
+
+		g.Expect(received).To(ConsistOf(int64(1), int64(2), int64(3)))
+	})
+
+	t.Run("only delivers the build events the subscriber wants", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var received []int64
+		subscriber := newSubscriberNode(func(evt *buildeventstream.BuildEvent, sn int64, stream StreamInfo) error {
+			received = append(received, sn)
+			return nil
+		}, "build_finished")
+		p := newSubscriberPool(subscriber, SubscriberOptions{MultiThreaded: true, Ordered: true}, func(error) {})
+		p.submit("progress", subscriberEvent{seqId: 1})
+		p.submit("build_finished", subscriberEvent{seqId: 2})
+		p.close()
+
+		g.Expect(received).To(Equal([]int64{2}))
+	})
+
+	t.Run("drops the oldest build events when the queue is full", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		// The worker holds the first event until every event was submitted.
+		unblock := make(chan struct{})
+		started := make(chan struct{})
+		var received []int64
+		subscriber := newSubscriberNode(func(evt *buildeventstream.BuildEvent, sn int64, stream StreamInfo) error {
+			if sn == 1 {
+				close(started)
+				<-unblock
+			}
+			received = append(received, sn)
+			return nil
+		})
+		p := newSubscriberPool(subscriber, SubscriberOptions{MultiThreaded: true, Ordered: true, QueueSize: 2, QueuePolicy: QueueDropOldest}, func(error) {})
+		p.submit("progress", subscriberEvent{seqId: 1})
+		<-started
+		for sn := int64(2); sn <= 5; sn++ {
+			p.submit("progress", subscriberEvent{seqId: sn})
+		}
+		close(unblock)
+		p.close()
+
+		g.Expect(received).To(Equal([]int64{1, 4, 5}))
+		g.Expect(p.dropped).To(Equal(2))
+	})
+
+	t.Run("fails the subscriber when the queue is full", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		unblock := make(chan struct{})
+		started := make(chan struct{})
+		var received []int64
+		subscriber := newSubscriberNode(func(evt *buildeventstream.BuildEvent, sn int64, stream StreamInfo) error {
+			if sn == 1 {
+				close(started)
+				<-unblock
+			}
+			received = append(received, sn)
+			return nil
+		})
+		var errs []error
+		p := newSubscriberPool(subscriber, SubscriberOptions{MultiThreaded: true, Ordered: true, QueueSize: 1, QueuePolicy: QueueFail, Name: "slow"}, func(err error) {
+			errs = append(errs, err)
+		})
+		p.submit("progress", subscriberEvent{seqId: 1})
+		<-started
+		for sn := int64(2); sn <= 4; sn++ {
+			p.submit("progress", subscriberEvent{seqId: sn})
+		}
+		close(unblock)
+		p.close()
+
+		g.Expect(received).To(Equal([]int64{1, 2}))
+		g.Expect(errs).To(HaveLen(1))
+		g.Expect(errs[0]).To(MatchError(ContainSubstring("the build event queue of slow is full")))
+	})
+}
+
+func TestPublishBesEvent(t *testing.T) {
+	t.Run("doesn't wait for multi-threaded subscribers", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		bb := &besPipe{
+			errors:      &aspecterrors.ErrorList{},
+			subscribers: &subscriberList{},
+		}
+		var ordered []int64
+		bb.RegisterSubscriber(func(evt *buildeventstream.BuildEvent, sn int64, stream StreamInfo) error {
+			ordered = append(ordered, sn)
+			return nil
+		}, SubscriberOptions{})
+		unblock := make(chan struct{})
+		bb.RegisterSubscriber(func(evt *buildeventstream.BuildEvent, sn int64, stream StreamInfo) error {
+			<-unblock
+			return fmt.Errorf("error from event %d", sn)
+		}, SubscriberOptions{MultiThreaded: true, Ordered: true})
+
+		inv := &pipeInvocation{streams: &invocationStreams{}}
+		event := &buildeventstream.BuildEvent{}
+		g.Expect(bb.publishBesEvent(inv, 1, event, nil)).To(Succeed())
+		g.Expect(bb.publishBesEvent(inv, 2, event, nil)).To(Succeed())
+		g.Expect(ordered).To(Equal([]int64{1, 2}))
+
+		close(unblock)
+		bb.drainSubscriberPools()
+		g.Expect(bb.Errors()).To(HaveLen(2))
+	})
+}
@@ -0,0 +1,243 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package besproxy
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel/workspace"
+)
+
+// BackendsKey is the key of the Aspect CLI config that lists the build event
+// stream backends to forward the build events to, in addition to those given
+// with --bes_backend.
+const BackendsKey = "bes_backends"
+
+// Backend is a build event stream backend the build events are forwarded to.
+type Backend struct {
+	// URL is the grpc://, grpcs:// or unix:// URL of the backend, or its
+	// http:// or https:// URL with the transports over HTTP.
+	URL string `json:"url"`
+	// Transport is how the backend is reached. TransportGRPC applies when
+	// empty.
+	Transport Transport `json:"transport,omitempty"`
+	// Headers are sent with every call to the backend as gRPC metadata.
+	Headers map[string]string `json:"headers,omitempty"`
+	TLS     TLSConfig         `json:"tls"`
+	// ResultsURL is the template of the link to the invocation in the UI of
+	// the backend. {invocation_id} and {build_id} are replaced with the IDs of
+	// the invocation; without either, the invocation ID is appended like bazel
+	// does with --bes_results_url.
+	ResultsURL string `json:"results_url,omitempty"`
+	// Recovery configures how the stream to the backend is resumed after the
+	// backend was unreachable. It is not part of the Aspect CLI config entry.
+	Recovery Recovery `json:"-"`
+	// FlowControl tunes the stream to the backend. It is not part of the
+	// Aspect CLI config entry.
+	FlowControl FlowControl `json:"-"`
+}
+
+// Recovery bounds how the build event stream to a backend that became
+// unreachable is probed to be resumed, with the build events sent meanwhile,
+// instead of taking the backend out of rotation right away.
+type Recovery struct {
+	// ProbeInterval is how often the stream is probed. It isn't when zero.
+	ProbeInterval time.Duration
+	// Timeout is how long the stream is probed before the backend is taken
+	// out of rotation.
+	Timeout time.Duration
+}
+
+// DefaultWriteBufferSize is the size of the write buffer of the connection to
+// a backend when FlowControl doesn't set one. gRPC writes at most 32 KB at a
+// time by default, which takes a syscall every few build events when bazel
+// reports a burst of them.
+const DefaultWriteBufferSize = 1 << 20
+
+// DefaultMaxInFlight is how many build events are sent to a backend without
+// being acknowledged when FlowControl doesn't set it. Bazel reports a few
+// thousand build events per second at most, so it keeps the stream busy over
+// links with a round trip of a second.
+const DefaultMaxInFlight = 10000
+
+// FlowControl tunes how much of the build event stream to a backend is in
+// flight. Sizes that are zero use the defaults.
+type FlowControl struct {
+	// WindowSize and ConnWindowSize are the initial HTTP/2 flow control
+	// windows of the stream and the connection, in bytes. gRPC sizes them
+	// from the bandwidth-delay product it measures on the connection unless
+	// they are set.
+	WindowSize     int32
+	ConnWindowSize int32
+	// WriteBufferSize and ReadBufferSize are the sizes of the buffers of the
+	// connection, in bytes. The write buffer defaults to
+	// DefaultWriteBufferSize and the read buffer to that of gRPC.
+	WriteBufferSize int
+	ReadBufferSize  int
+	// MaxInFlight is how many build events are sent without being
+	// acknowledged by the backend before Send waits for acknowledgements. It
+	// defaults to DefaultMaxInFlight.
+	MaxInFlight int
+}
+
+func (f FlowControl) writeBufferSize() int {
+	if f.WriteBufferSize > 0 {
+		return f.WriteBufferSize
+	}
+	return DefaultWriteBufferSize
+}
+
+func (f FlowControl) maxInFlight() int {
+	if f.MaxInFlight > 0 {
+		return f.MaxInFlight
+	}
+	return DefaultMaxInFlight
+}
+
+// InvocationURL returns the link to the invocation in the UI of the backend,
+// or an empty string when the backend has no ResultsURL.
+func (b Backend) InvocationURL(buildId, invocationId string) string {
+	if b.ResultsURL == "" {
+		return ""
+	}
+	if !strings.Contains(b.ResultsURL, "{invocation_id}") && !strings.Contains(b.ResultsURL, "{build_id}") {
+		return b.ResultsURL + invocationId
+	}
+	return strings.NewReplacer("{invocation_id}", invocationId, "{build_id}", buildId).Replace(b.ResultsURL)
+}
+
+// TLSConfig configures the TLS connection to a grpcs:// or https:// backend. Paths
+// starting with %workspace% are relative to the workspace root.
+type TLSConfig struct {
+	// CACert is a PEM file with the certificates trusted in addition to those
+	// of the system.
+	CACert string `json:"ca_cert,omitempty"`
+	// ServerName overrides the name the certificate of the backend is
+	// verified against.
+	ServerName string `json:"server_name,omitempty"`
+	// ClientCert and ClientKey are the PEM files of the certificate and key
+	// the CLI authenticates itself with.
+	ClientCert string `json:"client_cert,omitempty"`
+	ClientKey  string `json:"client_key,omitempty"`
+	// Insecure skips the verification of the certificate of the backend. It
+	// is meant for testing against backends with self-signed certificates.
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+// UnmarshalBackendConfig parses the bes_backends list of the Aspect CLI
+// config. Environment variables in header values are expanded, so that API
+// keys don't need to be checked in.
+func UnmarshalBackendConfig(backendsConfig any) ([]Backend, error) {
+	if backendsConfig == nil {
+		return nil, nil
+	}
+	backendsList, ok := backendsConfig.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected %s config to be a list", BackendsKey)
+	}
+
+	backends := make([]Backend, 0, len(backendsList))
+	for i, b := range backendsList {
+		backendMap, ok := b.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expected %s config entry %v to be a map", BackendsKey, i)
+		}
+
+		backendURL, ok := backendMap["url"].(string)
+		if !ok || backendURL == "" {
+			return nil, fmt.Errorf("expected %s config entry %v to have a 'url' attribute", BackendsKey, i)
+		}
+		u, err := url.Parse(backendURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid url of %s config entry '%v': %w", BackendsKey, backendURL, err)
+		}
+		backend := Backend{URL: backendURL, Headers: map[string]string{}}
+		if transport, ok := backendMap["transport"]; ok {
+			name, ok := transport.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected the transport of %s config entry '%v' to be a string", BackendsKey, backendURL)
+			}
+			backend.Transport = Transport(name)
+		}
+		if err := backend.Transport.checkScheme(u.Scheme); err != nil {
+			return nil, fmt.Errorf("invalid url of %s config entry '%v': %w", BackendsKey, backendURL, err)
+		}
+
+		if headers, ok := backendMap["headers"]; ok {
+			headersMap, ok := headers.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("expected the headers of %s config entry '%v' to be a map", BackendsKey, backendURL)
+			}
+			for key, value := range headersMap {
+				backend.Headers[key] = os.ExpandEnv(fmt.Sprint(value))
+			}
+		}
+
+		if resultsURL, ok := backendMap["results_url"]; ok {
+			if backend.ResultsURL, ok = resultsURL.(string); !ok {
+				return nil, fmt.Errorf("expected the results_url of %s config entry '%v' to be a string", BackendsKey, backendURL)
+			}
+		}
+
+		if tlsConfig, ok := backendMap["tls"]; ok {
+			tlsMap, ok := tlsConfig.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("expected the tls of %s config entry '%v' to be a map", BackendsKey, backendURL)
+			}
+			if secure := backend.Transport.secureScheme(); u.Scheme != secure {
+				return nil, fmt.Errorf("%s config entry '%v' sets tls but is not a %s:// backend", BackendsKey, backendURL, secure)
+			}
+			backend.TLS.CACert, _ = tlsMap["ca_cert"].(string)
+			backend.TLS.ServerName, _ = tlsMap["server_name"].(string)
+			backend.TLS.ClientCert, _ = tlsMap["client_cert"].(string)
+			backend.TLS.ClientKey, _ = tlsMap["client_key"].(string)
+			if insecure, ok := tlsMap["insecure"]; ok {
+				if backend.TLS.Insecure, ok = insecure.(bool); !ok {
+					return nil, fmt.Errorf("expected the tls insecure of %s config entry '%v' to be a boolean", BackendsKey, backendURL)
+				}
+			}
+			if (backend.TLS.ClientCert == "") != (backend.TLS.ClientKey == "") {
+				return nil, fmt.Errorf("%s config entry '%v' must set both or neither of client_cert and client_key", BackendsKey, backendURL)
+			}
+		}
+
+		backends = append(backends, backend)
+	}
+	return backends, nil
+}
+
+// resolveWorkspacePath resolves a path starting with %workspace% against the
+// workspace root.
+func resolveWorkspacePath(path string) (string, error) {
+	rest, ok := strings.CutPrefix(path, "%workspace%")
+	if !ok {
+		return path, nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	workspaceRoot, err := workspace.DefaultFinder.Find(wd)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %q: %w", path, err)
+	}
+	return filepath.Join(workspaceRoot, rest), nil
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package besproxy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestUnmarshalBackendConfig(t *testing.T) {
+	t.Run("parses the backends", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Setenv("BES_API_KEY", "secret")
+
+		backends, err := UnmarshalBackendConfig([]any{
+			map[string]any{
+				"url":     "grpcs://bes.example.com",
+				"headers": map[string]any{"x-api-key": "${BES_API_KEY}", "x-org-id": 42},
+				"tls": map[string]any{
+					"ca_cert":     "%workspace%/certs/ca.pem",
+					"server_name": "bes.internal",
+					"client_cert": "client.pem",
+					"client_key":  "client.key",
+					"insecure":    true,
+				},
+				"results_url": "https://bes.example.com/invocation/",
+			},
+			map[string]any{"url": "grpc://localhost:1985"},
+			map[string]any{"url": "https://bes.example.com/bes", "transport": "grpc-web", "tls": map[string]any{"server_name": "bes.internal"}},
+		})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(backends).To(Equal([]Backend{
+			{
+				URL:     "grpcs://bes.example.com",
+				Headers: map[string]string{"x-api-key": "secret", "x-org-id": "42"},
+				TLS: TLSConfig{
+					CACert:     "%workspace%/certs/ca.pem",
+					ServerName: "bes.internal",
+					ClientCert: "client.pem",
+					ClientKey:  "client.key",
+					Insecure:   true,
+				},
+				ResultsURL: "https://bes.example.com/invocation/",
+			},
+			{URL: "grpc://localhost:1985", Headers: map[string]string{}},
+			{URL: "https://bes.example.com/bes", Transport: TransportGRPCWeb, Headers: map[string]string{}, TLS: TLSConfig{ServerName: "bes.internal"}},
+		}))
+	})
+
+	t.Run("accepts no config", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		g.Expect(UnmarshalBackendConfig(nil)).To(BeEmpty())
+	})
+
+	for _, tc := range []struct {
+		name   string
+		config any
+		err    string
+	}{
+		{"a config that is not a list", map[string]any{}, "expected bes_backends config to be a list"},
+		{"a backend without a url", []any{map[string]any{}}, "to have a 'url' attribute"},
+		{"a url with another scheme", []any{map[string]any{"url": "https://bes.example.com"}}, "the scheme must be grpc, grpcs or unix"},
+		{"an unknown transport", []any{map[string]any{"url": "https://bes.example.com", "transport": "websocket"}}, `unknown transport "websocket", expected one of [connect grpc grpc-web]`},
+		{"a url with a scheme of another transport", []any{map[string]any{"url": "grpcs://bes.example.com", "transport": "connect"}}, "the scheme must be http or https with the connect transport"},
+		{"headers that are not a map", []any{map[string]any{"url": "grpc://localhost", "headers": "x-api-key"}}, "headers of bes_backends config entry"},
+		{"tls for a grpc:// backend", []any{map[string]any{"url": "grpc://localhost", "tls": map[string]any{}}}, "is not a grpcs:// backend"},
+		{"tls for an http:// backend", []any{map[string]any{"url": "http://localhost", "transport": "connect", "tls": map[string]any{}}}, "is not a https:// backend"},
+		{"a client cert without a key", []any{map[string]any{"url": "grpcs://localhost", "tls": map[string]any{"client_cert": "client.pem"}}}, "both or neither of client_cert and client_key"},
+		{"an insecure that is not a boolean", []any{map[string]any{"url": "grpcs://localhost", "tls": map[string]any{"insecure": "yes"}}}, "to be a boolean"},
+		{"a results_url that is not a string", []any{map[string]any{"url": "grpc://localhost", "results_url": 42}}, "results_url of bes_backends config entry"},
+	} {
+		t.Run("rejects "+tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			_, err := UnmarshalBackendConfig(tc.config)
+			g.Expect(err).To(MatchError(ContainSubstring(tc.err)))
+		})
+	}
+}
+
+func TestInvocationURL(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		resultsURL string
+		want       string
+	}{
+		{"no link without a results url", "", ""},
+		{"appends the invocation ID", "https://app.buildbuddy.io/invocation/", "https://app.buildbuddy.io/invocation/inv-1"},
+		{"fills in the template", "https://bes.example.com/invocations/{invocation_id}?build={build_id}", "https://bes.example.com/invocations/inv-1?build=build-1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			backend := Backend{URL: "grpcs://bes.example.com", ResultsURL: tc.resultsURL}
+			g.Expect(backend.InvocationURL("build-1", "inv-1")).To(Equal(tc.want))
+		})
+	}
+}
+
+func TestClientTLSConfig(t *testing.T) {
+	t.Run("sets the server name", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		config, err := clientTLSConfig(TLSConfig{ServerName: "bes.internal"})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(config.ServerName).To(Equal("bes.internal"))
+		g.Expect(config.RootCAs).ToNot(BeNil())
+		g.Expect(config.InsecureSkipVerify).To(BeFalse())
+	})
+
+	t.Run("skips the verification when insecure", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		config, err := clientTLSConfig(TLSConfig{Insecure: true})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(config.InsecureSkipVerify).To(BeTrue())
+	})
+
+	t.Run("fails when the CA cert has no certificates", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		caCert := filepath.Join(t.TempDir(), "ca.pem")
+		g.Expect(os.WriteFile(caCert, []byte("not a certificate"), 0644)).To(Succeed())
+
+		_, err := clientTLSConfig(TLSConfig{CACert: caCert})
+		g.Expect(err).To(MatchError(ContainSubstring("no certificates found in CA cert")))
+	})
+
+	t.Run("fails when the client certificate can't be loaded", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		dir := t.TempDir()
+
+		_, err := clientTLSConfig(TLSConfig{ClientCert: filepath.Join(dir, "client.pem"), ClientKey: filepath.Join(dir, "client.key")})
+		g.Expect(err).To(MatchError(ContainSubstring("failed to load client certificate")))
+	})
+}
@@ -17,11 +17,20 @@
 package besproxy
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protodelim"
 	"google.golang.org/protobuf/types/known/emptypb"
 
 	buildv1 "google.golang.org/genproto/googleapis/devtools/build/v1"
@@ -29,6 +38,21 @@ import (
 
 const maxStreamErrors = 5
 
+// maxStreamRetries is how often re-establishing a failed build event stream
+// is attempted before the stream is given up on.
+const maxStreamRetries = 5
+
+// initialRetryBackoff is how long to wait before the first attempt to
+// re-establish a failed build event stream. The wait doubles with every
+// attempt, up to maxRetryBackoff.
+const initialRetryBackoff = 100 * time.Millisecond
+const maxRetryBackoff = 5 * time.Second
+
+// ErrNotResumed is returned by CloseSend when the stream of a backend that
+// became unreachable couldn't be resumed before the end of the invocation, so
+// the backend didn't receive all its build events.
+var ErrNotResumed = errors.New("build event stream was not resumed")
+
 // BESProxy implements a Build Event Protocol backend to be passed to the
 // `bazel build` command so that the Aspect plugins can register as subscribers
 // to the build events.
@@ -39,6 +63,7 @@ type BESProxy interface {
 	PublishBuildToolEventStream(ctx context.Context, opts ...grpc.CallOption) error
 	PublishLifecycleEvent(ctx context.Context, req *buildv1.PublishLifecycleEventRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	StreamCreated() bool
+	Backend() Backend
 	Healthy() bool
 	MarkUnhealthy()
 	Recv() (*buildv1.PublishBuildToolEventStreamResponse, error)
@@ -46,27 +71,93 @@ type BESProxy interface {
 }
 
 func NewBesProxy(host string, headers map[string]string) *besProxy {
-	return &besProxy{
-		host:    host,
-		headers: headers,
+	return NewBesProxyForBackend(Backend{URL: host, Headers: headers})
+}
+
+// NewBesProxyForBackend creates a proxy to a backend from the Aspect CLI
+// config.
+func NewBesProxyForBackend(backend Backend) *besProxy {
+	bp := &besProxy{
+		host:         backend.URL,
+		transport:    backend.Transport,
+		headers:      backend.Headers,
+		tls:          backend.TLS,
+		resultsURL:   backend.ResultsURL,
+		recovery:     backend.Recovery,
+		flowControl:  backend.FlowControl,
+		retryBackoff: initialRetryBackoff,
 	}
+	bp.inFlight = sync.NewCond(&bp.mu)
+	return bp
 }
 
 type besProxy struct {
-	hadError int32
+	hadError atomic.Int32
+
+	client    buildv1.PublishBuildEventClient
+	host      string
+	transport Transport
+	headers   map[string]string
+	tls       TLSConfig
+	// resultsURL is the template of the link to the invocation in the UI of
+	// the backend.
+	resultsURL  string
+	flowControl FlowControl
+
+	// mu guards the stream and the events sent on it that the backend hasn't
+	// acknowledged yet. When the stream fails, it is re-established and those
+	// events are sent again, so that the backend resumes from the first
+	// sequence number it is missing. generation counts the streams, so that
+	// a stream that failed in both Send and Recv is only re-established once.
+	mu           sync.Mutex
+	stream       buildv1.PublishBuildEvent_PublishBuildToolEventStreamClient
+	sendClosed   bool
+	cancelStream context.CancelFunc
+	generation   int
+	unacked      []*buildv1.PublishBuildToolEventStreamRequest
+	streamCtx    context.Context
+	streamOpts   []grpc.CallOption
+	streamOpen   atomic.Bool
+	retryBackoff time.Duration
+	// reconnects counts the failed streams that were resumed.
+	reconnects atomic.Int32
+	// inFlight is signaled when Send may no longer need to wait for the
+	// backend to acknowledge events, once it has flowControl.MaxInFlight
+	// unacknowledged ones.
+	inFlight *sync.Cond
 
-	client  buildv1.PublishBuildEventClient
-	stream  buildv1.PublishBuildEvent_PublishBuildToolEventStreamClient
-	host    string
-	headers map[string]string
+	// recovery bounds how a stream that failed for good is probed to be
+	// resumed, and outage is set while it is. recovering mirrors whether
+	// outage is set, so that Healthy doesn't wait for mu.
+	recovery   Recovery
+	outage     *outage
+	recovering atomic.Bool
+}
+
+// outage is the time a backend is unreachable after its stream failed for
+// good. The build events sent meanwhile are journaled to disk, and sent once
+// the stream is resumed.
+type outage struct {
+	// mu serializes the attempts to resume the stream and ending the outage.
+	mu      sync.Mutex
+	since   time.Time
+	ctx     context.Context
+	opts    []grpc.CallOption
+	journal *os.File
+	// size is the size of the journal, guarded by the mu of the proxy.
+	size int64
+	// done is closed once the stream was resumed or given up on, which lost
+	// is set for.
+	done chan struct{}
+	lost bool
 }
 
 func (bp *besProxy) Connect() error {
-	c, err := grpcDial(bp.host, bp.headers)
+	client, err := dialTransport(bp.Backend())
 	if err != nil {
 		return fmt.Errorf("failed to connect to build event stream backend %s: %w", bp.host, err)
 	}
-	bp.client = buildv1.NewPublishBuildEventClient(c)
+	bp.client = client
 	return nil
 }
 
@@ -74,6 +165,12 @@ func (bp *besProxy) Host() string {
 	return bp.host
 }
 
+// Reconnects returns how many times the stream to the backend was resumed
+// after it failed.
+func (bp *besProxy) Reconnects() int {
+	return int(bp.reconnects.Load())
+}
+
 func (bp *besProxy) PublishLifecycleEvent(ctx context.Context, req *buildv1.PublishLifecycleEventRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
 	if bp.client == nil {
 		return &emptypb.Empty{}, fmt.Errorf("not connected to %v", bp.host)
@@ -89,67 +186,435 @@ func (bp *besProxy) PublishBuildToolEventStream(ctx context.Context, opts ...grp
 	if bp.client == nil {
 		return fmt.Errorf("not connected to %v", bp.host)
 	}
-	s, err := bp.client.PublishBuildToolEventStream(ctx, opts...)
+	streamCtx, cancel := context.WithCancel(ctx)
+	s, err := bp.client.PublishBuildToolEventStream(streamCtx, opts...)
 	if err != nil {
+		cancel()
 		bp.trackError(err)
 		return fmt.Errorf("failed calling PublishBuildToolEventStream to %v: %w", bp.host, err)
 	}
+	bp.mu.Lock()
+	o := bp.outage
+	bp.mu.Unlock()
+	if o != nil {
+		bp.endOutage(o, false)
+	}
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	// A new stream of the same connection starts over, as with the
+	// invocations of --watch, and the previous stream is no longer resumed.
 	bp.stream = s
+	bp.cancelStream = cancel
+	bp.streamCtx = ctx
+	bp.streamOpts = opts
+	bp.sendClosed = false
+	bp.unacked = nil
+	bp.generation++
+	bp.streamOpen.Store(true)
+	bp.inFlight.Broadcast()
 	return nil
 }
 
 func (bp *besProxy) StreamCreated() bool {
-	return bp.stream != nil
+	return bp.streamOpen.Load()
 }
 
+// Send sends a build event on the stream. It waits for the backend to
+// acknowledge events first when the maximum number of events of the flow
+// control is in flight, which bounds how far the backend lags behind and how
+// many events are sent again when the stream is resumed.
 func (bp *besProxy) Send(req *buildv1.PublishBuildToolEventStreamRequest) error {
-	if bp.stream == nil {
-		return fmt.Errorf("stream to %v not configured", bp.host)
+	bp.mu.Lock()
+	for {
+		if bp.stream == nil {
+			bp.mu.Unlock()
+			return fmt.Errorf("stream to %v not configured", bp.host)
+		}
+		if bp.sendClosed {
+			bp.mu.Unlock()
+			return fmt.Errorf("stream to %v is closed", bp.host)
+		}
+		if bp.outage != nil {
+			defer bp.mu.Unlock()
+			return bp.outage.append(req)
+		}
+		if len(bp.unacked) < bp.flowControl.maxInFlight() || bp.hadError.Load() >= maxStreamErrors {
+			break
+		}
+		bp.inFlight.Wait()
+	}
+	stream, generation := bp.stream, bp.generation
+	bp.unacked = append(bp.unacked, req)
+	bp.mu.Unlock()
+
+	// EOF indicates that the backend ended the stream, which is re-established
+	// like any other failed stream; the error itself is received by Recv.
+	if err := stream.Send(req); err != nil {
+		if err := bp.resume(generation, err); err != nil {
+			// The event is journaled with the unacknowledged ones when the
+			// stream is probed to be resumed later.
+			if bp.startOutage(generation) {
+				return nil
+			}
+			return bp.trackError(err)
+		}
 	}
+	return nil
+}
 
-	err := bp.stream.Send(req)
+func (bp *besProxy) Recv() (*buildv1.PublishBuildToolEventStreamResponse, error) {
+	for {
+		bp.mu.Lock()
+		stream, generation, o := bp.stream, bp.generation, bp.outage
+		bp.mu.Unlock()
+		if o != nil {
+			// The acknowledgements are received again once the stream was
+			// resumed.
+			<-o.done
+			if o.lost {
+				return nil, fmt.Errorf("stream to %v: %w", bp.host, ErrNotResumed)
+			}
+			continue
+		}
+		if stream == nil {
+			return nil, fmt.Errorf("stream to %v not configured", bp.host)
+		}
 
-	// EOF indicates the server sent an error which must be received.
-	if err == io.EOF {
-		_, err = bp.Recv()
+		resp, err := stream.Recv()
+		if err == nil {
+			bp.acknowledge(resp.GetSequenceNumber())
+			return resp, nil
+		}
+		if err == io.EOF {
+			return nil, bp.trackError(err)
+		}
+		if err := bp.resume(generation, err); err != nil && !bp.startOutage(generation) {
+			return nil, bp.trackError(err)
+		}
+		// Receive the acknowledgements of the re-established stream.
 	}
+}
 
-	return bp.trackError(err)
+// acknowledge forgets the events up to the given sequence number, which the
+// backend has received.
+func (bp *besProxy) acknowledge(sequenceNumber int64) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	i := 0
+	for i < len(bp.unacked) && bp.unacked[i].GetOrderedBuildEvent().GetSequenceNumber() <= sequenceNumber {
+		i++
+	}
+	bp.unacked = bp.unacked[i:]
+	if i > 0 {
+		bp.inFlight.Broadcast()
+	}
 }
 
-func (bp *besProxy) Recv() (*buildv1.PublishBuildToolEventStreamResponse, error) {
-	if bp.stream == nil {
-		return nil, fmt.Errorf("stream to %v not configured", bp.host)
+// resume re-establishes the stream of the given generation, which failed with
+// err, and sends the events the backend hasn't acknowledged on it again. It
+// retries with exponential backoff, and returns an error when err isn't
+// transient, the stream was closed or all retries failed. It returns nil right
+// away when the stream was already re-established.
+func (bp *besProxy) resume(generation int, err error) error {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	if bp.generation != generation {
+		return nil
+	}
+	if bp.stream == nil || bp.sendClosed || !isTransient(err) {
+		return err
+	}
+
+	backoff := bp.retryBackoff
+	for range maxStreamRetries {
+		select {
+		case <-bp.streamCtx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+
+		var stream buildv1.PublishBuildEvent_PublishBuildToolEventStreamClient
+		streamCtx, cancel := context.WithCancel(bp.streamCtx)
+		stream, err = bp.client.PublishBuildToolEventStream(streamCtx, bp.streamOpts...)
+		if err == nil {
+			err = sendAll(stream, bp.unacked)
+		}
+		if err == nil {
+			// Unblock the Recv of the failed stream, which then receives from
+			// the new one.
+			bp.cancelStream()
+			bp.stream = stream
+			bp.cancelStream = cancel
+			bp.generation++
+			bp.reconnects.Add(1)
+			return nil
+		}
+		cancel()
+		if !isTransient(err) {
+			break
+		}
+	}
+	return fmt.Errorf("failed to resume build event stream to %v: %w", bp.host, err)
+}
+
+func sendAll(stream buildv1.PublishBuildEvent_PublishBuildToolEventStreamClient, reqs []*buildv1.PublishBuildToolEventStreamRequest) error {
+	for _, req := range reqs {
+		if err := stream.Send(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isTransient returns whether a build event stream that failed with err is
+// worth re-establishing.
+func isTransient(err error) bool {
+	if err == io.EOF {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.Internal, codes.DeadlineExceeded:
+		return true
 	}
-	resp, err := bp.stream.Recv()
-	return resp, bp.trackError(err)
+	return false
 }
 
+// CloseSend closes the sending side of the stream. The acknowledgements of the
+// events sent so far can still be received until Recv returns io.EOF. A stream
+// that is probed to be resumed is tried once more right away, as no more
+// events will be sent, and ErrNotResumed is returned when that fails.
 func (bp *besProxy) CloseSend() error {
-	if bp.stream == nil {
+	bp.mu.Lock()
+	if bp.stream == nil || bp.sendClosed {
+		bp.mu.Unlock()
 		return nil
 	}
-	err := bp.stream.CloseSend()
-	bp.stream = nil
-	return err
+	bp.sendClosed = true
+	bp.streamOpen.Store(false)
+	bp.inFlight.Broadcast()
+	if o := bp.outage; o != nil {
+		bp.mu.Unlock()
+		if err := bp.resumeOutage(o); err != nil {
+			bp.endOutage(o, true)
+			return fmt.Errorf("stream to %v: %w: %w", bp.host, ErrNotResumed, err)
+		}
+		return nil
+	}
+	defer bp.mu.Unlock()
+	return bp.stream.CloseSend()
+}
+
+// Backend returns the backend the proxy forwards to.
+func (bp *besProxy) Backend() Backend {
+	return Backend{URL: bp.host, Transport: bp.transport, Headers: bp.headers, TLS: bp.tls, ResultsURL: bp.resultsURL, Recovery: bp.recovery, FlowControl: bp.flowControl}
 }
 
 // TrackError tracks errors and marks the stream as unhealthy if too many errors occur.
 func (bp *besProxy) trackError(err error) error {
 	if err != nil {
-		bp.hadError++
-		if bp.hadError == maxStreamErrors {
+		if bp.hadError.Add(1) == maxStreamErrors {
 			fmt.Printf("stream to %s is marked unhealthy, taking out of rotation.", bp.host)
 		}
 	}
 	return err
 }
 
+// Healthy returns whether build events are sent to the backend. A backend
+// whose stream is probed to be resumed stays in rotation, with the build
+// events journaled until it is.
 func (bp *besProxy) Healthy() bool {
-	return bp.hadError < maxStreamErrors && bp.stream != nil
+	return bp.recovering.Load() || bp.hadError.Load() < maxStreamErrors && bp.streamOpen.Load()
 }
 
 func (bp *besProxy) MarkUnhealthy() {
-	bp.hadError = maxStreamErrors
+	bp.mu.Lock()
+	generation := bp.generation
+	bp.mu.Unlock()
+	if bp.startOutage(generation) {
+		return
+	}
+	bp.mu.Lock()
+	bp.hadError.Store(maxStreamErrors)
+	bp.inFlight.Broadcast()
+	bp.mu.Unlock()
 	fmt.Printf("stream to %s is marked unhealthy, taking out of rotation.", bp.host)
 }
+
+// startOutage starts probing the failed stream of the given generation to
+// resume it, unless the proxy has no Recovery. The unacknowledged build
+// events are journaled, along with those sent until the stream is resumed.
+// It returns whether the stream is probed.
+func (bp *besProxy) startOutage(generation int) bool {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	if bp.outage != nil {
+		return true
+	}
+	if bp.recovery.ProbeInterval <= 0 || bp.generation != generation || bp.stream == nil || bp.sendClosed {
+		return false
+	}
+	if bp.hadError.Load() >= maxStreamErrors {
+		// The stream wasn't resumed before.
+		return false
+	}
+
+	journal, err := os.CreateTemp("", "aspect-cli-bes-journal-*")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to journal the build events of %s: %s\n", bp.host, err.Error())
+		return false
+	}
+	o := &outage{since: time.Now(), ctx: bp.streamCtx, opts: bp.streamOpts, journal: journal, done: make(chan struct{})}
+	for _, req := range bp.unacked {
+		if err := o.append(req); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to journal the build events of %s: %s\n", bp.host, err.Error())
+			o.close(true)
+			return false
+		}
+	}
+	bp.unacked = nil
+	bp.outage = o
+	bp.recovering.Store(true)
+	bp.inFlight.Broadcast()
+	// Unblock the Send and Recv of the failed stream.
+	bp.cancelStream()
+	bp.generation++
+	fmt.Fprintf(os.Stderr, "Stream to %s failed; probing it every %s to resume it\n", bp.host, bp.recovery.ProbeInterval)
+	go bp.probe(o)
+	return true
+}
+
+// probe tries to resume the stream of an outage every probe interval, until
+// it was resumed, or the timeout of the recovery passed and the backend is
+// taken out of rotation.
+func (bp *besProxy) probe(o *outage) {
+	deadline := o.since.Add(bp.recovery.Timeout)
+	for {
+		select {
+		case <-o.done:
+			return
+		case <-o.ctx.Done():
+			bp.endOutage(o, true)
+			return
+		case <-time.After(bp.recovery.ProbeInterval):
+		}
+		if err := bp.resumeOutage(o); err == nil {
+			return
+		}
+		if !time.Now().Before(deadline) {
+			bp.endOutage(o, true)
+			return
+		}
+	}
+}
+
+// resumeOutage re-establishes the stream of an outage and sends the journaled
+// build events on it, including those journaled meanwhile, before the build
+// events are sent on it directly again.
+func (bp *besProxy) resumeOutage(o *outage) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	select {
+	case <-o.done:
+		if o.lost {
+			return ErrNotResumed
+		}
+		return nil
+	default:
+	}
+
+	streamCtx, cancel := context.WithCancel(o.ctx)
+	stream, err := bp.client.PublishBuildToolEventStream(streamCtx, o.opts...)
+	if err != nil {
+		cancel()
+		return err
+	}
+	var sent []*buildv1.PublishBuildToolEventStreamRequest
+	var offset int64
+	for {
+		bp.mu.Lock()
+		size := o.size
+		if offset == size {
+			defer bp.mu.Unlock()
+			if bp.sendClosed {
+				if err := stream.CloseSend(); err != nil {
+					cancel()
+					return err
+				}
+			}
+			bp.stream = stream
+			bp.cancelStream = cancel
+			bp.generation++
+			bp.unacked = sent
+			bp.outage = nil
+			bp.hadError.Store(0)
+			bp.recovering.Store(false)
+			bp.reconnects.Add(1)
+			o.close(false)
+			fmt.Fprintf(os.Stderr, "Stream to %s resumed after %s\n", bp.host, time.Since(o.since).Round(time.Second))
+			return nil
+		}
+		bp.mu.Unlock()
+
+		r := bufio.NewReader(io.NewSectionReader(o.journal, offset, size-offset))
+		for {
+			req := &buildv1.PublishBuildToolEventStreamRequest{}
+			if err := protodelim.UnmarshalFrom(r, req); err != nil {
+				if err == io.EOF {
+					break
+				}
+				cancel()
+				return fmt.Errorf("failed to read the journaled build events: %w", err)
+			}
+			if err := stream.Send(req); err != nil {
+				cancel()
+				return err
+			}
+			sent = append(sent, req)
+		}
+		offset = size
+	}
+}
+
+// endOutage gives up on resuming the stream of an outage, and takes the
+// backend out of rotation when it is lost rather than discarded for a new
+// stream.
+func (bp *besProxy) endOutage(o *outage, lost bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	select {
+	case <-o.done:
+		return
+	default:
+	}
+	bp.mu.Lock()
+	bp.outage = nil
+	if lost {
+		bp.hadError.Store(maxStreamErrors)
+	}
+	bp.recovering.Store(false)
+	bp.inFlight.Broadcast()
+	bp.mu.Unlock()
+	o.close(true)
+	if lost {
+		fmt.Printf("stream to %s is marked unhealthy, taking out of rotation.", bp.host)
+	}
+}
+
+// append journals a build event. The mu of the proxy must be held.
+func (o *outage) append(req *buildv1.PublishBuildToolEventStreamRequest) error {
+	n, err := protodelim.MarshalTo(o.journal, req)
+	o.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to journal build event: %w", err)
+	}
+	return nil
+}
+
+// close ends the outage and removes its journal.
+func (o *outage) close(lost bool) {
+	o.lost = lost
+	close(o.done)
+	o.journal.Close()
+	os.Remove(o.journal.Name())
+}
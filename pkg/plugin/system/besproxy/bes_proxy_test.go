@@ -0,0 +1,290 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package besproxy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	buildv1 "google.golang.org/genproto/googleapis/devtools/build/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type fakeStream struct {
+	grpc.ClientStream
+	ctx context.Context
+	// sendErr fails sending the event at index failAt.
+	sendErr error
+	failAt  int
+	recvErr error
+	sent    []int64
+	acks    chan *buildv1.PublishBuildToolEventStreamResponse
+}
+
+func newFakeStream() *fakeStream {
+	return &fakeStream{failAt: -1, acks: make(chan *buildv1.PublishBuildToolEventStreamResponse, 10)}
+}
+
+func (s *fakeStream) Send(req *buildv1.PublishBuildToolEventStreamRequest) error {
+	if len(s.sent) == s.failAt {
+		return s.sendErr
+	}
+	s.sent = append(s.sent, req.OrderedBuildEvent.SequenceNumber)
+	return nil
+}
+
+func (s *fakeStream) Recv() (*buildv1.PublishBuildToolEventStreamResponse, error) {
+	if s.recvErr != nil {
+		return nil, s.recvErr
+	}
+	select {
+	case ack := <-s.acks:
+		return ack, nil
+	case <-s.ctx.Done():
+		return nil, status.Error(codes.Canceled, "stream cancelled")
+	}
+}
+
+func (s *fakeStream) CloseSend() error {
+	return nil
+}
+
+type fakeClient struct {
+	buildv1.PublishBuildEventClient
+	// streams are returned by PublishBuildToolEventStream in order, after
+	// which it fails with err.
+	streams []*fakeStream
+	err     error
+	calls   int
+}
+
+func (c *fakeClient) PublishBuildToolEventStream(ctx context.Context, _ ...grpc.CallOption) (buildv1.PublishBuildEvent_PublishBuildToolEventStreamClient, error) {
+	c.calls++
+	if len(c.streams) == 0 {
+		return nil, c.err
+	}
+	s := c.streams[0]
+	c.streams = c.streams[1:]
+	s.ctx = ctx
+	return s, nil
+}
+
+func (c *fakeClient) PublishLifecycleEvent(context.Context, *buildv1.PublishLifecycleEventRequest, ...grpc.CallOption) (*emptypb.Empty, error) {
+	return &emptypb.Empty{}, nil
+}
+
+func event(sequenceNumber int64) *buildv1.PublishBuildToolEventStreamRequest {
+	return &buildv1.PublishBuildToolEventStreamRequest{
+		OrderedBuildEvent: &buildv1.OrderedBuildEvent{SequenceNumber: sequenceNumber},
+	}
+}
+
+func ack(sequenceNumber int64) *buildv1.PublishBuildToolEventStreamResponse {
+	return &buildv1.PublishBuildToolEventStreamResponse{SequenceNumber: sequenceNumber}
+}
+
+func newTestProxy(t *testing.T, client *fakeClient) *besProxy {
+	bp := NewBesProxy("grpc://localhost", nil)
+	bp.client = client
+	bp.retryBackoff = 0
+	if err := bp.PublishBuildToolEventStream(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return bp
+}
+
+func TestBesProxy(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "backend restarting")
+
+	t.Run("resends the unacknowledged events when sending fails", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		first, second := newFakeStream(), newFakeStream()
+		first.sendErr, first.failAt = unavailable, 2
+		bp := newTestProxy(t, &fakeClient{streams: []*fakeStream{first, second}})
+
+		g.Expect(bp.Send(event(1))).To(Succeed())
+		g.Expect(bp.Send(event(2))).To(Succeed())
+		first.acks <- ack(1)
+		g.Expect(bp.Recv()).To(Equal(ack(1)))
+
+		g.Expect(bp.Send(event(3))).To(Succeed())
+		g.Expect(first.sent).To(Equal([]int64{1, 2}))
+		g.Expect(second.sent).To(Equal([]int64{2, 3}))
+		g.Expect(bp.Healthy()).To(BeTrue())
+
+		// The Recv of the failed stream is unblocked and moves on to the new one.
+		second.acks <- ack(3)
+		g.Expect(bp.Recv()).To(Equal(ack(3)))
+		g.Expect(bp.unacked).To(BeEmpty())
+		g.Expect(bp.Reconnects()).To(Equal(1))
+	})
+
+	t.Run("waits for acknowledgements once the maximum number of events is in flight", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		stream := newFakeStream()
+		bp := newTestProxy(t, &fakeClient{streams: []*fakeStream{stream}})
+		bp.flowControl.MaxInFlight = 2
+
+		g.Expect(bp.Send(event(1))).To(Succeed())
+		g.Expect(bp.Send(event(2))).To(Succeed())
+		sent := make(chan error, 1)
+		go func() { sent <- bp.Send(event(3)) }()
+		g.Consistently(sent, 50*time.Millisecond).ShouldNot(Receive())
+
+		stream.acks <- ack(1)
+		g.Expect(bp.Recv()).To(Equal(ack(1)))
+		g.Eventually(sent).Should(Receive(BeNil()))
+		g.Expect(bp.unacked).To(HaveLen(2))
+	})
+
+	t.Run("stops waiting for acknowledgements once the stream is closed", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		bp := newTestProxy(t, &fakeClient{streams: []*fakeStream{newFakeStream()}})
+		bp.flowControl.MaxInFlight = 1
+
+		g.Expect(bp.Send(event(1))).To(Succeed())
+		sent := make(chan error, 1)
+		go func() { sent <- bp.Send(event(2)) }()
+		g.Consistently(sent, 50*time.Millisecond).ShouldNot(Receive())
+
+		g.Expect(bp.CloseSend()).To(Succeed())
+		g.Eventually(sent).Should(Receive(MatchError("stream to grpc://localhost is closed")))
+	})
+
+	t.Run("resumes the stream when receiving fails", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		first, second := newFakeStream(), newFakeStream()
+		first.recvErr = unavailable
+		bp := newTestProxy(t, &fakeClient{streams: []*fakeStream{first, second}})
+
+		g.Expect(bp.Send(event(1))).To(Succeed())
+		second.acks <- ack(1)
+		g.Expect(bp.Recv()).To(Equal(ack(1)))
+		g.Expect(second.sent).To(Equal([]int64{1}))
+	})
+
+	t.Run("gives up after the retries", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		first := newFakeStream()
+		first.sendErr, first.failAt = unavailable, 0
+		client := &fakeClient{streams: []*fakeStream{first}, err: unavailable}
+		bp := newTestProxy(t, client)
+
+		g.Expect(bp.Send(event(1))).To(MatchError(ContainSubstring("failed to resume build event stream")))
+		g.Expect(client.calls).To(Equal(1 + maxStreamRetries))
+	})
+
+	t.Run("doesn't retry errors that aren't transient", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		first := newFakeStream()
+		invalid := status.Error(codes.InvalidArgument, "bad event")
+		first.sendErr, first.failAt = invalid, 0
+		client := &fakeClient{streams: []*fakeStream{first}}
+		bp := newTestProxy(t, client)
+
+		g.Expect(bp.Send(event(1))).To(MatchError(invalid))
+		g.Expect(client.calls).To(Equal(1))
+	})
+
+	t.Run("doesn't resume a closed stream", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		first := newFakeStream()
+		client := &fakeClient{streams: []*fakeStream{first}}
+		bp := newTestProxy(t, client)
+
+		g.Expect(bp.CloseSend()).To(Succeed())
+		g.Expect(bp.Healthy()).To(BeFalse())
+		g.Expect(bp.Send(event(1))).To(MatchError(ContainSubstring("is closed")))
+		g.Expect(client.calls).To(Equal(1))
+	})
+
+	t.Run("opens a new stream on the connection once the previous one was closed", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		first, second := newFakeStream(), newFakeStream()
+		client := &fakeClient{streams: []*fakeStream{first, second}}
+		bp := newTestProxy(t, client)
+
+		g.Expect(bp.Send(event(1))).To(Succeed())
+		g.Expect(bp.CloseSend()).To(Succeed())
+
+		g.Expect(bp.PublishBuildToolEventStream(context.Background())).To(Succeed())
+		g.Expect(bp.Healthy()).To(BeTrue())
+		g.Expect(bp.Send(event(1))).To(Succeed())
+		g.Expect(first.sent).To(Equal([]int64{1}))
+		g.Expect(second.sent).To(Equal([]int64{1}))
+		g.Expect(bp.unacked).To(HaveLen(1))
+	})
+	t.Run("journals the events while the backend is unreachable and resumes the stream once it recovers", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		first, second := newFakeStream(), newFakeStream()
+		bp := newTestProxy(t, &fakeClient{streams: []*fakeStream{first, second}})
+		bp.recovery = Recovery{ProbeInterval: 10 * time.Millisecond, Timeout: time.Minute}
+
+		g.Expect(bp.Send(event(1))).To(Succeed())
+		g.Expect(bp.Send(event(2))).To(Succeed())
+		first.acks <- ack(1)
+		g.Expect(bp.Recv()).To(Equal(ack(1)))
+
+		bp.MarkUnhealthy()
+		g.Expect(bp.Healthy()).To(BeTrue())
+		g.Expect(bp.Send(event(3))).To(Succeed())
+		g.Expect(first.sent).To(Equal([]int64{1, 2}))
+
+		// Recv waits for the stream to be resumed.
+		second.acks <- ack(3)
+		g.Expect(bp.Recv()).To(Equal(ack(3)))
+		g.Expect(second.sent).To(Equal([]int64{2, 3}))
+
+		g.Expect(bp.Send(event(4))).To(Succeed())
+		g.Expect(second.sent).To(Equal([]int64{2, 3, 4}))
+		g.Expect(bp.unacked).To(HaveLen(1))
+		g.Expect(bp.Healthy()).To(BeTrue())
+		g.Expect(bp.Reconnects()).To(Equal(1))
+	})
+
+	t.Run("takes the backend out of rotation when it doesn't recover in time", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		first := newFakeStream()
+		bp := newTestProxy(t, &fakeClient{streams: []*fakeStream{first}, err: unavailable})
+		bp.recovery = Recovery{ProbeInterval: time.Millisecond, Timeout: 20 * time.Millisecond}
+
+		g.Expect(bp.Send(event(1))).To(Succeed())
+		bp.MarkUnhealthy()
+		g.Expect(bp.Healthy()).To(BeTrue())
+
+		g.Eventually(bp.Healthy).Should(BeFalse())
+		_, err := bp.Recv()
+		g.Expect(err).To(HaveOccurred())
+	})
+
+	t.Run("reports the events as not resumed when the stream is closed during the outage", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		first := newFakeStream()
+		bp := newTestProxy(t, &fakeClient{streams: []*fakeStream{first}, err: unavailable})
+		bp.recovery = Recovery{ProbeInterval: time.Hour, Timeout: time.Hour}
+
+		g.Expect(bp.Send(event(1))).To(Succeed())
+		bp.MarkUnhealthy()
+
+		g.Expect(bp.CloseSend()).To(MatchError(ErrNotResumed))
+		g.Expect(bp.Healthy()).To(BeFalse())
+	})
+}
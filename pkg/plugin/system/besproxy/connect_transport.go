@@ -0,0 +1,188 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package besproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"connectrpc.com/connect"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	buildv1 "google.golang.org/genproto/googleapis/devtools/build/v1"
+)
+
+const (
+	publishLifecycleEventProcedure       = "/google.devtools.build.v1.PublishBuildEvent/PublishLifecycleEvent"
+	publishBuildToolEventStreamProcedure = "/google.devtools.build.v1.PublishBuildEvent/PublishBuildToolEventStream"
+)
+
+// connectClient reaches a backend over HTTP with connect-go. Its streams are
+// full duplex over HTTP/2; over HTTP/1.1, a proxy may hold the
+// acknowledgements back until the stream is closed.
+type connectClient struct {
+	headers   map[string]string
+	lifecycle *connect.Client[buildv1.PublishLifecycleEventRequest, emptypb.Empty]
+	stream    *connect.Client[buildv1.PublishBuildToolEventStreamRequest, buildv1.PublishBuildToolEventStreamResponse]
+}
+
+// dialConnect returns the DialFunc of a transport of connect-go.
+func dialConnect(t Transport) DialFunc {
+	return func(backend Backend) (buildv1.PublishBuildEventClient, error) {
+		u, err := url.Parse(backend.URL)
+		if err != nil {
+			return nil, err
+		}
+		httpTransport := http.DefaultTransport.(*http.Transport).Clone()
+		if u.Scheme == "https" {
+			config, err := clientTLSConfig(backend.TLS)
+			if err != nil {
+				return nil, fmt.Errorf("failed to initialize the TLS config of %s: %w", backend.URL, err)
+			}
+			httpTransport.TLSClientConfig = config
+		}
+		httpTransport.WriteBufferSize = backend.FlowControl.writeBufferSize()
+		if backend.FlowControl.ReadBufferSize > 0 {
+			httpTransport.ReadBufferSize = backend.FlowControl.ReadBufferSize
+		}
+		httpClient := &http.Client{Transport: httpTransport}
+
+		var opts []connect.ClientOption
+		if t == TransportGRPCWeb {
+			opts = append(opts, connect.WithGRPCWeb())
+		}
+		base := strings.TrimSuffix(backend.URL, "/")
+		return &connectClient{
+			headers:   backend.Headers,
+			lifecycle: connect.NewClient[buildv1.PublishLifecycleEventRequest, emptypb.Empty](httpClient, base+publishLifecycleEventProcedure, opts...),
+			stream:    connect.NewClient[buildv1.PublishBuildToolEventStreamRequest, buildv1.PublishBuildToolEventStreamResponse](httpClient, base+publishBuildToolEventStreamProcedure, opts...),
+		}, nil
+	}
+}
+
+func (c *connectClient) setHeaders(h http.Header) {
+	for key, value := range c.headers {
+		h.Set(key, value)
+	}
+}
+
+func (c *connectClient) PublishLifecycleEvent(ctx context.Context, req *buildv1.PublishLifecycleEventRequest, _ ...grpc.CallOption) (*emptypb.Empty, error) {
+	r := connect.NewRequest(req)
+	c.setHeaders(r.Header())
+	res, err := c.lifecycle.CallUnary(ctx, r)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return res.Msg, nil
+}
+
+func (c *connectClient) PublishBuildToolEventStream(ctx context.Context, _ ...grpc.CallOption) (buildv1.PublishBuildEvent_PublishBuildToolEventStreamClient, error) {
+	s := c.stream.CallBidiStream(ctx)
+	c.setHeaders(s.RequestHeader())
+	return &connectStream{ctx: ctx, stream: s}, nil
+}
+
+// connectStream is a build event stream of connect-go, with the errors of
+// grpc-go, so that it is resumed like the streams of grpc-go.
+type connectStream struct {
+	ctx    context.Context
+	stream *connect.BidiStreamForClient[buildv1.PublishBuildToolEventStreamRequest, buildv1.PublishBuildToolEventStreamResponse]
+}
+
+func (s *connectStream) Send(req *buildv1.PublishBuildToolEventStreamRequest) error {
+	return grpcError(s.stream.Send(req))
+}
+
+func (s *connectStream) Recv() (*buildv1.PublishBuildToolEventStreamResponse, error) {
+	res, err := s.stream.Receive()
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return res, nil
+}
+
+func (s *connectStream) CloseSend() error {
+	return grpcError(s.stream.CloseRequest())
+}
+
+func (s *connectStream) Header() (metadata.MD, error) {
+	return headerMetadata(s.stream.ResponseHeader()), nil
+}
+
+func (s *connectStream) Trailer() metadata.MD {
+	return headerMetadata(s.stream.ResponseTrailer())
+}
+
+func (s *connectStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *connectStream) SendMsg(m any) error {
+	req, ok := m.(*buildv1.PublishBuildToolEventStreamRequest)
+	if !ok {
+		return fmt.Errorf("unexpected message %T", m)
+	}
+	return s.Send(req)
+}
+
+func (s *connectStream) RecvMsg(m any) error {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return fmt.Errorf("unexpected message %T", m)
+	}
+	res, err := s.Recv()
+	if err != nil {
+		return err
+	}
+	proto.Merge(msg, res)
+	return nil
+}
+
+// grpcError returns the error of grpc-go for an error of connect-go: io.EOF
+// once the stream ended, and a status with the same code otherwise, as the
+// codes of connect-go are those of gRPC.
+func grpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, io.EOF) {
+		return io.EOF
+	}
+	var connectErr *connect.Error
+	if errors.As(err, &connectErr) {
+		return status.Error(codes.Code(connectErr.Code()), connectErr.Message())
+	}
+	return err
+}
+
+func headerMetadata(h http.Header) metadata.MD {
+	md := metadata.MD{}
+	for key, values := range h {
+		md.Append(key, values...)
+	}
+	return md
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package besproxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"connectrpc.com/connect"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	buildv1 "google.golang.org/genproto/googleapis/devtools/build/v1"
+)
+
+// connectBES is a BES backend served with connect-go, which speaks the
+// Connect, gRPC and gRPC-web protocols.
+type connectBES struct {
+	mu        sync.Mutex
+	apiKeys   []string
+	lifecycle int
+	events    []int64
+}
+
+func startConnectBES(t *testing.T) (*connectBES, *httptest.Server) {
+	bes := &connectBES{}
+	mux := http.NewServeMux()
+	mux.Handle(publishLifecycleEventProcedure, connect.NewUnaryHandler(publishLifecycleEventProcedure,
+		func(_ context.Context, req *connect.Request[buildv1.PublishLifecycleEventRequest]) (*connect.Response[emptypb.Empty], error) {
+			bes.mu.Lock()
+			defer bes.mu.Unlock()
+			bes.apiKeys = append(bes.apiKeys, req.Header().Get("x-api-key"))
+			bes.lifecycle++
+			return connect.NewResponse(&emptypb.Empty{}), nil
+		}))
+	mux.Handle(publishBuildToolEventStreamProcedure, connect.NewBidiStreamHandler(publishBuildToolEventStreamProcedure,
+		func(_ context.Context, stream *connect.BidiStream[buildv1.PublishBuildToolEventStreamRequest, buildv1.PublishBuildToolEventStreamResponse]) error {
+			bes.mu.Lock()
+			bes.apiKeys = append(bes.apiKeys, stream.RequestHeader().Get("x-api-key"))
+			bes.mu.Unlock()
+			for {
+				req, err := stream.Receive()
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				seq := req.GetOrderedBuildEvent().GetSequenceNumber()
+				if seq < 0 {
+					return connect.NewError(connect.CodeUnavailable, errors.New("backend restarting"))
+				}
+				bes.mu.Lock()
+				bes.events = append(bes.events, seq)
+				bes.mu.Unlock()
+				if err := stream.Send(&buildv1.PublishBuildToolEventStreamResponse{SequenceNumber: seq}); err != nil {
+					return err
+				}
+			}
+		}))
+	srv := httptest.NewUnstartedServer(mux)
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return bes, srv
+}
+
+func TestConnectTransport(t *testing.T) {
+	for _, transport := range []Transport{TransportConnect, TransportGRPCWeb} {
+		t.Run("streams the build events with "+string(transport), func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			bes, srv := startConnectBES(t)
+
+			bp := NewBesProxyForBackend(Backend{
+				URL:       srv.URL,
+				Transport: transport,
+				Headers:   map[string]string{"x-api-key": "secret"},
+				TLS:       TLSConfig{Insecure: true},
+			})
+			g.Expect(bp.Connect()).To(Succeed())
+			_, err := bp.PublishLifecycleEvent(context.Background(), &buildv1.PublishLifecycleEventRequest{})
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(bp.PublishBuildToolEventStream(context.Background())).To(Succeed())
+
+			g.Expect(bp.Send(event(1))).To(Succeed())
+			g.Expect(bp.Recv()).To(HaveField("SequenceNumber", int64(1)))
+			g.Expect(bp.Send(event(2))).To(Succeed())
+			g.Expect(bp.CloseSend()).To(Succeed())
+			g.Expect(bp.Recv()).To(HaveField("SequenceNumber", int64(2)))
+			_, err = bp.Recv()
+			g.Expect(err).To(Equal(io.EOF))
+			g.Expect(bp.unacked).To(BeEmpty())
+
+			bes.mu.Lock()
+			defer bes.mu.Unlock()
+			g.Expect(bes.lifecycle).To(Equal(1))
+			g.Expect(bes.events).To(Equal([]int64{1, 2}))
+			g.Expect(bes.apiKeys).To(Equal([]string{"secret", "secret"}))
+		})
+	}
+
+	t.Run("reports the errors of the backend as gRPC statuses", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		_, srv := startConnectBES(t)
+
+		client, err := dialTransport(Backend{URL: srv.URL, Transport: TransportConnect, TLS: TLSConfig{Insecure: true}})
+		g.Expect(err).ToNot(HaveOccurred())
+		stream, err := client.PublishBuildToolEventStream(context.Background())
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(stream.Send(event(-1))).To(Succeed())
+		_, err = stream.Recv()
+		g.Expect(status.Code(err)).To(Equal(codes.Unavailable))
+		g.Expect(isTransient(err)).To(BeTrue())
+	})
+}
+
+func TestRegisterTransport(t *testing.T) {
+	g := NewGomegaWithT(t)
+	t.Cleanup(func() { delete(transports, "fake") })
+
+	client := &fakeClient{}
+	RegisterTransport("fake", []string{"fake"}, func(Backend) (buildv1.PublishBuildEventClient, error) {
+		return client, nil
+	})
+	g.Expect(Transports()).To(ContainElement(Transport("fake")))
+
+	backends, err := UnmarshalBackendConfig([]any{map[string]any{"url": "fake://bes", "transport": "fake"}})
+	g.Expect(err).ToNot(HaveOccurred())
+	bp := NewBesProxyForBackend(backends[0])
+	g.Expect(bp.Connect()).To(Succeed())
+	g.Expect(bp.client).To(BeIdenticalTo(client))
+}
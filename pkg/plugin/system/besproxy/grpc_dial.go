@@ -18,10 +18,12 @@ package besproxy
 
 import (
 	"context"
+	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"math"
 	"net/url"
+	"os"
 	"time"
 
 	"google.golang.org/grpc"
@@ -42,7 +44,7 @@ func (c *grpcHeaders) RequireTransportSecurity() bool {
 	return false
 }
 
-func grpcDial(host string, headers map[string]string) (*grpc.ClientConn, error) {
+func grpcDial(host string, headers map[string]string, tlsConfig TLSConfig, flowControl FlowControl) (*grpc.ClientConn, error) {
 	opts := []grpc.DialOption{
 		grpc.WithPerRPCCredentials(&grpcHeaders{headers: headers}),
 		grpc.WithDefaultCallOptions(
@@ -60,19 +62,15 @@ func grpcDial(host string, headers map[string]string) (*grpc.ClientConn, error)
 			PermitWithoutStream: true,
 		}),
 	}
+	opts = append(opts, flowControlOptions(flowControl)...)
 	var transportCreds credentials.TransportCredentials
 	if p, err := url.Parse(host); err == nil {
 		if p.Scheme == "grpcs" {
-			// TODO(f0rmiga): allow for custom cert to be injected via config. Big enterprises usually have
-			// their own CA certs and they often will want to consume separately from the system certs, aka
-			// well-known CA certs (the most widely used list comes from Mozilla, and curl compiles it here:
-			// https://curl.se/ca/cacert.pem).
-			pool, err := x509.SystemCertPool()
+			config, err := clientTLSConfig(tlsConfig)
 			if err != nil {
 				return nil, fmt.Errorf("failed to initialize GOOGLE gRPC dial options: %w", err)
 			}
-			// TODO(f0rmiga): allow serverNameOverride from configuration file.
-			transportCreds = credentials.NewClientTLSFromCert(pool, "")
+			transportCreds = credentials.NewTLS(config)
 			host = p.Host
 			if p.Port() == "" {
 				host += ":443"
@@ -87,3 +85,66 @@ func grpcDial(host string, headers map[string]string) (*grpc.ClientConn, error)
 	opts = append(opts, grpc.WithTransportCredentials(transportCreds))
 	return grpc.Dial(host, opts...)
 }
+
+// flowControlOptions returns the dial options of the flow control of a
+// backend. The windows are only set when configured, as setting them turns
+// off the estimation of the bandwidth-delay product that grows them on
+// high-latency links.
+func flowControlOptions(f FlowControl) []grpc.DialOption {
+	opts := []grpc.DialOption{grpc.WithWriteBufferSize(f.writeBufferSize())}
+	if f.WindowSize > 0 {
+		opts = append(opts, grpc.WithInitialWindowSize(f.WindowSize))
+	}
+	if f.ConnWindowSize > 0 {
+		opts = append(opts, grpc.WithInitialConnWindowSize(f.ConnWindowSize))
+	}
+	if f.ReadBufferSize > 0 {
+		opts = append(opts, grpc.WithReadBufferSize(f.ReadBufferSize))
+	}
+	return opts
+}
+
+// clientTLSConfig builds the TLS config of a grpcs:// backend. Big enterprises
+// usually have their own CA certs, which are trusted in addition to the system
+// ones.
+func clientTLSConfig(c TLSConfig) (*tls.Config, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		return nil, err
+	}
+	if c.CACert != "" {
+		path, err := resolveWorkspacePath(c.CACert)
+		if err != nil {
+			return nil, err
+		}
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA cert: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA cert %s", path)
+		}
+	}
+
+	config := &tls.Config{
+		RootCAs:            pool,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.Insecure,
+	}
+	if c.ClientCert != "" {
+		certPath, err := resolveWorkspacePath(c.ClientCert)
+		if err != nil {
+			return nil, err
+		}
+		keyPath, err := resolveWorkspacePath(c.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
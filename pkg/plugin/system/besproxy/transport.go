@@ -0,0 +1,133 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package besproxy
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	buildv1 "google.golang.org/genproto/googleapis/devtools/build/v1"
+)
+
+// Transport is how the build events reach a backend.
+type Transport string
+
+const (
+	// TransportGRPC reaches grpc://, grpcs:// and unix:// backends with
+	// grpc-go over HTTP/2. It is the default.
+	TransportGRPC Transport = "grpc"
+	// TransportConnect reaches http:// and https:// backends with the
+	// Connect protocol of connect-go, over HTTP/2 when the backend supports
+	// it and HTTP/1.1 otherwise.
+	TransportConnect Transport = "connect"
+	// TransportGRPCWeb reaches http:// and https:// backends, or the
+	// gRPC-web bridges in front of them, with the gRPC-web protocol, which
+	// goes through proxies that only allow HTTP/1.1.
+	TransportGRPCWeb Transport = "grpc-web"
+)
+
+// DialFunc connects to a backend with a transport. The client is only
+// connected once its calls are made.
+type DialFunc func(backend Backend) (buildv1.PublishBuildEventClient, error)
+
+// transport is a registered Transport, with the schemes of the URLs of the
+// backends it reaches.
+type transport struct {
+	schemes []string
+	dial    DialFunc
+}
+
+var transports = map[Transport]transport{
+	TransportGRPC:    {schemes: []string{"grpc", "grpcs", "unix"}, dial: dialGRPC},
+	TransportConnect: {schemes: []string{"http", "https"}, dial: dialConnect(TransportConnect)},
+	TransportGRPCWeb: {schemes: []string{"http", "https"}, dial: dialConnect(TransportGRPCWeb)},
+}
+
+// RegisterTransport makes a transport available to the backends of the Aspect
+// CLI config, for the URLs of the given schemes. It must be called before the
+// config is read, e.g. from an init function, and replaces a transport of the
+// same name.
+func RegisterTransport(name Transport, schemes []string, dial DialFunc) {
+	transports[name] = transport{schemes: schemes, dial: dial}
+}
+
+// Transports returns the names of the registered transports, sorted.
+func Transports() []Transport {
+	names := make([]Transport, 0, len(transports))
+	for name := range transports {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+// orDefault returns the transport, or TransportGRPC when it is empty.
+func (t Transport) orDefault() Transport {
+	if t == "" {
+		return TransportGRPC
+	}
+	return t
+}
+
+// checkScheme returns an error unless the transport is registered and reaches
+// URLs of the scheme.
+func (t Transport) checkScheme(scheme string) error {
+	tr, ok := transports[t.orDefault()]
+	if !ok {
+		return fmt.Errorf("unknown transport %q, expected one of %v", t, Transports())
+	}
+	if !slices.Contains(tr.schemes, scheme) {
+		schemes := strings.Join(tr.schemes, ", ")
+		if i := strings.LastIndex(schemes, ", "); i >= 0 {
+			schemes = schemes[:i] + " or " + schemes[i+2:]
+		}
+		if t == "" {
+			return fmt.Errorf("the scheme must be %s", schemes)
+		}
+		return fmt.Errorf("the scheme must be %s with the %s transport", schemes, t)
+	}
+	return nil
+}
+
+// secureScheme returns the scheme of the URLs of the backends the transport
+// reaches over TLS.
+func (t Transport) secureScheme() string {
+	if t.orDefault() == TransportGRPC {
+		return "grpcs"
+	}
+	return "https"
+}
+
+// dialTransport connects to a backend with its transport.
+func dialTransport(backend Backend) (buildv1.PublishBuildEventClient, error) {
+	tr, ok := transports[backend.Transport.orDefault()]
+	if !ok {
+		return nil, fmt.Errorf("unknown transport %q", backend.Transport)
+	}
+	return tr.dial(backend)
+}
+
+// dialGRPC connects to a backend with grpc-go.
+func dialGRPC(backend Backend) (buildv1.PublishBuildEventClient, error) {
+	c, err := grpcDial(backend.URL, backend.Headers, backend.TLS, backend.FlowControl)
+	if err != nil {
+		return nil, err
+	}
+	return buildv1.NewPublishBuildEventClient(c), nil
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package system
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	rootFlags "github.com/aspect-build/aspect-cli-legacy/pkg/aspect/root/flags"
+)
+
+// The keys of the Aspect CLI config that declare the keywords the BES backends
+// are notified with, as with --bes_keywords, and the key/values of the
+// BuildMetadata build event, as with --build_metadata. Their values are
+// templates: environment variables such as ${USER} are expanded, as well as
+// the variables {branch}, {commit}, {user} and {ci_job_url}, which are read
+// from the CI system or else from git.
+const (
+	BESKeywordsKey   = "bes_keywords"
+	BuildMetadataKey = "build_metadata"
+)
+
+// buildMetadata are the keywords and metadata of the Aspect CLI config with
+// their templates expanded.
+type buildMetadata struct {
+	Keywords []string
+	Metadata map[string]string
+}
+
+// loadBuildMetadata returns the keywords and metadata of the Aspect CLI
+// config. The keywords and metadata whose templates expand to nothing are left
+// out.
+func loadBuildMetadata(vars *metadataVars) (buildMetadata, error) {
+	var m buildMetadata
+	if viper.IsSet(BESKeywordsKey) {
+		keywords, ok := viper.Get(BESKeywordsKey).([]any)
+		if !ok {
+			return buildMetadata{}, fmt.Errorf("expected %s to be a list", BESKeywordsKey)
+		}
+		for _, keyword := range keywords {
+			if keyword := vars.expand(fmt.Sprint(keyword)); keyword != "" {
+				m.Keywords = append(m.Keywords, keyword)
+			}
+		}
+	}
+	if viper.IsSet(BuildMetadataKey) {
+		if _, ok := viper.Get(BuildMetadataKey).(map[string]any); !ok {
+			return buildMetadata{}, fmt.Errorf("expected %s to be a map", BuildMetadataKey)
+		}
+		m.Metadata = map[string]string{}
+		// Bazel reports the keys of the build metadata as they are given,
+		// e.g. in upper case, while viper lower-cases them, so the keys are
+		// upper-cased as is the convention of bazel.
+		for key, value := range viper.GetStringMapString(BuildMetadataKey) {
+			if strings.Contains(key, "=") {
+				return buildMetadata{}, fmt.Errorf("invalid key of %s %q: keys must not contain '='", BuildMetadataKey, key)
+			}
+			if value := vars.expand(value); value != "" {
+				m.Metadata[strings.ToUpper(key)] = value
+			}
+		}
+	}
+	return m, nil
+}
+
+// args returns the flags that pass the keywords and metadata to bazel.
+func (m buildMetadata) args() []string {
+	var args []string
+	for _, keyword := range m.Keywords {
+		args = append(args, "--bes_keywords="+keyword)
+	}
+	keys := make([]string, 0, len(m.Metadata))
+	for key := range m.Metadata {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		args = append(args, "--build_metadata="+key+"="+m.Metadata[key])
+	}
+	return args
+}
+
+// addTo adds the flags of the keywords and metadata to the bazel command
+// line args.
+func (m buildMetadata) addTo(args []string) []string {
+	flags := m.args()
+	if len(flags) == 0 {
+		return args
+	}
+	return rootFlags.AddFlagToCommand(args, flags...)
+}
+
+// metadataVars resolves the variables of the templates of the build metadata,
+// each of them once.
+type metadataVars struct {
+	getenv func(string) string
+	// git runs git with the given arguments and returns its trimmed output.
+	git    func(args ...string) (string, error)
+	values map[string]string
+}
+
+func newMetadataVars() *metadataVars {
+	return &metadataVars{
+		getenv: os.Getenv,
+		git: func(args ...string) (string, error) {
+			out, err := exec.Command("git", args...).Output()
+			return strings.TrimSpace(string(out)), err
+		},
+	}
+}
+
+var metadataVarPattern = regexp.MustCompile(`\{(branch|commit|user|ci_job_url)\}`)
+
+// expand expands the environment variables and the variables of a template.
+func (v *metadataVars) expand(template string) string {
+	expanded := os.Expand(template, v.getenv)
+	return metadataVarPattern.ReplaceAllStringFunc(expanded, func(match string) string {
+		return v.get(match[1 : len(match)-1])
+	})
+}
+
+// get returns the value of a variable, or an empty string when it can't be
+// determined.
+func (v *metadataVars) get(name string) string {
+	if value, ok := v.values[name]; ok {
+		return value
+	}
+	var value string
+	switch name {
+	case "branch":
+		// GITHUB_HEAD_REF is the source branch of a pull request.
+		value = v.firstEnv("GITHUB_HEAD_REF", "GITHUB_REF_NAME", "BUILDKITE_BRANCH", "CI_COMMIT_REF_NAME", "CIRCLE_BRANCH")
+		if value == "" {
+			value = v.gitOutput("rev-parse", "--abbrev-ref", "HEAD")
+			if value == "HEAD" {
+				// The HEAD is detached.
+				value = ""
+			}
+		}
+	case "commit":
+		value = v.firstEnv("GITHUB_SHA", "BUILDKITE_COMMIT", "CI_COMMIT_SHA", "CIRCLE_SHA1")
+		if value == "" {
+			value = v.gitOutput("rev-parse", "HEAD")
+		}
+	case "user":
+		value = v.firstEnv("GITHUB_ACTOR", "GITLAB_USER_LOGIN", "CIRCLE_USERNAME", "USER")
+		if value == "" {
+			if u, err := user.Current(); err == nil {
+				value = u.Username
+			}
+		}
+	case "ci_job_url":
+		value = v.ciJobURL()
+	}
+	if v.values == nil {
+		v.values = map[string]string{}
+	}
+	v.values[name] = value
+	return value
+}
+
+// ciJobURL returns the link to the job of the CI system running the command.
+func (v *metadataVars) ciJobURL() string {
+	if server, repo, run := v.getenv("GITHUB_SERVER_URL"), v.getenv("GITHUB_REPOSITORY"), v.getenv("GITHUB_RUN_ID"); server != "" && repo != "" && run != "" {
+		return server + "/" + repo + "/actions/runs/" + run
+	}
+	if build := v.getenv("BUILDKITE_BUILD_URL"); build != "" {
+		if job := v.getenv("BUILDKITE_JOB_ID"); job != "" {
+			return build + "#" + job
+		}
+		return build
+	}
+	return v.firstEnv("CI_JOB_URL", "CIRCLE_BUILD_URL")
+}
+
+func (v *metadataVars) firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := v.getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func (v *metadataVars) gitOutput(args ...string) string {
+	out, err := v.git(args...)
+	if err != nil {
+		return ""
+	}
+	return out
+}
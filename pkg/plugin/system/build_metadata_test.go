@@ -0,0 +1,123 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package system
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+)
+
+func fakeMetadataVars(env map[string]string, git map[string]string) *metadataVars {
+	return &metadataVars{
+		getenv: func(name string) string { return env[name] },
+		git: func(args ...string) (string, error) {
+			out, ok := git[strings.Join(args, " ")]
+			if !ok {
+				return "", errors.New("not a git repository")
+			}
+			return out, nil
+		},
+	}
+}
+
+func TestLoadBuildMetadata(t *testing.T) {
+	t.Run("is empty by default", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+
+		m, err := loadBuildMetadata(fakeMetadataVars(nil, nil))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(m.args()).To(BeEmpty())
+		g.Expect(m.addTo([]string{"//..."})).To(Equal([]string{"//..."}))
+	})
+
+	t.Run("expands the variables of the CI system", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+		viper.Set(BESKeywordsKey, []any{"team=${TEAM}", "branch={branch}", "${UNSET}"})
+		viper.Set(BuildMetadataKey, map[string]any{
+			"commit_sha": "{commit}",
+			"user":       "{user}",
+			"ci_job_url": "{ci_job_url}",
+			"role":       "CI",
+		})
+
+		m, err := loadBuildMetadata(fakeMetadataVars(map[string]string{
+			"TEAM":              "infra",
+			"GITHUB_REF_NAME":   "main",
+			"GITHUB_SHA":        "abc123",
+			"GITHUB_ACTOR":      "octocat",
+			"GITHUB_SERVER_URL": "https://github.com",
+			"GITHUB_REPOSITORY": "aspect-build/aspect-cli",
+			"GITHUB_RUN_ID":     "42",
+		}, nil))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(m.args()).To(Equal([]string{
+			"--bes_keywords=team=infra",
+			"--bes_keywords=branch=main",
+			"--build_metadata=CI_JOB_URL=https://github.com/aspect-build/aspect-cli/actions/runs/42",
+			"--build_metadata=COMMIT_SHA=abc123",
+			"--build_metadata=ROLE=CI",
+			"--build_metadata=USER=octocat",
+		}))
+		g.Expect(m.addTo([]string{"//app", "--", "arg"})).To(Equal([]string{"//app", "--bes_keywords=team=infra", "--bes_keywords=branch=main",
+			"--build_metadata=CI_JOB_URL=https://github.com/aspect-build/aspect-cli/actions/runs/42",
+			"--build_metadata=COMMIT_SHA=abc123", "--build_metadata=ROLE=CI", "--build_metadata=USER=octocat", "--", "arg"}))
+	})
+
+	t.Run("falls back to git and leaves out what can't be determined", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+		viper.Set(BuildMetadataKey, map[string]any{
+			"branch_name": "{branch}",
+			"commit_sha":  "{commit}",
+			"ci_job_url":  "{ci_job_url}",
+		})
+
+		m, err := loadBuildMetadata(fakeMetadataVars(nil, map[string]string{
+			"rev-parse --abbrev-ref HEAD": "feature",
+			"rev-parse HEAD":              "abc123",
+		}))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(m.Metadata).To(Equal(map[string]string{"BRANCH_NAME": "feature", "COMMIT_SHA": "abc123"}))
+
+		// The branch of a detached HEAD is unknown.
+		m, err = loadBuildMetadata(fakeMetadataVars(nil, map[string]string{
+			"rev-parse --abbrev-ref HEAD": "HEAD",
+		}))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(m.Metadata).To(BeEmpty())
+	})
+
+	t.Run("rejects invalid config", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+
+		viper.Set(BESKeywordsKey, "ci")
+		_, err := loadBuildMetadata(fakeMetadataVars(nil, nil))
+		g.Expect(err).To(MatchError("expected bes_keywords to be a list"))
+
+		viper.Reset()
+		viper.Set(BuildMetadataKey, []any{"ROLE=CI"})
+		_, err = loadBuildMetadata(fakeMetadataVars(nil, nil))
+		g.Expect(err).To(MatchError("expected build_metadata to be a map"))
+	})
+}
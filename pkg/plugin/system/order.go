@@ -0,0 +1,122 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package system
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/types"
+)
+
+// orderPlugins sorts the plugins so that every plugin comes after the plugins
+// it depends on. Plugins that don't depend on each other are sorted by
+// descending priority, and keep the order they are configured in when their
+// priority is the same.
+func orderPlugins(plugins []types.PluginConfig) ([]types.PluginConfig, error) {
+	// A plugin is ordered as early as the plugins that depend on it, so that
+	// its dependencies don't hold back a plugin with a high priority.
+	priority := make(map[string]int, len(plugins))
+	for _, p := range plugins {
+		priority[p.Name] = p.Priority
+	}
+	for range plugins {
+		for _, p := range plugins {
+			for _, dep := range p.DependsOn {
+				priority[dep] = max(priority[dep], priority[p.Name])
+			}
+		}
+	}
+	plugins = slices.Clone(plugins)
+	slices.SortStableFunc(plugins, func(a, b types.PluginConfig) int {
+		return cmp.Compare(priority[b.Name], priority[a.Name])
+	})
+
+	configured := make(map[string]struct{}, len(plugins))
+	for _, p := range plugins {
+		configured[p.Name] = struct{}{}
+	}
+
+	dependents := make(map[string][]int, len(plugins))
+	pending := make([]int, len(plugins))
+	for i, p := range plugins {
+		for _, dep := range p.DependsOn {
+			if _, ok := configured[dep]; !ok {
+				return nil, fmt.Errorf("plugin %q depends on %q, which is not configured", p.Name, dep)
+			}
+			dependents[dep] = append(dependents[dep], i)
+			pending[i]++
+		}
+	}
+
+	ordered := make([]types.PluginConfig, 0, len(plugins))
+	done := make([]bool, len(plugins))
+	for len(ordered) < len(plugins) {
+		next := -1
+		for i := range plugins {
+			if !done[i] && pending[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			var cycle []string
+			for i, p := range plugins {
+				if !done[i] {
+					cycle = append(cycle, p.Name)
+				}
+			}
+			return nil, fmt.Errorf("plugins %s depend on each other in a cycle", strings.Join(cycle, ", "))
+		}
+		done[next] = true
+		ordered = append(ordered, plugins[next])
+		for _, i := range dependents[plugins[next].Name] {
+			pending[i]--
+		}
+	}
+
+	return ordered, nil
+}
+
+// scopePlugins returns the plugins to launch for the given command, in the
+// order of the given plugins, which must be ordered by orderPlugins: those not
+// scoped to any command, those scoped to the command, and the plugins they
+// depend on.
+func scopePlugins(plugins []types.PluginConfig, command string) []types.PluginConfig {
+	needed := make(map[string]bool, len(plugins))
+	for i := len(plugins) - 1; i >= 0; i-- {
+		p := plugins[i]
+		if len(p.Commands) == 0 || slices.Contains(p.Commands, command) {
+			needed[p.Name] = true
+		}
+		if needed[p.Name] {
+			for _, dep := range p.DependsOn {
+				needed[dep] = true
+			}
+		}
+	}
+
+	scoped := make([]types.PluginConfig, 0, len(plugins))
+	for _, p := range plugins {
+		if needed[p.Name] {
+			scoped = append(scoped, p)
+		}
+	}
+	return scoped
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package system
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/types"
+)
+
+func pluginNames(plugins []types.PluginConfig) []string {
+	names := make([]string, 0, len(plugins))
+	for _, p := range plugins {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func TestOrderPlugins(t *testing.T) {
+	t.Run("keeps the configured order of independent plugins", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		ordered, err := orderPlugins([]types.PluginConfig{
+			{Name: "a"},
+			{Name: "b"},
+			{Name: "c"},
+		})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(pluginNames(ordered)).To(Equal([]string{"a", "b", "c"}))
+	})
+
+	t.Run("orders plugins after their dependencies", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		ordered, err := orderPlugins([]types.PluginConfig{
+			{Name: "upload", DependsOn: []string{"auth"}},
+			{Name: "report", DependsOn: []string{"upload", "auth"}},
+			{Name: "lint"},
+			{Name: "auth"},
+		})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(pluginNames(ordered)).To(Equal([]string{"lint", "auth", "upload", "report"}))
+	})
+
+	t.Run("orders plugins by descending priority", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		ordered, err := orderPlugins([]types.PluginConfig{
+			{Name: "a"},
+			{Name: "b", Priority: 10},
+			{Name: "c", Priority: -1},
+			{Name: "d", Priority: 10},
+		})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(pluginNames(ordered)).To(Equal([]string{"b", "d", "a", "c"}))
+	})
+
+	t.Run("orders the dependencies of a plugin with its priority", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		ordered, err := orderPlugins([]types.PluginConfig{
+			{Name: "lint", Priority: 5},
+			{Name: "upload", Priority: 10, DependsOn: []string{"auth"}},
+			{Name: "auth"},
+		})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(pluginNames(ordered)).To(Equal([]string{"auth", "upload", "lint"}))
+	})
+
+	t.Run("fails on a dependency that is not configured", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		_, err := orderPlugins([]types.PluginConfig{
+			{Name: "upload", DependsOn: []string{"auth"}},
+		})
+		g.Expect(err).To(MatchError(`plugin "upload" depends on "auth", which is not configured`))
+	})
+
+	t.Run("fails on a dependency cycle", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		_, err := orderPlugins([]types.PluginConfig{
+			{Name: "a", DependsOn: []string{"b"}},
+			{Name: "b", DependsOn: []string{"a"}},
+			{Name: "c"},
+		})
+		g.Expect(err).To(MatchError("plugins a, b depend on each other in a cycle"))
+	})
+}
+
+func TestScopePlugins(t *testing.T) {
+	t.Run("launches plugins scoped to the command and unscoped plugins", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		plugins := []types.PluginConfig{
+			{Name: "lint-report", Commands: []string{"build", "test"}},
+			{Name: "notify"},
+			{Name: "coverage", Commands: []string{"coverage"}},
+		}
+		g.Expect(pluginNames(scopePlugins(plugins, "test"))).To(Equal([]string{"lint-report", "notify"}))
+		g.Expect(pluginNames(scopePlugins(plugins, "query"))).To(Equal([]string{"notify"}))
+		g.Expect(pluginNames(scopePlugins(plugins, ""))).To(Equal([]string{"notify"}))
+	})
+
+	t.Run("launches the dependencies of launched plugins", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		plugins := []types.PluginConfig{
+			{Name: "auth", Commands: []string{"build"}},
+			{Name: "upload", Commands: []string{"build"}, DependsOn: []string{"auth"}},
+			{Name: "report", Commands: []string{"test"}, DependsOn: []string{"upload"}},
+		}
+		g.Expect(pluginNames(scopePlugins(plugins, "test"))).To(Equal([]string{"auth", "upload", "report"}))
+		g.Expect(pluginNames(scopePlugins(plugins, "info"))).To(BeEmpty())
+	})
+}
@@ -21,36 +21,52 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"net"
 	"os"
+	"path/filepath"
 	"reflect"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/keepalive"
 	"gopkg.in/yaml.v3"
 
+	buildeventstream "github.com/aspect-build/aspect-cli-legacy/bazel/buildeventstream"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/fetchlogs"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/flaky"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/history"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/root/config"
 	rootFlags "github.com/aspect-build/aspect-cli-legacy/pkg/aspect/root/flags"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/aspect/teststatus"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/aspecterrors"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/interceptors"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils/linefilter"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils/prompt"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/client"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/daemon"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/plugin"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/proto"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/besproxy"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/types"
 )
 
 // PluginSystem is the interface that defines all the methods for the aspect CLI
 // plugin system intended to be used by the Core.
 type PluginSystem interface {
-	Configure(streams ioutils.Streams, pluginsConfig any) error
+	// Configure launches and sets up the configured plugins that are in scope
+	// for the given command.
+	Configure(ctx context.Context, streams ioutils.Streams, command string, pluginsConfig any) error
 	TearDown()
 	RegisterCustomCommands(cmd *cobra.Command, bazelStartupArgs []string) error
 	// Create an Interceptor for plugins if necessary.
@@ -62,12 +78,18 @@ type PluginSystem interface {
 	BuildHooksInterceptor(streams ioutils.Streams) interceptors.Interceptor
 	TestHooksInterceptor(streams ioutils.Streams) interceptors.Interceptor
 	RunHooksInterceptor(streams ioutils.Streams) interceptors.Interceptor
+	// An Interceptor that lets plugins rewrite the arguments of the bazel command.
+	RewriteArgsInterceptor() interceptors.Interceptor
+	// An Interceptor that lets plugins filter the output of the bazel command.
+	OutputFilterInterceptor(streams ioutils.Streams) interceptors.Interceptor
 }
 
 type pluginSystem struct {
 	clientFactory client.Factory
-	plugins       *PluginList
-	promptRunner  prompt.PromptRunner
+	// plugins are ordered by orderPlugins, which is the order their hooks and
+	// build event callbacks are called in.
+	plugins      []*client.PluginInstance
+	promptRunner prompt.PromptRunner
 }
 
 // NewPluginSystem instantiates a default internal implementation of the
@@ -75,23 +97,53 @@ type pluginSystem struct {
 func NewPluginSystem() PluginSystem {
 	return &pluginSystem{
 		clientFactory: client.NewFactory(),
-		plugins:       &PluginList{},
 		promptRunner:  prompt.NewPromptRunner(),
 	}
 }
 
-// Configure configures the plugin system.
-func (ps *pluginSystem) Configure(streams ioutils.Streams, pluginsConfig any) error {
+// DefaultSetupTimeout is how long a plugin may take in Setup unless its config
+// sets a setup_timeout.
+const DefaultSetupTimeout = 30 * time.Second
+
+// setupKillGracePeriod is how long to wait for Setup to return after killing a
+// plugin that timed out.
+const setupKillGracePeriod = 5 * time.Second
+
+// DefaultTeardownTimeout is how long a plugin may take to exit when the plugin
+// system is torn down unless its config sets a teardown_timeout.
+const DefaultTeardownTimeout = 5 * time.Second
+
+// teardownKillGracePeriod is how long to wait for a plugin to exit after
+// killing it with SIGKILL.
+const teardownKillGracePeriod = time.Second
+
+// Configure configures the plugin system. Plugins are set up in parallel,
+// except that a plugin is only set up once the plugins it depends on are; if
+// any of them fails, or ctx is cancelled, the setup of the others is abandoned.
+// Plugins are added to the plugin system in dependency order. Plugins scoped to
+// other commands than the given one are not launched.
+func (ps *pluginSystem) Configure(ctx context.Context, streams ioutils.Streams, command string, pluginsConfig any) error {
 	plugins, err := config.UnmarshalPluginConfig(pluginsConfig)
 	if err != nil {
 		return fmt.Errorf("failed to configure plugin system: %w", err)
 	}
 
-	g := new(errgroup.Group)
-	var mutex sync.Mutex
+	plugins, err = orderPlugins(plugins)
+	if err != nil {
+		return fmt.Errorf("failed to configure plugin system: %w", err)
+	}
+	plugins = scopePlugins(plugins, command)
+
+	g, ctx := errgroup.WithContext(ctx)
 
+	setUp := make(map[string]chan struct{}, len(plugins))
 	for _, p := range plugins {
-		p := p
+		setUp[p.Name] = make(chan struct{})
+	}
+	aspectplugins := make([]*client.PluginInstance, len(plugins))
+
+	for i, p := range plugins {
+		i, p := i, p
 
 		g.Go(func() error {
 			aspectplugin, err := ps.clientFactory.New(p, streams)
@@ -99,78 +151,357 @@ func (ps *pluginSystem) Configure(streams ioutils.Streams, pluginsConfig any) er
 				return err
 			}
 			if aspectplugin == nil {
+				close(setUp[p.Name])
 				return nil
 			}
 
-			properties, err := yaml.Marshal(p.Properties)
-			if err != nil {
-				return err
+			for _, dep := range p.DependsOn {
+				select {
+				case <-setUp[dep]:
+				case <-ctx.Done():
+					aspectplugin.Kill()
+					return fmt.Errorf("plugin %q setup was cancelled: %w", p.Name, ctx.Err())
+				}
 			}
 
-			setupConfig := plugin.NewSetupConfig(properties)
-			if err := aspectplugin.Setup(setupConfig); err != nil {
+			if err := SetupPlugin(ctx, p, aspectplugin); err != nil {
 				return err
 			}
+			startDaemons(p, aspectplugin, streams)
 
-			mutex.Lock()
-			ps.plugins.insert(aspectplugin)
-			mutex.Unlock()
+			aspectplugins[i] = aspectplugin
+			close(setUp[p.Name])
 			return nil
 		})
 	}
 
-	if err := g.Wait(); err != nil {
+	err = g.Wait()
+
+	for _, aspectplugin := range aspectplugins {
+		if aspectplugin != nil {
+			ps.plugins = append(ps.plugins, aspectplugin)
+		}
+	}
+
+	if err != nil {
 		return fmt.Errorf("failed to configure plugin system: %w", err)
 	}
 
 	return nil
 }
 
+// SetupPlugin calls Setup on the plugin with the properties from its config,
+// killing the plugin if it doesn't return within its setup timeout or ctx is
+// cancelled first.
+func SetupPlugin(ctx context.Context, p types.PluginConfig, aspectplugin *client.PluginInstance) error {
+	properties, err := yaml.Marshal(p.Properties)
+	if err != nil {
+		return err
+	}
+	setupConfig := plugin.NewSetupConfig(properties, setupWorkspace())
+
+	timeout := p.SetupTimeout
+	if timeout == 0 {
+		timeout = DefaultSetupTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- aspectplugin.Setup(setupConfig)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		// Setup can't be interrupted through the plugin API, so stop the plugin
+		// altogether rather than leave it running. Setup returns once the plugin
+		// is gone.
+		aspectplugin.Kill()
+		select {
+		case <-done:
+		case <-time.After(setupKillGracePeriod):
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("plugin %q did not complete setup within %s; set setup_timeout on the plugin config to allow more time", p.Name, timeout)
+		}
+		return fmt.Errorf("plugin %q setup was cancelled: %w", p.Name, ctx.Err())
+	}
+}
+
+// startDaemons starts the daemons of a plugin that aren't running yet. They
+// run in the workspace root with the environment of the plugin. A daemon that
+// fails to start doesn't fail the command, as the plugin may do without it.
+func startDaemons(p types.PluginConfig, aspectplugin *client.PluginInstance, streams ioutils.Streams) {
+	daemons, err := aspectplugin.Daemons()
+	if err != nil {
+		fmt.Fprintf(streams.Stderr, "Warning: failed to get the daemons of plugin %q: %v\n", p.Name, err)
+		return
+	}
+	if len(daemons) == 0 {
+		return
+	}
+
+	root, err := daemon.Root()
+	if err != nil {
+		fmt.Fprintf(streams.Stderr, "Warning: not starting the daemons of plugin %q: %v\n", p.Name, err)
+		return
+	}
+	if root == "" {
+		// Daemons are tracked in the output base of a workspace.
+		return
+	}
+
+	for _, d := range daemons {
+		env := client.PluginEnv(p, os.Environ())
+		keys := make([]string, 0, len(d.Env))
+		for k := range d.Env {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			env = append(env, k+"="+d.Env[k])
+		}
+
+		if _, err := daemon.Start(root, p.Name, d.Name, d.Args, env, bazel.WorkspaceFromWd.WorkspaceRoot()); err != nil {
+			fmt.Fprintf(streams.Stderr, "Warning: %v\n", err)
+		}
+	}
+}
+
 // RegisterCustomCommands processes custom commands provided by plugins and adds
 // them as commands to the core whilst setting up callbacks for the those commands.
 func (ps *pluginSystem) RegisterCustomCommands(cmd *cobra.Command, bazelStartupArgs []string) error {
-	internalCommands := make(map[string]struct{})
+	internalCommands := make(map[string]*cobra.Command)
 	for _, command := range cmd.Commands() {
 		cmdName := strings.SplitN(command.Use, " ", 2)[0]
-		internalCommands[cmdName] = struct{}{}
+		internalCommands[cmdName] = command
 	}
+	// overriddenBy holds the plugins overriding built-in commands by name.
+	overriddenBy := make(map[string]string)
 
-	for node := ps.plugins.head; node != nil; node = node.next {
-		result, err := node.payload.Plugin.CustomCommands()
+	for _, aspectplugin := range ps.plugins {
+		result, err := aspectplugin.Plugin.CustomCommands()
 		if err != nil {
 			return fmt.Errorf("failed to register custom commands: %w", err)
 		}
 
 		for _, command := range result {
 			cmdName := strings.SplitN(command.Use, " ", 2)[0]
-			if _, ok := internalCommands[cmdName]; ok {
-				return fmt.Errorf("failed to register custom commands: plugin implements a command with a protected name: %s", command.Use)
+			if builtin, ok := internalCommands[cmdName]; ok {
+				if !aspectplugin.AllowCommandOverride {
+					return fmt.Errorf("failed to register custom commands: plugin implements a command with a protected name: %s", command.Use)
+				}
+				if other, ok := overriddenBy[cmdName]; ok {
+					return fmt.Errorf("failed to register custom commands: command %s is already overridden by plugin %q", cmdName, other)
+				}
+				if err := overrideBuiltinCommand(builtin, command.Command, aspectplugin.CustomCommandExecutor, bazelStartupArgs); err != nil {
+					return fmt.Errorf("failed to register custom commands: %w", err)
+				}
+				overriddenBy[cmdName] = aspectplugin.Name
+				continue
+			}
+
+			if err := addPluginCommand(cmd, nil, command.Command, aspectplugin.CustomCommandExecutor, bazelStartupArgs); err != nil {
+				return fmt.Errorf("failed to register custom commands: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// overrideBuiltinCommand makes the built-in command run the custom command of
+// a plugin instead, which can run the built-in command in turn by returning
+// plugin.DelegateToBuiltin. The custom command receives the args of the
+// built-in command, so it can't declare flags of its own.
+func overrideBuiltinCommand(builtin *cobra.Command, command *proto.Command, callback client.CustomCommandExecutor, bazelStartupArgs []string) error {
+	cmdName := builtin.Name()
+	if builtin.RunE == nil {
+		return fmt.Errorf("built-in command %s cannot be overridden", cmdName)
+	}
+	if len(command.Subcommands) > 0 {
+		return fmt.Errorf("command %s overrides a built-in command and cannot have subcommands", cmdName)
+	}
+	if len(command.Flags) > 0 {
+		return fmt.Errorf("command %s overrides a built-in command and cannot declare flags", cmdName)
+	}
+
+	if command.ShortDesc != "" {
+		builtin.Short = command.ShortDesc
+	}
+	if command.LongDesc != "" {
+		builtin.Long = command.LongDesc
+	}
+
+	builtinRunE := builtin.RunE
+	builtin.RunE = func(cmd *cobra.Command, args []string) error {
+		err := callback.ExecuteCustomCommand(cmdName, cmd.Context(), args, bazelStartupArgs)
+		var delegation *plugin.BuiltinDelegation
+		if errors.As(err, &delegation) {
+			return builtinRunE(cmd, delegation.Args)
+		}
+		return err
+	}
+	return nil
+}
+
+// pluginGroupAnnotation marks the commands that group the subcommands of
+// plugins, so that plugins can add their subcommands to the same group.
+const pluginGroupAnnotation = "aspect:plugin_group"
+
+// addPluginCommand adds a custom command declared by a plugin, and the commands
+// nested under it, to the parent command. path holds the names of the commands
+// the parent is nested under.
+func addPluginCommand(parent *cobra.Command, path []string, command *proto.Command, callback client.CustomCommandExecutor, bazelStartupArgs []string) error {
+	cmdName := strings.SplitN(command.Use, " ", 2)[0]
+	path = append(slices.Clone(path), cmdName)
+	cmdPath := strings.Join(path, " ")
+
+	var existing *cobra.Command
+	for _, c := range parent.Commands() {
+		if c.Name() == cmdName {
+			existing = c
+			break
+		}
+	}
+
+	if len(command.Subcommands) > 0 {
+		if len(command.Flags) > 0 {
+			return fmt.Errorf("command %s has subcommands and cannot declare flags", cmdPath)
+		}
+		group := existing
+		if group == nil {
+			group = &cobra.Command{
+				Use:         command.Use,
+				Short:       command.ShortDesc,
+				Long:        command.LongDesc,
+				Annotations: map[string]string{pluginGroupAnnotation: ""},
+			}
+			if parent.Parent() == nil {
+				group.GroupID = "plugin"
 			}
+			parent.AddCommand(group)
+		} else if _, ok := group.Annotations[pluginGroupAnnotation]; !ok {
+			return fmt.Errorf("command %s is declared more than once", cmdPath)
+		}
+		for _, subcommand := range command.Subcommands {
+			if err := addPluginCommand(group, path, subcommand, callback, bazelStartupArgs); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 
-			callback := node.payload.CustomCommandExecutor
+	if existing != nil {
+		return fmt.Errorf("command %s is declared more than once", cmdPath)
+	}
+
+	declaredFlags := command.Flags
 
-			cmd.AddCommand(&cobra.Command{
-				Use:     command.Use,
-				Short:   command.ShortDesc,
-				Long:    command.LongDesc,
-				GroupID: "plugin",
-				RunE: interceptors.Run(
-					[]interceptors.Interceptor{},
-					func(ctx context.Context, cmd *cobra.Command, args []string) (exitErr error) {
-						return callback.ExecuteCustomCommand(cmdName, ctx, args, bazelStartupArgs)
-					},
-				),
-			})
+	pluginCmd := &cobra.Command{
+		Use:   command.Use,
+		Short: command.ShortDesc,
+		Long:  command.LongDesc,
+		RunE: interceptors.Run(
+			[]interceptors.Interceptor{},
+			func(ctx context.Context, cmd *cobra.Command, args []string) (exitErr error) {
+				flags := make(plugin.Flags, len(declaredFlags))
+				for _, flag := range declaredFlags {
+					flags[flag.Name] = cmd.Flags().Lookup(flag.Name).Value.String()
+				}
+				ctx = plugin.ContextWithFlags(ctx, flags)
+				return callback.ExecuteCustomCommand(cmdPath, ctx, args, bazelStartupArgs)
+			},
+		),
+	}
+	if parent.Parent() == nil {
+		pluginCmd.GroupID = "plugin"
+	}
+	for _, flag := range declaredFlags {
+		if err := addPluginFlag(pluginCmd, flag); err != nil {
+			return fmt.Errorf("command %s: %w", cmdPath, err)
 		}
 	}
+	parent.AddCommand(pluginCmd)
+	return nil
+}
+
+// addPluginFlag registers a flag declared by a plugin on its custom command.
+func addPluginFlag(cmd *cobra.Command, flag *proto.Flag) error {
+	flags := cmd.Flags()
+	if flags.Lookup(flag.Name) != nil {
+		return fmt.Errorf("flag --%s is declared more than once", flag.Name)
+	}
+	if len(flag.Shorthand) > 1 {
+		return fmt.Errorf("flag --%s shorthand %q must be a single character", flag.Name, flag.Shorthand)
+	}
+	if flag.Shorthand != "" && flags.ShorthandLookup(flag.Shorthand) != nil {
+		return fmt.Errorf("flag --%s shorthand -%s is already used", flag.Name, flag.Shorthand)
+	}
+
+	switch flag.Type {
+	case proto.Flag_STRING:
+		flags.StringP(flag.Name, flag.Shorthand, flag.DefaultValue, flag.Usage)
+	case proto.Flag_BOOL:
+		value, err := strconv.ParseBool(flag.DefaultValue)
+		if err != nil {
+			return fmt.Errorf("flag --%s has invalid default %q: %w", flag.Name, flag.DefaultValue, err)
+		}
+		flags.BoolP(flag.Name, flag.Shorthand, value, flag.Usage)
+	case proto.Flag_INT:
+		value, err := strconv.Atoi(flag.DefaultValue)
+		if err != nil {
+			return fmt.Errorf("flag --%s has invalid default %q: %w", flag.Name, flag.DefaultValue, err)
+		}
+		flags.IntP(flag.Name, flag.Shorthand, value, flag.Usage)
+	default:
+		return fmt.Errorf("flag --%s has unsupported type %v", flag.Name, flag.Type)
+	}
 	return nil
 }
 
 // TearDown tears down the plugin system, making all the necessary actions to
-// clean up the system.
+// clean up the system. Plugins are killed in parallel and any plugin that
+// doesn't exit within its teardown timeout is killed with SIGKILL, so TearDown
+// returns promptly even if a plugin is stuck.
 func (ps *pluginSystem) TearDown() {
-	for node := ps.plugins.head; node != nil; node = node.next {
-		node.payload.Kill()
+	var wg sync.WaitGroup
+	for _, aspectplugin := range ps.plugins {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tearDownPlugin(aspectplugin)
+		}()
+	}
+	wg.Wait()
+}
+
+// tearDownPlugin kills the plugin, escalating to SIGKILL if it doesn't exit
+// within its teardown timeout. It gives up waiting on the plugin shortly after.
+func tearDownPlugin(aspectplugin *client.PluginInstance) {
+	timeout := aspectplugin.TeardownTimeout
+	if timeout == 0 {
+		timeout = DefaultTeardownTimeout
+	}
+
+	done := make(chan struct{})
+	go func() {
+		aspectplugin.Kill()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-time.After(timeout):
+	}
+
+	aspectplugin.ForceKill()
+	select {
+	case <-done:
+	case <-time.After(teardownKillGracePeriod):
 	}
 }
 
@@ -178,7 +509,8 @@ func (ps *pluginSystem) TearDown() {
 // Use BESInterceptor to only create the grpc service when there is a known subscriber.
 func (ps *pluginSystem) BESPipeInterceptor() interceptors.Interceptor {
 	return func(ctx context.Context, cmd *cobra.Command, args []string, next interceptors.RunEContextFn) error {
-		return ps.createBesInterceptor(ctx, cmd, args, true, next)
+		besArgs, args := removeBESFlags(args)
+		return ps.createBesInterceptor(ctx, cmd, args, true, besArgs, next)
 	}
 }
 
@@ -192,12 +524,19 @@ func (ps *pluginSystem) BESPluginInterceptor() interceptors.Interceptor {
 		if err != nil {
 			return fmt.Errorf("failed to get value of --aspect:force_bes_backend: %w", err)
 		}
+		besArgs, args := removeBESFlags(args)
 
-		// If there are no plugins configured and --aspect:force_bes_backend is not set then short
-		// circuit here since we don't have any need to create a grpc server to consume the build event
-		// stream.
-		if !(forceBesBackend || ps.hasBESPlugins()) {
-			return next(ctx, cmd, args)
+		// If there are no plugins configured, no build event JSON file is configured, no build
+		// summary, progress UI, test status table, test history, failure artifacts, invocation
+		// history, GitHub annotations, build traces or strict BES delivery are requested and
+		// --aspect:force_bes_backend is not set then short circuit here since we don't have any
+		// need to create a grpc server to consume the build event stream.
+		if !(forceBesBackend || besArgs.strict || ps.hasBESPlugins() || history.Enabled() || viper.GetString(BuildEventJSONFileKey) != "" || rootFlags.HasFlag(args, rootFlags.AspectSummaryFlag) || rootFlags.HasFlag(args, rootFlags.AspectUIFlag) || cmd.Name() == "test" && (teststatus.Enabled() || flaky.Recording() || rootFlags.HasFlag(args, flaky.DetectFlag)) || (cmd.Name() == "build" || cmd.Name() == "test") && fetchlogs.Enabled() || githubAnnotations() || viper.GetBool(BuildTraceKey)) {
+			metadata, err := loadBuildMetadata(newMetadataVars())
+			if err != nil {
+				return err
+			}
+			return next(ctx, cmd, metadata.addTo(args))
 		}
 		if forceBesBackend {
 			fmt.Fprintf(os.Stderr, "Forcing creation of BES backend\n")
@@ -208,14 +547,57 @@ func (ps *pluginSystem) BESPluginInterceptor() interceptors.Interceptor {
 			fmt.Fprintf(os.Stderr, "Using BES pipe\n")
 		}
 
-		return ps.createBesInterceptor(ctx, cmd, args, usePipe, next)
+		return ps.createBesInterceptor(ctx, cmd, args, usePipe, besArgs, next)
+	}
+}
+
+// besFlags are the Aspect CLI flags of the BES interceptors, which bazel
+// doesn't know about.
+type besFlags struct {
+	// strict is set by --aspect:bes_strict or the Aspect CLI config.
+	strict bool
+	// pipeUploadMode is the value of --aspect:bes_pipe_upload_mode.
+	pipeUploadMode string
+}
+
+// removeBESFlags removes the flags of the BES interceptors from args.
+func removeBESFlags(args []string) (besFlags, []string) {
+	strict, args := rootFlags.RemoveFlag(args, rootFlags.AspectBESStrictFlag)
+	pipeUploadMode, args := rootFlags.RemoveFlagValue(args, rootFlags.AspectBESPipeUploadModeFlag)
+	return besFlags{strict: strict || viper.GetBool(BESStrictKey), pipeUploadMode: pipeUploadMode}, args
+}
+
+// BESStrictKey is the key of the Aspect CLI config that fails the command when
+// a BES backend didn't acknowledge every build event, like
+// --aspect:bes_strict.
+const BESStrictKey = "bes_strict"
+
+// besStrictExitCode is the exit code of a command whose build events weren't
+// all delivered in strict mode. It is bazel's for a persistent failure to
+// publish to the Build Event Service.
+const besStrictExitCode = 45
+
+// strictDeliveryError returns the error a command fails with in strict mode
+// when a BES backend didn't receive the complete invocation, or nil.
+func strictDeliveryError(reports []bep.AckReport) error {
+	undelivered := bep.Undelivered(reports)
+	if len(undelivered) == 0 {
+		return nil
+	}
+	hosts := make([]string, 0, len(undelivered))
+	for _, r := range undelivered {
+		hosts = append(hosts, r.Host)
+	}
+	return &aspecterrors.ExitError{
+		Err:      fmt.Errorf("%s: the build events were not all delivered to %s", rootFlags.AspectBESStrictFlag, strings.Join(hosts, ", ")),
+		ExitCode: besStrictExitCode,
 	}
 }
 
 // Check if any plugins are registered that require BES event processing
 func (ps *pluginSystem) hasBESPlugins() bool {
-	for node := ps.plugins.head; node != nil; node = node.next {
-		if !node.payload.DisableBESEvents {
+	for _, aspectplugin := range ps.plugins {
+		if !aspectplugin.DisableBESEvents {
 			return true
 		}
 	}
@@ -256,20 +638,107 @@ func removeLastBesBackend(args []string) ([]string, string) {
 	return slices.Delete(args, lastBackend, lastBackend+1), backend
 }
 
-func (ps *pluginSystem) createBesInterceptor(ctx context.Context, cmd *cobra.Command, args []string, usePipe bool, next interceptors.RunEContextFn) error {
+func (ps *pluginSystem) createBesInterceptor(ctx context.Context, cmd *cobra.Command, args []string, usePipe bool, besArgs besFlags, next interceptors.RunEContextFn) (err error) {
 	var besInterceptor bep.BESInterceptor
-	var err error
+
+	backends, err := besproxy.UnmarshalBackendConfig(viper.Get(besproxy.BackendsKey))
+	if err != nil {
+		return err
+	}
+	excludedEventTypes, err := excludedBesEventTypes()
+	if err != nil {
+		return err
+	}
+	sampling, err := besEventSampling()
+	if err != nil {
+		return err
+	}
+	timeouts, err := besTimeouts()
+	if err != nil {
+		return err
+	}
+	flowControl, err := besFlowControl()
+	if err != nil {
+		return err
+	}
+	metadata, err := loadBuildMetadata(newMetadataVars())
+	if err != nil {
+		return err
+	}
 
 	if usePipe {
-		besInterceptor, err = setupBesPipe(args)
+		besInterceptor, err = setupBesPipe(args, cmd.Name(), besArgs.pipeUploadMode, metadata.Keywords, excludedEventTypes, sampling, timeouts)
 		if err != nil {
 			return err
 		}
 	} else {
-		besInterceptor, err = setupBesBackend()
+		listenAddress, listenPort, err := besBackendListenAddress()
 		if err != nil {
 			return err
 		}
+		server, err := besServer()
+		if err != nil {
+			return err
+		}
+		besInterceptor, err = setupBesBackend(bep.BESBackendOptions{
+			Backends:           backends,
+			ExcludedEventTypes: excludedEventTypes,
+			Sampling:           sampling,
+			Timeouts:           timeouts,
+			FlowControl:        flowControl,
+			SocketPath:         besBackendSocketPath(),
+			ListenAddress:      listenAddress,
+			ListenPort:         listenPort,
+			Command:            cmd.Name(),
+		}, server)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The JSON file is closed once the BES backend has stopped, so that it
+	// holds every build event.
+	var jsonFile *bep.JSONFile
+	if path := viper.GetString(BuildEventJSONFileKey); path != "" {
+		jsonFile, err = bep.CreateJSONFile(path)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := jsonFile.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			}
+		}()
+	}
+
+	// The spans of an invocation whose last build event wasn't received are
+	// ended once the BES backend has stopped.
+	var buildTrace *bep.BuildTrace
+	if viper.GetBool(BuildTraceKey) {
+		buildTrace = bep.NewBuildTrace(ctx)
+		defer buildTrace.Close()
+	}
+
+	// The invocations are recorded in the history once the BES backend has
+	// stopped, so that their last build events are included.
+	var invocationHistory *history.Recorder
+	if history.Enabled() {
+		invocationHistory = history.NewRecorder(args)
+		defer func() {
+			if err := history.Save(invocationHistory.Invocations()); err != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			}
+		}()
+	}
+
+	// In strict mode, the command fails once the BES backend has stopped and
+	// reported what the backends acknowledged.
+	if besArgs.strict {
+		defer func() {
+			if strictErr := strictDeliveryError(besInterceptor.DeliveryReports()); strictErr != nil && err == nil {
+				err = strictErr
+			}
+		}()
 	}
 
 	// Start the BES backend
@@ -278,33 +747,363 @@ func (ps *pluginSystem) createBesInterceptor(ctx context.Context, cmd *cobra.Com
 	}
 	defer besInterceptor.GracefulStop()
 
-	for node := ps.plugins.head; node != nil; node = node.next {
-		if !node.payload.DisableBESEvents {
-			besInterceptor.RegisterSubscriber(node.payload.BEPEventCallback, node.payload.MultiThreaded)
+	if jsonFile != nil {
+		besInterceptor.RegisterSubscriber(jsonFile.Callback, bep.SubscriberOptions{})
+	}
+	if buildTrace != nil {
+		besInterceptor.RegisterSubscriber(buildTrace.Callback, bep.SubscriberOptions{})
+	}
+	if invocationHistory != nil {
+		besInterceptor.RegisterSubscriber(invocationHistory.Callback, bep.SubscriberOptions{})
+	}
+	if githubAnnotations() {
+		annotations := bep.NewGitHubAnnotations(os.Stdout)
+		besInterceptor.RegisterSubscriber(annotations.Callback, bep.SubscriberOptions{}, bep.GitHubAnnotationEventTypes...)
+	}
+
+	for _, aspectplugin := range ps.plugins {
+		if !aspectplugin.DisableBESEvents {
+			eventTypes, err := aspectplugin.BEPEventTypes()
+			if err != nil {
+				return fmt.Errorf("failed to get the build event types of plugin %q: %w", aspectplugin.Name, err)
+			}
+			for _, eventType := range eventTypes {
+				if !bep.IsEventType(eventType) {
+					return fmt.Errorf("plugin %q subscribed to unknown build event type %q", aspectplugin.Name, eventType)
+				}
+			}
+			opts := bep.SubscriberOptions{
+				MultiThreaded: aspectplugin.MultiThreaded,
+				Workers:       aspectplugin.BuildEventWorkers,
+				Ordered:       aspectplugin.OrderedBuildEvents,
+				QueueSize:     aspectplugin.BuildEventQueueSize,
+				QueuePolicy:   bep.QueuePolicy(aspectplugin.BuildEventQueuePolicy),
+				Name:          aspectplugin.Name,
+				Sampling:      sampling,
+			}
+			callback := func(event *buildeventstream.BuildEvent, sn int64, stream bep.StreamInfo) error {
+				return aspectplugin.BEPStreamEventCallback(event, sn, &proto.BEPStream{
+					BuildId:      stream.BuildId,
+					InvocationId: stream.InvocationId,
+					Command:      stream.Command,
+				})
+			}
+			besInterceptor.RegisterSubscriber(callback, opts, eventTypes...)
 		}
 	}
 
+	// The BES backend forwards to the configured backends itself once bazel
+	// reports its options, while the BES pipe forwards to them from the start.
+	var pipeBackends []besproxy.Backend
 	if os.Getenv("ASPECT_BEP_WRITE_LAST_VIA_PIPE") != "" {
 		newArgs, lastBackend := removeLastBesBackend(args)
-		fmt.Fprintf(os.Stderr, "Forwarding BES stream to %s\n", lastBackend)
-		besProxy := besproxy.NewBesProxy(lastBackend, map[string]string{})
+		pipeBackends = append(pipeBackends, besproxy.Backend{URL: lastBackend, Headers: map[string]string{}})
+		args = newArgs
+	}
+	if usePipe {
+		pipeBackends = bep.MergeBackends(pipeBackends, backends)
+	}
+	for _, backend := range pipeBackends {
+		fmt.Fprintf(os.Stderr, "Forwarding BES stream to %s\n", backend.URL)
+		backend.Recovery = timeouts.Recovery()
+		backend.FlowControl = flowControl
+		besProxy := besproxy.NewBesProxyForBackend(backend)
 		if err := besProxy.Connect(); err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to connect to build event stream backend %s: %s", lastBackend, err.Error())
+			fmt.Fprintf(os.Stderr, "Failed to connect to build event stream backend %s: %s", backend.URL, err.Error())
 		} else {
 			besInterceptor.RegisterBesProxy(ctx, besProxy)
 		}
-
-		args = newArgs
 	}
 
+	// The keywords and metadata are added once the invocation history
+	// recorded the args, so that a rerun doesn't repeat them.
 	ctx = bep.InjectBESInterceptor(ctx, besInterceptor)
-	return next(ctx, cmd, args)
+	return next(ctx, cmd, metadata.addTo(args))
+}
+
+// BuildEventSpoolKey is the key of the Aspect CLI config that names the file
+// the BES pipe writes a copy of the build event stream to, and
+// BuildEventSpoolCompressionKey the key of its compression.
+const (
+	BuildEventSpoolKey            = "build_event_spool"
+	BuildEventSpoolCompressionKey = "build_event_spool_compression"
+)
+
+// BuildEventJSONFileKey is the key of the Aspect CLI config that names the file
+// the build events are written to in the format of --build_event_json_file.
+const BuildEventJSONFileKey = "build_event_json_file"
+
+// BuildTraceKey is the key of the Aspect CLI config that converts the build
+// events into spans of the telemetry session.
+const BuildTraceKey = "telemetry.build_events"
+
+// GitHubAnnotationsKey is the key of the Aspect CLI config that turns off the
+// GitHub Actions annotations of the failures of a build, which are on by
+// default when running in a GitHub Actions workflow.
+const GitHubAnnotationsKey = "github_annotations"
+
+// githubAnnotations returns true if the failures of a build are annotated for
+// GitHub Actions.
+func githubAnnotations() bool {
+	if !bep.IsGitHubActions() {
+		return false
+	}
+	return !viper.IsSet(GitHubAnnotationsKey) || viper.GetBool(GitHubAnnotationsKey)
+}
+
+// DeferredUploadKey is the key of the Aspect CLI config that keeps the build
+// events that couldn't be uploaded to a BES backend to upload them later,
+// rather than aborting the upload when all backends are unhealthy. It is the
+// same as the spool UnhealthyPolicyKey.
+const DeferredUploadKey = "bes_deferred_upload"
+
+// UnhealthyPolicyKey is the key of the Aspect CLI config that chooses what
+// happens to the build when all BES backends are unhealthy: abort the upload,
+// continue-without-bes or spool the build events to upload them later.
+const UnhealthyPolicyKey = "bes_unhealthy_policy"
+
+// besUnhealthyPolicy returns the policy set in the Aspect CLI config when all
+// BES backends are unhealthy.
+func besUnhealthyPolicy() (bep.UnhealthyPolicy, error) {
+	policy := viper.GetString(UnhealthyPolicyKey)
+	if policy == "" && viper.GetBool(DeferredUploadKey) {
+		return bep.UnhealthySpool, nil
+	}
+	parsed, err := bep.ParseUnhealthyPolicy(policy)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s: %w", UnhealthyPolicyKey, err)
+	}
+	return parsed, nil
 }
 
-func setupBesPipe(args []string) (bep.BESPipeInterceptor, error) {
+// ExcludedEventTypesKey is the key of the Aspect CLI config that lists the
+// types of the build events that are not forwarded to the BES backends.
+const ExcludedEventTypesKey = "bes_exclude_event_types"
+
+// excludedBesEventTypes returns the build event types that are not forwarded
+// to the BES backends.
+func excludedBesEventTypes() ([]string, error) {
+	eventTypes := viper.GetStringSlice(ExcludedEventTypesKey)
+	for _, eventType := range eventTypes {
+		if !bep.IsEventType(eventType) {
+			return nil, fmt.Errorf("unknown build event type %q in %s", eventType, ExcludedEventTypesKey)
+		}
+	}
+	return eventTypes, nil
+}
+
+// EventSamplingKey is the key of the Aspect CLI config that maps the types of
+// high-volume build events to the number of them that are sampled or
+// aggregated into one before they are forwarded to the plugins and the BES
+// backends.
+const EventSamplingKey = "bes_sample_event_types"
+
+// besEventSampling returns the sampling of the build events forwarded to the
+// plugins and the BES backends.
+func besEventSampling() (bep.EventSampling, error) {
+	sampling := bep.EventSampling{}
+	for eventType, value := range viper.GetStringMapString(EventSamplingKey) {
+		if !bep.IsEventType(eventType) {
+			return nil, fmt.Errorf("unknown build event type %q in %s", eventType, EventSamplingKey)
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid %s for %q: %q is not a positive number of build events", EventSamplingKey, eventType, value)
+		}
+		sampling[eventType] = n
+	}
+	return sampling, nil
+}
+
+// UnixSocketKey is the key of the Aspect CLI config that serves the BES backend
+// the CLI passes to bazel on a Unix domain socket rather than a TCP port, to
+// avoid port collisions on shared hosts.
+const UnixSocketKey = "bes_backend_unix_socket"
+
+// besBackendSocketPath returns the Unix domain socket the BES backend is served
+// on, if any.
+func besBackendSocketPath() string {
+	if !viper.GetBool(UnixSocketKey) {
+		return ""
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("aspect-cli-%v-bes.sock", os.Getpid()))
+}
+
+// ListenAddressKey and ListenPortKey are the keys of the Aspect CLI config that
+// bind the BES backend the CLI passes to bazel to an address and a fixed port,
+// e.g. for firewalled CI environments. The backend is bound to the loopback
+// interface on a port assigned by the OS by default.
+const (
+	ListenAddressKey = "bes_backend_address"
+	ListenPortKey    = "bes_backend_port"
+)
+
+// besBackendListenAddress returns the address and the port the BES backend is
+// bound to, where the default address is empty and the default port zero.
+func besBackendListenAddress() (string, int, error) {
+	address := viper.GetString(ListenAddressKey)
+	// An IPv6 address may be given in brackets, as in a URL.
+	address = strings.TrimSuffix(strings.TrimPrefix(address, "["), "]")
+	// A host name can't hold a colon, so that a port isn't mistaken for part
+	// of an IPv6 address.
+	if strings.ContainsAny(address, "[]/") || (strings.Contains(address, ":") && net.ParseIP(address) == nil) {
+		return "", 0, fmt.Errorf("expected %s to be an IP address or a host name: %q", ListenAddressKey, viper.GetString(ListenAddressKey))
+	}
+	s := viper.GetString(ListenPortKey)
+	if s == "" {
+		return address, 0, nil
+	}
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 0 || port > math.MaxUint16 {
+		return "", 0, fmt.Errorf("expected %s to be a port number between 0 and %d: %q", ListenPortKey, math.MaxUint16, s)
+	}
+	return address, port, nil
+}
+
+// The keys of the Aspect CLI config that bound how long to wait for bazel and
+// the BES backends:
+//   - EventTimeoutKey is how long to wait for the next build event from
+//     bazel, e.g. while remote actions are queued, before giving up,
+//   - SendTimeoutKey how long to wait for a backend to accept a build event
+//     before marking it unhealthy,
+//   - ProbeIntervalKey how often a backend marked unhealthy is probed to
+//     resume its stream,
+//   - OutageTimeoutKey how long it is probed before it is taken out of
+//     rotation.
+const (
+	EventTimeoutKey  = "bes_event_timeout"
+	SendTimeoutKey   = "bes_send_timeout"
+	ProbeIntervalKey = "bes_probe_interval"
+	OutageTimeoutKey = "bes_outage_timeout"
+)
+
+// MaxEventSizeKey is the key of the Aspect CLI config that bounds the size of a
+// build event read from the BES pipe in megabytes, and MaxEventSizeEnv the
+// environment variable that overrides it.
+const (
+	MaxEventSizeKey = "bes_max_event_size_mb"
+	MaxEventSizeEnv = "ASPECT_BEP_MAX_EVENT_SIZE_MB"
+)
+
+// besMaxEventSize returns the maximum size of a build event in bytes set in
+// the environment or the Aspect CLI config, or zero for the default.
+func besMaxEventSize() (int, error) {
+	source, s := MaxEventSizeEnv, os.Getenv(MaxEventSizeEnv)
+	if s == "" {
+		source, s = MaxEventSizeKey, viper.GetString(MaxEventSizeKey)
+	}
+	if s == "" {
+		return 0, nil
+	}
+	mb, err := strconv.Atoi(s)
+	// The size in bytes must fit an int32.
+	if err != nil || mb <= 0 || mb > math.MaxInt32>>20 {
+		return 0, fmt.Errorf("expected %s to be a number of megabytes between 1 and %d: %q", source, math.MaxInt32>>20, s)
+	}
+	return mb << 20, nil
+}
+
+// besTimeouts returns the BES timeouts set in the Aspect CLI config.
+func besTimeouts() (bep.Timeouts, error) {
+	var timeouts bep.Timeouts
+	for _, t := range []struct {
+		key string
+		d   *time.Duration
+	}{
+		{EventTimeoutKey, &timeouts.Event},
+		{SendTimeoutKey, &timeouts.Send},
+		{ProbeIntervalKey, &timeouts.Probe},
+		{OutageTimeoutKey, &timeouts.Outage},
+	} {
+		s := viper.GetString(t.key)
+		if s == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(s)
+		if err != nil || parsed <= 0 {
+			return bep.Timeouts{}, fmt.Errorf("expected %s to be a positive duration such as 30s: %q", t.key, s)
+		}
+		*t.d = parsed
+	}
+	return timeouts, nil
+}
+
+// PipeUploadModeKey is the key of the Aspect CLI config that sets the
+// --build_event_binary_file_upload_mode bazel writes the build events to the
+// BES pipe with. It is either an upload mode for all the commands, or a map of
+// the commands to their upload modes.
+const PipeUploadModeKey = "bes_pipe_upload_mode"
+
+// besPipeUploadMode returns the upload mode of the BES pipe for a command,
+// from --aspect:bes_pipe_upload_mode or else the Aspect CLI config.
+func besPipeUploadMode(command, flagValue string) (bep.UploadMode, error) {
+	if flagValue != "" {
+		mode, err := bep.ParseUploadMode(flagValue)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s: %w", rootFlags.AspectBESPipeUploadModeFlag, err)
+		}
+		return mode, nil
+	}
+	var mode string
+	switch config := viper.Get(PipeUploadModeKey).(type) {
+	case nil:
+	case string:
+		mode = config
+	case map[string]any:
+		mode = viper.GetStringMapString(PipeUploadModeKey)[command]
+	default:
+		return "", fmt.Errorf("expected %s to be an upload mode or a map of commands to upload modes", PipeUploadModeKey)
+	}
+	parsed, err := bep.ParseUploadMode(mode)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s: %w", PipeUploadModeKey, err)
+	}
+	return parsed, nil
+}
+
+// buildEventSpool returns the build event spool set in the Aspect CLI config.
+func buildEventSpool() (bep.Spool, error) {
+	path := viper.GetString(BuildEventSpoolKey)
+	if path == "" {
+		return bep.Spool{}, nil
+	}
+	compression, err := bep.ParseCompression(viper.GetString(BuildEventSpoolCompressionKey), path)
+	if err != nil {
+		return bep.Spool{}, err
+	}
+	return bep.Spool{Path: path, Compression: compression}, nil
+}
+
+func setupBesPipe(args []string, command string, uploadModeFlag string, keywords []string, excludedEventTypes []string, sampling bep.EventSampling, timeouts bep.Timeouts) (bep.BESPipeInterceptor, error) {
 	buildId := determineBuildId(args)
 	invocationId := determineInvocationId(args)
-	besPipe, err := bep.NewBESPipe(buildId, invocationId)
+	spool, err := buildEventSpool()
+	if err != nil {
+		return nil, err
+	}
+	unhealthyPolicy, err := besUnhealthyPolicy()
+	if err != nil {
+		return nil, err
+	}
+	maxEventSize, err := besMaxEventSize()
+	if err != nil {
+		return nil, err
+	}
+	uploadMode, err := besPipeUploadMode(command, uploadModeFlag)
+	if err != nil {
+		return nil, err
+	}
+	besPipe, err := bep.NewBESPipe(buildId, invocationId, bep.BESPipeOptions{
+		Spool:              spool,
+		UnhealthyPolicy:    unhealthyPolicy,
+		ExcludedEventTypes: excludedEventTypes,
+		Sampling:           sampling,
+		Timeouts:           timeouts,
+		Command:            command,
+		MaxEventSize:       maxEventSize,
+		UploadMode:         uploadMode,
+		Keywords:           keywords,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create BES pipe: %w", err)
 	}
@@ -314,25 +1113,180 @@ func setupBesPipe(args []string) (bep.BESPipeInterceptor, error) {
 	return besPipe, nil
 }
 
-func setupBesBackend() (bep.BESInterceptor, error) {
-	besBackend := bep.NewBESBackend()
-	opts := []grpc.ServerOption{
+// besServerConfig tunes the gRPC server of the BES backend the CLI passes to
+// bazel.
+type besServerConfig struct {
+	// MaxRecvMsgSize and MaxSendMsgSize bound the size of the messages in
+	// bytes.
+	MaxRecvMsgSize int
+	MaxSendMsgSize int
+	// KeepaliveMinTime is how often bazel may ping the server, and
+	// PermitWithoutStream whether it may ping when no stream is open, before
+	// the server closes the connection.
+	KeepaliveMinTime    time.Duration
+	PermitWithoutStream bool
+	// MaxConcurrentStreams bounds the number of streams of a connection, or
+	// is zero for no limit.
+	MaxConcurrentStreams uint32
+}
+
+// The keys of the Aspect CLI config that tune the gRPC server of the BES
+// backend the CLI passes to bazel:
+//   - MaxRecvMessageSizeKey and MaxSendMessageSizeKey bound the size of the
+//     messages it receives and sends in megabytes,
+//   - KeepaliveMinTimeKey is how often bazel may ping it, and
+//     KeepalivePermitWithoutStreamKey whether bazel may ping it when no
+//     stream is open,
+//   - MaxConcurrentStreamsKey bounds the number of streams of a connection.
+const (
+	MaxRecvMessageSizeKey           = "bes_backend_max_recv_message_size_mb"
+	MaxSendMessageSizeKey           = "bes_backend_max_send_message_size_mb"
+	KeepaliveMinTimeKey             = "bes_backend_keepalive_min_time"
+	KeepalivePermitWithoutStreamKey = "bes_backend_keepalive_permit_without_stream"
+	MaxConcurrentStreamsKey         = "bes_backend_max_concurrent_streams"
+)
+
+// besServer returns the configuration of the gRPC server of the BES backend
+// set in the Aspect CLI config, with the defaults for the keys that are not
+// set.
+func besServer() (besServerConfig, error) {
+	server := besServerConfig{
 		// Bazel doesn't seem to set a maximum send message size, therefore
 		// we match the default send message for Go, which should be enough
 		// for all messages sent by Bazel (roughly 2.14GB).
-		grpc.MaxRecvMsgSize(math.MaxInt32),
+		MaxRecvMsgSize: math.MaxInt32,
 		// Here we are just being explicit with the default value since we
 		// also set the receive message size.
-		grpc.MaxSendMsgSize(math.MaxInt32),
+		MaxSendMsgSize: math.MaxInt32,
 		// Allow pings as frequent as every 1s
+		KeepaliveMinTime:    1 * time.Second,
+		PermitWithoutStream: true,
+	}
+	for _, size := range []struct {
+		key   string
+		bytes *int
+	}{
+		{MaxRecvMessageSizeKey, &server.MaxRecvMsgSize},
+		{MaxSendMessageSizeKey, &server.MaxSendMsgSize},
+	} {
+		s := viper.GetString(size.key)
+		if s == "" {
+			continue
+		}
+		mb, err := strconv.Atoi(s)
+		// The size in bytes must fit an int32.
+		if err != nil || mb <= 0 || mb > math.MaxInt32>>20 {
+			return besServerConfig{}, fmt.Errorf("expected %s to be a number of megabytes between 1 and %d: %q", size.key, math.MaxInt32>>20, s)
+		}
+		*size.bytes = mb << 20
+	}
+	if s := viper.GetString(KeepaliveMinTimeKey); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return besServerConfig{}, fmt.Errorf("expected %s to be a positive duration such as 30s: %q", KeepaliveMinTimeKey, s)
+		}
+		server.KeepaliveMinTime = d
+	}
+	if viper.IsSet(KeepalivePermitWithoutStreamKey) {
+		server.PermitWithoutStream = viper.GetBool(KeepalivePermitWithoutStreamKey)
+	}
+	if s := viper.GetString(MaxConcurrentStreamsKey); s != "" {
+		n, err := strconv.ParseUint(s, 10, 32)
+		if err != nil || n == 0 {
+			return besServerConfig{}, fmt.Errorf("expected %s to be a positive number of streams: %q", MaxConcurrentStreamsKey, s)
+		}
+		server.MaxConcurrentStreams = uint32(n)
+	}
+	return server, nil
+}
+
+// options returns the gRPC server options of the configuration.
+func (c besServerConfig) options() []grpc.ServerOption {
+	opts := []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(c.MaxRecvMsgSize),
+		grpc.MaxSendMsgSize(c.MaxSendMsgSize),
 		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
-			MinTime:             1 * time.Second,
-			PermitWithoutStream: true,
+			MinTime:             c.KeepaliveMinTime,
+			PermitWithoutStream: c.PermitWithoutStream,
 		}),
 	}
+	if c.MaxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(c.MaxConcurrentStreams))
+	}
+	return opts
+}
+
+// The keys of the Aspect CLI config that tune the flow control of the streams
+// to the BES backends the build events are forwarded to:
+//   - StreamWindowSizeKey and StreamConnWindowSizeKey are the initial HTTP/2
+//     windows of a stream and its connection in megabytes,
+//   - StreamWriteBufferSizeKey and StreamReadBufferSizeKey are the sizes of
+//     the buffers of the connection in kilobytes,
+//   - StreamMaxInFlightKey bounds the number of build events a backend
+//     hasn't acknowledged yet.
+const (
+	StreamWindowSizeKey      = "bes_stream_window_size_mb"
+	StreamConnWindowSizeKey  = "bes_stream_conn_window_size_mb"
+	StreamWriteBufferSizeKey = "bes_stream_write_buffer_size_kb"
+	StreamReadBufferSizeKey  = "bes_stream_read_buffer_size_kb"
+	StreamMaxInFlightKey     = "bes_stream_max_in_flight_events"
+)
+
+// besFlowControl returns the flow control of the streams to the BES backends
+// set in the Aspect CLI config. The keys that are not set are left zero, for
+// the defaults of besproxy.
+func besFlowControl() (besproxy.FlowControl, error) {
+	var flowControl besproxy.FlowControl
+	for _, window := range []struct {
+		key   string
+		bytes *int32
+	}{
+		{StreamWindowSizeKey, &flowControl.WindowSize},
+		{StreamConnWindowSizeKey, &flowControl.ConnWindowSize},
+	} {
+		s := viper.GetString(window.key)
+		if s == "" {
+			continue
+		}
+		mb, err := strconv.Atoi(s)
+		// The window in bytes must fit an int32.
+		if err != nil || mb <= 0 || mb > math.MaxInt32>>20 {
+			return besproxy.FlowControl{}, fmt.Errorf("expected %s to be a number of megabytes between 1 and %d: %q", window.key, math.MaxInt32>>20, s)
+		}
+		*window.bytes = int32(mb << 20)
+	}
+	for _, buffer := range []struct {
+		key   string
+		bytes *int
+	}{
+		{StreamWriteBufferSizeKey, &flowControl.WriteBufferSize},
+		{StreamReadBufferSizeKey, &flowControl.ReadBufferSize},
+	} {
+		s := viper.GetString(buffer.key)
+		if s == "" {
+			continue
+		}
+		kb, err := strconv.Atoi(s)
+		if err != nil || kb <= 0 || kb > math.MaxInt32>>10 {
+			return besproxy.FlowControl{}, fmt.Errorf("expected %s to be a number of kilobytes between 1 and %d: %q", buffer.key, math.MaxInt32>>10, s)
+		}
+		*buffer.bytes = kb << 10
+	}
+	if s := viper.GetString(StreamMaxInFlightKey); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return besproxy.FlowControl{}, fmt.Errorf("expected %s to be a positive number of build events: %q", StreamMaxInFlightKey, s)
+		}
+		flowControl.MaxInFlight = n
+	}
+	return flowControl, nil
+}
+
+func setupBesBackend(backendOpts bep.BESBackendOptions, server besServerConfig) (bep.BESInterceptor, error) {
+	besBackend := bep.NewBESBackend(backendOpts)
 
 	// Setup the BES backend grpc server
-	if err := besBackend.Setup(opts...); err != nil {
+	if err := besBackend.Setup(server.options()...); err != nil {
 		return nil, fmt.Errorf("failed to run BES backend: %w", err)
 	}
 
@@ -357,55 +1311,222 @@ func (ps *pluginSystem) RunHooksInterceptor(streams ioutils.Streams) interceptor
 	return ps.commandHooksInterceptor("PostRunHook", streams)
 }
 
+// RewriteArgsInterceptor returns an interceptor that passes the command
+// arguments through the RewriteArgs hook of all plugins, in the order they are
+// added, before calling the next interceptor with the result.
+func (ps *pluginSystem) RewriteArgsInterceptor() interceptors.Interceptor {
+	return func(ctx context.Context, cmd *cobra.Command, args []string, next interceptors.RunEContextFn) error {
+		for _, aspectplugin := range ps.plugins {
+			rewritten, err := aspectplugin.RewriteArgs(cmd.Name(), args)
+			if err != nil {
+				return fmt.Errorf("failed to run 'aspect %s' command: %w", cmd.CalledAs(), err)
+			}
+			args = rewritten
+		}
+		return next(ctx, cmd, args)
+	}
+}
+
+// OutputFilterInterceptor returns an interceptor that injects a
+// linefilter.Filter into the context when any plugin filters output. The
+// filter passes each line through the FilterOutputLine hook of those plugins,
+// in the order they are added. A plugin that fails to filter a line is
+// reported on stderr and leaves the rest of the output unfiltered.
+func (ps *pluginSystem) OutputFilterInterceptor(streams ioutils.Streams) interceptors.Interceptor {
+	return func(ctx context.Context, cmd *cobra.Command, args []string, next interceptors.RunEContextFn) error {
+		var filters []*client.PluginInstance
+		for _, aspectplugin := range ps.plugins {
+			filtersOutput, err := aspectplugin.FiltersOutput()
+			if err != nil {
+				return fmt.Errorf("failed to run 'aspect %s' command: %w", cmd.CalledAs(), err)
+			}
+			if filtersOutput {
+				filters = append(filters, aspectplugin)
+			}
+		}
+		if len(filters) == 0 {
+			return next(ctx, cmd, args)
+		}
+		return next(linefilter.Inject(ctx, pluginOutputFilter(filters, streams)), cmd, args)
+	}
+}
+
+func pluginOutputFilter(filters []*client.PluginInstance, streams ioutils.Streams) linefilter.Filter {
+	var mu sync.Mutex
+	failed := make([]bool, len(filters))
+	return func(stream linefilter.Stream, line string) []string {
+		pbStream := proto.OutputStream_STDOUT
+		if stream == linefilter.Stderr {
+			pbStream = proto.OutputStream_STDERR
+		}
+		lines := []string{line}
+		for i, p := range filters {
+			mu.Lock()
+			skip := failed[i]
+			mu.Unlock()
+			if skip {
+				continue
+			}
+
+			var filtered []string
+			for _, l := range lines {
+				out, err := p.FilterOutputLine(pbStream, l)
+				if err != nil {
+					mu.Lock()
+					failed[i] = true
+					mu.Unlock()
+					fmt.Fprintf(streams.Stderr, "Error: plugin %q failed to filter output: %v\n", p.Name, err)
+					filtered = lines
+					break
+				}
+				filtered = append(filtered, out...)
+			}
+			lines = filtered
+		}
+		return lines
+	}
+}
+
 func (ps *pluginSystem) commandHooksInterceptor(methodName string, streams ioutils.Streams) interceptors.Interceptor {
 	return func(ctx context.Context, cmd *cobra.Command, args []string, next interceptors.RunEContextFn) (exitErr error) {
 		isInteractiveMode, err := cmd.Root().PersistentFlags().GetBool(rootFlags.AspectInteractiveFlagName)
 		if err != nil {
 			return fmt.Errorf("failed to run 'aspect %s' command: %w", cmd.CalledAs(), err)
 		}
+		invocation, err := invocationContext(cmd.Name(), args, isInteractiveMode)
+		if err != nil {
+			return fmt.Errorf("failed to run 'aspect %s' command: %w", cmd.CalledAs(), err)
+		}
 
 		defer func() {
-			hasPluginErrors := false
-			for node := ps.plugins.head; node != nil; node = node.next {
+			var hookErrs []error
+			for _, aspectplugin := range ps.plugins {
 				params := []reflect.Value{
-					reflect.ValueOf(isInteractiveMode),
+					reflect.ValueOf(invocation),
 					reflect.ValueOf(ps.promptRunner),
 				}
-				if err := reflect.ValueOf(node.payload).MethodByName(methodName).Call(params)[0].Interface(); err != nil {
-					fmt.Fprintf(streams.Stderr, "Error: failed to run 'aspect %s' command: %v\n", cmd.CalledAs(), err)
-					hasPluginErrors = true
-				}
-			}
-			if hasPluginErrors {
-				var err *aspecterrors.ExitError
-				if errors.As(exitErr, &err) {
-					err.ExitCode = 1
+				if err := reflect.ValueOf(aspectplugin).MethodByName(methodName).Call(params)[0].Interface(); err != nil {
+					err := err.(error)
+					var result *plugin.HookResult
+					switch {
+					case !errors.As(err, &result):
+						fmt.Fprintf(streams.Stderr, "Error: failed to run 'aspect %s' command: %v\n", cmd.CalledAs(), err)
+					case result.Message == "":
+						// The hook only sets the exit code.
+					case result.Outcome == proto.HookResult_WARN || result.ExitCode == 0:
+						fmt.Fprintf(streams.Stderr, "Warning: %s\n", result.Message)
+					default:
+						fmt.Fprintf(streams.Stderr, "Error: %s\n", result.Message)
+					}
+					hookErrs = append(hookErrs, err)
 				}
 			}
+			exitErr = hookExitError(exitErr, hookErrs)
 		}()
 		return next(ctx, cmd, args)
 	}
 }
 
-// PluginList implements a simple linked list for the parsed plugins from the
-// plugins file.
-type PluginList struct {
-	head *PluginNode
-	tail *PluginNode
+// hookExitError returns the error of a command after its hooks returned the
+// given errors. The results of the hooks take precedence in this order:
+//  1. The first exit code set with plugin.ExitWithCode, in the order the
+//     plugins are configured, is the exit code of the command. An exit code of
+//     0 makes the command succeed.
+//  2. plugin.FailCommand fails the command with exit code 1, even when it
+//     succeeded.
+//  3. Any other error sets the exit code of a failed command to 1.
+//  4. plugin.DowngradeToWarning makes a failed command succeed.
+func hookExitError(exitErr error, hookErrs []error) error {
+	var exitCode *plugin.HookResult
+	var failed, errored, warned bool
+	for _, err := range hookErrs {
+		var result *plugin.HookResult
+		if !errors.As(err, &result) {
+			errored = true
+			continue
+		}
+		switch result.Outcome {
+		case proto.HookResult_EXIT_CODE:
+			if exitCode == nil {
+				exitCode = result
+			}
+		case proto.HookResult_FAIL:
+			failed = true
+		case proto.HookResult_WARN:
+			warned = true
+		default:
+			errored = true
+		}
+	}
+
+	switch {
+	case exitCode != nil:
+		if exitCode.ExitCode == 0 {
+			return nil
+		}
+		return withExitCode(exitErr, exitCode.ExitCode)
+	case failed:
+		return withExitCode(exitErr, aspecterrors.Failed)
+	case errored:
+		var err *aspecterrors.ExitError
+		if errors.As(exitErr, &err) {
+			err.ExitCode = aspecterrors.Failed
+		}
+		return exitErr
+	case warned:
+		return nil
+	}
+	return exitErr
 }
 
-func (l *PluginList) insert(p *client.PluginInstance) {
-	node := &PluginNode{payload: p}
-	if l.head == nil {
-		l.head = node
-	} else {
-		l.tail.next = node
+// withExitCode sets the exit code of the error returned by a command, which
+// may have succeeded.
+func withExitCode(exitErr error, exitCode int) error {
+	var err *aspecterrors.ExitError
+	if errors.As(exitErr, &err) {
+		err.ExitCode = exitCode
+		return exitErr
 	}
-	l.tail = node
+	return &aspecterrors.ExitError{Err: exitErr, ExitCode: exitCode}
 }
 
-// PluginNode is a node in the PluginList linked list.
-type PluginNode struct {
-	next    *PluginNode
-	payload *client.PluginInstance
+// invocationContext returns the context passed to the hooks of the bazel
+// command invoked with the given args.
+func invocationContext(command string, args []string, isInteractiveMode bool) (*proto.InvocationContext, error) {
+	var argsAfterDashDash []string
+	if i := slices.Index(args, "--"); i >= 0 {
+		args, argsAfterDashDash = args[:i], args[i+1:]
+	}
+
+	nonFlags, flagsArgs, err := bazel.SeparateBazelFlags(command, args)
+	if err != nil {
+		return nil, err
+	}
+
+	targetPatterns := make([]string, 0, len(nonFlags)+len(argsAfterDashDash))
+	for _, arg := range nonFlags {
+		if strings.HasPrefix(arg, "-") {
+			// Flags unknown to the CLI, such as --@rules_foo//:bar, are passed
+			// to bazel as they are.
+			if !strings.HasPrefix(arg, "--aspect:") {
+				flagsArgs = append(flagsArgs, arg)
+			}
+			continue
+		}
+		targetPatterns = append(targetPatterns, arg)
+	}
+	targetPatterns = append(targetPatterns, argsAfterDashDash...)
+	if command == "run" && len(targetPatterns) > 1 {
+		// The args after the target are passed to the binary that is run.
+		targetPatterns = targetPatterns[:1]
+	}
+
+	return &proto.InvocationContext{
+		Command:           command,
+		TargetPatterns:    targetPatterns,
+		StartupArgs:       bazel.StartupFlags(),
+		Flags:             bazel.CanonicalizeBazelFlags(command, flagsArgs),
+		WorkspaceRoot:     bazel.WorkspaceFromWd.WorkspaceRoot(),
+		IsInteractiveMode: isInteractiveMode,
+	}, nil
 }
@@ -20,22 +20,30 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/gomega"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
 
 	rootFlags "github.com/aspect-build/aspect-cli-legacy/pkg/aspect/root/flags"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/aspecterrors"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils/linefilter"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/ioutils/prompt"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/client"
 	client_mock "github.com/aspect-build/aspect-cli-legacy/pkg/plugin/client/mock"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/plugin"
 	plugin_mock "github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/plugin/mock"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/proto"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/bep"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/system/besproxy"
 	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/types"
 )
 
@@ -64,7 +72,7 @@ func TestPluginSystemInterceptors(t *testing.T) {
 
 		ps := NewPluginSystem().(*pluginSystem)
 		plugin := plugin_mock.NewMockPlugin(ctrl)
-		ps.plugins.insert(&client.PluginInstance{
+		ps.plugins = append(ps.plugins, &client.PluginInstance{
 			Plugin:   plugin,
 			Provider: client_mock.NewMockProvider(ctrl),
 		})
@@ -107,11 +115,11 @@ func TestPluginSystemInterceptors(t *testing.T) {
 		ps := NewPluginSystem().(*pluginSystem)
 		plugin1 := plugin_mock.NewMockPlugin(ctrl)
 		plugin2 := plugin_mock.NewMockPlugin(ctrl)
-		ps.plugins.insert(&client.PluginInstance{
+		ps.plugins = append(ps.plugins, &client.PluginInstance{
 			Plugin:   plugin1,
 			Provider: client_mock.NewMockProvider(ctrl),
 		})
-		ps.plugins.insert(&client.PluginInstance{
+		ps.plugins = append(ps.plugins, &client.PluginInstance{
 			Plugin:   plugin2,
 			Provider: client_mock.NewMockProvider(ctrl),
 		})
@@ -151,7 +159,7 @@ func TestPluginSystemInterceptors(t *testing.T) {
 		// Plugin to be invoked
 		ps := NewPluginSystem().(*pluginSystem)
 		plugin := plugin_mock.NewMockPlugin(ctrl)
-		ps.plugins.insert(&client.PluginInstance{
+		ps.plugins = append(ps.plugins, &client.PluginInstance{
 			Plugin:   plugin,
 			Provider: client_mock.NewMockProvider(ctrl),
 		})
@@ -194,7 +202,7 @@ func TestPluginSystemInterceptors(t *testing.T) {
 		// Plugin to be invoked
 		ps := NewPluginSystem().(*pluginSystem)
 		plugin := plugin_mock.NewMockPlugin(ctrl)
-		ps.plugins.insert(&client.PluginInstance{
+		ps.plugins = append(ps.plugins, &client.PluginInstance{
 			Plugin:   plugin,
 			Provider: client_mock.NewMockProvider(ctrl),
 		})
@@ -269,12 +277,12 @@ func TestPluginSystemInterceptors(t *testing.T) {
 		plugin.EXPECT().
 			PostRunHook(gomock.Any(), gomock.Any()).
 			DoAndReturn(func(
-				isInteractiveMode bool,
+				invocation *proto.InvocationContext,
 				promptRunner prompt.PromptRunner,
 			) error {
 				return fmt.Errorf("plugin error")
 			})
-		ps.plugins.insert(&client.PluginInstance{
+		ps.plugins = append(ps.plugins, &client.PluginInstance{
 			Plugin:   plugin,
 			Provider: client_mock.NewMockProvider(ctrl),
 		})
@@ -292,6 +300,335 @@ func TestPluginSystemInterceptors(t *testing.T) {
 		g.Expect(err.(*aspecterrors.ExitError).Err).To(MatchError("interceptor error"))
 		g.Expect(err.(*aspecterrors.ExitError).ExitCode).To(Equal(1))
 	})
+
+	t.Run("hook results set the exit code of a successful command", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// Setup
+		var stdout strings.Builder
+		streams := ioutils.Streams{Stdout: &stdout, Stderr: &stdout}
+		ctx := context.Background()
+		cmd := createInterceptorCommand()
+
+		ps := NewPluginSystem().(*pluginSystem)
+		plugin1 := plugin_mock.NewMockPlugin(ctrl)
+		plugin1.EXPECT().
+			PostBuildHook(gomock.Any(), gomock.Any()).
+			Return(plugin.DowngradeToWarning("flaky"))
+		plugin2 := plugin_mock.NewMockPlugin(ctrl)
+		plugin2.EXPECT().
+			PostBuildHook(gomock.Any(), gomock.Any()).
+			Return(plugin.ExitWithCode(42, "policy violation"))
+		ps.plugins = append(ps.plugins, &client.PluginInstance{
+			Plugin:   plugin1,
+			Provider: client_mock.NewMockProvider(ctrl),
+		})
+		ps.plugins = append(ps.plugins, &client.PluginInstance{
+			Plugin:   plugin2,
+			Provider: client_mock.NewMockProvider(ctrl),
+		})
+
+		buildInterceptor := ps.BuildHooksInterceptor(streams)
+		err := buildInterceptor(ctx, cmd, []string{}, func(ctx context.Context, cmd *cobra.Command, args []string) error {
+			return nil
+		})
+
+		var exitErr *aspecterrors.ExitError
+		g.Expect(errors.As(err, &exitErr)).To(BeTrue())
+		g.Expect(exitErr.Err).To(BeNil())
+		g.Expect(exitErr.ExitCode).To(Equal(42))
+		g.Expect(stdout.String()).To(Equal("Warning: flaky\nError: policy violation\n"))
+	})
+
+	t.Run("filters the output of bazel through the plugins that filter output", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// Setup
+		var stderr strings.Builder
+		streams := ioutils.Streams{Stdout: &stderr, Stderr: &stderr}
+		ctx := context.Background()
+		cmd := createInterceptorCommand()
+
+		ps := NewPluginSystem().(*pluginSystem)
+		plugin1 := plugin_mock.NewMockPlugin(ctrl)
+		plugin1.EXPECT().FiltersOutput().Return(true, nil)
+		plugin1.EXPECT().
+			FilterOutputLine(proto.OutputStream_STDOUT, gomock.Any()).
+			DoAndReturn(func(_ proto.OutputStream, line string) ([]string, error) {
+				if strings.HasPrefix(line, "WARNING:") {
+					return nil, nil
+				}
+				return []string{line, "  annotated"}, nil
+			}).
+			Times(2)
+		plugin2 := plugin_mock.NewMockPlugin(ctrl)
+		plugin2.EXPECT().FiltersOutput().Return(false, nil)
+		plugin3 := plugin_mock.NewMockPlugin(ctrl)
+		plugin3.EXPECT().FiltersOutput().Return(true, nil)
+		plugin3.EXPECT().
+			FilterOutputLine(proto.OutputStream_STDOUT, gomock.Any()).
+			Return(nil, fmt.Errorf("plugin error"))
+		for i, p := range []*plugin_mock.MockPlugin{plugin1, plugin2, plugin3} {
+			ps.plugins = append(ps.plugins, &client.PluginInstance{
+				Plugin:   p,
+				Name:     fmt.Sprintf("plugin%d", i+1),
+				Provider: client_mock.NewMockProvider(ctrl),
+			})
+		}
+
+		var stdout strings.Builder
+		interceptor := ps.OutputFilterInterceptor(streams)
+		err := interceptor(ctx, cmd, []string{}, func(ctx context.Context, cmd *cobra.Command, args []string) error {
+			filtered, flush := linefilter.Streams(ctx, ioutils.Streams{Stdout: &stdout})
+			fmt.Fprintln(filtered.Stdout, "WARNING: noisy")
+			fmt.Fprintln(filtered.Stdout, "ERROR: failed")
+			flush()
+			return nil
+		})
+
+		g.Expect(err).To(BeNil())
+		g.Expect(stdout.String()).To(Equal("ERROR: failed\n  annotated\n"))
+		g.Expect(stderr.String()).To(Equal("Error: plugin \"plugin3\" failed to filter output: plugin error\n"))
+	})
+
+	t.Run("does not filter output when no plugin filters output", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// Setup
+		var stdout strings.Builder
+		streams := ioutils.Streams{Stdout: &stdout, Stderr: &stdout}
+		ctx := context.Background()
+		cmd := createInterceptorCommand()
+
+		ps := NewPluginSystem().(*pluginSystem)
+		plugin := plugin_mock.NewMockPlugin(ctrl)
+		plugin.EXPECT().FiltersOutput().Return(false, nil)
+		ps.plugins = append(ps.plugins, &client.PluginInstance{
+			Plugin:   plugin,
+			Provider: client_mock.NewMockProvider(ctrl),
+		})
+
+		interceptor := ps.OutputFilterInterceptor(streams)
+		err := interceptor(ctx, cmd, []string{}, func(ctx context.Context, cmd *cobra.Command, args []string) error {
+			_, ok := linefilter.FromContext(ctx)
+			g.Expect(ok).To(BeFalse())
+			return nil
+		})
+
+		g.Expect(err).To(BeNil())
+	})
+
+	t.Run("passes the invocation context to the hooks", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// Setup
+		var stdout strings.Builder
+		streams := ioutils.Streams{Stdout: &stdout, Stderr: &stdout}
+		ctx := context.Background()
+		cmd := createInterceptorCommand()
+		g.Expect(cmd.PersistentFlags().Set(rootFlags.AspectInteractiveFlagName, "true")).To(Succeed())
+
+		ps := NewPluginSystem().(*pluginSystem)
+		plugin := plugin_mock.NewMockPlugin(ctrl)
+		var invocation *proto.InvocationContext
+		plugin.EXPECT().
+			PostBuildHook(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(i *proto.InvocationContext, _ prompt.PromptRunner) error {
+				invocation = i
+				return nil
+			})
+		ps.plugins = append(ps.plugins, &client.PluginInstance{
+			Plugin:   plugin,
+			Provider: client_mock.NewMockProvider(ctrl),
+		})
+
+		buildInterceptor := ps.BuildHooksInterceptor(streams)
+		args := []string{"//foo/...", "--", "-//foo/bar/..."}
+		err := buildInterceptor(ctx, cmd, args, func(ctx context.Context, cmd *cobra.Command, args []string) error {
+			return nil
+		})
+
+		g.Expect(err).To(BeNil())
+		g.Expect(invocation.Command).To(Equal("TestCommand"))
+		g.Expect(invocation.TargetPatterns).To(Equal([]string{"//foo/...", "-//foo/bar/..."}))
+		g.Expect(invocation.Flags).To(BeEmpty())
+		g.Expect(invocation.IsInteractiveMode).To(BeTrue())
+	})
+
+	t.Run("passes only the target to the hooks of run", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		invocation, err := invocationContext("run", []string{"//foo:bin", "arg", "--", "--bin_flag"}, false)
+
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(invocation.TargetPatterns).To(Equal([]string{"//foo:bin"}))
+	})
+
+	t.Run("passes args rewritten by plugins in order plugins are added", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// Setup
+		ctx := context.Background()
+		cmd := createInterceptorCommand()
+
+		ps := NewPluginSystem().(*pluginSystem)
+		plugin1 := plugin_mock.NewMockPlugin(ctrl)
+		plugin2 := plugin_mock.NewMockPlugin(ctrl)
+		ps.plugins = append(ps.plugins, &client.PluginInstance{
+			Plugin:   plugin1,
+			Provider: client_mock.NewMockProvider(ctrl),
+		})
+		ps.plugins = append(ps.plugins, &client.PluginInstance{
+			Plugin:   plugin2,
+			Provider: client_mock.NewMockProvider(ctrl),
+		})
+
+		gomock.InOrder(
+			plugin1.EXPECT().
+				RewriteArgs("TestCommand", []string{"//..."}).
+				Return([]string{"--config=remote", "//..."}, nil),
+			plugin2.EXPECT().
+				RewriteArgs("TestCommand", []string{"--config=remote", "//..."}).
+				Return([]string{"--config=remote", "//foo/..."}, nil),
+		)
+
+		var nextArgs []string
+		rewriteArgsInterceptor := ps.RewriteArgsInterceptor()
+		err := rewriteArgsInterceptor(ctx, cmd, []string{"//..."}, func(ctx context.Context, cmd *cobra.Command, args []string) error {
+			nextArgs = args
+			return nil
+		})
+
+		g.Expect(err).To(BeNil())
+		g.Expect(nextArgs).To(Equal([]string{"--config=remote", "//foo/..."}))
+	})
+
+	t.Run("does not run the command when a plugin fails to rewrite args", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// Setup
+		ctx := context.Background()
+		cmd := createInterceptorCommand()
+
+		ps := NewPluginSystem().(*pluginSystem)
+		plugin := plugin_mock.NewMockPlugin(ctrl)
+		plugin.EXPECT().RewriteArgs(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("plugin error"))
+		ps.plugins = append(ps.plugins, &client.PluginInstance{
+			Plugin:   plugin,
+			Provider: client_mock.NewMockProvider(ctrl),
+		})
+
+		called := false
+		rewriteArgsInterceptor := ps.RewriteArgsInterceptor()
+		err := rewriteArgsInterceptor(ctx, cmd, []string{"//..."}, func(ctx context.Context, cmd *cobra.Command, args []string) error {
+			called = true
+			return nil
+		})
+
+		g.Expect(err).To(MatchError(ContainSubstring("plugin error")))
+		g.Expect(called).To(BeFalse())
+	})
+}
+
+func TestHookExitError(t *testing.T) {
+	bazelErr := func() error {
+		return &aspecterrors.ExitError{Err: fmt.Errorf("build failed"), ExitCode: 3}
+	}
+	tests := []struct {
+		name     string
+		exitErr  error
+		hookErrs []error
+		exitCode int
+	}{
+		{
+			name:     "no hook errors keep the exit code",
+			exitErr:  bazelErr(),
+			exitCode: 3,
+		},
+		{
+			name:     "plain hook errors set the exit code of a failed command to 1",
+			exitErr:  bazelErr(),
+			hookErrs: []error{fmt.Errorf("plugin error")},
+			exitCode: 1,
+		},
+		{
+			name:     "plain hook errors do not fail a successful command",
+			hookErrs: []error{fmt.Errorf("plugin error")},
+			exitCode: 0,
+		},
+		{
+			name:     "FailCommand fails a successful command",
+			hookErrs: []error{plugin.FailCommand("policy violation")},
+			exitCode: 1,
+		},
+		{
+			name:     "DowngradeToWarning makes a failed command succeed",
+			exitErr:  bazelErr(),
+			hookErrs: []error{plugin.DowngradeToWarning("flaky")},
+			exitCode: 0,
+		},
+		{
+			name:     "plain hook errors take precedence over DowngradeToWarning",
+			exitErr:  bazelErr(),
+			hookErrs: []error{plugin.DowngradeToWarning("flaky"), fmt.Errorf("plugin error")},
+			exitCode: 1,
+		},
+		{
+			name:     "FailCommand takes precedence over DowngradeToWarning",
+			exitErr:  bazelErr(),
+			hookErrs: []error{plugin.DowngradeToWarning("flaky"), plugin.FailCommand("policy violation")},
+			exitCode: 1,
+		},
+		{
+			name:    "the first ExitWithCode takes precedence",
+			exitErr: bazelErr(),
+			hookErrs: []error{
+				plugin.FailCommand("policy violation"),
+				plugin.ExitWithCode(42, ""),
+				plugin.ExitWithCode(43, ""),
+			},
+			exitCode: 42,
+		},
+		{
+			name:     "ExitWithCode 0 makes a failed command succeed",
+			exitErr:  bazelErr(),
+			hookErrs: []error{fmt.Errorf("plugin error"), plugin.ExitWithCode(0, "")},
+			exitCode: 0,
+		},
+		{
+			name:     "wrapped hook results are recognized",
+			hookErrs: []error{fmt.Errorf("wrapped: %w", plugin.ExitWithCode(42, ""))},
+			exitCode: 42,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			err := hookExitError(tt.exitErr, tt.hookErrs)
+			if tt.exitCode == 0 {
+				g.Expect(err).To(BeNil())
+				return
+			}
+			var exitErr *aspecterrors.ExitError
+			g.Expect(errors.As(err, &exitErr)).To(BeTrue())
+			g.Expect(exitErr.ExitCode).To(Equal(tt.exitCode))
+			if tt.exitErr != nil {
+				g.Expect(exitErr.Err).To(MatchError("build failed"))
+			}
+		})
+	}
 }
 
 func TestConfigure(t *testing.T) {
@@ -305,7 +642,7 @@ func TestConfigure(t *testing.T) {
 
 		ps := &pluginSystem{}
 
-		err := ps.Configure(streams, nil)
+		err := ps.Configure(context.Background(), streams, "build", nil)
 
 		g.Expect(err).To(BeNil())
 	})
@@ -333,8 +670,10 @@ func TestConfigure(t *testing.T) {
 
 		p1 := plugin_mock.NewMockPlugin(ctrl)
 		p1.EXPECT().Setup(gomock.Any())
+		p1.EXPECT().Daemons()
 		p2 := plugin_mock.NewMockPlugin(ctrl)
 		p2.EXPECT().Setup(gomock.Any())
+		p2.EXPECT().Daemons()
 
 		factory := client_mock.NewMockFactory(ctrl)
 		factory.EXPECT().New(testPlugin, streams).Return(
@@ -354,7 +693,6 @@ func TestConfigure(t *testing.T) {
 
 		ps := &pluginSystem{
 			clientFactory: factory,
-			plugins:       &PluginList{},
 		}
 
 		pluginConfig := []interface{}{
@@ -372,11 +710,62 @@ func TestConfigure(t *testing.T) {
 			},
 		}
 
-		err := ps.Configure(streams, pluginConfig)
+		err := ps.Configure(context.Background(), streams, "build", pluginConfig)
+
+		g.Expect(err).To(BeNil())
+		g.Expect(ps.plugins[0].Plugin).To(Equal(p1))
+		g.Expect(ps.plugins[1].Plugin).To(Equal(p2))
+	})
+
+	t.Run("doesn't launch plugins scoped to other commands", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var stdout strings.Builder
+		streams := ioutils.Streams{Stdout: &stdout, Stderr: &stdout}
+
+		testPlugin := types.PluginConfig{
+			Name:     "test plugin",
+			From:     "...",
+			Commands: []string{"build", "test"},
+		}
+
+		p1 := plugin_mock.NewMockPlugin(ctrl)
+		p1.EXPECT().Setup(gomock.Any())
+		p1.EXPECT().Daemons()
+
+		factory := client_mock.NewMockFactory(ctrl)
+		factory.EXPECT().New(testPlugin, streams).Return(
+			&client.PluginInstance{
+				Plugin:   p1,
+				Provider: client_mock.NewMockProvider(ctrl),
+			},
+			nil,
+		)
+
+		ps := &pluginSystem{
+			clientFactory: factory,
+		}
+
+		pluginConfig := []interface{}{
+			map[string]interface{}{
+				"name":     "test plugin",
+				"from":     "...",
+				"commands": []interface{}{"build", "test"},
+			},
+			map[string]interface{}{
+				"name":     "test plugin2",
+				"from":     "...",
+				"commands": []interface{}{"query"},
+			},
+		}
+
+		err := ps.Configure(context.Background(), streams, "build", pluginConfig)
 
 		g.Expect(err).To(BeNil())
-		g.Expect(ps.plugins.head.payload.Plugin).To(Equal(p1))
-		g.Expect(ps.plugins.tail.payload.Plugin).To(Equal(p2))
+		g.Expect(ps.plugins[0].Plugin).To(Equal(p1))
+		g.Expect(ps.plugins).To(HaveLen(1))
 	})
 
 	t.Run("fails when a plugin initialization fails", func(t *testing.T) {
@@ -402,12 +791,16 @@ func TestConfigure(t *testing.T) {
 
 		p1 := plugin_mock.NewMockPlugin(ctrl)
 		p1.EXPECT().Setup(gomock.Any())
+		p1.EXPECT().Daemons().MaxTimes(1)
+		// The setup of p1 is abandoned if the failure of plugin2 wins the race.
+		provider1 := client_mock.NewMockProvider(ctrl)
+		provider1.EXPECT().Kill().MaxTimes(1)
 
 		factory := client_mock.NewMockFactory(ctrl)
 		factory.EXPECT().New(testPlugin, streams).Return(
 			&client.PluginInstance{
 				Plugin:   p1,
-				Provider: client_mock.NewMockProvider(ctrl),
+				Provider: provider1,
 			},
 			nil,
 		)
@@ -418,7 +811,6 @@ func TestConfigure(t *testing.T) {
 
 		ps := &pluginSystem{
 			clientFactory: factory,
-			plugins:       &PluginList{},
 		}
 
 		pluginConfig := []interface{}{
@@ -436,7 +828,7 @@ func TestConfigure(t *testing.T) {
 			},
 		}
 
-		err := ps.Configure(streams, pluginConfig)
+		err := ps.Configure(context.Background(), streams, "build", pluginConfig)
 
 		g.Expect(err).To(MatchError("failed to configure plugin system: plugin New() error"))
 	})
@@ -464,14 +856,18 @@ func TestConfigure(t *testing.T) {
 
 		p1 := plugin_mock.NewMockPlugin(ctrl)
 		p1.EXPECT().Setup(gomock.Any())
+		p1.EXPECT().Daemons().MaxTimes(1)
 		p2 := plugin_mock.NewMockPlugin(ctrl)
 		p2.EXPECT().Setup(gomock.Any()).Return(errors.New("setup error"))
+		// The setup of p1 is abandoned if the failure of plugin2 wins the race.
+		provider1 := client_mock.NewMockProvider(ctrl)
+		provider1.EXPECT().Kill().MaxTimes(1)
 
 		factory := client_mock.NewMockFactory(ctrl)
 		factory.EXPECT().New(testPlugin, streams).Return(
 			&client.PluginInstance{
 				Plugin:   p1,
-				Provider: client_mock.NewMockProvider(ctrl),
+				Provider: provider1,
 			},
 			nil,
 		)
@@ -485,7 +881,6 @@ func TestConfigure(t *testing.T) {
 
 		ps := &pluginSystem{
 			clientFactory: factory,
-			plugins:       &PluginList{},
 		}
 
 		pluginConfig := []interface{}{
@@ -503,11 +898,63 @@ func TestConfigure(t *testing.T) {
 			},
 		}
 
-		err := ps.Configure(streams, pluginConfig)
+		err := ps.Configure(context.Background(), streams, "build", pluginConfig)
 
 		g.Expect(err).To(MatchError("failed to configure plugin system: setup error"))
 	})
 
+	t.Run("fails when a plugin setup times out", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var stdout strings.Builder
+		streams := ioutils.Streams{Stdout: &stdout, Stderr: &stdout}
+
+		testPlugin := types.PluginConfig{
+			Name:         "test plugin",
+			From:         "...",
+			Version:      "1.2.3",
+			SetupTimeout: 10 * time.Millisecond,
+		}
+
+		killed := make(chan struct{})
+		p1 := plugin_mock.NewMockPlugin(ctrl)
+		p1.EXPECT().Setup(gomock.Any()).DoAndReturn(func(*plugin.SetupConfig) error {
+			<-killed
+			return errors.New("plugin killed")
+		})
+		provider1 := client_mock.NewMockProvider(ctrl)
+		provider1.EXPECT().Kill().Do(func() { close(killed) })
+
+		factory := client_mock.NewMockFactory(ctrl)
+		factory.EXPECT().New(testPlugin, streams).Return(
+			&client.PluginInstance{
+				Plugin:   p1,
+				Provider: provider1,
+			},
+			nil,
+		)
+
+		ps := &pluginSystem{
+			clientFactory: factory,
+		}
+
+		pluginConfig := []interface{}{
+			map[string]interface{}{
+				"name":          "test plugin",
+				"from":          "...",
+				"version":       "1.2.3",
+				"setup_timeout": "10ms",
+			},
+		}
+
+		err := ps.Configure(context.Background(), streams, "build", pluginConfig)
+
+		g.Expect(err).To(MatchError(`failed to configure plugin system: plugin "test plugin" did not complete setup within 10ms; set setup_timeout on the plugin config to allow more time`))
+		g.Expect(ps.plugins).To(BeEmpty())
+	})
+
 	t.Run("marshaled properties are passed to plugin.Setup", func(t *testing.T) {
 		g := NewGomegaWithT(t)
 		ctrl := gomock.NewController(t)
@@ -518,7 +965,7 @@ func TestConfigure(t *testing.T) {
 
 		propertiesMap := make(map[string]interface{})
 		propertiesBytes, _ := yaml.Marshal(propertiesMap)
-		setupConfig := plugin.NewSetupConfig(propertiesBytes)
+		setupConfig := plugin.NewSetupConfig(propertiesBytes, setupWorkspace())
 
 		testPlugin := types.PluginConfig{
 			Name:       "test plugin",
@@ -530,6 +977,7 @@ func TestConfigure(t *testing.T) {
 
 		p1 := plugin_mock.NewMockPlugin(ctrl)
 		p1.EXPECT().Setup(setupConfig)
+		p1.EXPECT().Daemons()
 
 		factory := client_mock.NewMockFactory(ctrl)
 		factory.EXPECT().New(testPlugin, streams).Return(
@@ -542,7 +990,6 @@ func TestConfigure(t *testing.T) {
 
 		ps := &pluginSystem{
 			clientFactory: factory,
-			plugins:       &PluginList{},
 		}
 
 		pluginConfig := []interface{}{
@@ -555,8 +1002,734 @@ func TestConfigure(t *testing.T) {
 			},
 		}
 
-		err := ps.Configure(streams, pluginConfig)
+		err := ps.Configure(context.Background(), streams, "build", pluginConfig)
 
 		g.Expect(err).To(BeNil())
 	})
+
+	t.Run("sets up plugins after the plugins they depend on", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var stdout strings.Builder
+		streams := ioutils.Streams{Stdout: &stdout, Stderr: &stdout}
+
+		uploadPlugin := types.PluginConfig{
+			Name:      "upload",
+			From:      "...",
+			DependsOn: []string{"auth"},
+		}
+		authPlugin := types.PluginConfig{
+			Name: "auth",
+			From: "...",
+		}
+
+		upload := plugin_mock.NewMockPlugin(ctrl)
+		auth := plugin_mock.NewMockPlugin(ctrl)
+		gomock.InOrder(
+			auth.EXPECT().Setup(gomock.Any()).Do(func(*plugin.SetupConfig) {
+				// Give the upload plugin a chance to be set up first if it
+				// didn't wait for the auth plugin.
+				time.Sleep(10 * time.Millisecond)
+			}),
+			upload.EXPECT().Setup(gomock.Any()),
+		)
+		auth.EXPECT().Daemons()
+		upload.EXPECT().Daemons()
+
+		factory := client_mock.NewMockFactory(ctrl)
+		factory.EXPECT().New(uploadPlugin, streams).Return(
+			&client.PluginInstance{
+				Plugin:   upload,
+				Provider: client_mock.NewMockProvider(ctrl),
+			},
+			nil,
+		)
+		factory.EXPECT().New(authPlugin, streams).Return(
+			&client.PluginInstance{
+				Plugin:   auth,
+				Provider: client_mock.NewMockProvider(ctrl),
+			},
+			nil,
+		)
+
+		ps := &pluginSystem{
+			clientFactory: factory,
+		}
+
+		pluginConfig := []interface{}{
+			map[string]interface{}{
+				"name":       "upload",
+				"from":       "...",
+				"depends_on": []interface{}{"auth"},
+			},
+			map[string]interface{}{
+				"name": "auth",
+				"from": "...",
+			},
+		}
+
+		err := ps.Configure(context.Background(), streams, "build", pluginConfig)
+
+		g.Expect(err).To(BeNil())
+		g.Expect(ps.plugins[0].Plugin).To(Equal(auth))
+		g.Expect(ps.plugins[1].Plugin).To(Equal(upload))
+	})
+}
+
+type customCommandExecutorFunc func(cmdName string, ctx context.Context, args []string, bazelStartupArgs []string) error
+
+func (f customCommandExecutorFunc) ExecuteCustomCommand(cmdName string, ctx context.Context, args []string, bazelStartupArgs []string) error {
+	return f(cmdName, ctx, args, bazelStartupArgs)
+}
+
+func TestRegisterCustomCommands(t *testing.T) {
+	t.Run("passes the flags declared by plugin commands", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var calledArgs []string
+		var calledFlags plugin.Flags
+		executor := customCommandExecutorFunc(func(cmdName string, ctx context.Context, args []string, bazelStartupArgs []string) error {
+			g.Expect(cmdName).To(Equal("greet"))
+			calledArgs = args
+			calledFlags = plugin.FlagsFromContext(ctx)
+			return nil
+		})
+
+		p := plugin_mock.NewMockPlugin(ctrl)
+		p.EXPECT().CustomCommands().Return([]*plugin.Command{
+			plugin.NewCommand("greet <name>", "Greets", "", nil).
+				StringFlag("greeting", "g", "Hello", "The greeting").
+				BoolFlag("loud", "", false, "Shout").
+				IntFlag("times", "n", 1, "Repetitions"),
+		}, nil)
+
+		ps := &pluginSystem{}
+		ps.plugins = append(ps.plugins, &client.PluginInstance{
+			Plugin:                p,
+			Provider:              client_mock.NewMockProvider(ctrl),
+			CustomCommandExecutor: executor,
+		})
+
+		cmd := &cobra.Command{Use: "aspect"}
+		cmd.AddGroup(&cobra.Group{ID: "plugin", Title: "Plugin Commands:"})
+		g.Expect(ps.RegisterCustomCommands(cmd, nil)).To(Succeed())
+
+		cmd.SetArgs([]string{"greet", "--loud", "-n", "3", "world"})
+		g.Expect(cmd.ExecuteContext(context.Background())).To(Succeed())
+
+		g.Expect(calledArgs).To(Equal([]string{"world"}))
+		g.Expect(calledFlags).To(Equal(plugin.Flags{
+			"greeting": "Hello",
+			"loud":     "true",
+			"times":    "3",
+		}))
+	})
+
+	t.Run("fails when a plugin command declares a flag twice", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		p := plugin_mock.NewMockPlugin(ctrl)
+		p.EXPECT().CustomCommands().Return([]*plugin.Command{
+			plugin.NewCommand("greet", "Greets", "", nil).
+				StringFlag("greeting", "", "Hello", "").
+				BoolFlag("greeting", "", false, ""),
+		}, nil)
+
+		ps := &pluginSystem{}
+		ps.plugins = append(ps.plugins, &client.PluginInstance{
+			Plugin:   p,
+			Provider: client_mock.NewMockProvider(ctrl),
+		})
+
+		err := ps.RegisterCustomCommands(&cobra.Command{Use: "aspect"}, nil)
+		g.Expect(err).To(MatchError("failed to register custom commands: command greet: flag --greeting is declared more than once"))
+	})
+
+	t.Run("nests plugin commands in groups shared by plugins", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var called []string
+		executor := customCommandExecutorFunc(func(cmdName string, ctx context.Context, args []string, bazelStartupArgs []string) error {
+			called = append(called, cmdName)
+			return nil
+		})
+
+		deploy := plugin_mock.NewMockPlugin(ctrl)
+		deploy.EXPECT().CustomCommands().Return([]*plugin.Command{
+			plugin.NewCommand("mycompany", "My company commands", "", nil).AddSubcommands(
+				plugin.NewCommand("deploy", "Deploys", "", nil).AddSubcommands(
+					plugin.NewCommand("staging", "Deploys to staging", "", nil),
+				),
+			),
+		}, nil)
+		oncall := plugin_mock.NewMockPlugin(ctrl)
+		oncall.EXPECT().CustomCommands().Return([]*plugin.Command{
+			plugin.NewCommand("mycompany", "", "", nil).AddSubcommands(
+				plugin.NewCommand("oncall", "Pages the oncall", "", nil),
+			),
+		}, nil)
+
+		ps := &pluginSystem{}
+		for _, p := range []plugin.Plugin{deploy, oncall} {
+			ps.plugins = append(ps.plugins, &client.PluginInstance{
+				Plugin:                p,
+				Provider:              client_mock.NewMockProvider(ctrl),
+				CustomCommandExecutor: executor,
+			})
+		}
+
+		cmd := &cobra.Command{Use: "aspect"}
+		cmd.AddGroup(&cobra.Group{ID: "plugin", Title: "Plugin Commands:"})
+		g.Expect(ps.RegisterCustomCommands(cmd, nil)).To(Succeed())
+
+		group, _, err := cmd.Find([]string{"mycompany"})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(group.Short).To(Equal("My company commands"))
+		g.Expect(group.Commands()).To(HaveLen(2))
+
+		cmd.SetArgs([]string{"mycompany", "deploy", "staging"})
+		g.Expect(cmd.ExecuteContext(context.Background())).To(Succeed())
+		cmd.SetArgs([]string{"mycompany", "oncall"})
+		g.Expect(cmd.ExecuteContext(context.Background())).To(Succeed())
+		g.Expect(called).To(Equal([]string{"mycompany deploy staging", "mycompany oncall"}))
+	})
+
+	t.Run("fails when plugins declare the same nested command", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ps := &pluginSystem{}
+		for range 2 {
+			p := plugin_mock.NewMockPlugin(ctrl)
+			p.EXPECT().CustomCommands().Return([]*plugin.Command{
+				plugin.NewCommand("mycompany", "", "", nil).AddSubcommands(
+					plugin.NewCommand("deploy", "Deploys", "", nil),
+				),
+			}, nil)
+			ps.plugins = append(ps.plugins, &client.PluginInstance{
+				Plugin:   p,
+				Provider: client_mock.NewMockProvider(ctrl),
+			})
+		}
+
+		err := ps.RegisterCustomCommands(&cobra.Command{Use: "aspect"}, nil)
+		g.Expect(err).To(MatchError("failed to register custom commands: command mycompany deploy is declared more than once"))
+	})
+
+	t.Run("fails when a plugin command overrides a built-in command without being allowed to", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		p := plugin_mock.NewMockPlugin(ctrl)
+		p.EXPECT().CustomCommands().Return([]*plugin.Command{
+			plugin.NewCommand("test", "Tests", "", nil),
+		}, nil)
+
+		ps := &pluginSystem{}
+		ps.plugins = append(ps.plugins, &client.PluginInstance{
+			Plugin:   p,
+			Provider: client_mock.NewMockProvider(ctrl),
+		})
+
+		cmd := &cobra.Command{Use: "aspect"}
+		cmd.AddCommand(&cobra.Command{Use: "test", RunE: func(cmd *cobra.Command, args []string) error { return nil }})
+		err := ps.RegisterCustomCommands(cmd, nil)
+		g.Expect(err).To(MatchError("failed to register custom commands: plugin implements a command with a protected name: test"))
+	})
+
+	t.Run("overrides a built-in command that the plugin command can delegate to", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var pluginArgs []string
+		executor := customCommandExecutorFunc(func(cmdName string, ctx context.Context, args []string, bazelStartupArgs []string) error {
+			g.Expect(cmdName).To(Equal("test"))
+			pluginArgs = args
+			if slices.Contains(args, "--skip") {
+				return nil
+			}
+			return plugin.DelegateToBuiltin(append([]string{"--config=ci"}, args...))
+		})
+
+		p := plugin_mock.NewMockPlugin(ctrl)
+		p.EXPECT().CustomCommands().Return([]*plugin.Command{
+			plugin.NewCommand("test", "Tests with the org defaults", "", nil),
+		}, nil)
+
+		ps := &pluginSystem{}
+		ps.plugins = append(ps.plugins, &client.PluginInstance{
+			Plugin:                p,
+			Provider:              client_mock.NewMockProvider(ctrl),
+			CustomCommandExecutor: executor,
+			AllowCommandOverride:  true,
+		})
+
+		var builtinArgs []string
+		cmd := &cobra.Command{Use: "aspect"}
+		cmd.AddCommand(&cobra.Command{
+			Use:                "test",
+			DisableFlagParsing: true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				builtinArgs = args
+				return nil
+			},
+		})
+		g.Expect(ps.RegisterCustomCommands(cmd, nil)).To(Succeed())
+
+		test, _, err := cmd.Find([]string{"test"})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(test.Short).To(Equal("Tests with the org defaults"))
+
+		cmd.SetArgs([]string{"test", "//..."})
+		g.Expect(cmd.ExecuteContext(context.Background())).To(Succeed())
+		g.Expect(pluginArgs).To(Equal([]string{"//..."}))
+		g.Expect(builtinArgs).To(Equal([]string{"--config=ci", "//..."}))
+
+		builtinArgs = nil
+		cmd.SetArgs([]string{"test", "--skip"})
+		g.Expect(cmd.ExecuteContext(context.Background())).To(Succeed())
+		g.Expect(builtinArgs).To(BeNil())
+	})
+}
+
+func TestTearDown(t *testing.T) {
+	t.Run("kills plugins in parallel", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ps := NewPluginSystem().(*pluginSystem)
+
+		// Each plugin only exits once the other one is being killed.
+		killed1 := make(chan struct{})
+		killed2 := make(chan struct{})
+		provider1 := client_mock.NewMockProvider(ctrl)
+		provider1.EXPECT().Kill().Do(func() {
+			close(killed1)
+			<-killed2
+		})
+		provider2 := client_mock.NewMockProvider(ctrl)
+		provider2.EXPECT().Kill().Do(func() {
+			close(killed2)
+			<-killed1
+		})
+		ps.plugins = append(ps.plugins, &client.PluginInstance{
+			Plugin:          plugin_mock.NewMockPlugin(ctrl),
+			Provider:        provider1,
+			TeardownTimeout: 10 * time.Second,
+		})
+		ps.plugins = append(ps.plugins, &client.PluginInstance{
+			Plugin:          plugin_mock.NewMockPlugin(ctrl),
+			Provider:        provider2,
+			TeardownTimeout: 10 * time.Second,
+		})
+
+		start := time.Now()
+		ps.TearDown()
+		g.Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+	})
+
+	t.Run("returns when a plugin doesn't exit within its teardown timeout", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ps := NewPluginSystem().(*pluginSystem)
+
+		stuck := make(chan struct{})
+		defer close(stuck)
+		provider := client_mock.NewMockProvider(ctrl)
+		provider.EXPECT().Kill().Do(func() {
+			<-stuck
+		})
+		ps.plugins = append(ps.plugins, &client.PluginInstance{
+			Plugin:          plugin_mock.NewMockPlugin(ctrl),
+			Provider:        provider,
+			TeardownTimeout: 10 * time.Millisecond,
+		})
+
+		start := time.Now()
+		ps.TearDown()
+		g.Expect(time.Since(start)).To(BeNumerically("<", teardownKillGracePeriod+time.Second))
+	})
+}
+
+func TestBesTimeouts(t *testing.T) {
+	t.Run("defaults to no timeouts", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+
+		g.Expect(besTimeouts()).To(Equal(bep.Timeouts{}))
+	})
+
+	t.Run("parses the configured timeouts", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+		viper.Set(EventTimeoutKey, "30m")
+		viper.Set(SendTimeoutKey, "10s")
+		viper.Set(ProbeIntervalKey, "1m")
+		viper.Set(OutageTimeoutKey, "1h")
+
+		g.Expect(besTimeouts()).To(Equal(bep.Timeouts{
+			Event:  30 * time.Minute,
+			Send:   10 * time.Second,
+			Probe:  time.Minute,
+			Outage: time.Hour,
+		}))
+	})
+
+	t.Run("probes the unhealthy backends by default", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		g.Expect(bep.Timeouts{}.Recovery()).To(Equal(besproxy.Recovery{
+			ProbeInterval: 30 * time.Second,
+			Timeout:       10 * time.Minute,
+		}))
+		g.Expect(bep.Timeouts{Outage: time.Hour}.Recovery().Timeout).To(Equal(time.Hour))
+	})
+
+	t.Run("rejects durations that are not positive", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+		viper.Set(SendTimeoutKey, "-1s")
+
+		_, err := besTimeouts()
+		g.Expect(err).To(MatchError(`expected bes_send_timeout to be a positive duration such as 30s: "-1s"`))
+	})
+}
+
+func TestBesUnhealthyPolicy(t *testing.T) {
+	t.Run("defaults to aborting the upload", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+
+		g.Expect(besUnhealthyPolicy()).To(Equal(bep.UnhealthyAbort))
+	})
+
+	t.Run("spools the build events with deferred uploads", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+		viper.Set(DeferredUploadKey, true)
+
+		g.Expect(besUnhealthyPolicy()).To(Equal(bep.UnhealthySpool))
+	})
+
+	t.Run("parses the configured policy", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+		viper.Set(UnhealthyPolicyKey, "continue-without-bes")
+
+		g.Expect(besUnhealthyPolicy()).To(Equal(bep.UnhealthyContinue))
+	})
+
+	t.Run("rejects unknown policies", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+		viper.Set(UnhealthyPolicyKey, "retry")
+
+		_, err := besUnhealthyPolicy()
+		g.Expect(err).To(MatchError(ContainSubstring("invalid bes_unhealthy_policy")))
+	})
+}
+
+func TestBesPipeUploadMode(t *testing.T) {
+	t.Run("leaves the upload mode to bazel by default", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+
+		g.Expect(besPipeUploadMode("build", "")).To(BeEmpty())
+	})
+
+	t.Run("applies the configured mode to every command", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+		viper.Set(PipeUploadModeKey, "nowait_for_upload_complete")
+
+		g.Expect(besPipeUploadMode("test", "")).To(Equal(bep.UploadNoWait))
+	})
+
+	t.Run("applies the mode configured for the command", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+		viper.Set(PipeUploadModeKey, map[string]any{"build": "fully_async", "test": "wait_for_upload_complete"})
+
+		g.Expect(besPipeUploadMode("build", "")).To(Equal(bep.UploadFullyAsync))
+		g.Expect(besPipeUploadMode("test", "")).To(Equal(bep.UploadWait))
+		g.Expect(besPipeUploadMode("run", "")).To(BeEmpty())
+	})
+
+	t.Run("prefers the flag to the config", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+		viper.Set(PipeUploadModeKey, "fully_async")
+
+		g.Expect(besPipeUploadMode("build", "wait_for_upload_complete")).To(Equal(bep.UploadWait))
+	})
+
+	t.Run("rejects unknown modes", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+		viper.Set(PipeUploadModeKey, map[string]any{"build": "eventually"})
+
+		_, err := besPipeUploadMode("build", "")
+		g.Expect(err).To(MatchError(ContainSubstring("invalid bes_pipe_upload_mode")))
+		_, err = besPipeUploadMode("build", "later")
+		g.Expect(err).To(MatchError(ContainSubstring("invalid --aspect:bes_pipe_upload_mode")))
+	})
+}
+
+func TestRemoveBESFlags(t *testing.T) {
+	g := NewGomegaWithT(t)
+	t.Cleanup(viper.Reset)
+
+	besArgs, args := removeBESFlags([]string{"//...", "--aspect:bes_strict", "--aspect:bes_pipe_upload_mode=fully_async", "--", "--aspect:bes_strict"})
+	g.Expect(besArgs).To(Equal(besFlags{strict: true, pipeUploadMode: "fully_async"}))
+	g.Expect(args).To(Equal([]string{"//...", "--", "--aspect:bes_strict"}))
+}
+
+func TestBesMaxEventSize(t *testing.T) {
+	t.Run("defaults to the default size", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+
+		g.Expect(besMaxEventSize()).To(BeZero())
+	})
+
+	t.Run("parses the configured size", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+		viper.Set(MaxEventSizeKey, 64)
+
+		g.Expect(besMaxEventSize()).To(Equal(64 << 20))
+	})
+
+	t.Run("prefers the environment", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+		viper.Set(MaxEventSizeKey, 64)
+		t.Setenv(MaxEventSizeEnv, "128")
+
+		g.Expect(besMaxEventSize()).To(Equal(128 << 20))
+	})
+
+	t.Run("rejects sizes that are not positive", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+		viper.Set(MaxEventSizeKey, "0")
+
+		_, err := besMaxEventSize()
+		g.Expect(err).To(MatchError(`expected bes_max_event_size_mb to be a number of megabytes between 1 and 2047: "0"`))
+	})
+}
+
+func TestBesEventSampling(t *testing.T) {
+	t.Run("defaults to no sampling", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+
+		g.Expect(besEventSampling()).To(BeEmpty())
+	})
+
+	t.Run("parses the configured sampling", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+		viper.Set(EventSamplingKey, map[string]any{"progress": 50, "action_completed": "100"})
+
+		g.Expect(besEventSampling()).To(Equal(bep.EventSampling{"progress": 50, "action_completed": 100}))
+	})
+
+	t.Run("rejects unknown event types", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+		viper.Set(EventSamplingKey, map[string]any{"actions": 100})
+
+		_, err := besEventSampling()
+		g.Expect(err).To(MatchError(`unknown build event type "actions" in bes_sample_event_types`))
+	})
+
+	t.Run("rejects numbers that are not positive", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+		viper.Set(EventSamplingKey, map[string]any{"progress": 0})
+
+		_, err := besEventSampling()
+		g.Expect(err).To(MatchError(`invalid bes_sample_event_types for "progress": "0" is not a positive number of build events`))
+	})
+}
+
+func TestBesBackendListenAddress(t *testing.T) {
+	t.Run("defaults to a port assigned by the OS", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+
+		address, port, err := besBackendListenAddress()
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(address).To(BeEmpty())
+		g.Expect(port).To(BeZero())
+	})
+
+	t.Run("parses the configured address and port", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+		viper.Set(ListenAddressKey, "[::1]")
+		viper.Set(ListenPortKey, 8080)
+
+		address, port, err := besBackendListenAddress()
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(address).To(Equal("::1"))
+		g.Expect(port).To(Equal(8080))
+	})
+
+	t.Run("rejects an address with a port", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+		viper.Set(ListenAddressKey, "127.0.0.1:8080")
+
+		_, _, err := besBackendListenAddress()
+		g.Expect(err).To(MatchError(`expected bes_backend_address to be an IP address or a host name: "127.0.0.1:8080"`))
+	})
+
+	t.Run("rejects invalid ports", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+		viper.Set(ListenPortKey, 65536)
+
+		_, _, err := besBackendListenAddress()
+		g.Expect(err).To(MatchError(`expected bes_backend_port to be a port number between 0 and 65535: "65536"`))
+	})
+}
+
+func TestBesServer(t *testing.T) {
+	t.Run("defaults to the largest messages and pings every second", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+
+		g.Expect(besServer()).To(Equal(besServerConfig{
+			MaxRecvMsgSize:      math.MaxInt32,
+			MaxSendMsgSize:      math.MaxInt32,
+			KeepaliveMinTime:    time.Second,
+			PermitWithoutStream: true,
+		}))
+	})
+
+	t.Run("parses the configured options", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+		viper.Set(MaxRecvMessageSizeKey, 512)
+		viper.Set(MaxSendMessageSizeKey, "16")
+		viper.Set(KeepaliveMinTimeKey, "10s")
+		viper.Set(KeepalivePermitWithoutStreamKey, false)
+		viper.Set(MaxConcurrentStreamsKey, 100)
+
+		server, err := besServer()
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(server).To(Equal(besServerConfig{
+			MaxRecvMsgSize:       512 << 20,
+			MaxSendMsgSize:       16 << 20,
+			KeepaliveMinTime:     10 * time.Second,
+			PermitWithoutStream:  false,
+			MaxConcurrentStreams: 100,
+		}))
+		g.Expect(server.options()).To(HaveLen(4))
+	})
+
+	t.Run("rejects invalid options", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		for key, value := range map[string]any{
+			MaxRecvMessageSizeKey:   "0",
+			MaxSendMessageSizeKey:   "4096",
+			KeepaliveMinTimeKey:     "-1s",
+			MaxConcurrentStreamsKey: "0",
+		} {
+			viper.Reset()
+			viper.Set(key, value)
+
+			_, err := besServer()
+			g.Expect(err).To(MatchError(ContainSubstring("expected %s to be", key)))
+		}
+		viper.Reset()
+	})
+}
+
+func TestBesFlowControl(t *testing.T) {
+	t.Run("leaves the options unset for the defaults", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+
+		g.Expect(besFlowControl()).To(Equal(besproxy.FlowControl{}))
+	})
+
+	t.Run("parses the configured options", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Cleanup(viper.Reset)
+		viper.Set(StreamWindowSizeKey, 8)
+		viper.Set(StreamConnWindowSizeKey, "32")
+		viper.Set(StreamWriteBufferSizeKey, 512)
+		viper.Set(StreamReadBufferSizeKey, 64)
+		viper.Set(StreamMaxInFlightKey, 50000)
+
+		g.Expect(besFlowControl()).To(Equal(besproxy.FlowControl{
+			WindowSize:      8 << 20,
+			ConnWindowSize:  32 << 20,
+			WriteBufferSize: 512 << 10,
+			ReadBufferSize:  64 << 10,
+			MaxInFlight:     50000,
+		}))
+	})
+
+	t.Run("rejects invalid options", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		for key, value := range map[string]any{
+			StreamWindowSizeKey:      "0",
+			StreamConnWindowSizeKey:  "4096",
+			StreamWriteBufferSizeKey: "-1",
+			StreamReadBufferSizeKey:  "64k",
+			StreamMaxInFlightKey:     "0",
+		} {
+			viper.Reset()
+			viper.Set(key, value)
+
+			_, err := besFlowControl()
+			g.Expect(err).To(MatchError(ContainSubstring("expected %s to be", key)))
+		}
+		viper.Reset()
+	})
+}
+
+func TestStrictDeliveryError(t *testing.T) {
+	t.Run("passes when every backend acknowledged every build event", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		g.Expect(strictDeliveryError(nil)).To(Succeed())
+		g.Expect(strictDeliveryError([]bep.AckReport{
+			{Host: "grpc://a", Sent: 3, Acknowledged: 3, Reconnects: 1, Healthy: true},
+		})).To(Succeed())
+	})
+
+	t.Run("fails with the backends that missed build events", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		err := strictDeliveryError([]bep.AckReport{
+			{Host: "grpc://a", Sent: 3, Acknowledged: 3, Healthy: true},
+			{Host: "grpc://b", Sent: 3, Acknowledged: 2, Healthy: true},
+			{Host: "grpc://c", Sent: 3, Acknowledged: 3},
+		})
+		g.Expect(err).To(MatchError("--aspect:bes_strict: the build events were not all delivered to grpc://b, grpc://c"))
+		var exitErr *aspecterrors.ExitError
+		g.Expect(errors.As(err, &exitErr)).To(BeTrue())
+		g.Expect(exitErr.ExitCode).To(Equal(45))
+	})
 }
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package system
+
+import (
+	"sync"
+
+	"github.com/aspect-build/aspect-cli-legacy/buildinfo"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/plugin/sdk/v1alpha4/plugin"
+)
+
+// setupWorkspace describes the workspace to the plugins in their Setup. It is
+// resolved once per CLI invocation and without starting the bazel server, so
+// that setting up plugins stays cheap.
+var setupWorkspace = sync.OnceValue(func() *plugin.Workspace {
+	return resolveWorkspace(bazel.WorkspaceFromWd.WorkspaceRoot())
+})
+
+// resolveWorkspace describes the workspace at workspaceRoot. Values that can't
+// be determined are left empty rather than failing the setup of the plugins.
+func resolveWorkspace(workspaceRoot string) *plugin.Workspace {
+	workspace := &plugin.Workspace{
+		Root:       workspaceRoot,
+		CLIVersion: buildinfo.Current().Version(),
+	}
+	if workspaceRoot == "" {
+		return workspace
+	}
+	if outputBase, err := bazel.OutputBase(workspaceRoot); err == nil {
+		workspace.OutputBase = outputBase
+		workspace.ExecutionRoot = bazel.ExecutionRoot(workspaceRoot, outputBase)
+	}
+	if version, err := bazel.RequestedVersion(workspaceRoot); err == nil {
+		workspace.BazelVersion = version
+	}
+	return workspace
+}
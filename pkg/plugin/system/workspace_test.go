@@ -0,0 +1,58 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/aspect-build/aspect-cli-legacy/buildinfo"
+	"github.com/aspect-build/aspect-cli-legacy/pkg/bazel"
+)
+
+func TestResolveWorkspace(t *testing.T) {
+	t.Run("only reports the CLI version outside of a workspace", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		workspace := resolveWorkspace("")
+		g.Expect(workspace.Root).To(BeEmpty())
+		g.Expect(workspace.OutputBase).To(BeEmpty())
+		g.Expect(workspace.ExecutionRoot).To(BeEmpty())
+		g.Expect(workspace.BazelVersion).To(BeEmpty())
+		g.Expect(workspace.CLIVersion).To(Equal(buildinfo.Current().Version()))
+	})
+
+	t.Run("describes the workspace without running bazel", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		t.Setenv("USE_BAZEL_VERSION", "")
+		workspaceRoot := t.TempDir()
+		g.Expect(os.WriteFile(filepath.Join(workspaceRoot, "MODULE.bazel"), nil, 0644)).To(Succeed())
+		g.Expect(os.WriteFile(filepath.Join(workspaceRoot, ".bazelversion"), []byte("7.4.1\n"), 0644)).To(Succeed())
+
+		outputBase, err := bazel.OutputBase(workspaceRoot)
+		g.Expect(err).ToNot(HaveOccurred())
+
+		workspace := resolveWorkspace(workspaceRoot)
+		g.Expect(workspace.Root).To(Equal(workspaceRoot))
+		g.Expect(workspace.OutputBase).To(Equal(outputBase))
+		g.Expect(workspace.ExecutionRoot).To(Equal(filepath.Join(outputBase, "execroot", "_main")))
+		g.Expect(workspace.BazelVersion).To(Equal("7.4.1"))
+		g.Expect(workspace.CLIVersion).To(Equal(buildinfo.Current().Version()))
+	})
+}
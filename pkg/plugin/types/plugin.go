@@ -16,13 +16,129 @@
 
 package types
 
+import "time"
+
+// Plugin runtimes supported by the plugin system.
+const (
+	// RuntimeNative runs the plugin as a go-plugin subprocess. This is the
+	// default when no runtime is configured.
+	RuntimeNative = ""
+	// RuntimeWasm runs a plugin compiled to WebAssembly in-process.
+	RuntimeWasm = "wasm"
+	// RuntimeStdio runs a plain executable that speaks newline-delimited JSON
+	// over its stdin and stdout.
+	RuntimeStdio = "stdio"
+)
+
+// Restart policies of a plugin whose process exits unexpectedly.
+const (
+	// RestartNever leaves a plugin that exited unexpectedly stopped. This is the
+	// default when no restart policy is configured.
+	RestartNever = "never"
+	// RestartOnFailure relaunches a plugin that exited unexpectedly, up to
+	// MaxRestarts times.
+	RestartOnFailure = "on-failure"
+)
+
+// Delivery modes of the build events of a plugin with multi-threaded build
+// events.
+const (
+	// BuildEventDeliveryOrdered delivers build events to the plugin one at a
+	// time in the order bazel emits them. This is the default when no delivery
+	// mode is configured.
+	BuildEventDeliveryOrdered = "ordered"
+	// BuildEventDeliveryUnordered delivers build events to the plugin from
+	// BuildEventWorkers workers concurrently.
+	BuildEventDeliveryUnordered = "unordered"
+)
+
+// Policies for the build event queue of a plugin with multi-threaded build
+// events once it is full.
+const (
+	// BuildEventQueueBlock holds up reading the build events until the plugin
+	// makes room in its queue. This is the default when no policy is
+	// configured.
+	BuildEventQueueBlock = "block"
+	// BuildEventQueueDropOldest drops the oldest build event queued for the
+	// plugin.
+	BuildEventQueueDropOldest = "drop-oldest"
+	// BuildEventQueueFail fails the plugin, which receives no more build
+	// events of the invocation.
+	BuildEventQueueFail = "fail"
+)
+
+// DefaultMaxRestarts is how often a plugin is restarted under the
+// RestartOnFailure policy unless its config sets max_restarts.
+const DefaultMaxRestarts = 3
+
 // PluginConfig represents a plugin entry in the config file.
 type PluginConfig struct {
 	Name                     string
 	From                     string
 	Version                  string
 	LogLevel                 string
+	Runtime                  string
 	MultiThreadedBuildEvents bool
 	DisableBESEvents         bool
 	Properties               map[string]any
+	// SHA256 pins the expected hex encoded sha256 of the plugin binary for
+	// each platform, keyed by os_arch (e.g. linux_amd64) or "wasm".
+	SHA256 map[string]string
+	// DependsOn names the plugins that must be set up before this one. Hooks and
+	// build event callbacks of the plugins it depends on are also called first.
+	DependsOn []string
+	// Priority orders the hooks and build event callbacks of the plugin before
+	// those of plugins with a lower priority, unless it depends on them.
+	// Plugins with the same priority keep the order they are configured in.
+	Priority int
+	// BuildEventDelivery is how build events are delivered to the plugin when
+	// MultiThreadedBuildEvents is set, either BuildEventDeliveryOrdered or
+	// BuildEventDeliveryUnordered. BuildEventDeliveryOrdered applies when empty.
+	BuildEventDelivery string
+	// BuildEventWorkers is the number of workers delivering build events to the
+	// plugin with BuildEventDeliveryUnordered. The plugin system default
+	// applies when zero.
+	BuildEventWorkers int
+	// BuildEventQueueSize is the number of build events queued for the plugin
+	// when MultiThreadedBuildEvents is set. The plugin system default applies
+	// when zero.
+	BuildEventQueueSize int
+	// BuildEventQueuePolicy applies when the build event queue of the plugin
+	// is full, one of BuildEventQueueBlock, BuildEventQueueDropOldest or
+	// BuildEventQueueFail. BuildEventQueueBlock applies when empty.
+	BuildEventQueuePolicy string
+	// Commands scopes the plugin to the named commands, e.g. build and test. A
+	// scoped plugin is only launched when one of them runs, unless a plugin that
+	// is launched depends on it. The plugin is launched for every command when
+	// empty.
+	Commands []string
+	// SetupTimeout bounds how long the plugin may take in Setup. The plugin
+	// system default applies when zero.
+	SetupTimeout time.Duration
+	// TeardownTimeout bounds how long the plugin may take to exit when the
+	// plugin system is torn down before it is killed with SIGKILL. The plugin
+	// system default applies when zero.
+	TeardownTimeout time.Duration
+	// EnvAllowlist restricts the environment variables the plugin inherits to
+	// those matching one of its names or glob patterns. The plugin inherits the
+	// whole environment when nil.
+	EnvAllowlist []string
+	// Env sets environment variables of the plugin, overriding inherited ones.
+	// It is configured as a list of NAME=value.
+	Env map[string]string
+	// PrefixOutput prefixes every line the plugin writes to stdout and stderr
+	// with its name.
+	PrefixOutput bool
+	// Restart is the restart policy of the plugin, either RestartNever or
+	// RestartOnFailure. RestartNever applies when empty.
+	Restart string
+	// MaxRestarts bounds how often the plugin is restarted under the
+	// RestartOnFailure policy.
+	MaxRestarts int
+	// AllowCommandOverride lets a custom command of the plugin override the
+	// built-in command of the same name, which it can delegate back to.
+	AllowCommandOverride bool
+	// Disabled leaves the plugin out, e.g. to disable a plugin of the workspace
+	// config in the home config.
+	Disabled bool
 }
@@ -31,6 +31,14 @@ var (
 	BazelArgsKey = attribute.Key("bazel.args")
 	// BazelInvocationIdKey is the Bazel invocation ID.
 	BazelInvocationIdKey = attribute.Key("bazel.invocation_id")
+	// BazelTargetKey is the label of the target of a span.
+	BazelTargetKey = attribute.Key("bazel.target")
+	// BazelMnemonicKey is the mnemonic of the action of a span.
+	BazelMnemonicKey = attribute.Key("bazel.mnemonic")
+	// BazelTestStatusKey is the status of the test attempt of a span.
+	BazelTestStatusKey = attribute.Key("bazel.test.status")
+	// BazelExitCodeKey is the exit code of the invocation or action of a span.
+	BazelExitCodeKey = attribute.Key("bazel.exit_code")
 )
 
 // BazelInvocationId returns a span attribute for the given Bazel invocation ID.
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package telemetry
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// PluginNameKey is the name of the plugin that reported a metric.
+	PluginNameKey = attribute.Key("aspect.plugin.name")
+	// PluginCounterValueKey is the value added to a counter of a plugin.
+	PluginCounterValueKey = attribute.Key("aspect.plugin.counter.value")
+)
+
+// PluginCounterKeyPrefix prefixes the names of the counters of a plugin in the
+// attributes of its span.
+const PluginCounterKeyPrefix = "aspect.plugin.counter."
+
+var (
+	openPluginsMu sync.Mutex
+	openPlugins   = map[*PluginTelemetry]struct{}{}
+)
+
+// PluginTelemetry records the metrics reported by a plugin in the telemetry
+// session. The plugin is traced as a span, started when the plugin reports its
+// first metric and ended when the plugin is closed or the session ends. The
+// timings of the plugin are its child spans. Each addition to a counter is an
+// event of the span, and the totals of the counters are its attributes.
+type PluginTelemetry struct {
+	name   string
+	tracer trace.Tracer
+
+	mu       sync.Mutex
+	ctx      context.Context
+	span     trace.Span
+	counters map[string]float64
+	closed   bool
+}
+
+// NewPluginTelemetry returns the telemetry of the named plugin.
+func NewPluginTelemetry(name string) *PluginTelemetry {
+	return &PluginTelemetry{
+		name:     name,
+		tracer:   otel.Tracer("aspect-plugin"),
+		counters: map[string]float64{},
+	}
+}
+
+// AddCounter adds value to the named counter.
+func (t *PluginTelemetry) AddCounter(name string, value float64, attributes map[string]string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.start() {
+		return nil
+	}
+	t.counters[name] += value
+	t.span.AddEvent(name, trace.WithAttributes(append(attributeList(attributes), PluginCounterValueKey.Float64(value))...))
+	return nil
+}
+
+// RecordTiming records that the named operation started at start and took
+// duration.
+func (t *PluginTelemetry) RecordTiming(name string, start time.Time, duration time.Duration, attributes map[string]string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.start() {
+		return nil
+	}
+	_, span := t.tracer.Start(t.ctx, name,
+		trace.WithTimestamp(start),
+		trace.WithAttributes(append(attributeList(attributes), PluginNameKey.String(t.name))...),
+	)
+	span.End(trace.WithTimestamp(start.Add(duration)))
+	return nil
+}
+
+// Close ends the span of the plugin. Metrics reported afterwards are dropped.
+func (t *PluginTelemetry) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.end()
+	return nil
+}
+
+// start starts the span of the plugin unless it is already started, and
+// returns false once the plugin is closed. The span is started lazily since
+// plugins are launched before the telemetry session starts.
+func (t *PluginTelemetry) start() bool {
+	if t.closed {
+		return false
+	}
+	if t.span == nil {
+		t.ctx, t.span = t.tracer.Start(context.Background(), "plugin "+t.name,
+			trace.WithAttributes(PluginNameKey.String(t.name)))
+
+		openPluginsMu.Lock()
+		openPlugins[t] = struct{}{}
+		openPluginsMu.Unlock()
+	}
+	return true
+}
+
+func (t *PluginTelemetry) end() {
+	if t.closed {
+		return
+	}
+	t.closed = true
+	if t.span == nil {
+		return
+	}
+	for name, total := range t.counters {
+		t.span.SetAttributes(attribute.Float64(PluginCounterKeyPrefix+name, total))
+	}
+	t.span.End()
+
+	openPluginsMu.Lock()
+	delete(openPlugins, t)
+	openPluginsMu.Unlock()
+}
+
+// endPluginSpans ends the spans of the plugins that are still running, so that
+// they are exported before the session ends.
+func endPluginSpans() {
+	openPluginsMu.Lock()
+	plugins := make([]*PluginTelemetry, 0, len(openPlugins))
+	for t := range openPlugins {
+		plugins = append(plugins, t)
+	}
+	openPluginsMu.Unlock()
+
+	for _, t := range plugins {
+		t.Close()
+	}
+}
+
+func attributeList(attributes map[string]string) []attribute.KeyValue {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	list := make([]attribute.KeyValue, 0, len(keys)+1)
+	for _, k := range keys {
+		list = append(list, attribute.String(k, attributes[k]))
+	}
+	return list
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2026 Aspect Build Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package telemetry
+
+import (
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestPluginTelemetry(t *testing.T) {
+	recorder := recordSpans(t)
+
+	pluginTelemetry := NewPluginTelemetry("lint")
+	start := time.Unix(1700000000, 0)
+	pluginTelemetry.AddCounter("files_linted", 3, nil)
+	pluginTelemetry.AddCounter("files_linted", 2, map[string]string{"linter": "eslint"})
+	pluginTelemetry.RecordTiming("upload", start, 2*time.Second, map[string]string{"status": "ok"})
+	endPluginSpans()
+
+	// Metrics reported once the session ended are dropped.
+	pluginTelemetry.AddCounter("files_linted", 1, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	timing, plugin := spans[0], spans[1]
+	if timing.Name() != "upload" || !timing.StartTime().Equal(start) || timing.EndTime().Sub(timing.StartTime()) != 2*time.Second {
+		t.Errorf("unexpected timing span %q from %v to %v", timing.Name(), timing.StartTime(), timing.EndTime())
+	}
+	if timing.Parent().SpanID() != plugin.SpanContext().SpanID() {
+		t.Errorf("expected the timing span to be a child of the plugin span")
+	}
+	if !hasAttribute(timing.Attributes(), attribute.String("status", "ok")) || !hasAttribute(timing.Attributes(), PluginNameKey.String("lint")) {
+		t.Errorf("unexpected timing attributes %v", timing.Attributes())
+	}
+
+	if plugin.Name() != "plugin lint" {
+		t.Errorf("unexpected plugin span %q", plugin.Name())
+	}
+	if !hasAttribute(plugin.Attributes(), attribute.Float64(PluginCounterKeyPrefix+"files_linted", 5)) {
+		t.Errorf("unexpected plugin attributes %v", plugin.Attributes())
+	}
+	if len(plugin.Events()) != 2 {
+		t.Errorf("expected an event per counter addition, got %v", plugin.Events())
+	}
+}
+
+func hasAttribute(attributes []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, a := range attributes {
+		if a == want {
+			return true
+		}
+	}
+	return false
+}
@@ -151,6 +151,7 @@ func setupOTelTracer(ctx context.Context, exp trace.SpanExporter) (func(), error
 	))
 
 	return func() {
+		endPluginSpans()
 		err := tp.ForceFlush(ctx)
 		if err != nil {
 			panic(err)